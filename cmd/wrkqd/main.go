@@ -1,9 +1,15 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/lherron/wrkq/internal/cli"
 )
@@ -13,17 +19,123 @@ func main() {
 	unixPath := flag.String("unix", os.Getenv("WRKQD_UNIX"), "Listen on unix socket path")
 	token := flag.String("token", os.Getenv("WRKQD_TOKEN"), "Shared token for local auth")
 	dbPath := flag.String("db", "", "Database path override (defaults to config)")
+	configPath := flag.String("config", "", "Config file (overrides WRKQ_CONFIG)")
+	profile := flag.String("profile", "", "Config profile to use (overrides WRKQ_PROFILE)")
+	watchPoll := flag.Duration("watch-poll", time.Second, "Poll interval for /v1/events/watch")
+	watchMax := flag.Duration("watch-max", 5*time.Minute, "Maximum duration of a single /v1/events/watch stream")
+	readTimeout := flag.Duration("read-timeout", envDuration("WRKQD_READ_TIMEOUT", 30*time.Second), "HTTP read timeout (0 disables)")
+	writeTimeout := flag.Duration("write-timeout", envDuration("WRKQD_WRITE_TIMEOUT", 30*time.Second), "HTTP write timeout (0 disables; /v1/events/watch streams are exempt)")
+	idleTimeout := flag.Duration("idle-timeout", envDuration("WRKQD_IDLE_TIMEOUT", 0), "HTTP idle keep-alive timeout (0 uses read timeout)")
+	maxBodyBytes := flag.Int64("max-body-bytes", envInt64("WRKQD_MAX_BODY_BYTES", 0), "Maximum request body size in bytes (0 = unlimited)")
+	shutdownTimeout := flag.Duration("shutdown-timeout", envDuration("WRKQD_SHUTDOWN_TIMEOUT", 10*time.Second), "Grace period for in-flight requests on SIGINT/SIGTERM")
+	idempotencyTTL := flag.Duration("idempotency-ttl", envDuration("WRKQD_IDEMPOTENCY_TTL", 24*time.Hour), "How long Idempotency-Key responses are replayed")
+	maxWrites := flag.Int("max-concurrent-writes", int(envInt64("WRKQD_MAX_CONCURRENT_WRITES", 0)), "Maximum concurrent write requests (0 = unlimited)")
+	writeQueueTimeout := flag.Duration("write-queue-timeout", envDuration("WRKQD_WRITE_QUEUE_TIMEOUT", 5*time.Second), "How long a write waits for a slot before failing with 429")
+	writeRate := flag.Float64("write-rate", envFloat64("WRKQD_WRITE_RATE", 0), "Write requests per second per token (0 = unlimited)")
+	writeBurst := flag.Int("write-burst", int(envInt64("WRKQD_WRITE_BURST", 0)), "Write burst size per token (defaults to the rate)")
+	syncWebhooks := flag.Bool("sync-webhooks", envBool("WRKQD_SYNC_WEBHOOKS", false), "Deliver webhooks before write requests return instead of in the background")
+	webhookPoll := flag.Duration("webhook-poll", envDuration("WRKQD_WEBHOOK_POLL", 5*time.Second), "How often the webhook dispatcher checks for due retries")
+	corsOrigins := flag.String("cors-origins", os.Getenv("WRKQD_CORS_ORIGINS"), "Comma-separated browser origins allowed to call the API, or * (empty disables CORS)")
+	corsMethods := flag.String("cors-methods", os.Getenv("WRKQD_CORS_METHODS"), "Comma-separated methods CORS preflights allow (default GET, POST)")
+	corsHeaders := flag.String("cors-headers", os.Getenv("WRKQD_CORS_HEADERS"), "Comma-separated request headers CORS preflights allow (default: those the API uses)")
+	corsCredentials := flag.Bool("cors-credentials", envBool("WRKQD_CORS_CREDENTIALS", false), "Allow browsers to send credentials with CORS requests")
+	logLevel := flag.String("log-level", envString("WRKQD_LOG_LEVEL", "info"), "Request log level on stderr (debug, info, warn, error, off)")
+	selectorCacheSize := flag.Int("selector-cache-size", int(envInt64("WRKQD_SELECTOR_CACHE_SIZE", 0)), "Project and assignee selectors to cache across requests (0 disables)")
 	flag.Parse()
 
 	opts := cli.DaemonOptions{
-		Addr:   *addr,
-		Unix:   *unixPath,
-		Token:  *token,
-		DBPath: *dbPath,
+		Addr:                *addr,
+		Unix:                *unixPath,
+		Token:               *token,
+		DBPath:              *dbPath,
+		ConfigFile:          *configPath,
+		Profile:             *profile,
+		WatchPollInterval:   *watchPoll,
+		WatchMaxDuration:    *watchMax,
+		ReadTimeout:         *readTimeout,
+		WriteTimeout:        *writeTimeout,
+		IdleTimeout:         *idleTimeout,
+		MaxBodyBytes:        *maxBodyBytes,
+		ShutdownTimeout:     *shutdownTimeout,
+		IdempotencyTTL:      *idempotencyTTL,
+		MaxConcurrentWrites: *maxWrites,
+		WriteQueueTimeout:   *writeQueueTimeout,
+		WriteRateLimit:      *writeRate,
+		WriteRateBurst:      *writeBurst,
+		SyncWebhooks:        *syncWebhooks,
+		WebhookPollInterval: *webhookPoll,
+		LogLevel:            *logLevel,
+		CORSOrigins:         splitList(*corsOrigins),
+		CORSMethods:         splitList(*corsMethods),
+		CORSHeaders:         splitList(*corsHeaders),
+		CORSCredentials:     *corsCredentials,
+		SelectorCacheSize:   *selectorCacheSize,
 	}
 
-	if err := cli.ServeDaemon(opts); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := cli.ServeDaemon(ctx, opts); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// envString reads a string from the environment, falling back to def when unset.
+func envString(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+// envDuration reads a duration from the environment, falling back to def when unset or invalid.
+func envDuration(name string, def time.Duration) time.Duration {
+	if v := os.Getenv(name); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return def
+}
+
+// envInt64 reads an integer from the environment, falling back to def when unset or invalid.
+func envInt64(name string, def int64) int64 {
+	if v := os.Getenv(name); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+// envFloat64 reads a float from the environment, falling back to def when unset or invalid.
+func envFloat64(name string, def float64) float64 {
+	if v := os.Getenv(name); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return def
+}
+
+// envBool reads a boolean from the environment, falling back to def when unset or invalid.
+func envBool(name string, def bool) bool {
+	if v := os.Getenv(name); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return def
+}
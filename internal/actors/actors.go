@@ -14,6 +14,18 @@ func parseTime(s string) (time.Time, error) {
 	return time.Parse(time.RFC3339, s)
 }
 
+// parseOptionalTime parses a nullable timestamp column
+func parseOptionalTime(s sql.NullString) (*time.Time, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	t, err := parseTime(s.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
 // Resolver handles actor resolution
 type Resolver struct {
 	db *sql.DB
@@ -75,12 +87,13 @@ func (r *Resolver) resolveBySlug(slug string) (string, error) {
 func (r *Resolver) GetByUUID(uuid string) (*domain.Actor, error) {
 	actor := &domain.Actor{}
 	var createdAt, updatedAt string
+	var archivedAt sql.NullString
 	err := r.db.QueryRow(`
-		SELECT uuid, id, slug, display_name, role, meta, created_at, updated_at
+		SELECT uuid, id, slug, display_name, role, meta, created_at, updated_at, archived_at
 		FROM actors WHERE uuid = ?
 	`, uuid).Scan(
 		&actor.UUID, &actor.ID, &actor.Slug, &actor.DisplayName,
-		&actor.Role, &actor.Meta, &createdAt, &updatedAt,
+		&actor.Role, &actor.Meta, &createdAt, &updatedAt, &archivedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -97,6 +110,10 @@ func (r *Resolver) GetByUUID(uuid string) (*domain.Actor, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse updated_at: %w", err)
 	}
+	actor.ArchivedAt, err = parseOptionalTime(archivedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse archived_at: %w", err)
+	}
 
 	return actor, nil
 }
@@ -105,12 +122,13 @@ func (r *Resolver) GetByUUID(uuid string) (*domain.Actor, error) {
 func (r *Resolver) GetBySlug(slug string) (*domain.Actor, error) {
 	actor := &domain.Actor{}
 	var createdAt, updatedAt string
+	var archivedAt sql.NullString
 	err := r.db.QueryRow(`
-		SELECT uuid, id, slug, display_name, role, meta, created_at, updated_at
+		SELECT uuid, id, slug, display_name, role, meta, created_at, updated_at, archived_at
 		FROM actors WHERE slug = ?
 	`, slug).Scan(
 		&actor.UUID, &actor.ID, &actor.Slug, &actor.DisplayName,
-		&actor.Role, &actor.Meta, &createdAt, &updatedAt,
+		&actor.Role, &actor.Meta, &createdAt, &updatedAt, &archivedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -127,16 +145,26 @@ func (r *Resolver) GetBySlug(slug string) (*domain.Actor, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse updated_at: %w", err)
 	}
+	actor.ArchivedAt, err = parseOptionalTime(archivedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse archived_at: %w", err)
+	}
 
 	return actor, nil
 }
 
-// List lists all actors
-func (r *Resolver) List() ([]*domain.Actor, error) {
-	rows, err := r.db.Query(`
-		SELECT uuid, id, slug, display_name, role, meta, created_at, updated_at
-		FROM actors ORDER BY created_at DESC
-	`)
+// List lists actors, newest first. Archived (inactive) actors are only
+// included when includeInactive is set.
+func (r *Resolver) List(includeInactive bool) ([]*domain.Actor, error) {
+	query := `
+		SELECT uuid, id, slug, display_name, role, meta, created_at, updated_at, archived_at
+		FROM actors`
+	if !includeInactive {
+		query += " WHERE archived_at IS NULL"
+	}
+	query += " ORDER BY created_at DESC"
+
+	rows, err := r.db.Query(query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list actors: %w", err)
 	}
@@ -146,9 +174,10 @@ func (r *Resolver) List() ([]*domain.Actor, error) {
 	for rows.Next() {
 		actor := &domain.Actor{}
 		var createdAt, updatedAt string
+		var archivedAt sql.NullString
 		err := rows.Scan(
 			&actor.UUID, &actor.ID, &actor.Slug, &actor.DisplayName,
-			&actor.Role, &actor.Meta, &createdAt, &updatedAt,
+			&actor.Role, &actor.Meta, &createdAt, &updatedAt, &archivedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan actor: %w", err)
@@ -162,6 +191,10 @@ func (r *Resolver) List() ([]*domain.Actor, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse updated_at: %w", err)
 		}
+		actor.ArchivedAt, err = parseOptionalTime(archivedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse archived_at: %w", err)
+		}
 
 		actors = append(actors, actor)
 	}
@@ -195,12 +228,13 @@ func (r *Resolver) Create(slug, displayName, role string) (*domain.Actor, error)
 	// Fetch the created actor
 	actor := &domain.Actor{}
 	var createdAt, updatedAt string
+	var archivedAt sql.NullString
 	err = tx.QueryRow(`
-		SELECT uuid, id, slug, display_name, role, meta, created_at, updated_at
+		SELECT uuid, id, slug, display_name, role, meta, created_at, updated_at, archived_at
 		FROM actors WHERE rowid = ?
 	`, rowID).Scan(
 		&actor.UUID, &actor.ID, &actor.Slug, &actor.DisplayName,
-		&actor.Role, &actor.Meta, &createdAt, &updatedAt,
+		&actor.Role, &actor.Meta, &createdAt, &updatedAt, &archivedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get created actor: %w", err)
@@ -215,6 +249,10 @@ func (r *Resolver) Create(slug, displayName, role string) (*domain.Actor, error)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse updated_at: %w", err)
 	}
+	actor.ArchivedAt, err = parseOptionalTime(archivedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse archived_at: %w", err)
+	}
 
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
@@ -222,3 +260,16 @@ func (r *Resolver) Create(slug, displayName, role string) (*domain.Actor, error)
 
 	return actor, nil
 }
+
+// SetActive deactivates (archives) or reactivates an actor. Archived actors
+// keep resolving so historical attribution stays intact.
+func (r *Resolver) SetActive(uuid string, active bool) (*domain.Actor, error) {
+	query := "UPDATE actors SET archived_at = strftime('%Y-%m-%dT%H:%M:%SZ','now') WHERE uuid = ? AND archived_at IS NULL"
+	if active {
+		query = "UPDATE actors SET archived_at = NULL WHERE uuid = ? AND archived_at IS NOT NULL"
+	}
+	if _, err := r.db.Exec(query, uuid); err != nil {
+		return nil, fmt.Errorf("failed to update actor: %w", err)
+	}
+	return r.GetByUUID(uuid)
+}
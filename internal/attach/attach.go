@@ -101,6 +101,22 @@ func CopyFile(src, dst string) (size int64, checksum string, err error) {
 	return size, checksum, nil
 }
 
+// Checksum returns the hex-encoded SHA-256 of a file's contents, matching the
+// checksum recorded by CopyFile.
+func Checksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
 // DetectMimeType attempts to detect MIME type from filename extension.
 // Falls back to application/octet-stream if unknown.
 func DetectMimeType(filename string) string {
@@ -236,3 +236,28 @@ func TestGetFileSize(t *testing.T) {
 		t.Error("GetFileSize() should error on non-existent file")
 	}
 }
+
+func TestChecksum(t *testing.T) {
+	tmpDir := t.TempDir()
+	srcPath := filepath.Join(tmpDir, "source.txt")
+	if err := os.WriteFile(srcPath, []byte("checksum me"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, copyChecksum, err := CopyFile(srcPath, filepath.Join(tmpDir, "copy.txt"))
+	if err != nil {
+		t.Fatalf("CopyFile() error = %v", err)
+	}
+
+	checksum, err := Checksum(srcPath)
+	if err != nil {
+		t.Fatalf("Checksum() error = %v", err)
+	}
+	if checksum != copyChecksum {
+		t.Errorf("Checksum() = %s, want %s", checksum, copyChecksum)
+	}
+
+	if _, err := Checksum(filepath.Join(tmpDir, "missing.txt")); err == nil {
+		t.Error("Checksum() expected error for missing file")
+	}
+}
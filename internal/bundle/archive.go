@@ -0,0 +1,334 @@
+package bundle
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IsArchive reports whether path names a bundle archive (.tar.gz, .tgz, or
+// .zip) rather than a bundle directory.
+func IsArchive(path string) bool {
+	return archiveFormat(path) != ""
+}
+
+func archiveFormat(path string) string {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tar.gz"
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip"
+	default:
+		return ""
+	}
+}
+
+// LoadFrom loads a bundle from either a directory or an archive file. Callers
+// should Close the returned bundle to remove any extracted files.
+func LoadFrom(path string) (*Bundle, error) {
+	if IsArchive(path) {
+		return LoadArchive(path)
+	}
+	return Load(path)
+}
+
+// LoadArchive extracts a .tar.gz or .zip bundle into a temporary directory
+// and loads it. The archive may hold the bundle at its root or inside a single
+// top-level directory. Close the returned bundle to remove the extracted files.
+func LoadArchive(archivePath string) (*Bundle, error) {
+	tmpDir, err := os.MkdirTemp("", "wrkq-bundle-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+
+	b, err := func() (*Bundle, error) {
+		var err error
+		switch archiveFormat(archivePath) {
+		case "tar.gz":
+			err = extractTarGz(archivePath, tmpDir)
+		case "zip":
+			err = extractZip(archivePath, tmpDir)
+		default:
+			err = fmt.Errorf("unsupported archive format: %s", archivePath)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		bundleDir, err := findBundleRoot(tmpDir)
+		if err != nil {
+			return nil, err
+		}
+		return Load(bundleDir)
+	}()
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		return nil, err
+	}
+
+	b.tempDir = tmpDir
+	return b, nil
+}
+
+// Close removes files extracted by LoadArchive. It is a no-op for bundles
+// loaded from a directory.
+func (b *Bundle) Close() error {
+	if b.tempDir == "" {
+		return nil
+	}
+	err := os.RemoveAll(b.tempDir)
+	b.tempDir = ""
+	return err
+}
+
+// findBundleRoot locates manifest.json at the extraction root or inside a
+// single top-level directory.
+func findBundleRoot(dir string) (string, error) {
+	if _, err := os.Stat(filepath.Join(dir, "manifest.json")); err == nil {
+		return dir, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read extracted archive: %w", err)
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		nested := filepath.Join(dir, entries[0].Name())
+		if _, err := os.Stat(filepath.Join(nested, "manifest.json")); err == nil {
+			return nested, nil
+		}
+	}
+
+	return "", fmt.Errorf("archive does not contain manifest.json")
+}
+
+// archiveTarget resolves an archive entry name inside destDir, rejecting
+// absolute paths and any entry that would escape destDir.
+func archiveTarget(destDir, name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		return "", fmt.Errorf("archive entry %q escapes bundle directory", name)
+	}
+	return filepath.Join(destDir, filepath.FromSlash(name)), nil
+}
+
+func writeExtractedFile(target string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", target, err)
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to extract %s: %w", target, err)
+	}
+	return out.Close()
+}
+
+func extractTarGz(archivePath, destDir string) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("failed to read gzip archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar archive: %w", err)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			target, err := archiveTarget(destDir, strings.TrimSuffix(hdr.Name, "/"))
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return fmt.Errorf("failed to create directory: %w", err)
+			}
+		case tar.TypeReg:
+			target, err := archiveTarget(destDir, hdr.Name)
+			if err != nil {
+				return err
+			}
+			if err := writeExtractedFile(target, tr); err != nil {
+				return err
+			}
+		case tar.TypeXGlobalHeader:
+			continue
+		default:
+			return fmt.Errorf("archive entry %q has unsupported type (only files and directories are allowed)", hdr.Name)
+		}
+	}
+}
+
+func extractZip(archivePath, destDir string) error {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open zip archive: %w", err)
+	}
+	defer zr.Close()
+
+	for _, file := range zr.File {
+		mode := file.Mode()
+		if mode.IsDir() {
+			target, err := archiveTarget(destDir, strings.TrimSuffix(file.Name, "/"))
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return fmt.Errorf("failed to create directory: %w", err)
+			}
+			continue
+		}
+		if !mode.IsRegular() {
+			return fmt.Errorf("archive entry %q has unsupported type (only files and directories are allowed)", file.Name)
+		}
+
+		target, err := archiveTarget(destDir, file.Name)
+		if err != nil {
+			return err
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+		err = writeExtractedFile(target, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteArchive packs the bundle directory srcDir into archivePath. The format
+// is chosen from the extension (.tar.gz, .tgz, or .zip) and entries are stored
+// relative to srcDir.
+func WriteArchive(srcDir, archivePath string) error {
+	format := archiveFormat(archivePath)
+	if format == "" {
+		return fmt.Errorf("unsupported archive format: %s (use .tar.gz, .tgz, or .zip)", archivePath)
+	}
+
+	if dir := filepath.Dir(archivePath); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create archive directory: %w", err)
+		}
+	}
+
+	out, err := os.Create(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+
+	if format == "zip" {
+		err = writeZip(srcDir, out)
+	} else {
+		err = writeTarGz(srcDir, out)
+	}
+	if closeErr := out.Close(); err == nil && closeErr != nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(archivePath)
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+// walkBundleFiles calls fn for every regular file under srcDir with its
+// slash-separated path relative to srcDir, in lexical order.
+func walkBundleFiles(srcDir string, fn func(rel, path string, info os.FileInfo) error) error {
+	return filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		return fn(filepath.ToSlash(rel), path, info)
+	})
+}
+
+func writeTarGz(srcDir string, w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	err := walkBundleFiles(srcDir, func(rel, path string, info os.FileInfo) error {
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		return copyInto(tw, path)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeZip(srcDir string, w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	err := walkBundleFiles(srcDir, func(rel, path string, info os.FileInfo) error {
+		hdr, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		hdr.Method = zip.Deflate
+		entry, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		return copyInto(entry, path)
+	})
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func copyInto(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,135 @@
+package bundle
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSampleBundle(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeBundleFile(t, dir, "manifest.json", `{"machine_interface_version": 1, "timestamp": "2025-11-19T12:00:00Z", "with_attachments": true}`)
+	writeBundleFile(t, dir, "containers.txt", "portal\n")
+	writeBundleFile(t, dir, "tasks/portal/login.md", "---\nuuid: 123e4567-e89b-12d3-a456-426614174000\nbase_etag: 2\n---\n\nBody\n")
+	writeBundleFile(t, dir, "attachments/123e4567-e89b-12d3-a456-426614174000/notes.txt", "notes")
+	return dir
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	srcDir := writeSampleBundle(t)
+
+	for _, name := range []string{"bundle.tar.gz", "bundle.tgz", "bundle.zip"} {
+		t.Run(name, func(t *testing.T) {
+			archivePath := filepath.Join(t.TempDir(), name)
+			if err := WriteArchive(srcDir, archivePath); err != nil {
+				t.Fatalf("WriteArchive failed: %v", err)
+			}
+			if !IsArchive(archivePath) {
+				t.Fatalf("Expected %s to be detected as an archive", archivePath)
+			}
+
+			b, err := LoadFrom(archivePath)
+			if err != nil {
+				t.Fatalf("LoadFrom failed: %v", err)
+			}
+			extracted := b.Dir
+
+			if len(b.Tasks) != 1 || b.Tasks[0].Path != "portal/login" || b.Tasks[0].BaseEtag != 2 {
+				t.Fatalf("Unexpected tasks: %+v", b.Tasks)
+			}
+			if len(b.Containers) != 1 || b.Containers[0] != "portal" {
+				t.Fatalf("Unexpected containers: %v", b.Containers)
+			}
+			data, err := os.ReadFile(filepath.Join(extracted, "attachments", "123e4567-e89b-12d3-a456-426614174000", "notes.txt"))
+			if err != nil || string(data) != "notes" {
+				t.Fatalf("Expected extracted attachment, got %q (%v)", data, err)
+			}
+
+			if err := b.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+			if _, err := os.Stat(extracted); !os.IsNotExist(err) {
+				t.Errorf("Expected extracted directory to be removed")
+			}
+		})
+	}
+}
+
+func TestLoadArchive_NestedRoot(t *testing.T) {
+	parent := t.TempDir()
+	srcDir := writeSampleBundle(t)
+	if err := os.Rename(srcDir, filepath.Join(parent, ".wrkq")); err != nil {
+		t.Fatalf("Failed to move bundle: %v", err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "bundle.zip")
+	if err := WriteArchive(parent, archivePath); err != nil {
+		t.Fatalf("WriteArchive failed: %v", err)
+	}
+
+	b, err := LoadArchive(archivePath)
+	if err != nil {
+		t.Fatalf("LoadArchive failed: %v", err)
+	}
+	defer b.Close()
+
+	if filepath.Base(b.Dir) != ".wrkq" || len(b.Tasks) != 1 {
+		t.Fatalf("Expected bundle loaded from nested .wrkq/, got dir=%s tasks=%d", b.Dir, len(b.Tasks))
+	}
+}
+
+func TestLoadArchive_RejectsPathTraversal(t *testing.T) {
+	dir := t.TempDir()
+
+	tarPath := filepath.Join(dir, "evil.tar.gz")
+	f, err := os.Create(tarPath)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, name := range []string{"manifest.json", "../escape.txt"} {
+		body := `{"machine_interface_version": 1}`
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("Failed to write header: %v", err)
+		}
+		if _, err := tw.Write([]byte(body)); err != nil {
+			t.Fatalf("Failed to write entry: %v", err)
+		}
+	}
+	tw.Close()
+	gz.Close()
+	f.Close()
+
+	zipPath := filepath.Join(dir, "evil.zip")
+	zf, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	zw := zip.NewWriter(zf)
+	if _, err := zw.Create("/tmp/absolute.txt"); err != nil {
+		t.Fatalf("Failed to write entry: %v", err)
+	}
+	zw.Close()
+	zf.Close()
+
+	for _, path := range []string{tarPath, zipPath} {
+		if _, err := LoadArchive(path); err == nil || !strings.Contains(err.Error(), "escapes bundle directory") {
+			t.Errorf("Expected path traversal error for %s, got %v", filepath.Base(path), err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(os.TempDir(), "escape.txt")); err == nil {
+		t.Errorf("Expected traversal entry not to be written outside the bundle")
+	}
+}
+
+func TestWriteArchive_UnsupportedFormat(t *testing.T) {
+	if err := WriteArchive(t.TempDir(), filepath.Join(t.TempDir(), "bundle.rar")); err == nil {
+		t.Fatal("Expected error for unsupported archive format")
+	}
+}
@@ -0,0 +1,220 @@
+package bundle
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lherron/wrkq/internal/attach"
+)
+
+// Bundle attachments are stored content-addressed: each distinct file is
+// written once to attachments/blobs/<sha256> and attachments/index.json maps
+// every task UUID + filename to its blob. Older bundles used a per-task
+// attachments/<task_uuid>/<filename> layout, which is still readable.
+const (
+	attachmentIndexFile = "index.json"
+	attachmentBlobsDir  = "blobs"
+)
+
+// AttachmentEntry maps one task attachment to its file inside the bundle.
+type AttachmentEntry struct {
+	TaskUUID string `json:"task_uuid"`
+	Filename string `json:"filename"`
+	Checksum string `json:"checksum,omitempty"`
+	Size     int64  `json:"size"`
+	MimeType string `json:"mime_type,omitempty"`
+	// Path is the file's location on disk within the bundle (not serialized)
+	Path string `json:"-"`
+}
+
+// ListAttachments returns the attachments stored in a bundle directory,
+// reading attachments/index.json when present and falling back to the legacy
+// per-task layout otherwise. It returns nil when the bundle has no attachments.
+func ListAttachments(bundleDir string) ([]AttachmentEntry, error) {
+	attachmentsDir := filepath.Join(bundleDir, "attachments")
+	if _, err := os.Stat(attachmentsDir); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(attachmentsDir, attachmentIndexFile))
+	if os.IsNotExist(err) {
+		return listLegacyAttachments(attachmentsDir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment index: %w", err)
+	}
+
+	var entries []AttachmentEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse attachment index: %w", err)
+	}
+	for i := range entries {
+		entry := &entries[i]
+		if !isPlainFilename(entry.TaskUUID) || !isPlainFilename(entry.Filename) {
+			return nil, fmt.Errorf("attachment index entry %s/%s is not a plain task UUID and filename", entry.TaskUUID, entry.Filename)
+		}
+		if !isSHA256Hex(entry.Checksum) {
+			return nil, fmt.Errorf("attachment index entry %s/%s has invalid checksum %q", entry.TaskUUID, entry.Filename, entry.Checksum)
+		}
+		entry.Path = filepath.Join(attachmentsDir, attachmentBlobsDir, entry.Checksum)
+	}
+
+	sortAttachments(entries)
+	return entries, nil
+}
+
+// listLegacyAttachments reads the attachments/<task_uuid>/<filename> layout.
+func listLegacyAttachments(attachmentsDir string) ([]AttachmentEntry, error) {
+	dirs, err := os.ReadDir(attachmentsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachments: %w", err)
+	}
+
+	var entries []AttachmentEntry
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+
+		taskAttachDir := filepath.Join(attachmentsDir, dir.Name())
+		files, err := os.ReadDir(taskAttachDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", taskAttachDir, err)
+		}
+		for _, file := range files {
+			if file.IsDir() {
+				continue
+			}
+			entries = append(entries, AttachmentEntry{
+				TaskUUID: dir.Name(),
+				Filename: file.Name(),
+				Path:     filepath.Join(taskAttachDir, file.Name()),
+			})
+		}
+	}
+
+	sortAttachments(entries)
+	return entries, nil
+}
+
+// exportAttachments writes attachments for the bundled tasks into the
+// content-addressed store, copying each distinct blob once. It returns the
+// number of attachments indexed.
+func exportAttachments(db *sql.DB, bundleDir, attachDir string, tasks []*TaskExport) (int, error) {
+	if attachDir == "" {
+		return 0, fmt.Errorf("attachment directory not configured")
+	}
+
+	attachmentsDir := filepath.Join(bundleDir, "attachments")
+	blobsDir := filepath.Join(attachmentsDir, attachmentBlobsDir)
+
+	var entries []AttachmentEntry
+	for _, task := range tasks {
+		taskEntries, relPaths, err := queryTaskAttachments(db, task.UUID)
+		if err != nil {
+			return 0, err
+		}
+
+		for i, entry := range taskEntries {
+			srcPath := attach.AbsolutePath(attachDir, relPaths[i])
+			checksum, err := attach.Checksum(srcPath)
+			if err != nil {
+				return 0, fmt.Errorf("failed to read attachment %s: %w", entry.Filename, err)
+			}
+			size, err := attach.GetFileSize(srcPath)
+			if err != nil {
+				return 0, fmt.Errorf("failed to read attachment %s: %w", entry.Filename, err)
+			}
+
+			blobPath := filepath.Join(blobsDir, checksum)
+			if _, err := os.Stat(blobPath); os.IsNotExist(err) {
+				if err := os.MkdirAll(blobsDir, 0755); err != nil {
+					return 0, fmt.Errorf("failed to create attachment directory: %w", err)
+				}
+				if err := copyFile(srcPath, blobPath); err != nil {
+					return 0, fmt.Errorf("failed to copy attachment %s: %w", entry.Filename, err)
+				}
+			}
+
+			entry.Checksum = checksum
+			entry.Size = size
+			entries = append(entries, entry)
+		}
+	}
+
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	sortAttachments(entries)
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode attachment index: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(attachmentsDir, attachmentIndexFile), append(data, '\n'), 0644); err != nil {
+		return 0, fmt.Errorf("failed to write attachment index: %w", err)
+	}
+
+	return len(entries), nil
+}
+
+// queryTaskAttachments loads a task's attachment rows along with their
+// relative paths under attach_dir.
+func queryTaskAttachments(db *sql.DB, taskUUID string) ([]AttachmentEntry, []string, error) {
+	rows, err := db.Query(`
+		SELECT filename, relative_path, mime_type FROM attachments
+		WHERE task_uuid = ?
+		ORDER BY filename
+	`, taskUUID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query attachments for task %s: %w", taskUUID, err)
+	}
+	defer rows.Close()
+
+	var entries []AttachmentEntry
+	var relPaths []string
+	for rows.Next() {
+		var entry AttachmentEntry
+		var relPath string
+		var mimeType sql.NullString
+		if err := rows.Scan(&entry.Filename, &relPath, &mimeType); err != nil {
+			return nil, nil, err
+		}
+		entry.TaskUUID = taskUUID
+		entry.MimeType = mimeType.String
+		entries = append(entries, entry)
+		relPaths = append(relPaths, relPath)
+	}
+
+	return entries, relPaths, rows.Err()
+}
+
+func sortAttachments(entries []AttachmentEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].TaskUUID != entries[j].TaskUUID {
+			return entries[i].TaskUUID < entries[j].TaskUUID
+		}
+		return entries[i].Filename < entries[j].Filename
+	})
+}
+
+// isPlainFilename reports whether name is a single path element, so index
+// entries cannot point outside the bundle.
+func isPlainFilename(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+func isSHA256Hex(s string) bool {
+	if len(s) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil && strings.ToLower(s) == s
+}
@@ -0,0 +1,149 @@
+package bundle
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lherron/wrkq/internal/db"
+)
+
+func TestCreateDeduplicatesAttachmentBlobs(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open db: %v", err)
+	}
+	defer database.Close()
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate db: %v", err)
+	}
+
+	actorUUID := "00000000-0000-0000-0000-000000000001"
+	projectUUID := "00000000-0000-0000-0000-000000000010"
+	firstUUID := "00000000-0000-0000-0000-000000000011"
+	secondUUID := "00000000-0000-0000-0000-000000000012"
+	attachDir := t.TempDir()
+
+	seed := []string{
+		`INSERT INTO actors (uuid, id, slug, role) VALUES ('` + actorUUID + `', 'A-00001', 'tester', 'human')`,
+		`INSERT INTO containers (uuid, id, slug, title, created_by_actor_uuid, updated_by_actor_uuid)
+			VALUES ('` + projectUUID + `', 'P-00010', 'portal', 'Portal', '` + actorUUID + `', '` + actorUUID + `')`,
+	}
+	for _, taskUUID := range []string{firstUUID, secondUUID} {
+		slug := "task-" + taskUUID[len(taskUUID)-2:]
+		relPath := filepath.Join("tasks", taskUUID, "logo.png")
+		seed = append(seed,
+			`INSERT INTO tasks (uuid, slug, title, project_uuid, state, created_by_actor_uuid, updated_by_actor_uuid)
+				VALUES ('`+taskUUID+`', '`+slug+`', 'Task', '`+projectUUID+`', 'open', '`+actorUUID+`', '`+actorUUID+`')`,
+			`INSERT INTO event_log (actor_uuid, resource_type, resource_uuid, event_type, etag)
+				VALUES ('`+actorUUID+`', 'task', '`+taskUUID+`', 'task.created', 1)`,
+			`INSERT INTO attachments (task_uuid, filename, relative_path, mime_type, size_bytes, created_by_actor_uuid)
+				VALUES ('`+taskUUID+`', 'logo.png', '`+relPath+`', 'image/png', 9, '`+actorUUID+`')`,
+		)
+		writeBundleFile(t, attachDir, relPath, "same-blob")
+	}
+	for _, stmt := range seed {
+		if _, err := database.Exec(stmt); err != nil {
+			t.Fatalf("Failed to seed: %v", err)
+		}
+	}
+
+	outDir := filepath.Join(t.TempDir(), "bundle")
+	if _, err := Create(database.DB, CreateOptions{
+		OutputDir:       outDir,
+		ProjectPath:     "portal",
+		ProjectUUID:     projectUUID,
+		WithAttachments: true,
+		AttachDir:       attachDir,
+	}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	blobs, err := os.ReadDir(filepath.Join(outDir, "attachments", "blobs"))
+	if err != nil {
+		t.Fatalf("Expected blobs directory: %v", err)
+	}
+	if len(blobs) != 1 {
+		t.Fatalf("Expected identical attachments to share one blob, got %d", len(blobs))
+	}
+
+	var index []AttachmentEntry
+	data, err := os.ReadFile(filepath.Join(outDir, "attachments", "index.json"))
+	if err != nil {
+		t.Fatalf("Expected attachment index: %v", err)
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatalf("Failed to parse attachment index: %v", err)
+	}
+	if len(index) != 2 || index[0].Checksum != blobs[0].Name() || index[1].Checksum != blobs[0].Name() {
+		t.Fatalf("Expected both tasks to reference blob %s, got %+v", blobs[0].Name(), index)
+	}
+
+	entries, err := ListAttachments(outDir)
+	if err != nil {
+		t.Fatalf("ListAttachments failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 attachments, got %+v", entries)
+	}
+	for i, taskUUID := range []string{firstUUID, secondUUID} {
+		entry := entries[i]
+		if entry.TaskUUID != taskUUID || entry.Filename != "logo.png" || entry.MimeType != "image/png" || entry.Size != 9 {
+			t.Errorf("Unexpected entry %d: %+v", i, entry)
+		}
+		if content, err := os.ReadFile(entry.Path); err != nil || string(content) != "same-blob" {
+			t.Errorf("Expected blob content for %s, got %q (%v)", taskUUID, content, err)
+		}
+	}
+
+	report, err := Verify(outDir)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !report.OK || report.Attachments != 2 {
+		t.Errorf("Expected consistent bundle with 2 attachments, got %+v", report)
+	}
+}
+
+func TestListAttachments_LegacyLayout(t *testing.T) {
+	dir := t.TempDir()
+	writeBundleFile(t, dir, "attachments/task-b/notes.txt", "b")
+	writeBundleFile(t, dir, "attachments/task-a/notes.txt", "a")
+	writeBundleFile(t, dir, "attachments/task-a/diagram.svg", "svg")
+
+	entries, err := ListAttachments(dir)
+	if err != nil {
+		t.Fatalf("ListAttachments failed: %v", err)
+	}
+
+	want := []string{"task-a/diagram.svg", "task-a/notes.txt", "task-b/notes.txt"}
+	if len(entries) != len(want) {
+		t.Fatalf("Expected %d entries, got %+v", len(want), entries)
+	}
+	for i, entry := range entries {
+		if got := entry.TaskUUID + "/" + entry.Filename; got != want[i] {
+			t.Errorf("Entry %d: expected %s, got %s", i, want[i], got)
+		}
+		if entry.Path != filepath.Join(dir, "attachments", entry.TaskUUID, entry.Filename) {
+			t.Errorf("Unexpected legacy path: %s", entry.Path)
+		}
+	}
+}
+
+func TestListAttachments_RejectsUnsafeIndex(t *testing.T) {
+	dir := t.TempDir()
+	writeBundleFile(t, dir, "attachments/index.json", `[{"task_uuid": "task-a", "filename": "../escape", "checksum": "`+
+		"0000000000000000000000000000000000000000000000000000000000000000"+`", "size": 1}]`)
+
+	if _, err := ListAttachments(dir); err == nil {
+		t.Fatal("Expected error for unsafe attachment index entry")
+	}
+}
+
+func TestListAttachments_None(t *testing.T) {
+	entries, err := ListAttachments(t.TempDir())
+	if err != nil || entries != nil {
+		t.Fatalf("Expected no attachments, got %+v (%v)", entries, err)
+	}
+}
@@ -10,8 +10,27 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
+// MachineInterfaceVersion is the bundle manifest version written by Create.
+// Apply and verify accept anything from MinMachineInterfaceVersion up to it;
+// see compat.go for how older bundles are upgraded.
+const MachineInterfaceVersion = 1
+
+// MinMachineInterfaceVersion is the oldest manifest version apply still
+// accepts. Every version between it and MachineInterfaceVersion needs an
+// entry in bundleUpgrades.
+const MinMachineInterfaceVersion = 1
+
+// CheckMachineInterfaceVersion returns an error unless the manifest version
+// is within [MinMachineInterfaceVersion, MachineInterfaceVersion]. Every
+// apply path uses it so the rejection message is the same everywhere.
+func (m *Manifest) CheckMachineInterfaceVersion() error {
+	return checkInterfaceVersion(m.MachineInterfaceVersion, MinMachineInterfaceVersion, MachineInterfaceVersion)
+}
+
 // Manifest represents the bundle manifest.json structure
 type Manifest struct {
 	MachineInterfaceVersion int      `json:"machine_interface_version"`
@@ -30,6 +49,7 @@ type Manifest struct {
 	WithEvents              bool     `json:"with_events"`
 	IncludeRefs             bool     `json:"include_refs,omitempty"`
 	RefCount                int      `json:"ref_count,omitempty"`
+	TimeEntryCount          int      `json:"time_entry_count,omitempty"`
 }
 
 // TaskDocument represents a task document from the bundle with metadata
@@ -41,6 +61,15 @@ type TaskDocument struct {
 	OriginalContent string // The full original document including frontmatter
 }
 
+// RefEntry records the identity of an exported task or container at bundle
+// creation time, so apply-side tooling can detect ID or path drift.
+type RefEntry struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+	Path string `json:"path"`
+	ETag int64  `json:"etag"`
+}
+
 // Bundle represents a complete bundle with all its components
 type Bundle struct {
 	Dir        string
@@ -48,6 +77,13 @@ type Bundle struct {
 	Containers []string
 	Tasks      []*TaskDocument
 	Refs       []*TaskDocument
+	// RefIndex maps exported task and container UUIDs to their refs.json entry
+	RefIndex map[string]RefEntry
+	// TimeEntries is the time logged against the bundled tasks
+	TimeEntries []TimeEntry
+
+	// tempDir is the extraction directory removed by Close for archive bundles
+	tempDir string
 }
 
 // LoadManifest reads and validates the bundle manifest
@@ -202,52 +238,80 @@ func LoadRefs(bundleDir string) ([]*TaskDocument, error) {
 	return refs, nil
 }
 
+// LoadRefIndex reads the refs.json index of exported tasks and containers
+func LoadRefIndex(bundleDir string) (map[string]RefEntry, error) {
+	refsPath := filepath.Join(bundleDir, "refs.json")
+
+	// refs.json is optional
+	data, err := os.ReadFile(refsPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read refs.json: %w", err)
+	}
+
+	var index map[string]RefEntry
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse refs.json: %w", err)
+	}
+
+	return index, nil
+}
+
+// taskFrontmatter holds the frontmatter keys the bundle loader needs. Other
+// keys (title, labels, meta, ...) are left to apply-side parsing.
+type taskFrontmatter struct {
+	UUID     string `yaml:"uuid"`
+	Path     string `yaml:"path"`
+	BaseEtag int    `yaml:"base_etag"`
+}
+
 // ParseTaskDocument parses a task document and extracts metadata from frontmatter
 func ParseTaskDocument(content string) (*TaskDocument, error) {
 	task := &TaskDocument{
 		OriginalContent: content,
 	}
 
-	// Split into frontmatter and description
-	parts := strings.SplitN(content, "---", 3)
-	if len(parts) < 3 {
+	frontmatter, body, ok := splitFrontmatter(content)
+	if !ok {
 		// No frontmatter, entire content is description
 		task.Description = content
 		return task, nil
 	}
+	task.Description = strings.TrimSpace(body)
 
-	// parts[0] is empty (before first ---)
-	// parts[1] is the frontmatter
-	// parts[2] is the description
-	frontmatter := parts[1]
-	task.Description = strings.TrimSpace(parts[2])
+	var fm taskFrontmatter
+	if err := yaml.Unmarshal([]byte(frontmatter), &fm); err != nil {
+		return nil, fmt.Errorf("failed to parse frontmatter: %w", err)
+	}
+	task.UUID = fm.UUID
+	task.Path = fm.Path
+	task.BaseEtag = fm.BaseEtag
 
-	// Parse frontmatter for metadata we care about
-	lines := strings.Split(frontmatter, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
+	return task, nil
+}
 
-		// Simple YAML parsing for the fields we need
-		if strings.HasPrefix(line, "uuid:") {
-			task.UUID = strings.TrimSpace(strings.TrimPrefix(line, "uuid:"))
-		} else if strings.HasPrefix(line, "path:") {
-			// path might be in frontmatter or derived from filename
-			if task.Path == "" {
-				task.Path = strings.TrimSpace(strings.TrimPrefix(line, "path:"))
-			}
-		} else if strings.HasPrefix(line, "base_etag:") {
-			var etag int
-			_, err := fmt.Sscanf(line, "base_etag: %d", &etag)
-			if err == nil {
-				task.BaseEtag = etag
-			}
-		}
+// splitFrontmatter splits a document into its YAML frontmatter and body. The
+// frontmatter must open on the first line and close with a line containing
+// only "---"; ok is false when the document has no frontmatter.
+func splitFrontmatter(content string) (frontmatter, body string, ok bool) {
+	normalized := strings.ReplaceAll(content, "\r\n", "\n")
+	if !strings.HasPrefix(normalized, "---\n") {
+		return "", "", false
 	}
+	rest := normalized[len("---\n"):]
 
-	return task, nil
+	if strings.HasPrefix(rest, "---\n") || rest == "---" {
+		return "", strings.TrimPrefix(rest, "---"), true
+	}
+	if idx := strings.Index(rest, "\n---\n"); idx >= 0 {
+		return rest[:idx], rest[idx+len("\n---\n"):], true
+	}
+	if strings.HasSuffix(rest, "\n---") {
+		return strings.TrimSuffix(rest, "\n---"), "", true
+	}
+	return "", "", false
 }
 
 // Load reads an entire bundle from a directory
@@ -272,12 +336,24 @@ func Load(bundleDir string) (*Bundle, error) {
 		return nil, err
 	}
 
+	refIndex, err := LoadRefIndex(bundleDir)
+	if err != nil {
+		return nil, err
+	}
+
+	timeEntries, err := LoadTimeEntries(bundleDir)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Bundle{
-		Dir:        bundleDir,
-		Manifest:   manifest,
-		Containers: containers,
-		Tasks:      tasks,
-		Refs:       refs,
+		Dir:         bundleDir,
+		Manifest:    manifest,
+		Containers:  containers,
+		Tasks:       tasks,
+		Refs:        refs,
+		RefIndex:    refIndex,
+		TimeEntries: timeEntries,
 	}, nil
 }
 
@@ -295,10 +371,12 @@ type CreateOptions struct {
 	ProjectPath string
 	// Path prefix filters (absolute paths)
 	PathPrefixes []string
-	// Include refs/ stubs
+	// Include refs/ stubs and the refs.json index
 	IncludeRefs bool
 	// Include attachments
 	WithAttachments bool
+	// Attachment root to read files from (attach_dir); required for WithAttachments
+	AttachDir string
 	// Include event log
 	WithEvents bool
 	// Output directory
@@ -498,13 +576,28 @@ func Create(db *sql.DB, opts CreateOptions) (*Bundle, error) {
 		}
 	}
 
+	// Write refs.json index if requested
+	var refIndex map[string]RefEntry
+	if opts.IncludeRefs {
+		var err error
+		refIndex, err = exportRefIndex(db, opts.OutputDir, tasks, containers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export refs index: %w", err)
+		}
+	}
+
 	// Copy attachments if requested
 	if opts.WithAttachments {
-		if err := exportAttachments(db, opts.OutputDir, tasks); err != nil {
+		if _, err := exportAttachments(db, opts.OutputDir, opts.AttachDir, tasks); err != nil {
 			return nil, fmt.Errorf("failed to export attachments: %w", err)
 		}
 	}
 
+	timeEntries, err := exportTimeEntries(db, opts.OutputDir, tasks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export time entries: %w", err)
+	}
+
 	// Export event log if requested
 	if opts.WithEvents {
 		if err := exportEvents(db, opts.OutputDir, opts); err != nil {
@@ -514,7 +607,7 @@ func Create(db *sql.DB, opts CreateOptions) (*Bundle, error) {
 
 	// Generate and write manifest
 	manifest := &Manifest{
-		MachineInterfaceVersion: 1,
+		MachineInterfaceVersion: MachineInterfaceVersion,
 		Version:                 opts.Version,
 		Commit:                  opts.Commit,
 		BuildDate:               opts.BuildDate,
@@ -530,6 +623,7 @@ func Create(db *sql.DB, opts CreateOptions) (*Bundle, error) {
 		WithEvents:              opts.WithEvents,
 		IncludeRefs:             opts.IncludeRefs,
 		RefCount:                len(refs),
+		TimeEntryCount:          len(timeEntries),
 	}
 
 	manifestPath := filepath.Join(opts.OutputDir, "manifest.json")
@@ -542,11 +636,13 @@ func Create(db *sql.DB, opts CreateOptions) (*Bundle, error) {
 	}
 
 	return &Bundle{
-		Dir:        opts.OutputDir,
-		Manifest:   manifest,
-		Containers: containers,
-		Tasks:      convertTaskExportsToTaskDocuments(tasks),
-		Refs:       refs,
+		Dir:         opts.OutputDir,
+		Manifest:    manifest,
+		Containers:  containers,
+		Tasks:       convertTaskExportsToTaskDocuments(tasks),
+		Refs:        refs,
+		RefIndex:    refIndex,
+		TimeEntries: timeEntries,
 	}, nil
 }
 
@@ -608,35 +704,33 @@ func exportTask(db *sql.DB, taskUUID string) (string, error) {
 	var id, slug, title, state, description string
 	var priority int
 	var startAt, dueAt, labels, meta, completedAt, archivedAt *string
+	var estimateMinutes *int64
 	var createdAt, updatedAt string
 	var etag int64
-	var projectUUID, createdByUUID, updatedByUUID string
+	var projectUUID, projectID, createdBySlug, updatedBySlug string
 
+	// The project ID and actor slugs are joined in rather than looked up
+	// separately, which would cost three more queries per exported task
 	err := db.QueryRow(`
-		SELECT id, slug, title, project_uuid, state, priority,
-		       start_at, due_at, labels, meta, description, etag,
-		       created_at, updated_at, completed_at, archived_at,
-		       created_by_actor_uuid, updated_by_actor_uuid
-		FROM tasks WHERE uuid = ?
+		SELECT t.id, t.slug, t.title, t.project_uuid, t.state, t.priority,
+		       t.start_at, t.due_at, t.estimate_minutes, t.labels, t.meta, t.description, t.etag,
+		       t.created_at, t.updated_at, t.completed_at, t.archived_at,
+		       COALESCE(c.id, ''), COALESCE(cb.slug, ''), COALESCE(ub.slug, '')
+		FROM tasks t
+		LEFT JOIN containers c ON c.uuid = t.project_uuid
+		LEFT JOIN actors cb ON cb.uuid = t.created_by_actor_uuid
+		LEFT JOIN actors ub ON ub.uuid = t.updated_by_actor_uuid
+		WHERE t.uuid = ?
 	`, taskUUID).Scan(
 		&id, &slug, &title, &projectUUID, &state, &priority,
-		&startAt, &dueAt, &labels, &meta, &description, &etag,
+		&startAt, &dueAt, &estimateMinutes, &labels, &meta, &description, &etag,
 		&createdAt, &updatedAt, &completedAt, &archivedAt,
-		&createdByUUID, &updatedByUUID,
+		&projectID, &createdBySlug, &updatedBySlug,
 	)
 	if err != nil {
 		return "", fmt.Errorf("failed to get task: %w", err)
 	}
 
-	// Get actor slugs
-	var createdBySlug, updatedBySlug string
-	db.QueryRow("SELECT slug FROM actors WHERE uuid = ?", createdByUUID).Scan(&createdBySlug)
-	db.QueryRow("SELECT slug FROM actors WHERE uuid = ?", updatedByUUID).Scan(&updatedBySlug)
-
-	// Get project info
-	var projectID string
-	db.QueryRow("SELECT id FROM containers WHERE uuid = ?", projectUUID).Scan(&projectID)
-
 	// Build frontmatter
 	var sb strings.Builder
 	sb.WriteString("---\n")
@@ -655,6 +749,9 @@ func exportTask(db *sql.DB, taskUUID string) (string, error) {
 	if dueAt != nil {
 		sb.WriteString(fmt.Sprintf("due_at: %s\n", *dueAt))
 	}
+	if estimateMinutes != nil {
+		sb.WriteString(fmt.Sprintf("estimate_minutes: %d\n", *estimateMinutes))
+	}
 	if labels != nil && *labels != "" {
 		sb.WriteString(fmt.Sprintf("labels: %s\n", *labels))
 	}
@@ -680,92 +777,20 @@ func exportTask(db *sql.DB, taskUUID string) (string, error) {
 
 // addBundleFieldsToFrontmatter adds path and base_etag to the frontmatter
 func addBundleFieldsToFrontmatter(content string, path string, baseEtag int) string {
-	// Find the end of frontmatter
-	parts := strings.SplitN(content, "---", 3)
-	if len(parts) < 3 {
+	frontmatter, body, ok := splitFrontmatter(content)
+	if !ok {
 		return content
 	}
+	frontmatter = strings.TrimSpace(frontmatter)
 
-	// parts[0] is empty (before first ---)
-	// parts[1] is the frontmatter content (with leading/trailing newlines)
-	// parts[2] is "\n\nbody" (starts with closing --- separator)
-	frontmatter := strings.TrimSpace(parts[1])
-
-	// Remove the leading "\n\n" from body (one newline from --- separator, one blank line)
-	body := parts[2]
-	if strings.HasPrefix(body, "\n\n") {
-		body = body[2:]
-	}
+	// Drop the blank line that separates the closing --- from the body
+	body = strings.TrimPrefix(body, "\n")
 
 	// Reconstruct with added fields
 	// Format: ---\nfrontmatter\nbase_etag\npath\n---\n\nbody
 	return fmt.Sprintf("---\n%s\nbase_etag: %d\npath: %s\n---\n\n%s", frontmatter, baseEtag, path, body)
 }
 
-// exportAttachments copies attachments for all tasks in the bundle
-func exportAttachments(db *sql.DB, bundleDir string, tasks []*TaskExport) error {
-	attachmentsDir := filepath.Join(bundleDir, "attachments")
-
-	// Query to find attachment directory
-	var attachDir string
-	err := db.QueryRow("SELECT value FROM config WHERE key = 'attach_dir'").Scan(&attachDir)
-	if err != nil {
-		// No attachments configured
-		return nil
-	}
-
-	for _, task := range tasks {
-		// Check if task has attachments
-		var hasAttachments bool
-		err := db.QueryRow(`
-			SELECT EXISTS(SELECT 1 FROM attachments WHERE task_uuid = ? AND deleted_at IS NULL)
-		`, task.UUID).Scan(&hasAttachments)
-		if err != nil {
-			return fmt.Errorf("failed to check attachments for task %s: %w", task.UUID, err)
-		}
-
-		if !hasAttachments {
-			continue
-		}
-
-		// Create attachments/<task_uuid>/ directory
-		taskAttachDir := filepath.Join(attachmentsDir, task.UUID)
-		if err := os.MkdirAll(taskAttachDir, 0755); err != nil {
-			return fmt.Errorf("failed to create attachment directory: %w", err)
-		}
-
-		// Copy attachment files
-		rows, err := db.Query(`
-			SELECT filename FROM attachments
-			WHERE task_uuid = ? AND deleted_at IS NULL
-		`, task.UUID)
-		if err != nil {
-			return fmt.Errorf("failed to query attachments: %w", err)
-		}
-		defer rows.Close()
-
-		for rows.Next() {
-			var filename string
-			if err := rows.Scan(&filename); err != nil {
-				return err
-			}
-
-			srcPath := filepath.Join(attachDir, "tasks", task.UUID, filename)
-			dstPath := filepath.Join(taskAttachDir, filename)
-
-			if err := copyFile(srcPath, dstPath); err != nil {
-				return fmt.Errorf("failed to copy attachment %s: %w", filename, err)
-			}
-		}
-
-		if err := rows.Err(); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
 // exportEvents exports the event log as NDJSON
 func exportEvents(db *sql.DB, bundleDir string, opts CreateOptions) error {
 	rawSince := opts.SinceCursor
@@ -1058,6 +1083,47 @@ func exportRefs(db *sql.DB, bundleDir string, tasks []*TaskExport) ([]*TaskDocum
 	return refs, nil
 }
 
+// exportRefIndex writes refs.json mapping each exported task and container
+// UUID to its friendly ID, path, and current etag.
+func exportRefIndex(db *sql.DB, bundleDir string, tasks []*TaskExport, containers []string) (map[string]RefEntry, error) {
+	index := make(map[string]RefEntry, len(tasks)+len(containers))
+
+	for _, task := range tasks {
+		var id sql.NullString
+		var etag int64
+		if err := db.QueryRow("SELECT id, etag FROM tasks WHERE uuid = ?", task.UUID).Scan(&id, &etag); err != nil {
+			return nil, fmt.Errorf("failed to load task ref %s: %w", task.UUID, err)
+		}
+		index[task.UUID] = RefEntry{Kind: "task", ID: id.String, Path: task.Path, ETag: etag}
+	}
+
+	for _, path := range containers {
+		var uuid string
+		var id sql.NullString
+		var etag int64
+		err := db.QueryRow(`
+			SELECT c.uuid, c.id, c.etag
+			FROM containers c
+			JOIN v_container_paths cp ON cp.uuid = c.uuid
+			WHERE cp.path = ?
+		`, path).Scan(&uuid, &id, &etag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load container ref %s: %w", path, err)
+		}
+		index[uuid] = RefEntry{Kind: "container", ID: id.String, Path: path, ETag: etag}
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal refs index: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(bundleDir, "refs.json"), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write refs.json: %w", err)
+	}
+
+	return index, nil
+}
+
 func exportRefStub(db *sql.DB, taskUUID string) (string, string, error) {
 	var (
 		id            string
@@ -1072,17 +1138,16 @@ func exportRefStub(db *sql.DB, taskUUID string) (string, string, error) {
 
 	err := db.QueryRow(`
 		SELECT t.id, t.slug, t.title, t.state, t.priority, t.project_uuid,
-		       cp.path
+		       cp.path, COALESCE(c.id, '')
 		FROM tasks t
 		LEFT JOIN v_container_paths cp ON cp.uuid = t.project_uuid
+		LEFT JOIN containers c ON c.uuid = t.project_uuid
 		WHERE t.uuid = ?
-	`, taskUUID).Scan(&id, &slug, &title, &state, &priority, &projectUUID, &containerPath)
+	`, taskUUID).Scan(&id, &slug, &title, &state, &priority, &projectUUID, &containerPath, &projectID)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to load ref task %s: %w", taskUUID, err)
 	}
 
-	_ = db.QueryRow("SELECT id FROM containers WHERE uuid = ?", projectUUID).Scan(&projectID)
-
 	path := slug
 	if containerPath != "" {
 		path = containerPath + "/" + slug
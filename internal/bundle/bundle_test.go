@@ -1,9 +1,14 @@
 package bundle
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/lherron/wrkq/internal/db"
 )
 
 func TestLoadManifest(t *testing.T) {
@@ -260,3 +265,309 @@ func TestLoad(t *testing.T) {
 		t.Errorf("Expected 1 task, got %d", len(bundle.Tasks))
 	}
 }
+
+func TestCreateIncludeRefsRoundTrip(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open db: %v", err)
+	}
+	defer database.Close()
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate db: %v", err)
+	}
+
+	actorUUID := "00000000-0000-0000-0000-000000000001"
+	projectUUID := "00000000-0000-0000-0000-000000000010"
+	taskUUID := "00000000-0000-0000-0000-000000000011"
+	seed := []string{
+		`INSERT INTO actors (uuid, id, slug, role) VALUES ('` + actorUUID + `', 'A-00001', 'tester', 'human')`,
+		`INSERT INTO containers (uuid, id, slug, title, etag, created_by_actor_uuid, updated_by_actor_uuid)
+			VALUES ('` + projectUUID + `', 'P-00010', 'portal', 'Portal', 3, '` + actorUUID + `', '` + actorUUID + `')`,
+		`INSERT INTO tasks (uuid, id, slug, title, project_uuid, state, etag, created_by_actor_uuid, updated_by_actor_uuid)
+			VALUES ('` + taskUUID + `', 'T-00011', 'login', 'Login', '` + projectUUID + `', 'open', 5, '` + actorUUID + `', '` + actorUUID + `')`,
+		`INSERT INTO event_log (actor_uuid, resource_type, resource_uuid, event_type, etag)
+			VALUES ('` + actorUUID + `', 'task', '` + taskUUID + `', 'task.created', 1)`,
+	}
+	for _, stmt := range seed {
+		if _, err := database.Exec(stmt); err != nil {
+			t.Fatalf("Failed to seed: %v", err)
+		}
+	}
+
+	outDir := filepath.Join(t.TempDir(), "bundle")
+	created, err := Create(database.DB, CreateOptions{OutputDir: outDir, IncludeRefs: true})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	want := map[string]RefEntry{
+		taskUUID:    {Kind: "task", ID: "T-00011", Path: "portal/login", ETag: 5},
+		projectUUID: {Kind: "container", ID: "P-00010", Path: "portal", ETag: 3},
+	}
+	if len(created.RefIndex) != len(want) {
+		t.Fatalf("Expected %d refs from Create, got %+v", len(want), created.RefIndex)
+	}
+
+	loaded, err := Load(outDir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(loaded.RefIndex) != len(want) {
+		t.Fatalf("Expected %d refs from Load, got %+v", len(want), loaded.RefIndex)
+	}
+	for uuid, entry := range want {
+		if got := loaded.RefIndex[uuid]; got != entry {
+			t.Errorf("Ref %s: expected %+v, got %+v", uuid, entry, got)
+		}
+		if got := created.RefIndex[uuid]; got != entry {
+			t.Errorf("Created ref %s: expected %+v, got %+v", uuid, entry, got)
+		}
+	}
+}
+
+func TestCreateCarriesTimeTracking(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open db: %v", err)
+	}
+	defer database.Close()
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate db: %v", err)
+	}
+
+	actorUUID := "00000000-0000-0000-0000-000000000001"
+	projectUUID := "00000000-0000-0000-0000-000000000010"
+	taskUUID := "00000000-0000-0000-0000-000000000011"
+	seed := []string{
+		`INSERT INTO actors (uuid, id, slug, role) VALUES ('` + actorUUID + `', 'A-00001', 'tester', 'human')`,
+		`INSERT INTO containers (uuid, id, slug, title, created_by_actor_uuid, updated_by_actor_uuid)
+			VALUES ('` + projectUUID + `', 'P-00010', 'portal', 'Portal', '` + actorUUID + `', '` + actorUUID + `')`,
+		`INSERT INTO tasks (uuid, id, slug, title, project_uuid, state, estimate_minutes, created_by_actor_uuid, updated_by_actor_uuid)
+			VALUES ('` + taskUUID + `', 'T-00011', 'login', 'Login', '` + projectUUID + `', 'open', 90, '` + actorUUID + `', '` + actorUUID + `')`,
+		`INSERT INTO event_log (actor_uuid, resource_type, resource_uuid, event_type, etag)
+			VALUES ('` + actorUUID + `', 'task', '` + taskUUID + `', 'task.created', 1)`,
+		`INSERT INTO time_entries (uuid, task_uuid, actor_uuid, minutes, note, started_at)
+			VALUES ('00000000-0000-0000-0000-000000000020', '` + taskUUID + `', '` + actorUUID + `', 30, 'spike', '2026-03-01T09:00:00Z')`,
+		`INSERT INTO time_entries (uuid, task_uuid, actor_uuid, minutes)
+			VALUES ('00000000-0000-0000-0000-000000000021', '` + taskUUID + `', '` + actorUUID + `', 15)`,
+	}
+	for _, stmt := range seed {
+		if _, err := database.Exec(stmt); err != nil {
+			t.Fatalf("Failed to seed: %v", err)
+		}
+	}
+
+	outDir := filepath.Join(t.TempDir(), "bundle")
+	created, err := Create(database.DB, CreateOptions{OutputDir: outDir})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if created.Manifest.TimeEntryCount != 2 {
+		t.Errorf("Expected time_entry_count 2, got %d", created.Manifest.TimeEntryCount)
+	}
+	if len(created.Tasks) != 1 || !strings.Contains(created.Tasks[0].OriginalContent, "\nestimate_minutes: 90\n") {
+		t.Fatalf("Expected estimate_minutes in the task frontmatter, got %+v", created.Tasks)
+	}
+
+	loaded, err := Load(outDir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(loaded.TimeEntries) != 2 {
+		t.Fatalf("Expected 2 time entries from Load, got %+v", loaded.TimeEntries)
+	}
+	first := loaded.TimeEntries[0]
+	if first.TaskUUID != taskUUID || first.ActorSlug != "tester" || first.Minutes != 30 ||
+		first.Note != "spike" || first.StartedAt != "2026-03-01T09:00:00Z" {
+		t.Errorf("Unexpected time entry: %+v", first)
+	}
+
+	report, err := Verify(outDir)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !report.OK || report.TimeEntries != 2 {
+		t.Errorf("Expected a clean report with 2 time entries, got %+v", report)
+	}
+}
+
+func TestMachineInterfaceVersionStampedAndChecked(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open db: %v", err)
+	}
+	defer database.Close()
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate db: %v", err)
+	}
+
+	outDir := filepath.Join(t.TempDir(), "bundle")
+	created, err := Create(database.DB, CreateOptions{OutputDir: outDir})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if created.Manifest.MachineInterfaceVersion != MachineInterfaceVersion {
+		t.Fatalf("Create stamped %d, want %d", created.Manifest.MachineInterfaceVersion, MachineInterfaceVersion)
+	}
+	loaded, err := LoadManifest(outDir)
+	if err != nil {
+		t.Fatalf("LoadManifest failed: %v", err)
+	}
+	if err := loaded.CheckMachineInterfaceVersion(); err != nil {
+		t.Fatalf("freshly created bundle rejected: %v", err)
+	}
+
+	// A bundle from a newer build is refused with the shared message
+	loaded.MachineInterfaceVersion = MachineInterfaceVersion + 1
+	err = loaded.CheckMachineInterfaceVersion()
+	want := fmt.Sprintf("bundle machine_interface_version (%d) doesn't match current version (%d)",
+		MachineInterfaceVersion+1, MachineInterfaceVersion)
+	if err == nil || err.Error() != want {
+		t.Fatalf("expected %q, got %v", want, err)
+	}
+
+	data, err := json.Marshal(loaded)
+	if err != nil {
+		t.Fatalf("Failed to marshal manifest: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(outDir, "manifest.json"), data, 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	report, err := Verify(outDir)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if report.OK || len(report.Issues) != 1 || report.Issues[0].Message != want {
+		t.Fatalf("expected a single version issue, got %+v", report)
+	}
+}
+
+func TestUpgradeBundleAcceptsSupportedRange(t *testing.T) {
+	// Pretend the next interface version renamed a container, so a bundle
+	// written today is one version behind
+	current := MachineInterfaceVersion + 1
+	min := MachineInterfaceVersion
+	upgrades := map[int]bundleUpgrade{
+		MachineInterfaceVersion: func(b *Bundle) error {
+			for i, c := range b.Containers {
+				b.Containers[i] = strings.Replace(c, "legacy", "current", 1)
+			}
+			return nil
+		},
+	}
+
+	old := &Bundle{
+		Manifest:   &Manifest{MachineInterfaceVersion: MachineInterfaceVersion},
+		Containers: []string{"legacy/inbox"},
+	}
+	if err := upgradeBundle(old, min, current, upgrades); err != nil {
+		t.Fatalf("N-1 bundle rejected: %v", err)
+	}
+	if old.Manifest.MachineInterfaceVersion != current || old.Containers[0] != "current/inbox" {
+		t.Fatalf("expected bundle upgraded to v%d, got v%d %v", current, old.Manifest.MachineInterfaceVersion, old.Containers)
+	}
+
+	// Already current: nothing to run
+	same := &Bundle{Manifest: &Manifest{MachineInterfaceVersion: current}, Containers: []string{"legacy/inbox"}}
+	if err := upgradeBundle(same, min, current, upgrades); err != nil || same.Containers[0] != "legacy/inbox" {
+		t.Fatalf("current bundle changed: %v %v", err, same.Containers)
+	}
+
+	for _, version := range []int{min - 1, current + 1} {
+		b := &Bundle{Manifest: &Manifest{MachineInterfaceVersion: version}}
+		err := upgradeBundle(b, min, current, upgrades)
+		want := fmt.Sprintf("bundle machine_interface_version (%d) is outside the supported range (%d-%d)", version, min, current)
+		if err == nil || err.Error() != want {
+			t.Errorf("v%d: expected %q, got %v", version, want, err)
+		}
+	}
+
+	// A range without an upgrade for every step is a programming error
+	gap := &Bundle{Manifest: &Manifest{MachineInterfaceVersion: min}}
+	if err := upgradeBundle(gap, min, current, nil); err == nil {
+		t.Error("expected missing upgrade to fail")
+	}
+
+	// The real registry covers every supported version
+	for v := MinMachineInterfaceVersion; v < MachineInterfaceVersion; v++ {
+		if _, ok := bundleUpgrades[v]; !ok {
+			t.Errorf("no upgrade registered from v%d", v)
+		}
+	}
+}
+
+func TestLoadRefIndex_Missing(t *testing.T) {
+	index, err := LoadRefIndex(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadRefIndex failed: %v", err)
+	}
+	if index != nil {
+		t.Errorf("Expected nil index, got %+v", index)
+	}
+}
+
+func TestParseTaskDocument_TrickyFrontmatter(t *testing.T) {
+	content := `---
+title: "Fix: login --- redirect"
+labels:
+  - backend
+  - "needs: review"
+base_etag: 7
+description: |
+  uuid: not-the-uuid
+  path: not/the/path
+path: "portal/auth/login"
+uuid: '123e4567-e89b-12d3-a456-426614174000'
+---
+
+Body mentions --- separators and uuid: lines.
+`
+
+	task, err := ParseTaskDocument(content)
+	if err != nil {
+		t.Fatalf("ParseTaskDocument failed: %v", err)
+	}
+	if task.UUID != "123e4567-e89b-12d3-a456-426614174000" {
+		t.Errorf("Expected quoted UUID to parse, got %q", task.UUID)
+	}
+	if task.Path != "portal/auth/login" {
+		t.Errorf("Expected quoted path to parse, got %q", task.Path)
+	}
+	if task.BaseEtag != 7 {
+		t.Errorf("Expected base_etag 7, got %d", task.BaseEtag)
+	}
+	if task.Description != "Body mentions --- separators and uuid: lines." {
+		t.Errorf("Unexpected description: %q", task.Description)
+	}
+	if task.OriginalContent != content {
+		t.Errorf("Expected OriginalContent to be preserved")
+	}
+}
+
+func TestParseTaskDocument_InvalidFrontmatter(t *testing.T) {
+	content := "---\nuuid: [unterminated\n---\n\nBody\n"
+
+	if _, err := ParseTaskDocument(content); err == nil {
+		t.Fatal("Expected error for invalid frontmatter")
+	}
+}
+
+func TestAddBundleFieldsToFrontmatter_RoundTrip(t *testing.T) {
+	content := "---\nid: T-00001\nuuid: 123e4567-e89b-12d3-a456-426614174000\ntitle: \"Deploy: step --- two\"\nlabels: [\"a\",\"b\"]\n---\n\nBody text\n"
+
+	withFields := addBundleFieldsToFrontmatter(content, "portal/deploy", 3)
+	if !strings.HasSuffix(withFields, "path: portal/deploy\n---\n\nBody text\n") {
+		t.Fatalf("Unexpected document layout:\n%s", withFields)
+	}
+
+	task, err := ParseTaskDocument(withFields)
+	if err != nil {
+		t.Fatalf("ParseTaskDocument failed: %v", err)
+	}
+	if task.BaseEtag != 3 || task.Path != "portal/deploy" || task.UUID != "123e4567-e89b-12d3-a456-426614174000" {
+		t.Errorf("Unexpected parsed fields: uuid=%q path=%q base_etag=%d", task.UUID, task.Path, task.BaseEtag)
+	}
+	if task.Description != "Body text" {
+		t.Errorf("Unexpected description: %q", task.Description)
+	}
+}
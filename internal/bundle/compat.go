@@ -0,0 +1,49 @@
+package bundle
+
+import "fmt"
+
+// bundleUpgrade rewrites a loaded bundle from the version it is keyed under
+// to the next one. It works on the in-memory Bundle only; the files on disk
+// are left as they are.
+type bundleUpgrade func(b *Bundle) error
+
+// bundleUpgrades holds one upgrade per manifest version older than
+// MachineInterfaceVersion, keyed by the version it upgrades from. When the
+// interface version is bumped, add the step from the previous version here
+// and leave MinMachineInterfaceVersion where it is until the old format
+// should stop applying.
+var bundleUpgrades = map[int]bundleUpgrade{}
+
+// Upgrade checks the manifest version and runs the registered upgrades on b
+// until it matches MachineInterfaceVersion, so apply only ever sees the
+// current format. Bundles outside the supported range are rejected.
+func (b *Bundle) Upgrade() error {
+	return upgradeBundle(b, MinMachineInterfaceVersion, MachineInterfaceVersion, bundleUpgrades)
+}
+
+func upgradeBundle(b *Bundle, min, current int, upgrades map[int]bundleUpgrade) error {
+	if err := checkInterfaceVersion(b.Manifest.MachineInterfaceVersion, min, current); err != nil {
+		return err
+	}
+	for v := b.Manifest.MachineInterfaceVersion; v < current; v++ {
+		upgrade, ok := upgrades[v]
+		if !ok {
+			return fmt.Errorf("no upgrade registered for bundle machine_interface_version %d", v)
+		}
+		if err := upgrade(b); err != nil {
+			return fmt.Errorf("failed to upgrade bundle from machine_interface_version %d: %w", v, err)
+		}
+		b.Manifest.MachineInterfaceVersion = v + 1
+	}
+	return nil
+}
+
+func checkInterfaceVersion(version, min, current int) error {
+	if version < min || version > current {
+		if min == current {
+			return fmt.Errorf("bundle machine_interface_version (%d) doesn't match current version (%d)", version, current)
+		}
+		return fmt.Errorf("bundle machine_interface_version (%d) is outside the supported range (%d-%d)", version, min, current)
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package bundle
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MarkdownOptions configures a Markdown tree export.
+type MarkdownOptions struct {
+	// Output directory; created if missing
+	OutputDir string
+	// Restrict the export to this container path and everything below it
+	ProjectPath string
+	// Include archived tasks and archived containers
+	IncludeArchived bool
+	// Include tasks in the deleted state
+	IncludeDeleted bool
+}
+
+// MarkdownResult summarizes a Markdown tree export.
+type MarkdownResult struct {
+	OutputDir  string `json:"output_dir"`
+	Containers int    `json:"containers"`
+	Tasks      int    `json:"tasks"`
+}
+
+type markdownContainer struct {
+	uuid, id, path, title, description string
+	children                           []*markdownContainer
+	tasks                              []markdownTask
+}
+
+type markdownTask struct {
+	uuid, id, slug, title, state string
+	priority                     int
+}
+
+// ExportMarkdown writes the container tree as directories under
+// opts.OutputDir: each task becomes <container path>/<slug>.md in wrkq cat
+// format (frontmatter, then the title as a heading and the description), and
+// each container gets a README.md indexing its subcontainers and tasks. A
+// README.md at the root lists the top-level containers.
+//
+// The tree is meant for reading, not for re-import: unlike a bundle it has
+// no manifest and the task files carry no base_etag.
+func ExportMarkdown(db *sql.DB, opts MarkdownOptions) (*MarkdownResult, error) {
+	if opts.OutputDir == "" {
+		return nil, fmt.Errorf("output directory is required")
+	}
+
+	containers, roots, err := loadMarkdownContainers(db, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MarkdownResult{OutputDir: opts.OutputDir}
+	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	for _, c := range containers {
+		dir := filepath.Join(opts.OutputDir, filepath.FromSlash(c.path))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %w", c.path, err)
+		}
+		for _, task := range c.tasks {
+			content, err := exportTask(db, task.uuid)
+			if err != nil {
+				return nil, err
+			}
+			frontmatter, body, ok := splitFrontmatter(content)
+			if !ok {
+				return nil, fmt.Errorf("task %s exported without frontmatter", task.id)
+			}
+			body = strings.TrimPrefix(body, "\n")
+			doc := fmt.Sprintf("---\n%s\n---\n\n# %s\n\n%s", strings.TrimSpace(frontmatter), task.title, body)
+			if !strings.HasSuffix(doc, "\n") {
+				doc += "\n"
+			}
+			if err := os.WriteFile(filepath.Join(dir, task.slug+".md"), []byte(doc), 0644); err != nil {
+				return nil, fmt.Errorf("failed to write task %s: %w", task.id, err)
+			}
+			result.Tasks++
+		}
+		if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte(containerIndex(c)), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write index for %s: %w", c.path, err)
+		}
+		result.Containers++
+	}
+
+	if err := os.WriteFile(filepath.Join(opts.OutputDir, "README.md"), []byte(rootIndex(roots)), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write index: %w", err)
+	}
+	return result, nil
+}
+
+// loadMarkdownContainers returns the containers to export ordered by path,
+// each with its exported tasks and children, plus the topmost ones.
+func loadMarkdownContainers(db *sql.DB, opts MarkdownOptions) ([]*markdownContainer, []*markdownContainer, error) {
+	query := `
+		SELECT cp.uuid, COALESCE(c.id, ''), cp.path, COALESCE(NULLIF(c.title, ''), c.slug),
+		       COALESCE(c.description, ''), COALESCE(c.parent_uuid, ''), c.archived_at IS NOT NULL
+		FROM v_container_paths cp
+		JOIN containers c ON c.uuid = cp.uuid
+	`
+	var args []interface{}
+	if opts.ProjectPath != "" {
+		query += " WHERE cp.path = ? OR substr(cp.path, 1, length(?) + 1) = ? || '/'"
+		args = append(args, opts.ProjectPath, opts.ProjectPath, opts.ProjectPath)
+	}
+	query += " ORDER BY cp.path"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query containers: %w", err)
+	}
+	defer rows.Close()
+
+	byUUID := make(map[string]*markdownContainer)
+	var containers, roots []*markdownContainer
+	// Rows come parents first, so a container whose parent was left out
+	// (archived, or outside ProjectPath) is either skipped or a root
+	skipped := make(map[string]bool)
+	for rows.Next() {
+		var c markdownContainer
+		var parentUUID string
+		var archived bool
+		if err := rows.Scan(&c.uuid, &c.id, &c.path, &c.title, &c.description, &parentUUID, &archived); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan container: %w", err)
+		}
+		if skipped[parentUUID] || (archived && !opts.IncludeArchived) {
+			skipped[c.uuid] = true
+			continue
+		}
+		container := &c
+		byUUID[c.uuid] = container
+		containers = append(containers, container)
+		if parent, ok := byUUID[parentUUID]; ok {
+			parent.children = append(parent.children, container)
+		} else {
+			roots = append(roots, container)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	rows.Close()
+
+	taskQuery := `
+		SELECT uuid, COALESCE(id, ''), slug, title, state, priority, project_uuid
+		FROM tasks
+	`
+	var excluded []string
+	if !opts.IncludeArchived {
+		excluded = append(excluded, "'archived'")
+	}
+	if !opts.IncludeDeleted {
+		excluded = append(excluded, "'deleted'")
+	}
+	if len(excluded) > 0 {
+		taskQuery += " WHERE state NOT IN (" + strings.Join(excluded, ", ") + ")"
+	}
+	taskQuery += " ORDER BY slug"
+
+	taskRows, err := db.Query(taskQuery)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query tasks: %w", err)
+	}
+	defer taskRows.Close()
+	for taskRows.Next() {
+		var t markdownTask
+		var projectUUID string
+		if err := taskRows.Scan(&t.uuid, &t.id, &t.slug, &t.title, &t.state, &t.priority, &projectUUID); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan task: %w", err)
+		}
+		if c, ok := byUUID[projectUUID]; ok {
+			c.tasks = append(c.tasks, t)
+		}
+	}
+	if err := taskRows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return containers, roots, nil
+}
+
+// containerIndex renders a container's README.md.
+func containerIndex(c *markdownContainer) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n", c.title)
+	fmt.Fprintf(&sb, "`%s`", c.path)
+	if c.id != "" {
+		fmt.Fprintf(&sb, " (%s)", c.id)
+	}
+	sb.WriteString("\n")
+	if desc := strings.TrimSpace(c.description); desc != "" {
+		fmt.Fprintf(&sb, "\n%s\n", desc)
+	}
+
+	if len(c.children) > 0 {
+		sb.WriteString("\n## Containers\n\n")
+		for _, child := range c.children {
+			name := child.path[strings.LastIndex(child.path, "/")+1:]
+			fmt.Fprintf(&sb, "- [%s](%s/README.md)\n", child.title, name)
+		}
+	}
+
+	sb.WriteString("\n## Tasks\n\n")
+	if len(c.tasks) == 0 {
+		sb.WriteString("No tasks.\n")
+		return sb.String()
+	}
+	sb.WriteString("| ID | Task | State | Priority |\n")
+	sb.WriteString("|----|------|-------|----------|\n")
+	for _, t := range c.tasks {
+		fmt.Fprintf(&sb, "| %s | [%s](%s.md) | %s | %d |\n",
+			t.id, markdownTableEscape(t.title), t.slug, t.state, t.priority)
+	}
+	return sb.String()
+}
+
+// rootIndex renders the README.md at the top of the export.
+func rootIndex(roots []*markdownContainer) string {
+	var sb strings.Builder
+	sb.WriteString("# wrkq export\n\n")
+	if len(roots) == 0 {
+		sb.WriteString("No containers.\n")
+		return sb.String()
+	}
+	for _, c := range roots {
+		fmt.Fprintf(&sb, "- [%s](%s/README.md)\n", c.title, c.path)
+	}
+	return sb.String()
+}
+
+func markdownTableEscape(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
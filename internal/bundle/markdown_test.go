@@ -0,0 +1,117 @@
+package bundle
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lherron/wrkq/internal/db"
+	"gopkg.in/yaml.v3"
+)
+
+func TestExportMarkdownTree(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open db: %v", err)
+	}
+	defer database.Close()
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate db: %v", err)
+	}
+
+	actor := "00000000-0000-0000-0000-000000000001"
+	portal := "00000000-0000-0000-0000-000000000010"
+	auth := "00000000-0000-0000-0000-000000000011"
+	old := "00000000-0000-0000-0000-000000000012"
+	seed := []string{
+		`INSERT INTO actors (uuid, id, slug, role) VALUES ('` + actor + `', 'A-00001', 'tester', 'human')`,
+		`INSERT INTO containers (uuid, id, slug, title, description, created_by_actor_uuid, updated_by_actor_uuid)
+			VALUES ('` + portal + `', 'P-00010', 'portal', 'Portal', 'Customer portal.', '` + actor + `', '` + actor + `')`,
+		`INSERT INTO containers (uuid, id, parent_uuid, slug, title, created_by_actor_uuid, updated_by_actor_uuid)
+			VALUES ('` + auth + `', 'P-00011', '` + portal + `', 'auth', 'Auth', '` + actor + `', '` + actor + `')`,
+		`INSERT INTO containers (uuid, id, parent_uuid, slug, title, archived_at, created_by_actor_uuid, updated_by_actor_uuid)
+			VALUES ('` + old + `', 'P-00012', '` + portal + `', 'old', 'Old', '2025-01-01T00:00:00Z', '` + actor + `', '` + actor + `')`,
+		`INSERT INTO tasks (uuid, id, slug, title, description, project_uuid, state, priority, created_by_actor_uuid, updated_by_actor_uuid)
+			VALUES ('00000000-0000-0000-0000-000000000020', 'T-00020', 'login', 'Login: SSO', 'Support SSO login.', '` + auth + `', 'open', 1, '` + actor + `', '` + actor + `')`,
+		`INSERT INTO tasks (uuid, id, slug, title, project_uuid, state, created_by_actor_uuid, updated_by_actor_uuid)
+			VALUES ('00000000-0000-0000-0000-000000000021', 'T-00021', 'landing', 'Landing page', '` + portal + `', 'completed', '` + actor + `', '` + actor + `')`,
+		`INSERT INTO tasks (uuid, id, slug, title, project_uuid, state, created_by_actor_uuid, updated_by_actor_uuid)
+			VALUES ('00000000-0000-0000-0000-000000000022', 'T-00022', 'shelved', 'Shelved', '` + portal + `', 'archived', '` + actor + `', '` + actor + `')`,
+		`INSERT INTO tasks (uuid, id, slug, title, project_uuid, state, created_by_actor_uuid, updated_by_actor_uuid)
+			VALUES ('00000000-0000-0000-0000-000000000023', 'T-00023', 'legacy', 'Legacy', '` + old + `', 'open', '` + actor + `', '` + actor + `')`,
+	}
+	for _, stmt := range seed {
+		if _, err := database.Exec(stmt); err != nil {
+			t.Fatalf("Failed to seed: %v", err)
+		}
+	}
+
+	outDir := filepath.Join(t.TempDir(), "md")
+	result, err := ExportMarkdown(database.DB, MarkdownOptions{OutputDir: outDir})
+	if err != nil {
+		t.Fatalf("ExportMarkdown failed: %v", err)
+	}
+	if result.Containers != 2 || result.Tasks != 2 {
+		t.Fatalf("Expected 2 containers and 2 tasks, got %+v", result)
+	}
+
+	for _, rel := range []string{
+		"README.md",
+		"portal/README.md",
+		"portal/landing.md",
+		"portal/auth/README.md",
+		"portal/auth/login.md",
+	} {
+		if _, err := os.Stat(filepath.Join(outDir, rel)); err != nil {
+			t.Errorf("Expected %s: %v", rel, err)
+		}
+	}
+	for _, rel := range []string{"portal/shelved.md", "portal/old"} {
+		if _, err := os.Stat(filepath.Join(outDir, rel)); !os.IsNotExist(err) {
+			t.Errorf("Archived %s should be left out", rel)
+		}
+	}
+
+	login, err := os.ReadFile(filepath.Join(outDir, "portal/auth/login.md"))
+	if err != nil {
+		t.Fatalf("Failed to read task file: %v", err)
+	}
+	frontmatter, _, ok := splitFrontmatter(string(login))
+	if !ok {
+		t.Fatalf("Task file has no frontmatter:\n%s", login)
+	}
+	var fields map[string]interface{}
+	if err := yaml.Unmarshal([]byte(frontmatter), &fields); err != nil {
+		t.Fatalf("Task file has invalid frontmatter: %v", err)
+	}
+	if fields["id"] != "T-00020" || fields["project_id"] != "P-00011" || fields["title"] != "Login: SSO" ||
+		fields["state"] != "open" || fields["priority"] != 1 || fields["created_by"] != "tester" {
+		t.Errorf("Unexpected frontmatter: %v", fields)
+	}
+	if !strings.Contains(string(login), "\n# Login: SSO\n\nSupport SSO login.\n") {
+		t.Errorf("Expected title heading and description, got:\n%s", login)
+	}
+
+	index, err := os.ReadFile(filepath.Join(outDir, "portal/README.md"))
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	for _, want := range []string{"# Portal", "Customer portal.", "[Auth](auth/README.md)", "[Landing page](landing.md)"} {
+		if !strings.Contains(string(index), want) {
+			t.Errorf("Index missing %q:\n%s", want, index)
+		}
+	}
+
+	archivedDir := filepath.Join(t.TempDir(), "all")
+	result, err = ExportMarkdown(database.DB, MarkdownOptions{OutputDir: archivedDir, ProjectPath: "portal", IncludeArchived: true})
+	if err != nil {
+		t.Fatalf("ExportMarkdown with archived failed: %v", err)
+	}
+	if result.Containers != 3 || result.Tasks != 4 {
+		t.Fatalf("Expected 3 containers and 4 tasks with archived, got %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(archivedDir, "portal/old/legacy.md")); err != nil {
+		t.Errorf("Expected archived container's task: %v", err)
+	}
+}
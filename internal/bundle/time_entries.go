@@ -0,0 +1,88 @@
+package bundle
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// timeEntriesFile holds the time logged against the bundled tasks, as a JSON
+// array of TimeEntry. Bundles without logged time have no such file.
+const timeEntriesFile = "time_entries.json"
+
+// TimeEntry is one time_entries row of a bundled task. ActorSlug lets apply
+// find the actor when its UUID differs in the target database.
+type TimeEntry struct {
+	UUID      string `json:"uuid"`
+	TaskUUID  string `json:"task_uuid"`
+	ActorUUID string `json:"actor_uuid"`
+	ActorSlug string `json:"actor_slug,omitempty"`
+	Minutes   int    `json:"minutes"`
+	Note      string `json:"note,omitempty"`
+	StartedAt string `json:"started_at,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+// LoadTimeEntries reads time_entries.json, returning nil when the bundle has
+// none.
+func LoadTimeEntries(bundleDir string) ([]TimeEntry, error) {
+	data, err := os.ReadFile(filepath.Join(bundleDir, timeEntriesFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", timeEntriesFile, err)
+	}
+
+	var entries []TimeEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", timeEntriesFile, err)
+	}
+	return entries, nil
+}
+
+// exportTimeEntries writes the time logged against the bundled tasks to
+// time_entries.json and returns them. Nothing is written when there are
+// none.
+func exportTimeEntries(db *sql.DB, bundleDir string, tasks []*TaskExport) ([]TimeEntry, error) {
+	var entries []TimeEntry
+	for _, task := range tasks {
+		rows, err := db.Query(`
+			SELECT te.uuid, te.task_uuid, te.actor_uuid, COALESCE(a.slug, ''), te.minutes, te.note,
+			       COALESCE(te.started_at, ''), te.created_at
+			FROM time_entries te
+			LEFT JOIN actors a ON a.uuid = te.actor_uuid
+			WHERE te.task_uuid = ?
+			ORDER BY te.created_at, te.rowid
+		`, task.UUID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query time entries: %w", err)
+		}
+		for rows.Next() {
+			var e TimeEntry
+			if err := rows.Scan(&e.UUID, &e.TaskUUID, &e.ActorUUID, &e.ActorSlug, &e.Minutes, &e.Note, &e.StartedAt, &e.CreatedAt); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan time entry: %w", err)
+			}
+			entries = append(entries, e)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to iterate time entries: %w", err)
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal time entries: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(bundleDir, timeEntriesFile), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", timeEntriesFile, err)
+	}
+	return entries, nil
+}
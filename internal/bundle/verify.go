@@ -0,0 +1,151 @@
+package bundle
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// VerifyIssue describes a single internal consistency problem in a bundle.
+type VerifyIssue struct {
+	Check   string `json:"check"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+// VerifyReport summarizes a bundle verification run.
+type VerifyReport struct {
+	Dir         string        `json:"dir"`
+	OK          bool          `json:"ok"`
+	Containers  int           `json:"containers"`
+	Tasks       int           `json:"tasks"`
+	Attachments int           `json:"attachments"`
+	TimeEntries int           `json:"time_entries"`
+	Issues      []VerifyIssue `json:"issues,omitempty"`
+}
+
+func (r *VerifyReport) addIssue(check, path, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, VerifyIssue{
+		Check:   check,
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Verify loads a bundle directory or archive and checks it for internal
+// consistency. It never opens a database; an error is returned only when the
+// bundle cannot be loaded at all, while consistency problems are collected in
+// the report.
+func Verify(bundleDir string) (*VerifyReport, error) {
+	b, err := LoadFrom(bundleDir)
+	if err != nil {
+		return nil, err
+	}
+	defer b.Close()
+
+	report := &VerifyReport{
+		Dir:        bundleDir,
+		Containers: len(b.Containers),
+		Tasks:      len(b.Tasks),
+	}
+
+	if err := b.Manifest.CheckMachineInterfaceVersion(); err != nil {
+		report.addIssue("manifest", "manifest.json", "%v", err)
+	}
+
+	containerSet := make(map[string]bool, len(b.Containers))
+	for _, container := range b.Containers {
+		containerSet[container] = true
+	}
+
+	seenPaths := make(map[string]string, len(b.Tasks))
+	seenUUIDs := make(map[string]string, len(b.Tasks))
+	taskUUIDs := make(map[string]bool, len(b.Tasks))
+	for _, task := range b.Tasks {
+		taskPath := filepath.ToSlash(task.Path)
+
+		// Slugs are case-insensitive, so paths differing only in case collide on apply
+		key := strings.ToLower(taskPath)
+		if other, ok := seenPaths[key]; ok {
+			report.addIssue("duplicate_path", taskPath, "task path duplicates %s", other)
+		} else {
+			seenPaths[key] = taskPath
+		}
+
+		segments := strings.Split(taskPath, "/")
+		for i := 1; i < len(segments); i++ {
+			parent := strings.Join(segments[:i], "/")
+			if !containerSet[parent] {
+				report.addIssue("missing_container", taskPath, "parent container %s not listed in containers.txt", parent)
+			}
+		}
+
+		if task.UUID == "" {
+			continue
+		}
+		taskUUIDs[task.UUID] = true
+		if other, ok := seenUUIDs[task.UUID]; ok {
+			report.addIssue("duplicate_uuid", taskPath, "uuid %s also used by %s", task.UUID, other)
+		} else {
+			seenUUIDs[task.UUID] = taskPath
+		}
+		if task.BaseEtag <= 0 {
+			report.addIssue("missing_base_etag", taskPath, "uuid %s present without base_etag", task.UUID)
+		}
+	}
+
+	if b.Manifest.WithAttachments {
+		verifyAttachments(b.Dir, taskUUIDs, report)
+	}
+	verifyTimeEntries(b.TimeEntries, taskUUIDs, report)
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		return report.Issues[i].Path < report.Issues[j].Path
+	})
+	report.OK = len(report.Issues) == 0
+	return report, nil
+}
+
+// verifyAttachments checks that every bundled attachment belongs to a task in
+// the bundle and that its file (or blob) exists on disk.
+func verifyAttachments(bundleDir string, taskUUIDs map[string]bool, report *VerifyReport) {
+	entries, err := ListAttachments(bundleDir)
+	if err != nil {
+		report.addIssue("attachment", "attachments", "%v", err)
+		return
+	}
+
+	for _, entry := range entries {
+		name := entry.TaskUUID + "/" + entry.Filename
+		if !taskUUIDs[entry.TaskUUID] {
+			report.addIssue("attachment", name, "no task in bundle has uuid %s", entry.TaskUUID)
+		}
+		info, err := os.Stat(entry.Path)
+		if err != nil {
+			report.addIssue("attachment", name, "attachment file missing: %v", err)
+			continue
+		}
+		if !info.Mode().IsRegular() {
+			report.addIssue("attachment", name, "attachment is not a regular file")
+			continue
+		}
+		report.Attachments++
+	}
+}
+
+// verifyTimeEntries checks that every bundled time entry belongs to a task in
+// the bundle and logs a positive number of minutes.
+func verifyTimeEntries(entries []TimeEntry, taskUUIDs map[string]bool, report *VerifyReport) {
+	for _, entry := range entries {
+		name := timeEntriesFile + ":" + entry.UUID
+		if !taskUUIDs[entry.TaskUUID] {
+			report.addIssue("time_entry", name, "no task in bundle has uuid %s", entry.TaskUUID)
+		}
+		if entry.Minutes < 1 {
+			report.addIssue("time_entry", name, "minutes must be at least 1, got %d", entry.Minutes)
+		}
+		report.TimeEntries++
+	}
+}
@@ -0,0 +1,93 @@
+package bundle
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeBundleFile(t *testing.T, dir, rel, content string) {
+	t.Helper()
+	path := filepath.Join(dir, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", rel, err)
+	}
+}
+
+func TestVerify_ConsistentBundle(t *testing.T) {
+	tmpDir := t.TempDir()
+	taskUUID := "123e4567-e89b-12d3-a456-426614174000"
+
+	writeBundleFile(t, tmpDir, "manifest.json", `{"machine_interface_version": 1, "timestamp": "2025-11-19T12:00:00Z", "with_attachments": true}`)
+	writeBundleFile(t, tmpDir, "containers.txt", "portal\nportal/auth\n")
+	writeBundleFile(t, tmpDir, "tasks/portal/auth/login.md", "---\nuuid: "+taskUUID+"\nbase_etag: 3\n---\n\nBody\n")
+	writeBundleFile(t, tmpDir, "tasks/portal/readme.md", "---\ntitle: New task\n---\n\nBody\n")
+	writeBundleFile(t, tmpDir, "attachments/"+taskUUID+"/notes.txt", "notes")
+
+	report, err := Verify(tmpDir)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !report.OK {
+		t.Fatalf("Expected consistent bundle, got issues: %+v", report.Issues)
+	}
+	if report.Containers != 2 || report.Tasks != 2 || report.Attachments != 1 {
+		t.Errorf("Unexpected counts: %+v", report)
+	}
+}
+
+func TestVerify_ReportsProblems(t *testing.T) {
+	tmpDir := t.TempDir()
+	taskUUID := "123e4567-e89b-12d3-a456-426614174000"
+
+	writeBundleFile(t, tmpDir, "manifest.json", `{"machine_interface_version": 2, "timestamp": "2025-11-19T12:00:00Z", "with_attachments": true}`)
+	writeBundleFile(t, tmpDir, "containers.txt", "portal\n")
+	writeBundleFile(t, tmpDir, "tasks/portal/auth/login.md", "---\nuuid: "+taskUUID+"\n---\n\nBody\n")
+	writeBundleFile(t, tmpDir, "tasks/portal/Other.md", "---\nuuid: "+taskUUID+"\nbase_etag: 1\n---\n\nBody\n")
+	writeBundleFile(t, tmpDir, "tasks/portal/other.md", "---\ntitle: Collides\n---\n\nBody\n")
+	writeBundleFile(t, tmpDir, "attachments/00000000-0000-0000-0000-000000000099/orphan.txt", "orphan")
+
+	report, err := Verify(tmpDir)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if report.OK {
+		t.Fatal("Expected verification problems")
+	}
+
+	got := make(map[string]int)
+	for _, issue := range report.Issues {
+		got[issue.Check]++
+	}
+	want := map[string]int{
+		"manifest":          1,
+		"missing_container": 1,
+		"duplicate_path":    1,
+		"duplicate_uuid":    1,
+		"missing_base_etag": 1,
+		"attachment":        1,
+	}
+	for check, count := range want {
+		if got[check] != count {
+			t.Errorf("Expected %d %s issues, got %d (%+v)", count, check, got[check], report.Issues)
+		}
+	}
+}
+
+func TestVerify_SkipsAttachmentsWhenDisabled(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	writeBundleFile(t, tmpDir, "manifest.json", `{"machine_interface_version": 1, "timestamp": "2025-11-19T12:00:00Z", "with_attachments": false}`)
+	writeBundleFile(t, tmpDir, "attachments/stray/file.txt", "stray")
+
+	report, err := Verify(tmpDir)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !report.OK {
+		t.Fatalf("Expected attachments to be ignored, got issues: %+v", report.Issues)
+	}
+}
@@ -1,11 +1,15 @@
 package cli
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 
 	"github.com/lherron/wrkq/internal/actors"
 	"github.com/lherron/wrkq/internal/cli/appctx"
+	"github.com/lherron/wrkq/internal/db"
+	"github.com/lherron/wrkq/internal/domain"
+	"github.com/lherron/wrkq/internal/events"
 	"github.com/lherron/wrkq/internal/paths"
 	"github.com/lherron/wrkq/internal/render"
 	"github.com/spf13/cobra"
@@ -20,7 +24,7 @@ var actorsAdmCmd = &cobra.Command{
 var actorsAdmLsCmd = &cobra.Command{
 	Use:   "ls",
 	Short: "List all actors",
-	Long:  `Lists all actors (users and agents) in the system.`,
+	Long:  `Lists actors (users and agents) in the system. Deactivated actors are hidden unless --all is given.`,
 	RunE:  appctx.WithApp(appctx.DefaultOptions(), runActorsAdmList),
 }
 
@@ -32,27 +36,53 @@ var actorAdmAddCmd = &cobra.Command{
 	RunE:  appctx.WithApp(appctx.DefaultOptions(), runActorAdmAdd),
 }
 
+var actorsAdmMergeCmd = &cobra.Command{
+	Use:   "merge",
+	Short: "Merge a duplicate actor into another",
+	Long: `Repoints every reference to the --from actor (created_by, updated_by,
+assignee, comment authors, deletions, relations, attachments, saved views and
+event log attribution) to the --into actor, then deletes --from. Everything
+happens in one transaction and an actor.merged event is recorded on --into.
+Use --dry-run to report how many rows would be repointed per table.`,
+	RunE: appctx.WithApp(appctx.WithActor(), runActorsAdmMerge),
+}
+
 var (
 	actorsAdmLsJSON      bool
 	actorsAdmLsNDJSON    bool
 	actorsAdmLsPorcelain bool
+	actorsAdmLsAll       bool
 	actorAdmAddName      string
 	actorAdmAddRole      string
+	actorsAdmMergeFrom   string
+	actorsAdmMergeInto   string
+	actorsAdmMergeDryRun bool
+	actorsAdmMergeJSON   bool
 )
 
 func init() {
 	rootAdmCmd.AddCommand(actorsAdmCmd)
 	actorsAdmCmd.AddCommand(actorsAdmLsCmd)
 	actorsAdmCmd.AddCommand(actorAdmAddCmd)
+	actorsAdmCmd.AddCommand(actorsAdmMergeCmd)
 
 	// actors ls flags
 	actorsAdmLsCmd.Flags().BoolVar(&actorsAdmLsJSON, "json", false, "Output as JSON")
 	actorsAdmLsCmd.Flags().BoolVar(&actorsAdmLsNDJSON, "ndjson", false, "Output as newline-delimited JSON")
 	actorsAdmLsCmd.Flags().BoolVar(&actorsAdmLsPorcelain, "porcelain", false, "Machine-readable output")
+	actorsAdmLsCmd.Flags().BoolVar(&actorsAdmLsAll, "all", false, "Include deactivated actors")
 
 	// actor add flags
 	actorAdmAddCmd.Flags().StringVar(&actorAdmAddName, "name", "", "Display name for the actor")
 	actorAdmAddCmd.Flags().StringVar(&actorAdmAddRole, "role", "human", "Actor role (human, agent, system)")
+
+	// actors merge flags
+	actorsAdmMergeCmd.Flags().StringVar(&actorsAdmMergeFrom, "from", "", "Actor to merge away (slug, ID or UUID)")
+	actorsAdmMergeCmd.Flags().StringVar(&actorsAdmMergeInto, "into", "", "Actor that receives the references (slug, ID or UUID)")
+	actorsAdmMergeCmd.Flags().BoolVar(&actorsAdmMergeDryRun, "dry-run", false, "Report counts without writing")
+	actorsAdmMergeCmd.Flags().BoolVar(&actorsAdmMergeJSON, "json", false, "Output as JSON")
+	_ = actorsAdmMergeCmd.MarkFlagRequired("from")
+	_ = actorsAdmMergeCmd.MarkFlagRequired("into")
 }
 
 func runActorsAdmList(app *appctx.App, cmd *cobra.Command, args []string) error {
@@ -60,7 +90,7 @@ func runActorsAdmList(app *appctx.App, cmd *cobra.Command, args []string) error
 
 	// List actors
 	resolver := actors.NewResolver(database.DB)
-	actorList, err := resolver.List()
+	actorList, err := resolver.List(actorsAdmLsAll)
 	if err != nil {
 		return fmt.Errorf("failed to list actors: %w", err)
 	}
@@ -135,3 +165,184 @@ func runActorAdmAdd(app *appctx.App, cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// actorRefColumns lists every column that points at an actor.
+var actorRefColumns = []struct{ Table, Column string }{
+	{"containers", "created_by_actor_uuid"},
+	{"containers", "updated_by_actor_uuid"},
+	{"tasks", "created_by_actor_uuid"},
+	{"tasks", "updated_by_actor_uuid"},
+	{"tasks", "assignee_actor_uuid"},
+	{"comments", "actor_uuid"},
+	{"comments", "deleted_by_actor_uuid"},
+	{"task_relations", "created_by_actor_uuid"},
+	{"attachments", "created_by_actor_uuid"},
+	{"views", "owner_actor_uuid"},
+	{"task_templates", "created_by_actor_uuid"},
+	{"reactions", "actor_uuid"},
+	{"time_entries", "actor_uuid"},
+	{"api_tokens", "actor_uuid"},
+	{"event_log", "actor_uuid"},
+}
+
+type actorMergeReport struct {
+	From      string         `json:"from"`
+	FromUUID  string         `json:"from_uuid"`
+	Into      string         `json:"into"`
+	IntoUUID  string         `json:"into_uuid"`
+	DryRun    bool           `json:"dry_run"`
+	Repointed map[string]int `json:"repointed"` // keyed by table.column
+	TotalRows int            `json:"total_rows"`
+}
+
+func runActorsAdmMerge(app *appctx.App, cmd *cobra.Command, args []string) error {
+	resolver := actors.NewResolver(app.DB.DB)
+	fromUUID, err := resolver.Resolve(actorsAdmMergeFrom)
+	if err != nil {
+		return err
+	}
+	intoUUID, err := resolver.Resolve(actorsAdmMergeInto)
+	if err != nil {
+		return err
+	}
+
+	report, err := mergeActorRefs(app.DB, app.ActorUUID, fromUUID, intoUUID, actorsAdmMergeDryRun)
+	if err != nil {
+		return err
+	}
+
+	if actorsAdmMergeJSON {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	out := cmd.OutOrStdout()
+	verb := "Merged"
+	if report.DryRun {
+		verb = "Would merge"
+	}
+	fmt.Fprintf(out, "%s actor %s into %s (%d rows repointed)\n", verb, report.From, report.Into, report.TotalRows)
+	for _, ref := range actorRefColumns {
+		key := ref.Table + "." + ref.Column
+		if n := report.Repointed[key]; n > 0 {
+			fmt.Fprintf(out, "  %s: %d\n", key, n)
+		}
+	}
+	return nil
+}
+
+// mergeActorRefs repoints every reference from fromUUID to intoUUID and
+// deletes the from actor, in one transaction. In dry-run mode it only counts
+// the rows that would change.
+func mergeActorRefs(database *db.DB, actingUUID, fromUUID, intoUUID string, dryRun bool) (*actorMergeReport, error) {
+	if fromUUID == intoUUID {
+		return nil, fmt.Errorf("cannot merge an actor into itself")
+	}
+
+	resolver := actors.NewResolver(database.DB)
+	from, err := resolver.GetByUUID(fromUUID)
+	if err != nil {
+		return nil, err
+	}
+	into, err := resolver.GetByUUID(intoUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := database.BeginTx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Saved view names are unique per owner, so colliding views would be lost
+	var collision string
+	err = tx.QueryRow(`
+		SELECT f.name FROM views f
+		JOIN views i ON i.owner_actor_uuid = ? AND i.name = f.name
+		WHERE f.owner_actor_uuid = ?
+		LIMIT 1
+	`, intoUUID, fromUUID).Scan(&collision)
+	if err == nil {
+		return nil, fmt.Errorf("both actors have a saved view named %q; rename or delete one first", collision)
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to check saved views: %w", err)
+	}
+
+	// A reaction both actors left on the same resource can only be kept
+	// once, so the from actor's copy is dropped. The transaction is rolled
+	// back in dry-run mode, so this only makes the counts below exact there.
+	if _, err := tx.Exec(`
+		DELETE FROM reactions
+		WHERE actor_uuid = ?1 AND EXISTS (
+			SELECT 1 FROM reactions i
+			WHERE i.actor_uuid = ?2 AND i.resource_type = reactions.resource_type
+			  AND i.resource_uuid = reactions.resource_uuid AND i.emoji = reactions.emoji
+		)
+	`, fromUUID, intoUUID); err != nil {
+		return nil, fmt.Errorf("failed to drop duplicate reactions: %w", err)
+	}
+
+	report := &actorMergeReport{
+		From:      from.Slug,
+		FromUUID:  fromUUID,
+		Into:      into.Slug,
+		IntoUUID:  intoUUID,
+		DryRun:    dryRun,
+		Repointed: make(map[string]int),
+	}
+
+	for _, ref := range actorRefColumns {
+		key := ref.Table + "." + ref.Column
+		var count int
+		if dryRun {
+			if err := tx.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s = ?", ref.Table, ref.Column), fromUUID).Scan(&count); err != nil {
+				return nil, fmt.Errorf("failed to count %s: %w", key, err)
+			}
+		} else {
+			result, err := tx.Exec(fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ?", ref.Table, ref.Column, ref.Column), intoUUID, fromUUID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to repoint %s: %w", key, err)
+			}
+			affected, _ := result.RowsAffected()
+			count = int(affected)
+		}
+		report.Repointed[key] = count
+		report.TotalRows += count
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	if _, err := tx.Exec("DELETE FROM actors WHERE uuid = ?", fromUUID); err != nil {
+		return nil, fmt.Errorf("failed to delete actor %s: %w", from.Slug, err)
+	}
+
+	if actingUUID == fromUUID {
+		actingUUID = intoUUID
+	}
+	payloadJSON, _ := json.Marshal(map[string]interface{}{
+		"from_uuid": fromUUID,
+		"from_id":   from.ID,
+		"from_slug": from.Slug,
+		"repointed": report.Repointed,
+	})
+	payload := string(payloadJSON)
+	if err := events.NewWriter(database.DB).LogEvent(tx, &domain.Event{
+		ActorUUID:    &actingUUID,
+		ResourceType: "actor",
+		ResourceUUID: &intoUUID,
+		EventType:    "actor.merged",
+		Payload:      &payload,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to log actor.merged: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return report, nil
+}
@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lherron/wrkq/internal/db"
+)
+
+const (
+	mergeIntoActorUUID = "00000000-0000-0000-0000-000000000001"
+	mergeFromActorUUID = "00000000-0000-0000-0000-0000000000d1"
+)
+
+func setupDuplicateActor(t *testing.T) *db.DB {
+	t.Helper()
+	database, _ := setupTestEnv(t)
+
+	stmts := []string{
+		`INSERT INTO actors (uuid, id, slug, role) VALUES ('` + mergeFromActorUUID + `', 'A-00009', 'test-user-dup', 'human')`,
+		`INSERT INTO tasks (uuid, id, slug, title, project_uuid, state, priority, assignee_actor_uuid,
+			created_by_actor_uuid, updated_by_actor_uuid, etag)
+		 VALUES ('00000000-0000-0000-0000-0000000000d2', 'T-00901', 'dup-task', 'Dup task', '00000000-0000-0000-0000-000000000002',
+			'open', 3, '` + mergeFromActorUUID + `', '` + mergeFromActorUUID + `', '` + mergeIntoActorUUID + `', 1)`,
+		`INSERT INTO comments (uuid, id, task_uuid, actor_uuid, body)
+		 VALUES ('00000000-0000-0000-0000-0000000000d3', 'C-00901', '00000000-0000-0000-0000-0000000000d2', '` + mergeFromActorUUID + `', 'dup comment')`,
+		`INSERT INTO views (owner_actor_uuid, name) VALUES ('` + mergeFromActorUUID + `', 'mine')`,
+		`INSERT INTO time_entries (uuid, task_uuid, actor_uuid, minutes)
+		 VALUES ('00000000-0000-0000-0000-0000000000d4', '00000000-0000-0000-0000-0000000000d2', '` + mergeFromActorUUID + `', 30)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := database.Exec(stmt); err != nil {
+			t.Fatalf("failed to seed: %v", err)
+		}
+	}
+	return database
+}
+
+func TestMergeActorRefsDryRun(t *testing.T) {
+	database := setupDuplicateActor(t)
+
+	report, err := mergeActorRefs(database, mergeIntoActorUUID, mergeFromActorUUID, mergeIntoActorUUID, true)
+	if err != nil {
+		t.Fatalf("mergeActorRefs failed: %v", err)
+	}
+	if report.Repointed["tasks.assignee_actor_uuid"] != 1 || report.Repointed["tasks.created_by_actor_uuid"] != 1 ||
+		report.Repointed["comments.actor_uuid"] != 1 || report.Repointed["views.owner_actor_uuid"] != 1 ||
+		report.Repointed["time_entries.actor_uuid"] != 1 {
+		t.Fatalf("unexpected dry-run counts: %v", report.Repointed)
+	}
+	if report.TotalRows != 5 {
+		t.Fatalf("expected 5 rows, got %d", report.TotalRows)
+	}
+
+	var count int
+	if err := database.QueryRow("SELECT COUNT(*) FROM actors WHERE uuid = ?", mergeFromActorUUID).Scan(&count); err != nil {
+		t.Fatalf("failed to query actor: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("dry-run should not delete the actor")
+	}
+}
+
+func TestMergeActorRefs(t *testing.T) {
+	database := setupDuplicateActor(t)
+
+	if _, err := mergeActorRefs(database, mergeIntoActorUUID, mergeFromActorUUID, mergeIntoActorUUID, false); err != nil {
+		t.Fatalf("mergeActorRefs failed: %v", err)
+	}
+
+	var remaining int
+	if err := database.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM actors WHERE uuid = ?1)
+		     + (SELECT COUNT(*) FROM tasks WHERE assignee_actor_uuid = ?1 OR created_by_actor_uuid = ?1)
+		     + (SELECT COUNT(*) FROM comments WHERE actor_uuid = ?1)
+		     + (SELECT COUNT(*) FROM views WHERE owner_actor_uuid = ?1)
+		     + (SELECT COUNT(*) FROM time_entries WHERE actor_uuid = ?1)
+	`, mergeFromActorUUID).Scan(&remaining); err != nil {
+		t.Fatalf("failed to query references: %v", err)
+	}
+	if remaining != 0 {
+		t.Fatalf("expected no references to the merged actor, found %d", remaining)
+	}
+
+	var events int
+	if err := database.QueryRow(
+		"SELECT COUNT(*) FROM event_log WHERE event_type = 'actor.merged' AND resource_uuid = ?", mergeIntoActorUUID,
+	).Scan(&events); err != nil {
+		t.Fatalf("failed to query events: %v", err)
+	}
+	if events != 1 {
+		t.Fatalf("expected one actor.merged event, got %d", events)
+	}
+
+	if _, err := mergeActorRefs(database, mergeIntoActorUUID, mergeIntoActorUUID, mergeIntoActorUUID, false); err == nil {
+		t.Fatalf("expected merging an actor into itself to fail")
+	}
+}
+
+func TestMergeActorRefsKeepsReactions(t *testing.T) {
+	database := setupDuplicateActor(t)
+	stmts := []string{
+		`INSERT INTO reactions (resource_type, resource_uuid, actor_uuid, emoji)
+		 VALUES ('task', '00000000-0000-0000-0000-0000000000d2', '` + mergeFromActorUUID + `', '+1')`,
+		`INSERT INTO reactions (resource_type, resource_uuid, actor_uuid, emoji)
+		 VALUES ('task', '00000000-0000-0000-0000-0000000000d2', '` + mergeFromActorUUID + `', 'eyes')`,
+		`INSERT INTO reactions (resource_type, resource_uuid, actor_uuid, emoji)
+		 VALUES ('task', '00000000-0000-0000-0000-0000000000d2', '` + mergeIntoActorUUID + `', '+1')`,
+		`INSERT INTO reactions (resource_type, resource_uuid, actor_uuid, emoji)
+		 VALUES ('comment', '00000000-0000-0000-0000-0000000000d3', '` + mergeFromActorUUID + `', '+1')`,
+	}
+	for _, stmt := range stmts {
+		if _, err := database.Exec(stmt); err != nil {
+			t.Fatalf("failed to seed reaction: %v", err)
+		}
+	}
+
+	if _, err := mergeActorRefs(database, mergeIntoActorUUID, mergeFromActorUUID, mergeIntoActorUUID, false); err != nil {
+		t.Fatalf("mergeActorRefs failed: %v", err)
+	}
+
+	rows, err := database.Query(`SELECT resource_type, emoji FROM reactions WHERE actor_uuid = ? ORDER BY resource_type, emoji`, mergeIntoActorUUID)
+	if err != nil {
+		t.Fatalf("failed to query reactions: %v", err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var resourceType, emoji string
+		if err := rows.Scan(&resourceType, &emoji); err != nil {
+			t.Fatalf("failed to scan reaction: %v", err)
+		}
+		got = append(got, resourceType+":"+emoji)
+	}
+	want := []string{"comment:+1", "task:+1", "task:eyes"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected reactions %v to survive the merge, got %v", want, got)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/lherron/wrkq/internal/config"
 	"github.com/lherron/wrkq/internal/db"
 	"github.com/lherron/wrkq/internal/selectors"
+	"github.com/lherron/wrkq/internal/webhooks"
 	"github.com/spf13/cobra"
 )
 
@@ -94,6 +95,7 @@ func Bootstrap(cmd *cobra.Command, opts Options) (*App, error) {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 	app.Config = cfg
+	webhooks.StrictTemplates = cfg.WebhookStrictTemplates
 
 	// Override DB path from --db flag if provided
 	if dbFlag := cmd.Flag("db"); dbFlag != nil {
@@ -10,6 +10,7 @@ import (
 	"github.com/lherron/wrkq/internal/attach"
 	"github.com/lherron/wrkq/internal/cli/appctx"
 	"github.com/lherron/wrkq/internal/cursor"
+	"github.com/lherron/wrkq/internal/db"
 	"github.com/lherron/wrkq/internal/domain"
 	"github.com/lherron/wrkq/internal/events"
 	"github.com/lherron/wrkq/internal/render"
@@ -105,104 +106,11 @@ func runAttachLs(app *appctx.App, cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	// Build cursor pagination
-	pag, err := cursor.Apply(attachLsCursor, cursor.ApplyOptions{
-		SortFields: []string{"created_at"},
-		SQLFields:  []string{"a.created_at"},
-		Descending: []bool{false}, // ASC
-		IDField:    "a.id",
-		Limit:      attachLsLimit,
-	})
+	attachments, nextCursorStr, err := listTaskAttachments(database, taskUUID, attachLsCursor, attachLsLimit)
 	if err != nil {
 		return err
 	}
 
-	// Query attachments with SQL-based pagination
-	query := `
-		SELECT a.uuid, a.id, a.filename, a.relative_path, a.mime_type, a.size_bytes,
-		       a.checksum, a.created_at, ac.slug as created_by
-		FROM attachments a
-		LEFT JOIN actors ac ON a.created_by_actor_uuid = ac.uuid
-		WHERE a.task_uuid = ?
-	`
-	queryArgs := []interface{}{taskUUID}
-
-	// Add cursor WHERE clause if present
-	if pag.WhereClause != "" {
-		query += " AND " + pag.WhereClause
-		queryArgs = append(queryArgs, pag.Params...)
-	}
-
-	// Add ORDER BY
-	query += " " + pag.OrderByClause
-
-	// Add LIMIT
-	if pag.LimitClause != "" {
-		query += " " + pag.LimitClause
-		queryArgs = append(queryArgs, *pag.LimitParam)
-	}
-
-	rows, err := database.Query(query, queryArgs...)
-	if err != nil {
-		return fmt.Errorf("failed to query attachments: %w", err)
-	}
-	defer rows.Close()
-
-	attachments := []map[string]interface{}{}
-	for rows.Next() {
-		var uuid, id, filename, relativePath, createdAt string
-		var mimeType, checksum, createdBy sql.NullString
-		var sizeBytes int64
-
-		err := rows.Scan(&uuid, &id, &filename, &relativePath, &mimeType, &sizeBytes,
-			&checksum, &createdAt, &createdBy)
-		if err != nil {
-			return fmt.Errorf("failed to scan attachment: %w", err)
-		}
-
-		att := map[string]interface{}{
-			"uuid":          uuid,
-			"id":            id,
-			"filename":      filename,
-			"relative_path": relativePath,
-			"size_bytes":    sizeBytes,
-			"created_at":    createdAt,
-		}
-		if mimeType.Valid {
-			att["mime_type"] = mimeType.String
-		}
-		if checksum.Valid {
-			att["checksum"] = checksum.String
-		}
-		if createdBy.Valid {
-			att["created_by"] = createdBy.String
-		}
-
-		attachments = append(attachments, att)
-	}
-
-	if err := rows.Err(); err != nil {
-		return fmt.Errorf("error iterating attachments: %w", err)
-	}
-
-	// Check if there are more results (we requested limit+1)
-	hasMore := false
-	if attachLsLimit > 0 && len(attachments) > attachLsLimit {
-		hasMore = true
-		attachments = attachments[:attachLsLimit]
-	}
-
-	// Generate next cursor if there are more results
-	var nextCursorStr string
-	if hasMore && len(attachments) > 0 {
-		lastAtt := attachments[len(attachments)-1]
-		nextCursorStr, _ = cursor.BuildNextCursor(
-			[]string{"created_at"},
-			[]interface{}{lastAtt["created_at"].(string)},
-			lastAtt["id"].(string),
-		)
-	}
-
 	// Output next_cursor to stderr in porcelain mode
 	if attachLsPorcelain && nextCursorStr != "" {
 		fmt.Fprintf(os.Stderr, "next_cursor=%s\n", nextCursorStr)
@@ -499,3 +407,108 @@ func runAttachRm(app *appctx.App, cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// listTaskAttachments returns a page of a task's attachments in created_at
+// order, shared by attach ls and the daemon. limit 0 means no limit; the
+// returned cursor is "" on the last page.
+func listTaskAttachments(database *db.DB, taskUUID, cursorStr string, limit int) ([]map[string]interface{}, string, error) {
+	// Build cursor pagination
+	pag, err := cursor.Apply(cursorStr, cursor.ApplyOptions{
+		SortFields: []string{"created_at"},
+		SQLFields:  []string{"a.created_at"},
+		Descending: []bool{false}, // ASC
+		IDField:    "a.id",
+		Limit:      limit,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Query attachments with SQL-based pagination
+	query := `
+		SELECT a.uuid, a.id, a.filename, a.relative_path, a.mime_type, a.size_bytes,
+		       a.checksum, a.created_at, ac.slug as created_by
+		FROM attachments a
+		LEFT JOIN actors ac ON a.created_by_actor_uuid = ac.uuid
+		WHERE a.task_uuid = ?
+	`
+	queryArgs := []interface{}{taskUUID}
+
+	// Add cursor WHERE clause if present
+	if pag.WhereClause != "" {
+		query += " AND " + pag.WhereClause
+		queryArgs = append(queryArgs, pag.Params...)
+	}
+
+	// Add ORDER BY
+	query += " " + pag.OrderByClause
+
+	// Add LIMIT
+	if pag.LimitClause != "" {
+		query += " " + pag.LimitClause
+		queryArgs = append(queryArgs, *pag.LimitParam)
+	}
+
+	rows, err := database.Query(query, queryArgs...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query attachments: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := []map[string]interface{}{}
+	for rows.Next() {
+		var uuid, id, filename, relativePath, createdAt string
+		var mimeType, checksum, createdBy sql.NullString
+		var sizeBytes int64
+
+		err := rows.Scan(&uuid, &id, &filename, &relativePath, &mimeType, &sizeBytes,
+			&checksum, &createdAt, &createdBy)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to scan attachment: %w", err)
+		}
+
+		att := map[string]interface{}{
+			"uuid":          uuid,
+			"id":            id,
+			"filename":      filename,
+			"relative_path": relativePath,
+			"size_bytes":    sizeBytes,
+			"created_at":    createdAt,
+		}
+		if mimeType.Valid {
+			att["mime_type"] = mimeType.String
+		}
+		if checksum.Valid {
+			att["checksum"] = checksum.String
+		}
+		if createdBy.Valid {
+			att["created_by"] = createdBy.String
+		}
+
+		attachments = append(attachments, att)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("error iterating attachments: %w", err)
+	}
+
+	// Check if there are more results (we requested limit+1)
+	hasMore := false
+	if limit > 0 && len(attachments) > limit {
+		hasMore = true
+		attachments = attachments[:limit]
+	}
+
+	// Generate next cursor if there are more results
+	var nextCursorStr string
+	if hasMore && len(attachments) > 0 {
+		lastAtt := attachments[len(attachments)-1]
+		nextCursorStr, _ = cursor.BuildNextCursor(
+			[]string{"created_at"},
+			[]interface{}{lastAtt["created_at"].(string)},
+			lastAtt["id"].(string),
+		)
+	}
+
+	return attachments, nextCursorStr, nil
+}
@@ -1,13 +1,21 @@
 package cli
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
+	"syscall"
 
+	"github.com/lherron/wrkq/internal/attach"
+	"github.com/lherron/wrkq/internal/cli/appctx"
 	"github.com/lherron/wrkq/internal/config"
 	"github.com/lherron/wrkq/internal/db"
+	"github.com/lherron/wrkq/internal/render"
 	"github.com/spf13/cobra"
 )
 
@@ -33,6 +41,86 @@ var (
 	attachPathPorcelain bool
 )
 
+var attachVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify attachment files against their recorded checksums",
+	Long: `Hashes every file in the attachments table and compares it with the
+checksum recorded when it was attached, reporting files that are missing or
+whose content has changed (bitrot, truncated copies, incomplete merges).
+Attachments recorded without a checksum are counted but not checked.
+
+With --fix, each missing or mismatched file is re-copied from the same
+relative path under --source (for example the attach_dir of the database a
+merge came from), provided the source copy matches the recorded checksum.
+
+Exits non-zero if any problem remains.`,
+	Args: cobra.NoArgs,
+	RunE: appctx.WithApp(appctx.DefaultOptions(), runAttachVerify),
+}
+
+var attachGCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Delete attachment files that no attachment references",
+	Long: `Removes files under attach_dir/tasks/<task_uuid>/ that have no row in the
+attachments table, such as the blobs of tasks purged without cleaning up
+their directory. Task directories left empty are removed too.
+
+Only directories named after a task the database knows about (an existing
+task, or one recorded in the event log) are examined; anything else under
+attach_dir is reported as skipped and never touched. Dot-files, which
+uploads and repairs use while in progress, are ignored.
+
+Attachments whose files are missing are reported but not changed; see
+'wrkqadm attach verify --fix' to restore them.
+
+Use --dry-run to list what would be deleted.`,
+	Args: cobra.NoArgs,
+	RunE: appctx.WithApp(appctx.DefaultOptions(), runAttachGC),
+}
+
+var (
+	attachGCDryRun    bool
+	attachGCJSON      bool
+	attachGCPorcelain bool
+)
+
+type attachGCReport struct {
+	DryRun       bool     `json:"dry_run"`
+	Orphans      []string `json:"orphans"`
+	BytesFreed   int64    `json:"bytes_freed"`
+	RemovedDirs  []string `json:"removed_dirs"`
+	SkippedDirs  []string `json:"skipped_dirs"`
+	MissingFiles []string `json:"missing_files"`
+}
+
+var (
+	attachVerifyFix       bool
+	attachVerifySource    string
+	attachVerifyJSON      bool
+	attachVerifyPorcelain bool
+)
+
+// attachmentProblem is one attachment whose file failed verification.
+// Status is "missing", "unreadable" or "mismatch", or "repaired" once --fix
+// restored it.
+type attachmentProblem struct {
+	AttachmentID string `json:"attachment_id"`
+	RelativePath string `json:"relative_path"`
+	Status       string `json:"status"`
+	Expected     string `json:"expected_checksum"`
+	Actual       string `json:"actual_checksum,omitempty"`
+	Detail       string `json:"detail,omitempty"`
+}
+
+type attachVerifyReport struct {
+	Checked    int                 `json:"checked"`
+	OK         int                 `json:"ok"`
+	Unchecked  int                 `json:"unchecked"`
+	Repaired   int                 `json:"repaired"`
+	Unresolved int                 `json:"unresolved"`
+	Problems   []attachmentProblem `json:"problems"`
+}
+
 type attachPathOutput struct {
 	AttachmentID string `json:"attachment_id,omitempty"`
 	TaskUUID     string `json:"task_uuid,omitempty"`
@@ -49,6 +137,17 @@ func init() {
 
 	attachPathCmd.Flags().BoolVar(&attachPathJSON, "json", false, "Output as JSON")
 	attachPathCmd.Flags().BoolVar(&attachPathPorcelain, "porcelain", false, "Machine-readable output")
+
+	attachAdmCmd.AddCommand(attachVerifyCmd)
+	attachVerifyCmd.Flags().BoolVar(&attachVerifyFix, "fix", false, "Re-copy missing or corrupt files from --source")
+	attachVerifyCmd.Flags().StringVar(&attachVerifySource, "source", "", "Attachment directory to repair from")
+	attachVerifyCmd.Flags().BoolVar(&attachVerifyJSON, "json", false, "Output as JSON")
+	attachVerifyCmd.Flags().BoolVar(&attachVerifyPorcelain, "porcelain", false, "Machine-readable output")
+
+	attachAdmCmd.AddCommand(attachGCCmd)
+	attachGCCmd.Flags().BoolVar(&attachGCDryRun, "dry-run", false, "List orphaned files without deleting them")
+	attachGCCmd.Flags().BoolVar(&attachGCJSON, "json", false, "Output as JSON")
+	attachGCCmd.Flags().BoolVar(&attachGCPorcelain, "porcelain", false, "Machine-readable output")
 }
 
 func runAttachPath(cmd *cobra.Command, args []string) error {
@@ -152,3 +251,332 @@ func runAttachPath(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+func runAttachVerify(app *appctx.App, cmd *cobra.Command, args []string) error {
+	if attachVerifyFix && attachVerifySource == "" {
+		return fmt.Errorf("--fix requires --source")
+	}
+	source := ""
+	if attachVerifyFix {
+		source = attachVerifySource
+	}
+
+	report, err := verifyAttachments(app.DB, app.Config.AttachDir, source)
+	if err != nil {
+		return err
+	}
+
+	if attachVerifyJSON {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		if !attachVerifyPorcelain {
+			encoder.SetIndent("", "  ")
+		}
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else if len(report.Problems) == 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "All %d attachment(s) verified.\n", report.Checked)
+	} else {
+		headers := []string{"Attachment", "Status", "Path", "Detail"}
+		rows := make([][]string, 0, len(report.Problems))
+		for _, p := range report.Problems {
+			rows = append(rows, []string{p.AttachmentID, p.Status, p.RelativePath, p.Detail})
+		}
+		r := render.NewRenderer(cmd.OutOrStdout(), render.Options{
+			Format:    render.FormatTable,
+			Porcelain: attachVerifyPorcelain,
+		})
+		if err := r.RenderTable(headers, rows); err != nil {
+			return err
+		}
+		if !attachVerifyPorcelain {
+			fmt.Fprintf(cmd.OutOrStdout(), "\nChecked %d, ok %d, repaired %d, unresolved %d.\n",
+				report.Checked, report.OK, report.Repaired, report.Unresolved)
+		}
+	}
+
+	if report.Unresolved > 0 {
+		return fmt.Errorf("%d attachment(s) failed verification", report.Unresolved)
+	}
+	return nil
+}
+
+// verifyAttachments hashes every attachment file under attachDir and compares
+// it with the recorded checksum. When source is non-empty, missing or
+// mismatched files are re-copied from the same relative path under source if
+// that copy has the recorded checksum.
+func verifyAttachments(database *db.DB, attachDir, source string) (*attachVerifyReport, error) {
+	rows, err := database.Query(`
+		SELECT COALESCE(id, uuid), relative_path, checksum
+		FROM attachments
+		ORDER BY id, uuid
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attachments: %w", err)
+	}
+	defer rows.Close()
+
+	type attachmentFile struct {
+		id, relativePath string
+		checksum         sql.NullString
+	}
+	var files []attachmentFile
+	for rows.Next() {
+		var f attachmentFile
+		if err := rows.Scan(&f.id, &f.relativePath, &f.checksum); err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		files = append(files, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	report := &attachVerifyReport{Problems: []attachmentProblem{}}
+	for _, f := range files {
+		if !f.checksum.Valid || f.checksum.String == "" {
+			report.Unchecked++
+			continue
+		}
+		report.Checked++
+
+		path := attach.AbsolutePath(attachDir, f.relativePath)
+		problem := attachmentProblem{
+			AttachmentID: f.id,
+			RelativePath: f.relativePath,
+			Expected:     f.checksum.String,
+		}
+		actual, err := attach.Checksum(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			problem.Status = "missing"
+		case err != nil:
+			problem.Status = "unreadable"
+			problem.Detail = err.Error()
+		case actual != f.checksum.String:
+			problem.Status = "mismatch"
+			problem.Actual = actual
+		default:
+			report.OK++
+			continue
+		}
+
+		if source != "" {
+			if detail := repairAttachmentFile(source, attachDir, f.relativePath, f.checksum.String); detail != "" {
+				problem.Detail = detail
+			} else {
+				problem.Status = "repaired"
+				problem.Detail = "re-copied from " + filepath.Join(source, f.relativePath)
+			}
+		}
+		if problem.Status == "repaired" {
+			report.Repaired++
+		} else {
+			report.Unresolved++
+		}
+		report.Problems = append(report.Problems, problem)
+	}
+	return report, nil
+}
+
+// repairAttachmentFile copies relativePath from source into attachDir when
+// the source copy has the expected checksum. It returns "" on success and
+// otherwise why the file could not be repaired.
+func repairAttachmentFile(source, attachDir, relativePath, expected string) string {
+	src := attach.AbsolutePath(source, relativePath)
+	sourceChecksum, err := attach.Checksum(src)
+	if err != nil {
+		return "no usable source copy: " + err.Error()
+	}
+	if sourceChecksum != expected {
+		return "source copy has checksum " + sourceChecksum
+	}
+
+	// Copy beside the destination and rename over it, so an interrupted
+	// repair never leaves a truncated file in place; the dot prefix keeps
+	// it hidden from anything scanning the attachments tree
+	dst := attach.AbsolutePath(attachDir, relativePath)
+	tmp := filepath.Join(filepath.Dir(dst), "."+filepath.Base(dst)+".verify-tmp")
+	_, copied, err := attach.CopyFile(src, tmp)
+	if err != nil {
+		os.Remove(tmp)
+		return "copy failed: " + err.Error()
+	}
+	if copied != expected {
+		os.Remove(tmp)
+		return "copied file has checksum " + copied
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return "copy failed: " + err.Error()
+	}
+	return ""
+}
+
+func runAttachGC(app *appctx.App, cmd *cobra.Command, args []string) error {
+	report, err := gcAttachments(app.DB, app.Config.AttachDir, attachGCDryRun)
+	if err != nil {
+		return err
+	}
+
+	if attachGCJSON {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		if !attachGCPorcelain {
+			encoder.SetIndent("", "  ")
+		}
+		return encoder.Encode(report)
+	}
+
+	out := cmd.OutOrStdout()
+	if attachGCPorcelain {
+		for _, path := range report.Orphans {
+			fmt.Fprintln(out, path)
+		}
+		return nil
+	}
+
+	verb := "Deleted"
+	if attachGCDryRun {
+		verb = "Would delete"
+	}
+	for _, path := range report.Orphans {
+		fmt.Fprintf(out, "%s %s\n", verb, path)
+	}
+	for _, dir := range report.SkippedDirs {
+		fmt.Fprintf(out, "Skipped %s (not a known task)\n", dir)
+	}
+	for _, path := range report.MissingFiles {
+		fmt.Fprintf(out, "Missing %s (attachment row has no file)\n", path)
+	}
+	fmt.Fprintf(out, "%s %d orphaned file(s), %d bytes.\n", verb, len(report.Orphans), report.BytesFreed)
+	return nil
+}
+
+// gcAttachments deletes (or, with dryRun, lists) files under
+// attachDir/tasks/<uuid>/ that no attachments row references, for task
+// directories whose UUID the database knows. It also reports rows whose
+// files are missing. Paths in the report are relative to attachDir.
+func gcAttachments(database *db.DB, attachDir string, dryRun bool) (*attachGCReport, error) {
+	report := &attachGCReport{
+		DryRun:       dryRun,
+		Orphans:      []string{},
+		RemovedDirs:  []string{},
+		SkippedDirs:  []string{},
+		MissingFiles: []string{},
+	}
+
+	referenced := make(map[string]bool)
+	rows, err := database.Query(`SELECT relative_path FROM attachments ORDER BY relative_path`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attachments: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var relativePath string
+		if err := rows.Scan(&relativePath); err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		referenced[relativePath] = true
+		if _, err := os.Stat(attach.AbsolutePath(attachDir, relativePath)); errors.Is(err, fs.ErrNotExist) {
+			report.MissingFiles = append(report.MissingFiles, relativePath)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	links := orphanLinks{}
+	tasksDir := filepath.Join(attachDir, "tasks")
+	entries, err := os.ReadDir(tasksDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return report, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		dirRel := filepath.ToSlash(filepath.Join("tasks", entry.Name()))
+		if !entry.IsDir() {
+			report.SkippedDirs = append(report.SkippedDirs, dirRel)
+			continue
+		}
+		taskUUID := entry.Name()
+		var taskExists, taskKnown bool
+		if err := database.QueryRow(`
+			SELECT EXISTS(SELECT 1 FROM tasks WHERE uuid = ?),
+			       EXISTS(SELECT 1 FROM event_log WHERE resource_type = 'task' AND resource_uuid = ?)
+		`, taskUUID, taskUUID).Scan(&taskExists, &taskKnown); err != nil {
+			return nil, fmt.Errorf("failed to look up task %s: %w", taskUUID, err)
+		}
+		if !taskExists && !taskKnown {
+			report.SkippedDirs = append(report.SkippedDirs, dirRel)
+			continue
+		}
+
+		files, err := os.ReadDir(filepath.Join(tasksDir, taskUUID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", dirRel, err)
+		}
+		remaining := 0
+		for _, file := range files {
+			name := file.Name()
+			relativePath := dirRel + "/" + name
+			if strings.HasPrefix(name, ".") || !file.Type().IsRegular() || referenced[relativePath] {
+				remaining++
+				continue
+			}
+			info, err := file.Info()
+			if err != nil {
+				return nil, fmt.Errorf("failed to stat %s: %w", relativePath, err)
+			}
+			if !dryRun {
+				if err := attach.DeleteFile(attachDir, relativePath); err != nil {
+					return nil, err
+				}
+			}
+			report.Orphans = append(report.Orphans, relativePath)
+			report.BytesFreed += links.freed(info)
+		}
+
+		if remaining == 0 && !taskExists {
+			if !dryRun {
+				if err := os.Remove(filepath.Join(tasksDir, taskUUID)); err != nil {
+					return nil, fmt.Errorf("failed to remove %s: %w", dirRel, err)
+				}
+			}
+			report.RemovedDirs = append(report.RemovedDirs, dirRel)
+		}
+	}
+	return report, nil
+}
+
+// orphanLinks counts removed links per inode. Deduplicated uploads hard-link
+// one blob into several task directories, so its bytes are only freed once
+// every link is an orphan.
+type orphanLinks map[[2]uint64]*orphanLink
+
+type orphanLink struct {
+	nlink   uint64 // link count when first seen, before gc removed any
+	removed uint64
+}
+
+// freed records the removal of one link to info's file and returns the bytes
+// that frees: the file's size for its last link, otherwise zero.
+func (o orphanLinks) freed(info fs.FileInfo) int64 {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || st.Nlink <= 1 {
+		return info.Size()
+	}
+	key := [2]uint64{uint64(st.Dev), uint64(st.Ino)}
+	link, ok := o[key]
+	if !ok {
+		link = &orphanLink{nlink: uint64(st.Nlink)}
+		o[key] = link
+	}
+	link.removed++
+	if link.removed == link.nlink {
+		return info.Size()
+	}
+	return 0
+}
@@ -0,0 +1,268 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lherron/wrkq/internal/attach"
+	"github.com/lherron/wrkq/internal/store"
+)
+
+func TestVerifyAttachmentsFlagsAndRepairsCorruption(t *testing.T) {
+	database, _ := setupTestEnv(t)
+	attachDir := t.TempDir()
+	sourceDir := t.TempDir()
+
+	taskUUID := "00000000-0000-0000-0000-0000000000a1"
+	if _, err := database.Exec(`
+		INSERT INTO tasks (uuid, id, slug, title, project_uuid, state, priority, created_by_actor_uuid, updated_by_actor_uuid, etag)
+		VALUES (?, 'T-00001', 'files', 'Files', '00000000-0000-0000-0000-000000000002',
+			'open', 3, '00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001', 1)
+	`, taskUUID); err != nil {
+		t.Fatalf("failed to seed task: %v", err)
+	}
+
+	// Attach two files, keeping a pristine copy of each in sourceDir as a
+	// merge source would
+	attachFile := func(name, content string) string {
+		t.Helper()
+		relativePath := attach.RelativePath(taskUUID, name)
+		src := attach.AbsolutePath(sourceDir, relativePath)
+		if err := os.MkdirAll(filepath.Dir(src), 0755); err != nil {
+			t.Fatalf("failed to create source dir: %v", err)
+		}
+		if err := os.WriteFile(src, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write source file: %v", err)
+		}
+		size, checksum, err := attach.CopyFile(src, attach.AbsolutePath(attachDir, relativePath))
+		if err != nil {
+			t.Fatalf("CopyFile failed: %v", err)
+		}
+		if _, err := database.Exec(`
+			INSERT INTO attachments (id, task_uuid, filename, relative_path, size_bytes, checksum, created_by_actor_uuid)
+			VALUES ('', ?, ?, ?, ?, ?, '00000000-0000-0000-0000-000000000001')
+		`, taskUUID, name, relativePath, size, checksum); err != nil {
+			t.Fatalf("failed to insert attachment: %v", err)
+		}
+		return relativePath
+	}
+	good := attachFile("good.txt", "intact\n")
+	corrupt := attachFile("corrupt.txt", "original content\n")
+	missing := attachFile("missing.txt", "will vanish\n")
+
+	report, err := verifyAttachments(database, attachDir, "")
+	if err != nil {
+		t.Fatalf("verifyAttachments failed: %v", err)
+	}
+	if report.Checked != 3 || report.OK != 3 || len(report.Problems) != 0 {
+		t.Fatalf("expected a clean store, got %+v", report)
+	}
+
+	if err := os.WriteFile(attach.AbsolutePath(attachDir, corrupt), []byte("original cOntent\n"), 0644); err != nil {
+		t.Fatalf("failed to corrupt file: %v", err)
+	}
+	if err := os.Remove(attach.AbsolutePath(attachDir, missing)); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+
+	report, err = verifyAttachments(database, attachDir, "")
+	if err != nil {
+		t.Fatalf("verifyAttachments failed: %v", err)
+	}
+	if report.OK != 1 || report.Unresolved != 2 || len(report.Problems) != 2 {
+		t.Fatalf("expected two problems, got %+v", report)
+	}
+	statuses := map[string]string{}
+	for _, p := range report.Problems {
+		statuses[p.RelativePath] = p.Status
+	}
+	if statuses[corrupt] != "mismatch" || statuses[missing] != "missing" {
+		t.Fatalf("unexpected problems: %+v", report.Problems)
+	}
+	if _, flagged := statuses[good]; flagged {
+		t.Fatalf("intact file flagged: %+v", report.Problems)
+	}
+
+	// A source copy that doesn't match the recorded checksum is not used
+	if err := os.WriteFile(attach.AbsolutePath(sourceDir, missing), []byte("different\n"), 0644); err != nil {
+		t.Fatalf("failed to rewrite source file: %v", err)
+	}
+
+	report, err = verifyAttachments(database, attachDir, sourceDir)
+	if err != nil {
+		t.Fatalf("verifyAttachments --fix failed: %v", err)
+	}
+	if report.Repaired != 1 || report.Unresolved != 1 {
+		t.Fatalf("expected one repair and one unresolved, got %+v", report)
+	}
+	content, err := os.ReadFile(attach.AbsolutePath(attachDir, corrupt))
+	if err != nil || string(content) != "original content\n" {
+		t.Fatalf("corrupt file not restored: %q, %v", content, err)
+	}
+	if _, err := os.Stat(attach.AbsolutePath(attachDir, missing)); !os.IsNotExist(err) {
+		t.Fatalf("missing file should not be restored from a mismatched source: %v", err)
+	}
+}
+
+func TestGCAttachmentsRemovesPurgedTaskBlobs(t *testing.T) {
+	database, _ := setupTestEnv(t)
+	attachDir := t.TempDir()
+	s := store.New(database)
+
+	actorUUID := "00000000-0000-0000-0000-000000000001"
+	newTask := func(slug string) string {
+		t.Helper()
+		task, err := s.Tasks.Create(actorUUID, store.CreateParams{
+			Slug:        slug,
+			Title:       slug,
+			ProjectUUID: "00000000-0000-0000-0000-000000000002",
+			State:       "open",
+			Priority:    3,
+		})
+		if err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		return task.UUID
+	}
+	attachFile := func(taskUUID, name string) string {
+		t.Helper()
+		relativePath := attach.RelativePath(taskUUID, name)
+		path := attach.AbsolutePath(attachDir, relativePath)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create task dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if _, err := database.Exec(`
+			INSERT INTO attachments (id, task_uuid, filename, relative_path, size_bytes, created_by_actor_uuid)
+			VALUES ('', ?, ?, ?, ?, ?)
+		`, taskUUID, name, relativePath, len(name), actorUUID); err != nil {
+			t.Fatalf("failed to insert attachment: %v", err)
+		}
+		return relativePath
+	}
+
+	kept := newTask("kept")
+	keptFile := attachFile(kept, "keep.txt")
+	vanished := attachFile(kept, "vanished.txt")
+	os.Remove(attach.AbsolutePath(attachDir, vanished))
+
+	purged := newTask("purged")
+	purgedFile := attachFile(purged, "blob.bin")
+	// Purging through the store drops the rows but leaves the files
+	if _, err := s.Tasks.Purge(actorUUID, purged, 0); err != nil {
+		t.Fatalf("purge failed: %v", err)
+	}
+
+	// A directory the database has never heard of is left alone
+	stranger := filepath.Join(attachDir, "tasks", "not-a-task")
+	os.MkdirAll(stranger, 0755)
+	os.WriteFile(filepath.Join(stranger, "precious.txt"), []byte("x"), 0644)
+
+	report, err := gcAttachments(database, attachDir, true)
+	if err != nil {
+		t.Fatalf("dry-run gc failed: %v", err)
+	}
+	if !reflect.DeepEqual(report.Orphans, []string{purgedFile}) {
+		t.Fatalf("expected only the purged blob as orphan, got %v", report.Orphans)
+	}
+	if !reflect.DeepEqual(report.MissingFiles, []string{vanished}) {
+		t.Fatalf("expected the vanished file to be reported, got %v", report.MissingFiles)
+	}
+	if !reflect.DeepEqual(report.SkippedDirs, []string{"tasks/not-a-task"}) {
+		t.Fatalf("expected the unknown directory to be skipped, got %v", report.SkippedDirs)
+	}
+	if _, err := os.Stat(attach.AbsolutePath(attachDir, purgedFile)); err != nil {
+		t.Fatalf("dry run should not delete anything: %v", err)
+	}
+
+	report, err = gcAttachments(database, attachDir, false)
+	if err != nil {
+		t.Fatalf("gc failed: %v", err)
+	}
+	if len(report.Orphans) != 1 || report.BytesFreed != int64(len("blob.bin")) {
+		t.Fatalf("unexpected gc report: %+v", report)
+	}
+	if _, err := os.Stat(attach.TaskDir(attachDir, purged)); !os.IsNotExist(err) {
+		t.Fatalf("expected the purged task's directory to be removed: %v", err)
+	}
+	if _, err := os.Stat(attach.AbsolutePath(attachDir, keptFile)); err != nil {
+		t.Fatalf("referenced file was removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(stranger, "precious.txt")); err != nil {
+		t.Fatalf("file outside known task directories was removed: %v", err)
+	}
+}
+
+func TestGCAttachmentsCountsHardLinkedBlobsOnce(t *testing.T) {
+	database, _ := setupTestEnv(t)
+	attachDir := t.TempDir()
+	s := store.New(database)
+
+	actorUUID := "00000000-0000-0000-0000-000000000001"
+	newTask := func(slug string) string {
+		t.Helper()
+		task, err := s.Tasks.Create(actorUUID, store.CreateParams{
+			Slug:        slug,
+			Title:       slug,
+			ProjectUUID: "00000000-0000-0000-0000-000000000002",
+			State:       "open",
+			Priority:    3,
+		})
+		if err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		return task.UUID
+	}
+	link := func(existing, taskUUID, name string) string {
+		t.Helper()
+		relativePath := attach.RelativePath(taskUUID, name)
+		path := attach.AbsolutePath(attachDir, relativePath)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create task dir: %v", err)
+		}
+		if existing == "" {
+			if err := os.WriteFile(path, []byte("0123456789"), 0644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+		} else if err := os.Link(attach.AbsolutePath(attachDir, existing), path); err != nil {
+			t.Fatalf("failed to link file: %v", err)
+		}
+		return relativePath
+	}
+
+	// shared is linked into two purged tasks; pinned is also linked into a
+	// task whose row still references it
+	first, second, kept := newTask("first"), newTask("second"), newTask("kept")
+	shared := link("", first, "shared.bin")
+	link(shared, second, "shared.bin")
+	pinned := link("", first, "pinned.bin")
+	keptFile := link(pinned, kept, "pinned.bin")
+	if _, err := database.Exec(`
+		INSERT INTO attachments (id, task_uuid, filename, relative_path, size_bytes, created_by_actor_uuid)
+		VALUES ('', ?, 'pinned.bin', ?, 10, ?)
+	`, kept, keptFile, actorUUID); err != nil {
+		t.Fatalf("failed to insert attachment: %v", err)
+	}
+	for _, taskUUID := range []string{first, second} {
+		if _, err := s.Tasks.Purge(actorUUID, taskUUID, 0); err != nil {
+			t.Fatalf("purge failed: %v", err)
+		}
+	}
+
+	for _, dryRun := range []bool{true, false} {
+		report, err := gcAttachments(database, attachDir, dryRun)
+		if err != nil {
+			t.Fatalf("gc (dry run %v) failed: %v", dryRun, err)
+		}
+		if len(report.Orphans) != 3 || report.BytesFreed != 10 {
+			t.Fatalf("expected 3 orphans freeing 10 bytes (dry run %v), got %+v", dryRun, report)
+		}
+	}
+	if _, err := os.Stat(attach.AbsolutePath(attachDir, keptFile)); err != nil {
+		t.Fatalf("referenced link was removed: %v", err)
+	}
+}
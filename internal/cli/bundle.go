@@ -3,6 +3,7 @@ package cli
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/lherron/wrkq/internal/bundle"
@@ -24,7 +25,8 @@ var bundleCreateCmd = &cobra.Command{
 	Long: `Create a bundle of changes for PR workflow.
 
 Export tasks touched by a specific actor or time window as a reviewable bundle.
-Bundles can be committed to git and applied using wrkqadm bundle apply.`,
+Bundles can be committed to git and applied using wrkqadm bundle apply.
+Use --archive to emit a single .tar.gz or .zip file (e.g. for CI artifacts).`,
 	RunE: runBundleCreate,
 }
 
@@ -41,6 +43,7 @@ var (
 	bundleCreateJSON            bool
 	bundleCreatePorcelain       bool
 	bundleCreateDryRun          bool
+	bundleCreateArchive         string
 )
 
 func init() {
@@ -53,12 +56,13 @@ func init() {
 	bundleCreateCmd.Flags().StringVar(&bundleCreateUntil, "until", "", "Filter by end timestamp (RFC3339)")
 	bundleCreateCmd.Flags().StringVar(&bundleCreateProject, "project", "", "Restrict export to a project (path or UUID)")
 	bundleCreateCmd.Flags().StringArrayVar(&bundleCreatePathPrefixes, "path-prefix", nil, "Restrict export to path prefix (repeatable)")
-	bundleCreateCmd.Flags().BoolVar(&bundleCreateIncludeRefs, "include-refs", false, "Include refs/ stubs for related tasks outside scope")
+	bundleCreateCmd.Flags().BoolVar(&bundleCreateIncludeRefs, "include-refs", false, "Include refs/ stubs for related tasks outside scope and a refs.json ID index")
 	bundleCreateCmd.Flags().BoolVar(&bundleCreateWithAttachments, "with-attachments", false, "Include attachment files")
 	bundleCreateCmd.Flags().BoolVar(&bundleCreateNoEvents, "no-events", false, "Skip events.ndjson")
 	bundleCreateCmd.Flags().BoolVar(&bundleCreateJSON, "json", false, "Output as JSON")
 	bundleCreateCmd.Flags().BoolVar(&bundleCreatePorcelain, "porcelain", false, "Machine-readable output")
 	bundleCreateCmd.Flags().BoolVar(&bundleCreateDryRun, "dry-run", false, "Show what would be exported without writing")
+	bundleCreateCmd.Flags().StringVar(&bundleCreateArchive, "archive", "", "Write the bundle as a single .tar.gz or .zip archive instead of a directory")
 }
 
 func runBundleCreate(cmd *cobra.Command, args []string) error {
@@ -87,11 +91,12 @@ func runBundleCreate(cmd *cobra.Command, args []string) error {
 		Since:           bundleCreateSince,
 		Until:           bundleCreateUntil,
 		WithAttachments: bundleCreateWithAttachments,
+		AttachDir:       cfg.AttachDir,
 		WithEvents:      !bundleCreateNoEvents,
 		IncludeRefs:     bundleCreateIncludeRefs,
-		Version:         "0.1.0",
-		Commit:          "",
-		BuildDate:       "",
+		Version:         Version,
+		Commit:          GitCommit,
+		BuildDate:       BuildDate,
 	}
 
 	// Resolve project scope if provided
@@ -128,10 +133,29 @@ func runBundleCreate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("at least one filter required (--actor, --since, --until, --project, or --path-prefix)")
 	}
 
+	if bundleCreateArchive != "" {
+		if !bundle.IsArchive(bundleCreateArchive) {
+			return fmt.Errorf("unsupported archive format: %s (use .tar.gz, .tgz, or .zip)", bundleCreateArchive)
+		}
+		if !bundleCreateDryRun {
+			// Stage the bundle in a scratch directory; only the archive is kept
+			stageDir, err := os.MkdirTemp("", "wrkq-bundle-")
+			if err != nil {
+				return fmt.Errorf("failed to create staging directory: %w", err)
+			}
+			defer os.RemoveAll(stageDir)
+			opts.OutputDir = stageDir
+		}
+	}
+
 	if bundleCreateDryRun {
 		// TODO: Implement dry-run preview
 		fmt.Fprintf(cmd.OutOrStdout(), "Dry run - would create bundle with:\n")
-		fmt.Fprintf(cmd.OutOrStdout(), "  Output: %s\n", opts.OutputDir)
+		if bundleCreateArchive != "" {
+			fmt.Fprintf(cmd.OutOrStdout(), "  Archive: %s\n", bundleCreateArchive)
+		} else {
+			fmt.Fprintf(cmd.OutOrStdout(), "  Output: %s\n", opts.OutputDir)
+		}
 		if opts.Actor != "" {
 			fmt.Fprintf(cmd.OutOrStdout(), "  Actor: %s\n", opts.Actor)
 		}
@@ -161,10 +185,18 @@ func runBundleCreate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create bundle: %w", err)
 	}
 
+	location := b.Dir
+	if bundleCreateArchive != "" {
+		if err := bundle.WriteArchive(b.Dir, bundleCreateArchive); err != nil {
+			return err
+		}
+		location = bundleCreateArchive
+	}
+
 	// Output results
 	if bundleCreateJSON {
 		result := map[string]interface{}{
-			"bundle_dir":       b.Dir,
+			"bundle_dir":       location,
 			"tasks_count":      len(b.Tasks),
 			"containers_count": len(b.Containers),
 			"manifest":         b.Manifest,
@@ -177,13 +209,13 @@ func runBundleCreate(cmd *cobra.Command, args []string) error {
 	if bundleCreatePorcelain {
 		// Tab-separated: tasks_count containers_count bundle_dir
 		fmt.Fprintf(cmd.OutOrStdout(), "%d\t%d\t%s\n",
-			len(b.Tasks), len(b.Containers), b.Dir)
+			len(b.Tasks), len(b.Containers), location)
 		return nil
 	}
 
 	// Human-readable output
 	fmt.Fprintf(cmd.OutOrStdout(), "✓ Bundle created successfully\n")
-	fmt.Fprintf(cmd.OutOrStdout(), "  Location: %s\n", b.Dir)
+	fmt.Fprintf(cmd.OutOrStdout(), "  Location: %s\n", location)
 	fmt.Fprintf(cmd.OutOrStdout(), "  Tasks: %d\n", len(b.Tasks))
 	fmt.Fprintf(cmd.OutOrStdout(), "  Containers: %d\n", len(b.Containers))
 
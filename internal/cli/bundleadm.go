@@ -6,18 +6,18 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
-	"path/filepath"
 	"strings"
 
 	"github.com/google/uuid"
 	"github.com/lherron/wrkq/internal/actors"
+	"github.com/lherron/wrkq/internal/attach"
 	"github.com/lherron/wrkq/internal/bundle"
 	"github.com/lherron/wrkq/internal/config"
 	"github.com/lherron/wrkq/internal/db"
 	"github.com/lherron/wrkq/internal/domain"
 	"github.com/lherron/wrkq/internal/events"
 	"github.com/lherron/wrkq/internal/paths"
+	"github.com/lherron/wrkq/internal/store"
 	"github.com/pmezard/go-difflib/difflib"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -35,10 +35,29 @@ var bundleApplyCmd = &cobra.Command{
 	Long: `Apply a PR bundle into the canonical database with conflict detection.
 
 Reads manifest.json, ensures containers exist, applies task documents with
-etag checking, and re-hydrates attachments. Exit code 4 on conflicts.`,
+etag checking, and re-hydrates attachments. --from may name a bundle directory
+or a .tar.gz/.zip archive. Exit code 4 on conflicts.`,
 	RunE: runBundleApply,
 }
 
+var bundleVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check a bundle for internal consistency",
+	Long: `Load a bundle and check it for internal consistency without touching any
+database.
+
+Checks that machine_interface_version matches, every task's parent containers
+appear in containers.txt, task paths and UUIDs are unique, base_etag is set
+whenever uuid is, and (with with_attachments) every bundled attachment belongs
+to a task in the bundle and exists on disk. Exits non-zero on any problem.`,
+	RunE: runBundleVerify,
+}
+
+var (
+	bundleVerifyFrom string
+	bundleVerifyJSON bool
+)
+
 var (
 	bundleApplyFrom      string
 	bundleApplyDryRun    bool
@@ -53,6 +72,7 @@ type applyResult struct {
 	TasksApplied     int             `json:"tasks_applied"`
 	TasksFailed      int             `json:"tasks_failed"`
 	AttachmentsAdded int             `json:"attachments_added"`
+	TimeEntriesAdded int             `json:"time_entries_added"`
 	Conflicts        []applyConflict `json:"conflicts,omitempty"`
 	Errors           []string        `json:"errors,omitempty"`
 }
@@ -76,12 +96,55 @@ type applyFieldChange struct {
 func init() {
 	rootAdmCmd.AddCommand(bundleAdmCmd)
 	bundleAdmCmd.AddCommand(bundleApplyCmd)
+	bundleAdmCmd.AddCommand(bundleVerifyCmd)
 
-	bundleApplyCmd.Flags().StringVar(&bundleApplyFrom, "from", ".wrkq", "Bundle directory path")
+	bundleApplyCmd.Flags().StringVar(&bundleApplyFrom, "from", ".wrkq", "Bundle directory or archive path")
 	bundleApplyCmd.Flags().BoolVar(&bundleApplyDryRun, "dry-run", false, "Validate without writing")
 	bundleApplyCmd.Flags().BoolVar(&bundleApplyContinue, "continue-on-error", false, "Continue after errors")
 	bundleApplyCmd.Flags().BoolVar(&bundleApplyJSON, "json", false, "Output as JSON")
 	bundleApplyCmd.Flags().BoolVar(&bundleApplyPorcelain, "porcelain", false, "Machine-readable output")
+
+	bundleVerifyCmd.Flags().StringVar(&bundleVerifyFrom, "from", ".wrkq", "Bundle directory or archive path")
+	bundleVerifyCmd.Flags().BoolVar(&bundleVerifyJSON, "json", false, "Output as JSON")
+}
+
+func runBundleVerify(cmd *cobra.Command, args []string) error {
+	if _, err := os.Stat(bundleVerifyFrom); err != nil {
+		return fmt.Errorf("bundle directory not found: %w", err)
+	}
+
+	report, err := bundle.Verify(bundleVerifyFrom)
+	if err != nil {
+		return fmt.Errorf("failed to load bundle: %w", err)
+	}
+
+	if bundleVerifyJSON {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else if report.OK {
+		fmt.Fprintf(cmd.OutOrStdout(), "✓ Bundle is consistent\n")
+		fmt.Fprintf(cmd.OutOrStdout(), "  Containers: %d\n", report.Containers)
+		fmt.Fprintf(cmd.OutOrStdout(), "  Tasks: %d\n", report.Tasks)
+		if report.Attachments > 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), "  Attachments: %d\n", report.Attachments)
+		}
+		if report.TimeEntries > 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), "  Time entries: %d\n", report.TimeEntries)
+		}
+	} else {
+		fmt.Fprintf(cmd.OutOrStderr(), "Bundle problems:\n")
+		for _, issue := range report.Issues {
+			fmt.Fprintf(cmd.OutOrStderr(), "  - [%s] %s: %s\n", issue.Check, issue.Path, issue.Message)
+		}
+	}
+
+	if !report.OK {
+		return exitError(1, fmt.Errorf("bundle verification found %d problems", len(report.Issues)))
+	}
+	return nil
 }
 
 func runBundleApply(cmd *cobra.Command, args []string) error {
@@ -103,20 +166,18 @@ func runBundleApply(cmd *cobra.Command, args []string) error {
 
 	// Validate bundle directory exists
 	if _, err := os.Stat(bundleApplyFrom); err != nil {
-		return fmt.Errorf("bundle directory not found: %w", err)
+		return fmt.Errorf("bundle not found: %w", err)
 	}
 
-	// Load bundle
-	b, err := bundle.Load(bundleApplyFrom)
+	// Load bundle (directory or .tar.gz/.zip archive)
+	b, err := bundle.LoadFrom(bundleApplyFrom)
 	if err != nil {
 		return fmt.Errorf("failed to load bundle: %w", err)
 	}
+	defer b.Close()
 
-	// Validate machine interface version
-	currentVersion := 1 // This should match the version in version.go
-	if b.Manifest.MachineInterfaceVersion != currentVersion {
-		return fmt.Errorf("bundle machine_interface_version (%d) doesn't match current version (%d)",
-			b.Manifest.MachineInterfaceVersion, currentVersion)
+	if err := b.Upgrade(); err != nil {
+		return err
 	}
 
 	result := &applyResult{
@@ -165,6 +226,25 @@ func runBundleApply(cmd *cobra.Command, args []string) error {
 				result.TasksApplied++
 			}
 		}
+
+		// Re-attach files from attachments/ once tasks are in place
+		if !bundleApplyDryRun && b.Manifest.WithAttachments && result.Success {
+			attached, err := reattachFiles(database, cfg, actorUUID, b.Dir)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("attachments: %v", err))
+				result.Success = false
+			}
+			result.AttachmentsAdded = attached
+		}
+
+		if !bundleApplyDryRun && len(b.TimeEntries) > 0 && result.Success {
+			added, err := applyTimeEntries(database, actorUUID, b.TimeEntries)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("time entries: %v", err))
+				result.Success = false
+			}
+			result.TimeEntriesAdded = added
+		}
 	} else {
 		// Transactional apply (all-or-nothing)
 		tx, err := database.Begin()
@@ -212,25 +292,29 @@ func runBundleApply(cmd *cobra.Command, args []string) error {
 		}
 
 		if !bundleApplyDryRun {
-			if err := tx.Commit(); err != nil {
-				return fmt.Errorf("failed to commit bundle apply: %w", err)
+			// Re-attach files from attachments/ as part of the same transaction
+			var written []string
+			if b.Manifest.WithAttachments {
+				attached, paths, err := reattachFilesTx(tx, ew, cfg, actorUUID, b.Dir)
+				written = paths
+				if err != nil {
+					removeAttachmentFiles(written)
+					return fmt.Errorf("failed to reattach files: %w", err)
+				}
+				result.AttachmentsAdded = attached
 			}
-		}
-	}
 
-	// Step 3: Re-attach files from attachments/<task_uuid>/
-	if !bundleApplyDryRun && b.Manifest.WithAttachments && result.Success {
-		attachmentsDir := filepath.Join(b.Dir, "attachments")
-		if _, err := os.Stat(attachmentsDir); err == nil {
-			attached, err := reattachFiles(cmd, cfg, attachmentsDir)
+			added, err := applyTimeEntriesTx(tx, ew, actorUUID, b.TimeEntries)
 			if err != nil {
-				if !bundleApplyContinue {
-					return fmt.Errorf("failed to reattach files: %w", err)
-				}
-				result.Errors = append(result.Errors, fmt.Sprintf("attachments: %v", err))
-				result.Success = false
+				removeAttachmentFiles(written)
+				return fmt.Errorf("failed to apply time entries: %w", err)
+			}
+			result.TimeEntriesAdded = added
+
+			if err := tx.Commit(); err != nil {
+				removeAttachmentFiles(written)
+				return fmt.Errorf("failed to commit bundle apply: %w", err)
 			}
-			result.AttachmentsAdded = attached
 		}
 	}
 
@@ -261,6 +345,9 @@ func runBundleApply(cmd *cobra.Command, args []string) error {
 	if result.AttachmentsAdded > 0 {
 		fmt.Fprintf(cmd.OutOrStdout(), "  Attachments: %d\n", result.AttachmentsAdded)
 	}
+	if result.TimeEntriesAdded > 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "  Time entries: %d\n", result.TimeEntriesAdded)
+	}
 
 	if len(result.Conflicts) > 0 {
 		fmt.Fprintf(cmd.OutOrStderr(), "\nConflicts detected:\n")
@@ -268,6 +355,7 @@ func runBundleApply(cmd *cobra.Command, args []string) error {
 			fmt.Fprintf(cmd.OutOrStderr(), "  - %s (%s)\n", conflict.Path, conflict.Reason)
 		}
 		if !bundleApplyJSON {
+			b.Close() // os.Exit skips deferred cleanup of extracted archives
 			os.Exit(4)
 		}
 		return fmt.Errorf("conflicts detected, use 'wrkq diff' to resolve")
@@ -282,6 +370,7 @@ func runBundleApply(cmd *cobra.Command, args []string) error {
 
 	if !result.Success {
 		if len(result.Conflicts) > 0 {
+			b.Close()
 			os.Exit(4) // Conflict exit code
 		}
 		return fmt.Errorf("bundle apply completed with errors")
@@ -425,6 +514,7 @@ type bundleTaskUpdate struct {
 	Priority    *int
 	DueAt       *string
 	StartAt     *string
+	Estimate    *int
 	Labels      *string
 	Meta        *string
 	MetaSet     bool
@@ -489,6 +579,11 @@ func applyTaskDocumentTx(tx *sql.Tx, ew *events.Writer, actorUUID string, task *
 			return err
 		}
 	}
+	if update.Estimate != nil {
+		if err := domain.ValidateEstimateMinutes(*update.Estimate); err != nil {
+			return err
+		}
+	}
 
 	var current *bundleTaskCurrent
 	var taskUUID string
@@ -568,6 +663,11 @@ func parseBundleTaskContent(content string) (*bundleTaskUpdate, error) {
 		if v, ok := fm["start_at"].(string); ok && v != "" {
 			update.StartAt = &v
 		}
+		if v, ok := fm["estimate_minutes"]; ok {
+			if m, ok := coerceInt(v); ok {
+				update.Estimate = &m
+			}
+		}
 		if v, ok := fm["labels"]; ok {
 			switch labels := v.(type) {
 			case string:
@@ -846,6 +946,11 @@ func createTaskTx(tx *sql.Tx, ew *events.Writer, actorUUID string, task *bundle.
 		startAt = *update.StartAt
 	}
 
+	estimate := interface{}(nil)
+	if update.Estimate != nil {
+		estimate = *update.Estimate
+	}
+
 	var (
 		res    sql.Result
 		errIns error
@@ -855,16 +960,16 @@ func createTaskTx(tx *sql.Tx, ew *events.Writer, actorUUID string, task *bundle.
 		res, errIns = tx.Exec(`
 			INSERT INTO tasks (
 				uuid, id, slug, title, description, project_uuid, state, priority, kind,
-				labels, meta, due_at, start_at, created_by_actor_uuid, updated_by_actor_uuid
-			) VALUES (?, '', ?, ?, ?, ?, ?, ?, 'task', ?, ?, ?, ?, ?, ?)
-		`, task.UUID, slug, title, description, projectUUID, state, priority, labels, meta, dueAt, startAt, actorUUID, actorUUID)
+				labels, meta, due_at, start_at, estimate_minutes, created_by_actor_uuid, updated_by_actor_uuid
+			) VALUES (?, '', ?, ?, ?, ?, ?, ?, 'task', ?, ?, ?, ?, ?, ?, ?)
+		`, task.UUID, slug, title, description, projectUUID, state, priority, labels, meta, dueAt, startAt, estimate, actorUUID, actorUUID)
 	} else {
 		res, errIns = tx.Exec(`
 			INSERT INTO tasks (
 				id, slug, title, description, project_uuid, state, priority, kind,
-				labels, meta, due_at, start_at, created_by_actor_uuid, updated_by_actor_uuid
-			) VALUES ('', ?, ?, ?, ?, ?, ?, 'task', ?, ?, ?, ?, ?, ?)
-		`, slug, title, description, projectUUID, state, priority, labels, meta, dueAt, startAt, actorUUID, actorUUID)
+				labels, meta, due_at, start_at, estimate_minutes, created_by_actor_uuid, updated_by_actor_uuid
+			) VALUES ('', ?, ?, ?, ?, ?, ?, 'task', ?, ?, ?, ?, ?, ?, ?)
+		`, slug, title, description, projectUUID, state, priority, labels, meta, dueAt, startAt, estimate, actorUUID, actorUUID)
 	}
 	if errIns != nil {
 		return fmt.Errorf("failed to create task %s: %w", task.Path, errIns)
@@ -924,6 +1029,9 @@ func updateTaskTx(tx *sql.Tx, ew *events.Writer, actorUUID string, current *bund
 	if update.StartAt != nil {
 		fields["start_at"] = *update.StartAt
 	}
+	if update.Estimate != nil {
+		fields["estimate_minutes"] = *update.Estimate
+	}
 	if update.Labels != nil {
 		fields["labels"] = *update.Labels
 	}
@@ -984,6 +1092,16 @@ func updateTaskTx(tx *sql.Tx, ew *events.Writer, actorUUID string, current *bund
 		}
 	}
 
+	if update.State != nil && *update.State != current.State {
+		var parentTaskUUID sql.NullString
+		if err := tx.QueryRow("SELECT parent_task_uuid FROM tasks WHERE uuid = ?", current.UUID).Scan(&parentTaskUUID); err != nil {
+			return fmt.Errorf("failed to get parent task: %w", err)
+		}
+		if err := store.RefreshParentProgressTx(tx, ew, actorUUID, parentTaskUUID.String); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -1113,57 +1231,241 @@ func conflictFromError(err error) *applyConflict {
 	return nil
 }
 
-// reattachFiles re-attaches files from the bundle's attachments directory
-func reattachFiles(cmd *cobra.Command, cfg *config.Config, attachmentsDir string) (int, error) {
-	count := 0
+// reattachFiles re-attaches bundled files in a transaction of its own. It is
+// used by partial (continue-on-error) applies, which have no enclosing tx.
+func reattachFiles(database *db.DB, cfg *config.Config, actorUUID string, bundleDir string) (int, error) {
+	tx, err := database.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	count, written, err := reattachFilesTx(tx, events.NewWriter(database.DB), cfg, actorUUID, bundleDir)
+	if err != nil {
+		removeAttachmentFiles(written)
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		removeAttachmentFiles(written)
+		return 0, fmt.Errorf("failed to commit attachments: %w", err)
+	}
 
-	// Walk through attachments/<task_uuid>/ directories
-	entries, err := os.ReadDir(attachmentsDir)
+	return count, nil
+}
+
+// applyTimeEntries adds bundled time entries in a transaction of its own,
+// for partial (continue-on-error) applies.
+func applyTimeEntries(database *db.DB, actorUUID string, entries []bundle.TimeEntry) (int, error) {
+	tx, err := database.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	count, err := applyTimeEntriesTx(tx, events.NewWriter(database.DB), actorUUID, entries)
 	if err != nil {
 		return 0, err
 	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit time entries: %w", err)
+	}
+	return count, nil
+}
 
+// applyTimeEntriesTx adds the bundled time entries the database doesn't
+// have yet, matched by UUID, so re-applying a bundle logs nothing twice. An
+// entry's actor is looked up by UUID, then by slug, and is otherwise
+// attributed to actorUUID.
+func applyTimeEntriesTx(tx *sql.Tx, ew *events.Writer, actorUUID string, entries []bundle.TimeEntry) (int, error) {
+	count := 0
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		if err := domain.ValidateTimeEntryMinutes(entry.Minutes); err != nil {
+			return count, fmt.Errorf("time entry %s: %w", entry.UUID, err)
+		}
+
+		var exists int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM time_entries WHERE uuid = ?", entry.UUID).Scan(&exists); err != nil {
+			return count, fmt.Errorf("failed to check time entry %s: %w", entry.UUID, err)
+		}
+		if exists > 0 {
 			continue
 		}
+		if err := tx.QueryRow("SELECT COUNT(*) FROM tasks WHERE uuid = ?", entry.TaskUUID).Scan(&exists); err != nil {
+			return count, fmt.Errorf("failed to check task %s: %w", entry.TaskUUID, err)
+		}
+		if exists == 0 {
+			return count, fmt.Errorf("time entry %s: task %s not found", entry.UUID, entry.TaskUUID)
+		}
 
-		taskUUID := entry.Name()
-		taskAttachDir := filepath.Join(attachmentsDir, taskUUID)
+		entryActor := actorUUID
+		var found string
+		err := tx.QueryRow("SELECT uuid FROM actors WHERE uuid = ? OR slug = ? ORDER BY uuid = ? DESC LIMIT 1",
+			entry.ActorUUID, entry.ActorSlug, entry.ActorUUID).Scan(&found)
+		if err == nil {
+			entryActor = found
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return count, fmt.Errorf("failed to resolve actor for time entry %s: %w", entry.UUID, err)
+		}
 
-		// Walk through files in this task's attachment directory
-		files, err := os.ReadDir(taskAttachDir)
-		if err != nil {
-			return count, fmt.Errorf("failed to read %s: %w", taskAttachDir, err)
+		startedAt := interface{}(nil)
+		if entry.StartedAt != "" {
+			startedAt = entry.StartedAt
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO time_entries (uuid, task_uuid, actor_uuid, minutes, note, started_at, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, entry.UUID, entry.TaskUUID, entryActor, entry.Minutes, entry.Note, startedAt, entry.CreatedAt); err != nil {
+			return count, fmt.Errorf("failed to add time entry %s: %w", entry.UUID, err)
 		}
 
-		for _, file := range files {
-			if file.IsDir() {
-				continue
-			}
+		payloadJSON, _ := json.Marshal(map[string]interface{}{
+			"time_entry_uuid": entry.UUID,
+			"minutes":         entry.Minutes,
+		})
+		payloadStr := string(payloadJSON)
+		if err := ew.LogEvent(tx, &domain.Event{
+			ActorUUID:    &actorUUID,
+			ResourceType: "task",
+			ResourceUUID: &entry.TaskUUID,
+			EventType:    "task.time_logged",
+			Payload:      &payloadStr,
+		}); err != nil {
+			return count, fmt.Errorf("failed to log task.time_logged: %w", err)
+		}
+		count++
+	}
+	return count, nil
+}
 
-			filePath := filepath.Join(taskAttachDir, file.Name())
+// reattachFilesTx copies the bundle's attachments (blob index or per-task
+// layout) into attach_dir and records them inside tx. It returns the files
+// written so callers can remove them if the transaction does not commit.
+func reattachFilesTx(tx *sql.Tx, ew *events.Writer, cfg *config.Config, actorUUID string, bundleDir string) (int, []string, error) {
+	entries, err := bundle.ListAttachments(bundleDir)
+	if err != nil {
+		return 0, nil, err
+	}
 
-			// Execute wrkq attach put
-			attachCmd := exec.Command("wrkq", "attach", "put", "t:"+taskUUID, filePath)
-			attachCmd.Env = os.Environ()
-			attachCmd.Env = append(attachCmd.Env, "WRKQ_DB_PATH="+cfg.DBPath)
-			actorIdentifier := cfg.GetActorID()
-			if actorIdentifier != "" {
-				attachCmd.Env = append(attachCmd.Env, "WRKQ_ACTOR="+actorIdentifier)
-			}
+	count := 0
+	var written []string
+	for _, entry := range entries {
+		absPath, err := reattachFileTx(tx, ew, cfg, actorUUID, entry)
+		if absPath != "" {
+			written = append(written, absPath)
+		}
+		if err != nil {
+			return count, written, fmt.Errorf("attachment %s for task %s: %w", entry.Filename, entry.TaskUUID, err)
+		}
+		if absPath != "" {
+			count++
+		}
+	}
 
-			output, err := attachCmd.CombinedOutput()
-			if err != nil {
-				return count, fmt.Errorf("wrkq attach put failed for %s: %w\nOutput: %s",
-					file.Name(), err, output)
-			}
+	return count, written, nil
+}
 
-			count++
+// reattachFileTx attaches a single bundled file to its task, mirroring
+// wrkq attach put. An attachment already present with identical content is
+// skipped so re-applying a bundle is idempotent; it returns the path written
+// under attach_dir, or "" when nothing was copied.
+func reattachFileTx(tx *sql.Tx, ew *events.Writer, cfg *config.Config, actorUUID string, entry bundle.AttachmentEntry) (string, error) {
+	var taskExists bool
+	if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM tasks WHERE uuid = ?)`, entry.TaskUUID).Scan(&taskExists); err != nil {
+		return "", fmt.Errorf("failed to look up task: %w", err)
+	}
+	if !taskExists {
+		return "", fmt.Errorf("task not found")
+	}
+
+	checksum, err := attach.Checksum(entry.Path)
+	if err != nil {
+		return "", err
+	}
+
+	var existingChecksum sql.NullString
+	err = tx.QueryRow(`
+		SELECT checksum FROM attachments WHERE task_uuid = ? AND filename = ?
+	`, entry.TaskUUID, entry.Filename).Scan(&existingChecksum)
+	if err == nil {
+		if existingChecksum.String == checksum {
+			return "", nil
 		}
+		return "", fmt.Errorf("attachment with filename %q already exists with different content", entry.Filename)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("failed to check existing attachments: %w", err)
 	}
 
-	return count, nil
+	size, err := attach.GetFileSize(entry.Path)
+	if err != nil {
+		return "", err
+	}
+	if err := attach.ValidateSize(size, int64(cfg.AttachmentsMaxMB)); err != nil {
+		return "", err
+	}
+
+	mimeType := entry.MimeType
+	if mimeType == "" {
+		mimeType = attach.DetectMimeType(entry.Filename)
+	}
+
+	if err := attach.EnsureTaskDir(cfg.AttachDir, entry.TaskUUID); err != nil {
+		return "", err
+	}
+	relativePath := attach.RelativePath(entry.TaskUUID, entry.Filename)
+	absPath := attach.AbsolutePath(cfg.AttachDir, relativePath)
+	if _, err := os.Stat(absPath); err == nil {
+		return "", fmt.Errorf("file already exists at %s", absPath)
+	}
+
+	size, checksum, err = attach.CopyFile(entry.Path, absPath)
+	if err != nil {
+		// Don't leave a partial copy behind to block the next apply
+		os.Remove(absPath)
+		return "", err
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO attachments (id, task_uuid, filename, relative_path, mime_type, size_bytes, checksum, created_by_actor_uuid)
+		VALUES ('', ?, ?, ?, ?, ?, ?, ?)
+	`, entry.TaskUUID, entry.Filename, relativePath, mimeType, size, checksum, actorUUID)
+	if err != nil {
+		return absPath, fmt.Errorf("failed to insert attachment: %w", err)
+	}
+
+	var attachUUID, attachID string
+	lastID, _ := result.LastInsertId()
+	if err := tx.QueryRow(`SELECT uuid, id FROM attachments WHERE rowid = ?`, lastID).Scan(&attachUUID, &attachID); err != nil {
+		return absPath, fmt.Errorf("failed to get attachment ID: %w", err)
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"attachment_id": attachID,
+		"filename":      entry.Filename,
+		"size_bytes":    size,
+		"mime_type":     mimeType,
+		"source":        "bundle",
+	})
+	payloadStr := string(payload)
+	if err := ew.LogEvent(tx, &domain.Event{
+		ActorUUID:    &actorUUID,
+		ResourceType: "attachment",
+		ResourceUUID: &attachUUID,
+		EventType:    "attachment.created",
+		Payload:      &payloadStr,
+	}); err != nil {
+		return absPath, fmt.Errorf("failed to log event: %w", err)
+	}
+
+	return absPath, nil
+}
+
+// removeAttachmentFiles deletes files copied by reattachFilesTx after the
+// enclosing transaction was rolled back.
+func removeAttachmentFiles(paths []string) {
+	for _, path := range paths {
+		os.Remove(path)
+	}
 }
 
 // conflictError represents an etag mismatch or merge conflict
@@ -87,6 +87,7 @@ func runCat(app *appctx.App, cmd *cobra.Command, args []string) error {
 		AssigneeUUID         *string         `json:"assignee_uuid,omitempty"`
 		StartAt              *string         `json:"start_at,omitempty"`
 		DueAt                *string         `json:"due_at,omitempty"`
+		EstimateMinutes      *int64          `json:"estimate_minutes,omitempty"`
 		Labels               *string         `json:"labels,omitempty"`
 		Meta                 json.RawMessage `json:"meta"`
 		Description          string          `json:"description"`
@@ -98,6 +99,7 @@ func runCat(app *appctx.App, cmd *cobra.Command, args []string) error {
 		CPSessionID          *string         `json:"cp_session_id,omitempty"`
 		SDKSessionID         *string         `json:"sdk_session_id,omitempty"`
 		RunStatus            *string         `json:"run_status,omitempty"`
+		SubtaskProgress      json.RawMessage `json:"subtask_progress,omitempty"`
 		Etag                 int64           `json:"etag"`
 		CreatedAt            string          `json:"created_at"`
 		UpdatedAt            string          `json:"updated_at"`
@@ -113,7 +115,8 @@ func runCat(app *appctx.App, cmd *cobra.Command, args []string) error {
 	var tasks []Task
 	taskCount := 0
 
-	// Process each argument
+	// Process each argument, resolving each actor and project once
+	lookups := newLookupCache(database)
 	for _, arg := range args {
 		taskUUID, _, err := selectors.ResolveTask(database, applyProjectRootToSelector(app.Config, arg, false))
 		if err != nil {
@@ -126,6 +129,8 @@ func runCat(app *appctx.App, cmd *cobra.Command, args []string) error {
 		var startAt, dueAt, labels, meta, completedAt, archivedAt *string
 		var requestedBy, assignedProject, acknowledgedAt, resolution *string
 		var cpProjectID, cpWorkItemID, cpRunID, cpSessionID, sdkSessionID, runStatus *string
+		var subtaskProgress *string
+		var estimateMinutes *int64
 		var parentTaskUUID, assigneeActorUUID *string
 		var createdAt, updatedAt string
 		var etag int64
@@ -139,7 +144,7 @@ func runCat(app *appctx.App, cmd *cobra.Command, args []string) error {
 			       created_at, updated_at, completed_at, archived_at,
 			       acknowledged_at, resolution,
 			       cp_project_id, cp_work_item_id, cp_run_id, cp_session_id, sdk_session_id, run_status,
-			       created_by_actor_uuid, updated_by_actor_uuid
+			       subtask_progress, estimate_minutes, created_by_actor_uuid, updated_by_actor_uuid
 			FROM tasks WHERE uuid = ?
 		`, taskUUID).Scan(
 			&id, &slug, &title, &projectUUID, &requestedBy, &assignedProject, &state, &priority,
@@ -148,20 +153,18 @@ func runCat(app *appctx.App, cmd *cobra.Command, args []string) error {
 			&createdAt, &updatedAt, &completedAt, &archivedAt,
 			&acknowledgedAt, &resolution,
 			&cpProjectID, &cpWorkItemID, &cpRunID, &cpSessionID, &sdkSessionID, &runStatus,
-			&createdByUUID, &updatedByUUID,
+			&subtaskProgress, &estimateMinutes, &createdByUUID, &updatedByUUID,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to get task: %w", err)
 		}
 
 		// Get actor slugs
-		var createdBySlug, updatedBySlug string
-		database.QueryRow("SELECT slug FROM actors WHERE uuid = ?", createdByUUID).Scan(&createdBySlug)
-		database.QueryRow("SELECT slug FROM actors WHERE uuid = ?", updatedByUUID).Scan(&updatedBySlug)
+		createdBySlug, _ := lookups.actorSlug(createdByUUID)
+		updatedBySlug, _ := lookups.actorSlug(updatedByUUID)
 
 		// Get project info
-		var projectID string
-		database.QueryRow("SELECT id FROM containers WHERE uuid = ?", projectUUID).Scan(&projectID)
+		projectID := lookups.containerID(projectUUID)
 
 		// Get task path from v_task_paths view
 		var taskPath string
@@ -170,8 +173,7 @@ func runCat(app *appctx.App, cmd *cobra.Command, args []string) error {
 		// Get parent task ID if parent exists
 		var parentTaskID *string
 		if parentTaskUUID != nil {
-			var ptID string
-			if err := database.QueryRow("SELECT id FROM tasks WHERE uuid = ?", *parentTaskUUID).Scan(&ptID); err == nil {
+			if ptID, ok := lookups.taskID(*parentTaskUUID); ok {
 				parentTaskID = &ptID
 			}
 		}
@@ -179,8 +181,7 @@ func runCat(app *appctx.App, cmd *cobra.Command, args []string) error {
 		// Get assignee slug if assignee exists
 		var assigneeSlug *string
 		if assigneeActorUUID != nil {
-			var aSlug string
-			if err := database.QueryRow("SELECT slug FROM actors WHERE uuid = ?", *assigneeActorUUID).Scan(&aSlug); err == nil {
+			if aSlug, ok := lookups.actorSlug(*assigneeActorUUID); ok {
 				assigneeSlug = &aSlug
 			}
 		}
@@ -208,6 +209,7 @@ func runCat(app *appctx.App, cmd *cobra.Command, args []string) error {
 			AssigneeUUID:         assigneeActorUUID,
 			StartAt:              startAt,
 			DueAt:                dueAt,
+			EstimateMinutes:      estimateMinutes,
 			Labels:               labels,
 			Meta:                 json.RawMessage(metaValue),
 			Description:          description,
@@ -227,6 +229,9 @@ func runCat(app *appctx.App, cmd *cobra.Command, args []string) error {
 			CreatedBy:            createdBySlug,
 			UpdatedBy:            updatedBySlug,
 		}
+		if subtaskProgress != nil {
+			task.SubtaskProgress = json.RawMessage(*subtaskProgress)
+		}
 
 		// Include comments by default (unless excluded)
 		if !catExcludeComments {
@@ -385,6 +390,9 @@ func runCat(app *appctx.App, cmd *cobra.Command, args []string) error {
 				if task.DueAt != nil {
 					fmt.Fprintf(cmd.OutOrStdout(), "due_at: %s\n", *task.DueAt)
 				}
+				if task.EstimateMinutes != nil {
+					fmt.Fprintf(cmd.OutOrStdout(), "estimate_minutes: %d\n", *task.EstimateMinutes)
+				}
 				if task.Labels != nil && *task.Labels != "" {
 					fmt.Fprintf(cmd.OutOrStdout(), "labels: %s\n", *task.Labels)
 				}
@@ -130,7 +130,11 @@ func runCommentRm(app *appctx.App, cmd *cobra.Command, args []string) error {
 		eventWriter := events.NewWriter(database.DB)
 
 		if commentRmPurge {
-			// Hard delete
+			// Hard delete; replies become top-level comments
+			_, err = tx.Exec("UPDATE comments SET parent_comment_uuid = NULL WHERE parent_comment_uuid = ?", commentUUID)
+			if err != nil {
+				return fmt.Errorf("failed to detach replies to comment %s: %w", commentID, err)
+			}
 			_, err = tx.Exec("DELETE FROM comments WHERE uuid = ?", commentUUID)
 			if err != nil {
 				return fmt.Errorf("failed to purge comment %s: %w", commentID, err)
@@ -8,6 +8,7 @@ import (
 	"github.com/lherron/wrkq/internal/actors"
 	"github.com/lherron/wrkq/internal/config"
 	"github.com/lherron/wrkq/internal/db"
+	"github.com/lherron/wrkq/internal/render"
 	"github.com/spf13/cobra"
 )
 
@@ -17,6 +18,34 @@ var configAdmCmd = &cobra.Command{
 	Long:  `Commands for inspecting and validating configuration. These are administrative operations.`,
 }
 
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Show every setting with its effective value and source",
+	Long: `Lists each configuration setting, its effective value, and where the value
+came from: a command-line flag, an environment variable, .env.local, the
+selected --profile, the config file (~/.config/wrkq/config.yaml unless
+--config or WRKQ_CONFIG names another), or the default. Secrets are masked.`,
+	RunE: runConfigList,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the effective value of a setting",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigGet,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Write a setting to the config file",
+	Long: `Writes key: value to ~/.config/wrkq/config.yaml (or the --config file),
+creating it if needed. With --profile the value goes into that profile.
+Environment variables still take precedence over the file; a warning is
+printed when one is set for the key.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runConfigSet,
+}
+
 var configDoctorCmd = &cobra.Command{
 	Use:   "doctor",
 	Short: "Show effective configuration and validate settings",
@@ -27,6 +56,7 @@ that all required settings are correctly configured.`,
 
 var (
 	configDoctorJSON bool
+	configListJSON   bool
 )
 
 type configValue struct {
@@ -44,8 +74,89 @@ type configDoctorReport struct {
 func init() {
 	rootAdmCmd.AddCommand(configAdmCmd)
 	configAdmCmd.AddCommand(configDoctorCmd)
+	configAdmCmd.AddCommand(configListCmd)
+	configAdmCmd.AddCommand(configGetCmd)
+	configAdmCmd.AddCommand(configSetCmd)
 
 	configDoctorCmd.Flags().BoolVar(&configDoctorJSON, "json", false, "Output as JSON")
+	configListCmd.Flags().BoolVar(&configListJSON, "json", false, "Output as JSON")
+}
+
+// resolvedSettings resolves the configuration, applying the --db flag and
+// masking secrets.
+func resolvedSettings(cmd *cobra.Command) ([]config.Setting, error) {
+	settings, err := config.Resolve()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	for i := range settings {
+		s := &settings[i]
+		if s.Key == "db_path" {
+			if dbFlag := cmd.Flag("db"); dbFlag != nil && dbFlag.Changed {
+				s.Value = dbFlag.Value.String()
+				s.Source = "command-line flag --db"
+			}
+		}
+		if s.Secret {
+			s.Value = config.Mask(s.Value)
+		}
+	}
+	return settings, nil
+}
+
+func runConfigList(cmd *cobra.Command, args []string) error {
+	settings, err := resolvedSettings(cmd)
+	if err != nil {
+		return err
+	}
+
+	if configListJSON {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(settings)
+	}
+
+	var rows [][]string
+	for _, s := range settings {
+		rows = append(rows, []string{s.Key, s.Value, s.Source})
+	}
+	r := render.NewRenderer(cmd.OutOrStdout(), render.Options{Format: render.FormatTable})
+	return r.RenderTable([]string{"Key", "Value", "Source"}, rows)
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	if _, ok := config.LookupKey(args[0]); !ok {
+		return fmt.Errorf("unknown config key: %s", args[0])
+	}
+	settings, err := resolvedSettings(cmd)
+	if err != nil {
+		return err
+	}
+	for _, s := range settings {
+		if s.Key == args[0] {
+			fmt.Fprintln(cmd.OutOrStdout(), s.Value)
+			return nil
+		}
+	}
+	return nil
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	key, value := args[0], args[1]
+	path, err := config.SetFileValue(key, value)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Set %s in %s\n", key, path)
+
+	k, _ := config.LookupKey(key)
+	for _, env := range k.Env {
+		if os.Getenv(env) != "" {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %s is set and overrides the config file\n", env)
+			break
+		}
+	}
+	return nil
 }
 
 func runConfigDoctor(cmd *cobra.Command, args []string) error {
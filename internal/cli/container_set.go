@@ -9,6 +9,7 @@ import (
 	"github.com/lherron/wrkq/internal/cli/appctx"
 	"github.com/lherron/wrkq/internal/selectors"
 	"github.com/lherron/wrkq/internal/store"
+	"github.com/lherron/wrkq/internal/webhooks"
 	"github.com/spf13/cobra"
 )
 
@@ -20,15 +21,32 @@ var containerSetCmd = &cobra.Command{
 Examples:
   wrkq container set inbox --webhook-urls '["http://localhost/hook/{ticket_id}"]'
   wrkq container set P-00001 --webhook-url http://localhost/hook/{ticket_id}
+  wrkq container set inbox --webhook-secret "$WEBHOOK_SECRET"
+  wrkq container set inbox --webhook-url 'http://localhost/hook/{project_id}/{state}?to={assignee}'
+  wrkq container set portal --subtask-rollup
+
+URL placeholders: {ticket_id}, {ticket_uuid}, {project_id}, {project_uuid},
+{state} and {assignee} (actor slug, empty when unassigned). Unknown
+placeholders are sent as-is, or rejected when webhook_strict_templates is set.
+
+A webhook secret makes dispatch sign each request with HMAC-SHA256
+(X-Wrkq-Signature); descendants without their own secret inherit it.
+Pass --webhook-secret "" to stop signing.
+
+With --subtask-rollup, parent tasks in the container keep a subtask_progress
+summary ({"done", "total"}) that is refreshed, bumping the parent's etag,
+whenever one of their subtasks changes state.
 `,
 	Args: cobra.ExactArgs(1),
 	RunE: appctx.WithApp(appctx.WithActor(), runContainerSet),
 }
 
 var (
-	containerSetWebhookURLs string
-	containerSetWebhookURL  []string
-	containerSetIfMatch     int64
+	containerSetWebhookURLs   string
+	containerSetWebhookURL    []string
+	containerSetWebhookSecret string
+	containerSetSubtaskRollup bool
+	containerSetIfMatch       int64
 )
 
 func init() {
@@ -36,6 +54,8 @@ func init() {
 
 	containerSetCmd.Flags().StringVar(&containerSetWebhookURLs, "webhook-urls", "", "Webhook URLs JSON array")
 	containerSetCmd.Flags().StringArrayVar(&containerSetWebhookURL, "webhook-url", nil, "Webhook URL (repeatable)")
+	containerSetCmd.Flags().StringVar(&containerSetWebhookSecret, "webhook-secret", "", "Secret for signing webhooks (empty clears)")
+	containerSetCmd.Flags().BoolVar(&containerSetSubtaskRollup, "subtask-rollup", false, "Keep subtask progress on parent tasks (--subtask-rollup=false turns it off)")
 	containerSetCmd.Flags().Int64Var(&containerSetIfMatch, "if-match", 0, "Conditional update (etag)")
 }
 
@@ -53,17 +73,39 @@ func runContainerSet(app *appctx.App, cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	if !hasWebhookURLs {
+	for _, u := range webhookURLs {
+		unknown := webhooks.UnknownPlaceholders(u)
+		if len(unknown) == 0 {
+			continue
+		}
+		if app.Config.WebhookStrictTemplates {
+			return fmt.Errorf("webhook url %s has unknown placeholder(s) %s", u, strings.Join(unknown, ", "))
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Warning: webhook url %s has unknown placeholder(s) %s; they will be sent as-is\n", u, strings.Join(unknown, ", "))
+	}
+	hasWebhookSecret := cmd.Flags().Changed("webhook-secret")
+	hasSubtaskRollup := cmd.Flags().Changed("subtask-rollup")
+	if !hasWebhookURLs && !hasWebhookSecret && !hasSubtaskRollup {
 		return fmt.Errorf("no updates specified")
 	}
 
-	payload, err := json.Marshal(webhookURLs)
-	if err != nil {
-		return fmt.Errorf("failed to encode webhook urls: %w", err)
+	fields := map[string]interface{}{}
+	if hasWebhookURLs {
+		payload, err := json.Marshal(webhookURLs)
+		if err != nil {
+			return fmt.Errorf("failed to encode webhook urls: %w", err)
+		}
+		fields["webhook_urls"] = string(payload)
 	}
-
-	fields := map[string]interface{}{
-		"webhook_urls": string(payload),
+	if hasWebhookSecret {
+		if containerSetWebhookSecret == "" {
+			fields["webhook_secret"] = nil
+		} else {
+			fields["webhook_secret"] = containerSetWebhookSecret
+		}
+	}
+	if hasSubtaskRollup {
+		fields["subtask_rollup"] = containerSetSubtaskRollup
 	}
 
 	s := store.New(database)
@@ -73,7 +115,19 @@ func runContainerSet(app *appctx.App, cmd *cobra.Command, args []string) error {
 	}
 
 	fmt.Fprintf(cmd.OutOrStdout(), "Updated container: %s\n", containerPath)
-	fmt.Fprintf(cmd.OutOrStdout(), "Webhook URLs: %d\n", len(webhookURLs))
+	if hasWebhookURLs {
+		fmt.Fprintf(cmd.OutOrStdout(), "Webhook URLs: %d\n", len(webhookURLs))
+	}
+	if hasWebhookSecret {
+		if containerSetWebhookSecret == "" {
+			fmt.Fprintln(cmd.OutOrStdout(), "Webhook secret: cleared")
+		} else {
+			fmt.Fprintln(cmd.OutOrStdout(), "Webhook secret: set")
+		}
+	}
+	if hasSubtaskRollup {
+		fmt.Fprintf(cmd.OutOrStdout(), "Subtask rollup: %t\n", containerSetSubtaskRollup)
+	}
 	return nil
 }
 
@@ -1,17 +1,22 @@
 package cli
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
-	"os/exec"
-	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/lherron/wrkq/internal/actors"
@@ -21,6 +26,7 @@ import (
 	"github.com/lherron/wrkq/internal/db"
 	"github.com/lherron/wrkq/internal/domain"
 	"github.com/lherron/wrkq/internal/events"
+	"github.com/lherron/wrkq/internal/id"
 	"github.com/lherron/wrkq/internal/paths"
 	"github.com/lherron/wrkq/internal/selectors"
 	"github.com/lherron/wrkq/internal/store"
@@ -33,10 +39,73 @@ type DaemonOptions struct {
 	Unix   string
 	Token  string
 	DBPath string
+	// ConfigFile and Profile select the config file and profile, as --config
+	// and --profile do for wrkq and wrkqadm.
+	ConfigFile string
+	Profile    string
+
+	// WatchPollInterval is how often /v1/events/watch polls event_log (default 1s).
+	WatchPollInterval time.Duration
+	// WatchMaxDuration caps how long a single watch stream stays open (default 5m).
+	WatchMaxDuration time.Duration
+
+	// ReadTimeout, WriteTimeout and IdleTimeout are passed to http.Server; zero
+	// disables the timeout. /v1/events/watch clears its own write deadline, so
+	// streams aren't cut off after WriteTimeout.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// MaxBodyBytes limits request bodies; larger payloads fail with 413. Zero means no limit.
+	MaxBodyBytes int64
+	// ShutdownTimeout is the grace period for in-flight requests on shutdown (default 10s).
+	ShutdownTimeout time.Duration
+	// IdempotencyTTL is how long Idempotency-Key responses are replayed (default 24h).
+	IdempotencyTTL time.Duration
+
+	// MaxConcurrentWrites caps how many write handlers run at once; further
+	// writes queue for up to WriteQueueTimeout (default 5s) and then fail with
+	// 429. Zero means unlimited.
+	MaxConcurrentWrites int
+	WriteQueueTimeout   time.Duration
+	// WriteRateLimit limits write requests per second per auth token, with
+	// bursts of up to WriteRateBurst. Zero disables rate limiting.
+	WriteRateLimit float64
+	WriteRateBurst int
+
+	// SyncWebhooks delivers webhooks inline before a write returns, instead of
+	// from the background dispatcher. Deliveries are queued either way.
+	SyncWebhooks bool
+	// WebhookPollInterval is how often the dispatcher checks the queue for due
+	// retries (default 5s).
+	WebhookPollInterval time.Duration
+
+	// LogLevel is the minimum level of the JSON request log written to
+	// stderr: debug, info (the default), warn, error or off.
+	LogLevel string
+
+	// CORSOrigins lists the browser origins allowed to call the API, or "*"
+	// for any; empty disables CORS. CORSMethods and CORSHeaders override the
+	// methods and request headers preflights allow (by default, all the API
+	// uses), and CORSCredentials lets browsers send credentials.
+	CORSOrigins     []string
+	CORSMethods     []string
+	CORSHeaders     []string
+	CORSCredentials bool
+
+	// SelectorCacheSize is how many resolved project and assignee selectors
+	// are kept across requests. Zero, the default, disables the cache.
+	SelectorCacheSize int
 }
 
-// ServeDaemon starts the wrkqd daemon.
-func ServeDaemon(opts DaemonOptions) error {
+// ServeDaemon starts the wrkqd daemon and blocks until ctx is cancelled or the
+// listener fails.
+func ServeDaemon(ctx context.Context, opts DaemonOptions) error {
+	if opts.ConfigFile != "" {
+		config.File = opts.ConfigFile
+	}
+	if opts.Profile != "" {
+		config.Profile = opts.Profile
+	}
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
@@ -45,6 +114,11 @@ func ServeDaemon(opts DaemonOptions) error {
 	if opts.DBPath != "" {
 		cfg.DBPath = opts.DBPath
 	}
+	logLevel, logging, err := parseLogLevel(opts.LogLevel)
+	if err != nil {
+		return err
+	}
+	webhooks.StrictTemplates = cfg.WebhookStrictTemplates
 
 	database, err := db.Open(cfg.DBPath)
 	if err != nil {
@@ -57,9 +131,21 @@ func ServeDaemon(opts DaemonOptions) error {
 	}
 
 	server := &daemonServer{
-		db:    database,
-		cfg:   cfg,
-		token: opts.Token,
+		db:                database,
+		cfg:               cfg,
+		token:             opts.Token,
+		watchPollInterval: opts.WatchPollInterval,
+		watchMaxDuration:  opts.WatchMaxDuration,
+		maxBodyBytes:      opts.MaxBodyBytes,
+		idempotencyTTL:    opts.IdempotencyTTL,
+		cors:              newCORSPolicy(opts),
+		selectorCache:     newSelectorCache(database, opts.SelectorCacheSize),
+	}
+	if opts.MaxConcurrentWrites > 0 || opts.WriteRateLimit > 0 {
+		server.writeLimiter = newWriteLimiter(opts.MaxConcurrentWrites, opts.WriteQueueTimeout, opts.WriteRateLimit, opts.WriteRateBurst)
+	}
+	if logging {
+		server.logger = slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
 	}
 
 	mux := http.NewServeMux()
@@ -67,34 +153,126 @@ func ServeDaemon(opts DaemonOptions) error {
 
 	httpServer := &http.Server{
 		Handler:      mux,
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		ReadTimeout:  opts.ReadTimeout,
+		WriteTimeout: opts.WriteTimeout,
+		IdleTimeout:  opts.IdleTimeout,
 	}
 
+	var listener net.Listener
 	if opts.Unix != "" {
 		_ = os.Remove(opts.Unix)
-		listener, err := net.Listen("unix", opts.Unix)
+		listener, err = net.Listen("unix", opts.Unix)
 		if err != nil {
 			database.Close()
 			return fmt.Errorf("failed to listen on unix socket: %w", err)
 		}
-		defer listener.Close()
-		return httpServer.Serve(listener)
+	} else {
+		addr := opts.Addr
+		if addr == "" {
+			addr = "127.0.0.1:7171"
+		}
+		listener, err = net.Listen("tcp", addr)
+		if err != nil {
+			database.Close()
+			return fmt.Errorf("failed to listen on %s: %w", addr, err)
+		}
+	}
+
+	grace := opts.ShutdownTimeout
+	if grace <= 0 {
+		grace = defaultShutdownTimeout
+	}
+
+	var dispatcher *webhooks.Dispatcher
+	if !opts.SyncWebhooks {
+		dispatcher = webhooks.StartDispatcher(database, opts.WebhookPollInterval)
+	}
+
+	return serveWithShutdown(ctx, httpServer, listener, grace, func() {
+		if dispatcher != nil {
+			dispatcher.Stop()
+		}
+		database.Close()
+	})
+}
+
+const defaultShutdownTimeout = 10 * time.Second
+
+// serveWithShutdown serves srv on ln until ctx is cancelled, then shuts down
+// gracefully. Request contexts are cancelled up front so long-lived streams
+// (events/watch) end immediately, while in-flight writes run to completion:
+// handlers do not bind database work to the request context, so their
+// transactions either commit or roll back on their own. onStop runs only after
+// every handler has returned. A clean shutdown returns nil.
+func serveWithShutdown(ctx context.Context, srv *http.Server, ln net.Listener, grace time.Duration, onStop func()) error {
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
+	srv.BaseContext = func(net.Listener) context.Context { return baseCtx }
+
+	var inflight sync.WaitGroup
+	next := srv.Handler
+	srv.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inflight.Add(1)
+		defer inflight.Done()
+		next.ServeHTTP(w, r)
+	})
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-serveErr:
+		cancelBase()
+		inflight.Wait()
+		onStop()
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
 	}
 
-	addr := opts.Addr
-	if addr == "" {
-		addr = "127.0.0.1:7171"
+	cancelBase()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	shutdownErr := srv.Shutdown(shutdownCtx)
+	if shutdownErr != nil {
+		_ = srv.Close()
 	}
-	httpServer.Addr = addr
+	inflight.Wait()
+	onStop()
 
-	return httpServer.ListenAndServe()
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	if shutdownErr != nil {
+		return fmt.Errorf("shutdown grace period exceeded: %w", shutdownErr)
+	}
+	return nil
 }
 
 type daemonServer struct {
 	db    *db.DB
 	cfg   *config.Config
 	token string
+
+	watchPollInterval time.Duration
+	watchMaxDuration  time.Duration
+	maxBodyBytes      int64
+	idempotencyTTL    time.Duration
+	writeLimiter      *writeLimiter
+	cors              corsPolicy
+	selectorCache     *selectorCache
+
+	// metrics is created by registerRoutes if not already set
+	metrics *daemonMetrics
+	// logger receives the request log; nil disables it
+	logger *slog.Logger
+
+	// routes is filled in by registerRoutes
+	routes []daemonRoute
 }
 
 // Task mirrors wrkq cat --json output with additional deleted_at metadata.
@@ -126,6 +304,17 @@ type Task struct {
 	UpdatedBy      string     `json:"updated_by"`
 	Comments       []Comment  `json:"comments,omitempty"`
 	Relations      []Relation `json:"relations,omitempty"`
+	// Reactions is only loaded when requested (tasks/get include_reactions)
+	Reactions []domain.ReactionSummary `json:"reactions,omitempty"`
+	// SubtaskProgress is kept on parents in containers with subtask_rollup on
+	SubtaskProgress *domain.SubtaskProgress `json:"subtask_progress,omitempty"`
+	// Subtasks is only loaded when requested (tasks/get include_subtasks, or
+	// tasks/subtasks with recursive)
+	Subtasks []*Task `json:"subtasks,omitempty"`
+	// EstimateMinutes is the planned effort; TimeLogged is only loaded when
+	// requested (tasks/get include_time)
+	EstimateMinutes *int64            `json:"estimate_minutes,omitempty"`
+	TimeLogged      *domain.TimeTotal `json:"time_logged,omitempty"`
 }
 
 type Comment struct {
@@ -137,53 +326,172 @@ type Comment struct {
 }
 
 func (s *daemonServer) registerRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("/v1/health", s.withAuth(s.handleHealth))
-	mux.HandleFunc("/v1/containers/tree", s.withAuth(s.handleContainersTree))
-
-	mux.HandleFunc("/v1/tasks/list", s.withAuth(s.handleTasksList))
-	mux.HandleFunc("/v1/tasks/get", s.withAuth(s.handleTasksGet))
-	mux.HandleFunc("/v1/tasks/create", s.withAuth(s.handleTasksCreate))
-	mux.HandleFunc("/v1/tasks/update", s.withAuth(s.handleTasksUpdate))
-	mux.HandleFunc("/v1/tasks/archive", s.withAuth(s.handleTasksArchive))
-	mux.HandleFunc("/v1/tasks/restore", s.withAuth(s.handleTasksRestore))
+	if s.metrics == nil {
+		s.metrics = newDaemonMetrics()
+	}
+	s.route(mux, http.MethodGet, "/v1/health", "Liveness check", s.withAuth(s.handleHealth))
+	s.route(mux, http.MethodGet, "/v1/metrics", "Request, latency and webhook metrics in Prometheus text format", s.withAuth(s.handleMetrics))
+	s.route(mux, http.MethodGet, "/v1/version", "Build version, machine interface version and features", s.withAuth(s.handleVersion))
+	s.route(mux, http.MethodGet, "/v1/routes", "List the registered endpoints", s.withAuth(s.handleRoutes))
+	s.route(mux, http.MethodGet, "/v1/openapi.json", "OpenAPI description of the API", s.withAuth(s.handleOpenAPI))
+	s.route(mux, http.MethodPost, "/v1/resolve", "Resolve a batch of task and container selectors", s.withAuth(s.handleResolve))
+	s.route(mux, http.MethodPost, "/v1/containers/tree", "Container and task tree", s.withAuth(s.handleContainersTree))
+	s.route(mux, http.MethodPost, "/v1/containers/activity", "Recent activity under a container", s.withAuth(s.handleContainersActivity))
+	s.route(mux, http.MethodPost, "/v1/containers/archive", "Archive a container, optionally with everything below it", s.withAuth(s.withWriteLimit(s.handleContainersArchive)))
+	s.route(mux, http.MethodPost, "/v1/containers/move", "Move a container under another parent or to the root", s.withAuth(s.withWriteLimit(s.handleContainersMove)))
+	s.route(mux, http.MethodPost, "/v1/containers/rename", "Change a container's slug", s.withAuth(s.withWriteLimit(s.handleContainersRename)))
+	s.route(mux, http.MethodPost, "/v1/containers/restore", "Restore an archived container and what was archived with it", s.withAuth(s.withWriteLimit(s.handleContainersRestore)))
+
+	s.route(mux, http.MethodPost, "/v1/tasks/list", "List tasks matching filters", s.withAuth(s.handleTasksList))
+	s.route(mux, http.MethodPost, "/v1/tasks/export", "Stream matching tasks as CSV", s.withAuth(s.handleTasksExport))
+	s.route(mux, http.MethodGet, "/v1/tasks/calendar.ics", "iCalendar feed of tasks with due dates", s.withQueryToken(s.withAuth(s.handleTasksCalendar)))
+	s.route(mux, http.MethodPost, "/v1/tasks/count", "Count matching tasks, optionally grouped", s.withAuth(s.handleTasksCount))
+	s.route(mux, http.MethodPost, "/v1/tasks/ready", "Open tasks with no unfinished blockers", s.withAuth(s.handleTasksReady))
+	s.route(mux, http.MethodPost, "/v1/tasks/search", "Full-text task search", s.withAuth(s.handleTasksSearch))
+	s.route(mux, http.MethodPost, "/v1/tasks/get", "Fetch one task", s.withAuth(s.handleTasksGet))
+	s.route(mux, http.MethodPost, "/v1/tasks/batch_get", "Fetch many tasks, keyed by selector", s.withAuth(s.handleTasksBatchGet))
+	s.route(mux, http.MethodPost, "/v1/tasks/subtasks", "List a task's subtasks", s.withAuth(s.handleTasksSubtasks))
+	s.route(mux, http.MethodPost, "/v1/tasks/history", "A task's change history", s.withAuth(s.handleTasksHistory))
+	s.route(mux, http.MethodPost, "/v1/tasks/create", "Create a task", s.withAuth(s.withIdempotency(s.withWriteLimit(s.handleTasksCreate))))
+	s.route(mux, http.MethodPost, "/v1/tasks/create_from_template", "Create a task from a template", s.withAuth(s.withIdempotency(s.withWriteLimit(s.handleTasksCreateFromTemplate))))
+	s.route(mux, http.MethodPost, "/v1/tasks/bulk_create", "Create many tasks", s.withAuth(s.withWriteLimit(s.handleTasksBulkCreate)))
+	s.route(mux, http.MethodPost, "/v1/tasks/update", "Update task fields", s.withAuth(s.withIdempotency(s.withWriteLimit(s.handleTasksUpdate))))
+	s.route(mux, http.MethodPost, "/v1/tasks/reassign", "Move unfinished tasks between assignees", s.withAuth(s.withWriteLimit(s.handleTasksReassign)))
+	s.route(mux, http.MethodPost, "/v1/tasks/archive", "Archive a task", s.withAuth(s.withWriteLimit(s.handleTasksArchive)))
+	s.route(mux, http.MethodPost, "/v1/tasks/restore", "Restore an archived or deleted task", s.withAuth(s.withWriteLimit(s.handleTasksRestore)))
+
+	s.route(mux, http.MethodPost, "/v1/sections/list", "List a project's sections", s.withAuth(s.handleSectionsList))
+	s.route(mux, http.MethodPost, "/v1/sections/create", "Create a section", s.withAuth(s.withWriteLimit(s.handleSectionsCreate)))
+	s.route(mux, http.MethodPost, "/v1/sections/update", "Update a section", s.withAuth(s.withWriteLimit(s.handleSectionsUpdate)))
+	s.route(mux, http.MethodPost, "/v1/sections/reorder", "Reorder a project's sections", s.withAuth(s.withWriteLimit(s.handleSectionsReorder)))
+
+	s.route(mux, http.MethodPost, "/v1/views/list", "List saved views", s.withAuth(s.handleViewsList))
+	s.route(mux, http.MethodPost, "/v1/views/create", "Save a tasks/list filter as a view", s.withAuth(s.withWriteLimit(s.handleViewsCreate)))
+	s.route(mux, http.MethodPost, "/v1/views/delete", "Delete a saved view", s.withAuth(s.withWriteLimit(s.handleViewsDelete)))
+	s.route(mux, http.MethodPost, "/v1/views/run", "Run a saved view", s.withAuth(s.handleViewsRun))
+
+	s.route(mux, http.MethodPost, "/v1/templates/list", "List task templates", s.withAuth(s.handleTemplatesList))
+	s.route(mux, http.MethodPost, "/v1/templates/create", "Create a task template", s.withAuth(s.withWriteLimit(s.handleTemplatesCreate)))
+
+	s.route(mux, http.MethodPost, "/v1/comments/list", "List a task's comments", s.withAuth(s.handleCommentsList))
+	s.route(mux, http.MethodPost, "/v1/comments/thread", "A task's comments as a reply tree", s.withAuth(s.handleCommentsThread))
+	s.route(mux, http.MethodPost, "/v1/comments/create", "Add a comment", s.withAuth(s.withIdempotency(s.withWriteLimit(s.handleCommentsCreate))))
+	s.route(mux, http.MethodPost, "/v1/comments/update", "Edit a comment", s.withAuth(s.withWriteLimit(s.handleCommentsUpdate)))
+	s.route(mux, http.MethodPost, "/v1/comments/delete", "Soft-delete a comment", s.withAuth(s.withWriteLimit(s.handleCommentsDelete)))
+	s.route(mux, http.MethodPost, "/v1/comments/restore", "Restore a deleted comment", s.withAuth(s.withWriteLimit(s.handleCommentsRestore)))
+
+	s.route(mux, http.MethodPost, "/v1/attachments/list", "List a task's attachments", s.withAuth(s.handleAttachmentsList))
+	s.route(mux, http.MethodGet, "/v1/attachments/get", "Download an attachment", s.withAuth(s.handleAttachmentsGet))
+	s.route(mux, http.MethodPost, "/v1/attachments/put", "Upload an attachment (multipart file part or raw body)", s.withAuth(s.withWriteLimit(s.handleAttachmentsPut)))
+
+	s.route(mux, http.MethodPost, "/v1/reactions/list", "List reactions on a task or comment", s.withAuth(s.handleReactionsList))
+	s.route(mux, http.MethodPost, "/v1/reactions/add", "Add a reaction", s.withAuth(s.withWriteLimit(s.handleReactionsAdd)))
+	s.route(mux, http.MethodPost, "/v1/reactions/remove", "Remove a reaction", s.withAuth(s.withWriteLimit(s.handleReactionsRemove)))
+
+	s.route(mux, http.MethodPost, "/v1/relations/list", "List a task's relations", s.withAuth(s.handleRelationsList))
+	s.route(mux, http.MethodPost, "/v1/relations/create", "Relate two tasks", s.withAuth(s.withIdempotency(s.withWriteLimit(s.handleRelationsCreate))))
+	s.route(mux, http.MethodPost, "/v1/relations/delete", "Remove a relation", s.withAuth(s.withWriteLimit(s.handleRelationsDelete)))
+
+	s.route(mux, http.MethodPost, "/v1/time/total", "A task's logged time next to its estimate", s.withAuth(s.handleTimeTotal))
+	s.route(mux, http.MethodPost, "/v1/time/log", "Log time against a task", s.withAuth(s.withIdempotency(s.withWriteLimit(s.handleTimeLog))))
+
+	s.route(mux, http.MethodPost, "/v1/events/list", "Page through the event log", s.withAuth(s.withAllProjects(s.handleEventsList)))
+	s.route(mux, http.MethodGet, "/v1/events/watch", "Stream new events as Server-Sent Events", s.withAuth(s.withAllProjects(s.handleEventsWatch)))
+
+	s.route(mux, http.MethodPost, "/v1/actors/list", "List actors", s.withAuth(s.handleActorsList))
+	s.route(mux, http.MethodPost, "/v1/actors/create", "Create an actor", s.withAuth(s.withScope(domain.TokenScopeAdmin, s.withWriteLimit(s.handleActorsCreate))))
+	s.route(mux, http.MethodPost, "/v1/actors/update", "Update an actor", s.withAuth(s.withScope(domain.TokenScopeAdmin, s.withWriteLimit(s.handleActorsUpdate))))
+	s.route(mux, http.MethodPost, "/v1/actors/deactivate", "Deactivate an actor", s.withAuth(s.withScope(domain.TokenScopeAdmin, s.withWriteLimit(s.handleActorsDeactivate))))
+	s.route(mux, http.MethodPost, "/v1/actors/reactivate", "Reactivate an actor", s.withAuth(s.withScope(domain.TokenScopeAdmin, s.withWriteLimit(s.handleActorsReactivate))))
+
+	s.route(mux, http.MethodPost, "/v1/bundle/create", "Write a bundle of changed tasks", s.withAuth(s.withScope(domain.TokenScopeAdmin, s.withAllProjects(s.handleBundleCreate))))
+	s.route(mux, http.MethodPost, "/v1/bundle/apply", "Apply a bundle", s.withAuth(s.withScope(domain.TokenScopeAdmin, s.withAllProjects(s.withWriteLimit(s.handleBundleApply)))))
+}
 
-	mux.HandleFunc("/v1/comments/list", s.withAuth(s.handleCommentsList))
-	mux.HandleFunc("/v1/comments/create", s.withAuth(s.handleCommentsCreate))
+// daemonRoute is a registered endpoint, as listed by /v1/routes.
+type daemonRoute struct {
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	Description string `json:"description"`
+}
 
-	mux.HandleFunc("/v1/relations/list", s.withAuth(s.handleRelationsList))
-	mux.HandleFunc("/v1/relations/create", s.withAuth(s.handleRelationsCreate))
-	mux.HandleFunc("/v1/relations/delete", s.withAuth(s.handleRelationsDelete))
+// route registers handler for path and records it for /v1/routes and
+// /v1/openapi.json, so an endpoint can't be added without describing it.
+// Every route gets the same outer middleware, outermost first: a
+// request-scoped lookup cache, request logging, metrics, CORS and response
+// compression.
+func (s *daemonServer) route(mux *http.ServeMux, method, path, description string, handler http.HandlerFunc) {
+	s.routes = append(s.routes, daemonRoute{Method: method, Path: path, Description: description})
+	mux.HandleFunc(path, s.withLookups(s.withRequestLog(s.instrument(path, s.withCORS(s.withCompression(handler))))))
+}
 
-	mux.HandleFunc("/v1/actors/list", s.withAuth(s.handleActorsList))
-	mux.HandleFunc("/v1/actors/create", s.withAuth(s.handleActorsCreate))
-	mux.HandleFunc("/v1/actors/update", s.withAuth(s.handleActorsUpdate))
+func (s *daemonServer) handleRoutes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
 
-	mux.HandleFunc("/v1/bundle/create", s.withAuth(s.handleBundleCreate))
-	mux.HandleFunc("/v1/bundle/apply", s.withAuth(s.handleBundleApply))
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"version": Version,
+		"routes":  s.routes,
+	})
 }
 
+// withAuth authenticates a request. While api_tokens is empty the daemon is
+// in legacy mode: the shared --token (if any) grants full access and the
+// actor comes from X-Wrkq-Actor. Once tokens exist, only they are accepted;
+// the token's scope, actor and project restriction are attached to the
+// request for the handlers below.
 func (s *daemonServer) withAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if s.token != "" {
-			token := r.Header.Get("Authorization")
-			if strings.HasPrefix(token, "Bearer ") {
-				token = strings.TrimPrefix(token, "Bearer ")
-			}
-			if token == "" {
-				token = r.Header.Get("X-Wrkqd-Token")
-			}
-			if token != s.token {
+		tokens := store.New(s.db).Tokens
+		count, err := tokens.Count()
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if count == 0 {
+			if s.token != "" && requestToken(r) != s.token {
 				s.writeError(w, http.StatusUnauthorized, fmt.Errorf("unauthorized"))
 				return
 			}
+			next(w, r)
+			return
+		}
+
+		token, err := tokens.Authenticate(requestToken(r))
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if token == nil {
+			s.writeError(w, http.StatusUnauthorized, fmt.Errorf("unauthorized"))
+			return
 		}
+		noteRequestActor(r, token.ActorUUID)
+		next(w, withAPIToken(r, token))
+	}
+}
 
-		next(w, r)
+// requestToken returns the auth token presented by the client, from either
+// "Authorization: Bearer" or X-Wrkqd-Token.
+func requestToken(r *http.Request) string {
+	token := r.Header.Get("Authorization")
+	if strings.HasPrefix(token, "Bearer ") {
+		token = strings.TrimPrefix(token, "Bearer ")
+	}
+	if token == "" {
+		token = r.Header.Get("X-Wrkqd-Token")
 	}
+	return token
 }
 
 func (s *daemonServer) decodeJSON(r *http.Request, dst interface{}) error {
-	decoder := json.NewDecoder(r.Body)
+	body := r.Body
+	if s.maxBodyBytes > 0 {
+		body = http.MaxBytesReader(nil, r.Body, s.maxBodyBytes)
+	}
+	decoder := json.NewDecoder(body)
 	return decoder.Decode(dst)
 }
 
@@ -193,13 +501,134 @@ func (s *daemonServer) writeJSON(w http.ResponseWriter, status int, payload inte
 	_ = json.NewEncoder(w).Encode(payload)
 }
 
+// writeJSONWithETag is writeJSON for a successful read of a versioned
+// resource. Its ETag pairs the resource's etag with a hash of the body, so it
+// also changes with included sub-resources (comments, relations, reactions,
+// subtasks) and with the request's include options. A request whose
+// If-None-Match lists that ETag gets 304 and no body. Unlike ifMatch on
+// writes, this is only a caching aid for pollers.
+func (s *daemonServer) writeJSONWithETag(w http.ResponseWriter, r *http.Request, etag int64, payload interface{}) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(payload); err != nil {
+		s.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to encode response: %w", err))
+		return
+	}
+	sum := sha256.Sum256(body.Bytes())
+	tag := fmt.Sprintf(`W/"%d-%s"`, etag, hex.EncodeToString(sum[:8]))
+
+	w.Header().Set("ETag", tag)
+	if etagListMatches(r.Header.Get("If-None-Match"), tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body.Bytes())
+}
+
+// etagListMatches reports whether an If-None-Match header lists tag, using
+// the weak comparison RFC 9110 prescribes for it.
+func etagListMatches(header, tag string) bool {
+	tag = strings.TrimPrefix(tag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Error codes returned in the "code" field of daemon error responses, so
+// clients can branch on the kind of failure without matching messages.
+const (
+	errorCodeETagConflict     = "etag_conflict"
+	errorCodeNotFound         = "not_found"
+	errorCodeValidation       = "validation"
+	errorCodeUnauthorized     = "unauthorized"
+	errorCodeForbidden        = "forbidden"
+	errorCodeConflict         = "conflict"
+	errorCodeMethodNotAllowed = "method_not_allowed"
+	errorCodeTooLarge         = "payload_too_large"
+	errorCodeRateLimited      = "rate_limited"
+	errorCodeInternal         = "internal"
+)
+
 func (s *daemonServer) writeError(w http.ResponseWriter, status int, err error) {
-	s.writeJSON(w, status, map[string]interface{}{
+	status, code := classifyError(status, err)
+	body := map[string]interface{}{
+		"code":    code,
 		"message": err.Error(),
-	})
+	}
+	// Ambiguous selectors list what they matched so clients can offer a choice
+	var ambiguousErr *selectors.AmbiguousError
+	if errors.As(err, &ambiguousErr) {
+		body["candidates"] = ambiguousErr.Candidates
+	}
+	s.writeJSON(w, status, body)
+}
+
+// classifyError picks the error code for a response. Typed errors win over
+// the handler's status (and may correct it); otherwise the code follows the
+// status.
+func classifyError(status int, err error) (int, string) {
+	var maxBytesErr *http.MaxBytesError
+	var etagErr *domain.ETagMismatchError
+	var wipErr *domain.WIPLimitExceededError
+	var validationErr *domain.ValidationError
+	var ambiguousErr *selectors.AmbiguousError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return http.StatusRequestEntityTooLarge, errorCodeTooLarge
+	case errors.Is(err, errForbidden):
+		return http.StatusForbidden, errorCodeForbidden
+	case errors.As(err, &etagErr):
+		return http.StatusConflict, errorCodeETagConflict
+	case errors.As(err, &wipErr):
+		return http.StatusConflict, errorCodeConflict
+	case errors.Is(err, sql.ErrNoRows), errors.As(err, &ambiguousErr):
+		return http.StatusNotFound, errorCodeNotFound
+	case errors.As(err, &validationErr):
+		return status, errorCodeValidation
+	}
+
+	switch {
+	case status == http.StatusNotFound:
+		return status, errorCodeNotFound
+	case status == http.StatusUnauthorized:
+		return status, errorCodeUnauthorized
+	case status == http.StatusForbidden:
+		return status, errorCodeForbidden
+	case status == http.StatusConflict, status == http.StatusUnprocessableEntity:
+		return status, errorCodeConflict
+	case status == http.StatusMethodNotAllowed:
+		return status, errorCodeMethodNotAllowed
+	case status == http.StatusRequestEntityTooLarge:
+		return status, errorCodeTooLarge
+	case status == http.StatusTooManyRequests:
+		return status, errorCodeRateLimited
+	case status >= 500:
+		return status, errorCodeInternal
+	default:
+		return status, errorCodeValidation
+	}
 }
 
+// resolveActorUUID resolves the actor a request acts as and notes it for the
+// request log.
 func (s *daemonServer) resolveActorUUID(r *http.Request) (string, error) {
+	actorUUID, err := s.resolveRequestActor(r)
+	if err == nil {
+		noteRequestActor(r, actorUUID)
+	}
+	return actorUUID, err
+}
+
+func (s *daemonServer) resolveRequestActor(r *http.Request) (string, error) {
+	if token := requestAPIToken(r); token != nil {
+		return s.resolveTokenActor(r, token)
+	}
+
 	actorIdentifier := r.Header.Get("X-Wrkq-Actor")
 	if actorIdentifier == "" {
 		actorIdentifier = s.cfg.GetActorID()
@@ -227,6 +656,76 @@ func (s *daemonServer) resolveActorUUID(r *http.Request) (string, error) {
 	return actor.UUID, nil
 }
 
+// resolveTokenActor picks the actor for a request authenticated with token.
+// A token acts as its own actor. X-Wrkq-Actor may name another actor only
+// with an admin token, for services that act on behalf of users; from any
+// other token it is refused rather than quietly ignored, unless it names the
+// token's own actor. Unlike legacy mode, unknown actors are never created.
+func (s *daemonServer) resolveTokenActor(r *http.Request, token *domain.APIToken) (string, error) {
+	identifier := strings.TrimSpace(r.Header.Get("X-Wrkq-Actor"))
+	if identifier == "" {
+		return token.ActorUUID, nil
+	}
+
+	actorUUID, err := actors.NewResolver(s.db.DB).Resolve(identifier)
+	if err == nil && actorUUID == token.ActorUUID {
+		return actorUUID, nil
+	}
+	if !token.Scope.Allows(domain.TokenScopeAdmin) {
+		return "", fmt.Errorf("%w: token %q acts as its own actor and can't act as %s", errForbidden, token.Name, identifier)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve actor: %w", err)
+	}
+	return actorUUID, nil
+}
+
+// requestCwd returns the container path relative selectors in a request are
+// resolved against, taken from the X-Wrkq-Cwd header ("" means the root).
+func requestCwd(r *http.Request) string {
+	return strings.Trim(strings.TrimSpace(r.Header.Get("X-Wrkq-Cwd")), "/")
+}
+
+// resolveTask resolves a task selector, first joining a relative selector
+// ("./x", "../x") onto the request's cwd. A task outside the project the
+// request's token is restricted to fails with errForbidden.
+func (s *daemonServer) resolveTask(r *http.Request, selector string) (string, string, error) {
+	resolved, err := selectors.ResolveRelative(requestCwd(r), selector)
+	if err != nil {
+		return "", "", err
+	}
+	taskUUID, friendlyID, err := selectors.ResolveTask(s.db, resolved)
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.checkTaskInScope(r, taskUUID); err != nil {
+		return "", "", err
+	}
+	return taskUUID, friendlyID, nil
+}
+
+// resolveContainer resolves a container selector, first joining a relative
+// selector onto the request's cwd. Like resolveTask, it refuses containers
+// outside the token's project; that check runs even when the resolution
+// comes from the selector cache.
+func (s *daemonServer) resolveContainer(r *http.Request, selector string) (string, string, error) {
+	resolved, err := selectors.ResolveRelative(requestCwd(r), selector)
+	if err != nil {
+		return "", "", err
+	}
+	containerUUID, friendlyID, generation, ok := s.selectorCache.get("container", resolved)
+	if !ok {
+		if containerUUID, friendlyID, err = selectors.ResolveContainer(s.db, resolved); err != nil {
+			return "", "", err
+		}
+		s.selectorCache.put("container", resolved, containerUUID, friendlyID, generation)
+	}
+	if err := s.checkContainerInScope(r, containerUUID); err != nil {
+		return "", "", err
+	}
+	return containerUUID, friendlyID, nil
+}
+
 func (s *daemonServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
@@ -239,11 +738,54 @@ func (s *daemonServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleVersion reports the build and the optional features this daemon
+// serves, so clients can check bundle compatibility before sending one.
+func (s *daemonServer) handleVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	features, err := s.features()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"version":                   Version,
+		"commit":                    GitCommit,
+		"build_date":                BuildDate,
+		"machine_interface_version": bundle.MachineInterfaceVersion,
+		"features":                  features,
+	})
+}
+
+// features lists the optional capabilities clients may probe for: fts when
+// the search index exists, plus webhooks and watch, which every daemon runs.
+func (s *daemonServer) features() ([]string, error) {
+	var features []string
+	var searchIndex int
+	if err := s.db.QueryRow(
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'task_search'",
+	).Scan(&searchIndex); err != nil {
+		return nil, err
+	}
+	if searchIndex > 0 {
+		features = append(features, "fts")
+	}
+	return append(features, "webhooks", "watch"), nil
+}
+
 type containersTreeRequest struct {
 	Path            string `json:"path,omitempty"`
 	Depth           int    `json:"depth,omitempty"`
 	IncludeArchived bool   `json:"include_archived,omitempty"`
 	OpenOnly        bool   `json:"open_only,omitempty"`
+	// IncludeCounts adds task_counts to every container node; RollupCounts
+	// makes them include the tasks of descendant containers
+	IncludeCounts bool `json:"include_counts,omitempty"`
+	RollupCounts  bool `json:"rollup_counts,omitempty"`
 }
 
 func (s *daemonServer) handleContainersTree(w http.ResponseWriter, r *http.Request) {
@@ -259,11 +801,30 @@ func (s *daemonServer) handleContainersTree(w http.ResponseWriter, r *http.Reque
 	}
 
 	rootPath := strings.Trim(req.Path, "/")
+	tokenProject, err := s.tokenProjectPath(r)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if tokenProject != "" {
+		if rootPath == "" {
+			rootPath = tokenProject
+		} else if !pathInProject(rootPath, tokenProject) {
+			s.writeError(w, http.StatusForbidden, fmt.Errorf("%w: %s is outside the token's project", errForbidden, rootPath))
+			return
+		}
+	}
 	root, err := buildTree(s.db, rootPath, req.Depth, req.IncludeArchived, req.OpenOnly, 0)
 	if err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
+	if req.IncludeCounts {
+		if err := annotateTreeCounts(s.db, root.Children, req.IncludeArchived, req.OpenOnly, req.RollupCounts); err != nil {
+			s.writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
 
 	path := rootPath
 	if path == "" {
@@ -276,313 +837,1964 @@ func (s *daemonServer) handleContainersTree(w http.ResponseWriter, r *http.Reque
 	})
 }
 
-type tasksListRequest struct {
-	Project    string   `json:"project,omitempty"`
-	Filter     string   `json:"filter,omitempty"`
-	Sort       string   `json:"sort,omitempty"`
-	Direction  string   `json:"direction,omitempty"`
-	Limit      int      `json:"limit,omitempty"`
-	Cursor     string   `json:"cursor,omitempty"`
-	PathPrefix []string `json:"path_prefix,omitempty"`
-	Assignee   string   `json:"assignee,omitempty"`
-	Kind       string   `json:"kind,omitempty"`
-	ParentTask string   `json:"parent_task,omitempty"`
-	DueBefore  string   `json:"due_before,omitempty"`
-	DueAfter   string   `json:"due_after,omitempty"`
-	SlugGlob   string   `json:"slug_glob,omitempty"`
+type containersActivityRequest struct {
+	Container string `json:"container"`
+	Since     string `json:"since,omitempty"`
+	Limit     int    `json:"limit,omitempty"`
+	Cursor    string `json:"cursor,omitempty"`
 }
 
-func (s *daemonServer) handleTasksList(w http.ResponseWriter, r *http.Request) {
+// handleContainersActivity returns the activity feed for a container and its
+// sub-containers, newest first.
+func (s *daemonServer) handleContainersActivity(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
 		return
 	}
 
-	var req tasksListRequest
+	var req containersActivityRequest
 	if err := s.decodeJSON(r, &req); err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
-
-	var pathsFilter []string
-
-	if req.Project != "" {
-		projectUUID, _, err := selectors.ResolveContainer(s.db, req.Project)
-		if err != nil {
-			s.writeError(w, http.StatusBadRequest, err)
-			return
-		}
-		var projectPath string
-		if err := s.db.QueryRow("SELECT path FROM v_container_paths WHERE uuid = ?", projectUUID).Scan(&projectPath); err != nil {
-			s.writeError(w, http.StatusBadRequest, err)
-			return
-		}
-		pathsFilter = append(pathsFilter, projectPath)
-	}
-
-	for _, prefix := range req.PathPrefix {
-		trimmed := strings.Trim(prefix, "/")
-		if trimmed != "" {
-			pathsFilter = append(pathsFilter, trimmed)
-		}
+	if req.Container == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("container is required"))
+		return
 	}
 
-	var assigneeUUID string
-	if req.Assignee != "" {
-		resolver := actors.NewResolver(s.db.DB)
-		uuid, err := resolver.Resolve(req.Assignee)
-		if err != nil {
-			s.writeError(w, http.StatusBadRequest, err)
-			return
-		}
-		assigneeUUID = uuid
+	containerUUID, _, err := s.resolveContainer(r, req.Container)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
 	}
 
-	var parentTaskUUID string
-	if req.ParentTask != "" {
-		uuid, _, err := selectors.ResolveTask(s.db, req.ParentTask)
+	var since time.Time
+	if req.Since != "" {
+		since, err = parseTimeFilter(req.Since)
 		if err != nil {
-			s.writeError(w, http.StatusBadRequest, err)
+			s.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid since value: %w", err))
 			return
 		}
-		parentTaskUUID = uuid
-	}
-
-	stateFilter := ""
-	switch req.Filter {
-	case "all":
-		stateFilter = "all"
-	case "deleted":
-		stateFilter = "deleted"
-	case "active", "":
-		stateFilter = ""
-	default:
-		stateFilter = req.Filter
 	}
 
-	opts := findOptions{
-		paths:          pathsFilter,
-		typeFilter:     "t",
-		slugGlob:       req.SlugGlob,
-		state:          stateFilter,
-		dueBefore:      req.DueBefore,
-		dueAfter:       req.DueAfter,
-		kind:           req.Kind,
-		assigneeUUID:   assigneeUUID,
-		parentTaskUUID: parentTaskUUID,
-		limit:          req.Limit,
-		cursor:         req.Cursor,
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 100
 	}
 
-	results, hasMore, err := findTasks(s.db, opts, false)
+	entries, nextCursor, err := store.New(s.db).Activity(containerUUID, since, limit, req.Cursor)
 	if err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	var nextCursor string
-	if hasMore && len(results) > 0 {
-		lastEntry := results[len(results)-1]
-		nextCursor, _ = cursor.BuildNextCursor(
-			[]string{"updated_at"},
-			[]interface{}{lastEntry.UpdatedAt},
-			lastEntry.ID,
-		)
-	}
-
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"tasks":       results,
+		"activity":    entries,
 		"next_cursor": nextCursor,
 	})
 }
 
-type taskGetRequest struct {
-	Selector         string `json:"selector"`
-	IncludeComments  *bool  `json:"include_comments,omitempty"`
-	IncludeRelations *bool  `json:"include_relations,omitempty"`
+type containerArchiveRequest struct {
+	Container string `json:"container"`
+	IfMatch   int64  `json:"ifMatch,omitempty"`
+	Cascade   bool   `json:"cascade,omitempty"`
 }
 
-func (s *daemonServer) handleTasksGet(w http.ResponseWriter, r *http.Request) {
+type containerRestoreRequest struct {
+	Container string `json:"container"`
+	IfMatch   int64  `json:"ifMatch,omitempty"`
+}
+
+// handleContainersArchive archives a container. A container with active
+// tasks or subcontainers is refused with 409 unless cascade is set, in which
+// case everything below it is archived with it.
+func (s *daemonServer) handleContainersArchive(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
 		return
 	}
 
-	var req taskGetRequest
+	var req containerArchiveRequest
 	if err := s.decodeJSON(r, &req); err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
-
-	if req.Selector == "" {
-		s.writeError(w, http.StatusBadRequest, fmt.Errorf("selector required"))
+	if req.Container == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("container required"))
 		return
 	}
 
-	taskUUID, _, err := selectors.ResolveTask(s.db, req.Selector)
+	actorUUID, err := s.resolveActorUUID(r)
 	if err != nil {
-		s.writeError(w, http.StatusNotFound, err)
+		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
-
-	includeComments := true
-	includeRelations := true
-	if req.IncludeComments != nil {
-		includeComments = *req.IncludeComments
-	}
-	if req.IncludeRelations != nil {
-		includeRelations = *req.IncludeRelations
+	containerUUID, _, err := s.resolveContainer(r, req.Container)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
 	}
 
-	task, err := loadTaskDetail(s.db, taskUUID, includeComments, includeRelations)
+	svc := store.New(s.db)
+	result, err := svc.Containers.ArchiveTree(actorUUID, containerUUID, req.IfMatch, req.Cascade)
 	if err != nil {
-		s.writeError(w, http.StatusBadRequest, err)
+		s.writeError(w, http.StatusConflict, err)
 		return
 	}
-
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"task": task,
-	})
-}
-
-type taskCreateRequest struct {
-	Path      string                 `json:"path"`
-	Fields    map[string]interface{} `json:"fields,omitempty"`
-	ForceUUID string                 `json:"force_uuid,omitempty"`
+	s.writeContainerLifecycle(w, svc, containerUUID, result)
 }
 
-func (s *daemonServer) handleTasksCreate(w http.ResponseWriter, r *http.Request) {
+// handleContainersRestore restores an archived container together with the
+// subcontainers and tasks that were archived along with it.
+func (s *daemonServer) handleContainersRestore(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
 		return
 	}
 
-	var req taskCreateRequest
+	var req containerRestoreRequest
 	if err := s.decodeJSON(r, &req); err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
-
-	if req.Path == "" {
-		s.writeError(w, http.StatusBadRequest, fmt.Errorf("path required"))
+	if req.Container == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("container required"))
 		return
 	}
-	if req.ForceUUID != "" {
-		if err := domain.ValidateUUID(req.ForceUUID); err != nil {
-			s.writeError(w, http.StatusBadRequest, err)
-			return
-		}
-	}
 
 	actorUUID, err := s.resolveActorUUID(r)
 	if err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
-
-	parentUUID, normalizedSlug, _, err := selectors.ResolveParentContainer(s.db, req.Path)
+	containerUUID, _, err := s.resolveContainer(r, req.Container)
 	if err != nil {
-		s.writeError(w, http.StatusBadRequest, err)
+		s.writeError(w, http.StatusNotFound, err)
 		return
 	}
 
-	fields := req.Fields
-	if fields == nil {
-		fields = map[string]interface{}{}
+	svc := store.New(s.db)
+	result, err := svc.Containers.RestoreTree(actorUUID, containerUUID, req.IfMatch)
+	if err != nil {
+		s.writeError(w, http.StatusConflict, err)
+		return
+	}
+	s.writeContainerLifecycle(w, svc, containerUUID, result)
+}
+
+func (s *daemonServer) writeContainerLifecycle(w http.ResponseWriter, svc *store.Store, containerUUID string, result *store.ContainerLifecycleResult) {
+	container, err := svc.Containers.GetByUUID(containerUUID)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"container":  container,
+		"containers": result.Containers,
+		"tasks":      result.Tasks,
+	})
+}
+
+type containerMoveRequest struct {
+	Selector     string  `json:"selector"`
+	TargetParent *string `json:"target_parent"`
+	IfMatch      int64   `json:"ifMatch,omitempty"`
+}
+
+// handleContainersMove moves a container, with everything below it, under
+// target_parent, or to the root when target_parent is null.
+func (s *daemonServer) handleContainersMove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req containerMoveRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Selector == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("selector required"))
+		return
+	}
+
+	actorUUID, err := s.resolveActorUUID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	containerUUID, _, err := s.resolveContainer(r, req.Selector)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+	var parentUUID *string
+	if req.TargetParent != nil {
+		if *req.TargetParent == "" {
+			s.writeError(w, http.StatusBadRequest, fmt.Errorf("target_parent must be a container selector or null"))
+			return
+		}
+		uuid, _, err := s.resolveContainer(r, *req.TargetParent)
+		if err != nil {
+			s.writeError(w, http.StatusNotFound, err)
+			return
+		}
+		parentUUID = &uuid
+	} else if token := requestAPIToken(r); token != nil && token.ProjectUUID != nil {
+		s.writeError(w, http.StatusForbidden, fmt.Errorf("%w: token %q can't move containers to the root", errForbidden, token.Name))
+		return
+	}
+
+	svc := store.New(s.db)
+	if _, err := svc.Containers.Move(actorUUID, containerUUID, parentUUID, req.IfMatch); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.writeContainerPath(w, svc, containerUUID)
+}
+
+type containerRenameRequest struct {
+	Selector string `json:"selector"`
+	Slug     string `json:"slug"`
+	IfMatch  int64  `json:"ifMatch,omitempty"`
+}
+
+// handleContainersRename changes a container's slug in place. Every path
+// below it changes with it.
+func (s *daemonServer) handleContainersRename(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req containerRenameRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Selector == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("selector required"))
+		return
+	}
+	slug, err := paths.NormalizeSlug(req.Slug)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid slug %q: %w", req.Slug, err))
+		return
+	}
+
+	actorUUID, err := s.resolveActorUUID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	containerUUID, _, err := s.resolveContainer(r, req.Selector)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	svc := store.New(s.db)
+	if _, err := svc.Containers.Rename(actorUUID, containerUUID, slug, req.IfMatch); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.writeContainerPath(w, svc, containerUUID)
+}
+
+// writeContainerPath answers with the container and its current path.
+func (s *daemonServer) writeContainerPath(w http.ResponseWriter, svc *store.Store, containerUUID string) {
+	container, err := svc.Containers.GetByUUID(containerUUID)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	var path string
+	if err := s.db.QueryRow("SELECT path FROM v_container_paths WHERE uuid = ?", containerUUID).Scan(&path); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"container": container,
+		"path":      path,
+	})
+}
+
+type tasksListRequest struct {
+	Project    string   `json:"project,omitempty"`
+	Filter     string   `json:"filter,omitempty"`
+	Sort       string   `json:"sort,omitempty"`
+	Direction  string   `json:"direction,omitempty"`
+	Limit      int      `json:"limit,omitempty"`
+	Cursor     string   `json:"cursor,omitempty"`
+	PathPrefix []string `json:"path_prefix,omitempty"`
+	Assignee   string   `json:"assignee,omitempty"`
+	Kind       string   `json:"kind,omitempty"`
+	ParentTask string   `json:"parent_task,omitempty"`
+	DueBefore  string   `json:"due_before,omitempty"`
+	DueAfter   string   `json:"due_after,omitempty"`
+	// DueWindow is overdue, today or this_week, taken in TZ (an IANA zone,
+	// default UTC). DueBefore/DueAfter override the matching bound.
+	DueWindow   string   `json:"due_window,omitempty"`
+	TZ          string   `json:"tz,omitempty"`
+	PriorityMin int      `json:"priority_min,omitempty"`
+	PriorityMax int      `json:"priority_max,omitempty"`
+	SlugGlob    string   `json:"slug_glob,omitempty"`
+	LabelsAny   []string `json:"labels_any,omitempty"`
+	LabelsAll   []string `json:"labels_all,omitempty"`
+}
+
+func (s *daemonServer) handleTasksList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req tasksListRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	opts, err := s.taskFindOptions(r, req)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	results, hasMore, err := findTasks(s.db, opts, false)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var nextCursor string
+	if hasMore && len(results) > 0 {
+		nextCursor, _ = nextTaskCursor(results[len(results)-1], opts)
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"tasks":       results,
+		"next_cursor": nextCursor,
+	})
+}
+
+type tasksExportRequest struct {
+	tasksListRequest
+	// Format is the export format; only csv is supported. ?format= overrides it.
+	Format  string   `json:"format,omitempty"`
+	Columns []string `json:"columns,omitempty"`
+}
+
+// handleTasksExport streams every task matching a tasks/list filter as CSV.
+// Limit and cursor are ignored.
+func (s *daemonServer) handleTasksExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req tasksExportRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	format := req.Format
+	if q := r.URL.Query().Get("format"); q != "" {
+		format = q
+	}
+	if format != "" && format != "csv" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("unsupported export format: %s", format))
+		return
+	}
+
+	opts, err := s.taskFindOptions(r, req.tasksListRequest)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	query, args, err := taskCSVQuery(opts, req.Columns)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	out := &csvExportWriter{w: w}
+	if _, err := writeTasksCSV(out, s.db, query, args, csvColumnsOrDefault(req.Columns)); err != nil {
+		if !out.started {
+			s.writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		// The status line is already sent; abort the connection so the
+		// client sees a truncated response rather than a short export
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// csvExportWriter sends the CSV headers with the first bytes of an export, so
+// an export that fails before then can still answer with a JSON error.
+type csvExportWriter struct {
+	w       http.ResponseWriter
+	started bool
+}
+
+func (c *csvExportWriter) Write(p []byte) (int, error) {
+	if !c.started {
+		c.started = true
+		c.w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		c.w.Header().Set("Content-Disposition", `attachment; filename="tasks.csv"`)
+	}
+	return c.w.Write(p)
+}
+
+// taskFindOptions translates a list request into findOptions, resolving
+// selectors against the request's X-Wrkq-Cwd and "@me" against its actor.
+//
+// A token restricted to a project only sees the tasks under it: with no
+// project or path_prefix the filter defaults to the project, an explicit
+// prefix outside it is refused, and wildcard prefixes only match containers
+// inside it.
+func (s *daemonServer) taskFindOptions(r *http.Request, req tasksListRequest) (findOptions, error) {
+	tokenProject, err := s.tokenProjectPath(r)
+	if err != nil {
+		return findOptions{}, err
+	}
+	lookups := s.lookups(r)
+	var pathsFilter []string
+
+	if req.Project != "" {
+		projectUUID, _, err := s.resolveContainer(r, req.Project)
+		if err != nil {
+			return findOptions{}, err
+		}
+		projectPath, err := lookups.containerPath(projectUUID)
+		if err != nil {
+			return findOptions{}, err
+		}
+		pathsFilter = append(pathsFilter, projectPath)
+	}
+
+	for _, prefix := range req.PathPrefix {
+		trimmed := strings.Trim(prefix, "/")
+		if trimmed == "" {
+			continue
+		}
+		if !paths.IsGlobPattern(trimmed) {
+			if tokenProject != "" && !pathInProject(trimmed, tokenProject) {
+				return findOptions{}, fmt.Errorf("%w: path_prefix %q is outside the token's project", errForbidden, trimmed)
+			}
+			pathsFilter = append(pathsFilter, trimmed)
+			continue
+		}
+
+		// A wildcard prefix selects the tasks under every matching container
+		containerUUIDs, err := selectors.ResolveContainersGlob(s.db, trimmed)
+		if err != nil {
+			return findOptions{}, err
+		}
+		matched := 0
+		for _, containerUUID := range containerUUIDs {
+			containerPath, err := lookups.containerPath(containerUUID)
+			if err != nil {
+				return findOptions{}, err
+			}
+			if tokenProject != "" && !pathInProject(containerPath, tokenProject) {
+				continue
+			}
+			pathsFilter = append(pathsFilter, containerPath+"/")
+			matched++
+		}
+		if matched == 0 {
+			return findOptions{}, fmt.Errorf("no containers match path_prefix %q", trimmed)
+		}
+	}
+	if tokenProject != "" && len(pathsFilter) == 0 {
+		pathsFilter = append(pathsFilter, tokenProject+"/")
+	}
+
+	var assigneeUUID string
+	var unassigned bool
+	if req.Assignee != "" {
+		var err error
+		if assigneeUUID, unassigned, err = s.resolveAssignee(r, req.Assignee); err != nil {
+			return findOptions{}, err
+		}
+	}
+
+	var parentTaskUUID string
+	if req.ParentTask != "" {
+		uuid, _, err := s.resolveTask(r, req.ParentTask)
+		if err != nil {
+			return findOptions{}, err
+		}
+		parentTaskUUID = uuid
+	}
+
+	stateFilter := ""
+	switch req.Filter {
+	case "all":
+		stateFilter = "all"
+	case "deleted":
+		stateFilter = "deleted"
+	case "active", "":
+		stateFilter = ""
+	default:
+		stateFilter = req.Filter
+	}
+
+	for _, p := range []int{req.PriorityMin, req.PriorityMax} {
+		if p != 0 {
+			if err := domain.ValidatePriority(p); err != nil {
+				return findOptions{}, err
+			}
+		}
+	}
+	if req.PriorityMin != 0 && req.PriorityMax != 0 && req.PriorityMin > req.PriorityMax {
+		return findOptions{}, fmt.Errorf("priority_min %d is greater than priority_max %d", req.PriorityMin, req.PriorityMax)
+	}
+
+	var dueFrom, dueUntil string
+	var excludeTerminal bool
+	if req.DueWindow != "" {
+		loc, err := time.LoadLocation(req.TZ) // "" is UTC
+		if err != nil {
+			return findOptions{}, fmt.Errorf("invalid tz: %w", err)
+		}
+		dueFrom, dueUntil, excludeTerminal, err = dueWindowBounds(req.DueWindow, time.Now().In(loc))
+		if err != nil {
+			return findOptions{}, err
+		}
+		if req.DueAfter != "" {
+			dueFrom = ""
+		}
+		if req.DueBefore != "" {
+			dueUntil = ""
+		}
+	}
+
+	sortKeys, err := parseTaskSort(req.Sort)
+	if err != nil {
+		return findOptions{}, err
+	}
+
+	return findOptions{
+		paths:           pathsFilter,
+		typeFilter:      "t",
+		slugGlob:        req.SlugGlob,
+		state:           stateFilter,
+		dueBefore:       req.DueBefore,
+		dueAfter:        req.DueAfter,
+		kind:            req.Kind,
+		assigneeUUID:    assigneeUUID,
+		unassigned:      unassigned,
+		parentTaskUUID:  parentTaskUUID,
+		labelsAny:       req.LabelsAny,
+		labelsAll:       req.LabelsAll,
+		priorityMin:     req.PriorityMin,
+		priorityMax:     req.PriorityMax,
+		dueFrom:         dueFrom,
+		dueUntil:        dueUntil,
+		excludeTerminal: excludeTerminal,
+		limit:           req.Limit,
+		cursor:          req.Cursor,
+		sort:            sortKeys,
+		direction:       strings.ToLower(req.Direction),
+	}, nil
+}
+
+// resolveAssignee resolves an actor slug or ID, or one of the sentinels
+// "@me" (the request's actor) and "@none" (reported as unassigned).
+func (s *daemonServer) resolveAssignee(r *http.Request, assignee string) (string, bool, error) {
+	switch assignee {
+	case assigneeNone:
+		return "", true, nil
+	case assigneeMe:
+		uuid, err := s.resolveActorUUID(r)
+		return uuid, false, err
+	default:
+		uuid, _, generation, ok := s.selectorCache.get("actor", assignee)
+		if ok {
+			return uuid, false, nil
+		}
+		resolver := actors.NewResolver(s.db.DB)
+		uuid, err := resolver.Resolve(assignee)
+		if err == nil {
+			s.selectorCache.put("actor", assignee, uuid, "", generation)
+		}
+		return uuid, false, err
+	}
+}
+
+type tasksCountRequest struct {
+	tasksListRequest
+	GroupBy []string `json:"group_by,omitempty"`
+}
+
+// taskCountDimensions maps group_by names to the SQL expression grouped on.
+var taskCountDimensions = map[string]string{
+	"state":    "t.state",
+	"assignee": "COALESCE(a.slug, 'unassigned')",
+	"kind":     "t.kind",
+	"priority": "CAST(t.priority AS TEXT)",
+	"project":  "cp.path",
+}
+
+func (s *daemonServer) handleTasksCount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req tasksCountRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	opts, err := s.taskFindOptions(r, req.tasksListRequest)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	groupBy := req.GroupBy
+	if groupBy == nil {
+		groupBy = []string{"state", "assignee"}
+	}
+	for _, dim := range groupBy {
+		if _, ok := taskCountDimensions[dim]; !ok {
+			s.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid group_by dimension: %s", dim))
+			return
+		}
+	}
+
+	filterClause, filterArgs, err := taskFilterClause(opts)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	from := `
+		FROM tasks t
+		JOIN v_container_paths cp ON cp.uuid = t.project_uuid
+		LEFT JOIN actors a ON a.uuid = t.assignee_actor_uuid
+		WHERE 1=1` + filterClause
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*)"+from, filterArgs...).Scan(&total); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := map[string]interface{}{"total": total}
+	for _, dim := range groupBy {
+		expr := taskCountDimensions[dim]
+		rows, err := s.db.Query("SELECT "+expr+", COUNT(*)"+from+" GROUP BY 1", filterArgs...)
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		counts := map[string]int{}
+		for rows.Next() {
+			var key string
+			var count int
+			if err := rows.Scan(&key, &count); err != nil {
+				rows.Close()
+				s.writeError(w, http.StatusInternalServerError, err)
+				return
+			}
+			counts[key] = count
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		resp["by_"+dim] = counts
+	}
+
+	s.writeJSON(w, http.StatusOK, resp)
+}
+
+type tasksReadyRequest struct {
+	Project    string   `json:"project,omitempty"`
+	PathPrefix []string `json:"path_prefix,omitempty"`
+}
+
+func (s *daemonServer) handleTasksReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req tasksReadyRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var projectUUID string
+	if req.Project != "" {
+		uuid, _, err := s.resolveContainer(r, req.Project)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		projectUUID = uuid
+	} else if token := requestAPIToken(r); token != nil && token.ProjectUUID != nil {
+		projectUUID = *token.ProjectUUID
+	}
+
+	var prefixes []string
+	for _, prefix := range req.PathPrefix {
+		if trimmed := strings.Trim(prefix, "/"); trimmed != "" {
+			prefixes = append(prefixes, trimmed)
+		}
+	}
+
+	tasks, err := store.New(s.db).Tasks.Ready(projectUUID, prefixes)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"tasks": tasks,
+	})
+}
+
+type tasksSearchRequest struct {
+	Query      string   `json:"query"`
+	Project    string   `json:"project,omitempty"`
+	PathPrefix []string `json:"path_prefix,omitempty"`
+	Filter     string   `json:"filter,omitempty"`
+	Limit      int      `json:"limit,omitempty"`
+}
+
+func (s *daemonServer) handleTasksSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req tasksSearchRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	filters := store.SearchFilters{Limit: req.Limit}
+	if req.Project != "" {
+		uuid, _, err := s.resolveContainer(r, req.Project)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		filters.ProjectUUID = uuid
+	} else if token := requestAPIToken(r); token != nil && token.ProjectUUID != nil {
+		filters.ProjectUUID = *token.ProjectUUID
+	}
+	for _, prefix := range req.PathPrefix {
+		if trimmed := strings.Trim(prefix, "/"); trimmed != "" {
+			filters.PathPrefixes = append(filters.PathPrefixes, trimmed)
+		}
+	}
+	if req.Filter != "active" {
+		filters.State = req.Filter
+	}
+
+	results, err := store.New(s.db).Tasks.Search(req.Query, filters)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"tasks": results,
+	})
+}
+
+type taskGetRequest struct {
+	Selector         string `json:"selector"`
+	IncludeComments  *bool  `json:"include_comments,omitempty"`
+	IncludeRelations *bool  `json:"include_relations,omitempty"`
+	IncludeReactions bool   `json:"include_reactions,omitempty"`
+	IncludeSubtasks  bool   `json:"include_subtasks,omitempty"`
+	IncludeTime      bool   `json:"include_time,omitempty"`
+	// AsOf returns the task's fields at that time, rebuilt from the event
+	// log, instead of its current state; it may also be given as ?as_of=
+	AsOf string `json:"as_of,omitempty"`
+}
+
+func (s *daemonServer) handleTasksGet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req taskGetRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Selector == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("selector required"))
+		return
+	}
+
+	taskUUID, _, err := s.resolveTask(r, req.Selector)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if req.AsOf == "" {
+		req.AsOf = r.URL.Query().Get("as_of")
+	}
+	if req.AsOf != "" {
+		asOf, err := parseTimeFilter(req.AsOf)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid as_of value: %w", err))
+			return
+		}
+		snapshot, err := store.New(s.db).TaskAsOf(taskUUID, asOf)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		s.writeJSONWithETag(w, r, snapshot.ETag, map[string]interface{}{
+			"task": snapshot,
+		})
+		return
+	}
+
+	includeComments := true
+	includeRelations := true
+	if req.IncludeComments != nil {
+		includeComments = *req.IncludeComments
+	}
+	if req.IncludeRelations != nil {
+		includeRelations = *req.IncludeRelations
+	}
+
+	task, err := loadTaskDetail(s.db, taskUUID, includeComments, includeRelations)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.IncludeReactions {
+		if task.Reactions, err = store.New(s.db).Reactions.Summary("task", taskUUID); err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	if req.IncludeSubtasks {
+		if task.Subtasks, err = loadSubtasks(s.db, taskUUID, false, false); err != nil {
+			s.writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	if req.IncludeTime {
+		total, err := store.New(s.db).TimeEntries.Total(taskUUID)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		task.TimeLogged = &total
+	}
+
+	s.writeJSONWithETag(w, r, task.Etag, map[string]interface{}{
+		"task": task,
+	})
+}
+
+type tasksSubtasksRequest struct {
+	Selector       string `json:"selector"`
+	Recursive      bool   `json:"recursive,omitempty"`
+	IncludeDeleted bool   `json:"include_deleted,omitempty"`
+}
+
+// handleTasksSubtasks lists a task's direct subtasks, ordered by priority.
+// With recursive, each subtask carries its own subtasks in the same way.
+// Deleted subtasks are left out unless include_deleted is set.
+func (s *daemonServer) handleTasksSubtasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req tasksSubtasksRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Selector == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("selector required"))
+		return
+	}
+
+	taskUUID, taskID, err := s.resolveTask(r, req.Selector)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	subtasks, err := loadSubtasks(s.db, taskUUID, req.Recursive, req.IncludeDeleted)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if subtasks == nil {
+		subtasks = []*Task{}
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"parent_id":   taskID,
+		"parent_uuid": taskUUID,
+		"subtasks":    subtasks,
+	})
+}
+
+// loadSubtasks loads the subtasks of parentUUID with loadTaskDetail (without
+// comments or relations), ordered by priority then ID. With recursive, each
+// one's own subtasks are loaded into its Subtasks.
+func loadSubtasks(database *db.DB, parentUUID string, recursive, includeDeleted bool) ([]*Task, error) {
+	return loadSubtasksSeen(database, parentUUID, recursive, includeDeleted, map[string]bool{parentUUID: true})
+}
+
+func loadSubtasksSeen(database *db.DB, parentUUID string, recursive, includeDeleted bool, seen map[string]bool) ([]*Task, error) {
+	query := `SELECT uuid FROM tasks WHERE parent_task_uuid = ?`
+	if !includeDeleted {
+		query += ` AND state != 'deleted'`
+	}
+	query += ` ORDER BY priority, id`
+
+	rows, err := database.Query(query, parentUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query subtasks: %w", err)
+	}
+	var uuids []string
+	for rows.Next() {
+		var uuid string
+		if err := rows.Scan(&uuid); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan subtask: %w", err)
+		}
+		uuids = append(uuids, uuid)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var subtasks []*Task
+	for _, uuid := range uuids {
+		// parent_task_uuid is not constrained to be acyclic
+		if seen[uuid] {
+			continue
+		}
+		seen[uuid] = true
+		task, err := loadTaskDetail(database, uuid, false, false)
+		if err != nil {
+			return nil, err
+		}
+		if recursive {
+			if task.Subtasks, err = loadSubtasksSeen(database, uuid, true, includeDeleted, seen); err != nil {
+				return nil, err
+			}
+		}
+		subtasks = append(subtasks, task)
+	}
+	return subtasks, nil
+}
+
+type tasksHistoryRequest struct {
+	Selector string `json:"selector"`
+}
+
+// handleTasksHistory returns the events for one task, its comments,
+// attachments and relations, oldest first, with field changes spelled out.
+func (s *daemonServer) handleTasksHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req tasksHistoryRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Selector == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("selector required"))
+		return
+	}
+
+	taskUUID, taskID, err := s.resolveTask(r, req.Selector)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	history, err := store.New(s.db).TaskHistory(taskUUID)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"task_id":   taskID,
+		"task_uuid": taskUUID,
+		"history":   history,
+	})
+}
+
+type taskCreateRequest struct {
+	Path           string                 `json:"path"`
+	Fields         map[string]interface{} `json:"fields,omitempty"`
+	ForceUUID      string                 `json:"force_uuid,omitempty"`
+	BypassWIPLimit bool                   `json:"bypass_wip_limit,omitempty"`
+}
+
+func (s *daemonServer) handleTasksCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req taskCreateRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	actorUUID, err := s.resolveActorUUID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	params, err := s.buildTaskCreateParams(r, req)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	svc := store.New(s.db)
+	result, err := svc.Tasks.Create(actorUUID, params)
+	if err != nil {
+		var wipErr *domain.WIPLimitExceededError
+		if errors.As(err, &wipErr) {
+			s.writeError(w, http.StatusConflict, err)
+			return
+		}
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	task, err := loadTaskDetail(s.db, result.UUID, true, true)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"task": task,
+	})
+}
+
+// buildTaskCreateParams validates a create request and resolves its selectors
+// into store parameters.
+func (s *daemonServer) buildTaskCreateParams(r *http.Request, req taskCreateRequest) (store.CreateParams, error) {
+	if req.Path == "" {
+		return store.CreateParams{}, fmt.Errorf("path required")
+	}
+	if token := requestAPIToken(r); req.BypassWIPLimit && token != nil && !token.Scope.Allows(domain.TokenScopeAdmin) {
+		return store.CreateParams{}, fmt.Errorf("%w: token %q has %s scope, bypass_wip_limit needs %s", errForbidden, token.Name, token.Scope, domain.TokenScopeAdmin)
+	}
+	if req.ForceUUID != "" {
+		if err := domain.ValidateUUID(req.ForceUUID); err != nil {
+			return store.CreateParams{}, err
+		}
+	}
+
+	taskPath, err := selectors.ResolveRelative(requestCwd(r), req.Path)
+	if err != nil {
+		return store.CreateParams{}, err
+	}
+
+	parentUUID, normalizedSlug, _, err := selectors.ResolveParentContainer(s.db, taskPath)
+	if err != nil {
+		return store.CreateParams{}, err
+	}
+
+	fields := req.Fields
+	if fields == nil {
+		fields = map[string]interface{}{}
+	}
+
+	title := getStringField(fields, "title", normalizedSlug)
+	description := getStringField(fields, "description", "")
+	state := getStringField(fields, "state", "open")
+	priority := getIntField(fields, "priority", 3)
+	kind := getStringField(fields, "kind", "")
+	labels := getLabelsField(fields, "labels")
+	dueAt := getStringField(fields, "due_at", "")
+	startAt := getStringField(fields, "start_at", "")
+
+	var estimateMinutes *int
+	if v, ok := fields["estimate_minutes"]; ok && v != nil {
+		estimate := getIntField(fields, "estimate_minutes", 0)
+		estimateMinutes = &estimate
+	}
+
+	var parentTaskUUID *string
+	if parentTask := getStringField(fields, "parent_task", ""); parentTask != "" {
+		uuid, _, err := s.resolveTask(r, parentTask)
+		if err != nil {
+			return store.CreateParams{}, err
+		}
+		parentTaskUUID = &uuid
+	}
+
+	var assigneeActorUUID *string
+	if assignee := getStringField(fields, "assignee", ""); assignee != "" {
+		resolver := actors.NewResolver(s.db.DB)
+		uuid, err := resolver.Resolve(assignee)
+		if err != nil {
+			return store.CreateParams{}, err
+		}
+		assigneeActorUUID = &uuid
+	}
+
+	projectUUID := ""
+	if parentUUID != nil {
+		projectUUID = *parentUUID
+	} else {
+		if err := s.db.QueryRow(`SELECT uuid FROM containers WHERE parent_uuid IS NULL LIMIT 1`).Scan(&projectUUID); err != nil {
+			return store.CreateParams{}, fmt.Errorf("no root container found")
+		}
+	}
+	if err := s.checkContainerInScope(r, projectUUID); err != nil {
+		return store.CreateParams{}, err
+	}
+
+	return store.CreateParams{
+		UUID:              req.ForceUUID,
+		Slug:              normalizedSlug,
+		Title:             title,
+		Description:       description,
+		ProjectUUID:       projectUUID,
+		State:             state,
+		Priority:          priority,
+		Kind:              kind,
+		ParentTaskUUID:    parentTaskUUID,
+		AssigneeActorUUID: assigneeActorUUID,
+		Labels:            labels,
+		DueAt:             dueAt,
+		StartAt:           startAt,
+		EstimateMinutes:   estimateMinutes,
+		BypassWIPLimit:    req.BypassWIPLimit,
+	}, nil
+}
+
+type tasksBulkCreateRequest struct {
+	Items  []taskCreateRequest `json:"items"`
+	Atomic bool                `json:"atomic,omitempty"`
+}
+
+type bulkItemError struct {
+	Index   int    `json:"index"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+func (s *daemonServer) handleTasksBulkCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req tasksBulkCreateRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if len(req.Items) == 0 {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("items required"))
+		return
+	}
+
+	actorUUID, err := s.resolveActorUUID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Resolve every item up front; resolution failures are reported per item
+	// and, in atomic mode, prevent the batch from being written at all.
+	itemErrors := []bulkItemError{}
+	var params []store.CreateParams
+	var paramIndexes []int
+	for i, item := range req.Items {
+		p, err := s.buildTaskCreateParams(r, item)
+		if err != nil {
+			itemErrors = append(itemErrors, bulkItemError{Index: i, Path: item.Path, Message: err.Error()})
+			continue
+		}
+		params = append(params, p)
+		paramIndexes = append(paramIndexes, i)
+	}
+
+	tasks := []*Task{}
+	if req.Atomic && len(itemErrors) > 0 {
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"tasks":     tasks,
+			"errors":    itemErrors,
+			"committed": false,
+		})
+		return
+	}
+
+	svc := store.New(s.db)
+	results, err := svc.Tasks.CreateMany(actorUUID, params, req.Atomic)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	committed := true
+	for _, res := range results {
+		index := paramIndexes[res.Index]
+		if res.Err != nil {
+			itemErrors = append(itemErrors, bulkItemError{Index: index, Path: req.Items[index].Path, Message: res.Err.Error()})
+			if req.Atomic {
+				committed = false
+			}
+			continue
+		}
+		if res.Result == nil {
+			continue
+		}
+		task, err := loadTaskDetail(s.db, res.Result.UUID, false, false)
+		if err != nil {
+			itemErrors = append(itemErrors, bulkItemError{Index: index, Path: req.Items[index].Path, Message: err.Error()})
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+
+	sort.Slice(itemErrors, func(i, j int) bool { return itemErrors[i].Index < itemErrors[j].Index })
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"tasks":     tasks,
+		"errors":    itemErrors,
+		"committed": committed,
+	})
+}
+
+type taskUpdateRequest struct {
+	Selector string                 `json:"selector"`
+	Fields   map[string]interface{} `json:"fields,omitempty"`
+	IfMatch  int64                  `json:"ifMatch,omitempty"`
+}
+
+func (s *daemonServer) handleTasksUpdate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req taskUpdateRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Selector == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("selector required"))
+		return
+	}
+
+	actorUUID, err := s.resolveActorUUID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	taskUUID, _, err := s.resolveTask(r, req.Selector)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	fields := map[string]interface{}{}
+	for key, value := range req.Fields {
+		switch key {
+		case "title", "state", "description", "due_at", "start_at", "kind":
+			if s, ok := value.(string); ok {
+				fields[key] = s
+			}
+		case "labels":
+			fields["labels"] = getLabelsField(req.Fields, "labels")
+		case "priority":
+			if p, ok := coerceInt(value); ok {
+				fields["priority"] = p
+			}
+		case "estimate_minutes":
+			if value == nil {
+				fields["estimate_minutes"] = nil
+			} else if m, ok := coerceInt(value); ok {
+				fields["estimate_minutes"] = m
+			}
+		case "assignee":
+			if assignee, ok := value.(string); ok {
+				if assignee == "" {
+					fields["assignee_actor_uuid"] = nil
+					continue
+				}
+				resolver := actors.NewResolver(s.db.DB)
+				uuid, err := resolver.Resolve(assignee)
+				if err != nil {
+					s.writeError(w, http.StatusBadRequest, err)
+					return
+				}
+				fields["assignee_actor_uuid"] = uuid
+			}
+		}
+	}
+
+	if len(fields) == 0 {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("no valid fields to update"))
+		return
+	}
+
+	svc := store.New(s.db)
+	if _, err := svc.Tasks.UpdateFields(actorUUID, taskUUID, fields, req.IfMatch); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	task, err := loadTaskDetail(s.db, taskUUID, true, true)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"task": task,
+	})
+}
+
+type tasksReassignRequest struct {
+	FromAssignee string   `json:"from_assignee"`
+	ToAssignee   string   `json:"to_assignee"`
+	Project      string   `json:"project,omitempty"`
+	PathPrefix   []string `json:"path_prefix,omitempty"`
+}
+
+func (s *daemonServer) handleTasksReassign(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req tasksReassignRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.FromAssignee == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("from_assignee required"))
+		return
+	}
+	if req.ToAssignee == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("to_assignee required (use %s to unassign)", assigneeNone))
+		return
+	}
+
+	actorUUID, err := s.resolveActorUUID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	toUUID, toNone, err := s.resolveAssignee(r, req.ToAssignee)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Select with the list filters, then keep only tasks that aren't finished
+	opts, err := s.taskFindOptions(r, tasksListRequest{
+		Project:    req.Project,
+		PathPrefix: req.PathPrefix,
+		Assignee:   req.FromAssignee,
+		Filter:     "all",
+	})
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	opts.excludeTerminal = true
+
+	results, _, err := findTasks(s.db, opts, true)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	taskUUIDs := make([]string, 0, len(results))
+	for _, result := range results {
+		taskUUIDs = append(taskUUIDs, result.UUID)
+	}
+
+	var from, to *string
+	if !opts.unassigned {
+		from = &opts.assigneeUUID
+	}
+	if !toNone {
+		to = &toUUID
+	}
+
+	svc := store.New(s.db)
+	reassigned, err := svc.Tasks.Reassign(actorUUID, taskUUIDs, from, to)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"reassigned": reassigned,
+	})
+}
+
+type taskArchiveRequest struct {
+	Selector string `json:"selector"`
+	IfMatch  int64  `json:"ifMatch,omitempty"`
+}
+
+func (s *daemonServer) handleTasksArchive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req taskArchiveRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Selector == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("selector required"))
+		return
+	}
+
+	actorUUID, err := s.resolveActorUUID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	taskUUID, _, err := s.resolveTask(r, req.Selector)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	svc := store.New(s.db)
+	if _, err := svc.Tasks.Archive(actorUUID, taskUUID, req.IfMatch); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	task, err := loadTaskDetail(s.db, taskUUID, true, true)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"task": task,
+	})
+}
+
+type taskRestoreRequest struct {
+	Selector string                 `json:"selector"`
+	State    string                 `json:"state,omitempty"`
+	IfMatch  int64                  `json:"ifMatch,omitempty"`
+	Fields   map[string]interface{} `json:"fields,omitempty"`
+}
+
+func (s *daemonServer) handleTasksRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req taskRestoreRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Selector == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("selector required"))
+		return
+	}
+
+	actorUUID, err := s.resolveActorUUID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
 	}
 
-	title := getStringField(fields, "title", normalizedSlug)
-	description := getStringField(fields, "description", "")
-	state := getStringField(fields, "state", "open")
-	priority := getIntField(fields, "priority", 3)
-	kind := getStringField(fields, "kind", "")
-	labels := getLabelsField(fields, "labels")
-	dueAt := getStringField(fields, "due_at", "")
-	startAt := getStringField(fields, "start_at", "")
+	taskUUID, _, err := s.resolveTask(r, req.Selector)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
 
-	var parentTaskUUID *string
-	if parentTask := getStringField(fields, "parent_task", ""); parentTask != "" {
-		uuid, _, err := selectors.ResolveTask(s.db, parentTask)
+	targetState := req.State
+	if targetState == "" {
+		targetState = "open"
+	}
+	if err := domain.ValidateState(targetState); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if targetState == "archived" || targetState == "deleted" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("cannot restore to %s state", targetState))
+		return
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer tx.Rollback()
+
+	var currentState string
+	var currentETag int64
+	var parentTaskUUID sql.NullString
+	if err := tx.QueryRow("SELECT state, etag, parent_task_uuid FROM tasks WHERE uuid = ?", taskUUID).Scan(&currentState, &currentETag, &parentTaskUUID); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if currentState != "archived" && currentState != "deleted" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("task is not deleted or archived (current state: %s)", currentState))
+		return
+	}
+
+	if req.IfMatch != 0 && req.IfMatch != currentETag {
+		s.writeError(w, http.StatusConflict, &domain.ETagMismatchError{Expected: req.IfMatch, Actual: currentETag})
+		return
+	}
+
+	fields := map[string]interface{}{
+		"state":       targetState,
+		"archived_at": nil,
+		"deleted_at":  nil,
+	}
+
+	for key, value := range req.Fields {
+		switch key {
+		case "title", "description", "labels", "due_at", "start_at":
+			fields[key] = value
+		case "priority":
+			if p, ok := coerceInt(value); ok {
+				fields["priority"] = p
+			}
+		}
+	}
+
+	setClauses := []string{}
+	args := []interface{}{}
+	for key, value := range fields {
+		setClauses = append(setClauses, fmt.Sprintf("%s = ?", key))
+		args = append(args, value)
+	}
+
+	setClauses = append(setClauses, "etag = etag + 1")
+	setClauses = append(setClauses, "updated_by_actor_uuid = ?")
+	args = append(args, actorUUID, taskUUID)
+
+	query := fmt.Sprintf("UPDATE tasks SET %s WHERE uuid = ?", strings.Join(setClauses, ", "))
+	if _, err := tx.Exec(query, args...); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	newETag := currentETag + 1
+	payloadJSON, _ := json.Marshal(fields)
+	payloadStr := string(payloadJSON)
+	ew := events.NewWriter(s.db.DB)
+	if err := ew.LogEvent(tx, &domain.Event{
+		ActorUUID:    &actorUUID,
+		ResourceType: "task",
+		ResourceUUID: &taskUUID,
+		EventType:    "task.updated",
+		ETag:         &newETag,
+		Payload:      &payloadStr,
+	}); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := store.RefreshParentProgressTx(tx, ew, actorUUID, parentTaskUUID.String); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	webhooks.DispatchTask(s.db, taskUUID)
+
+	task, err := loadTaskDetail(s.db, taskUUID, true, true)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"task": task,
+	})
+}
+
+type commentsListRequest struct {
+	Task           string `json:"task"`
+	IncludeDeleted bool   `json:"include_deleted,omitempty"`
+	Limit          int    `json:"limit,omitempty"`
+	Cursor         string `json:"cursor,omitempty"`
+}
+
+// defaultCommentsPageSize is the comments/list page size when no limit is given.
+const defaultCommentsPageSize = 100
+
+func (s *daemonServer) handleCommentsList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req commentsListRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Task == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("task required"))
+		return
+	}
+
+	taskUUID, _, err := s.resolveTask(r, req.Task)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultCommentsPageSize
+	}
+	pag, err := cursor.Apply(req.Cursor, cursor.ApplyOptions{
+		SortFields: []string{"created_at"},
+		SQLFields:  []string{"c.created_at"},
+		Descending: []bool{false},
+		IDField:    "c.uuid",
+		Limit:      limit,
+	})
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	query := daemonCommentSelect + " WHERE c.task_uuid = ?"
+	args := []interface{}{taskUUID}
+	if !req.IncludeDeleted {
+		query += " AND c.deleted_at IS NULL"
+	}
+	if pag.WhereClause != "" {
+		query += " AND " + pag.WhereClause
+		args = append(args, pag.Params...)
+	}
+	query += " " + pag.OrderByClause + " " + pag.LimitClause
+	args = append(args, *pag.LimitParam)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer rows.Close()
+
+	var comments []map[string]interface{}
+	for rows.Next() {
+		comment, err := scanDaemonComment(rows)
 		if err != nil {
-			s.writeError(w, http.StatusBadRequest, err)
+			s.writeError(w, http.StatusInternalServerError, err)
 			return
 		}
-		parentTaskUUID = &uuid
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
 	}
 
-	var assigneeActorUUID *string
-	if assignee := getStringField(fields, "assignee", ""); assignee != "" {
-		resolver := actors.NewResolver(s.db.DB)
-		uuid, err := resolver.Resolve(assignee)
+	var nextCursor string
+	if len(comments) > limit {
+		comments = comments[:limit]
+		last := comments[limit-1]
+		// Break created_at ties on uuid: friendly IDs sort lexically, so
+		// C-100000 would land before C-99999
+		nextCursor, err = cursor.BuildNextCursor(
+			[]string{"created_at"},
+			[]interface{}{last["created_at"]},
+			last["uuid"].(string),
+		)
 		if err != nil {
-			s.writeError(w, http.StatusBadRequest, err)
+			s.writeError(w, http.StatusInternalServerError, err)
 			return
 		}
-		assigneeActorUUID = &uuid
 	}
 
-	projectUUID := ""
-	if parentUUID != nil {
-		projectUUID = *parentUUID
-	} else {
-		if err := s.db.QueryRow(`SELECT uuid FROM containers WHERE parent_uuid IS NULL LIMIT 1`).Scan(&projectUUID); err != nil {
-			s.writeError(w, http.StatusBadRequest, fmt.Errorf("no root container found"))
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"comments":    comments,
+		"next_cursor": nextCursor,
+	})
+}
+
+type commentsThreadRequest struct {
+	Task           string `json:"task"`
+	IncludeDeleted bool   `json:"include_deleted,omitempty"`
+}
+
+// handleCommentsThread returns a task's comments as a tree: top-level
+// comments in created_at order, each with its replies nested under
+// "replies". Replies whose parent isn't returned (a deleted comment when
+// include_deleted is off) are listed at the top level.
+func (s *daemonServer) handleCommentsThread(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req commentsThreadRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Task == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("task required"))
+		return
+	}
+
+	taskUUID, _, err := s.resolveTask(r, req.Task)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	query := daemonCommentSelect + " WHERE c.task_uuid = ?"
+	if !req.IncludeDeleted {
+		query += " AND c.deleted_at IS NULL"
+	}
+	query += " ORDER BY c.created_at ASC, c.id ASC"
+
+	rows, err := s.db.Query(query, taskUUID)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer rows.Close()
+
+	var comments []map[string]interface{}
+	byUUID := make(map[string]map[string]interface{})
+	for rows.Next() {
+		comment, err := scanDaemonComment(rows)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
 			return
 		}
+		comment["replies"] = []map[string]interface{}{}
+		comments = append(comments, comment)
+		byUUID[comment["uuid"].(string)] = comment
+	}
+	if err := rows.Err(); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	thread := []map[string]interface{}{}
+	for _, comment := range comments {
+		parentUUID, _ := comment["parent_comment_uuid"].(string)
+		if parent, ok := byUUID[parentUUID]; ok {
+			parent["replies"] = append(parent["replies"].([]map[string]interface{}), comment)
+			continue
+		}
+		thread = append(thread, comment)
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"thread": thread,
+	})
+}
+
+const daemonCommentSelect = `
+	SELECT c.uuid, c.id, c.task_uuid, c.actor_uuid, c.body, c.meta, c.etag,
+	       c.created_at, c.updated_at, c.deleted_at, c.deleted_by_actor_uuid,
+	       a.slug as actor_slug, a.role as actor_role,
+	       t.id as task_id, c.parent_comment_uuid
+	FROM comments c
+	LEFT JOIN actors a ON c.actor_uuid = a.uuid
+	LEFT JOIN tasks t ON c.task_uuid = t.uuid
+`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanDaemonComment scans a row selected with daemonCommentSelect into the
+// JSON shape returned by the comments endpoints.
+func scanDaemonComment(row rowScanner) (map[string]interface{}, error) {
+	var uuid, id, taskUUID, actorUUID, body, createdAt string
+	var actorSlug, actorRole, taskIDStr string
+	var meta, updatedAt, deletedAt, deletedByActorUUID, parentUUID sql.NullString
+	var etag int64
+
+	if err := row.Scan(&uuid, &id, &taskUUID, &actorUUID, &body, &meta, &etag,
+		&createdAt, &updatedAt, &deletedAt, &deletedByActorUUID,
+		&actorSlug, &actorRole, &taskIDStr, &parentUUID); err != nil {
+		return nil, err
+	}
+
+	comment := map[string]interface{}{
+		"uuid":       uuid,
+		"id":         id,
+		"task_uuid":  taskUUID,
+		"task_id":    taskIDStr,
+		"actor_uuid": actorUUID,
+		"actor_slug": actorSlug,
+		"actor_role": actorRole,
+		"body":       body,
+		"etag":       etag,
+		"created_at": createdAt,
+	}
+
+	if meta.Valid && meta.String != "" {
+		comment["meta"] = meta.String
+	}
+	if updatedAt.Valid {
+		comment["updated_at"] = updatedAt.String
 	}
+	if deletedAt.Valid {
+		comment["deleted_at"] = deletedAt.String
+	}
+	if deletedByActorUUID.Valid {
+		comment["deleted_by_actor_uuid"] = deletedByActorUUID.String
+	}
+	if parentUUID.Valid {
+		comment["parent_comment_uuid"] = parentUUID.String
+	}
+
+	return comment, nil
+}
+
+// loadDaemonComment loads a single comment (including soft-deleted ones) by UUID.
+func loadDaemonComment(database *db.DB, commentUUID string) (map[string]interface{}, error) {
+	return scanDaemonComment(database.QueryRow(daemonCommentSelect+" WHERE c.uuid = ?", commentUUID))
+}
+
+// daemonCommentRef is the minimal state needed to mutate a comment.
+type daemonCommentRef struct {
+	UUID     string
+	ID       string
+	TaskUUID string
+	ETag     int64
+	Deleted  bool
+}
 
-	svc := store.New(s.db)
-	result, err := svc.Tasks.Create(actorUUID, store.CreateParams{
-		UUID:              req.ForceUUID,
-		Slug:              normalizedSlug,
-		Title:             title,
-		Description:       description,
-		ProjectUUID:       projectUUID,
-		State:             state,
-		Priority:          priority,
-		Kind:              kind,
-		ParentTaskUUID:    parentTaskUUID,
-		AssigneeActorUUID: assigneeActorUUID,
-		Labels:            labels,
-		DueAt:             dueAt,
-		StartAt:           startAt,
-	})
-	if err != nil {
-		s.writeError(w, http.StatusBadRequest, err)
-		return
+// errInvalidCommentSelector marks a comment selector that can't name a
+// comment at all.
+var errInvalidCommentSelector = errors.New("invalid comment selector")
+
+// lookupDaemonComment resolves a comment selector (C-00012, uuid, or c:<token>)
+// without filtering out soft-deleted comments. A missing comment fails
+// wrapping sql.ErrNoRows; see commentLookupStatus.
+func lookupDaemonComment(tx *sql.Tx, selector string) (*daemonCommentRef, error) {
+	token := strings.TrimPrefix(selector, "c:")
+	column := ""
+	switch {
+	case id.IsUUID(token):
+		column = "uuid"
+	case id.IsFriendlyID(token):
+		column = "id"
+	default:
+		return nil, fmt.Errorf("%w: %s (expected C-00001 or UUID)", errInvalidCommentSelector, selector)
 	}
 
-	task, err := loadTaskDetail(s.db, result.UUID, true, true)
+	var ref daemonCommentRef
+	var deletedAt sql.NullString
+	err := tx.QueryRow(
+		fmt.Sprintf("SELECT uuid, id, task_uuid, etag, deleted_at FROM comments WHERE %s = ?", column),
+		token,
+	).Scan(&ref.UUID, &ref.ID, &ref.TaskUUID, &ref.ETag, &deletedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("comment not found: %s: %w", selector, err)
+	}
 	if err != nil {
-		s.writeError(w, http.StatusBadRequest, err)
-		return
+		return nil, fmt.Errorf("database error: %w", err)
 	}
+	ref.Deleted = deletedAt.Valid
+	return &ref, nil
+}
 
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"task": task,
-	})
+// commentLookupStatus is the response status for a lookupDaemonComment
+// error: 404 when no comment matched, 400 for a malformed selector and 500
+// when the database failed.
+func commentLookupStatus(err error) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	case errors.Is(err, errInvalidCommentSelector):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
 }
 
-type taskUpdateRequest struct {
-	Selector string                 `json:"selector"`
-	Fields   map[string]interface{} `json:"fields,omitempty"`
-	IfMatch  int64                  `json:"ifMatch,omitempty"`
+type commentsCreateRequest struct {
+	Task string                 `json:"task"`
+	Body string                 `json:"body"`
+	Meta map[string]interface{} `json:"meta,omitempty"`
+	// ReplyTo is a comment on the same task that this comment answers
+	ReplyTo string `json:"reply_to,omitempty"`
+	IfMatch int64  `json:"ifMatch,omitempty"`
 }
 
-func (s *daemonServer) handleTasksUpdate(w http.ResponseWriter, r *http.Request) {
+func (s *daemonServer) handleCommentsCreate(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
 		return
 	}
 
-	var req taskUpdateRequest
+	var req commentsCreateRequest
 	if err := s.decodeJSON(r, &req); err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	if req.Selector == "" {
-		s.writeError(w, http.StatusBadRequest, fmt.Errorf("selector required"))
+	if req.Task == "" || strings.TrimSpace(req.Body) == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("task and body required"))
 		return
 	}
 
@@ -592,136 +2804,144 @@ func (s *daemonServer) handleTasksUpdate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	taskUUID, _, err := selectors.ResolveTask(s.db, req.Selector)
+	taskUUID, _, err := s.resolveTask(r, req.Task)
 	if err != nil {
 		s.writeError(w, http.StatusNotFound, err)
 		return
 	}
 
-	fields := map[string]interface{}{}
-	for key, value := range req.Fields {
-		switch key {
-		case "title", "state", "description", "due_at", "start_at":
-			if s, ok := value.(string); ok {
-				fields[key] = s
-			}
-		case "labels":
-			fields["labels"] = getLabelsField(req.Fields, "labels")
-		case "priority":
-			if p, ok := coerceInt(value); ok {
-				fields["priority"] = p
-			}
-		case "assignee":
-			if assignee, ok := value.(string); ok {
-				if assignee == "" {
-					fields["assignee_actor_uuid"] = nil
-					continue
-				}
-				resolver := actors.NewResolver(s.db.DB)
-				uuid, err := resolver.Resolve(assignee)
-				if err != nil {
-					s.writeError(w, http.StatusBadRequest, err)
-					return
-				}
-				fields["assignee_actor_uuid"] = uuid
-			}
+	metaStr := ""
+	if req.Meta != nil {
+		if data, err := json.Marshal(req.Meta); err == nil {
+			metaStr = string(data)
 		}
 	}
 
-	if len(fields) == 0 {
-		s.writeError(w, http.StatusBadRequest, fmt.Errorf("no valid fields to update"))
-		return
-	}
-
-	svc := store.New(s.db)
-	if _, err := svc.Tasks.UpdateFields(actorUUID, taskUUID, fields, req.IfMatch); err != nil {
-		s.writeError(w, http.StatusBadRequest, err)
-		return
-	}
-
-	task, err := loadTaskDetail(s.db, taskUUID, true, true)
+	tx, err := s.db.Begin()
 	if err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
+	defer tx.Rollback()
 
-	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"task": task,
-	})
-}
+	if req.IfMatch > 0 {
+		var currentEtag int64
+		if err := tx.QueryRow("SELECT etag FROM tasks WHERE uuid = ?", taskUUID).Scan(&currentEtag); err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if currentEtag != req.IfMatch {
+			s.writeError(w, http.StatusConflict, &domain.ETagMismatchError{Expected: req.IfMatch, Actual: currentEtag})
+			return
+		}
+	}
 
-type taskArchiveRequest struct {
-	Selector string `json:"selector"`
-	IfMatch  int64  `json:"ifMatch,omitempty"`
-}
+	// A new comment can't close a cycle, so checking the parent is enough
+	var parentUUID *string
+	if req.ReplyTo != "" {
+		parent, err := lookupDaemonComment(tx, req.ReplyTo)
+		if err != nil {
+			s.writeError(w, commentLookupStatus(err), err)
+			return
+		}
+		if parent.TaskUUID != taskUUID {
+			s.writeError(w, http.StatusBadRequest, fmt.Errorf("reply_to comment %s belongs to another task", parent.ID))
+			return
+		}
+		if parent.Deleted {
+			s.writeError(w, http.StatusBadRequest, fmt.Errorf("reply_to comment %s is deleted", parent.ID))
+			return
+		}
+		parentUUID = &parent.UUID
+	}
 
-func (s *daemonServer) handleTasksArchive(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+	var nextSeq int
+	if err := tx.QueryRow("SELECT COALESCE(MAX(CAST(SUBSTR(id, 3) AS INTEGER)), 0) + 1 FROM comments").Scan(&nextSeq); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	var req taskArchiveRequest
-	if err := s.decodeJSON(r, &req); err != nil {
+	if _, err := tx.Exec("UPDATE comment_sequences SET value = ? WHERE name = 'next_comment'", nextSeq); err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	if req.Selector == "" {
-		s.writeError(w, http.StatusBadRequest, fmt.Errorf("selector required"))
-		return
+	commentUUID := generateUUID()
+	commentID := fmt.Sprintf("C-%05d", nextSeq)
+
+	var metaPtr *string
+	if metaStr != "" {
+		metaPtr = &metaStr
 	}
 
-	actorUUID, err := s.resolveActorUUID(r)
-	if err != nil {
+	if _, err := tx.Exec(`
+		INSERT INTO comments (uuid, id, task_uuid, actor_uuid, body, meta, etag, parent_comment_uuid)
+		VALUES (?, ?, ?, ?, ?, ?, 1, ?)
+	`, commentUUID, commentID, taskUUID, actorUUID, strings.TrimSpace(req.Body), metaPtr, parentUUID); err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	taskUUID, _, err := selectors.ResolveTask(s.db, req.Selector)
-	if err != nil {
-		s.writeError(w, http.StatusNotFound, err)
+	var comment domain.Comment
+	var createdAtStr string
+	if err := tx.QueryRow(`
+		SELECT uuid, id, task_uuid, actor_uuid, body, meta, etag, created_at, parent_comment_uuid
+		FROM comments WHERE uuid = ?
+	`, commentUUID).Scan(
+		&comment.UUID, &comment.ID, &comment.TaskUUID, &comment.ActorUUID,
+		&comment.Body, &comment.Meta, &comment.ETag, &createdAtStr, &comment.ParentCommentUUID,
+	); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	svc := store.New(s.db)
-	if _, err := svc.Tasks.Archive(actorUUID, taskUUID, req.IfMatch); err != nil {
+	payload := fmt.Sprintf(`{"task_id":"%s","comment_id":"%s","actor_id":"%s"}`, comment.TaskUUID, comment.ID, comment.ActorUUID)
+	if err := events.NewWriter(s.db.DB).LogEvent(tx, &domain.Event{
+		ActorUUID:    &actorUUID,
+		ResourceType: "comment",
+		ResourceUUID: &comment.UUID,
+		EventType:    "comment.created",
+		ETag:         &comment.ETag,
+		Payload:      &payload,
+	}); err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	task, err := loadTaskDetail(s.db, taskUUID, true, true)
-	if err != nil {
+	if err := tx.Commit(); err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
+	webhooks.DispatchTask(s.db, taskUUID)
+
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"task": task,
+		"comment": comment,
 	})
 }
 
-type taskRestoreRequest struct {
-	Selector string                 `json:"selector"`
-	State    string                 `json:"state,omitempty"`
-	IfMatch  int64                  `json:"ifMatch,omitempty"`
-	Fields   map[string]interface{} `json:"fields,omitempty"`
+type commentsUpdateRequest struct {
+	Comment string `json:"comment"`
+	Body    string `json:"body"`
+	IfMatch int64  `json:"ifMatch,omitempty"`
+	Force   bool   `json:"force,omitempty"`
 }
 
-func (s *daemonServer) handleTasksRestore(w http.ResponseWriter, r *http.Request) {
+func (s *daemonServer) handleCommentsUpdate(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
 		return
 	}
 
-	var req taskRestoreRequest
+	var req commentsUpdateRequest
 	if err := s.decodeJSON(r, &req); err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	if req.Selector == "" {
-		s.writeError(w, http.StatusBadRequest, fmt.Errorf("selector required"))
+	body := strings.TrimSpace(req.Body)
+	if req.Comment == "" || body == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("comment and body required"))
 		return
 	}
 
@@ -731,25 +2951,6 @@ func (s *daemonServer) handleTasksRestore(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	taskUUID, _, err := selectors.ResolveTask(s.db, req.Selector)
-	if err != nil {
-		s.writeError(w, http.StatusNotFound, err)
-		return
-	}
-
-	targetState := req.State
-	if targetState == "" {
-		targetState = "open"
-	}
-	if err := domain.ValidateState(targetState); err != nil {
-		s.writeError(w, http.StatusBadRequest, err)
-		return
-	}
-	if targetState == "archived" || targetState == "deleted" {
-		s.writeError(w, http.StatusBadRequest, fmt.Errorf("cannot restore to %s state", targetState))
-		return
-	}
-
 	tx, err := s.db.Begin()
 	if err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
@@ -757,67 +2958,40 @@ func (s *daemonServer) handleTasksRestore(w http.ResponseWriter, r *http.Request
 	}
 	defer tx.Rollback()
 
-	var currentState string
-	var currentETag int64
-	if err := tx.QueryRow("SELECT state, etag FROM tasks WHERE uuid = ?", taskUUID).Scan(&currentState, &currentETag); err != nil {
-		s.writeError(w, http.StatusBadRequest, err)
+	ref, err := lookupDaemonComment(tx, req.Comment)
+	if err != nil {
+		s.writeError(w, commentLookupStatus(err), err)
 		return
 	}
-
-	if currentState != "archived" && currentState != "deleted" {
-		s.writeError(w, http.StatusBadRequest, fmt.Errorf("task is not deleted or archived (current state: %s)", currentState))
+	if err := s.checkTaskInScope(r, ref.TaskUUID); err != nil {
+		s.writeError(w, http.StatusNotFound, err)
 		return
 	}
-
-	if req.IfMatch != 0 && req.IfMatch != currentETag {
-		s.writeError(w, http.StatusConflict, fmt.Errorf("etag mismatch: expected %d, got %d", req.IfMatch, currentETag))
+	if ref.Deleted && !req.Force {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("comment %s is deleted", ref.ID))
 		return
 	}
-
-	fields := map[string]interface{}{
-		"state":       targetState,
-		"archived_at": nil,
-		"deleted_at":  nil,
-	}
-
-	for key, value := range req.Fields {
-		switch key {
-		case "title", "description", "labels", "due_at", "start_at":
-			fields[key] = value
-		case "priority":
-			if p, ok := coerceInt(value); ok {
-				fields["priority"] = p
-			}
-		}
-	}
-
-	setClauses := []string{}
-	args := []interface{}{}
-	for key, value := range fields {
-		setClauses = append(setClauses, fmt.Sprintf("%s = ?", key))
-		args = append(args, value)
+	if req.IfMatch > 0 && req.IfMatch != ref.ETag {
+		s.writeError(w, http.StatusConflict, &domain.ETagMismatchError{Expected: req.IfMatch, Actual: ref.ETag})
+		return
 	}
 
-	setClauses = append(setClauses, "etag = etag + 1")
-	setClauses = append(setClauses, "updated_by_actor_uuid = ?")
-	args = append(args, actorUUID, taskUUID)
-
-	query := fmt.Sprintf("UPDATE tasks SET %s WHERE uuid = ?", strings.Join(setClauses, ", "))
-	if _, err := tx.Exec(query, args...); err != nil {
+	if _, err := tx.Exec(`
+		UPDATE comments
+		SET body = ?,
+		    updated_at = datetime('now'),
+		    etag = etag + 1
+		WHERE uuid = ?
+	`, body, ref.UUID); err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	newETag := currentETag + 1
-	payloadJSON, _ := json.Marshal(fields)
-	payloadStr := string(payloadJSON)
-	if err := events.NewWriter(s.db.DB).LogEvent(tx, &domain.Event{
-		ActorUUID:    &actorUUID,
-		ResourceType: "task",
-		ResourceUUID: &taskUUID,
-		EventType:    "task.updated",
-		ETag:         &newETag,
-		Payload:      &payloadStr,
+	if err := events.NewWriter(s.db.DB).LogCommentUpdated(tx, actorUUID, &domain.Comment{
+		UUID:     ref.UUID,
+		ID:       ref.ID,
+		TaskUUID: ref.TaskUUID,
+		ETag:     ref.ETag + 1,
 	}); err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
@@ -828,138 +3002,130 @@ func (s *daemonServer) handleTasksRestore(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	webhooks.DispatchTask(s.db, taskUUID)
+	webhooks.DispatchTask(s.db, ref.TaskUUID)
 
-	task, err := loadTaskDetail(s.db, taskUUID, true, true)
+	comment, err := loadDaemonComment(s.db, ref.UUID)
 	if err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"task": task,
+		"comment": comment,
 	})
 }
 
-type commentsListRequest struct {
-	Task           string `json:"task"`
-	IncludeDeleted bool   `json:"include_deleted,omitempty"`
+type commentsDeleteRequest struct {
+	Comment string `json:"comment"`
+	IfMatch int64  `json:"ifMatch,omitempty"`
 }
 
-func (s *daemonServer) handleCommentsList(w http.ResponseWriter, r *http.Request) {
+func (s *daemonServer) handleCommentsDelete(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
 		return
 	}
 
-	var req commentsListRequest
+	var req commentsDeleteRequest
 	if err := s.decodeJSON(r, &req); err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
-	if req.Task == "" {
-		s.writeError(w, http.StatusBadRequest, fmt.Errorf("task required"))
+
+	if req.Comment == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("comment required"))
+		return
+	}
+
+	actorUUID, err := s.resolveActorUUID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
+	defer tx.Rollback()
 
-	taskUUID, _, err := selectors.ResolveTask(s.db, req.Task)
+	ref, err := lookupDaemonComment(tx, req.Comment)
 	if err != nil {
+		s.writeError(w, commentLookupStatus(err), err)
+		return
+	}
+	if err := s.checkTaskInScope(r, ref.TaskUUID); err != nil {
 		s.writeError(w, http.StatusNotFound, err)
 		return
 	}
-
-	query := `
-		SELECT c.uuid, c.id, c.task_uuid, c.actor_uuid, c.body, c.meta, c.etag,
-		       c.created_at, c.updated_at, c.deleted_at, c.deleted_by_actor_uuid,
-		       a.slug as actor_slug, a.role as actor_role,
-		       t.id as task_id
-		FROM comments c
-		LEFT JOIN actors a ON c.actor_uuid = a.uuid
-		LEFT JOIN tasks t ON c.task_uuid = t.uuid
-		WHERE c.task_uuid = ?
-	`
-	args := []interface{}{taskUUID}
-	if !req.IncludeDeleted {
-		query += " AND c.deleted_at IS NULL"
+	if ref.Deleted {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("comment %s is already deleted", ref.ID))
+		return
+	}
+	if req.IfMatch > 0 && req.IfMatch != ref.ETag {
+		s.writeError(w, http.StatusConflict, &domain.ETagMismatchError{Expected: req.IfMatch, Actual: ref.ETag})
+		return
 	}
-	query += " ORDER BY c.created_at ASC"
 
-	rows, err := s.db.Query(query, args...)
-	if err != nil {
+	if _, err := tx.Exec(`
+		UPDATE comments
+		SET deleted_at = datetime('now'),
+		    deleted_by_actor_uuid = ?,
+		    etag = etag + 1
+		WHERE uuid = ?
+	`, actorUUID, ref.UUID); err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
-	defer rows.Close()
 
-	var comments []map[string]interface{}
-	for rows.Next() {
-		var uuid, id, taskUUID, actorUUID, body, createdAt string
-		var actorSlug, actorRole, taskIDStr string
-		var meta, updatedAt, deletedAt, deletedByActorUUID sql.NullString
-		var etag int64
-
-		if err := rows.Scan(&uuid, &id, &taskUUID, &actorUUID, &body, &meta, &etag,
-			&createdAt, &updatedAt, &deletedAt, &deletedByActorUUID,
-			&actorSlug, &actorRole, &taskIDStr); err != nil {
-			s.writeError(w, http.StatusBadRequest, err)
-			return
-		}
+	if err := events.NewWriter(s.db.DB).LogCommentDeleted(tx, actorUUID, &domain.Comment{
+		UUID:     ref.UUID,
+		ID:       ref.ID,
+		TaskUUID: ref.TaskUUID,
+		ETag:     ref.ETag + 1,
+	}); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
 
-		comment := map[string]interface{}{
-			"uuid":       uuid,
-			"id":         id,
-			"task_uuid":  taskUUID,
-			"task_id":    taskIDStr,
-			"actor_uuid": actorUUID,
-			"actor_slug": actorSlug,
-			"actor_role": actorRole,
-			"body":       body,
-			"etag":       etag,
-			"created_at": createdAt,
-		}
+	if err := tx.Commit(); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
 
-		if meta.Valid && meta.String != "" {
-			comment["meta"] = meta.String
-		}
-		if updatedAt.Valid {
-			comment["updated_at"] = updatedAt.String
-		}
-		if deletedAt.Valid {
-			comment["deleted_at"] = deletedAt.String
-		}
-		if deletedByActorUUID.Valid {
-			comment["deleted_by_actor_uuid"] = deletedByActorUUID.String
-		}
+	webhooks.DispatchTask(s.db, ref.TaskUUID)
 
-		comments = append(comments, comment)
+	comment, err := loadDaemonComment(s.db, ref.UUID)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
 	}
 
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"comments": comments,
+		"comment": comment,
 	})
 }
 
-type commentsCreateRequest struct {
-	Task    string                 `json:"task"`
-	Body    string                 `json:"body"`
-	Meta    map[string]interface{} `json:"meta,omitempty"`
-	IfMatch int64                  `json:"ifMatch,omitempty"`
+type commentsRestoreRequest struct {
+	Comment string `json:"comment"`
+	IfMatch int64  `json:"ifMatch,omitempty"`
 }
 
-func (s *daemonServer) handleCommentsCreate(w http.ResponseWriter, r *http.Request) {
+func (s *daemonServer) handleCommentsRestore(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
 		return
 	}
 
-	var req commentsCreateRequest
+	var req commentsRestoreRequest
 	if err := s.decodeJSON(r, &req); err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	if req.Task == "" || strings.TrimSpace(req.Body) == "" {
-		s.writeError(w, http.StatusBadRequest, fmt.Errorf("task and body required"))
+	if req.Comment == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("comment required"))
 		return
 	}
 
@@ -969,19 +3135,6 @@ func (s *daemonServer) handleCommentsCreate(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	taskUUID, _, err := selectors.ResolveTask(s.db, req.Task)
-	if err != nil {
-		s.writeError(w, http.StatusNotFound, err)
-		return
-	}
-
-	metaStr := ""
-	if req.Meta != nil {
-		if data, err := json.Marshal(req.Meta); err == nil {
-			metaStr = string(data)
-		}
-	}
-
 	tx, err := s.db.Begin()
 	if err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
@@ -989,66 +3142,49 @@ func (s *daemonServer) handleCommentsCreate(w http.ResponseWriter, r *http.Reque
 	}
 	defer tx.Rollback()
 
-	if req.IfMatch > 0 {
-		var currentEtag int64
-		if err := tx.QueryRow("SELECT etag FROM tasks WHERE uuid = ?", taskUUID).Scan(&currentEtag); err != nil {
-			s.writeError(w, http.StatusBadRequest, err)
-			return
-		}
-		if currentEtag != req.IfMatch {
-			s.writeError(w, http.StatusConflict, fmt.Errorf("etag mismatch: task has etag %d, expected %d", currentEtag, req.IfMatch))
-			return
-		}
-	}
-
-	var nextSeq int
-	if err := tx.QueryRow("SELECT COALESCE(MAX(CAST(SUBSTR(id, 3) AS INTEGER)), 0) + 1 FROM comments").Scan(&nextSeq); err != nil {
-		s.writeError(w, http.StatusBadRequest, err)
+	ref, err := lookupDaemonComment(tx, req.Comment)
+	if err != nil {
+		s.writeError(w, commentLookupStatus(err), err)
 		return
 	}
-
-	if _, err := tx.Exec("UPDATE comment_sequences SET value = ? WHERE name = 'next_comment'", nextSeq); err != nil {
-		s.writeError(w, http.StatusBadRequest, err)
+	if err := s.checkTaskInScope(r, ref.TaskUUID); err != nil {
+		s.writeError(w, http.StatusNotFound, err)
 		return
 	}
-
-	commentUUID := generateUUID()
-	commentID := fmt.Sprintf("C-%05d", nextSeq)
-
-	var metaPtr *string
-	if metaStr != "" {
-		metaPtr = &metaStr
+	if !ref.Deleted {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("comment %s is not deleted", ref.ID))
+		return
 	}
-
-	if _, err := tx.Exec(`
-		INSERT INTO comments (uuid, id, task_uuid, actor_uuid, body, meta, etag)
-		VALUES (?, ?, ?, ?, ?, ?, 1)
-	`, commentUUID, commentID, taskUUID, actorUUID, strings.TrimSpace(req.Body), metaPtr); err != nil {
-		s.writeError(w, http.StatusBadRequest, err)
+	if req.IfMatch > 0 && req.IfMatch != ref.ETag {
+		s.writeError(w, http.StatusConflict, &domain.ETagMismatchError{Expected: req.IfMatch, Actual: ref.ETag})
 		return
 	}
 
-	var comment domain.Comment
-	var createdAtStr string
-	if err := tx.QueryRow(`
-		SELECT uuid, id, task_uuid, actor_uuid, body, meta, etag, created_at
-		FROM comments WHERE uuid = ?
-	`, commentUUID).Scan(
-		&comment.UUID, &comment.ID, &comment.TaskUUID, &comment.ActorUUID,
-		&comment.Body, &comment.Meta, &comment.ETag, &createdAtStr,
-	); err != nil {
+	if _, err := tx.Exec(`
+		UPDATE comments
+		SET deleted_at = NULL,
+		    deleted_by_actor_uuid = NULL,
+		    etag = etag + 1
+		WHERE uuid = ?
+	`, ref.UUID); err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	payload := fmt.Sprintf(`{"task_id":"%s","comment_id":"%s","actor_id":"%s"}`, comment.TaskUUID, comment.ID, comment.ActorUUID)
+	newETag := ref.ETag + 1
+	payload, _ := json.Marshal(map[string]interface{}{
+		"task_id":              ref.TaskUUID,
+		"comment_id":           ref.ID,
+		"restored_by_actor_id": actorUUID,
+	})
+	payloadStr := string(payload)
 	if err := events.NewWriter(s.db.DB).LogEvent(tx, &domain.Event{
 		ActorUUID:    &actorUUID,
 		ResourceType: "comment",
-		ResourceUUID: &comment.UUID,
-		EventType:    "comment.created",
-		ETag:         &comment.ETag,
-		Payload:      &payload,
+		ResourceUUID: &ref.UUID,
+		EventType:    "comment.restored",
+		ETag:         &newETag,
+		Payload:      &payloadStr,
 	}); err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
@@ -1059,7 +3195,13 @@ func (s *daemonServer) handleCommentsCreate(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	webhooks.DispatchTask(s.db, taskUUID)
+	webhooks.DispatchTask(s.db, ref.TaskUUID)
+
+	comment, err := loadDaemonComment(s.db, ref.UUID)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
 
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
 		"comment": comment,
@@ -1082,7 +3224,7 @@ func (s *daemonServer) handleRelationsList(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	taskUUID, _, err := selectors.ResolveTask(s.db, req.Task)
+	taskUUID, _, err := s.resolveTask(r, req.Task)
 	if err != nil {
 		s.writeError(w, http.StatusNotFound, err)
 		return
@@ -1178,13 +3320,13 @@ func (s *daemonServer) handleRelationsCreate(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	fromUUID, _, err := selectors.ResolveTask(s.db, req.From)
+	fromUUID, _, err := s.resolveTask(r, req.From)
 	if err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	toUUID, _, err := selectors.ResolveTask(s.db, req.To)
+	toUUID, _, err := s.resolveTask(r, req.To)
 	if err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
@@ -1195,16 +3337,15 @@ func (s *daemonServer) handleRelationsCreate(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if _, err := s.db.Exec(`
-		INSERT INTO task_relations (from_task_uuid, to_task_uuid, kind, created_by_actor_uuid)
-		VALUES (?, ?, ?, ?)
-	`, fromUUID, toUUID, req.Kind, actorUUID); err != nil {
+	created, err := insertTaskRelation(s.db, fromUUID, toUUID, req.Kind, actorUUID)
+	if err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
 	s.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"ok": true,
+		"ok":      true,
+		"created": created,
 	})
 }
 
@@ -1231,29 +3372,30 @@ func (s *daemonServer) handleRelationsDelete(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	fromUUID, _, err := selectors.ResolveTask(s.db, req.From)
+	actorUUID, err := s.resolveActorUUID(r)
 	if err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	toUUID, _, err := selectors.ResolveTask(s.db, req.To)
+	fromUUID, _, err := s.resolveTask(r, req.From)
 	if err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	result, err := s.db.Exec(`
-		DELETE FROM task_relations
-		WHERE from_task_uuid = ? AND to_task_uuid = ? AND kind = ?
-	`, fromUUID, toUUID, req.Kind)
+	toUUID, _, err := s.resolveTask(r, req.To)
 	if err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
+	deleted, err := deleteTaskRelation(s.db, fromUUID, toUUID, req.Kind, actorUUID)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !deleted {
 		s.writeError(w, http.StatusNotFound, fmt.Errorf("relation not found"))
 		return
 	}
@@ -1263,7 +3405,9 @@ func (s *daemonServer) handleRelationsDelete(w http.ResponseWriter, r *http.Requ
 	})
 }
 
-type actorsListRequest struct{}
+type actorsListRequest struct {
+	IncludeInactive bool `json:"include_inactive,omitempty"`
+}
 
 func (s *daemonServer) handleActorsList(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -1271,8 +3415,14 @@ func (s *daemonServer) handleActorsList(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	var req actorsListRequest
+	if err := s.decodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
 	resolver := actors.NewResolver(s.db.DB)
-	actorsList, err := resolver.List()
+	actorsList, err := resolver.List(req.IncludeInactive)
 	if err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
@@ -1324,10 +3474,12 @@ func (s *daemonServer) handleActorsCreate(w http.ResponseWriter, r *http.Request
 	})
 }
 
+// actorsUpdateRequest is a partial update: omitted fields are left alone,
+// and an empty display_name clears it.
 type actorsUpdateRequest struct {
-	Actor       string `json:"actor"`
-	DisplayName string `json:"display_name,omitempty"`
-	Role        string `json:"role,omitempty"`
+	Actor       string  `json:"actor"`
+	DisplayName *string `json:"display_name,omitempty"`
+	Role        *string `json:"role,omitempty"`
 }
 
 func (s *daemonServer) handleActorsUpdate(w http.ResponseWriter, r *http.Request) {
@@ -1356,13 +3508,21 @@ func (s *daemonServer) handleActorsUpdate(w http.ResponseWriter, r *http.Request
 
 	setClauses := []string{}
 	args := []interface{}{}
-	if req.DisplayName != "" {
+	if req.DisplayName != nil {
 		setClauses = append(setClauses, "display_name = ?")
-		args = append(args, req.DisplayName)
+		if *req.DisplayName == "" {
+			args = append(args, nil)
+		} else {
+			args = append(args, *req.DisplayName)
+		}
 	}
-	if req.Role != "" {
+	if req.Role != nil {
+		if err := domain.ValidateActorRole(*req.Role); err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
 		setClauses = append(setClauses, "role = ?")
-		args = append(args, req.Role)
+		args = append(args, *req.Role)
 	}
 
 	if len(setClauses) == 0 {
@@ -1388,6 +3548,53 @@ func (s *daemonServer) handleActorsUpdate(w http.ResponseWriter, r *http.Request
 	})
 }
 
+type actorsSetActiveRequest struct {
+	Actor string `json:"actor"`
+}
+
+func (s *daemonServer) handleActorsDeactivate(w http.ResponseWriter, r *http.Request) {
+	s.setActorActive(w, r, false)
+}
+
+func (s *daemonServer) handleActorsReactivate(w http.ResponseWriter, r *http.Request) {
+	s.setActorActive(w, r, true)
+}
+
+func (s *daemonServer) setActorActive(w http.ResponseWriter, r *http.Request, active bool) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req actorsSetActiveRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Actor == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("actor required"))
+		return
+	}
+
+	resolver := actors.NewResolver(s.db.DB)
+	actorUUID, err := resolver.Resolve(req.Actor)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	actor, err := resolver.SetActive(actorUUID, active)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"actor": actor,
+	})
+}
+
 type bundleCreateRequest struct {
 	Out             string   `json:"out,omitempty"`
 	Actor           string   `json:"actor,omitempty"`
@@ -1423,24 +3630,25 @@ func (s *daemonServer) handleBundleCreate(w http.ResponseWriter, r *http.Request
 		Since:           req.Since,
 		Until:           req.Until,
 		WithAttachments: req.WithAttachments,
+		AttachDir:       s.cfg.AttachDir,
 		WithEvents:      true,
 		IncludeRefs:     req.IncludeRefs,
-		Version:         "0.1.0",
-		Commit:          "",
-		BuildDate:       "",
+		Version:         Version,
+		Commit:          GitCommit,
+		BuildDate:       BuildDate,
 	}
 	if req.WithEvents != nil {
 		opts.WithEvents = *req.WithEvents
 	}
 
 	if req.Project != "" {
-		projectUUID, _, err := selectors.ResolveContainer(s.db, req.Project)
+		projectUUID, _, err := s.resolveContainer(r, req.Project)
 		if err != nil {
 			s.writeError(w, http.StatusBadRequest, err)
 			return
 		}
-		var projectPath string
-		if err := s.db.QueryRow("SELECT path FROM v_container_paths WHERE uuid = ?", projectUUID).Scan(&projectPath); err != nil {
+		projectPath, err := s.lookups(r).containerPath(projectUUID)
+		if err != nil {
 			s.writeError(w, http.StatusBadRequest, err)
 			return
 		}
@@ -1466,6 +3674,7 @@ func (s *daemonServer) handleBundleCreate(w http.ResponseWriter, r *http.Request
 		"tasks_count":      len(b.Tasks),
 		"containers_count": len(b.Containers),
 		"refs_count":       len(b.Refs),
+		"ref_index_count":  len(b.RefIndex),
 		"manifest":         b.Manifest,
 	})
 }
@@ -1493,13 +3702,14 @@ func (s *daemonServer) handleBundleApply(w http.ResponseWriter, r *http.Request)
 		from = ".wrkq"
 	}
 
-	b, err := bundle.Load(from)
+	b, err := bundle.LoadFrom(from)
 	if err != nil {
 		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
-	if b.Manifest.MachineInterfaceVersion != 1 {
-		s.writeError(w, http.StatusBadRequest, fmt.Errorf("bundle machine_interface_version (%d) doesn't match current version (1)", b.Manifest.MachineInterfaceVersion))
+	defer b.Close()
+	if err := b.Upgrade(); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
 		return
 	}
 
@@ -1537,6 +3747,26 @@ func (s *daemonServer) handleBundleApply(w http.ResponseWriter, r *http.Request)
 			}
 			result.TasksApplied++
 		}
+
+		if result.Success && !req.DryRun && b.Manifest.WithAttachments {
+			attached, err := reattachFiles(s.db, s.cfg, actorUUID, b.Dir)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("attachments: %v", err))
+				result.Success = false
+			} else {
+				result.AttachmentsAdded = attached
+			}
+		}
+
+		if result.Success && !req.DryRun && len(b.TimeEntries) > 0 {
+			added, err := applyTimeEntries(s.db, actorUUID, b.TimeEntries)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("time entries: %v", err))
+				result.Success = false
+			} else {
+				result.TimeEntriesAdded = added
+			}
+		}
 	} else {
 		tx, err := s.db.Begin()
 		if err != nil {
@@ -1575,194 +3805,52 @@ func (s *daemonServer) handleBundleApply(w http.ResponseWriter, r *http.Request)
 			}
 		}
 
-		if result.Success && !req.DryRun {
-			if err := tx.Commit(); err != nil {
-				s.writeError(w, http.StatusBadRequest, err)
-				return
-			}
-		}
-	}
-
-	if result.Success && !req.DryRun && b.Manifest.WithAttachments {
-		attachmentsDir := filepath.Join(b.Dir, "attachments")
-		if _, err := os.Stat(attachmentsDir); err == nil {
-			attached, err := reattachFilesDaemon(s.cfg, attachmentsDir)
+		var written []string
+		if result.Success && !req.DryRun && b.Manifest.WithAttachments {
+			attached, paths, err := reattachFilesTx(tx, ew, s.cfg, actorUUID, b.Dir)
+			written = paths
 			if err != nil {
+				removeAttachmentFiles(written)
 				result.Errors = append(result.Errors, fmt.Sprintf("attachments: %v", err))
 				result.Success = false
 			} else {
 				result.AttachmentsAdded = attached
 			}
 		}
-	}
-
-	s.writeJSON(w, http.StatusOK, result)
-}
-
-func loadTaskDetail(database *db.DB, taskUUID string, includeComments bool, includeRelations bool) (*Task, error) {
-	var id, slug, title, state, description, kind string
-	var priority int
-	var startAt, dueAt, labels, completedAt, archivedAt, deletedAt *string
-	var parentTaskUUID, assigneeActorUUID *string
-	var createdAt, updatedAt string
-	var etag int64
-	var projectUUID, createdByUUID, updatedByUUID string
-
-	err := database.QueryRow(`
-		SELECT id, slug, title, project_uuid, state, priority,
-		       kind, parent_task_uuid, assignee_actor_uuid,
-		       start_at, due_at, labels, description, etag,
-		       created_at, updated_at, completed_at, archived_at, deleted_at,
-		       created_by_actor_uuid, updated_by_actor_uuid
-		FROM tasks WHERE uuid = ?
-	`, taskUUID).Scan(
-		&id, &slug, &title, &projectUUID, &state, &priority,
-		&kind, &parentTaskUUID, &assigneeActorUUID,
-		&startAt, &dueAt, &labels, &description, &etag,
-		&createdAt, &updatedAt, &completedAt, &archivedAt, &deletedAt,
-		&createdByUUID, &updatedByUUID,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get task: %w", err)
-	}
-
-	var createdBySlug, updatedBySlug string
-	database.QueryRow("SELECT slug FROM actors WHERE uuid = ?", createdByUUID).Scan(&createdBySlug)
-	database.QueryRow("SELECT slug FROM actors WHERE uuid = ?", updatedByUUID).Scan(&updatedBySlug)
-
-	var projectID string
-	database.QueryRow("SELECT id FROM containers WHERE uuid = ?", projectUUID).Scan(&projectID)
-
-	var parentTaskID *string
-	if parentTaskUUID != nil {
-		var ptID string
-		if err := database.QueryRow("SELECT id FROM tasks WHERE uuid = ?", *parentTaskUUID).Scan(&ptID); err == nil {
-			parentTaskID = &ptID
-		}
-	}
-
-	var assigneeSlug *string
-	if assigneeActorUUID != nil {
-		var aSlug string
-		if err := database.QueryRow("SELECT slug FROM actors WHERE uuid = ?", *assigneeActorUUID).Scan(&aSlug); err == nil {
-			assigneeSlug = &aSlug
-		}
-	}
-
-	task := &Task{
-		ID:             id,
-		UUID:           taskUUID,
-		ProjectID:      projectID,
-		ProjectUUID:    projectUUID,
-		Slug:           slug,
-		Title:          title,
-		State:          state,
-		Priority:       priority,
-		Kind:           kind,
-		ParentTaskID:   parentTaskID,
-		ParentTaskUUID: parentTaskUUID,
-		AssigneeSlug:   assigneeSlug,
-		AssigneeUUID:   assigneeActorUUID,
-		StartAt:        startAt,
-		DueAt:          dueAt,
-		Labels:         labels,
-		Description:    description,
-		Etag:           etag,
-		CreatedAt:      createdAt,
-		UpdatedAt:      updatedAt,
-		CompletedAt:    completedAt,
-		ArchivedAt:     archivedAt,
-		DeletedAt:      deletedAt,
-		CreatedBy:      createdBySlug,
-		UpdatedBy:      updatedBySlug,
-	}
-
-	if includeComments {
-		rows, err := database.Query(`
-			SELECT c.id, c.created_at, c.body, a.slug as actor_slug, a.role as actor_role
-			FROM comments c
-			LEFT JOIN actors a ON c.actor_uuid = a.uuid
-			WHERE c.task_uuid = ? AND c.deleted_at IS NULL
-			ORDER BY c.created_at ASC
-		`, taskUUID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to query comments: %w", err)
-		}
 
-		var comments []Comment
-		for rows.Next() {
-			var comment Comment
-			if err := rows.Scan(&comment.ID, &comment.CreatedAt, &comment.Body, &comment.ActorSlug, &comment.ActorRole); err != nil {
-				rows.Close()
-				return nil, fmt.Errorf("failed to scan comment: %w", err)
+		if result.Success && !req.DryRun {
+			added, err := applyTimeEntriesTx(tx, ew, actorUUID, b.TimeEntries)
+			if err != nil {
+				removeAttachmentFiles(written)
+				result.Errors = append(result.Errors, fmt.Sprintf("time entries: %v", err))
+				result.Success = false
+			} else {
+				result.TimeEntriesAdded = added
 			}
-			comments = append(comments, comment)
-		}
-		rows.Close()
-
-		if len(comments) > 0 {
-			task.Comments = comments
-		}
-	}
-
-	if includeRelations {
-		var relations []Relation
-
-		outgoingRows, err := database.Query(`
-			SELECT r.kind, r.created_at,
-			       t.id AS task_id, t.uuid AS task_uuid, t.slug, t.title,
-			       a.id AS created_by_id
-			FROM task_relations r
-			JOIN tasks t ON r.to_task_uuid = t.uuid
-			JOIN actors a ON r.created_by_actor_uuid = a.uuid
-			WHERE r.from_task_uuid = ?
-			ORDER BY r.kind, t.id
-		`, taskUUID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to query outgoing relations: %w", err)
 		}
 
-		for outgoingRows.Next() {
-			var rel Relation
-			if err := outgoingRows.Scan(&rel.Kind, &rel.CreatedAt, &rel.TaskID, &rel.TaskUUID, &rel.TaskSlug, &rel.TaskTitle, &rel.CreatedByID); err != nil {
-				outgoingRows.Close()
-				return nil, fmt.Errorf("failed to scan relation: %w", err)
+		if result.Success && !req.DryRun {
+			if err := tx.Commit(); err != nil {
+				removeAttachmentFiles(written)
+				s.writeError(w, http.StatusBadRequest, err)
+				return
 			}
-			rel.Direction = "outgoing"
-			relations = append(relations, rel)
-		}
-		outgoingRows.Close()
-
-		incomingRows, err := database.Query(`
-			SELECT r.kind, r.created_at,
-			       t.id AS task_id, t.uuid AS task_uuid, t.slug, t.title,
-			       a.id AS created_by_id
-			FROM task_relations r
-			JOIN tasks t ON r.from_task_uuid = t.uuid
-			JOIN actors a ON r.created_by_actor_uuid = a.uuid
-			WHERE r.to_task_uuid = ?
-			ORDER BY r.kind, t.id
-		`, taskUUID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to query incoming relations: %w", err)
 		}
+	}
 
-		for incomingRows.Next() {
-			var rel Relation
-			if err := incomingRows.Scan(&rel.Kind, &rel.CreatedAt, &rel.TaskID, &rel.TaskUUID, &rel.TaskSlug, &rel.TaskTitle, &rel.CreatedByID); err != nil {
-				incomingRows.Close()
-				return nil, fmt.Errorf("failed to scan relation: %w", err)
-			}
-			rel.Direction = "incoming"
-			relations = append(relations, rel)
-		}
-		incomingRows.Close()
+	s.writeJSON(w, http.StatusOK, result)
+}
 
-		if len(relations) > 0 {
-			task.Relations = relations
-		}
+// loadTaskDetail loads one task in the tasks/get shape with loadTaskDetails.
+func loadTaskDetail(database *db.DB, taskUUID string, includeComments bool, includeRelations bool) (*Task, error) {
+	tasks, err := loadTaskDetails(database, []string{taskUUID}, includeComments, includeRelations)
+	if err != nil {
+		return nil, err
+	}
+	task := tasks[taskUUID]
+	if task == nil {
+		return nil, fmt.Errorf("failed to get task: %w", sql.ErrNoRows)
 	}
-
 	return task, nil
 }
 
@@ -1808,50 +3896,3 @@ func getLabelsField(fields map[string]interface{}, key string) string {
 	}
 	return ""
 }
-
-func reattachFilesDaemon(cfg *config.Config, attachmentsDir string) (int, error) {
-	count := 0
-
-	entries, err := os.ReadDir(attachmentsDir)
-	if err != nil {
-		return 0, err
-	}
-
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-
-		taskUUID := entry.Name()
-		taskAttachDir := filepath.Join(attachmentsDir, taskUUID)
-
-		files, err := os.ReadDir(taskAttachDir)
-		if err != nil {
-			return count, fmt.Errorf("failed to read %s: %w", taskAttachDir, err)
-		}
-
-		for _, file := range files {
-			if file.IsDir() {
-				continue
-			}
-
-			filePath := filepath.Join(taskAttachDir, file.Name())
-
-			attachCmd := exec.Command("wrkq", "attach", "put", "t:"+taskUUID, filePath)
-			attachCmd.Env = os.Environ()
-			attachCmd.Env = append(attachCmd.Env, "WRKQ_DB_PATH="+cfg.DBPath)
-			if actorIdentifier := cfg.GetActorID(); actorIdentifier != "" {
-				attachCmd.Env = append(attachCmd.Env, "WRKQ_ACTOR="+actorIdentifier)
-			}
-
-			output, err := attachCmd.CombinedOutput()
-			if err != nil {
-				return count, fmt.Errorf("wrkq attach put failed for %s: %w\nOutput: %s", file.Name(), err, output)
-			}
-
-			count++
-		}
-	}
-
-	return count, nil
-}
@@ -0,0 +1,420 @@
+package cli
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lherron/wrkq/internal/attach"
+	"github.com/lherron/wrkq/internal/domain"
+	"github.com/lherron/wrkq/internal/events"
+)
+
+type attachmentsListRequest struct {
+	Task   string `json:"task"`
+	Limit  int    `json:"limit,omitempty"`
+	Cursor string `json:"cursor,omitempty"`
+}
+
+func (s *daemonServer) handleAttachmentsList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req attachmentsListRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Task == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("task required"))
+		return
+	}
+
+	taskUUID, _, err := s.resolveTask(r, req.Task)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	attachments, nextCursor, err := listTaskAttachments(s.db, taskUUID, req.Cursor, limit)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"attachments": attachments,
+		"next_cursor": nextCursor,
+	})
+}
+
+// handleAttachmentsGet streams an attachment's content.
+//
+// Query parameters:
+//   - attachment: an ATT- friendly ID or UUID (required).
+//   - task: optional task selector the attachment must belong to.
+//
+// The response carries the stored MIME type, the filename in
+// Content-Disposition and the SHA-256 checksum in X-Wrkq-Checksum. Range
+// requests are supported.
+func (s *daemonServer) handleAttachmentsGet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	query := r.URL.Query()
+	selector := query.Get("attachment")
+	if selector == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("attachment required"))
+		return
+	}
+
+	// Attachments are only served through a task that still resolves
+	var taskUUID, filename, relativePath string
+	var mimeType, checksum sql.NullString
+	err := s.db.QueryRow(`
+		SELECT a.task_uuid, a.filename, a.relative_path, a.mime_type, a.checksum
+		FROM attachments a
+		JOIN v_task_paths t ON t.uuid = a.task_uuid
+		WHERE a.id = ? OR a.uuid = ?
+	`, selector, selector).Scan(&taskUUID, &filename, &relativePath, &mimeType, &checksum)
+	if err == sql.ErrNoRows {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("attachment not found: %s", selector))
+		return
+	}
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := s.checkTaskInScope(r, taskUUID); err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	if task := query.Get("task"); task != "" {
+		expected, _, err := s.resolveTask(r, task)
+		if err != nil {
+			s.writeError(w, http.StatusNotFound, err)
+			return
+		}
+		if expected != taskUUID {
+			s.writeError(w, http.StatusNotFound, fmt.Errorf("attachment %s does not belong to task %s", selector, task))
+			return
+		}
+	}
+
+	path, err := attachmentFilePath(s.cfg.AttachDir, relativePath)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("attachment file missing: %s", relativePath))
+		return
+	}
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	contentType := mimeType.String
+	if contentType == "" {
+		contentType = attach.DetectMimeType(filename)
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if checksum.Valid {
+		w.Header().Set("X-Wrkq-Checksum", checksum.String)
+	}
+	http.ServeContent(w, r, filename, info.ModTime(), file)
+}
+
+// attachmentFilePath resolves a stored relative path under attachDir,
+// refusing paths that would escape it.
+func attachmentFilePath(attachDir, relativePath string) (string, error) {
+	path := attach.AbsolutePath(attachDir, relativePath)
+	rel, err := filepath.Rel(attachDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("attachment path escapes the attachment directory: %s", relativePath)
+	}
+	return path, nil
+}
+
+// handleAttachmentsPut stores an uploaded file as an attachment on a task.
+//
+// The body is either multipart/form-data with the content in a "file" part,
+// or the raw content with the filename in X-Wrkq-Filename (or ?name=) and
+// the MIME type in Content-Type. The task selector is given as ?task= or a
+// "task" form field; "name" and "mime" form fields override the part's own.
+//
+// Content identical (by checksum) to a file already attached to the same
+// task is hard-linked to that file rather than written again, so each row
+// keeps its own path but the blob is stored once. Removing either
+// attachment leaves the other intact. When linking fails (e.g. on
+// filesystems without hard links) the content is copied as usual.
+func (s *daemonServer) handleAttachmentsPut(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	maxMB := int64(s.cfg.AttachmentsMaxMB)
+	if maxMB > 0 {
+		// Leave room for multipart framing; the content itself is checked
+		// against the limit once it has been read
+		r.Body = http.MaxBytesReader(w, r.Body, maxMB*1024*1024+1024*1024)
+	}
+
+	var content io.Reader
+	var taskSelector, filename, mimeType string
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			s.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid multipart body: %w", err))
+			return
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, fmt.Errorf("file part required: %w", err))
+			return
+		}
+		defer file.Close()
+		content = file
+		taskSelector = r.FormValue("task")
+		filename = header.Filename
+		mimeType = header.Header.Get("Content-Type")
+		if name := r.FormValue("name"); name != "" {
+			filename = name
+		}
+		if m := r.FormValue("mime"); m != "" {
+			mimeType = m
+		}
+	} else {
+		content = r.Body
+		taskSelector = r.URL.Query().Get("task")
+		filename = r.Header.Get("X-Wrkq-Filename")
+		if name := r.URL.Query().Get("name"); name != "" {
+			filename = name
+		}
+		mimeType = r.Header.Get("Content-Type")
+	}
+
+	if taskSelector == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("task required"))
+		return
+	}
+	if filename == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("filename required"))
+		return
+	}
+	if filename == "." || filename == ".." || strings.ContainsAny(filename, "/\\") {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid filename: %q", filename))
+		return
+	}
+	// Clients that don't know the type send application/octet-stream
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		mimeType = attach.DetectMimeType(filename)
+	}
+
+	taskUUID, taskID, err := s.resolveTask(r, taskSelector)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	actorUUID, err := s.resolveActorUUID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var existingCount int
+	if err := s.db.QueryRow(`
+		SELECT COUNT(*) FROM attachments WHERE task_uuid = ? AND filename = ?
+	`, taskUUID, filename).Scan(&existingCount); err != nil {
+		s.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to check existing attachments: %w", err))
+		return
+	}
+	if existingCount > 0 {
+		s.writeError(w, http.StatusConflict, fmt.Errorf("attachment with filename %q already exists for task %s", filename, taskID))
+		return
+	}
+
+	if err := attach.EnsureTaskDir(s.cfg.AttachDir, taskUUID); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Spool the upload next to its destination so the checksum is known
+	// before deciding whether a copy is needed
+	spool, err := os.CreateTemp(attach.TaskDir(s.cfg.AttachDir, taskUUID), ".upload-*")
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to create upload file: %w", err))
+		return
+	}
+	spoolPath := spool.Name()
+	defer os.Remove(spoolPath)
+	_, err = io.Copy(spool, content)
+	if closeErr := spool.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("failed to read upload: %w", err))
+		return
+	}
+
+	checksum, err := attach.Checksum(spoolPath)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	size, err := attach.GetFileSize(spoolPath)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := attach.ValidateSize(size, maxMB); err != nil {
+		s.writeError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+
+	relativePath := attach.RelativePath(taskUUID, filename)
+	written := attach.AbsolutePath(s.cfg.AttachDir, relativePath)
+	if _, err := os.Stat(written); err == nil {
+		s.writeError(w, http.StatusConflict, fmt.Errorf("file already exists at %s", relativePath))
+		return
+	}
+	existing, err := s.findAttachmentBlob(taskUUID, checksum)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	deduped := existing != "" && os.Link(existing, written) == nil
+	if !deduped {
+		if _, _, err := attach.CopyFile(spoolPath, written); err != nil {
+			s.writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	attachment, err := s.insertUploadedAttachment(actorUUID, taskUUID, filename, relativePath, mimeType, size, checksum)
+	if err != nil {
+		os.Remove(written)
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"attachment":   attachment,
+		"deduplicated": deduped,
+	})
+}
+
+// findAttachmentBlob returns the path of a file already attached to taskUUID
+// whose content has the given checksum, or "" if there is none.
+func (s *daemonServer) findAttachmentBlob(taskUUID, checksum string) (string, error) {
+	rows, err := s.db.Query(`
+		SELECT relative_path FROM attachments
+		WHERE task_uuid = ? AND checksum = ?
+		ORDER BY created_at, id
+	`, taskUUID, checksum)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up attachment checksum: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var relativePath string
+		if err := rows.Scan(&relativePath); err != nil {
+			return "", fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		path, err := attachmentFilePath(s.cfg.AttachDir, relativePath)
+		if err != nil {
+			continue
+		}
+		// Trust the recorded checksum only if the file still matches it
+		if onDisk, err := attach.Checksum(path); err == nil && onDisk == checksum {
+			return path, nil
+		}
+	}
+	return "", rows.Err()
+}
+
+// insertUploadedAttachment records an uploaded file and logs
+// attachment.created, returning the new attachment's metadata.
+func (s *daemonServer) insertUploadedAttachment(actorUUID, taskUUID, filename, relativePath, mimeType string, size int64, checksum string) (map[string]interface{}, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO attachments (id, task_uuid, filename, relative_path, mime_type, size_bytes, checksum, created_by_actor_uuid)
+		VALUES ('', ?, ?, ?, ?, ?, ?, ?)
+	`, taskUUID, filename, relativePath, mimeType, size, checksum, actorUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert attachment: %w", err)
+	}
+
+	var attachUUID, attachID, createdAt string
+	lastID, _ := result.LastInsertId()
+	if err := tx.QueryRow(`
+		SELECT uuid, id, created_at FROM attachments WHERE rowid = ?
+	`, lastID).Scan(&attachUUID, &attachID, &createdAt); err != nil {
+		return nil, fmt.Errorf("failed to get attachment ID: %w", err)
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"attachment_id": attachID,
+		"filename":      filename,
+		"size_bytes":    size,
+		"mime_type":     mimeType,
+		"source":        "daemon",
+	})
+	payloadStr := string(payload)
+	if err := events.NewWriter(s.db.DB).LogEvent(tx, &domain.Event{
+		ActorUUID:    &actorUUID,
+		ResourceType: "attachment",
+		ResourceUUID: &attachUUID,
+		EventType:    "attachment.created",
+		Payload:      &payloadStr,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to log event: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return map[string]interface{}{
+		"uuid":          attachUUID,
+		"id":            attachID,
+		"filename":      filename,
+		"relative_path": relativePath,
+		"mime_type":     mimeType,
+		"size_bytes":    size,
+		"checksum":      checksum,
+		"created_at":    createdAt,
+	}, nil
+}
@@ -0,0 +1,222 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/lherron/wrkq/internal/db"
+	"github.com/lherron/wrkq/internal/domain"
+	"github.com/lherron/wrkq/internal/selectors"
+)
+
+type tasksBatchGetRequest struct {
+	Selectors        []string `json:"selectors"`
+	IncludeComments  *bool    `json:"include_comments,omitempty"`
+	IncludeRelations *bool    `json:"include_relations,omitempty"`
+}
+
+// batchGetEntry is one entry of a tasks/batch_get response: the task a
+// selector names, or why it names none.
+type batchGetEntry struct {
+	Task       *Task                 `json:"task,omitempty"`
+	Error      string                `json:"error,omitempty"`
+	Candidates []selectors.Candidate `json:"candidates,omitempty"`
+}
+
+// handleTasksBatchGet fetches many tasks at once, keyed by the selector as
+// given, in the tasks/get shape. Like /v1/resolve, a selector that fails
+// gets an error entry instead of failing the request. Selectors are resolved
+// one by one, but the tasks, their comments and their relations are each
+// loaded with a single query.
+func (s *daemonServer) handleTasksBatchGet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req tasksBatchGetRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(req.Selectors) == 0 {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("selectors is required"))
+		return
+	}
+	if len(req.Selectors) > maxResolveSelectors {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("at most %d selectors per request", maxResolveSelectors))
+		return
+	}
+	includeComments := true
+	includeRelations := true
+	if req.IncludeComments != nil {
+		includeComments = *req.IncludeComments
+	}
+	if req.IncludeRelations != nil {
+		includeRelations = *req.IncludeRelations
+	}
+
+	results := make(map[string]batchGetEntry, len(req.Selectors))
+	uuidsBySelector := make(map[string]string, len(req.Selectors))
+	var uuids []string
+	for _, sel := range req.Selectors {
+		if _, ok := results[sel]; ok {
+			continue
+		}
+		taskUUID, _, err := s.resolveTask(r, sel)
+		if err != nil {
+			entry := batchGetEntry{Error: err.Error()}
+			var ambiguous *selectors.AmbiguousError
+			if errors.As(err, &ambiguous) {
+				entry.Candidates = ambiguous.Candidates
+			}
+			results[sel] = entry
+			continue
+		}
+		results[sel] = batchGetEntry{}
+		uuidsBySelector[sel] = taskUUID
+		uuids = append(uuids, taskUUID)
+	}
+
+	tasks, err := loadTaskDetails(s.db, uuids, includeComments, includeRelations)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	for sel, taskUUID := range uuidsBySelector {
+		if task := tasks[taskUUID]; task != nil {
+			results[sel] = batchGetEntry{Task: task}
+		} else {
+			results[sel] = batchGetEntry{Error: fmt.Sprintf("task not found: %s", sel)}
+		}
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{"tasks": results})
+}
+
+// loadTaskDetails loads the tasks with the given UUIDs in the tasks/get
+// shape, keyed by UUID; UUIDs with no task are left out. It makes one query
+// for the tasks and one each for their comments and incoming and outgoing
+// relations, however many tasks there are.
+func loadTaskDetails(database *db.DB, taskUUIDs []string, includeComments bool, includeRelations bool) (map[string]*Task, error) {
+	tasks := make(map[string]*Task, len(taskUUIDs))
+	if len(taskUUIDs) == 0 {
+		return tasks, nil
+	}
+	placeholders := strings.TrimRight(strings.Repeat("?,", len(taskUUIDs)), ",")
+	args := make([]interface{}, len(taskUUIDs))
+	for i, taskUUID := range taskUUIDs {
+		args[i] = taskUUID
+	}
+
+	rows, err := database.Query(`
+		SELECT t.uuid, t.id, t.slug, t.title, t.project_uuid, COALESCE(c.id, ''), t.state, t.priority,
+		       t.kind, t.parent_task_uuid, pt.id, t.assignee_actor_uuid, asg.slug,
+		       t.start_at, t.due_at, t.labels, t.description, t.etag,
+		       t.created_at, t.updated_at, t.completed_at, t.archived_at, t.deleted_at,
+		       t.subtask_progress, t.estimate_minutes, COALESCE(cb.slug, ''), COALESCE(ub.slug, '')
+		FROM tasks t
+		LEFT JOIN containers c ON c.uuid = t.project_uuid
+		LEFT JOIN tasks pt ON pt.uuid = t.parent_task_uuid
+		LEFT JOIN actors asg ON asg.uuid = t.assignee_actor_uuid
+		LEFT JOIN actors cb ON cb.uuid = t.created_by_actor_uuid
+		LEFT JOIN actors ub ON ub.uuid = t.updated_by_actor_uuid
+		WHERE t.uuid IN (`+placeholders+`)
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tasks: %w", err)
+	}
+	for rows.Next() {
+		task := &Task{}
+		var subtaskProgress *string
+		if err := rows.Scan(
+			&task.UUID, &task.ID, &task.Slug, &task.Title, &task.ProjectUUID, &task.ProjectID, &task.State, &task.Priority,
+			&task.Kind, &task.ParentTaskUUID, &task.ParentTaskID, &task.AssigneeUUID, &task.AssigneeSlug,
+			&task.StartAt, &task.DueAt, &task.Labels, &task.Description, &task.Etag,
+			&task.CreatedAt, &task.UpdatedAt, &task.CompletedAt, &task.ArchivedAt, &task.DeletedAt,
+			&subtaskProgress, &task.EstimateMinutes, &task.CreatedBy, &task.UpdatedBy,
+		); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan task: %w", err)
+		}
+		if subtaskProgress != nil {
+			var progress domain.SubtaskProgress
+			if err := json.Unmarshal([]byte(*subtaskProgress), &progress); err == nil {
+				task.SubtaskProgress = &progress
+			}
+		}
+		tasks[task.UUID] = task
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get tasks: %w", err)
+	}
+
+	if includeComments {
+		rows, err := database.Query(`
+			SELECT c.task_uuid, c.id, c.created_at, c.body, a.slug as actor_slug, a.role as actor_role
+			FROM comments c
+			LEFT JOIN actors a ON c.actor_uuid = a.uuid
+			WHERE c.task_uuid IN (`+placeholders+`) AND c.deleted_at IS NULL
+			ORDER BY c.created_at ASC
+		`, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query comments: %w", err)
+		}
+		for rows.Next() {
+			var taskUUID string
+			var comment Comment
+			if err := rows.Scan(&taskUUID, &comment.ID, &comment.CreatedAt, &comment.Body, &comment.ActorSlug, &comment.ActorRole); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan comment: %w", err)
+			}
+			if task := tasks[taskUUID]; task != nil {
+				task.Comments = append(task.Comments, comment)
+			}
+		}
+		rows.Close()
+	}
+
+	if includeRelations {
+		// Outgoing relations are listed before incoming ones, each ordered by
+		// kind and the other task's ID
+		for _, side := range []struct {
+			direction, self, other string
+		}{
+			{"outgoing", "from_task_uuid", "to_task_uuid"},
+			{"incoming", "to_task_uuid", "from_task_uuid"},
+		} {
+			rows, err := database.Query(`
+				SELECT r.`+side.self+`, r.kind, r.created_at,
+				       t.id AS task_id, t.uuid AS task_uuid, t.slug, t.title,
+				       a.id AS created_by_id
+				FROM task_relations r
+				JOIN tasks t ON r.`+side.other+` = t.uuid
+				JOIN actors a ON r.created_by_actor_uuid = a.uuid
+				WHERE r.`+side.self+` IN (`+placeholders+`)
+				ORDER BY r.kind, t.id
+			`, args...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to query %s relations: %w", side.direction, err)
+			}
+			for rows.Next() {
+				var taskUUID string
+				var rel Relation
+				if err := rows.Scan(&taskUUID, &rel.Kind, &rel.CreatedAt, &rel.TaskID, &rel.TaskUUID, &rel.TaskSlug, &rel.TaskTitle, &rel.CreatedByID); err != nil {
+					rows.Close()
+					return nil, fmt.Errorf("failed to scan relation: %w", err)
+				}
+				rel.Direction = side.direction
+				if task := tasks[taskUUID]; task != nil {
+					task.Relations = append(task.Relations, rel)
+				}
+			}
+			rows.Close()
+		}
+	}
+
+	return tasks, nil
+}
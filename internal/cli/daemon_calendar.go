@@ -0,0 +1,247 @@
+package cli
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// calendarDateLayout is the due_at/start_at form that becomes an all-day event.
+const calendarDateLayout = "2006-01-02"
+
+// withQueryToken lets clients that can't set headers, such as calendar apps
+// subscribing to a feed URL, pass the daemon token as ?token=. It only
+// applies to the routes it wraps.
+func (s *daemonServer) withQueryToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token := r.URL.Query().Get("token"); token != "" && requestToken(r) == "" {
+			r.Header.Set("X-Wrkqd-Token", token)
+		}
+		next(w, r)
+	}
+}
+
+// handleTasksCalendar serves the tasks with a due date as an iCalendar feed,
+// one VEVENT per task. Query parameters mirror tasks/list: project,
+// assignee, path_prefix (repeatable), kind and labels_any (comma-separated).
+// Completed and cancelled tasks are left out unless include_closed=true, in
+// which case they are marked as done or cancelled.
+func (s *daemonServer) handleTasksCalendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	query := r.URL.Query()
+	req := tasksListRequest{
+		Project:    query.Get("project"),
+		Assignee:   query.Get("assignee"),
+		PathPrefix: query["path_prefix"],
+		Kind:       query.Get("kind"),
+	}
+	if labels := query.Get("labels_any"); labels != "" {
+		req.LabelsAny = strings.Split(labels, ",")
+	}
+	includeClosed := false
+	if raw := query.Get("include_closed"); raw != "" {
+		var err error
+		if includeClosed, err = strconv.ParseBool(raw); err != nil {
+			s.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid include_closed: %s", raw))
+			return
+		}
+	}
+
+	opts, err := s.taskFindOptions(r, req)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	filterClause, args, err := taskFilterClause(opts)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !includeClosed {
+		filterClause += " AND t.state NOT IN ('completed', 'cancelled')"
+	}
+
+	rows, err := s.db.Query(`
+		SELECT t.uuid, t.id, cp.path || '/' || t.slug, t.title, t.state,
+		       COALESCE(t.start_at, ''), t.due_at, t.updated_at, t.etag
+		FROM tasks t
+		JOIN v_container_paths cp ON cp.uuid = t.project_uuid
+		WHERE t.due_at IS NOT NULL AND t.due_at != ''`+filterClause+`
+		ORDER BY t.due_at, t.id
+	`, args...)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer rows.Close()
+
+	var events []calendarEvent
+	for rows.Next() {
+		var ev calendarEvent
+		if err := rows.Scan(&ev.uuid, &ev.id, &ev.path, &ev.title, &ev.state, &ev.startAt, &ev.dueAt, &ev.updatedAt, &ev.etag); err != nil {
+			s.writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		events = append(events, ev)
+	}
+	if err := rows.Err(); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="wrkq.ics"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(renderCalendar(events)))
+}
+
+type calendarEvent struct {
+	uuid, id, path, title, state string
+	startAt, dueAt, updatedAt    string
+	etag                         int64
+}
+
+// renderCalendar renders events as an RFC 5545 VCALENDAR. Tasks whose
+// due_at is neither RFC 3339 nor YYYY-MM-DD are skipped.
+func renderCalendar(events []calendarEvent) string {
+	var sb strings.Builder
+	line := func(s string) {
+		sb.WriteString(foldICSLine(s))
+		sb.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//wrkq//tasks//EN")
+	line("CALSCALE:GREGORIAN")
+	line("X-WR-CALNAME:wrkq")
+	for _, ev := range events {
+		start, end, ok := calendarEventTimes(ev.startAt, ev.dueAt)
+		if !ok {
+			continue
+		}
+		summary := ev.path
+		if ev.state == "completed" {
+			summary = "[done] " + summary
+		}
+
+		line("BEGIN:VEVENT")
+		// UIDs must not change between fetches, or clients duplicate events
+		line("UID:" + ev.uuid + "@wrkq")
+		line("DTSTAMP:" + icsTimestamp(ev.updatedAt))
+		line("LAST-MODIFIED:" + icsTimestamp(ev.updatedAt))
+		line("SEQUENCE:" + strconv.FormatInt(ev.etag, 10))
+		line(start)
+		if end != "" {
+			line(end)
+		}
+		line("SUMMARY:" + escapeICSText(summary))
+		line("DESCRIPTION:" + escapeICSText(ev.id+": "+ev.title))
+		if ev.state == "cancelled" {
+			line("STATUS:CANCELLED")
+		} else {
+			line("STATUS:CONFIRMED")
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return sb.String()
+}
+
+// calendarEventTimes returns the DTSTART and (possibly empty) DTEND lines for
+// a task. A date-only due_at makes an all-day event, and then start_at counts
+// by its date too. start_at becomes the event start when it is earlier.
+func calendarEventTimes(startAt, dueAt string) (string, string, bool) {
+	due, dueDateOnly, ok := parseCalendarTime(dueAt)
+	if !ok {
+		return "", "", false
+	}
+	start, startDateOnly, hasStart := parseCalendarTime(startAt)
+
+	if dueDateOnly || (hasStart && startDateOnly) {
+		dueDay := calendarDay(due)
+		startDay := dueDay
+		if hasStart && calendarDay(start).Before(dueDay) {
+			startDay = calendarDay(start)
+		}
+		return "DTSTART;VALUE=DATE:" + startDay.Format("20060102"),
+			"DTEND;VALUE=DATE:" + dueDay.AddDate(0, 0, 1).Format("20060102"), true
+	}
+	if hasStart && start.Before(due) {
+		return "DTSTART:" + start.UTC().Format("20060102T150405Z"),
+			"DTEND:" + due.UTC().Format("20060102T150405Z"), true
+	}
+	return "DTSTART:" + due.UTC().Format("20060102T150405Z"), "", true
+}
+
+func calendarDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// parseCalendarTime parses an RFC 3339 timestamp or a YYYY-MM-DD date,
+// reporting which it was.
+func parseCalendarTime(value string) (time.Time, bool, bool) {
+	if value == "" {
+		return time.Time{}, false, false
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, true
+	}
+	if t, err := time.Parse(calendarDateLayout, value); err == nil {
+		return t, true, true
+	}
+	return time.Time{}, false, false
+}
+
+// icsTimestamp converts a stored timestamp to iCalendar UTC form, falling
+// back to the epoch for unparseable values so DTSTAMP stays stable.
+func icsTimestamp(value string) string {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t = time.Unix(0, 0)
+	}
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// escapeICSText escapes a TEXT property value (RFC 5545 section 3.3.11).
+func escapeICSText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+// foldICSLine splits a content line into 75-octet pieces joined by CRLF and
+// a space, without breaking UTF-8 sequences.
+func foldICSLine(s string) string {
+	const limit = 75
+	if len(s) <= limit {
+		return s
+	}
+	var sb strings.Builder
+	width := limit
+	for len(s) > width {
+		cut := width
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		sb.WriteString(s[:cut])
+		sb.WriteString("\r\n ")
+		s = s[cut:]
+		// Continuation lines lose one octet to the leading space
+		width = limit - 1
+	}
+	sb.WriteString(s)
+	return sb.String()
+}
@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// gzipMinBytes is the smallest response worth compressing; below it the
+// gzip framing costs more than it saves.
+const gzipMinBytes = 1024
+
+// withCompression gzips JSON and text responses for clients that send
+// Accept-Encoding: gzip. The start of the body is buffered to decide: a
+// response that ends, or is flushed, before reaching gzipMinBytes goes out
+// as is, so small replies and streams such as events/watch are unaffected.
+// Responses that set their own Content-Length or Content-Encoding, such as
+// attachment downloads with range support, are never compressed.
+func (s *daemonServer) withCompression(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next(w, r)
+			return
+		}
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.finish()
+		next(gw, r)
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if name, value, ok := strings.Cut(strings.TrimSpace(param), "="); ok && strings.TrimSpace(name) == "q" {
+				q, _ = strconv.ParseFloat(strings.TrimSpace(value), 64)
+			}
+		}
+		if q > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter holds back the status and the first gzipMinBytes of
+// the body until it knows whether to compress.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status  int
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+}
+
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	if g.status == 0 {
+		g.status = status
+	}
+}
+
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+	if g.status == 0 {
+		g.status = http.StatusOK
+	}
+	if g.decided {
+		if g.gz != nil {
+			return g.gz.Write(p)
+		}
+		return g.ResponseWriter.Write(p)
+	}
+	g.buf = append(g.buf, p...)
+	if len(g.buf) >= gzipMinBytes {
+		if err := g.decide(g.compressible()); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// compressible reports whether the response may be gzipped, judging by its
+// status and headers.
+func (g *gzipResponseWriter) compressible() bool {
+	h := g.Header()
+	if g.status < 200 || g.status == http.StatusNoContent || g.status == http.StatusPartialContent ||
+		h.Get("Content-Encoding") != "" || h.Get("Content-Length") != "" {
+		return false
+	}
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(h.Get("Content-Type"), ";")[0]))
+	return mediaType == "application/json" || strings.HasPrefix(mediaType, "text/")
+}
+
+// decide sends the status line and buffered body, through gzip if compress.
+func (g *gzipResponseWriter) decide(compress bool) error {
+	g.decided = true
+	if compress {
+		g.Header().Set("Content-Encoding", "gzip")
+		g.Header().Del("Content-Length")
+		g.gz = gzip.NewWriter(g.ResponseWriter)
+	}
+	if g.status != 0 {
+		g.ResponseWriter.WriteHeader(g.status)
+	}
+	if len(g.buf) == 0 {
+		return nil
+	}
+	buf := g.buf
+	g.buf = nil
+	var err error
+	if g.gz != nil {
+		_, err = g.gz.Write(buf)
+	} else {
+		_, err = g.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// Flush sends what has been written so far. Flushing before the body is
+// large enough to compress sends it uncompressed, keeping streams live.
+func (g *gzipResponseWriter) Flush() {
+	if !g.decided {
+		_ = g.decide(false)
+	}
+	if g.gz != nil {
+		_ = g.gz.Flush()
+	}
+	if flusher, ok := g.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+// finish sends a response that never reached gzipMinBytes and closes the
+// gzip stream of one that did.
+func (g *gzipResponseWriter) finish() {
+	if !g.decided {
+		_ = g.decide(false)
+	}
+	if g.gz != nil {
+		_ = g.gz.Close()
+	}
+}
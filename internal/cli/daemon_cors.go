@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	// defaultCORSMethods and defaultCORSHeaders are what preflights allow
+	// unless overridden: every method and request header the API uses.
+	defaultCORSMethods = []string{http.MethodGet, http.MethodPost}
+	defaultCORSHeaders = []string{
+		"Authorization", "Content-Type", idempotencyKeyHeader, "If-None-Match", requestIDHeader,
+		"X-Wrkq-Actor", "X-Wrkq-Cwd", "X-Wrkq-Filename", "X-Wrkqd-Token",
+	}
+	// corsExposedHeaders are the response headers browser code may read.
+	corsExposedHeaders = []string{
+		"Content-Disposition", "ETag", idempotencyReplayedHeader, "Retry-After", requestIDHeader, "X-Wrkq-Checksum",
+	}
+)
+
+// corsPolicy controls which browser origins may call the API. The zero
+// value, with no origins, disables CORS.
+type corsPolicy struct {
+	origins     []string
+	methods     []string
+	headers     []string
+	credentials bool
+}
+
+func newCORSPolicy(opts DaemonOptions) corsPolicy {
+	policy := corsPolicy{
+		origins:     opts.CORSOrigins,
+		methods:     opts.CORSMethods,
+		headers:     opts.CORSHeaders,
+		credentials: opts.CORSCredentials,
+	}
+	if len(policy.methods) == 0 {
+		policy.methods = defaultCORSMethods
+	}
+	if len(policy.headers) == 0 {
+		policy.headers = defaultCORSHeaders
+	}
+	return policy
+}
+
+func (p corsPolicy) allows(origin string) bool {
+	origin = strings.TrimSuffix(origin, "/")
+	for _, allowed := range p.origins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// withCORS adds CORS headers to responses for allowed origins and answers
+// their preflight OPTIONS requests itself, before authentication, since
+// browsers send preflights without credentials. The request's origin is
+// echoed rather than "*" so that credentials can be allowed. Requests
+// without an Origin, and all requests while CORS is disabled, pass through
+// untouched; a preflight from an origin that isn't allowed gets 403.
+func (s *daemonServer) withCORS(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if len(s.cors.origins) == 0 || origin == "" {
+			next(w, r)
+			return
+		}
+
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if !s.cors.allows(origin) {
+			if preflight {
+				s.writeError(w, http.StatusForbidden, fmt.Errorf("origin %s is not allowed", origin))
+				return
+			}
+			next(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		h.Set("Access-Control-Allow-Origin", origin)
+		if s.cors.credentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			h.Set("Access-Control-Allow-Methods", strings.Join(s.cors.methods, ", "))
+			h.Set("Access-Control-Allow-Headers", strings.Join(s.cors.headers, ", "))
+			h.Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		h.Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+		next(w, r)
+	}
+}
@@ -0,0 +1,360 @@
+package cli
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lherron/wrkq/internal/actors"
+	"github.com/lherron/wrkq/internal/cursor"
+	"github.com/lherron/wrkq/internal/db"
+)
+
+// maxEventsListLimit caps how many events one /v1/events/list page may hold.
+const maxEventsListLimit = 500
+
+type eventsListRequest struct {
+	ResourceType string `json:"resource_type,omitempty"`
+	ResourceUUID string `json:"resource_uuid,omitempty"`
+	Since        string `json:"since,omitempty"`
+	Until        string `json:"until,omitempty"`
+	Actor        string `json:"actor,omitempty"`
+	Limit        int    `json:"limit,omitempty"`
+	Cursor       string `json:"cursor,omitempty"`
+}
+
+func (s *daemonServer) handleEventsList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req eventsListRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	filter := eventFilter{
+		resourceType: req.ResourceType,
+		resourceUUID: req.ResourceUUID,
+		since:        req.Since,
+		until:        req.Until,
+	}
+
+	if req.Actor != "" {
+		resolver := actors.NewResolver(s.db.DB)
+		actorUUID, err := resolver.Resolve(req.Actor)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		filter.actorUUID = actorUUID
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	if limit > maxEventsListLimit {
+		limit = maxEventsListLimit
+	}
+
+	events, hasMore, err := queryEvents(s.db, filter, req.Cursor, limit)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var nextCursor string
+	if hasMore && len(events) > 0 {
+		nextCursor, _ = eventCursor(events[len(events)-1])
+	}
+
+	if events == nil {
+		events = []logEvent{}
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"events":      events,
+		"next_cursor": nextCursor,
+	})
+}
+
+// eventFilter narrows an event_log query. Empty fields are ignored.
+type eventFilter struct {
+	resourceType string
+	resourceUUID string
+	actorUUID    string
+	since        string
+	until        string
+	afterID      int64
+	// tail orders by id alone, so a watch that resumes after the highest id
+	// it has seen never misses or repeats an event whose timestamp is out of
+	// order.
+	tail bool
+}
+
+// queryEvents returns events matching filter in (timestamp, id) ascending order,
+// starting after cursorStr. Because id is monotonically increasing, events that
+// share a timestamp keep a stable order and a cursor never skips rows inserted
+// later with the same timestamp. A tail filter orders by id alone instead.
+func queryEvents(database *db.DB, filter eventFilter, cursorStr string, limit int) ([]logEvent, bool, error) {
+	pag, err := cursor.Apply(cursorStr, cursor.ApplyOptions{
+		SortFields: []string{"timestamp"},
+		SQLFields:  []string{"e.timestamp"},
+		Descending: []bool{false},
+		IDField:    "e.id",
+		Limit:      limit,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	query := `
+		SELECT e.id, e.timestamp, e.actor_uuid, e.resource_type, e.resource_uuid, e.event_type, e.etag, e.payload,
+		       a.slug as actor_slug, a.id as actor_id
+		FROM event_log e
+		LEFT JOIN actors a ON a.uuid = e.actor_uuid
+		WHERE 1=1
+	`
+	args := []interface{}{}
+
+	if filter.resourceType != "" {
+		query += " AND e.resource_type = ?"
+		args = append(args, filter.resourceType)
+	}
+	if filter.resourceUUID != "" {
+		query += " AND e.resource_uuid = ?"
+		args = append(args, filter.resourceUUID)
+	}
+	if filter.actorUUID != "" {
+		query += " AND e.actor_uuid = ?"
+		args = append(args, filter.actorUUID)
+	}
+	if filter.afterID > 0 {
+		query += " AND e.id > ?"
+		args = append(args, filter.afterID)
+	}
+	if filter.since != "" {
+		sinceTime, err := parseTimeFilter(filter.since)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid since value: %w", err)
+		}
+		query += " AND e.timestamp >= ?"
+		args = append(args, sinceTime.UTC().Format(time.RFC3339))
+	}
+	if filter.until != "" {
+		untilTime, err := parseTimeFilter(filter.until)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid until value: %w", err)
+		}
+		query += " AND e.timestamp <= ?"
+		args = append(args, untilTime.UTC().Format(time.RFC3339))
+	}
+
+	if pag.WhereClause != "" {
+		query += " AND " + pag.WhereClause
+		args = append(args, pag.Params...)
+	}
+
+	if filter.tail {
+		query += " ORDER BY e.id ASC"
+	} else {
+		query += " " + pag.OrderByClause
+	}
+
+	if pag.LimitClause != "" {
+		query += " " + pag.LimitClause
+		args = append(args, *pag.LimitParam)
+	}
+
+	rows, err := database.Query(query, args...)
+	if err != nil {
+		return nil, false, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var events []logEvent
+	for rows.Next() {
+		var e logEvent
+		var timestampStr string
+		var resourceUUID, actorSlug, actorID sql.NullString
+
+		if err := rows.Scan(
+			&e.ID,
+			&timestampStr,
+			&e.ActorUUID,
+			&e.ResourceType,
+			&resourceUUID,
+			&e.EventType,
+			&e.ETag,
+			&e.Payload,
+			&actorSlug,
+			&actorID,
+		); err != nil {
+			return nil, false, fmt.Errorf("scan failed: %w", err)
+		}
+
+		e.Timestamp, err = time.Parse(time.RFC3339, timestampStr)
+		if err != nil {
+			e.Timestamp, _ = time.Parse("2006-01-02 15:04:05", timestampStr)
+		}
+		if resourceUUID.Valid {
+			e.ResourceUUID = resourceUUID.String
+		}
+		if actorSlug.Valid {
+			e.ActorSlug = &actorSlug.String
+		}
+		if actorID.Valid {
+			e.ActorID = &actorID.String
+		}
+
+		events = append(events, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	hasMore := false
+	if limit > 0 && len(events) > limit {
+		hasMore = true
+		events = events[:limit]
+	}
+
+	return events, hasMore, nil
+}
+
+// eventCursor builds the pagination cursor pointing just past e.
+func eventCursor(e logEvent) (string, error) {
+	return cursor.BuildNextCursor(
+		[]string{"timestamp"},
+		[]interface{}{e.Timestamp.UTC().Format(time.RFC3339)},
+		strconv.FormatInt(e.ID, 10),
+	)
+}
+
+const (
+	defaultWatchPollInterval = time.Second
+	defaultWatchMaxDuration  = 5 * time.Minute
+)
+
+// handleEventsWatch streams newly appended events as newline-delimited JSON.
+//
+// Query parameters:
+//   - cursor: resume after the event identified by a cursor from events/list or
+//     a previous watch line; without it, only events appended after the request
+//     starts are streamed.
+//   - resource_type, resource_uuid: optional filters.
+//   - timeout: optional duration (e.g. "30s") capped at the daemon's max watch duration.
+//
+// Each line is {"event": {...}, "cursor": "..."}. The stream ends when the
+// client disconnects or the duration elapses. The server's write deadline is
+// cleared for the stream, so it outlives WriteTimeout.
+func (s *daemonServer) handleEventsWatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming not supported"))
+		return
+	}
+
+	query := r.URL.Query()
+	filter := eventFilter{
+		resourceType: query.Get("resource_type"),
+		resourceUUID: query.Get("resource_uuid"),
+		tail:         true,
+	}
+
+	// Tail strictly by id: catch-up and live polling run the same query from
+	// the last delivered id, so no event can fall between the two phases.
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		c, err := cursor.Decode(cursorStr)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid cursor: %w", err))
+			return
+		}
+		lastID, err := strconv.ParseInt(c.LastID, 10, 64)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid cursor: %w", err))
+			return
+		}
+		filter.afterID = lastID
+	} else {
+		if err := s.db.QueryRow("SELECT COALESCE(MAX(id), 0) FROM event_log").Scan(&filter.afterID); err != nil {
+			s.writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	maxDuration := s.watchMaxDuration
+	if maxDuration <= 0 {
+		maxDuration = defaultWatchMaxDuration
+	}
+	if timeout := query.Get("timeout"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			s.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid timeout: %s", timeout))
+			return
+		}
+		if d < maxDuration {
+			maxDuration = d
+		}
+	}
+
+	pollInterval := s.watchPollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultWatchPollInterval
+	}
+
+	// Not every ResponseWriter supports deadlines (httptest's doesn't); those
+	// that don't have none to clear.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	deadline := time.NewTimer(maxDuration)
+	defer deadline.Stop()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	encoder := json.NewEncoder(w)
+	for {
+		events, _, err := queryEvents(s.db, filter, "", 0)
+		if err != nil {
+			return
+		}
+		for _, e := range events {
+			next, _ := eventCursor(e)
+			if err := encoder.Encode(map[string]interface{}{
+				"event":  e,
+				"cursor": next,
+			}); err != nil {
+				return
+			}
+			flusher.Flush()
+			if e.ID > filter.afterID {
+				filter.afterID = e.ID
+			}
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-deadline.C:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotency-Replayed"
+	defaultIdempotencyTTL     = 24 * time.Hour
+)
+
+// withIdempotency makes a write endpoint safe to retry. When the request
+// carries an Idempotency-Key header, the first successful response is stored
+// per actor and key; a repeat of the same request returns that response
+// without executing the handler again. Reusing a key for a different endpoint
+// or body is rejected with 422. Requests without the header pass through.
+//
+// The key is reserved with a pending row (status 0) before the handler
+// runs, so a duplicate that arrives while the first request is still
+// executing gets 409 instead of running the write twice. Only 2xx responses
+// are stored; any other outcome releases the reservation, so a request that
+// failed can be retried with the same key.
+func (s *daemonServer) withIdempotency(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" || r.Method != http.MethodPost {
+			next(w, r)
+			return
+		}
+		if len(key) > 255 {
+			s.writeError(w, http.StatusBadRequest, fmt.Errorf("%s must be at most 255 characters", idempotencyKeyHeader))
+			return
+		}
+
+		actorUUID, err := s.resolveActorUUID(r)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		body, err := s.readBody(r)
+		if err != nil {
+			s.writeError(w, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(body)
+		requestHash := hex.EncodeToString(sum[:])
+
+		cutoff := time.Now().Add(-s.idempotencyWindow()).UTC().Format(time.RFC3339)
+		if _, err := s.db.Exec("DELETE FROM idempotency_keys WHERE created_at < ?", cutoff); err != nil {
+			s.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to prune idempotency keys: %w", err))
+			return
+		}
+
+		res, err := s.db.Exec(`
+			INSERT OR IGNORE INTO idempotency_keys (actor_uuid, key, endpoint, request_hash, status, response)
+			VALUES (?, ?, ?, ?, 0, '')
+		`, actorUUID, key, r.URL.Path, requestHash)
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to reserve idempotency key: %w", err))
+			return
+		}
+		reserved, err := res.RowsAffected()
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to reserve idempotency key: %w", err))
+			return
+		}
+		if reserved == 0 {
+			s.replayIdempotent(w, r, actorUUID, key, requestHash)
+			return
+		}
+
+		rec := &responseCapture{ResponseWriter: w, status: http.StatusOK}
+		stored := false
+		defer func() {
+			if !stored {
+				_, _ = s.db.Exec("DELETE FROM idempotency_keys WHERE actor_uuid = ? AND key = ? AND status = 0", actorUUID, key)
+			}
+		}()
+		next(rec, r)
+
+		if rec.status < 200 || rec.status >= 300 {
+			return
+		}
+		// The response has already been sent; failing to record it only
+		// means a retry would execute again
+		if _, err := s.db.Exec(`
+			UPDATE idempotency_keys SET resource_uuid = ?, status = ?, response = ?
+			WHERE actor_uuid = ? AND key = ?
+		`, responseResourceUUID(rec.body.Bytes()), rec.status, rec.body.String(), actorUUID, key); err == nil {
+			stored = true
+		}
+	}
+}
+
+// replayIdempotent answers a request whose key is already taken: with the
+// stored response, 409 while the first request is still running, or 422 if
+// the key was used for a different request.
+func (s *daemonServer) replayIdempotent(w http.ResponseWriter, r *http.Request, actorUUID, key, requestHash string) {
+	var endpoint, storedHash, response string
+	var status int
+	err := s.db.QueryRow(`
+		SELECT endpoint, request_hash, status, response
+		FROM idempotency_keys
+		WHERE actor_uuid = ? AND key = ?
+	`, actorUUID, key).Scan(&endpoint, &storedHash, &status, &response)
+	switch {
+	case err != nil && err != sql.ErrNoRows:
+		s.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to look up idempotency key: %w", err))
+	case err == nil && (endpoint != r.URL.Path || storedHash != requestHash):
+		s.writeError(w, http.StatusUnprocessableEntity,
+			fmt.Errorf("%s %q was already used for a different request", idempotencyKeyHeader, key))
+	case err == sql.ErrNoRows || status == 0:
+		// Gone means the first request just failed and released the key
+		s.writeError(w, http.StatusConflict,
+			fmt.Errorf("a request with %s %q is still in progress", idempotencyKeyHeader, key))
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(idempotencyReplayedHeader, "true")
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, response)
+	}
+}
+
+func (s *daemonServer) idempotencyWindow() time.Duration {
+	if s.idempotencyTTL > 0 {
+		return s.idempotencyTTL
+	}
+	return defaultIdempotencyTTL
+}
+
+// readBody reads the whole request body, honouring maxBodyBytes.
+func (s *daemonServer) readBody(r *http.Request) ([]byte, error) {
+	var body io.Reader = r.Body
+	if s.maxBodyBytes > 0 {
+		body = http.MaxBytesReader(nil, r.Body, s.maxBodyBytes)
+	}
+	return io.ReadAll(body)
+}
+
+// responseResourceUUID extracts the UUID of the resource in a response such
+// as {"task": {"uuid": ...}}, or nil if there isn't exactly one.
+func responseResourceUUID(body []byte) interface{} {
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(body, &payload); err != nil || len(payload) != 1 {
+		return nil
+	}
+	for _, raw := range payload {
+		var resource struct {
+			UUID string `json:"uuid"`
+		}
+		if err := json.Unmarshal(raw, &resource); err == nil && resource.UUID != "" {
+			return resource.UUID
+		}
+	}
+	return nil
+}
+
+// responseCapture records the status and body written by a handler while
+// passing them through to the client.
+type responseCapture struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (c *responseCapture) WriteHeader(status int) {
+	c.status = status
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *responseCapture) Write(p []byte) (int, error) {
+	c.body.Write(p)
+	return c.ResponseWriter.Write(p)
+}
@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lherron/wrkq/internal/domain"
+)
+
+const defaultWriteQueueTimeout = 5 * time.Second
+
+// writeLimiter bounds concurrent write handlers and, optionally, the write
+// request rate per auth token. SQLite serializes writers anyway; queueing them
+// here keeps a burst from piling up on the database's busy timeout.
+type writeLimiter struct {
+	slots        chan struct{} // nil means unlimited
+	queueTimeout time.Duration
+
+	rate  float64 // tokens per second; 0 disables rate limiting
+	burst float64
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newWriteLimiter(maxWrites int, queueTimeout time.Duration, rate float64, burst int) *writeLimiter {
+	l := &writeLimiter{
+		queueTimeout: queueTimeout,
+		rate:         rate,
+		burst:        float64(burst),
+		buckets:      make(map[string]*tokenBucket),
+	}
+	if maxWrites > 0 {
+		l.slots = make(chan struct{}, maxWrites)
+	}
+	if l.queueTimeout <= 0 {
+		l.queueTimeout = defaultWriteQueueTimeout
+	}
+	if l.rate > 0 && l.burst < 1 {
+		l.burst = math.Max(1, math.Ceil(l.rate))
+	}
+	return l
+}
+
+// allow takes a token from key's bucket, returning how long to wait before
+// retrying when the bucket is empty.
+func (l *writeLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	if l.rate <= 0 {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep drops buckets idle long enough to have refilled to burst; they are
+// indistinguishable from a new bucket. It runs at most once per refill
+// interval so allow stays cheap. The caller holds l.mu.
+func (l *writeLimiter) sweep(now time.Time) {
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	if now.Sub(l.lastSweep) < refill {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// acquire waits up to queueTimeout for a write slot. The returned release
+// func must be called when the handler finishes.
+func (l *writeLimiter) acquire() (func(), bool) {
+	if l.slots == nil {
+		return func() {}, true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, true
+	default:
+	}
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, true
+	case <-timer.C:
+		return nil, false
+	}
+}
+
+// withWriteLimit guards a write endpoint: it applies the per-token rate limit
+// and holds a write slot for the duration of the handler. Saturation is
+// reported as 429 with Retry-After. Read endpoints are not wrapped, so this is
+// also where tokens with only the read scope are refused.
+func (s *daemonServer) withWriteLimit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.checkScope(w, r, domain.TokenScopeWrite) {
+			return
+		}
+		s.selectorCache.beginWrite()
+		defer s.selectorCache.endWrite()
+		if s.writeLimiter == nil {
+			s.metrics.timeWrite(func() { next(w, r) })
+			return
+		}
+
+		if ok, wait := s.writeLimiter.allow(requestToken(r), time.Now()); !ok {
+			w.Header().Set("Retry-After", retryAfterSeconds(wait))
+			s.writeError(w, http.StatusTooManyRequests, fmt.Errorf("write rate limit exceeded"))
+			return
+		}
+
+		release, ok := s.writeLimiter.acquire()
+		if !ok {
+			w.Header().Set("Retry-After", retryAfterSeconds(time.Second))
+			s.writeError(w, http.StatusTooManyRequests, fmt.Errorf("too many concurrent writes"))
+			return
+		}
+		defer release()
+
+		s.metrics.timeWrite(func() { next(w, r) })
+	}
+}
+
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Max(1, math.Ceil(d.Seconds()))))
+}
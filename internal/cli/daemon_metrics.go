@@ -0,0 +1,213 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lherron/wrkq/internal/webhooks"
+)
+
+// metricsBuckets are the upper bounds, in seconds, of the latency histograms;
+// they match the Prometheus client defaults.
+var metricsBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// histogram is a cumulative Prometheus histogram over metricsBuckets.
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(seconds float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(metricsBuckets))
+	}
+	for i, bound := range metricsBuckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+type routeStatus struct {
+	route  string
+	status int
+}
+
+// daemonMetrics collects the counters and histograms served by
+// /v1/metrics. Routes are labelled by their registered path, never the
+// request URL, so the label set stays bounded. A nil *daemonMetrics records
+// nothing.
+type daemonMetrics struct {
+	mu       sync.Mutex
+	requests map[routeStatus]uint64
+	latency  map[string]*histogram
+	writes   histogram
+}
+
+func newDaemonMetrics() *daemonMetrics {
+	return &daemonMetrics{
+		requests: map[routeStatus]uint64{},
+		latency:  map[string]*histogram{},
+	}
+}
+
+func (m *daemonMetrics) observeRequest(route string, status int, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[routeStatus{route, status}]++
+	h := m.latency[route]
+	if h == nil {
+		h = &histogram{}
+		m.latency[route] = h
+	}
+	h.observe(elapsed.Seconds())
+}
+
+// timeWrite runs fn, a write handler holding its write slot, and records
+// how long it took.
+func (m *daemonMetrics) timeWrite(fn func()) {
+	if m == nil {
+		fn()
+		return
+	}
+	start := time.Now()
+	fn()
+	elapsed := time.Since(start)
+	m.mu.Lock()
+	m.writes.observe(elapsed.Seconds())
+	m.mu.Unlock()
+}
+
+// writeTo writes the metrics in the Prometheus text exposition format,
+// with series in a stable order.
+func (m *daemonMetrics) writeTo(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP wrkqd_http_requests_total HTTP requests handled, by route and status.")
+	fmt.Fprintln(w, "# TYPE wrkqd_http_requests_total counter")
+	keys := make([]routeStatus, 0, len(m.requests))
+	for key := range m.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		return keys[i].status < keys[j].status
+	})
+	for _, key := range keys {
+		fmt.Fprintf(w, "wrkqd_http_requests_total{route=%q,status=\"%d\"} %d\n", key.route, key.status, m.requests[key])
+	}
+
+	fmt.Fprintln(w, "# HELP wrkqd_http_request_duration_seconds Handler latency, by route.")
+	fmt.Fprintln(w, "# TYPE wrkqd_http_request_duration_seconds histogram")
+	routes := make([]string, 0, len(m.latency))
+	for route := range m.latency {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	for _, route := range routes {
+		writeHistogram(w, "wrkqd_http_request_duration_seconds", fmt.Sprintf("route=%q", route), m.latency[route])
+	}
+
+	fmt.Fprintln(w, "# HELP wrkqd_db_write_duration_seconds Time write requests spend executing against the database.")
+	fmt.Fprintln(w, "# TYPE wrkqd_db_write_duration_seconds histogram")
+	writeHistogram(w, "wrkqd_db_write_duration_seconds", "", &m.writes)
+
+	succeeded, failed := webhooks.DeliveryCounts()
+	fmt.Fprintln(w, "# HELP wrkqd_webhook_deliveries_total Webhook delivery attempts, by result.")
+	fmt.Fprintln(w, "# TYPE wrkqd_webhook_deliveries_total counter")
+	fmt.Fprintf(w, "wrkqd_webhook_deliveries_total{result=\"success\"} %d\n", succeeded)
+	fmt.Fprintf(w, "wrkqd_webhook_deliveries_total{result=\"failure\"} %d\n", failed)
+}
+
+func writeHistogram(w io.Writer, name, labels string, h *histogram) {
+	prefix := labels
+	if prefix != "" {
+		prefix += ","
+	}
+	for i, bound := range metricsBuckets {
+		var n uint64
+		if h.counts != nil {
+			n = h.counts[i]
+		}
+		fmt.Fprintf(w, "%s_bucket{%sle=%q} %d\n", name, prefix, strconv.FormatFloat(bound, 'g', -1, 64), n)
+	}
+	fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", name, prefix, h.count)
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels, h.count)
+}
+
+// instrument wraps a route's handler to count its responses by status and
+// record its latency.
+func (s *daemonServer) instrument(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		s.metrics.observeRequest(route, rec.status, time.Since(start))
+	}
+}
+
+// statusRecorder notes the status a handler sends. It forwards Flush so
+// streaming endpoints such as events/watch keep working behind it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (s *daemonServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var b strings.Builder
+	s.metrics.writeTo(&b)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.WriteString(w, b.String())
+}
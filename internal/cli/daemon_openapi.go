@@ -0,0 +1,524 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/lherron/wrkq/internal/bundle"
+	"github.com/lherron/wrkq/internal/domain"
+	"github.com/lherron/wrkq/internal/store"
+)
+
+// apiSchema describes the bodies of one daemon endpoint for the OpenAPI
+// document; the method and summary come from its registration (see
+// daemonServer.route). Request and the values in Response are zero values
+// whose types are turned into schemas by reflection, so the spec follows the
+// structs the handlers decode and encode. The tests check that every
+// registered path has an entry in daemonAPISchemas.
+type apiSchema struct {
+	// Request is the JSON body type; nil when the endpoint takes none
+	Request interface{}
+	// RequestContent overrides the request media type for non-JSON bodies
+	RequestContent []string
+	// Query lists query parameters (all strings)
+	Query []string
+	// Response maps the top-level JSON response keys to sample values
+	Response map[string]interface{}
+	// ResponseBody is used instead of Response when the handler encodes a
+	// single value as the whole body
+	ResponseBody interface{}
+	// ResponseContent is set for endpoints that don't answer with JSON
+	ResponseContent string
+}
+
+var daemonAPISchemas = map[string]apiSchema{
+	"/v1/health": {
+		Response: map[string]interface{}{"ok": true, "time": ""},
+	},
+	"/v1/version": {
+		Response: map[string]interface{}{"version": "", "commit": "", "build_date": "",
+			"machine_interface_version": 0, "features": []string{}},
+	},
+	"/v1/routes": {
+		Response: map[string]interface{}{"version": "", "routes": []daemonRoute{}},
+	},
+	"/v1/metrics": {
+		ResponseContent: "text/plain",
+	},
+	"/v1/openapi.json": {
+		Response: map[string]interface{}{},
+	},
+	"/v1/resolve": {
+		Request:  resolveRequest{},
+		Response: map[string]interface{}{"results": map[string]resolvedSelector{}},
+	},
+	"/v1/containers/tree": {
+		Request:  containersTreeRequest{},
+		Response: map[string]interface{}{"path": "", "children": []*treeNode{}},
+	},
+	"/v1/containers/activity": {
+		Request:  containersActivityRequest{},
+		Response: map[string]interface{}{"activity": []store.ActivityEntry{}, "next_cursor": ""},
+	},
+	"/v1/containers/archive": {
+		Request:  containerArchiveRequest{},
+		Response: map[string]interface{}{"container": domain.Container{}, "containers": 0, "tasks": 0},
+	},
+	"/v1/containers/move": {
+		Request:  containerMoveRequest{},
+		Response: map[string]interface{}{"container": domain.Container{}, "path": ""},
+	},
+	"/v1/containers/rename": {
+		Request:  containerRenameRequest{},
+		Response: map[string]interface{}{"container": domain.Container{}, "path": ""},
+	},
+	"/v1/containers/restore": {
+		Request:  containerRestoreRequest{},
+		Response: map[string]interface{}{"container": domain.Container{}, "containers": 0, "tasks": 0},
+	},
+
+	"/v1/tasks/list": {
+		Request:  tasksListRequest{},
+		Response: map[string]interface{}{"tasks": []findResult{}, "next_cursor": ""},
+	},
+	"/v1/tasks/export": {
+		Request:         tasksExportRequest{},
+		Query:           []string{"format"},
+		ResponseContent: "text/csv",
+	},
+	"/v1/tasks/calendar.ics": {
+		Query:           []string{"token", "project", "assignee", "path_prefix", "kind", "labels_any", "include_closed"},
+		ResponseContent: "text/calendar",
+	},
+	"/v1/tasks/count": {
+		Request:  tasksCountRequest{},
+		Response: map[string]interface{}{"total": 0},
+	},
+	"/v1/tasks/ready": {
+		Request:  tasksReadyRequest{},
+		Response: map[string]interface{}{"tasks": []store.ReadyTask{}},
+	},
+	"/v1/tasks/search": {
+		Request:  tasksSearchRequest{},
+		Response: map[string]interface{}{"tasks": []store.SearchResult{}},
+	},
+	"/v1/tasks/get": {
+		Request:  taskGetRequest{},
+		Response: map[string]interface{}{"task": Task{}},
+	},
+	"/v1/tasks/batch_get": {
+		Request:  tasksBatchGetRequest{},
+		Response: map[string]interface{}{"tasks": map[string]batchGetEntry{}},
+	},
+	"/v1/tasks/subtasks": {
+		Request:  tasksSubtasksRequest{},
+		Response: map[string]interface{}{"parent_id": "", "parent_uuid": "", "subtasks": []*Task{}},
+	},
+	"/v1/tasks/history": {
+		Request:  tasksHistoryRequest{},
+		Response: map[string]interface{}{"task_id": "", "task_uuid": "", "history": []store.HistoryEntry{}},
+	},
+	"/v1/tasks/create": {
+		Request:  taskCreateRequest{},
+		Response: map[string]interface{}{"task": Task{}},
+	},
+	"/v1/tasks/create_from_template": {
+		Request:  tasksCreateFromTemplateRequest{},
+		Response: map[string]interface{}{"task": Task{}, "template": ""},
+	},
+	"/v1/tasks/bulk_create": {
+		Request:  tasksBulkCreateRequest{},
+		Response: map[string]interface{}{"tasks": []*Task{}, "errors": []bulkItemError{}, "committed": true},
+	},
+	"/v1/tasks/update": {
+		Request:  taskUpdateRequest{},
+		Response: map[string]interface{}{"task": Task{}},
+	},
+	"/v1/tasks/reassign": {
+		Request:  tasksReassignRequest{},
+		Response: map[string]interface{}{"reassigned": 0},
+	},
+	"/v1/tasks/archive": {
+		Request:  taskArchiveRequest{},
+		Response: map[string]interface{}{"task": Task{}},
+	},
+	"/v1/tasks/restore": {
+		Request:  taskRestoreRequest{},
+		Response: map[string]interface{}{"task": Task{}},
+	},
+
+	"/v1/sections/list": {
+		Request:  sectionsListRequest{},
+		Response: map[string]interface{}{"sections": []domain.Section{}},
+	},
+	"/v1/sections/create": {
+		Request:  sectionsCreateRequest{},
+		Response: map[string]interface{}{"section": domain.Section{}},
+	},
+	"/v1/sections/update": {
+		Request:  sectionsUpdateRequest{},
+		Response: map[string]interface{}{"section": domain.Section{}},
+	},
+	"/v1/sections/reorder": {
+		Request:  sectionsReorderRequest{},
+		Response: map[string]interface{}{"sections": []domain.Section{}},
+	},
+
+	"/v1/views/list": {
+		Request:  viewsListRequest{},
+		Response: map[string]interface{}{"views": []domain.View{}},
+	},
+	"/v1/views/create": {
+		Request:  viewsCreateRequest{},
+		Response: map[string]interface{}{"view": domain.View{}},
+	},
+	"/v1/views/delete": {
+		Request:  viewsDeleteRequest{},
+		Response: map[string]interface{}{"deleted": ""},
+	},
+	"/v1/views/run": {
+		Request:  viewsRunRequest{},
+		Response: map[string]interface{}{"view": domain.View{}, "tasks": []findResult{}, "next_cursor": ""},
+	},
+
+	"/v1/templates/list": {
+		Response: map[string]interface{}{"templates": []domain.TaskTemplate{}},
+	},
+	"/v1/templates/create": {
+		Request:  templatesCreateRequest{},
+		Response: map[string]interface{}{"template": domain.TaskTemplate{}},
+	},
+
+	"/v1/comments/list": {
+		Request:  commentsListRequest{},
+		Response: map[string]interface{}{"comments": []map[string]interface{}{}, "next_cursor": ""},
+	},
+	"/v1/comments/thread": {
+		Request:  commentsThreadRequest{},
+		Response: map[string]interface{}{"thread": []map[string]interface{}{}},
+	},
+	"/v1/comments/create": {
+		Request:  commentsCreateRequest{},
+		Response: map[string]interface{}{"comment": map[string]interface{}{}},
+	},
+	"/v1/comments/update": {
+		Request:  commentsUpdateRequest{},
+		Response: map[string]interface{}{"comment": map[string]interface{}{}},
+	},
+	"/v1/comments/delete": {
+		Request:  commentsDeleteRequest{},
+		Response: map[string]interface{}{"comment": map[string]interface{}{}},
+	},
+	"/v1/comments/restore": {
+		Request:  commentsRestoreRequest{},
+		Response: map[string]interface{}{"comment": map[string]interface{}{}},
+	},
+
+	"/v1/attachments/list": {
+		Request:  attachmentsListRequest{},
+		Response: map[string]interface{}{"attachments": []map[string]interface{}{}, "next_cursor": ""},
+	},
+	"/v1/attachments/get": {
+		Query:           []string{"attachment", "task"},
+		ResponseContent: "application/octet-stream",
+	},
+	"/v1/attachments/put": {
+		RequestContent: []string{"multipart/form-data", "application/octet-stream"},
+		Query:          []string{"task", "name", "mime"},
+		Response:       map[string]interface{}{"attachment": map[string]interface{}{}, "deduplicated": true},
+	},
+
+	"/v1/reactions/list": {
+		Request:  reactionRequest{},
+		Response: map[string]interface{}{"reactions": []domain.Reaction{}},
+	},
+	"/v1/reactions/add": {
+		Request:  reactionRequest{},
+		Response: map[string]interface{}{"changed": true, "reactions": []domain.ReactionSummary{}},
+	},
+	"/v1/reactions/remove": {
+		Request:  reactionRequest{},
+		Response: map[string]interface{}{"changed": true, "reactions": []domain.ReactionSummary{}},
+	},
+
+	"/v1/relations/list": {
+		Request:  relationsListRequest{},
+		Response: map[string]interface{}{"relations": []Relation{}},
+	},
+	"/v1/relations/create": {
+		Request:  relationsCreateRequest{},
+		Response: map[string]interface{}{"ok": true},
+	},
+	"/v1/relations/delete": {
+		Request:  relationsDeleteRequest{},
+		Response: map[string]interface{}{"ok": true},
+	},
+
+	"/v1/time/total": {
+		Request:  timeTotalRequest{},
+		Response: map[string]interface{}{"task_id": "T-00001", "task_uuid": "", "estimate_minutes": 0, "total": domain.TimeTotal{}, "entries": []domain.TimeEntry{}},
+	},
+	"/v1/time/log": {
+		Request:  timeLogRequest{},
+		Response: map[string]interface{}{"time_entry": domain.TimeEntry{}, "total": domain.TimeTotal{}},
+	},
+
+	"/v1/events/list": {
+		Request:  eventsListRequest{},
+		Response: map[string]interface{}{"events": []logEvent{}, "next_cursor": ""},
+	},
+	"/v1/events/watch": {
+		Query:           []string{"resource_type", "resource_uuid", "cursor", "timeout"},
+		ResponseContent: "text/event-stream",
+	},
+
+	"/v1/actors/list": {
+		Request:  actorsListRequest{},
+		Response: map[string]interface{}{"actors": []*domain.Actor{}},
+	},
+	"/v1/actors/create": {
+		Request:  actorsCreateRequest{},
+		Response: map[string]interface{}{"actor": domain.Actor{}},
+	},
+	"/v1/actors/update": {
+		Request:  actorsUpdateRequest{},
+		Response: map[string]interface{}{"actor": domain.Actor{}},
+	},
+	"/v1/actors/deactivate": {
+		Request:  actorsSetActiveRequest{},
+		Response: map[string]interface{}{"actor": domain.Actor{}},
+	},
+	"/v1/actors/reactivate": {
+		Request:  actorsSetActiveRequest{},
+		Response: map[string]interface{}{"actor": domain.Actor{}},
+	},
+
+	"/v1/bundle/create": {
+		Request:  bundleCreateRequest{},
+		Response: map[string]interface{}{"bundle_dir": "", "tasks_count": 0, "containers_count": 0, "refs_count": 0, "ref_index_count": 0, "manifest": bundle.Manifest{}},
+	},
+	"/v1/bundle/apply": {
+		Request:      bundleApplyRequest{},
+		ResponseBody: applyResult{},
+	},
+}
+
+func (s *daemonServer) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+	s.writeJSON(w, http.StatusOK, buildOpenAPISpec(s.routes, daemonAPISchemas))
+}
+
+// buildOpenAPISpec renders routes as an OpenAPI 3.0 document, taking bodies
+// from schemas; a route without an entry is listed with no bodies. Named struct
+// types become shared component schemas, which also keeps recursive types
+// such as the container tree finite.
+func buildOpenAPISpec(routes []daemonRoute, schemas map[string]apiSchema) map[string]interface{} {
+	g := &schemaGenerator{schemas: map[string]interface{}{}, names: map[reflect.Type]string{}}
+
+	errorResponse := map[string]interface{}{
+		"description": "Error",
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": map[string]interface{}{"$ref": "#/components/schemas/Error"}},
+		},
+	}
+	g.schemas["Error"] = map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"code":       map[string]interface{}{"type": "string"},
+			"message":    map[string]interface{}{"type": "string"},
+			"candidates": map[string]interface{}{"type": "array", "items": map[string]interface{}{}},
+		},
+	}
+
+	paths := map[string]interface{}{}
+	for _, r := range routes {
+		route := schemas[r.Path]
+		op := map[string]interface{}{
+			"summary":     r.Description,
+			"operationId": operationID(r.Path),
+		}
+
+		var params []interface{}
+		for _, name := range route.Query {
+			params = append(params, map[string]interface{}{
+				"name": name, "in": "query", "schema": map[string]interface{}{"type": "string"},
+			})
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+
+		if route.Request != nil {
+			op["requestBody"] = map[string]interface{}{
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": g.schemaFor(reflect.TypeOf(route.Request))},
+				},
+			}
+		} else if len(route.RequestContent) > 0 {
+			content := map[string]interface{}{}
+			for _, mediaType := range route.RequestContent {
+				content[mediaType] = map[string]interface{}{}
+			}
+			op["requestBody"] = map[string]interface{}{"content": content}
+		}
+
+		ok := map[string]interface{}{"description": "OK"}
+		switch {
+		case route.ResponseContent != "":
+			ok["content"] = map[string]interface{}{route.ResponseContent: map[string]interface{}{}}
+		case route.ResponseBody != nil:
+			ok["content"] = map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": g.schemaFor(reflect.TypeOf(route.ResponseBody))},
+			}
+		default:
+			props := map[string]interface{}{}
+			for key, sample := range route.Response {
+				props[key] = g.schemaFor(reflect.TypeOf(sample))
+			}
+			ok["content"] = map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": map[string]interface{}{"type": "object", "properties": props},
+				},
+			}
+		}
+		op["responses"] = map[string]interface{}{"200": ok, "default": errorResponse}
+
+		paths[r.Path] = map[string]interface{}{strings.ToLower(r.Method): op}
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "wrkq daemon API",
+			"version": Version,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": g.schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearer": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []interface{}{map[string]interface{}{"bearer": []string{}}},
+	}
+}
+
+// operationID turns /v1/tasks/create_from_template into
+// tasks_create_from_template.
+func operationID(path string) string {
+	id := strings.TrimPrefix(path, "/v1/")
+	return strings.NewReplacer("/", "_", ".", "_").Replace(id)
+}
+
+type schemaGenerator struct {
+	schemas map[string]interface{}
+	names   map[reflect.Type]string
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaFor returns the JSON schema for t, registering named structs as
+// components and referring to them by $ref.
+func (g *schemaGenerator) schemaFor(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schemaFor(t.Elem())}
+	case reflect.Interface:
+		return map[string]interface{}{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name, ok := g.names[t]
+		if !ok {
+			name = g.componentName(t)
+			g.names[t] = name
+			// Register before recursing so self-references resolve to the $ref
+			g.schemas[name] = map[string]interface{}{}
+			g.schemas[name] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+// structSchema describes a struct's JSON fields, flattening embedded
+// structs the way encoding/json does.
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	props := map[string]interface{}{}
+	g.addFields(t, props)
+	return map[string]interface{}{"type": "object", "properties": props}
+}
+
+func (g *schemaGenerator) addFields(t reflect.Type, props map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			for embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.addFields(embedded, props)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		props[name] = g.schemaFor(field.Type)
+	}
+}
+
+// componentName picks a schema name for t: the capitalized type name, or
+// the package-qualified one if another type already took it.
+func (g *schemaGenerator) componentName(t reflect.Type) string {
+	name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+	if _, taken := g.schemas[name]; taken {
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	return name
+}
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/lherron/wrkq/internal/selectors"
+	"github.com/lherron/wrkq/internal/store"
+)
+
+// reactionRequest names the reaction target with exactly one of Task or
+// Comment.
+type reactionRequest struct {
+	Task    string `json:"task,omitempty"`
+	Comment string `json:"comment,omitempty"`
+	Emoji   string `json:"emoji,omitempty"`
+}
+
+// reactionTarget resolves the task or comment a reaction request refers to,
+// returning its resource type and UUID.
+func (s *daemonServer) reactionTarget(r *http.Request, req reactionRequest) (string, string, int, error) {
+	switch {
+	case req.Task != "" && req.Comment != "":
+		return "", "", http.StatusBadRequest, fmt.Errorf("specify either task or comment, not both")
+	case req.Task != "":
+		uuid, _, err := s.resolveTask(r, req.Task)
+		if err != nil {
+			return "", "", http.StatusNotFound, err
+		}
+		return "task", uuid, 0, nil
+	case req.Comment != "":
+		uuid, _, err := selectors.ResolveComment(s.db, req.Comment)
+		if err != nil {
+			return "", "", http.StatusNotFound, err
+		}
+		var taskUUID string
+		if err := s.db.QueryRow("SELECT task_uuid FROM comments WHERE uuid = ?", uuid).Scan(&taskUUID); err != nil {
+			return "", "", http.StatusNotFound, err
+		}
+		if err := s.checkTaskInScope(r, taskUUID); err != nil {
+			return "", "", http.StatusNotFound, err
+		}
+		return "comment", uuid, 0, nil
+	default:
+		return "", "", http.StatusBadRequest, fmt.Errorf("task or comment required")
+	}
+}
+
+func (s *daemonServer) handleReactionsAdd(w http.ResponseWriter, r *http.Request) {
+	s.handleReactionChange(w, r, true)
+}
+
+func (s *daemonServer) handleReactionsRemove(w http.ResponseWriter, r *http.Request) {
+	s.handleReactionChange(w, r, false)
+}
+
+// handleReactionChange adds or removes the caller's reaction and responds
+// with whether anything changed and the resource's updated summary.
+func (s *daemonServer) handleReactionChange(w http.ResponseWriter, r *http.Request, add bool) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req reactionRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Emoji == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("emoji required"))
+		return
+	}
+
+	resourceType, resourceUUID, status, err := s.reactionTarget(r, req)
+	if err != nil {
+		s.writeError(w, status, err)
+		return
+	}
+
+	actorUUID, err := s.resolveActorUUID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	reactions := store.New(s.db).Reactions
+	var changed bool
+	if add {
+		changed, err = reactions.Add(actorUUID, resourceType, resourceUUID, req.Emoji)
+	} else {
+		changed, err = reactions.Remove(actorUUID, resourceType, resourceUUID, req.Emoji)
+	}
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	summary, err := reactions.Summary(resourceType, resourceUUID)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"changed":   changed,
+		"reactions": summary,
+	})
+}
+
+func (s *daemonServer) handleReactionsList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req reactionRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	resourceType, resourceUUID, status, err := s.reactionTarget(r, req)
+	if err != nil {
+		s.writeError(w, status, err)
+		return
+	}
+
+	reactions, err := store.New(s.db).Reactions.List(resourceType, resourceUUID)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"reactions": reactions,
+	})
+}
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/lherron/wrkq/internal/selectors"
+)
+
+// maxResolveSelectors caps how many selectors one /v1/resolve call may carry.
+const maxResolveSelectors = 500
+
+type resolveRequest struct {
+	Selectors []string `json:"selectors"`
+}
+
+// resolvedSelector is one entry of a /v1/resolve response: the resource a
+// selector names, or why it names none.
+type resolvedSelector struct {
+	Type       string                `json:"type,omitempty"`
+	UUID       string                `json:"uuid,omitempty"`
+	ID         string                `json:"id,omitempty"`
+	Path       string                `json:"path,omitempty"`
+	Error      string                `json:"error,omitempty"`
+	Candidates []selectors.Candidate `json:"candidates,omitempty"`
+}
+
+// handleResolve resolves a batch of task and container selectors, keyed by
+// the selector as given. A failed selector gets an error entry instead of
+// failing the request.
+func (s *daemonServer) handleResolve(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req resolveRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(req.Selectors) == 0 {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("selectors is required"))
+		return
+	}
+	if len(req.Selectors) > maxResolveSelectors {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("at most %d selectors per request", maxResolveSelectors))
+		return
+	}
+
+	results := make(map[string]resolvedSelector, len(req.Selectors))
+	for _, sel := range req.Selectors {
+		if _, ok := results[sel]; ok {
+			continue
+		}
+		entry, err := s.resolveSelector(r, sel)
+		if err != nil {
+			entry = resolvedSelector{Error: err.Error()}
+			var ambiguous *selectors.AmbiguousError
+			if errors.As(err, &ambiguous) {
+				entry.Candidates = ambiguous.Candidates
+			}
+		}
+		results[sel] = entry
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{"results": results})
+}
+
+// resolveSelector sniffs what kind of resource sel names: t: selectors and
+// T- IDs are tasks, P- IDs are containers, and UUIDs and paths are tried as a
+// task first and then as a container.
+func (s *daemonServer) resolveSelector(r *http.Request, sel string) (resolvedSelector, error) {
+	parsed := selectors.Parse(strings.TrimSpace(sel))
+	if parsed.Token == "" {
+		return resolvedSelector{}, fmt.Errorf("empty selector")
+	}
+	switch {
+	case parsed.Type == selectors.TypeComment:
+		return resolvedSelector{}, fmt.Errorf("comment selectors are not supported")
+	case parsed.Type == selectors.TypeTask || strings.HasPrefix(parsed.Token, "T-"):
+		return s.resolveTaskEntry(r, sel)
+	case strings.HasPrefix(parsed.Token, "P-"):
+		return s.resolveContainerEntry(r, sel)
+	}
+
+	entry, taskErr := s.resolveTaskEntry(r, sel)
+	if taskErr == nil {
+		return entry, nil
+	}
+	entry, containerErr := s.resolveContainerEntry(r, sel)
+	if containerErr == nil {
+		return entry, nil
+	}
+	var ambiguous *selectors.AmbiguousError
+	switch {
+	case errors.As(taskErr, &ambiguous):
+		return resolvedSelector{}, taskErr
+	case errors.As(containerErr, &ambiguous):
+		return resolvedSelector{}, containerErr
+	}
+	return resolvedSelector{}, fmt.Errorf("no task or container matches %s", sel)
+}
+
+func (s *daemonServer) resolveTaskEntry(r *http.Request, sel string) (resolvedSelector, error) {
+	uuid, friendlyID, err := s.resolveTask(r, sel)
+	if err != nil {
+		return resolvedSelector{}, err
+	}
+	entry := resolvedSelector{Type: string(selectors.TypeTask), UUID: uuid, ID: friendlyID}
+	err = s.db.QueryRow(`
+		SELECT cp.path || '/' || t.slug
+		FROM tasks t
+		JOIN v_container_paths cp ON cp.uuid = t.project_uuid
+		WHERE t.uuid = ?
+	`, uuid).Scan(&entry.Path)
+	return entry, err
+}
+
+func (s *daemonServer) resolveContainerEntry(r *http.Request, sel string) (resolvedSelector, error) {
+	uuid, friendlyID, err := s.resolveContainer(r, sel)
+	if err != nil {
+		return resolvedSelector{}, err
+	}
+	entry := resolvedSelector{Type: string(selectors.TypeContainer), UUID: uuid, ID: friendlyID}
+	err = s.db.QueryRow(`SELECT path FROM v_container_paths WHERE uuid = ?`, uuid).Scan(&entry.Path)
+	return entry, err
+}
@@ -0,0 +1,275 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/lherron/wrkq/internal/paths"
+	"github.com/lherron/wrkq/internal/store"
+)
+
+type sectionsListRequest struct {
+	Project         string `json:"project"`
+	IncludeArchived bool   `json:"include_archived,omitempty"`
+}
+
+func (s *daemonServer) handleSectionsList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req sectionsListRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	projectUUID, ok := s.resolveSectionProject(w, r, req.Project)
+	if !ok {
+		return
+	}
+
+	sections, err := store.New(s.db).Sections.List(projectUUID, req.IncludeArchived)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"sections": sections,
+	})
+}
+
+type sectionsCreateRequest struct {
+	Project    string          `json:"project"`
+	Slug       string          `json:"slug"`
+	Title      string          `json:"title,omitempty"`
+	Role       string          `json:"role,omitempty"`
+	OrderIndex *int            `json:"order_index,omitempty"`
+	IsDefault  bool            `json:"is_default,omitempty"`
+	WIPLimit   *int            `json:"wip_limit,omitempty"`
+	Meta       json.RawMessage `json:"meta,omitempty"`
+}
+
+func (s *daemonServer) handleSectionsCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req sectionsCreateRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	projectUUID, ok := s.resolveSectionProject(w, r, req.Project)
+	if !ok {
+		return
+	}
+
+	slug, err := paths.NormalizeSlug(req.Slug)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid slug: %w", err))
+		return
+	}
+
+	actorUUID, err := s.resolveActorUUID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	params := store.SectionCreateParams{
+		ProjectUUID: projectUUID,
+		Slug:        slug,
+		Title:       req.Title,
+		Role:        req.Role,
+		OrderIndex:  req.OrderIndex,
+		IsDefault:   req.IsDefault,
+		WIPLimit:    req.WIPLimit,
+	}
+	if len(req.Meta) > 0 && string(req.Meta) != "null" {
+		meta := string(req.Meta)
+		params.Meta = &meta
+	}
+
+	section, err := store.New(s.db).Sections.Create(actorUUID, params)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"section": section,
+	})
+}
+
+type sectionsUpdateRequest struct {
+	Project string                 `json:"project"`
+	Section string                 `json:"section"`
+	Fields  map[string]interface{} `json:"fields"`
+}
+
+func (s *daemonServer) handleSectionsUpdate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req sectionsUpdateRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	projectUUID, ok := s.resolveSectionProject(w, r, req.Project)
+	if !ok {
+		return
+	}
+
+	sections := store.New(s.db).Sections
+	sectionUUID, err := sections.Resolve(projectUUID, req.Section)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	fields := map[string]interface{}{}
+	for key, value := range req.Fields {
+		switch key {
+		case "slug":
+			raw, _ := value.(string)
+			slug, err := paths.NormalizeSlug(raw)
+			if err != nil {
+				s.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid slug: %w", err))
+				return
+			}
+			fields[key] = slug
+		case "title", "role":
+			str, ok := value.(string)
+			if !ok || str == "" {
+				s.writeError(w, http.StatusBadRequest, fmt.Errorf("%s must be a non-empty string", key))
+				return
+			}
+			fields[key] = str
+		case "is_default":
+			b, ok := value.(bool)
+			if !ok {
+				s.writeError(w, http.StatusBadRequest, fmt.Errorf("is_default must be a boolean"))
+				return
+			}
+			fields[key] = b
+		case "wip_limit":
+			// The store validates every wip_limit form, including JSON numbers
+			fields[key] = value
+		case "meta":
+			if value == nil {
+				fields[key] = nil
+				continue
+			}
+			data, err := json.Marshal(value)
+			if err != nil {
+				s.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid meta: %w", err))
+				return
+			}
+			fields[key] = string(data)
+		default:
+			s.writeError(w, http.StatusBadRequest, fmt.Errorf("unsupported section field: %s", key))
+			return
+		}
+	}
+
+	if len(fields) == 0 {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("no fields to update"))
+		return
+	}
+
+	actorUUID, err := s.resolveActorUUID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	section, err := sections.UpdateFields(actorUUID, sectionUUID, fields)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"section": section,
+	})
+}
+
+type sectionsReorderRequest struct {
+	Project  string   `json:"project"`
+	Sections []string `json:"sections"`
+}
+
+func (s *daemonServer) handleSectionsReorder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req sectionsReorderRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if len(req.Sections) == 0 {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("sections required"))
+		return
+	}
+
+	projectUUID, ok := s.resolveSectionProject(w, r, req.Project)
+	if !ok {
+		return
+	}
+
+	sections := store.New(s.db).Sections
+	ordered := make([]string, 0, len(req.Sections))
+	for _, selector := range req.Sections {
+		sectionUUID, err := sections.Resolve(projectUUID, selector)
+		if err != nil {
+			s.writeError(w, http.StatusNotFound, err)
+			return
+		}
+		ordered = append(ordered, sectionUUID)
+	}
+
+	actorUUID, err := s.resolveActorUUID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := sections.Reorder(actorUUID, projectUUID, ordered)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"sections": result,
+	})
+}
+
+// resolveSectionProject resolves the project selector for a sections request,
+// writing the error response itself when resolution fails.
+func (s *daemonServer) resolveSectionProject(w http.ResponseWriter, r *http.Request, project string) (string, bool) {
+	if project == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("project required"))
+		return "", false
+	}
+	projectUUID, _, err := s.resolveContainer(r, project)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return "", false
+	}
+	return projectUUID, true
+}
@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/lherron/wrkq/internal/db"
+)
+
+// selectorCacheCheckInterval is how often a selector cache polls event_log
+// for changes made outside the daemon, such as by the wrkq CLI.
+const selectorCacheCheckInterval = time.Second
+
+// selectorCache is an LRU of resolved project and assignee selectors,
+// shared across requests. Any container or actor event other than a create
+// (a rename, move, archive, delete, restore or merge) can change what a
+// selector names, so one empties the cache. The daemon's own writes are
+// seen by the next lookup after they start; writes by other processes
+// within selectorCacheCheckInterval. A nil *selectorCache caches nothing.
+//
+// Invalidation relies on event_log ids only growing, so the cache assumes
+// the database file isn't replaced under a running daemon. A restore that
+// leaves event_log shorter than before is noticed and empties the cache;
+// one that leaves it as long or longer is not, so restart the daemon after
+// swapping the database.
+type selectorCache struct {
+	db   *db.DB
+	size int
+
+	mu      sync.Mutex
+	entries map[selectorCacheKey]*list.Element
+	order   *list.List // most recently used first
+	// lastEventID is the newest event_log row checked for changes, and
+	// checkedAt when that was. generation counts the times the cache was
+	// emptied, so a resolution that raced with one isn't stored.
+	lastEventID int64
+	checkedAt   time.Time
+	generation  uint64
+	// writes counts the daemon's write requests in flight; while there are
+	// any, or one has finished since the last check, lookups check first.
+	writes int
+	dirty  bool
+}
+
+type selectorCacheKey struct {
+	kind     string // "container" or "actor"
+	selector string
+}
+
+type selectorCacheEntry struct {
+	key        selectorCacheKey
+	uuid       string
+	friendlyID string
+}
+
+// newSelectorCache returns a cache of up to size selectors, or nil if size
+// isn't positive.
+func newSelectorCache(database *db.DB, size int) *selectorCache {
+	if size <= 0 {
+		return nil
+	}
+	c := &selectorCache{
+		db:      database,
+		size:    size,
+		entries: map[selectorCacheKey]*list.Element{},
+		order:   list.New(),
+	}
+	_ = database.QueryRow("SELECT COALESCE(MAX(id), 0) FROM event_log").Scan(&c.lastEventID)
+	c.checkedAt = time.Now()
+	return c
+}
+
+// get returns a cached resolution and the generation to pass to put when
+// the selector has to be resolved afresh.
+func (c *selectorCache) get(kind, selector string) (uuid, friendlyID string, generation uint64, ok bool) {
+	if c == nil {
+		return "", "", 0, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checkLocked()
+	elem, ok := c.entries[selectorCacheKey{kind, selector}]
+	if !ok {
+		return "", "", c.generation, false
+	}
+	c.order.MoveToFront(elem)
+	entry := elem.Value.(*selectorCacheEntry)
+	return entry.uuid, entry.friendlyID, c.generation, true
+}
+
+// put caches a resolution made after get returned generation, unless the
+// cache has been emptied since.
+func (c *selectorCache) put(kind, selector, uuid, friendlyID string, generation uint64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	key := selectorCacheKey{kind, selector}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = &selectorCacheEntry{key: key, uuid: uuid, friendlyID: friendlyID}
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&selectorCacheEntry{key: key, uuid: uuid, friendlyID: friendlyID})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*selectorCacheEntry).key)
+	}
+}
+
+// beginWrite and endWrite bracket a write request, so lookups made during
+// or after it check event_log before trusting the cache.
+func (c *selectorCache) beginWrite() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.writes++
+	c.mu.Unlock()
+}
+
+func (c *selectorCache) endWrite() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.writes--
+	c.dirty = true
+	c.mu.Unlock()
+}
+
+// checkLocked empties the cache if event_log has a change since it last
+// looked, or has fewer events than then (the database was restored or
+// replaced). It looks when a daemon write may have happened since, and
+// otherwise at most every selectorCacheCheckInterval. If the check fails
+// the cache is emptied, to be safe.
+func (c *selectorCache) checkLocked() {
+	if c.writes == 0 && !c.dirty && time.Since(c.checkedAt) < selectorCacheCheckInterval {
+		return
+	}
+	c.dirty = false
+	c.checkedAt = time.Now()
+
+	var maxID, maxChangeID int64
+	err := c.db.QueryRow(`
+		SELECT (SELECT COALESCE(MAX(id), 0) FROM event_log),
+		       (SELECT COALESCE(MAX(CASE WHEN resource_type IN ('container', 'actor') AND event_type NOT LIKE '%.created' THEN id END), 0)
+		        FROM event_log WHERE id > ?)
+	`, c.lastEventID).Scan(&maxID, &maxChangeID)
+	if err != nil || maxChangeID > 0 || maxID < c.lastEventID {
+		c.entries = map[selectorCacheKey]*list.Element{}
+		c.order.Init()
+		c.generation++
+	}
+	if err == nil {
+		c.lastEventID = maxID
+	}
+}
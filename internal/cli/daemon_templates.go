@@ -0,0 +1,211 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/lherron/wrkq/internal/domain"
+	"github.com/lherron/wrkq/internal/store"
+)
+
+// templateFieldKeys are the tasks/create fields a template may set.
+var templateFieldKeys = map[string]bool{
+	"title":       true,
+	"description": true,
+	"state":       true,
+	"priority":    true,
+	"kind":        true,
+	"labels":      true,
+	"due_at":      true,
+	"start_at":    true,
+	"assignee":    true,
+	"parent_task": true,
+}
+
+// templatePlaceholder matches a {{var}} placeholder in a template's title or
+// description.
+var templatePlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+
+func (s *daemonServer) handleTemplatesList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	templates, err := store.New(s.db).Templates.List()
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"templates": templates,
+	})
+}
+
+type templatesCreateRequest struct {
+	Name   string                 `json:"name"`
+	Fields map[string]interface{} `json:"fields,omitempty"` // tasks/create fields
+}
+
+func (s *daemonServer) handleTemplatesCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req templatesCreateRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Reject unknown keys so typos fail at save time rather than being
+	// silently dropped by every task created from the template
+	for key := range req.Fields {
+		if !templateFieldKeys[key] {
+			s.writeError(w, http.StatusBadRequest, fmt.Errorf("unknown template field: %s", key))
+			return
+		}
+	}
+	if req.Fields == nil {
+		req.Fields = map[string]interface{}{}
+	}
+	data, err := json.Marshal(req.Fields)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	actorUUID, err := s.resolveActorUUID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	template, err := store.New(s.db).Templates.Create(actorUUID, req.Name, data)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"template": template,
+	})
+}
+
+type tasksCreateFromTemplateRequest struct {
+	Template  string                 `json:"template"` // name or UUID
+	Path      string                 `json:"path"`
+	Overrides map[string]interface{} `json:"overrides,omitempty"`
+	Args      map[string]string      `json:"args,omitempty"` // {{var}} values
+}
+
+// handleTasksCreateFromTemplate creates a task from a template's fields with
+// overrides merged on top, then behaves exactly like /v1/tasks/create.
+func (s *daemonServer) handleTasksCreateFromTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req tasksCreateFromTemplateRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if req.Template == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("template required"))
+		return
+	}
+
+	template, err := store.New(s.db).Templates.Resolve(req.Template)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	fields, err := templateTaskFields(template.Fields, req.Overrides, req.Args)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	actorUUID, err := s.resolveActorUUID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	params, err := s.buildTaskCreateParams(r, taskCreateRequest{Path: req.Path, Fields: fields})
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	svc := store.New(s.db)
+	result, err := svc.Tasks.Create(actorUUID, params)
+	if err != nil {
+		var wipErr *domain.WIPLimitExceededError
+		if errors.As(err, &wipErr) {
+			s.writeError(w, http.StatusConflict, err)
+			return
+		}
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	task, err := loadTaskDetail(s.db, result.UUID, true, true)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"task":     task,
+		"template": template.Name,
+	})
+}
+
+// templateTaskFields merges overrides onto a template's stored fields and
+// fills {{var}} placeholders in the title and description from args. A
+// placeholder without a matching arg is an error rather than left in place.
+func templateTaskFields(stored json.RawMessage, overrides map[string]interface{}, args map[string]string) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if len(stored) > 0 {
+		if err := json.Unmarshal(stored, &fields); err != nil {
+			return nil, fmt.Errorf("invalid template fields: %w", err)
+		}
+	}
+	for key, value := range overrides {
+		if !templateFieldKeys[key] {
+			return nil, fmt.Errorf("unknown override field: %s", key)
+		}
+		fields[key] = value
+	}
+
+	for _, key := range []string{"title", "description"} {
+		text, ok := fields[key].(string)
+		if !ok {
+			continue
+		}
+		var missing []string
+		fields[key] = templatePlaceholder.ReplaceAllStringFunc(text, func(match string) string {
+			name := templatePlaceholder.FindStringSubmatch(match)[1]
+			value, ok := args[name]
+			if !ok {
+				missing = append(missing, name)
+				return match
+			}
+			return value
+		})
+		if len(missing) > 0 {
+			return nil, fmt.Errorf("missing template arg %q for %s", missing[0], key)
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,4120 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lherron/wrkq/internal/actors"
+	"github.com/lherron/wrkq/internal/attach"
+	"github.com/lherron/wrkq/internal/bundle"
+	"github.com/lherron/wrkq/internal/config"
+	"github.com/lherron/wrkq/internal/store"
+)
+
+// newTestDaemon returns a daemon server backed by a fresh test database.
+func newTestDaemon(t *testing.T) (*daemonServer, http.Handler) {
+	t.Helper()
+	database, dbPath := setupTestEnv(t)
+	server := &daemonServer{
+		db:  database,
+		cfg: &config.Config{DBPath: dbPath},
+	}
+	mux := http.NewServeMux()
+	server.registerRoutes(mux)
+	return server, mux
+}
+
+// daemonPost sends a JSON POST request to the handler and decodes the response.
+func daemonPost(t *testing.T, handler http.Handler, path string, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	return daemonPostWithHeaders(t, handler, path, nil, body)
+}
+
+// daemonPostWithHeaders is daemonPost with extra request headers, which may
+// override the default X-Wrkq-Actor.
+func daemonPostWithHeaders(t *testing.T, handler http.Handler, path string, headers map[string]string, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("failed to marshal request: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Wrkq-Actor", "test-user")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, resp
+}
+
+func TestDaemonTasksBulkCreate(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	forced := "550e8400-e29b-41d4-a716-446655440501"
+
+	code, resp := daemonPost(t, handler, "/v1/tasks/bulk_create", map[string]interface{}{
+		"items": []map[string]interface{}{
+			{"path": "inbox/bulk-one", "fields": map[string]interface{}{"title": "Bulk One"}},
+			{"path": "inbox/bulk-two", "force_uuid": forced},
+			{"path": "inbox/bulk-three", "force_uuid": forced},
+		},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %v", code, resp)
+	}
+	if tasks := resp["tasks"].([]interface{}); len(tasks) != 2 {
+		t.Fatalf("expected 2 created tasks, got %d", len(tasks))
+	}
+	errs := resp["errors"].([]interface{})
+	if len(errs) != 1 || errs[0].(map[string]interface{})["index"].(float64) != 2 {
+		t.Fatalf("expected a single error for item 2, got %v", errs)
+	}
+	if resp["committed"] != true {
+		t.Fatalf("expected non-atomic batch to commit")
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/tasks/bulk_create", map[string]interface{}{
+		"atomic": true,
+		"items": []map[string]interface{}{
+			{"path": "inbox/atomic-one"},
+			{"path": "inbox/atomic-two", "force_uuid": forced},
+		},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %v", code, resp)
+	}
+	if resp["committed"] != false {
+		t.Fatalf("expected atomic batch to roll back")
+	}
+	if tasks := resp["tasks"].([]interface{}); len(tasks) != 0 {
+		t.Fatalf("expected no tasks from rolled back batch, got %d", len(tasks))
+	}
+
+	var count int
+	if err := server.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE slug = 'atomic-one'").Scan(&count); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("expected atomic-one to be rolled back, found %d", count)
+	}
+}
+
+func TestDaemonCommentsUpdate(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "inbox/commented"})
+	if code != http.StatusOK {
+		t.Fatalf("task create failed: %d %v", code, resp)
+	}
+	taskID := resp["task"].(map[string]interface{})["id"].(string)
+
+	code, resp = daemonPost(t, handler, "/v1/comments/create", map[string]interface{}{"task": taskID, "body": "first"})
+	if code != http.StatusOK {
+		t.Fatalf("comment create failed: %d %v", code, resp)
+	}
+	commentID := resp["comment"].(map[string]interface{})["id"].(string)
+
+	code, resp = daemonPost(t, handler, "/v1/comments/update", map[string]interface{}{"comment": commentID, "body": "edited", "ifMatch": 1})
+	if code != http.StatusOK {
+		t.Fatalf("comment update failed: %d %v", code, resp)
+	}
+	comment := resp["comment"].(map[string]interface{})
+	if comment["body"] != "edited" || comment["etag"].(float64) != 2 {
+		t.Fatalf("unexpected updated comment: %v", comment)
+	}
+	if _, ok := comment["updated_at"]; !ok {
+		t.Fatalf("expected updated_at to be set")
+	}
+
+	code, _ = daemonPost(t, handler, "/v1/comments/update", map[string]interface{}{"comment": commentID, "body": "stale", "ifMatch": 1})
+	if code != http.StatusConflict {
+		t.Fatalf("expected 409 for stale etag, got %d", code)
+	}
+
+	if _, err := server.db.Exec("UPDATE comments SET deleted_at = datetime('now') WHERE id = ?", commentID); err != nil {
+		t.Fatalf("failed to soft-delete comment: %v", err)
+	}
+
+	code, _ = daemonPost(t, handler, "/v1/comments/update", map[string]interface{}{"comment": commentID, "body": "ghost"})
+	if code != http.StatusNotFound {
+		t.Fatalf("expected 404 for deleted comment, got %d", code)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/comments/update", map[string]interface{}{"comment": commentID, "body": "forced", "force": true})
+	if code != http.StatusOK {
+		t.Fatalf("expected forced update to succeed, got %d %v", code, resp)
+	}
+
+	var eventCount int
+	if err := server.db.QueryRow("SELECT COUNT(*) FROM event_log WHERE event_type = 'comment.updated'").Scan(&eventCount); err != nil {
+		t.Fatalf("event count failed: %v", err)
+	}
+	if eventCount != 2 {
+		t.Fatalf("expected 2 comment.updated events, got %d", eventCount)
+	}
+}
+
+func TestDaemonCommentsListPagination(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "inbox/chatty"})
+	if code != http.StatusOK {
+		t.Fatalf("task create failed: %d %v", code, resp)
+	}
+	taskID := resp["task"].(map[string]interface{})["id"].(string)
+
+	var ids []string
+	uuids := map[string]string{}
+	for i := 0; i < 5; i++ {
+		code, resp := daemonPost(t, handler, "/v1/comments/create", map[string]interface{}{"task": taskID, "body": fmt.Sprintf("comment %d", i)})
+		if code != http.StatusOK {
+			t.Fatalf("comment create failed: %d %v", code, resp)
+		}
+		comment := resp["comment"].(map[string]interface{})
+		ids = append(ids, comment["id"].(string))
+		uuids[comment["id"].(string)] = comment["uuid"].(string)
+	}
+	// The rest share a created_at, so their order falls back to uuid
+	if _, err := server.db.Exec("UPDATE comments SET created_at = CASE id WHEN ? THEN '2099-01-01 00:00:00' ELSE '2098-01-01 00:00:00' END", ids[0]); err != nil {
+		t.Fatalf("failed to backdate comments: %v", err)
+	}
+	want := append([]string{}, ids[1:]...)
+	sort.Slice(want, func(i, j int) bool { return uuids[want[i]] < uuids[want[j]] })
+	want = append(want, ids[0])
+
+	var got []string
+	cursorStr := ""
+	for page := 0; ; page++ {
+		if page > len(want) {
+			t.Fatalf("pagination did not terminate, got %v", got)
+		}
+		code, resp := daemonPost(t, handler, "/v1/comments/list", map[string]interface{}{"task": taskID, "limit": 2, "cursor": cursorStr})
+		if code != http.StatusOK {
+			t.Fatalf("comments list failed: %d %v", code, resp)
+		}
+		comments := resp["comments"].([]interface{})
+		if len(comments) > 2 {
+			t.Fatalf("page %d exceeded the limit: %v", page, comments)
+		}
+		for _, c := range comments {
+			got = append(got, c.(map[string]interface{})["id"].(string))
+		}
+		cursorStr = resp["next_cursor"].(string)
+		if cursorStr == "" {
+			break
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v across pages, got %v", want, got)
+	}
+}
+
+func TestDaemonCommentsThread(t *testing.T) {
+	_, handler := newTestDaemon(t)
+
+	taskIDs := map[string]string{}
+	for _, path := range []string{"inbox/discussed", "inbox/unrelated"} {
+		code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": path})
+		if code != http.StatusOK {
+			t.Fatalf("task create failed: %d %v", code, resp)
+		}
+		taskIDs[path] = resp["task"].(map[string]interface{})["id"].(string)
+	}
+	taskID := taskIDs["inbox/discussed"]
+
+	post := func(body, replyTo string) string {
+		t.Helper()
+		req := map[string]interface{}{"task": taskID, "body": body}
+		if replyTo != "" {
+			req["reply_to"] = replyTo
+		}
+		code, resp := daemonPost(t, handler, "/v1/comments/create", req)
+		if code != http.StatusOK {
+			t.Fatalf("comment create failed: %d %v", code, resp)
+		}
+		return resp["comment"].(map[string]interface{})["id"].(string)
+	}
+	root := post("Should we cache this?", "")
+	reply := post("Only the hot path", root)
+	post("Agreed, measured 3x", reply)
+	post("Second reply", root)
+	post("Unrelated note", "")
+
+	code, resp := daemonPost(t, handler, "/v1/comments/create", map[string]interface{}{
+		"task": taskIDs["inbox/unrelated"], "body": "Cross-task", "reply_to": root,
+	})
+	if code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a cross-task reply, got %d %v", code, resp)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/comments/thread", map[string]interface{}{"task": taskID})
+	if code != http.StatusOK {
+		t.Fatalf("thread failed: %d %v", code, resp)
+	}
+
+	// Render the tree as nested bodies so the shape is easy to compare
+	var render func(nodes []interface{}) []interface{}
+	render = func(nodes []interface{}) []interface{} {
+		out := []interface{}{}
+		for _, raw := range nodes {
+			node := raw.(map[string]interface{})
+			out = append(out, node["body"])
+			if replies := node["replies"].([]interface{}); len(replies) > 0 {
+				out = append(out, render(replies))
+			}
+		}
+		return out
+	}
+	got := render(resp["thread"].([]interface{}))
+	want := []interface{}{
+		"Should we cache this?",
+		[]interface{}{"Only the hot path", []interface{}{"Agreed, measured 3x"}, "Second reply"},
+		"Unrelated note",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected thread:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestDaemonAttachmentsDownload(t *testing.T) {
+	server, handler := newTestDaemon(t)
+	server.cfg.AttachDir = t.TempDir()
+
+	code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "inbox/with-file"})
+	if code != http.StatusOK {
+		t.Fatalf("task create failed: %d %v", code, resp)
+	}
+	task := resp["task"].(map[string]interface{})
+	taskID := task["id"].(string)
+	taskUUID := task["uuid"].(string)
+
+	// Upload the way `wrkq attach put` does
+	content := []byte("%PDF-1.4\n\x00\x01binary body\n")
+	src := filepath.Join(t.TempDir(), "report.pdf")
+	if err := os.WriteFile(src, content, 0644); err != nil {
+		t.Fatalf("failed to write source file: %v", err)
+	}
+	if err := attach.EnsureTaskDir(server.cfg.AttachDir, taskUUID); err != nil {
+		t.Fatalf("EnsureTaskDir failed: %v", err)
+	}
+	relativePath := attach.RelativePath(taskUUID, "report.pdf")
+	size, checksum, err := attach.CopyFile(src, attach.AbsolutePath(server.cfg.AttachDir, relativePath))
+	if err != nil {
+		t.Fatalf("CopyFile failed: %v", err)
+	}
+	if _, err := server.db.Exec(`
+		INSERT INTO attachments (id, task_uuid, filename, relative_path, mime_type, size_bytes, checksum, created_by_actor_uuid)
+		VALUES ('', ?, 'report.pdf', ?, 'application/pdf', ?, ?, '00000000-0000-0000-0000-000000000001')
+	`, taskUUID, relativePath, size, checksum); err != nil {
+		t.Fatalf("failed to insert attachment: %v", err)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/attachments/list", map[string]interface{}{"task": taskID})
+	if code != http.StatusOK {
+		t.Fatalf("attachments list failed: %d %v", code, resp)
+	}
+	list := resp["attachments"].([]interface{})
+	if len(list) != 1 {
+		t.Fatalf("expected 1 attachment, got %v", list)
+	}
+	meta := list[0].(map[string]interface{})
+	if meta["filename"] != "report.pdf" || meta["mime_type"] != "application/pdf" ||
+		meta["checksum"] != checksum || meta["size_bytes"] != float64(len(content)) {
+		t.Fatalf("unexpected attachment metadata: %v", meta)
+	}
+	attachmentID := meta["id"].(string)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/attachments/get?"+query, nil)
+		req.Header.Set("X-Wrkq-Actor", "test-user")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("attachment=" + attachmentID + "&task=" + taskID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("attachment get failed: %d %s", rec.Code, rec.Body.String())
+	}
+	if !bytes.Equal(rec.Body.Bytes(), content) {
+		t.Fatalf("downloaded bytes differ: got %q, want %q", rec.Body.Bytes(), content)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/pdf" {
+		t.Errorf("unexpected Content-Type: %s", got)
+	}
+	if got := rec.Header().Get("X-Wrkq-Checksum"); got != checksum {
+		t.Errorf("unexpected checksum header: %s", got)
+	}
+
+	// The attachment must belong to the named task
+	code, resp = daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "inbox/other"})
+	if code != http.StatusOK {
+		t.Fatalf("task create failed: %d %v", code, resp)
+	}
+	otherID := resp["task"].(map[string]interface{})["id"].(string)
+	if rec := get("attachment=" + attachmentID + "&task=" + otherID); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for wrong task, got %d", rec.Code)
+	}
+
+	if rec := get("attachment=ATT-99999"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown attachment, got %d", rec.Code)
+	}
+
+}
+
+func TestDaemonAttachmentsPutDedupes(t *testing.T) {
+	server, handler := newTestDaemon(t)
+	server.cfg.AttachDir = t.TempDir()
+
+	code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "inbox/uploads"})
+	if code != http.StatusOK {
+		t.Fatalf("task create failed: %d %v", code, resp)
+	}
+	task := resp["task"].(map[string]interface{})
+	taskID := task["id"].(string)
+	taskUUID := task["uuid"].(string)
+
+	content := []byte("same bytes twice\n")
+	put := func(req *http.Request) map[string]interface{} {
+		t.Helper()
+		req.Header.Set("X-Wrkq-Actor", "test-user")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("attachment put failed: %d %s", rec.Code, rec.Body.String())
+		}
+		var out map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		return out
+	}
+
+	// Multipart upload
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if err := mw.WriteField("task", taskID); err != nil {
+		t.Fatalf("failed to write field: %v", err)
+	}
+	part, err := mw.CreateFormFile("file", "first.txt")
+	if err != nil {
+		t.Fatalf("failed to create part: %v", err)
+	}
+	part.Write(content)
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/v1/attachments/put", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	first := put(req)
+	if first["deduplicated"] != false {
+		t.Fatalf("first upload should not be deduplicated: %v", first)
+	}
+	firstMeta := first["attachment"].(map[string]interface{})
+	if firstMeta["mime_type"] != "text/plain" || firstMeta["size_bytes"] != float64(len(content)) {
+		t.Fatalf("unexpected metadata: %v", firstMeta)
+	}
+
+	// Raw upload of the same content under another name
+	req = httptest.NewRequest(http.MethodPost, "/v1/attachments/put?task="+taskID, bytes.NewReader(content))
+	req.Header.Set("X-Wrkq-Filename", "second.txt")
+	second := put(req)
+	if second["deduplicated"] != true {
+		t.Fatalf("second upload should be deduplicated: %v", second)
+	}
+	secondMeta := second["attachment"].(map[string]interface{})
+	if secondMeta["id"] == firstMeta["id"] || secondMeta["filename"] != "second.txt" {
+		t.Fatalf("expected a new attachment row: %v", secondMeta)
+	}
+	if secondMeta["checksum"] != firstMeta["checksum"] {
+		t.Fatalf("expected matching checksums: %v vs %v", secondMeta, firstMeta)
+	}
+
+	// Both rows have their own path, but the blob is stored once
+	firstInfo, err := os.Stat(attach.AbsolutePath(server.cfg.AttachDir, firstMeta["relative_path"].(string)))
+	if err != nil {
+		t.Fatalf("failed to stat first blob: %v", err)
+	}
+	secondInfo, err := os.Stat(attach.AbsolutePath(server.cfg.AttachDir, secondMeta["relative_path"].(string)))
+	if err != nil {
+		t.Fatalf("failed to stat second blob: %v", err)
+	}
+	if !os.SameFile(firstInfo, secondInfo) {
+		t.Fatalf("expected the second upload to share the first blob")
+	}
+	entries, err := os.ReadDir(attach.TaskDir(server.cfg.AttachDir, taskUUID))
+	if err != nil {
+		t.Fatalf("failed to read task dir: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected only the two attachment names on disk, got %v", entries)
+	}
+
+	var rows, events int
+	server.db.QueryRow("SELECT COUNT(*) FROM attachments WHERE task_uuid = ?", taskUUID).Scan(&rows)
+	server.db.QueryRow("SELECT COUNT(*) FROM event_log WHERE event_type = 'attachment.created'").Scan(&events)
+	if rows != 2 || events != 2 {
+		t.Fatalf("expected 2 rows and 2 events, got %d and %d", rows, events)
+	}
+
+	get := httptest.NewRequest(http.MethodGet, "/v1/attachments/get?attachment="+secondMeta["id"].(string), nil)
+	get.Header.Set("X-Wrkq-Actor", "test-user")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, get)
+	if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), content) {
+		t.Fatalf("unexpected download: %d %q", rec.Code, rec.Body.Bytes())
+	}
+
+	// A duplicate filename on the same task is a conflict
+	req = httptest.NewRequest(http.MethodPost, "/v1/attachments/put?task="+taskID+"&name=first.txt", bytes.NewReader(content))
+	req.Header.Set("X-Wrkq-Actor", "test-user")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for duplicate filename, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestDaemonReactions(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "inbox/liked"})
+	if code != http.StatusOK {
+		t.Fatalf("task create failed: %d %v", code, resp)
+	}
+	taskID := resp["task"].(map[string]interface{})["id"].(string)
+	code, resp = daemonPost(t, handler, "/v1/comments/create", map[string]interface{}{"task": taskID, "body": "Shipped"})
+	if code != http.StatusOK {
+		t.Fatalf("comment create failed: %d %v", code, resp)
+	}
+	commentID := resp["comment"].(map[string]interface{})["id"].(string)
+
+	countEvents := func(eventType string) int {
+		t.Helper()
+		var n int
+		if err := server.db.QueryRow("SELECT COUNT(*) FROM event_log WHERE event_type = ?", eventType).Scan(&n); err != nil {
+			t.Fatalf("failed to count events: %v", err)
+		}
+		return n
+	}
+
+	for i, wantChanged := range []bool{true, false} {
+		code, resp := daemonPost(t, handler, "/v1/reactions/add", map[string]interface{}{"task": taskID, "emoji": "+1"})
+		if code != http.StatusOK || resp["changed"] != wantChanged {
+			t.Fatalf("add #%d: expected changed=%v, got %d %v", i+1, wantChanged, code, resp)
+		}
+		summary := resp["reactions"].([]interface{})
+		if len(summary) != 1 || summary[0].(map[string]interface{})["count"].(float64) != 1 {
+			t.Fatalf("add #%d: expected a single +1, got %v", i+1, summary)
+		}
+	}
+	if n := countEvents("reaction.added"); n != 1 {
+		t.Errorf("expected the repeated add to log nothing, got %d reaction.added events", n)
+	}
+
+	if code, resp := daemonPost(t, handler, "/v1/reactions/add", map[string]interface{}{"comment": commentID, "emoji": "eyes"}); code != http.StatusOK || resp["changed"] != true {
+		t.Fatalf("comment reaction failed: %d %v", code, resp)
+	}
+	if code, _ := daemonPost(t, handler, "/v1/reactions/add", map[string]interface{}{"task": taskID, "emoji": "thumbs up"}); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an emoji with whitespace, got %d", code)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/tasks/get", map[string]interface{}{"selector": taskID, "include_reactions": true})
+	if code != http.StatusOK {
+		t.Fatalf("task get failed: %d %v", code, resp)
+	}
+	reactions, _ := resp["task"].(map[string]interface{})["reactions"].([]interface{})
+	if len(reactions) != 1 {
+		t.Fatalf("expected the task's reaction summary, got %v", resp["task"])
+	}
+	if got := reactions[0].(map[string]interface{}); got["emoji"] != "+1" || !reflect.DeepEqual(got["actors"], []interface{}{"test-user"}) {
+		t.Errorf("unexpected summary: %v", got)
+	}
+
+	for i, wantChanged := range []bool{true, false} {
+		code, resp := daemonPost(t, handler, "/v1/reactions/remove", map[string]interface{}{"task": taskID, "emoji": "+1"})
+		if code != http.StatusOK || resp["changed"] != wantChanged {
+			t.Fatalf("remove #%d: expected changed=%v, got %d %v", i+1, wantChanged, code, resp)
+		}
+	}
+	if n := countEvents("reaction.removed"); n != 1 {
+		t.Errorf("expected 1 reaction.removed event, got %d", n)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/reactions/list", map[string]interface{}{"task": taskID})
+	if code != http.StatusOK || len(resp["reactions"].([]interface{})) != 0 {
+		t.Errorf("expected no reactions left on the task, got %d %v", code, resp)
+	}
+	code, resp = daemonPost(t, handler, "/v1/reactions/list", map[string]interface{}{"comment": commentID})
+	if code != http.StatusOK || len(resp["reactions"].([]interface{})) != 1 {
+		t.Errorf("expected the comment's reaction to remain, got %d %v", code, resp)
+	}
+}
+
+func TestDaemonTimeTracking(t *testing.T) {
+	_, handler := newTestDaemon(t)
+
+	code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{
+		"path":   "inbox/timed",
+		"fields": map[string]interface{}{"estimate_minutes": 90},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("task create failed: %d %v", code, resp)
+	}
+	task := resp["task"].(map[string]interface{})
+	if task["estimate_minutes"] != float64(90) {
+		t.Fatalf("expected estimate_minutes 90, got %v", task["estimate_minutes"])
+	}
+	taskID := task["id"].(string)
+
+	for i, minutes := range []int{30, 45} {
+		code, resp := daemonPost(t, handler, "/v1/time/log", map[string]interface{}{
+			"selector":   taskID,
+			"minutes":    minutes,
+			"note":       "pairing",
+			"started_at": "2026-03-01T09:00:00Z",
+		})
+		if code != http.StatusOK {
+			t.Fatalf("log #%d failed: %d %v", i+1, code, resp)
+		}
+		if entry := resp["time_entry"].(map[string]interface{}); entry["minutes"] != float64(minutes) || entry["actor_slug"] != "test-user" {
+			t.Errorf("log #%d: unexpected entry %v", i+1, entry)
+		}
+	}
+	if code, _ := daemonPost(t, handler, "/v1/time/log", map[string]interface{}{"selector": taskID, "minutes": 0}); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for zero minutes, got %d", code)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/time/total", map[string]interface{}{"selector": taskID, "include_entries": true})
+	if code != http.StatusOK {
+		t.Fatalf("time total failed: %d %v", code, resp)
+	}
+	total := resp["total"].(map[string]interface{})
+	if total["minutes"] != float64(75) || total["entries"] != float64(2) {
+		t.Errorf("expected 75 minutes over 2 entries, got %v", total)
+	}
+	if resp["estimate_minutes"] != float64(90) {
+		t.Errorf("expected estimate_minutes 90, got %v", resp["estimate_minutes"])
+	}
+	if entries := resp["entries"].([]interface{}); len(entries) != 2 {
+		t.Errorf("expected 2 entries, got %v", entries)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/tasks/get", map[string]interface{}{"selector": taskID, "include_time": true})
+	if code != http.StatusOK {
+		t.Fatalf("task get failed: %d %v", code, resp)
+	}
+	logged, _ := resp["task"].(map[string]interface{})["time_logged"].(map[string]interface{})
+	if logged["minutes"] != float64(75) || logged["entries"] != float64(2) {
+		t.Errorf("expected time_logged in task detail, got %v", resp["task"])
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/tasks/update", map[string]interface{}{"selector": taskID, "fields": map[string]interface{}{"estimate_minutes": -5}})
+	if code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a negative estimate, got %d %v", code, resp)
+	}
+	code, resp = daemonPost(t, handler, "/v1/tasks/update", map[string]interface{}{"selector": taskID, "fields": map[string]interface{}{"estimate_minutes": nil}})
+	if code != http.StatusOK {
+		t.Fatalf("clearing the estimate failed: %d %v", code, resp)
+	}
+	if _, ok := resp["task"].(map[string]interface{})["estimate_minutes"]; ok {
+		t.Errorf("expected the estimate to be cleared, got %v", resp["task"])
+	}
+}
+
+func TestDaemonCommentLookupErrors(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	code, resp := daemonPost(t, handler, "/v1/comments/delete", map[string]interface{}{"comment": "C-99999"})
+	if code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing comment, got %d %v", code, resp)
+	}
+	code, resp = daemonPost(t, handler, "/v1/comments/delete", map[string]interface{}{"comment": "not-a-comment"})
+	if code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a malformed selector, got %d %v", code, resp)
+	}
+
+	// A database failure is not a missing comment
+	if _, err := server.db.Exec("ALTER TABLE comments RENAME TO comments_gone"); err != nil {
+		t.Fatalf("failed to rename comments: %v", err)
+	}
+	code, resp = daemonPost(t, handler, "/v1/comments/delete", map[string]interface{}{"comment": "C-00001"})
+	if code != http.StatusInternalServerError {
+		t.Errorf("expected 500 when the lookup fails, got %d %v", code, resp)
+	}
+}
+
+func TestDaemonCommentsDeleteAndRestore(t *testing.T) {
+	_, handler := newTestDaemon(t)
+
+	_, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "inbox/deletable"})
+	taskID := resp["task"].(map[string]interface{})["id"].(string)
+	_, resp = daemonPost(t, handler, "/v1/comments/create", map[string]interface{}{"task": taskID, "body": "oops"})
+	commentID := resp["comment"].(map[string]interface{})["id"].(string)
+
+	code, resp := daemonPost(t, handler, "/v1/comments/delete", map[string]interface{}{"comment": commentID})
+	if code != http.StatusOK {
+		t.Fatalf("delete failed: %d %v", code, resp)
+	}
+	comment := resp["comment"].(map[string]interface{})
+	if _, ok := comment["deleted_at"]; !ok {
+		t.Fatalf("expected deleted_at on deleted comment: %v", comment)
+	}
+	if comment["deleted_by_actor_uuid"] != "00000000-0000-0000-0000-000000000001" {
+		t.Fatalf("expected deleted_by_actor_uuid to be the acting actor, got %v", comment["deleted_by_actor_uuid"])
+	}
+
+	_, resp = daemonPost(t, handler, "/v1/comments/list", map[string]interface{}{"task": taskID})
+	if resp["comments"] != nil {
+		t.Fatalf("expected deleted comment to be hidden from list, got %v", resp["comments"])
+	}
+
+	code, _ = daemonPost(t, handler, "/v1/comments/delete", map[string]interface{}{"comment": commentID})
+	if code != http.StatusNotFound {
+		t.Fatalf("expected 404 deleting an already deleted comment, got %d", code)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/comments/restore", map[string]interface{}{"comment": commentID, "ifMatch": 2})
+	if code != http.StatusOK {
+		t.Fatalf("restore failed: %d %v", code, resp)
+	}
+	comment = resp["comment"].(map[string]interface{})
+	if _, ok := comment["deleted_at"]; ok {
+		t.Fatalf("expected deleted_at to be cleared: %v", comment)
+	}
+	if comment["etag"].(float64) != 3 {
+		t.Fatalf("expected etag 3 after delete+restore, got %v", comment["etag"])
+	}
+}
+
+func TestDaemonEventsListPagination(t *testing.T) {
+	_, handler := newTestDaemon(t)
+
+	for _, slug := range []string{"ev-one", "ev-two", "ev-three"} {
+		if code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "inbox/" + slug}); code != http.StatusOK {
+			t.Fatalf("task create failed: %d %v", code, resp)
+		}
+	}
+
+	var seen []float64
+	cursorStr := ""
+	for page := 0; page < 10; page++ {
+		code, resp := daemonPost(t, handler, "/v1/events/list", map[string]interface{}{
+			"resource_type": "task",
+			"limit":         1,
+			"cursor":        cursorStr,
+		})
+		if code != http.StatusOK {
+			t.Fatalf("events list failed: %d %v", code, resp)
+		}
+		for _, raw := range resp["events"].([]interface{}) {
+			seen = append(seen, raw.(map[string]interface{})["id"].(float64))
+		}
+		cursorStr, _ = resp["next_cursor"].(string)
+		if cursorStr == "" {
+			break
+		}
+	}
+
+	if len(seen) != 3 {
+		t.Fatalf("expected 3 task events, got %v", seen)
+	}
+	for i := 1; i < len(seen); i++ {
+		if seen[i] <= seen[i-1] {
+			t.Fatalf("expected ascending event ids, got %v", seen)
+		}
+	}
+}
+
+func TestDaemonEventsListClampsLimit(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	if _, err := server.db.Exec(`
+		WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 600)
+		INSERT INTO event_log (resource_type, event_type) SELECT 'system', 'test.event' FROM n
+	`); err != nil {
+		t.Fatalf("failed to seed events: %v", err)
+	}
+
+	code, resp := daemonPost(t, handler, "/v1/events/list", map[string]interface{}{"limit": 10000})
+	if code != http.StatusOK {
+		t.Fatalf("events list failed: %d %v", code, resp)
+	}
+	if got := len(resp["events"].([]interface{})); got != maxEventsListLimit {
+		t.Fatalf("expected %d events, got %d", maxEventsListLimit, got)
+	}
+	if cursor, _ := resp["next_cursor"].(string); cursor == "" {
+		t.Fatalf("expected a next_cursor after a clamped page")
+	}
+}
+
+func TestDaemonContainersTreeCounts(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	if _, err := server.db.Exec(`
+		INSERT INTO containers (uuid, id, slug, title, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f1', 'P-00002', 'portal', 'Portal',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+		INSERT INTO containers (uuid, id, slug, title, parent_uuid, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f2', 'P-00003', 'auth', 'Auth', '00000000-0000-0000-0000-0000000000f1',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+		INSERT INTO containers (uuid, id, slug, title, parent_uuid, archived_at, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f3', 'P-00004', 'old', 'Old', '00000000-0000-0000-0000-0000000000f1',
+			'2025-01-01T00:00:00Z', '00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+	`); err != nil {
+		t.Fatalf("failed to seed containers: %v", err)
+	}
+	for path, state := range map[string]string{
+		"portal/home":        "open",
+		"portal/launch":      "completed",
+		"portal/auth/login":  "in_progress",
+		"portal/auth/sso":    "blocked",
+		"portal/auth/legacy": "archived",
+		"portal/old/thing":   "open",
+	} {
+		if code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": path}); code != http.StatusOK {
+			t.Fatalf("create %s failed: %d %v", path, code, resp)
+		}
+		slug := path[strings.LastIndex(path, "/")+1:]
+		if _, err := server.db.Exec(`UPDATE tasks SET state = ? WHERE slug = ?`, state, slug); err != nil {
+			t.Fatalf("failed to set state: %v", err)
+		}
+	}
+
+	countsOf := func(req map[string]interface{}) map[string]map[string]interface{} {
+		t.Helper()
+		code, resp := daemonPost(t, handler, "/v1/containers/tree", req)
+		if code != http.StatusOK {
+			t.Fatalf("tree failed: %d %v", code, resp)
+		}
+		counts := map[string]map[string]interface{}{}
+		var walk func(nodes []interface{})
+		walk = func(nodes []interface{}) {
+			for _, raw := range nodes {
+				node := raw.(map[string]interface{})
+				if node["type"] != "container" {
+					continue
+				}
+				if c, ok := node["task_counts"].(map[string]interface{}); ok {
+					counts[node["slug"].(string)] = c
+				}
+				if children, ok := node["children"].([]interface{}); ok {
+					walk(children)
+				}
+			}
+		}
+		walk(resp["children"].([]interface{}))
+		return counts
+	}
+
+	if counts := countsOf(map[string]interface{}{"path": "portal"}); len(counts) != 0 {
+		t.Fatalf("expected no counts unless requested, got %v", counts)
+	}
+
+	// Direct tasks only
+	counts := countsOf(map[string]interface{}{"include_counts": true})
+	if c := counts["portal"]; c["open"] != float64(1) || c["completed"] != float64(1) || c["in_progress"] != float64(0) || c["total"] != float64(2) {
+		t.Errorf("unexpected portal counts: %v", c)
+	}
+	if c := counts["auth"]; c["in_progress"] != float64(1) || c["blocked"] != float64(1) || c["total"] != float64(2) {
+		t.Errorf("unexpected auth counts: %v", c)
+	}
+	if _, ok := counts["old"]; ok {
+		t.Errorf("archived container should be hidden: %v", counts)
+	}
+
+	// Rolled up, leaving out the archived task and the archived container
+	counts = countsOf(map[string]interface{}{"include_counts": true, "rollup_counts": true})
+	if c := counts["portal"]; c["open"] != float64(1) || c["in_progress"] != float64(1) || c["blocked"] != float64(1) ||
+		c["completed"] != float64(1) || c["total"] != float64(4) {
+		t.Errorf("unexpected rolled-up portal counts: %v", c)
+	}
+	if c := counts["auth"]; c["total"] != float64(2) {
+		t.Errorf("unexpected rolled-up auth counts: %v", c)
+	}
+
+	counts = countsOf(map[string]interface{}{"include_counts": true, "rollup_counts": true, "include_archived": true})
+	if c := counts["portal"]; c["open"] != float64(2) || c["other"] != float64(1) || c["total"] != float64(6) {
+		t.Errorf("unexpected portal counts with archived: %v", c)
+	}
+	if c := counts["old"]; c["open"] != float64(1) || c["total"] != float64(1) {
+		t.Errorf("unexpected archived container counts: %v", c)
+	}
+
+	counts = countsOf(map[string]interface{}{"include_counts": true, "rollup_counts": true, "open_only": true})
+	if c := counts["portal"]; c["completed"] != float64(0) || c["total"] != float64(3) {
+		t.Errorf("unexpected open-only portal counts: %v", c)
+	}
+
+	// Rollups still cover containers past the depth limit
+	counts = countsOf(map[string]interface{}{"include_counts": true, "rollup_counts": true, "depth": 1})
+	if c := counts["portal"]; c["total"] != float64(4) {
+		t.Errorf("unexpected depth-limited portal counts: %v", c)
+	}
+}
+
+func TestDaemonContainersTreeHasChildren(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	if _, err := server.db.Exec(`
+		INSERT INTO containers (uuid, id, slug, title, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f1', 'P-00002', 'portal', 'Portal',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+		INSERT INTO containers (uuid, id, slug, title, parent_uuid, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f2', 'P-00003', 'auth', 'Auth', '00000000-0000-0000-0000-0000000000f1',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+		INSERT INTO containers (uuid, id, slug, title, parent_uuid, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f3', 'P-00004', 'sso', 'SSO', '00000000-0000-0000-0000-0000000000f2',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+		INSERT INTO containers (uuid, id, slug, title, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f4', 'P-00005', 'empty', 'Empty',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+		INSERT INTO containers (uuid, id, slug, title, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f5', 'P-00006', 'shelved', 'Shelved',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+		INSERT INTO containers (uuid, id, slug, title, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f6', 'P-00007', 'finished', 'Finished',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+	`); err != nil {
+		t.Fatalf("failed to seed containers: %v", err)
+	}
+	if code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "portal/auth/sso/saml"}); code != http.StatusOK {
+		t.Fatalf("create failed: %d %v", code, resp)
+	}
+	if code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "shelved/idea"}); code != http.StatusOK {
+		t.Fatalf("create failed: %d %v", code, resp)
+	}
+	if code, resp := daemonPost(t, handler, "/v1/tasks/archive", map[string]interface{}{"selector": "shelved/idea"}); code != http.StatusOK {
+		t.Fatalf("archive failed: %d %v", code, resp)
+	}
+	if code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "finished/shipped"}); code != http.StatusOK {
+		t.Fatalf("create failed: %d %v", code, resp)
+	}
+	if _, err := server.db.Exec(`UPDATE tasks SET state = 'completed' WHERE slug = 'shipped'`); err != nil {
+		t.Fatalf("failed to complete task: %v", err)
+	}
+
+	tree := func(req map[string]interface{}) map[string]map[string]interface{} {
+		t.Helper()
+		code, resp := daemonPost(t, handler, "/v1/containers/tree", req)
+		if code != http.StatusOK {
+			t.Fatalf("tree failed: %d %v", code, resp)
+		}
+		nodes := map[string]map[string]interface{}{}
+		var walk func(nodes []interface{})
+		walk = func(children []interface{}) {
+			for _, raw := range children {
+				node := raw.(map[string]interface{})
+				nodes[node["slug"].(string)] = node
+				if grandchildren, ok := node["children"].([]interface{}); ok {
+					walk(grandchildren)
+				}
+			}
+		}
+		walk(resp["children"].([]interface{}))
+		return nodes
+	}
+
+	nodes := tree(map[string]interface{}{"depth": 1})
+	if _, ok := nodes["auth"]; ok {
+		t.Fatalf("depth=1 should stop at the top level, got %v", nodes)
+	}
+	if nodes["portal"]["has_children"] != true {
+		t.Errorf("expected portal to report children, got %v", nodes["portal"])
+	}
+	if nodes["empty"]["has_children"] != false {
+		t.Errorf("expected empty to report no children, got %v", nodes["empty"])
+	}
+	// Only an archived task, which the tree hides by default
+	if nodes["shelved"]["has_children"] != false {
+		t.Errorf("expected shelved to report no visible children, got %v", nodes["shelved"])
+	}
+	// Even with archived items shown, a container whose tasks are all closed
+	// collapses them, so there is nothing to expand
+	if nodes := tree(map[string]interface{}{"depth": 1, "include_archived": true}); nodes["shelved"]["has_children"] != false {
+		t.Errorf("expected shelved's collapsed archived task not to count, got %v", nodes["shelved"])
+	}
+	if nodes := tree(map[string]interface{}{"depth": 2, "include_archived": true}); nodes["idea"] != nil {
+		t.Errorf("expected the expanded tree to hide shelved's archived task, got %v", nodes["idea"])
+	}
+	if nodes["finished"]["has_children"] != false {
+		t.Errorf("expected finished to report no visible children, got %v", nodes["finished"])
+	}
+
+	nodes = tree(map[string]interface{}{"depth": 2})
+	if _, ok := nodes["portal"]["has_children"]; ok {
+		t.Errorf("expanded nodes shouldn't carry has_children, got %v", nodes["portal"])
+	}
+	if nodes["auth"]["has_children"] != true {
+		t.Errorf("expected auth at the boundary to report children, got %v", nodes["auth"])
+	}
+
+	for slug, node := range tree(map[string]interface{}{}) {
+		if _, ok := node["has_children"]; ok {
+			t.Errorf("unlimited depth shouldn't set has_children on %s", slug)
+		}
+	}
+}
+
+func TestDaemonContainersActivity(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	if _, err := server.db.Exec(`
+		INSERT INTO containers (uuid, id, slug, title, parent_uuid, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f1', 'P-00002', 'auth', 'Auth', '00000000-0000-0000-0000-000000000002',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+		INSERT INTO containers (uuid, id, slug, title, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f2', 'P-00003', 'portal', 'Portal',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+	`); err != nil {
+		t.Fatalf("failed to seed containers: %v", err)
+	}
+	for _, path := range []string{"inbox/top", "inbox/auth/login", "portal/elsewhere"} {
+		if code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": path}); code != http.StatusOK {
+			t.Fatalf("create %s failed: %d %v", path, code, resp)
+		}
+	}
+	code, resp := daemonPost(t, handler, "/v1/tasks/update", map[string]interface{}{
+		"selector": "inbox/auth/login", "fields": map[string]interface{}{"state": "in_progress"},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("update failed: %d %v", code, resp)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/containers/activity", map[string]interface{}{"container": "inbox"})
+	if code != http.StatusOK {
+		t.Fatalf("activity failed: %d %v", code, resp)
+	}
+	entries := resp["activity"].([]interface{})
+	var summaries []string
+	var lastID float64
+	for i, raw := range entries {
+		entry := raw.(map[string]interface{})
+		if i > 0 && entry["event_id"].(float64) >= lastID {
+			t.Errorf("expected newest first, got %v", entries)
+		}
+		lastID = entry["event_id"].(float64)
+		if strings.HasPrefix(entry["path"].(string), "portal") {
+			t.Errorf("unexpected event from another project: %v", entry)
+		}
+		summaries = append(summaries, entry["summary"].(string))
+	}
+	if len(summaries) != 3 {
+		t.Fatalf("expected 3 entries, got %v", summaries)
+	}
+	loginID := entries[0].(map[string]interface{})["resource_id"].(string)
+	if want := "test-user changed " + loginID + " state open→in_progress"; summaries[0] != want {
+		t.Errorf("expected %q first, got %q", want, summaries[0])
+	}
+	if entries[1].(map[string]interface{})["path"] != "inbox/auth/login" {
+		t.Errorf("expected the sub-container task's creation second, got %v", entries[1])
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/containers/activity", map[string]interface{}{"container": "inbox", "limit": 2})
+	if code != http.StatusOK || len(resp["activity"].([]interface{})) != 2 || resp["next_cursor"] == "" {
+		t.Fatalf("expected a first page of 2 with a cursor, got %d %v", code, resp)
+	}
+	code, resp = daemonPost(t, handler, "/v1/containers/activity", map[string]interface{}{"container": "inbox", "limit": 2, "cursor": resp["next_cursor"]})
+	if code != http.StatusOK {
+		t.Fatalf("second page failed: %d %v", code, resp)
+	}
+	page := resp["activity"].([]interface{})
+	if len(page) != 1 || page[0].(map[string]interface{})["summary"] != summaries[2] || resp["next_cursor"] != "" {
+		t.Errorf("expected the last entry alone on page 2, got %v", resp)
+	}
+}
+
+func TestDaemonTasksHistory(t *testing.T) {
+	_, handler := newTestDaemon(t)
+
+	for _, path := range []string{"inbox/api", "inbox/ui", "inbox/other"} {
+		if code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": path}); code != http.StatusOK {
+			t.Fatalf("create %s failed: %d %v", path, code, resp)
+		}
+	}
+	code, resp := daemonPost(t, handler, "/v1/tasks/update", map[string]interface{}{
+		"selector": "inbox/ui", "fields": map[string]interface{}{"state": "in_progress", "priority": 1},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("update failed: %d %v", code, resp)
+	}
+	if code, resp := daemonPost(t, handler, "/v1/comments/create", map[string]interface{}{"task": "inbox/ui", "body": "started"}); code != http.StatusOK {
+		t.Fatalf("comment failed: %d %v", code, resp)
+	}
+	if code, resp := daemonPost(t, handler, "/v1/relations/create", map[string]interface{}{"from": "inbox/api", "kind": "blocks", "to": "inbox/ui"}); code != http.StatusOK {
+		t.Fatalf("relation failed: %d %v", code, resp)
+	}
+	if code, resp := daemonPost(t, handler, "/v1/tasks/update", map[string]interface{}{
+		"selector": "inbox/other", "fields": map[string]interface{}{"title": "Unrelated"},
+	}); code != http.StatusOK {
+		t.Fatalf("update other failed: %d %v", code, resp)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/tasks/history", map[string]interface{}{"selector": "inbox/ui"})
+	if code != http.StatusOK {
+		t.Fatalf("history failed: %d %v", code, resp)
+	}
+	history := resp["history"].([]interface{})
+	var types []string
+	for _, raw := range history {
+		types = append(types, raw.(map[string]interface{})["event_type"].(string))
+	}
+	want := []string{"task.created", "task.updated", "comment.created", "task.relation.created"}
+	if strings.Join(types, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected history %v, got %v", want, types)
+	}
+
+	updated := history[1].(map[string]interface{})
+	if updated["actor"] != "test-user" {
+		t.Errorf("expected the actor slug, got %v", updated["actor"])
+	}
+	changes := map[string]map[string]interface{}{}
+	for _, raw := range updated["changes"].([]interface{}) {
+		change := raw.(map[string]interface{})
+		changes[change["field"].(string)] = change
+	}
+	if len(changes) != 2 {
+		t.Fatalf("expected state and priority changes, got %v", updated["changes"])
+	}
+	if changes["state"]["from"] != "open" || changes["state"]["to"] != "in_progress" {
+		t.Errorf("unexpected state change: %v", changes["state"])
+	}
+	if changes["priority"]["from"] != float64(3) || changes["priority"]["to"] != float64(1) {
+		t.Errorf("unexpected priority change: %v", changes["priority"])
+	}
+	if summary := updated["summary"].(string); !strings.Contains(summary, "state open→in_progress") {
+		t.Errorf("unexpected summary: %q", summary)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/tasks/history", map[string]interface{}{"selector": "inbox/missing"})
+	if code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown task, got %d %v", code, resp)
+	}
+}
+
+func TestDaemonTasksGetAsOf(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{
+		"path": "inbox/report", "fields": map[string]interface{}{"title": "Draft report"},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("create failed: %d %v", code, resp)
+	}
+	taskUUID := resp["task"].(map[string]interface{})["uuid"].(string)
+	if code, resp := daemonPost(t, handler, "/v1/tasks/update", map[string]interface{}{
+		"selector": "inbox/report", "fields": map[string]interface{}{"title": "Final report"},
+	}); code != http.StatusOK {
+		t.Fatalf("update failed: %d %v", code, resp)
+	}
+	if _, err := server.db.Exec(`
+		UPDATE tasks SET created_at = '2026-03-01T09:00:00Z' WHERE uuid = ?;
+		UPDATE event_log SET timestamp = CASE event_type WHEN 'task.created' THEN '2026-03-01T09:00:00Z' ELSE '2026-03-02T09:00:00Z' END
+		WHERE resource_uuid = ?;
+	`, taskUUID, taskUUID); err != nil {
+		t.Fatalf("failed to backdate events: %v", err)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/tasks/get?as_of=2026-03-01T12:00:00Z", map[string]interface{}{"selector": "inbox/report"})
+	if code != http.StatusOK {
+		t.Fatalf("get as_of failed: %d %v", code, resp)
+	}
+	fields := resp["task"].(map[string]interface{})["fields"].(map[string]interface{})
+	if fields["title"] != "Draft report" {
+		t.Errorf("expected the title before the rename, got %v", fields["title"])
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/tasks/get", map[string]interface{}{"selector": "inbox/report", "as_of": "yesterday"})
+	if code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a bad as_of, got %d %v", code, resp)
+	}
+}
+
+func TestDaemonTasksRestoreRefreshesSubtaskProgress(t *testing.T) {
+	server, handler := newTestDaemon(t)
+	actorUUID := "00000000-0000-0000-0000-000000000001"
+	inboxUUID := "00000000-0000-0000-0000-000000000002"
+	svc := store.New(server.db)
+
+	if _, err := svc.Containers.UpdateFields(actorUUID, inboxUUID, map[string]interface{}{"subtask_rollup": true}, 0); err != nil {
+		t.Fatalf("failed to enable subtask_rollup: %v", err)
+	}
+	parent, err := svc.Tasks.Create(actorUUID, store.CreateParams{Slug: "parent", Title: "Parent", ProjectUUID: inboxUUID, State: "open", Priority: 3})
+	if err != nil {
+		t.Fatalf("failed to create parent: %v", err)
+	}
+	child, err := svc.Tasks.Create(actorUUID, store.CreateParams{
+		Slug: "child", Title: "Child", ProjectUUID: inboxUUID, State: "open", Priority: 3,
+		Kind: "subtask", ParentTaskUUID: &parent.UUID,
+	})
+	if err != nil {
+		t.Fatalf("failed to create subtask: %v", err)
+	}
+	if _, err := svc.Tasks.Archive(actorUUID, child.UUID, 0); err != nil {
+		t.Fatalf("failed to archive subtask: %v", err)
+	}
+
+	code, resp := daemonPost(t, handler, "/v1/tasks/restore", map[string]interface{}{"selector": "inbox/child"})
+	if code != http.StatusOK {
+		t.Fatalf("restore failed: %d %v", code, resp)
+	}
+	var progress string
+	if err := server.db.QueryRow("SELECT subtask_progress FROM tasks WHERE uuid = ?", parent.UUID).Scan(&progress); err != nil {
+		t.Fatalf("failed to read parent: %v", err)
+	}
+	if progress != `{"done":0,"total":1}` {
+		t.Errorf("expected 0/1 after restoring the subtask, got %s", progress)
+	}
+}
+
+func TestDaemonContainersArchiveRestore(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	if _, err := server.db.Exec(`
+		INSERT INTO containers (uuid, id, slug, title, parent_uuid, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f1', 'P-00002', 'auth', 'Auth', '00000000-0000-0000-0000-000000000002',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+	`); err != nil {
+		t.Fatalf("failed to seed container: %v", err)
+	}
+	for _, path := range []string{"inbox/auth/login", "inbox/auth/logout", "inbox/auth/old"} {
+		if code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": path}); code != http.StatusOK {
+			t.Fatalf("create %s failed: %d %v", path, code, resp)
+		}
+	}
+	if code, resp := daemonPost(t, handler, "/v1/tasks/archive", map[string]interface{}{"selector": "inbox/auth/old"}); code != http.StatusOK {
+		t.Fatalf("archive old failed: %d %v", code, resp)
+	}
+	// Different second, so the separately archived task isn't part of the undo
+	if _, err := server.db.Exec(`UPDATE tasks SET archived_at = '2025-01-01T00:00:00Z' WHERE slug = 'old'`); err != nil {
+		t.Fatalf("failed to backdate archive: %v", err)
+	}
+	if _, err := server.db.Exec(`UPDATE tasks SET state = 'completed', completed_at = '2025-01-02T00:00:00Z' WHERE slug = 'logout'`); err != nil {
+		t.Fatalf("failed to complete task: %v", err)
+	}
+
+	code, resp := daemonPost(t, handler, "/v1/containers/archive", map[string]interface{}{"container": "inbox/auth"})
+	if code != http.StatusConflict || !strings.Contains(resp["message"].(string), "not empty: has 2 active task(s)") {
+		t.Fatalf("expected non-empty refusal, got %d %v", code, resp)
+	}
+	code, resp = daemonPost(t, handler, "/v1/containers/archive", map[string]interface{}{"container": "inbox/auth", "cascade": true, "ifMatch": 99})
+	if code != http.StatusConflict || resp["code"] != "etag_conflict" {
+		t.Fatalf("expected etag conflict, got %d %v", code, resp)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/containers/archive", map[string]interface{}{"container": "inbox/auth", "cascade": true, "ifMatch": 1})
+	if code != http.StatusOK || resp["tasks"] != float64(2) || resp["containers"] != float64(1) {
+		t.Fatalf("cascade archive failed: %d %v", code, resp)
+	}
+	container := resp["container"].(map[string]interface{})
+	archivedAt, _ := container["archived_at"].(string)
+	if archivedAt == "" || container["etag"] != float64(2) {
+		t.Fatalf("expected archived container at etag 2, got %v", container)
+	}
+
+	taskStates := func() map[string]string {
+		t.Helper()
+		rows, err := server.db.Query(`SELECT slug, state, COALESCE(archived_at, '') FROM tasks WHERE slug IN ('login', 'logout', 'old')`)
+		if err != nil {
+			t.Fatalf("failed to query tasks: %v", err)
+		}
+		defer rows.Close()
+		states := map[string]string{}
+		for rows.Next() {
+			var slug, state, at string
+			if err := rows.Scan(&slug, &state, &at); err != nil {
+				t.Fatalf("failed to scan task: %v", err)
+			}
+			states[slug] = state
+			if state == "archived" && at == "" {
+				t.Errorf("task %s archived without archived_at", slug)
+			}
+		}
+		return states
+	}
+	if states := taskStates(); states["login"] != "archived" || states["logout"] != "archived" {
+		t.Fatalf("expected cascaded tasks archived, got %v", states)
+	}
+	var archiveEvents int
+	if err := server.db.QueryRow(`SELECT COUNT(*) FROM event_log WHERE event_type IN ('task.archived', 'container.archived')`).Scan(&archiveEvents); err != nil {
+		t.Fatalf("failed to count events: %v", err)
+	}
+	if archiveEvents != 4 {
+		t.Errorf("expected 4 archive events (old, login, logout, auth), got %d", archiveEvents)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/containers/archive", map[string]interface{}{"container": "inbox/auth", "cascade": true})
+	if code != http.StatusConflict {
+		t.Fatalf("expected archiving twice to fail, got %d %v", code, resp)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/containers/restore", map[string]interface{}{"container": "inbox/auth", "ifMatch": 2})
+	if code != http.StatusOK || resp["tasks"] != float64(2) {
+		t.Fatalf("restore failed: %d %v", code, resp)
+	}
+	if _, ok := resp["container"].(map[string]interface{})["archived_at"]; ok {
+		t.Errorf("expected archived_at cleared, got %v", resp["container"])
+	}
+	if states := taskStates(); states["login"] != "open" || states["logout"] != "completed" || states["old"] != "archived" {
+		t.Errorf("expected only the cascaded tasks restored to their prior states, got %v", states)
+	}
+
+	// A container gone by the time the store looks is a 404, not a conflict
+	_, err := store.New(server.db).Containers.ArchiveTree("00000000-0000-0000-0000-000000000001", "00000000-0000-0000-0000-0000000000ff", 0, true)
+	if status, _ := classifyError(http.StatusConflict, err); status != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing container, got %d (%v)", status, err)
+	}
+}
+
+func TestDaemonContainersMove(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	if _, err := server.db.Exec(`
+		INSERT INTO containers (uuid, id, slug, title, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f1', 'P-00002', 'portal', 'Portal',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+		INSERT INTO containers (uuid, id, slug, title, parent_uuid, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f2', 'P-00003', 'auth', 'Auth', '00000000-0000-0000-0000-0000000000f1',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+		INSERT INTO containers (uuid, id, slug, title, parent_uuid, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f3', 'P-00004', 'sso', 'SSO', '00000000-0000-0000-0000-0000000000f2',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+		INSERT INTO containers (uuid, id, slug, title, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f4', 'P-00005', 'platform', 'Platform',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+		INSERT INTO containers (uuid, id, slug, title, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f5', 'P-00006', 'sso', 'Old SSO',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+	`); err != nil {
+		t.Fatalf("failed to seed containers: %v", err)
+	}
+	if code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "portal/auth/sso/saml"}); code != http.StatusOK {
+		t.Fatalf("create failed: %d %v", code, resp)
+	}
+
+	code, resp := daemonPost(t, handler, "/v1/containers/move", map[string]interface{}{"selector": "portal/auth", "target_parent": "platform", "ifMatch": 7})
+	if code != http.StatusConflict || resp["code"] != "etag_conflict" {
+		t.Fatalf("expected etag conflict, got %d %v", code, resp)
+	}
+	code, resp = daemonPost(t, handler, "/v1/containers/move", map[string]interface{}{"selector": "portal/auth", "target_parent": "platform", "ifMatch": 1})
+	if code != http.StatusOK || resp["path"] != "platform/auth" {
+		t.Fatalf("move failed: %d %v", code, resp)
+	}
+
+	for uuid, want := range map[string]string{
+		"00000000-0000-0000-0000-0000000000f2": "platform/auth",
+		"00000000-0000-0000-0000-0000000000f3": "platform/auth/sso",
+	} {
+		var path string
+		if err := server.db.QueryRow("SELECT path FROM v_container_paths WHERE uuid = ?", uuid).Scan(&path); err != nil {
+			t.Fatalf("failed to read path: %v", err)
+		}
+		if path != want {
+			t.Errorf("expected %s, got %s", want, path)
+		}
+	}
+	if code, resp := daemonPost(t, handler, "/v1/tasks/get", map[string]interface{}{"selector": "platform/auth/sso/saml"}); code != http.StatusOK {
+		t.Errorf("expected task to follow its container: %d %v", code, resp)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/containers/move", map[string]interface{}{"selector": "platform", "target_parent": "platform/auth/sso"})
+	if code != http.StatusBadRequest || !strings.Contains(resp["message"].(string), "descendants") {
+		t.Fatalf("expected cycle refusal, got %d %v", code, resp)
+	}
+	code, resp = daemonPost(t, handler, "/v1/containers/move", map[string]interface{}{"selector": "platform/auth/sso", "target_parent": nil})
+	if code != http.StatusBadRequest || !strings.Contains(resp["message"].(string), `"sso" already exists`) {
+		t.Fatalf("expected slug conflict at the root, got %d %v", code, resp)
+	}
+	code, resp = daemonPost(t, handler, "/v1/containers/move", map[string]interface{}{"selector": "platform/auth", "target_parent": nil})
+	if code != http.StatusOK || resp["path"] != "auth" {
+		t.Fatalf("move to root failed: %d %v", code, resp)
+	}
+}
+
+func TestDaemonContainersRename(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	if _, err := server.db.Exec(`
+		INSERT INTO containers (uuid, id, slug, title, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f1', 'P-00002', 'portal', 'Portal',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+		INSERT INTO containers (uuid, id, slug, title, parent_uuid, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f2', 'P-00003', 'auth', 'Auth', '00000000-0000-0000-0000-0000000000f1',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+		INSERT INTO containers (uuid, id, slug, title, parent_uuid, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f3', 'P-00004', 'sso', 'SSO', '00000000-0000-0000-0000-0000000000f2',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+		INSERT INTO containers (uuid, id, slug, title, parent_uuid, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f4', 'P-00005', 'billing', 'Billing', '00000000-0000-0000-0000-0000000000f1',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+	`); err != nil {
+		t.Fatalf("failed to seed containers: %v", err)
+	}
+	code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "portal/auth/sso/saml-login"})
+	if code != http.StatusOK {
+		t.Fatalf("create failed: %d %v", code, resp)
+	}
+	taskID := resp["task"].(map[string]interface{})["id"]
+
+	code, resp = daemonPost(t, handler, "/v1/containers/rename", map[string]interface{}{"selector": "portal/auth", "slug": "billing"})
+	if code != http.StatusBadRequest || !strings.Contains(resp["message"].(string), `"billing" already exists`) {
+		t.Fatalf("expected sibling slug conflict, got %d %v", code, resp)
+	}
+	code, resp = daemonPost(t, handler, "/v1/containers/rename", map[string]interface{}{"selector": "portal/auth", "slug": "Identity", "ifMatch": 1})
+	if code != http.StatusOK || resp["path"] != "portal/identity" {
+		t.Fatalf("rename failed: %d %v", code, resp)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/tasks/get", map[string]interface{}{"selector": "portal/identity/sso/saml-login"})
+	if code != http.StatusOK || resp["task"].(map[string]interface{})["id"] != taskID {
+		t.Fatalf("expected the deep task at its new path, got %d %v", code, resp)
+	}
+	if code, _ := daemonPost(t, handler, "/v1/tasks/get", map[string]interface{}{"selector": "portal/auth/sso/saml-login"}); code != http.StatusNotFound {
+		t.Errorf("expected the old path to stop resolving, got %d", code)
+	}
+
+	for _, filter := range []map[string]interface{}{
+		{"path_prefix": []string{"portal/identity"}},
+		{"path_prefix": []string{"portal/ident*"}},
+		{"path_prefix": []string{"portal/identity"}, "slug_glob": "saml-*"},
+	} {
+		code, resp := daemonPost(t, handler, "/v1/tasks/list", filter)
+		if code != http.StatusOK || len(resp["tasks"].([]interface{})) != 1 {
+			t.Errorf("filter %v: expected the renamed task, got %d %v", filter, code, resp)
+		}
+	}
+	code, resp = daemonPost(t, handler, "/v1/tasks/list", map[string]interface{}{"path_prefix": []string{"portal/auth"}})
+	if code != http.StatusOK || len(resp["tasks"].([]interface{})) != 0 {
+		t.Errorf("expected nothing under the old path, got %d %v", code, resp)
+	}
+
+	var payload string
+	if err := server.db.QueryRow(`SELECT payload FROM event_log WHERE event_type = 'container.updated' ORDER BY id DESC LIMIT 1`).Scan(&payload); err != nil {
+		t.Fatalf("expected a container.updated event: %v", err)
+	}
+	if !strings.Contains(payload, `"old_slug":"auth"`) || !strings.Contains(payload, `"slug":"identity"`) {
+		t.Errorf("unexpected event payload: %s", payload)
+	}
+}
+
+func TestDaemonRelationsCreateIdempotentAndDelete(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	for _, path := range []string{"inbox/api", "inbox/ui"} {
+		if code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": path}); code != http.StatusOK {
+			t.Fatalf("create %s failed: %d %v", path, code, resp)
+		}
+	}
+	relation := map[string]interface{}{"from": "inbox/api", "kind": "blocks", "to": "inbox/ui"}
+
+	code, resp := daemonPost(t, handler, "/v1/relations/create", relation)
+	if code != http.StatusOK || resp["created"] != true {
+		t.Fatalf("create failed: %d %v", code, resp)
+	}
+	code, resp = daemonPost(t, handler, "/v1/relations/create", relation)
+	if code != http.StatusOK || resp["created"] != false {
+		t.Fatalf("expected duplicate create to be a no-op, got %d %v", code, resp)
+	}
+
+	countEvents := func(eventType string) int {
+		t.Helper()
+		var n int
+		if err := server.db.QueryRow(`SELECT COUNT(*) FROM event_log WHERE event_type = ?`, eventType).Scan(&n); err != nil {
+			t.Fatalf("failed to count events: %v", err)
+		}
+		return n
+	}
+	var relations int
+	if err := server.db.QueryRow(`SELECT COUNT(*) FROM task_relations`).Scan(&relations); err != nil {
+		t.Fatalf("failed to count relations: %v", err)
+	}
+	if relations != 1 || countEvents("task.relation.created") != 1 {
+		t.Errorf("expected one relation and one created event, got %d and %d", relations, countEvents("task.relation.created"))
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/relations/delete", relation)
+	if code != http.StatusOK {
+		t.Fatalf("delete failed: %d %v", code, resp)
+	}
+	if countEvents("task.relation.deleted") != 1 {
+		t.Errorf("expected a deleted event")
+	}
+	code, resp = daemonPost(t, handler, "/v1/relations/delete", relation)
+	if code != http.StatusNotFound || resp["code"] != "not_found" {
+		t.Fatalf("expected 404 for a missing relation, got %d %v", code, resp)
+	}
+	if countEvents("task.relation.deleted") != 1 {
+		t.Errorf("a missing relation shouldn't log an event")
+	}
+}
+
+func TestDaemonRelationsLogEventsAndNotifyBlockedTask(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	for _, path := range []string{"inbox/api", "inbox/ui"} {
+		if code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": path}); code != http.StatusOK {
+			t.Fatalf("create %s failed: %d %v", path, code, resp)
+		}
+	}
+
+	hooks := make(chan map[string]interface{}, 4)
+	hookServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		hooks <- payload
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer hookServer.Close()
+	if _, err := server.db.Exec(`UPDATE containers SET webhook_urls = ? WHERE slug = 'inbox'`, `["`+hookServer.URL+`"]`); err != nil {
+		t.Fatalf("failed to set webhook urls: %v", err)
+	}
+	var apiID, apiUUID string
+	if err := server.db.QueryRow(`SELECT id, uuid FROM tasks WHERE slug = 'api'`).Scan(&apiID, &apiUUID); err != nil {
+		t.Fatalf("failed to look up task: %v", err)
+	}
+
+	relation := map[string]interface{}{"from": "inbox/api", "kind": "blocks", "to": "inbox/ui"}
+	if code, resp := daemonPost(t, handler, "/v1/relations/create", relation); code != http.StatusOK {
+		t.Fatalf("create failed: %d %v", code, resp)
+	}
+
+	var resourceUUID, payload string
+	if err := server.db.QueryRow(`
+		SELECT resource_uuid, payload FROM event_log WHERE event_type = 'task.relation.created'
+	`).Scan(&resourceUUID, &payload); err != nil {
+		t.Fatalf("expected a task.relation.created event: %v", err)
+	}
+	if resourceUUID != apiUUID || !strings.Contains(payload, `"kind":"blocks"`) {
+		t.Errorf("unexpected event: %s %s", resourceUUID, payload)
+	}
+
+	select {
+	case got := <-hooks:
+		blockers, _ := got["blocked_by"].([]interface{})
+		if got["ticket_id"] == apiID || len(blockers) != 1 || blockers[0].(map[string]interface{})["id"] != apiID {
+			t.Errorf("expected a webhook for the blocked task listing %s, got %v", apiID, got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a webhook for the newly blocked task")
+	}
+
+	// Relations other than blocks don't change any payload
+	if code, resp := daemonPost(t, handler, "/v1/relations/create", map[string]interface{}{"from": "inbox/api", "kind": "relates_to", "to": "inbox/ui"}); code != http.StatusOK {
+		t.Fatalf("create relates_to failed: %d %v", code, resp)
+	}
+
+	if code, resp := daemonPost(t, handler, "/v1/relations/delete", relation); code != http.StatusOK {
+		t.Fatalf("delete failed: %d %v", code, resp)
+	}
+	select {
+	case got := <-hooks:
+		if _, blocked := got["blocked_by"]; blocked {
+			t.Errorf("expected the unblocked task's webhook without blockers, got %v", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a webhook for the unblocked task")
+	}
+	if len(hooks) != 0 {
+		t.Errorf("unexpected extra webhooks: %d", len(hooks))
+	}
+}
+
+func TestDaemonEventsWatch(t *testing.T) {
+	server, handler := newTestDaemon(t)
+	server.watchPollInterval = 10 * time.Millisecond
+
+	daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "inbox/watch-one"})
+	_, resp := daemonPost(t, handler, "/v1/events/list", map[string]interface{}{"resource_type": "task"})
+	events := resp["events"].([]interface{})
+	firstID := events[len(events)-1].(map[string]interface{})["id"].(float64)
+	since, err := eventCursor(logEvent{ID: int64(firstID), Timestamp: time.Now()})
+	if err != nil {
+		t.Fatalf("failed to build cursor: %v", err)
+	}
+
+	// Appended before the watch starts: must arrive during catch-up.
+	daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "inbox/watch-two"})
+
+	go func() {
+		// Appended while the watch is tailing.
+		time.Sleep(50 * time.Millisecond)
+		daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "inbox/watch-three"})
+	}()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/events/watch?resource_type=task&timeout=300ms&cursor="+url.QueryEscape(since), nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var ids []float64
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("invalid stream line %q: %v", scanner.Text(), err)
+		}
+		if line["cursor"] == "" {
+			t.Fatalf("expected a resume cursor on each line: %v", line)
+		}
+		ids = append(ids, line["event"].(map[string]interface{})["id"].(float64))
+	}
+
+	if len(ids) != 2 {
+		t.Fatalf("expected 2 streamed events, got %v", ids)
+	}
+	if ids[0] <= firstID || ids[1] <= ids[0] {
+		t.Fatalf("expected ascending events after the cursor, got %v", ids)
+	}
+}
+
+func TestDaemonEventsWatchOutOfOrderTimestamps(t *testing.T) {
+	server, handler := newTestDaemon(t)
+	server.watchPollInterval = 10 * time.Millisecond
+
+	var lastID int64
+	if err := server.db.QueryRow("SELECT COALESCE(MAX(id), 0) FROM event_log").Scan(&lastID); err != nil {
+		t.Fatalf("failed to read last event id: %v", err)
+	}
+	since, err := eventCursor(logEvent{ID: lastID, Timestamp: time.Now()})
+	if err != nil {
+		t.Fatalf("failed to build cursor: %v", err)
+	}
+	// The later event carries the earlier timestamp
+	if _, err := server.db.Exec(`
+		INSERT INTO event_log (timestamp, resource_type, event_type) VALUES ('2030-01-01T00:00:00Z', 'system', 'test.first');
+		INSERT INTO event_log (timestamp, resource_type, event_type) VALUES ('2020-01-01T00:00:00Z', 'system', 'test.second');
+	`); err != nil {
+		t.Fatalf("failed to seed events: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/events/watch?timeout=100ms&cursor="+url.QueryEscape(since), nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var types []string
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("invalid stream line %q: %v", scanner.Text(), err)
+		}
+		types = append(types, line["event"].(map[string]interface{})["event_type"].(string))
+	}
+	if !reflect.DeepEqual(types, []string{"test.first", "test.second"}) {
+		t.Fatalf("expected each event once in id order, got %v", types)
+	}
+}
+
+func TestDaemonEventsWatchOutlivesWriteTimeout(t *testing.T) {
+	server, handler := newTestDaemon(t)
+	server.watchPollInterval = 10 * time.Millisecond
+
+	ts := httptest.NewUnstartedServer(handler)
+	ts.Config.WriteTimeout = 100 * time.Millisecond
+	ts.Start()
+	defer ts.Close()
+
+	go func() {
+		time.Sleep(250 * time.Millisecond)
+		server.db.Exec("INSERT INTO event_log (resource_type, event_type) VALUES ('system', 'test.late')")
+	}()
+
+	resp, err := http.Get(ts.URL + "/v1/events/watch?timeout=500ms")
+	if err != nil {
+		t.Fatalf("watch failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("stream was cut off: %v", err)
+	}
+	if !strings.Contains(string(body), "test.late") {
+		t.Fatalf("expected the event appended after the write timeout, got %q", body)
+	}
+}
+
+func TestDaemonErrorCodes(t *testing.T) {
+	_, handler := newTestDaemon(t)
+
+	if code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "inbox/coded"}); code != http.StatusOK {
+		t.Fatalf("create failed: %d %v", code, resp)
+	}
+
+	code, resp := daemonPost(t, handler, "/v1/tasks/update", map[string]interface{}{
+		"selector": "inbox/coded", "fields": map[string]interface{}{"title": "Stale"}, "ifMatch": 99,
+	})
+	if code != http.StatusConflict || resp["code"] != "etag_conflict" {
+		t.Fatalf("expected 409 etag_conflict, got %d %v", code, resp)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/tasks/get", map[string]interface{}{"selector": "inbox/missing"})
+	if code != http.StatusNotFound || resp["code"] != "not_found" {
+		t.Fatalf("expected 404 not_found, got %d %v", code, resp)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/tasks/update", map[string]interface{}{
+		"selector": "inbox/coded", "fields": map[string]interface{}{"state": "bogus"},
+	})
+	if code != http.StatusBadRequest || resp["code"] != "validation" {
+		t.Fatalf("expected 400 validation, got %d %v", code, resp)
+	}
+}
+
+func TestDaemonAmbiguousSelectorListsCandidates(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	if _, err := server.db.Exec(`
+		INSERT INTO containers (uuid, id, slug, title, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f1', 'P-00002', 'portal', 'Portal',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001')
+	`); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	for _, path := range []string{"inbox/login", "portal/login"} {
+		if code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": path}); code != http.StatusOK {
+			t.Fatalf("create %s failed: %d %v", path, code, resp)
+		}
+	}
+
+	code, resp := daemonPost(t, handler, "/v1/tasks/get", map[string]interface{}{"selector": "login"})
+	if code != http.StatusNotFound || resp["code"] != "not_found" {
+		t.Fatalf("expected 404 not_found, got %d %v", code, resp)
+	}
+	candidates, _ := resp["candidates"].([]interface{})
+	if len(candidates) != 2 {
+		t.Fatalf("expected 2 candidates, got %v", resp)
+	}
+	if path := candidates[0].(map[string]interface{})["path"]; path != "inbox/login" {
+		t.Errorf("expected inbox/login first, got %v", path)
+	}
+
+	// Plain misses carry no candidates
+	_, resp = daemonPost(t, handler, "/v1/tasks/get", map[string]interface{}{"selector": "inbox/missing"})
+	if _, ok := resp["candidates"]; ok {
+		t.Errorf("expected no candidates for a plain miss, got %v", resp)
+	}
+}
+
+func TestDaemonRelativeSelectors(t *testing.T) {
+	_, handler := newTestDaemon(t)
+
+	post := func(path, cwd string, body map[string]interface{}) (int, map[string]interface{}) {
+		t.Helper()
+		return daemonPostWithHeaders(t, handler, path, map[string]string{"X-Wrkq-Cwd": cwd}, body)
+	}
+
+	if code, resp := post("/v1/tasks/create", "inbox", map[string]interface{}{"path": "./relative"}); code != http.StatusOK {
+		t.Fatalf("relative create failed: %d %v", code, resp)
+	}
+
+	code, resp := daemonPost(t, handler, "/v1/tasks/get", map[string]interface{}{"selector": "inbox/relative"})
+	if code != http.StatusOK {
+		t.Fatalf("expected task at inbox/relative, got %d %v", code, resp)
+	}
+
+	if code, resp := post("/v1/tasks/get", "inbox/sub", map[string]interface{}{"selector": "../relative"}); code != http.StatusOK {
+		t.Fatalf("expected ../relative to resolve, got %d %v", code, resp)
+	}
+
+	code, resp = post("/v1/tasks/get", "inbox", map[string]interface{}{"selector": "../../relative"})
+	if code == http.StatusOK || !strings.Contains(fmt.Sprint(resp["message"]), "escapes above the root") {
+		t.Fatalf("expected escape error, got %d %v", code, resp)
+	}
+}
+
+func TestDaemonMaxBodyBytes(t *testing.T) {
+	server, handler := newTestDaemon(t)
+	server.maxBodyBytes = 64
+
+	code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{
+		"path":   "inbox/too-big",
+		"fields": map[string]interface{}{"description": strings.Repeat("x", 256)},
+	})
+	if code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d: %v", code, resp)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "inbox/small"})
+	if code != http.StatusOK {
+		t.Fatalf("expected small request to succeed, got %d: %v", code, resp)
+	}
+}
+
+func TestDaemonIdempotencyKey(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	send := func(key string, body map[string]interface{}) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, "/v1/tasks/create", bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Wrkq-Actor", "test-user")
+		req.Header.Set("Idempotency-Key", key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	body := map[string]interface{}{"path": "inbox/retried"}
+	first := send("create-1", body)
+	if first.Code != http.StatusOK {
+		t.Fatalf("create failed: %d %s", first.Code, first.Body.String())
+	}
+	second := send("create-1", body)
+	if second.Code != http.StatusOK {
+		t.Fatalf("retry failed: %d %s", second.Code, second.Body.String())
+	}
+	if second.Header().Get("Idempotency-Replayed") != "true" || second.Body.String() != first.Body.String() {
+		t.Fatalf("expected the original response to be replayed, got %s", second.Body.String())
+	}
+
+	var count int
+	if err := server.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE slug = 'retried'").Scan(&count); err != nil {
+		t.Fatalf("failed to count tasks: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected a single task, got %d", count)
+	}
+
+	if rec := send("create-1", map[string]interface{}{"path": "inbox/other"}); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for key reuse with a different body, got %d", rec.Code)
+	}
+
+	// Expired keys execute again
+	if _, err := server.db.Exec("UPDATE idempotency_keys SET created_at = '2000-01-01T00:00:00Z'"); err != nil {
+		t.Fatalf("failed to age keys: %v", err)
+	}
+	if rec := send("create-1", body); rec.Code == http.StatusOK || rec.Header().Get("Idempotency-Replayed") != "" {
+		t.Fatalf("expected expired key to re-execute (and hit the slug conflict), got %d", rec.Code)
+	}
+}
+
+func TestDaemonIdempotencyKeyConcurrent(t *testing.T) {
+	server, _ := newTestDaemon(t)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var executions int32
+	handler := server.withIdempotency(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&executions, 1) == 1 {
+			close(started)
+			<-release
+		}
+		server.handleTasksCreate(w, r)
+	})
+	send := func() *httptest.ResponseRecorder {
+		data, _ := json.Marshal(map[string]interface{}{"path": "inbox/once"})
+		req := httptest.NewRequest(http.MethodPost, "/v1/tasks/create", bytes.NewReader(data))
+		req.Header.Set("X-Wrkq-Actor", "test-user")
+		req.Header.Set("Idempotency-Key", "create-once")
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	firstDone := make(chan *httptest.ResponseRecorder)
+	go func() { firstDone <- send() }()
+	<-started
+
+	// A duplicate that arrives while the first is running doesn't execute
+	if rec := send(); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a duplicate in flight, got %d %s", rec.Code, rec.Body.String())
+	}
+	close(release)
+	if first := <-firstDone; first.Code != http.StatusOK {
+		t.Fatalf("first request failed: %d %s", first.Code, first.Body.String())
+	}
+	if rec := send(); rec.Code != http.StatusOK || rec.Header().Get("Idempotency-Replayed") != "true" {
+		t.Fatalf("expected the stored response replayed, got %d %s", rec.Code, rec.Body.String())
+	}
+	if n := atomic.LoadInt32(&executions); n != 1 {
+		t.Errorf("expected the write to execute once, got %d", n)
+	}
+
+	// A failed request releases its key for a retry
+	failing := server.withIdempotency(func(w http.ResponseWriter, r *http.Request) {
+		server.writeError(w, http.StatusBadRequest, fmt.Errorf("nope"))
+	})
+	data, _ := json.Marshal(map[string]interface{}{"path": "inbox/failing"})
+	req := httptest.NewRequest(http.MethodPost, "/v1/tasks/create", bytes.NewReader(data))
+	req.Header.Set("X-Wrkq-Actor", "test-user")
+	req.Header.Set("Idempotency-Key", "create-failing")
+	failing(httptest.NewRecorder(), req)
+	var count int
+	if err := server.db.QueryRow("SELECT COUNT(*) FROM idempotency_keys WHERE key = 'create-failing'").Scan(&count); err != nil || count != 0 {
+		t.Errorf("expected the failed request's key released, got %d (%v)", count, err)
+	}
+}
+
+func TestWriteLimitSerializesConcurrentWrites(t *testing.T) {
+	server, _ := newTestDaemon(t)
+	server.writeLimiter = newWriteLimiter(1, 10*time.Second, 0, 0)
+
+	var inflight, maxInflight int32
+	handler := server.withWriteLimit(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inflight, 1)
+		defer atomic.AddInt32(&inflight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInflight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInflight, max, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		server.handleTasksCreate(w, r)
+	})
+
+	const writers = 8
+	var wg sync.WaitGroup
+	codes := make([]int, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data, _ := json.Marshal(map[string]interface{}{"path": fmt.Sprintf("inbox/concurrent-%d", i)})
+			req := httptest.NewRequest(http.MethodPost, "/v1/tasks/create", bytes.NewReader(data))
+			req.Header.Set("X-Wrkq-Actor", "test-user")
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+			codes[i] = rec.Code
+		}(i)
+	}
+	wg.Wait()
+
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Fatalf("write %d failed with %d", i, code)
+		}
+	}
+	if maxInflight != 1 {
+		t.Fatalf("expected writes to be serialized, saw %d at once", maxInflight)
+	}
+
+	var count int
+	if err := server.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE slug LIKE 'concurrent-%'").Scan(&count); err != nil {
+		t.Fatalf("failed to count tasks: %v", err)
+	}
+	if count != writers {
+		t.Fatalf("expected %d tasks, got %d", writers, count)
+	}
+}
+
+func TestWriteLimitReturns429WhenSaturated(t *testing.T) {
+	server, _ := newTestDaemon(t)
+	server.writeLimiter = newWriteLimiter(1, 10*time.Millisecond, 0, 0)
+
+	release, ok := server.writeLimiter.acquire()
+	if !ok {
+		t.Fatal("expected to acquire the only slot")
+	}
+	defer release()
+
+	handler := server.withWriteLimit(server.handleTasksCreate)
+	req := httptest.NewRequest(http.MethodPost, "/v1/tasks/create", bytes.NewReader([]byte(`{"path":"inbox/blocked"}`)))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatal("expected Retry-After header")
+	}
+}
+
+func TestWriteLimiterRatePerToken(t *testing.T) {
+	limiter := newWriteLimiter(0, 0, 1, 2)
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := limiter.allow("a", now); !ok {
+			t.Fatalf("request %d within burst was rejected", i)
+		}
+	}
+	ok, wait := limiter.allow("a", now)
+	if ok || wait <= 0 {
+		t.Fatalf("expected third request to be limited, got ok=%v wait=%v", ok, wait)
+	}
+	if ok, _ := limiter.allow("b", now); !ok {
+		t.Fatal("expected other tokens to have their own bucket")
+	}
+	if ok, _ := limiter.allow("a", now.Add(time.Second)); !ok {
+		t.Fatal("expected bucket to refill after a second")
+	}
+}
+
+func TestWriteLimiterEvictsIdleBuckets(t *testing.T) {
+	limiter := newWriteLimiter(0, 0, 1, 2)
+	now := time.Now()
+
+	for i := 0; i < 100; i++ {
+		limiter.allow(fmt.Sprintf("token-%d", i), now)
+	}
+	limiter.allow("recent", now.Add(1500*time.Millisecond))
+	if len(limiter.buckets) != 101 {
+		t.Fatalf("expected 101 buckets before the refill interval, got %d", len(limiter.buckets))
+	}
+
+	// Two seconds refills a burst of 2 at 1/s, so the idle buckets are full
+	limiter.allow("fresh", now.Add(3*time.Second))
+	if len(limiter.buckets) != 2 {
+		t.Fatalf("expected only the recent and fresh buckets to remain, got %d", len(limiter.buckets))
+	}
+	if _, ok := limiter.buckets["recent"]; !ok {
+		t.Fatal("expected a bucket used within the refill interval to be kept")
+	}
+}
+
+func TestServeWithShutdownDrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	serveDone := make(chan error, 1)
+	go func() {
+		serveDone <- serveWithShutdown(ctx, srv, ln, 5*time.Second, func() { close(stopped) })
+	}()
+
+	respDone := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/")
+		if err != nil {
+			respDone <- 0
+			return
+		}
+		resp.Body.Close()
+		respDone <- resp.StatusCode
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case <-stopped:
+		t.Fatalf("onStop ran before the in-flight request returned")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if code := <-respDone; code != http.StatusOK {
+		t.Fatalf("expected in-flight request to complete with 200, got %d", code)
+	}
+	if err := <-serveDone; err != nil {
+		t.Fatalf("expected clean shutdown to return nil, got %v", err)
+	}
+	<-stopped
+}
+
+func TestDaemonTasksCount(t *testing.T) {
+	_, handler := newTestDaemon(t)
+
+	for _, fields := range []map[string]interface{}{
+		{"state": "open", "assignee": "test-user"},
+		{"state": "open"},
+		{"state": "in_progress", "assignee": "test-user"},
+		{"state": "archived"},
+	} {
+		path := fmt.Sprintf("inbox/count-%v-%v", fields["state"], fields["assignee"])
+		if code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": path, "fields": fields}); code != http.StatusOK {
+			t.Fatalf("task create failed: %d %v", code, resp)
+		}
+	}
+
+	code, resp := daemonPost(t, handler, "/v1/tasks/count", map[string]interface{}{"project": "inbox"})
+	if code != http.StatusOK {
+		t.Fatalf("count failed: %d %v", code, resp)
+	}
+	if resp["total"].(float64) != 3 {
+		t.Fatalf("expected 3 active tasks, got %v", resp["total"])
+	}
+	byState := resp["by_state"].(map[string]interface{})
+	if byState["open"].(float64) != 2 || byState["in_progress"].(float64) != 1 {
+		t.Fatalf("unexpected by_state: %v", byState)
+	}
+	byAssignee := resp["by_assignee"].(map[string]interface{})
+	if byAssignee["test-user"].(float64) != 2 || byAssignee["unassigned"].(float64) != 1 {
+		t.Fatalf("unexpected by_assignee: %v", byAssignee)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/tasks/count", map[string]interface{}{"filter": "all", "group_by": []string{"kind"}})
+	if code != http.StatusOK {
+		t.Fatalf("count failed: %d %v", code, resp)
+	}
+	if resp["total"].(float64) != 4 {
+		t.Fatalf("expected 4 tasks with filter all, got %v", resp["total"])
+	}
+	if _, ok := resp["by_state"]; ok {
+		t.Fatalf("expected only requested dimensions, got %v", resp)
+	}
+
+	code, _ = daemonPost(t, handler, "/v1/tasks/count", map[string]interface{}{"group_by": []string{"bogus"}})
+	if code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown dimension, got %d", code)
+	}
+}
+
+func TestDaemonTasksListLabels(t *testing.T) {
+	_, handler := newTestDaemon(t)
+
+	for path, labels := range map[string][]string{
+		"inbox/labeled-ab": {"a", "b"},
+		"inbox/labeled-c":  {"c"},
+	} {
+		fields := map[string]interface{}{"labels": labels}
+		if code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": path, "fields": fields}); code != http.StatusOK {
+			t.Fatalf("task create failed: %d %v", code, resp)
+		}
+	}
+
+	for _, tc := range []struct {
+		body  map[string]interface{}
+		count int
+	}{
+		{map[string]interface{}{"labels_all": []string{"a"}}, 1},
+		{map[string]interface{}{"labels_all": []string{"a", "c"}}, 0},
+		{map[string]interface{}{"labels_any": []string{"a", "c"}}, 2},
+	} {
+		code, resp := daemonPost(t, handler, "/v1/tasks/list", tc.body)
+		if code != http.StatusOK {
+			t.Fatalf("list failed: %d %v", code, resp)
+		}
+		tasks, _ := resp["tasks"].([]interface{})
+		if len(tasks) != tc.count {
+			t.Errorf("%v: expected %d tasks, got %d", tc.body, tc.count, len(tasks))
+		}
+	}
+}
+
+func TestDaemonTasksExportCSV(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	for _, stmt := range []string{
+		`INSERT INTO tasks (uuid, id, slug, title, project_uuid, state, priority, assignee_actor_uuid, due_at, labels, created_at, created_by_actor_uuid, updated_by_actor_uuid, etag)
+			VALUES ('00000000-0000-0000-0000-0000000000c1', 'T-00101', 'report', 'Quarterly report, draft', '00000000-0000-0000-0000-000000000002',
+				'open', 1, '00000000-0000-0000-0000-000000000001', '2026-03-01T00:00:00Z', '["finance","q1"]', '2026-01-01T00:00:00Z',
+				'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001', 1)`,
+		`INSERT INTO tasks (uuid, id, slug, title, project_uuid, state, priority, created_at, created_by_actor_uuid, updated_by_actor_uuid, etag)
+			VALUES ('00000000-0000-0000-0000-0000000000c2', 'T-00102', 'later', 'Later', '00000000-0000-0000-0000-000000000002',
+				'open', 3, '2026-01-02T00:00:00Z', '00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001', 1)`,
+	} {
+		if _, err := server.db.Exec(stmt); err != nil {
+			t.Fatalf("failed to seed task: %v", err)
+		}
+	}
+
+	export := func(path string, body interface{}) (int, string) {
+		t.Helper()
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+		req.Header.Set("X-Wrkq-Actor", "test-user")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+
+	code, body := export("/v1/tasks/export?format=csv", map[string]interface{}{})
+	if code != http.StatusOK {
+		t.Fatalf("export failed: %d %s", code, body)
+	}
+	records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV %q: %v", body, err)
+	}
+	want := [][]string{
+		{"id", "path", "title", "state", "priority", "assignee", "due_at", "labels", "created_at"},
+		{"T-00101", "inbox/report", "Quarterly report, draft", "open", "1", "A-00001", "2026-03-01T00:00:00Z", "finance;q1", "2026-01-01T00:00:00Z"},
+	}
+	if len(records) != 3 || !reflect.DeepEqual(records[:2], want) {
+		t.Fatalf("unexpected export:\n%s", body)
+	}
+	if records[2][0] != "T-00102" || records[2][5] != "" || records[2][7] != "" {
+		t.Errorf("unexpected second row: %v", records[2])
+	}
+
+	code, body = export("/v1/tasks/export", map[string]interface{}{"columns": []string{"id", "project"}, "labels_any": []string{"q1"}})
+	if code != http.StatusOK || body != "id,project\nT-00101,P-00001\n" {
+		t.Fatalf("unexpected filtered export: %d %q", code, body)
+	}
+
+	if code, _ := export("/v1/tasks/export", map[string]interface{}{"columns": []string{"id", "secret"}}); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown column, got %d", code)
+	}
+	if code, _ := export("/v1/tasks/export?format=xlsx", map[string]interface{}{}); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unsupported format, got %d", code)
+	}
+
+	// A query that fails before any row is sent still gets a JSON error
+	if _, err := server.db.Exec("DROP VIEW v_container_paths"); err != nil {
+		t.Fatalf("failed to drop view: %v", err)
+	}
+	code, body = export("/v1/tasks/export", map[string]interface{}{})
+	if code != http.StatusInternalServerError || !strings.Contains(body, `"message"`) {
+		t.Errorf("expected a 500 JSON error, got %d %q", code, body)
+	}
+}
+
+// parseICS unfolds an iCalendar document and returns the properties of each
+// VEVENT, failing on unbalanced BEGIN/END or lines that aren't CRLF-terminated.
+func parseICS(t *testing.T, doc string) []map[string]string {
+	t.Helper()
+	if !strings.HasSuffix(doc, "\r\n") {
+		t.Fatalf("ICS does not end with CRLF: %q", doc)
+	}
+	var lines []string
+	for _, raw := range strings.Split(strings.TrimSuffix(doc, "\r\n"), "\r\n") {
+		if strings.HasPrefix(raw, " ") && len(lines) > 0 {
+			lines[len(lines)-1] += raw[1:]
+			continue
+		}
+		if len(raw) > 75 {
+			t.Errorf("line longer than 75 octets: %q", raw)
+		}
+		lines = append(lines, raw)
+	}
+
+	var stack []string
+	var events []map[string]string
+	var current map[string]string
+	for _, l := range lines {
+		name, value, ok := strings.Cut(l, ":")
+		if !ok {
+			t.Fatalf("malformed ICS line: %q", l)
+		}
+		switch name {
+		case "BEGIN":
+			stack = append(stack, value)
+			if value == "VEVENT" {
+				current = map[string]string{}
+			}
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1] != value {
+				t.Fatalf("unbalanced END:%s in %v", value, stack)
+			}
+			stack = stack[:len(stack)-1]
+			if value == "VEVENT" {
+				events = append(events, current)
+				current = nil
+			}
+		default:
+			if current != nil {
+				current[name] = value
+			}
+		}
+	}
+	if len(stack) != 0 || len(lines) == 0 || lines[0] != "BEGIN:VCALENDAR" {
+		t.Fatalf("ICS is not a single VCALENDAR: %v", stack)
+	}
+	return events
+}
+
+func TestDaemonTasksCalendarICS(t *testing.T) {
+	server, handler := newTestDaemon(t)
+	server.token = "secret"
+
+	for _, stmt := range []string{
+		`INSERT INTO tasks (uuid, id, slug, title, project_uuid, state, priority, start_at, due_at, created_by_actor_uuid, updated_by_actor_uuid, etag)
+			VALUES ('00000000-0000-0000-0000-0000000000d1', 'T-00201', 'launch', 'Launch; phase one, go', '00000000-0000-0000-0000-000000000002',
+				'open', 1, '2026-03-01T09:00:00Z', '2026-03-02T17:00:00Z', '00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001', 1)`,
+		`INSERT INTO tasks (uuid, id, slug, title, project_uuid, state, priority, due_at, created_by_actor_uuid, updated_by_actor_uuid, etag)
+			VALUES ('00000000-0000-0000-0000-0000000000d2', 'T-00202', 'shipped', 'Shipped', '00000000-0000-0000-0000-000000000002',
+				'completed', 3, '2026-02-01', '00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001', 1)`,
+		`INSERT INTO tasks (uuid, id, slug, title, project_uuid, state, priority, created_by_actor_uuid, updated_by_actor_uuid, etag)
+			VALUES ('00000000-0000-0000-0000-0000000000d3', 'T-00203', 'someday', 'Someday', '00000000-0000-0000-0000-000000000002',
+				'open', 3, '00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001', 1)`,
+	} {
+		if _, err := server.db.Exec(stmt); err != nil {
+			t.Fatalf("failed to seed task: %v", err)
+		}
+	}
+
+	get := func(path string) (int, string, string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code, rec.Header().Get("Content-Type"), rec.Body.String()
+	}
+
+	if code, _, _ := get("/v1/tasks/calendar.ics"); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", code)
+	}
+
+	code, contentType, body := get("/v1/tasks/calendar.ics?token=secret&project=inbox")
+	if code != http.StatusOK || !strings.HasPrefix(contentType, "text/calendar") {
+		t.Fatalf("calendar failed: %d %s %s", code, contentType, body)
+	}
+	events := parseICS(t, body)
+	if len(events) != 1 {
+		t.Fatalf("expected only the open task with a due date, got %v", events)
+	}
+	ev := events[0]
+	want := map[string]string{
+		"UID":         "00000000-0000-0000-0000-0000000000d1@wrkq",
+		"DTSTART":     "20260301T090000Z",
+		"DTEND":       "20260302T170000Z",
+		"SUMMARY":     "inbox/launch",
+		"DESCRIPTION": `T-00201: Launch\; phase one\, go`,
+		"STATUS":      "CONFIRMED",
+	}
+	for key, value := range want {
+		if ev[key] != value {
+			t.Errorf("%s = %q, want %q", key, ev[key], value)
+		}
+	}
+
+	_, _, body = get("/v1/tasks/calendar.ics?token=secret&include_closed=true")
+	events = parseICS(t, body)
+	if len(events) != 2 {
+		t.Fatalf("expected the completed task with include_closed, got %v", events)
+	}
+	done := events[0]
+	if done["UID"] != "00000000-0000-0000-0000-0000000000d2@wrkq" || done["SUMMARY"] != "[done] inbox/shipped" ||
+		done["DTSTART;VALUE=DATE"] != "20260201" || done["DTEND;VALUE=DATE"] != "20260202" {
+		t.Errorf("unexpected completed event: %v", done)
+	}
+}
+
+func TestDaemonVersion(t *testing.T) {
+	_, handler := newTestDaemon(t)
+
+	get := func() map[string]interface{} {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/v1/version", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("version failed: %d %s", rec.Code, rec.Body.String())
+		}
+		var resp map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		return resp
+	}
+
+	// Unstamped builds still report every field
+	resp := get()
+	for _, key := range []string{"version", "commit", "build_date"} {
+		if value, _ := resp[key].(string); value == "" {
+			t.Errorf("%s is empty: %v", key, resp)
+		}
+	}
+	if resp["machine_interface_version"] != float64(bundle.MachineInterfaceVersion) {
+		t.Errorf("unexpected machine_interface_version: %v", resp["machine_interface_version"])
+	}
+	features, _ := resp["features"].([]interface{})
+	if !reflect.DeepEqual(features, []interface{}{"fts", "webhooks", "watch"}) {
+		t.Errorf("unexpected features: %v", resp["features"])
+	}
+
+	// Release builds stamp the variables with -ldflags -X
+	saved := []string{Version, GitCommit, BuildDate}
+	Version, GitCommit, BuildDate = "1.2.3", "abc1234", "2026-01-02T03:04:05Z"
+	defer func() { Version, GitCommit, BuildDate = saved[0], saved[1], saved[2] }()
+	resp = get()
+	if resp["version"] != "1.2.3" || resp["commit"] != "abc1234" || resp["build_date"] != "2026-01-02T03:04:05Z" {
+		t.Errorf("build stamp not reported: %v", resp)
+	}
+}
+
+func TestDaemonMetricsCountsRequests(t *testing.T) {
+	server, handler := newTestDaemon(t)
+	server.token = "secret"
+
+	get := func(path string, authorized bool) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if authorized {
+			req.Header.Set("Authorization", "Bearer secret")
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	scrape := func() string {
+		t.Helper()
+		rec := get("/v1/metrics", true)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("metrics failed: %d %s", rec.Code, rec.Body.String())
+		}
+		if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+			t.Fatalf("unexpected content type %q", rec.Header().Get("Content-Type"))
+		}
+		return rec.Body.String()
+	}
+
+	if rec := get("/v1/metrics", false); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", rec.Code)
+	}
+
+	before := scrape()
+	if strings.Contains(before, `wrkqd_http_requests_total{route="/v1/health",status="200"}`) {
+		t.Fatalf("health counted before any request:\n%s", before)
+	}
+	get("/v1/health", true)
+	get("/v1/health", true)
+	get("/v1/health", false)
+
+	status, _ := daemonPostWithHeaders(t, handler, "/v1/tasks/create", map[string]string{"Authorization": "Bearer secret"}, map[string]interface{}{
+		"path": "inbox/metered", "title": "Metered",
+	})
+	if status != http.StatusOK {
+		t.Fatalf("create failed: %d", status)
+	}
+
+	after := scrape()
+	for _, line := range []string{
+		`wrkqd_http_requests_total{route="/v1/health",status="200"} 2`,
+		`wrkqd_http_requests_total{route="/v1/health",status="401"} 1`,
+		`wrkqd_http_requests_total{route="/v1/metrics",status="200"} 1`,
+		`wrkqd_http_requests_total{route="/v1/tasks/create",status="200"} 1`,
+		`wrkqd_http_request_duration_seconds_count{route="/v1/health"} 3`,
+		`wrkqd_http_request_duration_seconds_bucket{route="/v1/health",le="+Inf"} 3`,
+		"wrkqd_db_write_duration_seconds_count 1",
+		`wrkqd_webhook_deliveries_total{result="failure"}`,
+	} {
+		if !strings.Contains(after, line+"\n") && !strings.Contains(after, line+" ") {
+			t.Errorf("expected %q in metrics:\n%s", line, after)
+		}
+	}
+}
+
+func TestDaemonRequestLog(t *testing.T) {
+	server, handler := newTestDaemon(t)
+	var logs bytes.Buffer
+	server.logger = slog.New(slog.NewJSONHandler(&logs, nil))
+
+	data, _ := json.Marshal(map[string]interface{}{"path": "inbox/logged"})
+	req := httptest.NewRequest(http.MethodPost, "/v1/tasks/create?token=hidden", bytes.NewReader(data))
+	req.Header.Set("X-Wrkq-Actor", "test-user")
+	req.Header.Set("X-Request-Id", "req-123")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create failed: %d %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("X-Request-Id"); got != "req-123" {
+		t.Errorf("expected the client's request id echoed, got %q", got)
+	}
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(logs.Bytes(), &line); err != nil {
+		t.Fatalf("expected one JSON log line, got %q: %v", logs.String(), err)
+	}
+	for key, want := range map[string]interface{}{
+		"level":      "INFO",
+		"msg":        "request",
+		"method":     "POST",
+		"path":       "/v1/tasks/create",
+		"status":     float64(200),
+		"actor":      "test-user",
+		"request_id": "req-123",
+	} {
+		if line[key] != want {
+			t.Errorf("log %s = %v, want %v", key, line[key], want)
+		}
+	}
+	if _, ok := line["duration_ms"].(float64); !ok {
+		t.Errorf("expected a numeric duration_ms, got %v", line["duration_ms"])
+	}
+	if strings.Contains(logs.String(), "hidden") {
+		t.Errorf("query string leaked into the log: %s", logs.String())
+	}
+
+	// Refused requests are logged as warnings, with a generated request id
+	server.token = "secret"
+	logs.Reset()
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/health", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rec.Code)
+	}
+	generated := rec.Header().Get("X-Request-Id")
+	if generated == "" {
+		t.Fatal("expected a generated request id")
+	}
+	line = nil
+	if err := json.Unmarshal(logs.Bytes(), &line); err != nil {
+		t.Fatalf("expected one JSON log line, got %q: %v", logs.String(), err)
+	}
+	if line["level"] != "WARN" || line["status"] != float64(401) || line["request_id"] != generated || line["actor"] != "" {
+		t.Errorf("unexpected log line for a refused request: %v", line)
+	}
+
+	// Below the configured level nothing is written
+	server.logger = slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelError}))
+	logs.Reset()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/health", nil))
+	if logs.Len() != 0 {
+		t.Errorf("expected no log below the level, got %s", logs.String())
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	for value, want := range map[string]slog.Level{
+		"":      slog.LevelInfo,
+		"debug": slog.LevelDebug,
+		"WARN":  slog.LevelWarn,
+		"error": slog.LevelError,
+	} {
+		level, ok, err := parseLogLevel(value)
+		if err != nil || !ok || level != want {
+			t.Errorf("parseLogLevel(%q) = %v, %v, %v; want %v", value, level, ok, err, want)
+		}
+	}
+	if _, ok, err := parseLogLevel("off"); err != nil || ok {
+		t.Errorf("expected off to disable logging, got %v, %v", ok, err)
+	}
+	if _, _, err := parseLogLevel("loud"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
+
+func TestDaemonCORS(t *testing.T) {
+	server, handler := newTestDaemon(t)
+	server.token = "secret"
+
+	preflight := func(origin string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodOptions, "/v1/tasks/list", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "authorization, content-type")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Disabled by default
+	if rec := preflight("https://ui.example"); rec.Code == http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("expected CORS disabled by default, got %d %v", rec.Code, rec.Header())
+	}
+
+	server.cors = newCORSPolicy(DaemonOptions{CORSOrigins: []string{"https://ui.example"}, CORSCredentials: true})
+
+	// Preflights are answered without credentials
+	rec := preflight("https://ui.example")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 for an allowed preflight, got %d %s", rec.Code, rec.Body.String())
+	}
+	for header, want := range map[string]string{
+		"Access-Control-Allow-Origin":      "https://ui.example",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Methods":     "GET, POST",
+		"Access-Control-Max-Age":           "600",
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+	allowed := strings.ToLower(rec.Header().Get("Access-Control-Allow-Headers"))
+	for _, header := range []string{"authorization", "content-type", "x-wrkq-actor", "idempotency-key"} {
+		if !strings.Contains(allowed, header) {
+			t.Errorf("expected %s in Access-Control-Allow-Headers %q", header, allowed)
+		}
+	}
+	if vary := rec.Header().Values("Vary"); !slices.Contains(vary, "Origin") {
+		t.Errorf("expected Vary: Origin, got %v", vary)
+	}
+
+	// Other origins are refused
+	rec = preflight("https://evil.example")
+	if rec.Code != http.StatusForbidden || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("expected 403 without CORS headers for another origin, got %d %v", rec.Code, rec.Header())
+	}
+
+	// Actual requests still need the token, and get CORS headers either way
+	req := httptest.NewRequest(http.MethodGet, "/v1/health", nil)
+	req.Header.Set("Origin", "https://ui.example")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("Access-Control-Allow-Origin") != "https://ui.example" {
+		t.Fatalf("expected a readable 401 without the token, got %d %v", rec.Code, rec.Header())
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "https://ui.example" {
+		t.Fatalf("expected CORS headers on an allowed request, got %d %v", rec.Code, rec.Header())
+	}
+	if exposed := rec.Header().Get("Access-Control-Expose-Headers"); !strings.Contains(exposed, "X-Request-Id") {
+		t.Errorf("expected X-Request-Id exposed, got %q", exposed)
+	}
+}
+
+func TestDaemonGzipsLargeResponses(t *testing.T) {
+	_, handler := newTestDaemon(t)
+	for i := 0; i < 40; i++ {
+		if code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{
+			"path": fmt.Sprintf("inbox/compressed-%02d", i), "fields": map[string]interface{}{"title": "A task with a reasonably long title"},
+		}); code != http.StatusOK {
+			t.Fatalf("create failed: %d %v", code, resp)
+		}
+	}
+
+	list := func(acceptEncoding string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/v1/tasks/list", strings.NewReader(`{"path_prefix": ["inbox/"]}`))
+		req.Header.Set("X-Wrkq-Actor", "test-user")
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("list failed: %d %s", rec.Code, rec.Body.String())
+		}
+		return rec
+	}
+
+	plain := list("")
+	if plain.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("expected no compression without Accept-Encoding, got %v", plain.Header())
+	}
+	compressed := list("br;q=1, gzip;q=0.8")
+	if compressed.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected a gzipped response, got %v", compressed.Header())
+	}
+	if compressed.Body.Len() >= plain.Body.Len() {
+		t.Errorf("expected the gzipped body (%d bytes) smaller than the plain one (%d)", compressed.Body.Len(), plain.Body.Len())
+	}
+	zr, err := gzip.NewReader(compressed.Body)
+	if err != nil {
+		t.Fatalf("invalid gzip stream: %v", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("failed to decompress: %v", err)
+	}
+	if !bytes.Equal(body, plain.Body.Bytes()) {
+		t.Fatalf("decompressed body differs from the plain response")
+	}
+	var resp map[string]interface{}
+	if err := json.Unmarshal(body, &resp); err != nil || len(resp["tasks"].([]interface{})) != 40 {
+		t.Fatalf("unexpected decompressed response: %v", err)
+	}
+
+	// Small bodies and refusals go out as is
+	req := httptest.NewRequest(http.MethodGet, "/v1/health", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Header().Get("Content-Encoding") != "" || !json.Valid(rec.Body.Bytes()) {
+		t.Fatalf("expected a small response uncompressed, got %v %q", rec.Header(), rec.Body.String())
+	}
+	if rejected := list("gzip;q=0"); rejected.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("expected q=0 to refuse gzip, got %v", rejected.Header())
+	}
+}
+
+func TestDaemonTasksGetETag(t *testing.T) {
+	_, handler := newTestDaemon(t)
+
+	code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "inbox/polled"})
+	if code != http.StatusOK {
+		t.Fatalf("task create failed: %d %v", code, resp)
+	}
+	taskID := resp["task"].(map[string]interface{})["id"].(string)
+
+	get := func(body map[string]interface{}, ifNoneMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, "/v1/tasks/get", bytes.NewReader(data))
+		req.Header.Set("X-Wrkq-Actor", "test-user")
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	taskETag := func(rec *httptest.ResponseRecorder) string {
+		t.Helper()
+		var body struct {
+			Task Task `json:"task"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("invalid response %q: %v", rec.Body.String(), err)
+		}
+		return fmt.Sprintf(`W/"%d-`, body.Task.Etag)
+	}
+
+	rec := get(map[string]interface{}{"selector": taskID}, "")
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(etag, taskETag(rec)) {
+		t.Fatalf("expected 200 with an ETag derived from the task's etag, got %d %q", rec.Code, etag)
+	}
+
+	rec = get(map[string]interface{}{"selector": taskID}, etag)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 || rec.Header().Get("ETag") != etag {
+		t.Fatalf("expected 304 with no body for an unchanged task, got %d %q", rec.Code, rec.Body.String())
+	}
+	if rec = get(map[string]interface{}{"selector": taskID}, `"other", `+strings.TrimPrefix(etag, "W/")); rec.Code != http.StatusNotModified {
+		t.Fatalf("expected a listed strong form of the ETag to match, got %d", rec.Code)
+	}
+
+	// Different include options describe a different representation
+	rec = get(map[string]interface{}{"selector": taskID, "include_time": true}, etag)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Fatalf("expected a new ETag with include_time, got %d %q", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	// A new comment changes the ETag though the task's etag is unchanged
+	if code, resp := daemonPost(t, handler, "/v1/comments/create", map[string]interface{}{"task": taskID, "body": "ping"}); code != http.StatusOK {
+		t.Fatalf("comment create failed: %d %v", code, resp)
+	}
+	rec = get(map[string]interface{}{"selector": taskID}, etag)
+	commented := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || commented == etag {
+		t.Fatalf("expected 200 with a new ETag after a comment, got %d %q", rec.Code, commented)
+	}
+
+	if code, resp := daemonPost(t, handler, "/v1/tasks/update", map[string]interface{}{"selector": taskID, "fields": map[string]interface{}{"title": "Polled"}}); code != http.StatusOK {
+		t.Fatalf("update failed: %d %v", code, resp)
+	}
+	rec = get(map[string]interface{}{"selector": taskID}, commented)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == commented || !strings.HasPrefix(rec.Header().Get("ETag"), taskETag(rec)) {
+		t.Fatalf("expected 200 with an ETag for the new etag after an update, got %d %q", rec.Code, rec.Header().Get("ETag"))
+	}
+	if strings.HasPrefix(commented, taskETag(rec)) {
+		t.Errorf("expected the update to bump the task's etag, still %q", commented)
+	}
+}
+
+func TestDaemonTasksBatchGet(t *testing.T) {
+	_, handler := newTestDaemon(t)
+
+	ids := map[string]string{}
+	for _, slug := range []string{"api", "ui", "docs"} {
+		code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "inbox/" + slug})
+		if code != http.StatusOK {
+			t.Fatalf("create %s failed: %d %v", slug, code, resp)
+		}
+		ids[slug] = resp["task"].(map[string]interface{})["id"].(string)
+	}
+	if code, resp := daemonPost(t, handler, "/v1/relations/create", map[string]interface{}{"from": "inbox/api", "kind": "blocks", "to": "inbox/ui"}); code != http.StatusOK {
+		t.Fatalf("relation create failed: %d %v", code, resp)
+	}
+	if code, resp := daemonPost(t, handler, "/v1/comments/create", map[string]interface{}{"task": ids["docs"], "body": "write me"}); code != http.StatusOK {
+		t.Fatalf("comment create failed: %d %v", code, resp)
+	}
+
+	selectorsList := []string{ids["api"], "inbox/ui", "t:" + ids["docs"], "T-99999"}
+	code, resp := daemonPost(t, handler, "/v1/tasks/batch_get", map[string]interface{}{"selectors": selectorsList})
+	if code != http.StatusOK {
+		t.Fatalf("batch_get failed: %d %v", code, resp)
+	}
+	entries := resp["tasks"].(map[string]interface{})
+	if len(entries) != 4 {
+		t.Fatalf("expected an entry per selector, got %v", entries)
+	}
+	entry := func(sel string) map[string]interface{} {
+		return entries[sel].(map[string]interface{})
+	}
+	for sel, slug := range map[string]string{ids["api"]: "api", "inbox/ui": "ui", "t:" + ids["docs"]: "docs"} {
+		task, ok := entry(sel)["task"].(map[string]interface{})
+		if !ok || task["slug"] != slug || task["id"] != ids[slug] {
+			t.Errorf("expected %s for %q, got %v", slug, sel, entry(sel))
+		}
+	}
+	if missing := entry("T-99999"); missing["task"] != nil || missing["error"] == "" || missing["error"] == nil {
+		t.Errorf("expected an error entry for the bad selector, got %v", missing)
+	}
+
+	// Sub-resources match what tasks/get returns for each task
+	for sel, slug := range map[string]string{ids["api"]: "api", "inbox/ui": "ui", "t:" + ids["docs"]: "docs"} {
+		_, single := daemonPost(t, handler, "/v1/tasks/get", map[string]interface{}{"selector": ids[slug]})
+		if !reflect.DeepEqual(entry(sel)["task"], single["task"]) {
+			t.Errorf("batch entry for %s differs from tasks/get:\n%v\n%v", slug, entry(sel)["task"], single["task"])
+		}
+	}
+	api := entry(ids["api"])["task"].(map[string]interface{})
+	ui := entry("inbox/ui")["task"].(map[string]interface{})
+	docs := entry("t:" + ids["docs"])["task"].(map[string]interface{})
+	if rels, _ := api["relations"].([]interface{}); len(rels) != 1 || rels[0].(map[string]interface{})["direction"] != "outgoing" {
+		t.Errorf("expected api's outgoing relation, got %v", api["relations"])
+	}
+	if rels, _ := ui["relations"].([]interface{}); len(rels) != 1 || rels[0].(map[string]interface{})["direction"] != "incoming" {
+		t.Errorf("expected ui's incoming relation, got %v", ui["relations"])
+	}
+	if comments, _ := docs["comments"].([]interface{}); len(comments) != 1 {
+		t.Errorf("expected docs' comment, got %v", docs["comments"])
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/tasks/batch_get", map[string]interface{}{
+		"selectors": []string{"inbox/api", "inbox/docs"}, "include_comments": false, "include_relations": false,
+	})
+	if code != http.StatusOK {
+		t.Fatalf("batch_get failed: %d %v", code, resp)
+	}
+	for sel, e := range resp["tasks"].(map[string]interface{}) {
+		task := e.(map[string]interface{})["task"].(map[string]interface{})
+		if task["comments"] != nil || task["relations"] != nil {
+			t.Errorf("expected %s without comments or relations, got %v", sel, task)
+		}
+	}
+
+	if code, _ := daemonPost(t, handler, "/v1/tasks/batch_get", map[string]interface{}{"selectors": []string{}}); code != http.StatusBadRequest {
+		t.Errorf("expected 400 without selectors, got %d", code)
+	}
+}
+
+func TestDaemonLookupsArePerRequest(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	var caches []*lookupCache
+	probe := server.withLookups(func(w http.ResponseWriter, r *http.Request) {
+		caches = append(caches, server.lookups(r), server.lookups(r))
+	})
+	for i := 0; i < 2; i++ {
+		probe(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	if caches[0] != caches[1] || caches[1] == caches[2] || caches[2] != caches[3] {
+		t.Fatalf("expected one lookup cache per request")
+	}
+
+	for _, slug := range []string{"one", "two", "three"} {
+		if code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "inbox/" + slug}); code != http.StatusOK {
+			t.Fatalf("create %s failed: %d %v", slug, code, resp)
+		}
+	}
+	listed := func(project string) int {
+		t.Helper()
+		code, resp := daemonPost(t, handler, "/v1/tasks/list", map[string]interface{}{"project": project})
+		if code != http.StatusOK {
+			t.Fatalf("list failed: %d %v", code, resp)
+		}
+		return len(resp["tasks"].([]interface{}))
+	}
+	if got := listed("inbox"); got != 3 {
+		t.Fatalf("expected 3 tasks in inbox, got %d", got)
+	}
+
+	// A change between requests is seen by the next one
+	if _, err := server.db.Exec("UPDATE containers SET slug = 'tray' WHERE slug = 'inbox'"); err != nil {
+		t.Fatalf("failed to rename container: %v", err)
+	}
+	if got := listed("tray"); got != 3 {
+		t.Fatalf("expected the renamed project's 3 tasks, got %d", got)
+	}
+}
+
+func TestDaemonSelectorCacheInvalidation(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	if _, err := server.db.Exec(`
+		INSERT INTO containers (uuid, id, slug, title, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f1', 'P-00002', 'portal', 'Portal',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+		INSERT INTO containers (uuid, id, slug, title, parent_uuid, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f2', 'P-00003', 'auth', 'Auth', '00000000-0000-0000-0000-0000000000f1',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+	`); err != nil {
+		t.Fatalf("failed to seed containers: %v", err)
+	}
+	server.selectorCache = newSelectorCache(server.db, 8)
+	if code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "portal/auth/login"}); code != http.StatusOK {
+		t.Fatalf("create failed: %d %v", code, resp)
+	}
+
+	list := func(project string) (int, map[string]interface{}) {
+		t.Helper()
+		return daemonPost(t, handler, "/v1/tasks/list", map[string]interface{}{"project": project, "assignee": "test-user"})
+	}
+	if code, resp := list("portal/auth"); code != http.StatusOK || len(resp["tasks"].([]interface{})) != 0 {
+		t.Fatalf("list failed: %d %v", code, resp)
+	}
+	if _, _, _, ok := server.selectorCache.get("container", "portal/auth"); !ok {
+		t.Fatalf("expected the project selector to be cached")
+	}
+	if _, _, _, ok := server.selectorCache.get("actor", "test-user"); !ok {
+		t.Fatalf("expected the assignee selector to be cached")
+	}
+
+	// A rename through the daemon is seen by the next request
+	if code, resp := daemonPost(t, handler, "/v1/containers/rename", map[string]interface{}{"selector": "portal/auth", "slug": "identity"}); code != http.StatusOK {
+		t.Fatalf("rename failed: %d %v", code, resp)
+	}
+	if code, resp := list("portal/auth"); code != http.StatusBadRequest || !strings.Contains(resp["message"].(string), "not found") {
+		t.Fatalf("expected the old path to stop resolving, got %d %v", code, resp)
+	}
+	if code, resp := list("portal/identity"); code != http.StatusOK {
+		t.Fatalf("expected the new path to resolve, got %d %v", code, resp)
+	}
+
+	// So is one made by another process, once the cache next checks
+	if _, err := store.New(server.db).Containers.Rename("00000000-0000-0000-0000-000000000001", "00000000-0000-0000-0000-0000000000f2", "sso", 0); err != nil {
+		t.Fatalf("out-of-band rename failed: %v", err)
+	}
+	server.selectorCache.checkedAt = time.Time{}
+	if code, _ := list("portal/identity"); code != http.StatusBadRequest {
+		t.Fatalf("expected the renamed path to stop resolving, got %d", code)
+	}
+
+	// A restored database can have fewer events than the cache has seen, and
+	// changes the cache never got an event for
+	if code, resp := list("portal/sso"); code != http.StatusOK {
+		t.Fatalf("expected the new path to resolve, got %d %v", code, resp)
+	}
+	if _, err := server.db.Exec(`
+		DELETE FROM event_log WHERE id > (SELECT MIN(id) FROM event_log);
+		UPDATE containers SET slug = 'restored' WHERE uuid = '00000000-0000-0000-0000-0000000000f2';
+	`); err != nil {
+		t.Fatalf("failed to simulate a restore: %v", err)
+	}
+	server.selectorCache.checkedAt = time.Time{}
+	if code, _ := list("portal/sso"); code != http.StatusBadRequest {
+		t.Fatalf("expected the cache to be emptied after event_log shrank, got %d", code)
+	}
+
+	// The least recently used selector is evicted first
+	cache := newSelectorCache(server.db, 2)
+	for _, sel := range []string{"a", "b", "c"} {
+		_, _, generation, _ := cache.get("container", sel)
+		cache.put("container", sel, sel+"-uuid", "", generation)
+	}
+	if _, _, _, ok := cache.get("container", "a"); ok {
+		t.Errorf("expected a to be evicted")
+	}
+	if uuid, _, _, ok := cache.get("container", "c"); !ok || uuid != "c-uuid" {
+		t.Errorf("expected c to be cached, got %q %v", uuid, ok)
+	}
+}
+
+func TestDaemonRoutesListsRegisteredEndpoints(t *testing.T) {
+	server, handler := newTestDaemon(t)
+	server.token = "secret"
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/routes", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/routes", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("routes failed: %d %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Version string        `json:"version"`
+		Routes  []daemonRoute `json:"routes"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if resp.Version != Version || !reflect.DeepEqual(resp.Routes, server.routes) {
+		t.Fatalf("unexpected routes response: %+v", resp)
+	}
+
+	seen := map[string]bool{}
+	for _, route := range resp.Routes {
+		if seen[route.Path] {
+			t.Errorf("%s listed twice", route.Path)
+		}
+		seen[route.Path] = true
+		if route.Description == "" || (route.Method != http.MethodGet && route.Method != http.MethodPost) {
+			t.Errorf("incomplete route metadata: %+v", route)
+		}
+	}
+	for _, path := range []string{"/v1/routes", "/v1/health", "/v1/tasks/list", "/v1/bundle/apply"} {
+		if !seen[path] {
+			t.Errorf("%s not listed", path)
+		}
+	}
+}
+
+func TestDaemonOpenAPIListsEveryRoute(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("openapi.json failed: %d %s", rec.Code, rec.Body.String())
+	}
+
+	var spec struct {
+		OpenAPI    string                                       `json:"openapi"`
+		Paths      map[string]map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("spec is not valid JSON: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("unexpected openapi version %q", spec.OpenAPI)
+	}
+
+	for _, route := range server.routes {
+		if _, ok := spec.Paths[route.Path][strings.ToLower(route.Method)]; !ok {
+			t.Errorf("registered route %s %s missing from spec", route.Method, route.Path)
+		}
+		if _, ok := daemonAPISchemas[route.Path]; !ok {
+			t.Errorf("registered route %s has no daemonAPISchemas entry", route.Path)
+		}
+	}
+	if len(spec.Paths) != len(server.routes) || len(daemonAPISchemas) != len(server.routes) {
+		t.Errorf("spec lists %d paths and %d schemas, registerRoutes has %d",
+			len(spec.Paths), len(daemonAPISchemas), len(server.routes))
+	}
+
+	// Each documented method must be the one the handler accepts. The
+	// context is already cancelled so streaming endpoints return at once.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for path, ops := range spec.Paths {
+		for method := range ops {
+			req := httptest.NewRequest(strings.ToUpper(method), path, strings.NewReader("{")).WithContext(ctx)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code == http.StatusMethodNotAllowed {
+				t.Errorf("%s %s: documented method rejected", method, path)
+			}
+		}
+	}
+
+	// Request and response structs resolve to component schemas
+	list := spec.Paths["/v1/tasks/list"]["post"]
+	body, _ := json.Marshal(list["requestBody"])
+	if !strings.Contains(string(body), "TasksListRequest") {
+		t.Errorf("tasks/list request not described: %s", body)
+	}
+	listRequest, _ := json.Marshal(spec.Components.Schemas["TasksListRequest"])
+	for _, field := range []string{"path_prefix", "labels_any", "due_window"} {
+		if !strings.Contains(string(listRequest), `"`+field+`"`) {
+			t.Errorf("TasksListRequest schema missing %s: %s", field, listRequest)
+		}
+	}
+	export, _ := json.Marshal(spec.Components.Schemas["TasksExportRequest"])
+	if !strings.Contains(string(export), `"columns"`) || !strings.Contains(string(export), `"path_prefix"`) {
+		t.Errorf("embedded request fields not flattened: %s", export)
+	}
+	if _, ok := spec.Components.Schemas["TreeNode"]; !ok {
+		t.Errorf("recursive tree schema missing")
+	}
+}
+
+func TestDaemonTasksListPathPrefixGlob(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	containers := []struct{ uuid, id, slug, parent string }{
+		{"00000000-0000-0000-0000-0000000000c1", "P-00002", "acme", "00000000-0000-0000-0000-000000000002"},
+		{"00000000-0000-0000-0000-0000000000c2", "P-00003", "beta", "00000000-0000-0000-0000-000000000002"},
+		{"00000000-0000-0000-0000-0000000000c3", "P-00004", "active", "00000000-0000-0000-0000-0000000000c1"},
+		{"00000000-0000-0000-0000-0000000000c4", "P-00005", "active", "00000000-0000-0000-0000-0000000000c2"},
+		{"00000000-0000-0000-0000-0000000000c5", "P-00006", "archive", "00000000-0000-0000-0000-0000000000c2"},
+	}
+	for _, c := range containers {
+		if _, err := server.db.Exec(`
+			INSERT INTO containers (uuid, id, slug, title, parent_uuid, created_by_actor_uuid, updated_by_actor_uuid)
+			VALUES (?, ?, ?, ?, ?, '00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001')
+		`, c.uuid, c.id, c.slug, c.slug, c.parent); err != nil {
+			t.Fatalf("failed to create container %s: %v", c.slug, err)
+		}
+	}
+	for _, path := range []string{"inbox/acme/active/one", "inbox/beta/active/two", "inbox/beta/archive/three", "inbox/top"} {
+		if code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": path}); code != http.StatusOK {
+			t.Fatalf("create %s failed: %d %v", path, code, resp)
+		}
+	}
+
+	for _, tc := range []struct {
+		prefixes []string
+		count    int
+	}{
+		{[]string{"inbox/*/active"}, 2},
+		{[]string{"inbox/beta/*"}, 2},
+		{[]string{"inbox/*/*"}, 3},
+		{[]string{"inbox/*"}, 3},
+		{[]string{"inbox/acme/*", "inbox/beta/archive"}, 2},
+	} {
+		code, resp := daemonPost(t, handler, "/v1/tasks/list", map[string]interface{}{"path_prefix": tc.prefixes})
+		if code != http.StatusOK {
+			t.Fatalf("list %v failed: %d %v", tc.prefixes, code, resp)
+		}
+		tasks, _ := resp["tasks"].([]interface{})
+		if len(tasks) != tc.count {
+			t.Errorf("%v: expected %d tasks, got %d", tc.prefixes, tc.count, len(tasks))
+		}
+	}
+
+	code, resp := daemonPost(t, handler, "/v1/tasks/list", map[string]interface{}{"path_prefix": []string{"inbox/*/missing"}})
+	if code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a pattern matching no containers, got %d %v", code, resp)
+	}
+	code, resp = daemonPost(t, handler, "/v1/tasks/list", map[string]interface{}{"path_prefix": []string{"inbox/*/../x"}})
+	if code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unsafe segment, got %d %v", code, resp)
+	}
+}
+
+func TestDaemonTasksListDueWindowAndPriority(t *testing.T) {
+	_, handler := newTestDaemon(t)
+
+	past := time.Now().Add(-48 * time.Hour).UTC().Format(time.RFC3339)
+	future := time.Now().Add(30 * 24 * time.Hour).UTC().Format(time.RFC3339)
+	for _, task := range []struct {
+		path     string
+		dueAt    string
+		state    string
+		priority int
+	}{
+		{"inbox/late", past, "open", 1},
+		{"inbox/late-done", past, "completed", 2},
+		{"inbox/later", future, "open", 3},
+		{"inbox/undated", "", "open", 4},
+	} {
+		fields := map[string]interface{}{"state": task.state, "priority": task.priority}
+		if task.dueAt != "" {
+			fields["due_at"] = task.dueAt
+		}
+		if code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": task.path, "fields": fields}); code != http.StatusOK {
+			t.Fatalf("create %s failed: %d %v", task.path, code, resp)
+		}
+	}
+
+	listSlugs := func(body map[string]interface{}) []string {
+		t.Helper()
+		code, resp := daemonPost(t, handler, "/v1/tasks/list", body)
+		if code != http.StatusOK {
+			t.Fatalf("list %v failed: %d %v", body, code, resp)
+		}
+		var slugs []string
+		for _, task := range resp["tasks"].([]interface{}) {
+			slugs = append(slugs, task.(map[string]interface{})["slug"].(string))
+		}
+		sort.Strings(slugs)
+		return slugs
+	}
+
+	if got := listSlugs(map[string]interface{}{"due_window": "overdue", "tz": "America/New_York"}); !reflect.DeepEqual(got, []string{"late"}) {
+		t.Errorf("overdue: expected [late], got %v", got)
+	}
+	if got := listSlugs(map[string]interface{}{"priority_min": 2, "priority_max": 3}); !reflect.DeepEqual(got, []string{"late-done", "later"}) {
+		t.Errorf("priority 2-3: expected [late-done later], got %v", got)
+	}
+	// A raw due_before replaces the window's upper bound
+	dueBefore := time.Now().AddDate(0, 2, 0).Format("2006-01-02")
+	if got := listSlugs(map[string]interface{}{"due_window": "overdue", "due_before": dueBefore}); !reflect.DeepEqual(got, []string{"late", "later"}) {
+		t.Errorf("overdue with due_before override: expected [late later], got %v", got)
+	}
+
+	for _, body := range []map[string]interface{}{
+		{"due_window": "someday"},
+		{"due_window": "today", "tz": "Not/AZone"},
+		{"priority_min": 3, "priority_max": 1},
+		{"priority_max": 9},
+	} {
+		if code, _ := daemonPost(t, handler, "/v1/tasks/list", body); code != http.StatusBadRequest {
+			t.Errorf("%v: expected 400, got %d", body, code)
+		}
+	}
+}
+
+func TestDaemonTasksListAssigneeSentinels(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	if _, err := server.db.Exec(`INSERT INTO actors (uuid, id, slug, role) VALUES ('00000000-0000-0000-0000-0000000000a9', 'A-00009', 'other-agent', 'agent')`); err != nil {
+		t.Fatalf("failed to create actor: %v", err)
+	}
+	for _, task := range []struct {
+		path, assignee string
+		priority       int
+	}{
+		{"inbox/mine", "test-user", 1},
+		{"inbox/theirs", "other-agent", 1},
+		{"inbox/nobody-urgent", "", 1},
+		{"inbox/nobody-later", "", 4},
+	} {
+		fields := map[string]interface{}{"priority": task.priority}
+		if task.assignee != "" {
+			fields["assignee"] = task.assignee
+		}
+		if code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": task.path, "fields": fields}); code != http.StatusOK {
+			t.Fatalf("create %s failed: %d %v", task.path, code, resp)
+		}
+	}
+
+	listSlugs := func(headers map[string]string, body map[string]interface{}) []string {
+		t.Helper()
+		code, resp := daemonPostWithHeaders(t, handler, "/v1/tasks/list", headers, body)
+		if code != http.StatusOK {
+			t.Fatalf("list %v failed: %d %v", body, code, resp)
+		}
+		var slugs []string
+		for _, task := range resp["tasks"].([]interface{}) {
+			slugs = append(slugs, task.(map[string]interface{})["slug"].(string))
+		}
+		sort.Strings(slugs)
+		return slugs
+	}
+
+	if got := listSlugs(nil, map[string]interface{}{"assignee": "@me"}); !reflect.DeepEqual(got, []string{"mine"}) {
+		t.Errorf("@me as test-user: expected [mine], got %v", got)
+	}
+	other := map[string]string{"X-Wrkq-Actor": "other-agent"}
+	if got := listSlugs(other, map[string]interface{}{"assignee": "@me"}); !reflect.DeepEqual(got, []string{"theirs"}) {
+		t.Errorf("@me as other-agent: expected [theirs], got %v", got)
+	}
+	if got := listSlugs(nil, map[string]interface{}{"assignee": "@none"}); !reflect.DeepEqual(got, []string{"nobody-later", "nobody-urgent"}) {
+		t.Errorf("@none: expected unassigned tasks, got %v", got)
+	}
+	if got := listSlugs(nil, map[string]interface{}{"assignee": "@none", "priority_max": 2}); !reflect.DeepEqual(got, []string{"nobody-urgent"}) {
+		t.Errorf("@none with priority_max: expected [nobody-urgent], got %v", got)
+	}
+
+	code, resp := daemonPost(t, handler, "/v1/tasks/count", map[string]interface{}{"assignee": "@none", "group_by": []string{"assignee"}})
+	if code != http.StatusOK || resp["total"].(float64) != 2 {
+		t.Fatalf("expected 2 unassigned tasks counted, got %d %v", code, resp)
+	}
+}
+
+func TestDaemonTasksReassign(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	if _, err := server.db.Exec(`
+		INSERT INTO actors (uuid, id, slug, role) VALUES ('00000000-0000-0000-0000-0000000000a9', 'A-00009', 'offline-agent', 'agent');
+		INSERT INTO containers (uuid, id, slug, title, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f1', 'P-00002', 'portal', 'Portal',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+	`); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+	for _, task := range []struct{ path, assignee, state string }{
+		{"inbox/one", "offline-agent", "open"},
+		{"inbox/two", "offline-agent", "in_progress"},
+		{"inbox/three", "offline-agent", "blocked"},
+		{"inbox/finished", "offline-agent", "completed"},
+		{"inbox/other", "test-user", "open"},
+		{"portal/elsewhere", "offline-agent", "open"},
+	} {
+		fields := map[string]interface{}{"assignee": task.assignee, "state": task.state}
+		if code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": task.path, "fields": fields}); code != http.StatusOK {
+			t.Fatalf("create %s failed: %d %v", task.path, code, resp)
+		}
+	}
+
+	snapshot := func() (map[string]string, map[string]int) {
+		t.Helper()
+		assignees := map[string]string{}
+		etags := map[string]int{}
+		rows, err := server.db.Query(`
+			SELECT t.slug, COALESCE(a.slug, ''), t.etag FROM tasks t
+			LEFT JOIN actors a ON a.uuid = t.assignee_actor_uuid
+		`)
+		if err != nil {
+			t.Fatalf("failed to query tasks: %v", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var slug, assignee string
+			var etag int
+			if err := rows.Scan(&slug, &assignee, &etag); err != nil {
+				t.Fatalf("failed to scan task: %v", err)
+			}
+			assignees[slug] = assignee
+			etags[slug] = etag
+		}
+		return assignees, etags
+	}
+	_, before := snapshot()
+
+	code, resp := daemonPost(t, handler, "/v1/tasks/reassign", map[string]interface{}{
+		"from_assignee": "offline-agent",
+		"to_assignee":   "test-user",
+		"project":       "inbox",
+	})
+	if code != http.StatusOK || resp["reassigned"].(float64) != 3 {
+		t.Fatalf("expected 3 reassigned, got %d %v", code, resp)
+	}
+
+	assignees, etags := snapshot()
+	for _, slug := range []string{"one", "two", "three"} {
+		if assignees[slug] != "test-user" || etags[slug] != before[slug]+1 {
+			t.Errorf("%s: expected test-user with a bumped etag, got %q at etag %d (was %d)", slug, assignees[slug], etags[slug], before[slug])
+		}
+	}
+	for _, slug := range []string{"finished", "elsewhere"} {
+		if assignees[slug] != "offline-agent" || etags[slug] != before[slug] {
+			t.Errorf("%s: expected untouched, got %q at etag %d (was %d)", slug, assignees[slug], etags[slug], before[slug])
+		}
+	}
+
+	var updatedEvents int
+	if err := server.db.QueryRow("SELECT COUNT(*) FROM event_log WHERE event_type = 'task.updated'").Scan(&updatedEvents); err != nil {
+		t.Fatalf("failed to count events: %v", err)
+	}
+	if updatedEvents != 3 {
+		t.Errorf("expected 3 task.updated events, got %d", updatedEvents)
+	}
+
+	// @none unassigns, scoped by path prefix
+	code, resp = daemonPost(t, handler, "/v1/tasks/reassign", map[string]interface{}{
+		"from_assignee": "offline-agent",
+		"to_assignee":   "@none",
+		"path_prefix":   []string{"portal"},
+	})
+	if code != http.StatusOK || resp["reassigned"].(float64) != 1 {
+		t.Fatalf("expected 1 unassigned, got %d %v", code, resp)
+	}
+	var unassigned int
+	if err := server.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE slug = 'elsewhere' AND assignee_actor_uuid IS NULL").Scan(&unassigned); err != nil || unassigned != 1 {
+		t.Errorf("expected elsewhere to be unassigned, got %d (%v)", unassigned, err)
+	}
+
+	if code, _ := daemonPost(t, handler, "/v1/tasks/reassign", map[string]interface{}{"from_assignee": "offline-agent"}); code != http.StatusBadRequest {
+		t.Errorf("expected 400 without to_assignee, got %d", code)
+	}
+}
+
+func TestDaemonTasksSearch(t *testing.T) {
+	_, handler := newTestDaemon(t)
+
+	code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "inbox/quiet-task"})
+	if code != http.StatusOK {
+		t.Fatalf("task create failed: %d %v", code, resp)
+	}
+	taskID := resp["task"].(map[string]interface{})["id"].(string)
+	if code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "inbox/other-task"}); code != http.StatusOK {
+		t.Fatalf("task create failed: %d %v", code, resp)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/comments/create", map[string]interface{}{"task": taskID, "body": "Traced it to the zeppelin cache"})
+	if code != http.StatusOK {
+		t.Fatalf("comment create failed: %d %v", code, resp)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/tasks/search", map[string]interface{}{"query": "zeppelin"})
+	if code != http.StatusOK {
+		t.Fatalf("search failed: %d %v", code, resp)
+	}
+	tasks := resp["tasks"].([]interface{})
+	if len(tasks) != 1 {
+		t.Fatalf("expected 1 match, got %v", tasks)
+	}
+	match := tasks[0].(map[string]interface{})
+	if match["id"] != taskID || !strings.Contains(match["snippet"].(string), "[zeppelin]") {
+		t.Fatalf("expected parent task %s with snippet, got %v", taskID, match)
+	}
+
+	code, _ = daemonPost(t, handler, "/v1/tasks/search", map[string]interface{}{"query": ""})
+	if code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for empty query, got %d", code)
+	}
+}
+
+func TestDaemonCreateFromTemplate(t *testing.T) {
+	_, handler := newTestDaemon(t)
+
+	code, resp := daemonPost(t, handler, "/v1/templates/create", map[string]interface{}{
+		"name": "bug-report",
+		"fields": map[string]interface{}{
+			"title":       "Bug: {{summary}}",
+			"description": "Reported by {{reporter}}.",
+			"kind":        "bug",
+			"priority":    2,
+			"labels":      []string{"bug", "triage"},
+		},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("template create failed: %d %v", code, resp)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/tasks/create_from_template", map[string]interface{}{
+		"template":  "bug-report",
+		"path":      "inbox/login-crash",
+		"overrides": map[string]interface{}{"priority": 1},
+		"args":      map[string]string{"summary": "login crashes", "reporter": "qa"},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("create from template failed: %d %v", code, resp)
+	}
+	task := resp["task"].(map[string]interface{})
+	if task["title"] != "Bug: login crashes" || task["description"] != "Reported by qa." {
+		t.Errorf("placeholders not filled: %v", task)
+	}
+	if task["priority"].(float64) != 1 || task["kind"] != "bug" {
+		t.Errorf("expected override priority 1 and template kind bug, got %v", task)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/templates/list", map[string]interface{}{})
+	if code != http.StatusOK || len(resp["templates"].([]interface{})) != 1 {
+		t.Fatalf("expected 1 template, got %d %v", code, resp)
+	}
+
+	for _, tc := range []struct {
+		path string
+		body map[string]interface{}
+		code int
+	}{
+		{"/v1/tasks/create_from_template", map[string]interface{}{"template": "bug-report", "path": "inbox/no-args"}, http.StatusBadRequest},
+		{"/v1/tasks/create_from_template", map[string]interface{}{"template": "missing", "path": "inbox/x"}, http.StatusNotFound},
+		{"/v1/templates/create", map[string]interface{}{"name": "bug-report"}, http.StatusBadRequest},
+		{"/v1/templates/create", map[string]interface{}{"name": "typo", "fields": map[string]interface{}{"titel": "x"}}, http.StatusBadRequest},
+	} {
+		if code, resp := daemonPost(t, handler, tc.path, tc.body); code != tc.code {
+			t.Errorf("%s %v: expected %d, got %d %v", tc.path, tc.body, tc.code, code, resp)
+		}
+	}
+}
+
+func TestDaemonViewsRunResolvesAtRunTime(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	if code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "inbox/viewed"}); code != http.StatusOK {
+		t.Fatalf("task create failed: %d %v", code, resp)
+	}
+	var inboxID string
+	if err := server.db.QueryRow("SELECT id FROM containers WHERE slug = 'inbox'").Scan(&inboxID); err != nil {
+		t.Fatalf("failed to read inbox id: %v", err)
+	}
+
+	filter := map[string]interface{}{"project": inboxID, "filter": "all"}
+	code, resp := daemonPost(t, handler, "/v1/views/create", map[string]interface{}{"name": "my inbox", "filter": filter})
+	if code != http.StatusOK {
+		t.Fatalf("view create failed: %d %v", code, resp)
+	}
+
+	code, _ = daemonPost(t, handler, "/v1/views/create", map[string]interface{}{"name": "my inbox", "filter": filter})
+	if code != http.StatusBadRequest {
+		t.Fatalf("expected duplicate name to fail, got %d", code)
+	}
+	code, _ = daemonPost(t, handler, "/v1/views/create", map[string]interface{}{"name": "typo", "filter": map[string]interface{}{"projcet": "inbox"}})
+	if code != http.StatusBadRequest {
+		t.Fatalf("expected unknown filter field to fail, got %d", code)
+	}
+
+	// The project selector is resolved on each run, so a rename doesn't break the view
+	if _, err := server.db.Exec("UPDATE containers SET slug = 'renamed-inbox' WHERE id = ?", inboxID); err != nil {
+		t.Fatalf("failed to rename inbox: %v", err)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/views/run", map[string]interface{}{"view": "my inbox"})
+	if code != http.StatusOK {
+		t.Fatalf("view run failed: %d %v", code, resp)
+	}
+	tasks := resp["tasks"].([]interface{})
+	if len(tasks) != 1 || tasks[0].(map[string]interface{})["path"] != "renamed-inbox/viewed" {
+		t.Fatalf("expected the renamed task, got %v", tasks)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/views/list", map[string]interface{}{})
+	if code != http.StatusOK || len(resp["views"].([]interface{})) != 1 {
+		t.Fatalf("expected 1 view, got %d %v", code, resp)
+	}
+
+	if code, resp := daemonPost(t, handler, "/v1/views/delete", map[string]interface{}{"view": "my inbox"}); code != http.StatusOK {
+		t.Fatalf("view delete failed: %d %v", code, resp)
+	}
+	code, _ = daemonPost(t, handler, "/v1/views/run", map[string]interface{}{"view": "my inbox"})
+	if code != http.StatusNotFound {
+		t.Fatalf("expected deleted view to be gone, got %d", code)
+	}
+}
+
+func TestDaemonActorsUpdatePartial(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	if _, err := server.db.Exec(`
+		INSERT INTO actors (uuid, id, slug, display_name, role)
+		VALUES ('00000000-0000-0000-0000-0000000000a2', 'A-00002', 'reviewer', 'Reviewer Bot', 'human')
+	`); err != nil {
+		t.Fatalf("failed to seed actor: %v", err)
+	}
+
+	// Omitted fields are untouched
+	code, resp := daemonPost(t, handler, "/v1/actors/update", map[string]interface{}{"actor": "reviewer", "role": "agent"})
+	if code != http.StatusOK {
+		t.Fatalf("actor update failed: %d %v", code, resp)
+	}
+	actor := resp["actor"].(map[string]interface{})
+	if actor["role"] != "agent" || actor["display_name"] != "Reviewer Bot" {
+		t.Fatalf("expected role change only, got %v", actor)
+	}
+
+	// An empty display_name clears it
+	code, resp = daemonPost(t, handler, "/v1/actors/update", map[string]interface{}{"actor": "reviewer", "display_name": ""})
+	if code != http.StatusOK {
+		t.Fatalf("actor update failed: %d %v", code, resp)
+	}
+	actor = resp["actor"].(map[string]interface{})
+	if _, ok := actor["display_name"]; ok {
+		t.Fatalf("expected display_name cleared, got %v", actor)
+	}
+
+	code, _ = daemonPost(t, handler, "/v1/actors/update", map[string]interface{}{"actor": "reviewer", "role": "overlord"})
+	if code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid role, got %d", code)
+	}
+	code, _ = daemonPost(t, handler, "/v1/actors/update", map[string]interface{}{"actor": "reviewer"})
+	if code != http.StatusBadRequest {
+		t.Fatalf("expected 400 with no fields, got %d", code)
+	}
+}
+
+func TestDaemonActorsDeactivate(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	if _, err := server.db.Exec(`
+		INSERT INTO actors (uuid, id, slug, role)
+		VALUES ('00000000-0000-0000-0000-0000000000a3', 'A-00003', 'retired-bot', 'agent')
+	`); err != nil {
+		t.Fatalf("failed to seed actor: %v", err)
+	}
+
+	code, resp := daemonPost(t, handler, "/v1/actors/deactivate", map[string]interface{}{"actor": "retired-bot"})
+	if code != http.StatusOK {
+		t.Fatalf("deactivate failed: %d %v", code, resp)
+	}
+	if resp["actor"].(map[string]interface{})["archived_at"] == nil {
+		t.Fatalf("expected archived_at to be set, got %v", resp["actor"])
+	}
+
+	listSlugs := func(body map[string]interface{}) []string {
+		code, resp := daemonPost(t, handler, "/v1/actors/list", body)
+		if code != http.StatusOK {
+			t.Fatalf("actors list failed: %d %v", code, resp)
+		}
+		var slugs []string
+		for _, item := range resp["actors"].([]interface{}) {
+			slugs = append(slugs, item.(map[string]interface{})["slug"].(string))
+		}
+		return slugs
+	}
+	if slugs := listSlugs(map[string]interface{}{}); slices.Contains(slugs, "retired-bot") {
+		t.Fatalf("expected inactive actor hidden from default list, got %v", slugs)
+	}
+	if slugs := listSlugs(map[string]interface{}{"include_inactive": true}); !slices.Contains(slugs, "retired-bot") {
+		t.Fatalf("expected inactive actor with include_inactive, got %v", slugs)
+	}
+
+	// Inactive actors still resolve for attribution
+	resolved, err := actors.NewResolver(server.db.DB).Resolve("retired-bot")
+	if err != nil || resolved != "00000000-0000-0000-0000-0000000000a3" {
+		t.Fatalf("expected inactive actor to resolve, got %q (%v)", resolved, err)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/actors/reactivate", map[string]interface{}{"actor": "retired-bot"})
+	if code != http.StatusOK {
+		t.Fatalf("reactivate failed: %d %v", code, resp)
+	}
+	if _, ok := resp["actor"].(map[string]interface{})["archived_at"]; ok {
+		t.Fatalf("expected archived_at cleared, got %v", resp["actor"])
+	}
+}
+
+func TestDaemonSectionsCRUD(t *testing.T) {
+	_, handler := newTestDaemon(t)
+
+	var ids []string
+	for _, slug := range []string{"backlog", "doing", "done"} {
+		code, resp := daemonPost(t, handler, "/v1/sections/create", map[string]interface{}{
+			"project":    "inbox",
+			"slug":       slug,
+			"is_default": true,
+		})
+		if code != http.StatusOK {
+			t.Fatalf("section create failed: %d %v", code, resp)
+		}
+		ids = append(ids, resp["section"].(map[string]interface{})["id"].(string))
+	}
+
+	code, resp := daemonPost(t, handler, "/v1/sections/create", map[string]interface{}{
+		"project":   "inbox",
+		"slug":      "negative",
+		"wip_limit": -1,
+	})
+	if code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for negative wip_limit, got %d %v", code, resp)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/sections/update", map[string]interface{}{
+		"project": "inbox",
+		"section": "doing",
+		"fields":  map[string]interface{}{"wip_limit": 0},
+	})
+	if code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for zero wip_limit, got %d %v", code, resp)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/sections/update", map[string]interface{}{
+		"project": "inbox",
+		"section": "doing",
+		"fields":  map[string]interface{}{"title": "In Progress", "role": "active", "wip_limit": 2},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("section update failed: %d %v", code, resp)
+	}
+	section := resp["section"].(map[string]interface{})
+	if section["title"] != "In Progress" || section["wip_limit"].(float64) != 2 {
+		t.Fatalf("unexpected updated section: %v", section)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/sections/reorder", map[string]interface{}{
+		"project":  "inbox",
+		"sections": []string{ids[2], "backlog"},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("section reorder failed: %d %v", code, resp)
+	}
+
+	_, resp = daemonPost(t, handler, "/v1/sections/list", map[string]interface{}{"project": "inbox"})
+	sections := resp["sections"].([]interface{})
+	var order []string
+	defaults := 0
+	for _, raw := range sections {
+		sec := raw.(map[string]interface{})
+		order = append(order, sec["slug"].(string))
+		if sec["is_default"] == true {
+			defaults++
+		}
+	}
+	if strings.Join(order, ",") != "done,backlog,doing" {
+		t.Fatalf("unexpected section order: %v", order)
+	}
+	if defaults != 1 {
+		t.Fatalf("expected exactly one default section, got %d", defaults)
+	}
+
+	code, _ = daemonPost(t, handler, "/v1/sections/update", map[string]interface{}{
+		"project": "inbox",
+		"section": "missing",
+		"fields":  map[string]interface{}{"title": "x"},
+	})
+	if code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown section, got %d", code)
+	}
+}
+
+// writeAttachmentBundle writes a bundle with a single legacy-layout
+// attachment for taskUUID and returns its directory.
+func writeAttachmentBundle(t *testing.T, taskUUID string) string {
+	t.Helper()
+	bundleDir := t.TempDir()
+	manifest := `{"machine_interface_version": 1, "timestamp": "2025-11-19T12:00:00Z", "with_attachments": true}`
+	if err := os.WriteFile(filepath.Join(bundleDir, "manifest.json"), []byte(manifest), 0644); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+	taskDir := filepath.Join(bundleDir, "attachments", taskUUID)
+	if err := os.MkdirAll(taskDir, 0755); err != nil {
+		t.Fatalf("failed to create attachment dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(taskDir, "notes.txt"), []byte("bundle notes"), 0644); err != nil {
+		t.Fatalf("failed to write attachment: %v", err)
+	}
+	return bundleDir
+}
+
+func TestDaemonBundleApplyRejectsNewerInterfaceVersion(t *testing.T) {
+	_, handler := newTestDaemon(t)
+
+	bundleDir := t.TempDir()
+	manifest := fmt.Sprintf(`{"machine_interface_version": %d, "timestamp": "2025-11-19T12:00:00Z"}`, bundle.MachineInterfaceVersion+1)
+	if err := os.WriteFile(filepath.Join(bundleDir, "manifest.json"), []byte(manifest), 0644); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+
+	code, resp := daemonPost(t, handler, "/v1/bundle/apply", map[string]interface{}{"from": bundleDir})
+	want := fmt.Sprintf("bundle machine_interface_version (%d) doesn't match current version (%d)",
+		bundle.MachineInterfaceVersion+1, bundle.MachineInterfaceVersion)
+	if code != http.StatusBadRequest || resp["message"] != want {
+		t.Fatalf("expected 400 %q, got %d %v", want, code, resp)
+	}
+}
+
+func TestDaemonBundleApplyReattachesInProcess(t *testing.T) {
+	server, handler := newTestDaemon(t)
+	server.cfg.AttachDir = t.TempDir()
+	// No wrkq binary is reachable, so reattachment must not shell out.
+	t.Setenv("PATH", "")
+
+	taskUUID := "550e8400-e29b-41d4-a716-446655440601"
+	if _, err := server.db.Exec(`
+		INSERT INTO tasks (uuid, slug, title, project_uuid, state, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES (?, 'with-files', 'With Files', '00000000-0000-0000-0000-000000000002', 'open',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001')
+	`, taskUUID); err != nil {
+		t.Fatalf("failed to insert task: %v", err)
+	}
+	bundleDir := writeAttachmentBundle(t, taskUUID)
+
+	for i, wantAdded := range []float64{1, 0} {
+		code, resp := daemonPost(t, handler, "/v1/bundle/apply", map[string]interface{}{"from": bundleDir})
+		if code != http.StatusOK || resp["success"] != true {
+			t.Fatalf("apply %d: expected success, got %d %v", i+1, code, resp)
+		}
+		if resp["attachments_added"] != wantAdded {
+			t.Fatalf("apply %d: expected %v attachments added, got %v", i+1, wantAdded, resp["attachments_added"])
+		}
+	}
+
+	var count int
+	var filename, relPath, checksum string
+	var size int64
+	if err := server.db.QueryRow(`
+		SELECT COUNT(*), MAX(filename), MAX(relative_path), MAX(size_bytes), MAX(checksum) FROM attachments WHERE task_uuid = ?
+	`, taskUUID).Scan(&count, &filename, &relPath, &size, &checksum); err != nil {
+		t.Fatalf("failed to query attachments: %v", err)
+	}
+	if count != 1 || filename != "notes.txt" || size != int64(len("bundle notes")) || checksum == "" {
+		t.Fatalf("unexpected attachment rows: count=%d filename=%s size=%d checksum=%q", count, filename, size, checksum)
+	}
+	data, err := os.ReadFile(filepath.Join(server.cfg.AttachDir, relPath))
+	if err != nil || string(data) != "bundle notes" {
+		t.Fatalf("expected attachment file in attach dir, got %q (%v)", data, err)
+	}
+
+	var events int
+	if err := server.db.QueryRow(`
+		SELECT COUNT(*) FROM event_log WHERE event_type = 'attachment.created'
+	`).Scan(&events); err != nil {
+		t.Fatalf("failed to count events: %v", err)
+	}
+	if events != 1 {
+		t.Fatalf("expected 1 attachment.created event, got %d", events)
+	}
+}
+
+func TestDaemonBundleApplyAttachmentFailureRollsBack(t *testing.T) {
+	server, handler := newTestDaemon(t)
+	server.cfg.AttachDir = t.TempDir()
+
+	bundleDir := writeAttachmentBundle(t, "550e8400-e29b-41d4-a716-446655440699")
+	if err := os.WriteFile(filepath.Join(bundleDir, "containers.txt"), []byte("fresh\n"), 0644); err != nil {
+		t.Fatalf("failed to write containers.txt: %v", err)
+	}
+
+	code, resp := daemonPost(t, handler, "/v1/bundle/apply", map[string]interface{}{"from": bundleDir})
+	if code != http.StatusOK || resp["success"] != false {
+		t.Fatalf("expected failed apply, got %d %v", code, resp)
+	}
+
+	var containers, attachments int
+	if err := server.db.QueryRow(`SELECT COUNT(*) FROM containers WHERE slug = 'fresh'`).Scan(&containers); err != nil {
+		t.Fatalf("failed to count containers: %v", err)
+	}
+	if err := server.db.QueryRow(`SELECT COUNT(*) FROM attachments`).Scan(&attachments); err != nil {
+		t.Fatalf("failed to count attachments: %v", err)
+	}
+	if containers != 0 || attachments != 0 {
+		t.Fatalf("expected apply to roll back, got containers=%d attachments=%d", containers, attachments)
+	}
+}
+
+func TestDaemonResolveSelectors(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	if _, err := server.db.Exec(`
+		INSERT INTO containers (uuid, id, slug, title, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f1', 'P-00002', 'portal', 'Portal',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+		INSERT INTO containers (uuid, id, slug, title, parent_uuid, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f2', 'P-00003', 'auth', 'Auth', '00000000-0000-0000-0000-0000000000f1',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+		INSERT INTO containers (uuid, id, slug, title, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f3', 'P-00004', 'platform', 'Platform',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+		INSERT INTO containers (uuid, id, slug, title, parent_uuid, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000f4', 'P-00005', 'auth', 'Platform auth', '00000000-0000-0000-0000-0000000000f3',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001');
+	`); err != nil {
+		t.Fatalf("failed to seed containers: %v", err)
+	}
+	code, created := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "portal/auth/login"})
+	if code != http.StatusOK {
+		t.Fatalf("create failed: %d %v", code, created)
+	}
+	taskID := created["task"].(map[string]interface{})["id"].(string)
+
+	code, resp := daemonPostWithHeaders(t, handler, "/v1/resolve", map[string]string{"X-Wrkq-Cwd": "portal/auth"}, map[string]interface{}{
+		"selectors": []string{
+			"portal/auth/login", "t:" + taskID, "./login", "P-00002",
+			"00000000-0000-0000-0000-0000000000f2", "auth", "T-99999", "portal/missing", "c:C-00001",
+		},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("resolve failed: %d %v", code, resp)
+	}
+	results, _ := resp["results"].(map[string]interface{})
+	entry := func(sel string) map[string]interface{} {
+		t.Helper()
+		e, ok := results[sel].(map[string]interface{})
+		if !ok {
+			t.Fatalf("no result for %q: %v", sel, results)
+		}
+		return e
+	}
+
+	for _, sel := range []string{"portal/auth/login", "t:" + taskID, "./login"} {
+		if e := entry(sel); e["type"] != "task" || e["id"] != taskID || e["path"] != "portal/auth/login" || e["error"] != nil {
+			t.Errorf("unexpected result for %q: %v", sel, e)
+		}
+	}
+	if e := entry("P-00002"); e["type"] != "container" || e["uuid"] != "00000000-0000-0000-0000-0000000000f1" || e["path"] != "portal" {
+		t.Errorf("unexpected result for P-00002: %v", e)
+	}
+	if e := entry("00000000-0000-0000-0000-0000000000f2"); e["type"] != "container" || e["id"] != "P-00003" || e["path"] != "portal/auth" {
+		t.Errorf("unexpected result for container uuid: %v", e)
+	}
+
+	ambiguous := entry("auth")
+	if candidates, _ := ambiguous["candidates"].([]interface{}); ambiguous["error"] == nil || ambiguous["type"] != nil || len(candidates) != 2 {
+		t.Errorf("expected an ambiguous error with two candidates, got %v", ambiguous)
+	}
+	for _, sel := range []string{"T-99999", "portal/missing", "c:C-00001"} {
+		if e := entry(sel); e["error"] == nil || e["uuid"] != nil {
+			t.Errorf("expected an error for %q, got %v", sel, e)
+		}
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/resolve", map[string]interface{}{"selectors": []string{}})
+	if code != http.StatusBadRequest {
+		t.Errorf("expected 400 for no selectors, got %d %v", code, resp)
+	}
+}
+
+func TestDaemonTaskKindValidation(t *testing.T) {
+	server, handler := newTestDaemon(t)
+
+	code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{
+		"path": "inbox/outage", "fields": map[string]interface{}{"kind": "incident"},
+	})
+	if code != http.StatusBadRequest || resp["code"] != "validation" {
+		t.Fatalf("expected a validation error for an unknown kind, got %d %v", code, resp)
+	}
+
+	if _, err := server.db.Exec(`INSERT INTO task_kinds (kind) VALUES ('incident')`); err != nil {
+		t.Fatalf("failed to add kind: %v", err)
+	}
+	code, resp = daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{
+		"path": "inbox/outage", "fields": map[string]interface{}{"kind": "incident"},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("create with a configured kind failed: %d %v", code, resp)
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/tasks/update", map[string]interface{}{
+		"selector": "inbox/outage", "fields": map[string]interface{}{"kind": "story"},
+	})
+	if code != http.StatusBadRequest || resp["code"] != "validation" {
+		t.Fatalf("expected a validation error updating to an unknown kind, got %d %v", code, resp)
+	}
+	code, resp = daemonPost(t, handler, "/v1/tasks/update", map[string]interface{}{
+		"selector": "inbox/outage", "fields": map[string]interface{}{"kind": "bug"},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("update to a configured kind failed: %d %v", code, resp)
+	}
+	var kind string
+	if err := server.db.QueryRow(`SELECT kind FROM tasks WHERE slug = 'outage'`).Scan(&kind); err != nil || kind != "bug" {
+		t.Errorf("expected kind bug, got %q (%v)", kind, err)
+	}
+}
+
+func TestDaemonTasksSubtasks(t *testing.T) {
+	_, handler := newTestDaemon(t)
+
+	code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "inbox/parent"})
+	if code != http.StatusOK {
+		t.Fatalf("create parent failed: %d %v", code, resp)
+	}
+	parentUUID := resp["task"].(map[string]interface{})["uuid"].(string)
+	for _, sub := range []struct {
+		path     string
+		priority int
+	}{{"inbox/later", 4}, {"inbox/urgent", 1}} {
+		code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{
+			"path": sub.path, "fields": map[string]interface{}{"parent_task": "inbox/parent", "kind": "subtask", "priority": sub.priority},
+		})
+		if code != http.StatusOK {
+			t.Fatalf("create %s failed: %d %v", sub.path, code, resp)
+		}
+	}
+	if code, resp := daemonPost(t, handler, "/v1/tasks/create", map[string]interface{}{"path": "inbox/unrelated"}); code != http.StatusOK {
+		t.Fatalf("create unrelated failed: %d %v", code, resp)
+	}
+
+	checkSubtasks := func(label string, raw interface{}) {
+		t.Helper()
+		subtasks, _ := raw.([]interface{})
+		if len(subtasks) != 2 {
+			t.Fatalf("%s: expected 2 subtasks, got %v", label, raw)
+		}
+		for i, slug := range []string{"urgent", "later"} {
+			sub := subtasks[i].(map[string]interface{})
+			if sub["slug"] != slug || sub["parent_task_uuid"] != parentUUID {
+				t.Errorf("%s: subtask %d = %v, want %s under %s", label, i, sub, slug, parentUUID)
+			}
+		}
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/tasks/subtasks", map[string]interface{}{"selector": "inbox/parent", "recursive": true})
+	if code != http.StatusOK {
+		t.Fatalf("subtasks failed: %d %v", code, resp)
+	}
+	if resp["parent_uuid"] != parentUUID {
+		t.Errorf("expected parent_uuid %s, got %v", parentUUID, resp["parent_uuid"])
+	}
+	checkSubtasks("tasks/subtasks", resp["subtasks"])
+
+	code, resp = daemonPost(t, handler, "/v1/tasks/get", map[string]interface{}{"selector": "inbox/parent", "include_subtasks": true})
+	if code != http.StatusOK {
+		t.Fatalf("get failed: %d %v", code, resp)
+	}
+	checkSubtasks("tasks/get", resp["task"].(map[string]interface{})["subtasks"])
+
+	code, resp = daemonPost(t, handler, "/v1/tasks/get", map[string]interface{}{"selector": "inbox/parent"})
+	if code != http.StatusOK {
+		t.Fatalf("get failed: %d %v", code, resp)
+	}
+	if _, ok := resp["task"].(map[string]interface{})["subtasks"]; ok {
+		t.Errorf("expected no subtasks unless include_subtasks is set")
+	}
+
+	code, resp = daemonPost(t, handler, "/v1/tasks/subtasks", map[string]interface{}{"selector": "inbox/unrelated"})
+	if subtasks, ok := resp["subtasks"].([]interface{}); code != http.StatusOK || !ok || len(subtasks) != 0 {
+		t.Errorf("expected an empty list for a task without subtasks, got %d %v", code, resp)
+	}
+}
+
+func TestDaemonScopedTokens(t *testing.T) {
+	server, handler := newTestDaemon(t)
+	server.token = "legacy-secret"
+
+	// With no tokens the shared token still works
+	code, resp := daemonPostWithHeaders(t, handler, "/v1/tasks/create",
+		map[string]string{"Authorization": "Bearer legacy-secret"},
+		map[string]interface{}{"path": "inbox/legacy-task"})
+	if code != http.StatusOK {
+		t.Fatalf("legacy create failed: %d %v", code, resp)
+	}
+
+	if _, err := server.db.Exec(`
+		INSERT INTO containers (uuid, id, slug, title, created_by_actor_uuid, updated_by_actor_uuid, etag)
+		VALUES ('00000000-0000-0000-0000-0000000000e1', 'P-00002', 'portal', 'Portal',
+			'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001', 1)
+	`); err != nil {
+		t.Fatalf("failed to seed container: %v", err)
+	}
+	if _, err := server.db.Exec(`INSERT INTO actors (uuid, id, slug, role) VALUES ('00000000-0000-0000-0000-0000000000e2', 'A-00002', 'ci-bot', 'agent')`); err != nil {
+		t.Fatalf("failed to seed actor: %v", err)
+	}
+	tokens := store.New(server.db).Tokens
+	_, reader, err := tokens.Create("00000000-0000-0000-0000-000000000001", "reader", "read", "")
+	if err != nil {
+		t.Fatalf("failed to create read token: %v", err)
+	}
+	_, writer, err := tokens.Create("00000000-0000-0000-0000-0000000000e2", "writer", "write", "")
+	if err != nil {
+		t.Fatalf("failed to create write token: %v", err)
+	}
+	_, portal, err := tokens.Create("00000000-0000-0000-0000-0000000000e2", "portal-only", "write", "00000000-0000-0000-0000-0000000000e1")
+	if err != nil {
+		t.Fatalf("failed to create project token: %v", err)
+	}
+	bearer := func(token string) map[string]string {
+		return map[string]string{"Authorization": "Bearer " + token, "X-Wrkq-Actor": ""}
+	}
+
+	// Once tokens exist the shared token is no longer accepted
+	code, resp = daemonPostWithHeaders(t, handler, "/v1/tasks/list", bearer("legacy-secret"), map[string]interface{}{})
+	if code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for the shared token, got %d %v", code, resp)
+	}
+
+	// A read-scoped token can read but not write
+	code, resp = daemonPostWithHeaders(t, handler, "/v1/tasks/list", bearer(reader), map[string]interface{}{})
+	if code != http.StatusOK {
+		t.Fatalf("expected read token to list tasks, got %d %v", code, resp)
+	}
+	code, resp = daemonPostWithHeaders(t, handler, "/v1/tasks/create", bearer(reader), map[string]interface{}{"path": "inbox/denied"})
+	if code != http.StatusForbidden || resp["code"] != "forbidden" {
+		t.Fatalf("expected 403 forbidden for a write with a read token, got %d %v", code, resp)
+	}
+	var count int
+	if err := server.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE slug = 'denied'").Scan(&count); err != nil || count != 0 {
+		t.Fatalf("expected the denied write to create nothing, got %d (%v)", count, err)
+	}
+
+	// Requests act as the token's actor, and admin endpoints need admin
+	code, resp = daemonPostWithHeaders(t, handler, "/v1/tasks/create", bearer(writer), map[string]interface{}{"path": "inbox/by-bot"})
+	if code != http.StatusOK {
+		t.Fatalf("write token create failed: %d %v", code, resp)
+	}
+	var creator string
+	if err := server.db.QueryRow("SELECT created_by_actor_uuid FROM tasks WHERE slug = 'by-bot'").Scan(&creator); err != nil {
+		t.Fatalf("failed to read creator: %v", err)
+	}
+	if creator != "00000000-0000-0000-0000-0000000000e2" {
+		t.Errorf("expected the task created as the token's actor, got %s", creator)
+	}
+	code, resp = daemonPostWithHeaders(t, handler, "/v1/actors/create", bearer(writer), map[string]interface{}{"slug": "someone"})
+	if code != http.StatusForbidden {
+		t.Errorf("expected 403 creating an actor with a write token, got %d %v", code, resp)
+	}
+	code, resp = daemonPostWithHeaders(t, handler, "/v1/tasks/create", bearer(writer), map[string]interface{}{"path": "inbox/over-limit", "bypass_wip_limit": true})
+	if code != http.StatusForbidden {
+		t.Errorf("expected 403 bypassing the WIP limit with a write token, got %d %v", code, resp)
+	}
+
+	// A project token only sees and touches its project
+	code, resp = daemonPostWithHeaders(t, handler, "/v1/tasks/create", bearer(portal), map[string]interface{}{"path": "portal/mine"})
+	if code != http.StatusOK {
+		t.Fatalf("project token create failed: %d %v", code, resp)
+	}
+	code, resp = daemonPostWithHeaders(t, handler, "/v1/tasks/create", bearer(portal), map[string]interface{}{"path": "inbox/theirs"})
+	if code != http.StatusForbidden {
+		t.Errorf("expected 403 creating outside the project, got %d %v", code, resp)
+	}
+	code, resp = daemonPostWithHeaders(t, handler, "/v1/tasks/get", bearer(portal), map[string]interface{}{"selector": "inbox/legacy-task"})
+	if code != http.StatusForbidden {
+		t.Errorf("expected 403 reading outside the project, got %d %v", code, resp)
+	}
+	code, resp = daemonPostWithHeaders(t, handler, "/v1/tasks/list", bearer(portal), map[string]interface{}{})
+	if code != http.StatusOK {
+		t.Fatalf("project token list failed: %d %v", code, resp)
+	}
+	if listed, _ := resp["tasks"].([]interface{}); len(listed) != 1 || listed[0].(map[string]interface{})["slug"] != "mine" {
+		t.Errorf("expected only portal/mine listed, got %v", resp["tasks"])
+	}
+	code, resp = daemonPostWithHeaders(t, handler, "/v1/tasks/list", bearer(portal), map[string]interface{}{"path_prefix": []string{"inbox"}})
+	if code != http.StatusForbidden {
+		t.Errorf("expected 403 listing outside the project, got %d %v", code, resp)
+	}
+	code, resp = daemonPostWithHeaders(t, handler, "/v1/events/list", bearer(portal), map[string]interface{}{})
+	if code != http.StatusForbidden {
+		t.Errorf("expected 403 reading the event log with a project token, got %d %v", code, resp)
+	}
+
+	// Revoking every token returns to legacy mode
+	for _, name := range []string{"reader", "writer", "portal-only"} {
+		if err := tokens.Revoke(name); err != nil {
+			t.Fatalf("failed to revoke %s: %v", name, err)
+		}
+	}
+	code, resp = daemonPostWithHeaders(t, handler, "/v1/tasks/list", bearer("legacy-secret"), map[string]interface{}{})
+	if code != http.StatusOK {
+		t.Errorf("expected the shared token accepted again, got %d %v", code, resp)
+	}
+	code, _ = daemonPostWithHeaders(t, handler, "/v1/tasks/list", bearer(reader), map[string]interface{}{})
+	if code != http.StatusUnauthorized {
+		t.Errorf("expected a revoked token refused, got %d", code)
+	}
+}
+
+func TestDaemonTokenActorImpersonation(t *testing.T) {
+	server, handler := newTestDaemon(t)
+	const botUUID = "00000000-0000-0000-0000-0000000000e2"
+	if _, err := server.db.Exec(`INSERT INTO actors (uuid, id, slug, role) VALUES ('` + botUUID + `', 'A-00002', 'ci-bot', 'agent')`); err != nil {
+		t.Fatalf("failed to seed actor: %v", err)
+	}
+	tokens := store.New(server.db).Tokens
+	_, writer, err := tokens.Create(botUUID, "writer", "write", "")
+	if err != nil {
+		t.Fatalf("failed to create write token: %v", err)
+	}
+	_, admin, err := tokens.Create(botUUID, "bridge", "admin", "")
+	if err != nil {
+		t.Fatalf("failed to create admin token: %v", err)
+	}
+
+	createdBy := func(slug string) string {
+		t.Helper()
+		var actorUUID string
+		if err := server.db.QueryRow("SELECT created_by_actor_uuid FROM tasks WHERE slug = ?", slug).Scan(&actorUUID); err != nil {
+			t.Fatalf("failed to read creator of %s: %v", slug, err)
+		}
+		return actorUUID
+	}
+	create := func(token, actor, slug string) (int, map[string]interface{}) {
+		return daemonPostWithHeaders(t, handler, "/v1/tasks/create",
+			map[string]string{"Authorization": "Bearer " + token, "X-Wrkq-Actor": actor},
+			map[string]interface{}{"path": "inbox/" + slug})
+	}
+
+	// A non-admin token can't attribute changes to someone else
+	code, resp := create(writer, "test-user", "spoofed")
+	if code != http.StatusForbidden || resp["code"] != "forbidden" {
+		t.Fatalf("expected 403 impersonating with a write token, got %d %v", code, resp)
+	}
+	code, resp = create(writer, "new-actor", "spoofed")
+	if code != http.StatusForbidden {
+		t.Fatalf("expected 403 naming an unknown actor with a write token, got %d %v", code, resp)
+	}
+	var count int
+	if err := server.db.QueryRow("SELECT COUNT(*) FROM actors WHERE slug = 'new-actor'").Scan(&count); err != nil || count != 0 {
+		t.Fatalf("expected no actor auto-created in token mode, got %d (%v)", count, err)
+	}
+
+	// Naming its own actor, or no actor, is fine
+	if code, resp = create(writer, "ci-bot", "own-name"); code != http.StatusOK {
+		t.Fatalf("expected the token's own actor accepted, got %d %v", code, resp)
+	}
+	if got := createdBy("own-name"); got != botUUID {
+		t.Errorf("expected own-name created by the token's actor, got %s", got)
+	}
+	if code, resp = create(writer, "", "no-header"); code != http.StatusOK {
+		t.Fatalf("expected a request without X-Wrkq-Actor accepted, got %d %v", code, resp)
+	}
+	if got := createdBy("no-header"); got != botUUID {
+		t.Errorf("expected no-header created by the token's actor, got %s", got)
+	}
+
+	// An admin token may act on behalf of an existing actor
+	if code, resp = create(admin, "test-user", "on-behalf"); code != http.StatusOK {
+		t.Fatalf("expected an admin token to act as test-user, got %d %v", code, resp)
+	}
+	if got := createdBy("on-behalf"); got != "00000000-0000-0000-0000-000000000001" {
+		t.Errorf("expected on-behalf created by test-user, got %s", got)
+	}
+	if code, resp = create(admin, "new-actor", "unknown"); code == http.StatusOK {
+		t.Errorf("expected an admin token naming an unknown actor to fail, got %d %v", code, resp)
+	}
+}
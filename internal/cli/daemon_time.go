@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/lherron/wrkq/internal/store"
+)
+
+type timeLogRequest struct {
+	Selector  string `json:"selector"`
+	Minutes   int    `json:"minutes"`
+	Note      string `json:"note,omitempty"`
+	StartedAt string `json:"started_at,omitempty"`
+}
+
+// handleTimeLog logs time against a task as the calling actor and responds
+// with the new entry and the task's updated total.
+func (s *daemonServer) handleTimeLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req timeLogRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Selector == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("selector required"))
+		return
+	}
+
+	taskUUID, _, err := s.resolveTask(r, req.Selector)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	actorUUID, err := s.resolveActorUUID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	timeEntries := store.New(s.db).TimeEntries
+	entry, err := timeEntries.Log(actorUUID, taskUUID, store.LogTimeParams{
+		Minutes:   req.Minutes,
+		Note:      req.Note,
+		StartedAt: req.StartedAt,
+	})
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	total, err := timeEntries.Total(taskUUID)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"time_entry": entry,
+		"total":      total,
+	})
+}
+
+type timeTotalRequest struct {
+	Selector       string `json:"selector"`
+	IncludeEntries bool   `json:"include_entries,omitempty"`
+}
+
+// handleTimeTotal reports the time logged against a task next to its
+// estimate, with the individual entries when include_entries is set.
+func (s *daemonServer) handleTimeTotal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req timeTotalRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Selector == "" {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("selector required"))
+		return
+	}
+
+	taskUUID, friendlyID, err := s.resolveTask(r, req.Selector)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	var estimate *int64
+	if err := s.db.QueryRow("SELECT estimate_minutes FROM tasks WHERE uuid = ?", taskUUID).Scan(&estimate); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	timeEntries := store.New(s.db).TimeEntries
+	total, err := timeEntries.Total(taskUUID)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	resp := map[string]interface{}{
+		"task_id":          friendlyID,
+		"task_uuid":        taskUUID,
+		"estimate_minutes": estimate,
+		"total":            total,
+	}
+	if req.IncludeEntries {
+		entries, err := timeEntries.List(taskUUID)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		resp["entries"] = entries
+	}
+
+	s.writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/lherron/wrkq/internal/domain"
+)
+
+// errForbidden marks a request its API token is not allowed to make. It is
+// reported as 403 whatever status the handler asked for.
+var errForbidden = errors.New("forbidden")
+
+type apiTokenContextKey struct{}
+
+// requestAPIToken returns the API token withAuth authenticated the request
+// with, or nil in legacy single-token mode.
+func requestAPIToken(r *http.Request) *domain.APIToken {
+	token, _ := r.Context().Value(apiTokenContextKey{}).(*domain.APIToken)
+	return token
+}
+
+func withAPIToken(r *http.Request, token *domain.APIToken) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), apiTokenContextKey{}, token))
+}
+
+// checkScope reports whether the request's token may make a request that
+// needs scope, writing a 403 if not. Legacy mode allows everything.
+func (s *daemonServer) checkScope(w http.ResponseWriter, r *http.Request, scope domain.TokenScope) bool {
+	token := requestAPIToken(r)
+	if token == nil || token.Scope.Allows(scope) {
+		return true
+	}
+	s.writeError(w, http.StatusForbidden, fmt.Errorf("%w: token %q has %s scope, this endpoint needs %s", errForbidden, token.Name, token.Scope, scope))
+	return false
+}
+
+// withScope guards an endpoint that needs more than the write scope
+// withWriteLimit already requires, such as managing actors.
+func (s *daemonServer) withScope(scope domain.TokenScope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.checkScope(w, r, scope) {
+			return
+		}
+		next(w, r)
+	}
+}
+
+// withAllProjects guards an endpoint that reads or writes across the whole
+// database, such as the event log, which a token restricted to a project
+// can't be confined to.
+func (s *daemonServer) withAllProjects(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token := requestAPIToken(r); token != nil && token.ProjectUUID != nil {
+			s.writeError(w, http.StatusForbidden, fmt.Errorf("%w: token %q is restricted to a project", errForbidden, token.Name))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// tokenProjectPath returns the path of the container the request's token is
+// restricted to, or "" if it is not restricted.
+func (s *daemonServer) tokenProjectPath(r *http.Request) (string, error) {
+	token := requestAPIToken(r)
+	if token == nil || token.ProjectUUID == nil {
+		return "", nil
+	}
+	path, err := s.lookups(r).containerPath(*token.ProjectUUID)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up token project: %w", err)
+	}
+	return path, nil
+}
+
+// pathInProject reports whether a container or task path lies in the
+// subtree rooted at projectPath.
+func pathInProject(path, projectPath string) bool {
+	path = strings.Trim(path, "/")
+	return path == projectPath || strings.HasPrefix(path, projectPath+"/")
+}
+
+// checkContainerInScope fails with errForbidden when the request's token is
+// restricted to a project and containerUUID is outside it.
+func (s *daemonServer) checkContainerInScope(r *http.Request, containerUUID string) error {
+	token := requestAPIToken(r)
+	if token == nil || token.ProjectUUID == nil {
+		return nil
+	}
+	var inside int
+	err := s.db.QueryRow(`
+		WITH RECURSIVE ancestors(uuid) AS (
+			SELECT ?
+			UNION
+			SELECT c.parent_uuid FROM containers c JOIN ancestors a ON c.uuid = a.uuid
+			WHERE c.parent_uuid IS NOT NULL
+		)
+		SELECT COUNT(*) FROM ancestors WHERE uuid = ?
+	`, containerUUID, *token.ProjectUUID).Scan(&inside)
+	if err != nil {
+		return fmt.Errorf("failed to check token project: %w", err)
+	}
+	if inside == 0 {
+		return fmt.Errorf("%w: outside the project token %q is restricted to", errForbidden, token.Name)
+	}
+	return nil
+}
+
+// checkTaskInScope is checkContainerInScope for the container a task is in.
+func (s *daemonServer) checkTaskInScope(r *http.Request, taskUUID string) error {
+	if token := requestAPIToken(r); token == nil || token.ProjectUUID == nil {
+		return nil
+	}
+	var projectUUID string
+	if err := s.db.QueryRow("SELECT project_uuid FROM tasks WHERE uuid = ?", taskUUID).Scan(&projectUUID); err != nil {
+		return fmt.Errorf("failed to look up task project: %w", err)
+	}
+	return s.checkContainerInScope(r, projectUUID)
+}
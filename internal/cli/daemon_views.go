@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/lherron/wrkq/internal/store"
+)
+
+type viewsListRequest struct {
+	All bool `json:"all,omitempty"` // include views owned by other actors
+}
+
+func (s *daemonServer) handleViewsList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req viewsListRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ownerUUID := ""
+	if !req.All {
+		actorUUID, err := s.resolveActorUUID(r)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		ownerUUID = actorUUID
+	}
+
+	views, err := store.New(s.db).Views.List(ownerUUID)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"views": views,
+	})
+}
+
+type viewsCreateRequest struct {
+	Name   string          `json:"name"`
+	Filter json.RawMessage `json:"filter,omitempty"` // tasks/list request body
+}
+
+func (s *daemonServer) handleViewsCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req viewsCreateRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	filter, err := parseViewFilter(req.Filter)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	// Pagination belongs to each run, not to the saved filter
+	filter.Cursor = ""
+	data, err := json.Marshal(filter)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	actorUUID, err := s.resolveActorUUID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	view, err := store.New(s.db).Views.Create(actorUUID, req.Name, data)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"view": view,
+	})
+}
+
+type viewsDeleteRequest struct {
+	View string `json:"view"` // name (own views) or UUID
+}
+
+func (s *daemonServer) handleViewsDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req viewsDeleteRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	actorUUID, err := s.resolveActorUUID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	view, err := store.New(s.db).Views.Delete(actorUUID, req.View)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"deleted": view.UUID,
+	})
+}
+
+type viewsRunRequest struct {
+	View   string `json:"view"` // name (own views) or UUID
+	Limit  int    `json:"limit,omitempty"`
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// handleViewsRun executes a saved filter exactly as /v1/tasks/list would,
+// resolving its selectors against the current tree.
+func (s *daemonServer) handleViewsRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	var req viewsRunRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	actorUUID, err := s.resolveActorUUID(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	view, err := store.New(s.db).Views.Resolve(actorUUID, req.View)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	filter, err := parseViewFilter(view.Filter)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, fmt.Errorf("view %s has an invalid filter: %w", view.Name, err))
+		return
+	}
+	if req.Limit > 0 {
+		filter.Limit = req.Limit
+	}
+	filter.Cursor = req.Cursor
+
+	opts, err := s.taskFindOptions(r, filter)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	results, hasMore, err := findTasks(s.db, opts, false)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var nextCursor string
+	if hasMore && len(results) > 0 {
+		nextCursor, _ = nextTaskCursor(results[len(results)-1], opts)
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"view":        view,
+		"tasks":       results,
+		"next_cursor": nextCursor,
+	})
+}
+
+// parseViewFilter decodes a saved filter, rejecting fields tasks/list
+// doesn't accept so typos fail at save time rather than silently matching
+// everything.
+func parseViewFilter(raw json.RawMessage) (tasksListRequest, error) {
+	var filter tasksListRequest
+	if len(raw) == 0 || string(raw) == "null" {
+		return filter, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&filter); err != nil {
+		return filter, fmt.Errorf("invalid view filter: %w", err)
+	}
+	return filter, nil
+}
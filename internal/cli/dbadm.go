@@ -65,11 +65,6 @@ func runDBSnapshot(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("source database not found: %w", err)
 	}
 
-	// Validate output path doesn't exist (or confirm overwrite)
-	if _, err := os.Stat(dbSnapshotOut); err == nil {
-		return fmt.Errorf("output file already exists: %s (remove it first or choose a different path)", dbSnapshotOut)
-	}
-
 	// Open source database
 	sourceDB, err := db.Open(cfg.DBPath)
 	if err != nil {
@@ -79,10 +74,7 @@ func runDBSnapshot(cmd *cobra.Command, args []string) error {
 
 	// Perform online backup using VACUUM INTO
 	// This creates a clean, optimized copy without WAL/SHM files
-	_, err = sourceDB.Exec(fmt.Sprintf("VACUUM INTO '%s'", dbSnapshotOut))
-	if err != nil {
-		// Clean up failed snapshot
-		os.Remove(dbSnapshotOut)
+	if err := sourceDB.BackupTo(dbSnapshotOut); err != nil {
 		return fmt.Errorf("failed to create snapshot: %w", err)
 	}
 
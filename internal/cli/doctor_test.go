@@ -390,6 +390,117 @@ func TestDoctorDataIntegrityChecks(t *testing.T) {
 			t.Error("Expected duplicate_slugs check in results")
 		}
 	})
+
+	t.Run("orphaned comments detected", func(t *testing.T) {
+		database.Exec("PRAGMA foreign_keys = OFF")
+		defer database.Exec("PRAGMA foreign_keys = ON")
+
+		database.Exec(`
+			INSERT INTO comments (uuid, id, task_uuid, actor_uuid, body)
+			VALUES ('orphan-comment-uuid', 'C-09999', 'nonexistent-task', ?, 'Dangling')
+		`, actorUUID)
+		defer database.Exec(`DELETE FROM comments WHERE uuid = 'orphan-comment-uuid'`)
+
+		results := checkDataIntegrityAdm(database)
+
+		comments := findCheckAdm(results, "orphaned_comments")
+		if comments == nil || comments.Status != "warning" {
+			t.Errorf("Expected orphaned_comments warning, got %+v", comments)
+		}
+
+		fk := findCheckAdm(results, "foreign_key_check")
+		if fk == nil || fk.Status != "warning" {
+			t.Fatalf("Expected foreign_key_check warning, got %+v", fk)
+		}
+		if len(fk.Details) == 0 || fk.Details[0] != "comments -> tasks: 1 row(s)" {
+			t.Errorf("Expected comments -> tasks detail, got %v", fk.Details)
+		}
+	})
+
+	t.Run("no dangling foreign keys in healthy database", func(t *testing.T) {
+		results := checkDataIntegrityAdm(database)
+
+		for _, name := range []string{"orphaned_comments", "foreign_key_check"} {
+			result := findCheckAdm(results, name)
+			if result == nil || result.Status != "ok" {
+				t.Errorf("Expected %s to pass, got %+v", name, result)
+			}
+		}
+	})
+}
+
+func TestDoctorMigrationChecks(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	database, err := db.Open(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	t.Run("pending migrations reported as error", func(t *testing.T) {
+		result := findCheckAdm(checkMigrationsAdm(database), "migrations")
+		if result == nil || result.Status != "error" {
+			t.Fatalf("Expected migrations error on unmigrated database, got %+v", result)
+		}
+	})
+
+	t.Run("migrated database passes", func(t *testing.T) {
+		if err := database.Migrate(); err != nil {
+			t.Fatalf("Failed to migrate database: %v", err)
+		}
+
+		result := findCheckAdm(checkMigrationsAdm(database), "migrations")
+		if result == nil || result.Status != "ok" {
+			t.Errorf("Expected migrations check to pass, got %+v", result)
+		}
+	})
+}
+
+func TestDoctorSequenceDriftFix(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	database, err := db.Open(dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	database.Migrate()
+
+	// Explicit friendly ID bypasses the sequence trigger
+	if _, err := database.Exec(`
+		INSERT INTO actors (uuid, id, slug, role)
+		VALUES ('drift-actor-uuid', 'A-00042', 'drift-actor', 'human')
+	`); err != nil {
+		t.Fatalf("Failed to insert actor: %v", err)
+	}
+
+	result := findCheckAdm(checkSequenceDriftAdm(database), "sequence_drift")
+	if result == nil || result.Status != "error" {
+		t.Fatalf("Expected sequence_drift error, got %+v", result)
+	}
+
+	fixes := applyFixesAdm(database)
+	if len(fixes) == 0 {
+		t.Error("Expected --fix to report the repaired sequence")
+	}
+
+	result = findCheckAdm(checkSequenceDriftAdm(database), "sequence_drift")
+	if result == nil || result.Status != "ok" {
+		t.Errorf("Expected sequence_drift to pass after fix, got %+v", result)
+	}
+}
+
+func findCheckAdm(results []checkResultAdm, name string) *checkResultAdm {
+	for i := range results {
+		if results[i].Name == name {
+			return &results[i]
+		}
+	}
+	return nil
 }
 
 func TestDoctorAttachmentChecks(t *testing.T) {
@@ -481,6 +592,28 @@ func TestDoctorAttachmentChecks(t *testing.T) {
 		}
 	})
 
+	t.Run("missing attachment files detected", func(t *testing.T) {
+		// The attachment row above has no file on disk yet
+		result := findCheckAdm(checkAttachmentsAdm(database, attachDir), "missing_attachment_files")
+		if result == nil || result.Status != "warning" {
+			t.Fatalf("Expected missing_attachment_files warning, got %+v", result)
+		}
+		if len(result.Details) != 1 || result.Details[0] != "tasks/task/file.txt" {
+			t.Errorf("Expected missing file detail, got %v", result.Details)
+		}
+
+		filePath := filepath.Join(attachDir, "tasks", "task", "file.txt")
+		os.MkdirAll(filepath.Dir(filePath), 0755)
+		if err := os.WriteFile(filePath, []byte("data"), 0644); err != nil {
+			t.Fatalf("Failed to write attachment: %v", err)
+		}
+
+		result = findCheckAdm(checkAttachmentsAdm(database, attachDir), "missing_attachment_files")
+		if result == nil || result.Status != "ok" {
+			t.Errorf("Expected missing_attachment_files to pass, got %+v", result)
+		}
+	})
+
 	t.Run("orphaned directories detected", func(t *testing.T) {
 		// Create orphaned directory
 		tasksDir := filepath.Join(attachDir, "tasks")
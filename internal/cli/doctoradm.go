@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/lherron/wrkq/internal/config"
 	"github.com/lherron/wrkq/internal/db"
@@ -16,8 +15,11 @@ import (
 var doctorAdmCmd = &cobra.Command{
 	Use:   "doctor",
 	Short: "Check database health and configuration",
-	Long:  `Performs health checks on the database, schema, configuration, and attachments. This is an administrative operation.`,
-	RunE:  runDoctorAdm,
+	Long: `Performs health checks on the database, schema, migrations, sequences, data
+integrity, and attachments. Exits non-zero if any check reports an error.
+--fix repairs sqlite_sequence drift before the checks run. This is an
+administrative operation.`,
+	RunE: runDoctorAdm,
 }
 
 var (
@@ -37,6 +39,7 @@ type doctorReportAdm struct {
 	Version       string           `json:"version"`
 	DBPath        string           `json:"db_path"`
 	Checks        []checkResultAdm `json:"checks"`
+	Fixes         []string         `json:"fixes,omitempty"`
 	Warnings      int              `json:"warnings"`
 	Errors        int              `json:"errors"`
 	OverallStatus string           `json:"overall_status"`
@@ -45,7 +48,7 @@ type doctorReportAdm struct {
 func init() {
 	rootAdmCmd.AddCommand(doctorAdmCmd)
 	doctorAdmCmd.Flags().BoolVar(&doctorAdmJSON, "json", false, "Output JSON")
-	doctorAdmCmd.Flags().BoolVar(&doctorAdmFix, "fix", false, "Auto-repair issues")
+	doctorAdmCmd.Flags().BoolVar(&doctorAdmFix, "fix", false, "Repair sqlite_sequence drift")
 	doctorAdmCmd.Flags().BoolVar(&doctorAdmVerbose, "verbose", false, "Verbose output")
 }
 
@@ -72,8 +75,13 @@ func runDoctorAdm(cmd *cobra.Command, args []string) error {
 	database, err := db.Open(cfg.DBPath)
 	if err == nil {
 		defer database.Close()
+		// Fix first so the checks report the repaired state
+		if doctorAdmFix {
+			report.Fixes = applyFixesAdm(database)
+		}
 		report.Checks = append(report.Checks, checkDatabasePragmasAdm(database)...)
 		report.Checks = append(report.Checks, checkSchemaAdm(database)...)
+		report.Checks = append(report.Checks, checkMigrationsAdm(database)...)
 		report.Checks = append(report.Checks, checkDataIntegrityAdm(database)...)
 		report.Checks = append(report.Checks, checkSequenceDriftAdm(database)...)
 		report.Checks = append(report.Checks, checkAttachmentsAdm(database, cfg.AttachDir)...)
@@ -100,18 +108,15 @@ func runDoctorAdm(cmd *cobra.Command, args []string) error {
 		report.OverallStatus = "warning"
 	}
 
-	// Apply fixes if requested
-	if doctorAdmFix && database != nil {
-		applyFixesAdm(database, report)
-	}
-
 	// Output report
 	if doctorAdmJSON {
-		return render.RenderJSON(report, false)
+		if err := render.RenderJSON(report, false); err != nil {
+			return err
+		}
+	} else {
+		printHumanReportAdm(cmd, report)
 	}
 
-	printHumanReportAdm(cmd, report)
-
 	if report.Errors > 0 {
 		os.Exit(1)
 	}
@@ -252,6 +257,32 @@ func checkSchemaAdm(database *db.DB) []checkResultAdm {
 	return results
 }
 
+func checkMigrationsAdm(database *db.DB) []checkResultAdm {
+	applied, pending, err := database.MigrationStatus()
+	if err != nil {
+		return []checkResultAdm{{
+			Name:    "migrations",
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to read migration status: %v", err),
+		}}
+	}
+
+	if len(pending) == 0 {
+		return []checkResultAdm{{
+			Name:    "migrations",
+			Status:  "ok",
+			Message: fmt.Sprintf("Schema up to date (%d migrations applied)", len(applied)),
+		}}
+	}
+
+	return []checkResultAdm{{
+		Name:    "migrations",
+		Status:  "error",
+		Message: fmt.Sprintf("%d pending migration(s)", len(pending)),
+		Details: append(pending, "Run 'wrkqadm migrate' to apply them"),
+	}}
+}
+
 func checkDataIntegrityAdm(database *db.DB) []checkResultAdm {
 	var results []checkResultAdm
 
@@ -299,6 +330,30 @@ func checkDataIntegrityAdm(database *db.DB) []checkResultAdm {
 		})
 	}
 
+	// Check for orphaned comments
+	var orphanedComments int
+	database.QueryRow(`
+		SELECT COUNT(*) FROM comments
+		WHERE task_uuid NOT IN (SELECT uuid FROM tasks)
+	`).Scan(&orphanedComments)
+
+	if orphanedComments == 0 {
+		results = append(results, checkResultAdm{
+			Name:    "orphaned_comments",
+			Status:  "ok",
+			Message: "No orphaned comments",
+		})
+	} else {
+		results = append(results, checkResultAdm{
+			Name:    "orphaned_comments",
+			Status:  "warning",
+			Message: fmt.Sprintf("%d comments reference non-existent tasks", orphanedComments),
+		})
+	}
+
+	// Any other dangling foreign keys (rows written with foreign_keys off)
+	results = append(results, checkForeignKeysAdm(database))
+
 	// Check for duplicate slugs
 	var duplicateSlugs int
 	database.QueryRow(`
@@ -328,6 +383,60 @@ func checkDataIntegrityAdm(database *db.DB) []checkResultAdm {
 	return results
 }
 
+func checkForeignKeysAdm(database *db.DB) checkResultAdm {
+	rows, err := database.Query("PRAGMA foreign_key_check")
+	if err != nil {
+		return checkResultAdm{
+			Name:    "foreign_key_check",
+			Status:  "error",
+			Message: fmt.Sprintf("Failed to run foreign_key_check: %v", err),
+		}
+	}
+	defer rows.Close()
+
+	// Rows are (table, rowid, parent, fkid); summarize per table -> parent
+	counts := map[string]int{}
+	var order []string
+	total := 0
+	for rows.Next() {
+		var table, parent string
+		var rowID sql.NullInt64
+		var fkID int
+		if err := rows.Scan(&table, &rowID, &parent, &fkID); err != nil {
+			return checkResultAdm{
+				Name:    "foreign_key_check",
+				Status:  "error",
+				Message: fmt.Sprintf("Failed to read foreign_key_check: %v", err),
+			}
+		}
+		key := table + " -> " + parent
+		if _, ok := counts[key]; !ok {
+			order = append(order, key)
+		}
+		counts[key]++
+		total++
+	}
+
+	if total == 0 {
+		return checkResultAdm{
+			Name:    "foreign_key_check",
+			Status:  "ok",
+			Message: "No dangling foreign keys",
+		}
+	}
+
+	details := make([]string, 0, len(order))
+	for _, key := range order {
+		details = append(details, fmt.Sprintf("%s: %d row(s)", key, counts[key]))
+	}
+	return checkResultAdm{
+		Name:    "foreign_key_check",
+		Status:  "warning",
+		Message: fmt.Sprintf("%d row(s) with dangling foreign keys", total),
+		Details: details,
+	}
+}
+
 func checkSequenceDriftAdm(database *db.DB) []checkResultAdm {
 	var results []checkResultAdm
 
@@ -359,7 +468,7 @@ func checkSequenceDriftAdm(database *db.DB) []checkResultAdm {
 		Name:    "sequence_drift",
 		Status:  "error",
 		Message: fmt.Sprintf("Detected sqlite_sequence drift (%d table(s))", len(drifts)),
-		Details: details,
+		Details: append(details, "Use --fix to repair sqlite_sequence"),
 	})
 
 	return results
@@ -404,6 +513,36 @@ func checkAttachmentsAdm(database *db.DB, attachDir string) []checkResultAdm {
 		})
 	}
 
+	// Check for attachment rows whose file is gone
+	var missing []string
+	if rows, err := database.Query("SELECT relative_path FROM attachments ORDER BY relative_path"); err == nil {
+		for rows.Next() {
+			var relativePath string
+			if err := rows.Scan(&relativePath); err != nil {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(attachDir, relativePath)); os.IsNotExist(err) {
+				missing = append(missing, relativePath)
+			}
+		}
+		rows.Close()
+	}
+
+	if len(missing) == 0 {
+		results = append(results, checkResultAdm{
+			Name:    "missing_attachment_files",
+			Status:  "ok",
+			Message: "All attachment files present",
+		})
+	} else {
+		results = append(results, checkResultAdm{
+			Name:    "missing_attachment_files",
+			Status:  "warning",
+			Message: fmt.Sprintf("%d attachments have no file on disk", len(missing)),
+			Details: missing,
+		})
+	}
+
 	// Check for orphaned files
 	tasksDir := filepath.Join(attachDir, "tasks")
 	orphanedDirs := 0
@@ -478,7 +617,9 @@ func checkPerformanceAdm(database *db.DB) []checkResultAdm {
 	return results
 }
 
-func applyFixesAdm(database *db.DB, report *doctorReportAdm) {
+// applyFixesAdm repairs what doctor can fix automatically and describes
+// what it did.
+func applyFixesAdm(database *db.DB) []string {
 	var outputs []string
 
 	if drifts, err := db.FixSequenceDrifts(database, db.DefaultSequenceSpecs()); err != nil {
@@ -489,16 +630,21 @@ func applyFixesAdm(database *db.DB, report *doctorReportAdm) {
 		outputs = append(outputs, "No sqlite_sequence drift detected")
 	}
 
-	if len(outputs) > 0 {
-		fmt.Fprintln(os.Stdout, "\n--fix results")
-		fmt.Fprintln(os.Stdout, strings.Join(outputs, "\n"))
-	}
+	return outputs
 }
 
 func printHumanReportAdm(cmd *cobra.Command, report *doctorReportAdm) {
 	fmt.Fprintf(cmd.OutOrStdout(), "wrkqadm doctor v%s\n\n", report.Version)
 	fmt.Fprintf(cmd.OutOrStdout(), "Database: %s\n\n", report.DBPath)
 
+	if len(report.Fixes) > 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "--fix results")
+		for _, fix := range report.Fixes {
+			fmt.Fprintf(cmd.OutOrStdout(), "  %s\n", fix)
+		}
+		fmt.Fprintln(cmd.OutOrStdout())
+	}
+
 	// Group checks by category
 	categories := map[string][]checkResultAdm{
 		"Database File":   {},
@@ -516,13 +662,13 @@ func printHumanReportAdm(cmd *cobra.Command, report *doctorReportAdm) {
 			categories["Database File"] = append(categories["Database File"], check)
 		case "wal_mode", "foreign_keys", "integrity_check":
 			categories["Database Health"] = append(categories["Database Health"], check)
-		case "schema_tables":
+		case "schema_tables", "migrations":
 			categories["Schema"] = append(categories["Schema"], check)
-		case "orphaned_tasks", "orphaned_attachments", "duplicate_slugs":
+		case "orphaned_tasks", "orphaned_attachments", "orphaned_comments", "foreign_key_check", "duplicate_slugs":
 			categories["Data Integrity"] = append(categories["Data Integrity"], check)
 		case "sequence_drift":
 			categories["Sequences"] = append(categories["Sequences"], check)
-		case "attach_dir_exists", "attachments_count", "orphaned_files":
+		case "attach_dir_exists", "attachments_count", "missing_attachment_files", "orphaned_files":
 			categories["Attachments"] = append(categories["Attachments"], check)
 		case "task_counts", "container_count", "database_size":
 			categories["Performance"] = append(categories["Performance"], check)
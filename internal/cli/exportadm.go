@@ -0,0 +1,348 @@
+package cli
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/lherron/wrkq/internal/actors"
+	"github.com/lherron/wrkq/internal/bundle"
+	"github.com/lherron/wrkq/internal/cli/appctx"
+	"github.com/lherron/wrkq/internal/cursor"
+	"github.com/lherron/wrkq/internal/db"
+	"github.com/lherron/wrkq/internal/selectors"
+	"github.com/spf13/cobra"
+)
+
+var exportAdmCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the database in human-readable formats",
+}
+
+var exportMdCmd = &cobra.Command{
+	Use:   "md",
+	Short: "Export tasks as a Markdown tree",
+	Long: `Writes every task as a Markdown file under --out, in a directory tree that
+mirrors the containers. Task files use the wrkq cat format (frontmatter, then
+the description); each container directory gets a README.md listing its
+subcontainers and tasks.
+
+The export is for browsing in an editor, not for re-import; use bundles or
+'wrkqadm state export' to move data between databases. Archived and deleted
+tasks (and archived containers) are left out unless asked for. --out must be
+empty or not exist yet.`,
+	Args: cobra.NoArgs,
+	RunE: appctx.WithApp(appctx.DefaultOptions(), runExportMd),
+}
+
+var (
+	exportMdOut             string
+	exportMdProject         string
+	exportMdIncludeArchived bool
+	exportMdIncludeDeleted  bool
+	exportMdJSON            bool
+)
+
+var exportCsvCmd = &cobra.Command{
+	Use:   "csv [PATH...]",
+	Short: "Export tasks as CSV",
+	Long: `Writes the tasks matching the wrkq find filters as CSV, one row per task,
+streaming rows as they are read. PATH arguments restrict the export to tasks
+under those paths (globs allowed).
+
+--columns picks and orders the columns from: ` + strings.Join(taskCSVColumnNames(), ", ") + `.
+The default is ` + strings.Join(defaultTaskCSVColumns, ",") + `.
+Assignee and project are friendly IDs (A-, P-); labels are joined with ";".`,
+	RunE: appctx.WithApp(appctx.DefaultOptions(), runExportCsv),
+}
+
+var (
+	exportCsvOut       string
+	exportCsvColumns   []string
+	exportCsvState     string
+	exportCsvKind      string
+	exportCsvAssignee  string
+	exportCsvSlugGlob  string
+	exportCsvLabelsAny []string
+	exportCsvLabelsAll []string
+	exportCsvDueBefore string
+	exportCsvDueAfter  string
+	exportCsvSort      string
+	exportCsvDirection string
+)
+
+func init() {
+	rootAdmCmd.AddCommand(exportAdmCmd)
+	exportAdmCmd.AddCommand(exportMdCmd)
+	exportAdmCmd.AddCommand(exportCsvCmd)
+
+	exportCsvCmd.Flags().StringVar(&exportCsvOut, "out", "-", "Output file (- for stdout)")
+	exportCsvCmd.Flags().StringSliceVar(&exportCsvColumns, "columns", nil, "Columns to export, in order (comma-separated)")
+	exportCsvCmd.Flags().StringVar(&exportCsvState, "state", "", "Filter by state, or 'all' (default excludes archived, deleted, and idea)")
+	exportCsvCmd.Flags().StringVar(&exportCsvKind, "kind", "", "Filter by task kind")
+	exportCsvCmd.Flags().StringVar(&exportCsvAssignee, "assignee", "", "Filter by assignee (actor slug or ID)")
+	exportCsvCmd.Flags().StringVar(&exportCsvSlugGlob, "slug-glob", "", "Filter by slug glob pattern")
+	exportCsvCmd.Flags().StringSliceVar(&exportCsvLabelsAny, "labels-any", nil, "Filter tasks having any of these labels")
+	exportCsvCmd.Flags().StringSliceVar(&exportCsvLabelsAll, "labels-all", nil, "Filter tasks having all of these labels")
+	exportCsvCmd.Flags().StringVar(&exportCsvDueBefore, "due-before", "", "Filter tasks due before date (YYYY-MM-DD)")
+	exportCsvCmd.Flags().StringVar(&exportCsvDueAfter, "due-after", "", "Filter tasks due after date (YYYY-MM-DD)")
+	exportCsvCmd.Flags().StringVar(&exportCsvSort, "sort", "", "Sort keys as for wrkq find (default: creation order)")
+	exportCsvCmd.Flags().StringVar(&exportCsvDirection, "direction", "", "Sort direction: asc or desc")
+
+	exportMdCmd.Flags().StringVar(&exportMdOut, "out", "", "Output directory (required)")
+	exportMdCmd.Flags().StringVar(&exportMdProject, "project", "", "Restrict export to a container subtree (path, ID, or UUID)")
+	exportMdCmd.Flags().BoolVar(&exportMdIncludeArchived, "include-archived", false, "Include archived tasks and containers")
+	exportMdCmd.Flags().BoolVar(&exportMdIncludeDeleted, "include-deleted", false, "Include deleted tasks")
+	exportMdCmd.Flags().BoolVar(&exportMdJSON, "json", false, "Output as JSON")
+	exportMdCmd.MarkFlagRequired("out")
+}
+
+func runExportMd(app *appctx.App, cmd *cobra.Command, args []string) error {
+	entries, err := os.ReadDir(exportMdOut)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read output directory: %w", err)
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("output directory %s is not empty", exportMdOut)
+	}
+
+	opts := bundle.MarkdownOptions{
+		OutputDir:       exportMdOut,
+		IncludeArchived: exportMdIncludeArchived,
+		IncludeDeleted:  exportMdIncludeDeleted,
+	}
+	if exportMdProject != "" {
+		projectUUID, _, err := selectors.ResolveContainer(app.DB, exportMdProject)
+		if err != nil {
+			return fmt.Errorf("failed to resolve project %q: %w", exportMdProject, err)
+		}
+		if err := app.DB.QueryRow("SELECT path FROM v_container_paths WHERE uuid = ?", projectUUID).Scan(&opts.ProjectPath); err != nil {
+			return fmt.Errorf("failed to resolve project path: %w", err)
+		}
+	}
+
+	result, err := bundle.ExportMarkdown(app.DB.DB, opts)
+	if err != nil {
+		return err
+	}
+
+	if exportMdJSON {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Exported %d task(s) in %d container(s) to %s\n", result.Tasks, result.Containers, result.OutputDir)
+	return nil
+}
+
+func runExportCsv(app *appctx.App, cmd *cobra.Command, args []string) error {
+	var assigneeUUID string
+	if exportCsvAssignee != "" {
+		uuid, err := actors.NewResolver(app.DB.DB).Resolve(exportCsvAssignee)
+		if err != nil {
+			return fmt.Errorf("failed to resolve assignee: %w", err)
+		}
+		assigneeUUID = uuid
+	}
+
+	var sortKeys []string
+	if exportCsvSort != "" {
+		keys, err := parseTaskSort(exportCsvSort)
+		if err != nil {
+			return err
+		}
+		sortKeys = keys
+	}
+
+	query, queryArgs, err := taskCSVQuery(findOptions{
+		paths:        args,
+		slugGlob:     exportCsvSlugGlob,
+		state:        exportCsvState,
+		dueBefore:    exportCsvDueBefore,
+		dueAfter:     exportCsvDueAfter,
+		kind:         exportCsvKind,
+		assigneeUUID: assigneeUUID,
+		labelsAny:    exportCsvLabelsAny,
+		labelsAll:    exportCsvLabelsAll,
+		sort:         sortKeys,
+		direction:    exportCsvDirection,
+	}, exportCsvColumns)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if exportCsvOut != "-" {
+		f, err := os.Create(exportCsvOut)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	_, err = writeTasksCSV(out, app.DB, query, queryArgs, csvColumnsOrDefault(exportCsvColumns))
+	return err
+}
+
+// taskCSVColumns maps CSV column names to the SQL producing them. The query
+// joins tasks t, v_container_paths cp, containers pc (the project) and
+// actors aa (the assignee).
+var taskCSVColumns = map[string]string{
+	"id":           "t.id",
+	"uuid":         "t.uuid",
+	"path":         "cp.path || '/' || t.slug",
+	"slug":         "t.slug",
+	"title":        "t.title",
+	"state":        "t.state",
+	"priority":     "t.priority",
+	"kind":         "t.kind",
+	"project":      "pc.id",
+	"assignee":     "aa.id",
+	"start_at":     "t.start_at",
+	"due_at":       "t.due_at",
+	"estimate":     "t.estimate_minutes",
+	"time_logged":  "(SELECT SUM(te.minutes) FROM time_entries te WHERE te.task_uuid = t.uuid)",
+	"labels":       "t.labels",
+	"created_at":   "t.created_at",
+	"updated_at":   "t.updated_at",
+	"completed_at": "t.completed_at",
+}
+
+var defaultTaskCSVColumns = []string{"id", "path", "title", "state", "priority", "assignee", "due_at", "labels", "created_at"}
+
+// csvLabelSeparator joins a task's labels into one CSV field.
+const csvLabelSeparator = ";"
+
+func taskCSVColumnNames() []string {
+	names := make([]string, 0, len(taskCSVColumns))
+	for name := range taskCSVColumns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func csvColumnsOrDefault(columns []string) []string {
+	if len(columns) == 0 {
+		return defaultTaskCSVColumns
+	}
+	return columns
+}
+
+// taskCSVQuery validates columns (empty means the defaults) and builds the
+// export query for the tasks matching opts. Pagination in opts is ignored:
+// an export covers every match, oldest first unless opts sets a sort.
+func taskCSVQuery(opts findOptions, columns []string) (string, []interface{}, error) {
+	columns = csvColumnsOrDefault(columns)
+	selects := make([]string, 0, len(columns))
+	seen := make(map[string]bool)
+	for _, name := range columns {
+		expr, ok := taskCSVColumns[name]
+		if !ok {
+			return "", nil, fmt.Errorf("unknown column: %s (valid: %s)", name, strings.Join(taskCSVColumnNames(), ", "))
+		}
+		if seen[name] {
+			return "", nil, fmt.Errorf("duplicate column: %s", name)
+		}
+		seen[name] = true
+		selects = append(selects, expr)
+	}
+
+	orderBy := "ORDER BY t.created_at ASC, t.id ASC"
+	if len(opts.sort) > 0 || opts.direction != "" {
+		opts.cursor = ""
+		opts.limit = 0
+		applyOpts, err := taskSortApplyOptions(opts)
+		if err != nil {
+			return "", nil, err
+		}
+		pag, err := cursor.Apply("", applyOpts)
+		if err != nil {
+			return "", nil, err
+		}
+		orderBy = pag.OrderByClause
+	}
+
+	filterClause, args, err := taskFilterClause(opts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	query := "SELECT " + strings.Join(selects, ", ") + `
+		FROM tasks t
+		JOIN v_container_paths cp ON cp.uuid = t.project_uuid
+		LEFT JOIN containers pc ON pc.uuid = t.project_uuid
+		LEFT JOIN actors aa ON aa.uuid = t.assignee_actor_uuid
+		WHERE 1=1` + filterClause + " " + orderBy
+	return query, args, nil
+}
+
+// writeTasksCSV runs a query from taskCSVQuery and writes the header and one
+// row per task to w, flushing as it goes rather than buffering the export.
+// It returns the number of rows written.
+func writeTasksCSV(w io.Writer, database *db.DB, query string, args []interface{}, columns []string) (int, error) {
+	rows, err := database.Query(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return 0, err
+	}
+
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	record := make([]string, len(columns))
+
+	count := 0
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return count, fmt.Errorf("scan failed: %w", err)
+		}
+		for i, name := range columns {
+			record[i] = values[i].String
+			if name == "labels" {
+				record[i] = flattenLabels(values[i].String)
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return count, err
+		}
+		count++
+		if count%100 == 0 {
+			cw.Flush()
+			if err := cw.Error(); err != nil {
+				return count, err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+	cw.Flush()
+	return count, cw.Error()
+}
+
+// flattenLabels turns a stored JSON label array into a delimited string.
+// Values that aren't a JSON array are passed through unchanged.
+func flattenLabels(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	var labels []string
+	if err := json.Unmarshal([]byte(raw), &labels); err != nil {
+		return raw
+	}
+	return strings.Join(labels, csvLabelSeparator)
+}
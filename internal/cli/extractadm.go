@@ -0,0 +1,235 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/lherron/wrkq/internal/actors"
+	"github.com/lherron/wrkq/internal/config"
+	"github.com/lherron/wrkq/internal/db"
+	"github.com/lherron/wrkq/internal/paths"
+	"github.com/spf13/cobra"
+)
+
+var extractAdmCmd = &cobra.Command{
+	Use:     "extract",
+	Aliases: []string{"unmerge"},
+	Short:   "Extract a subtree from a canonical database into a standalone database",
+	Long: `Extract a project subtree from a canonical wrkq database into a fresh
+standalone database.
+
+This is the reverse of merge: containers, tasks, comments, relations,
+attachments, sections, and referenced actors under --prefix are copied into a
+new database at --dest, preserving UUIDs and friendly IDs. The subtree root
+becomes a top-level project (override with --path-prefix). Attachment files
+are copied into --dest-attach-dir. Use --dry-run to emit the report without
+creating the destination.`,
+	RunE: runExtractAdm,
+}
+
+var (
+	extractSourceDB      string
+	extractPrefix        string
+	extractDestDB        string
+	extractPathPrefix    string
+	extractDryRun        bool
+	extractReportPath    string
+	extractSrcAttachDir  string
+	extractDestAttachDir string
+)
+
+func init() {
+	rootAdmCmd.AddCommand(extractAdmCmd)
+
+	extractAdmCmd.Flags().StringVar(&extractSourceDB, "source", "", "Canonical database path (overrides --db)")
+	extractAdmCmd.Flags().StringVar(&extractPrefix, "prefix", "", "Path prefix of the subtree to extract")
+	extractAdmCmd.Flags().StringVar(&extractDestDB, "dest", "", "Path of the new standalone database")
+	extractAdmCmd.Flags().StringVar(&extractPathPrefix, "path-prefix", "", "Project path in the new database (defaults to the last prefix segment)")
+	extractAdmCmd.Flags().BoolVar(&extractDryRun, "dry-run", false, "Validate without creating the destination")
+	extractAdmCmd.Flags().StringVar(&extractReportPath, "report", "", "Write JSON report to path")
+	extractAdmCmd.Flags().StringVar(&extractSrcAttachDir, "source-attach-dir", "", "Canonical attachments directory (defaults to WRKQ_ATTACH_DIR)")
+	extractAdmCmd.Flags().StringVar(&extractDestAttachDir, "dest-attach-dir", "", "Attachments directory for the new database (defaults to attachments/ beside --dest)")
+}
+
+func runExtractAdm(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return exitError(1, fmt.Errorf("failed to load config: %w", err))
+	}
+
+	sourcePath := extractSourceDB
+	if sourcePath == "" {
+		if dbFlag := cmd.Flag("db").Value.String(); dbFlag != "" {
+			sourcePath = dbFlag
+		} else {
+			sourcePath = cfg.DBPath
+		}
+	}
+	if sourcePath == "" {
+		return exitError(2, fmt.Errorf("source database path not specified (use --source or --db or set WRKQ_DB_PATH)"))
+	}
+	if extractPrefix == "" {
+		return exitError(2, fmt.Errorf("path prefix not specified (use --prefix)"))
+	}
+	if extractDestDB == "" {
+		return exitError(2, fmt.Errorf("destination database path not specified (use --dest)"))
+	}
+	if _, err := os.Stat(extractDestDB); err == nil {
+		return exitError(2, fmt.Errorf("destination database already exists: %s", extractDestDB))
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return exitError(1, fmt.Errorf("failed to check destination: %w", err))
+	}
+
+	srcDB, err := db.Open(sourcePath)
+	if err != nil {
+		return exitError(1, fmt.Errorf("failed to open source database: %w", err))
+	}
+	defer srcDB.Close()
+
+	if err := ensureMigrationsReady(srcDB, "source", false, true); err != nil {
+		return exitError(1, err)
+	}
+
+	actorUUID, err := resolveExtractActor(srcDB, cmd, cfg)
+	if err != nil {
+		return exitError(1, err)
+	}
+
+	srcAttachDir := cfg.AttachDir
+	if extractSrcAttachDir != "" {
+		srcAttachDir = extractSrcAttachDir
+	}
+	destAttachDir := extractDestAttachDir
+	if destAttachDir == "" {
+		destAttachDir = filepath.Join(filepath.Dir(extractDestDB), "attachments")
+	}
+
+	report, err := extractSubtree(extractOptions{
+		SourceDB:        srcDB,
+		DestPath:        extractDestDB,
+		Prefix:          extractPrefix,
+		PathPrefix:      extractPathPrefix,
+		SourceAttachDir: srcAttachDir,
+		DestAttachDir:   destAttachDir,
+		DryRun:          extractDryRun,
+		ActorUUID:       actorUUID,
+	})
+	if err != nil {
+		return exitError(1, err)
+	}
+
+	if extractReportPath != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return exitError(1, fmt.Errorf("failed to encode report: %w", err))
+		}
+		if err := os.WriteFile(extractReportPath, data, 0644); err != nil {
+			return exitError(1, fmt.Errorf("failed to write report: %w", err))
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "✓ Report written to %s\n", extractReportPath)
+	}
+
+	printMergeSummary(cmd.OutOrStdout(), report)
+	if !extractDryRun {
+		fmt.Fprintf(cmd.OutOrStdout(), "✓ Extracted %s to %s\n", extractPrefix, extractDestDB)
+	}
+
+	return nil
+}
+
+// resolveExtractActor resolves the acting actor against the canonical
+// database without creating it, since extraction must not write there.
+func resolveExtractActor(database *db.DB, cmd *cobra.Command, cfg *config.Config) (string, error) {
+	identifier := cmd.Flag("as").Value.String()
+	if identifier == "" {
+		identifier = cfg.GetActorID()
+	}
+	if identifier == "" {
+		return "", fmt.Errorf("no actor configured (set WRKQ_ACTOR, WRKQ_ACTOR_ID, or use --as flag)")
+	}
+	actorUUID, err := actors.NewResolver(database.DB).Resolve(identifier)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve actor: %w", err)
+	}
+	return actorUUID, nil
+}
+
+type extractOptions struct {
+	SourceDB        *db.DB
+	DestPath        string
+	Prefix          string
+	PathPrefix      string
+	SourceAttachDir string
+	DestAttachDir   string
+	DryRun          bool
+	ActorUUID       string
+}
+
+// extractSubtree copies the subtree at Prefix into a new database at DestPath
+// by running the merge pipeline with the canonical database as its source. In
+// dry-run mode the merge runs against a throwaway database instead.
+func extractSubtree(opts extractOptions) (*mergeReport, error) {
+	pathPrefix := opts.PathPrefix
+	if pathPrefix == "" {
+		segments := paths.SplitPath(opts.Prefix)
+		if len(segments) == 0 {
+			return nil, fmt.Errorf("path prefix cannot be empty")
+		}
+		pathPrefix = segments[len(segments)-1]
+	}
+
+	destPath := opts.DestPath
+	if opts.DryRun {
+		tmpDir, err := os.MkdirTemp("", "wrkq-extract-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create scratch directory: %w", err)
+		}
+		defer os.RemoveAll(tmpDir)
+		destPath = filepath.Join(tmpDir, "extract.db")
+	}
+
+	destDB, err := db.Open(destPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create destination database: %w", err)
+	}
+
+	report, err := func() (*mergeReport, error) {
+		if err := destDB.Migrate(); err != nil {
+			return nil, fmt.Errorf("failed to migrate destination database: %w", err)
+		}
+		return mergeProjectIntoCanonical(mergeOptions{
+			SourceDB:        opts.SourceDB,
+			DestDB:          destDB,
+			SourceAttachDir: opts.SourceAttachDir,
+			DestAttachDir:   opts.DestAttachDir,
+			ProjectSelector: opts.Prefix,
+			PathPrefix:      pathPrefix,
+			DryRun:          opts.DryRun,
+			ActorUUID:       opts.ActorUUID,
+			IncludeActors:   []string{opts.ActorUUID},
+		})
+	}()
+	closeErr := destDB.Close()
+	if err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close destination database: %w", closeErr)
+	}
+	if err != nil {
+		if !opts.DryRun {
+			removeDBFiles(destPath)
+		}
+		return nil, err
+	}
+
+	// Report the real destination rather than the dry-run scratch path.
+	report.DestDB = opts.DestPath
+	return report, nil
+}
+
+func removeDBFiles(path string) {
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		os.Remove(path + suffix)
+	}
+}
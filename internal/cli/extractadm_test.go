@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lherron/wrkq/internal/attach"
+	"github.com/lherron/wrkq/internal/db"
+)
+
+func setupExtractSource(t *testing.T) (*db.DB, string) {
+	t.Helper()
+	srcDB, _ := setupMergeDB(t)
+
+	rootUUID := "00000000-0000-0000-0000-0000000000c0"
+	childUUID := "00000000-0000-0000-0000-0000000000c1"
+	otherUUID := "00000000-0000-0000-0000-0000000000c2"
+	taskUUID := "00000000-0000-0000-0000-0000000000c3"
+	blockerUUID := "00000000-0000-0000-0000-0000000000c4"
+	otherTaskUUID := "00000000-0000-0000-0000-0000000000c5"
+
+	insertContainer(t, srcDB, rootUUID, "P-000c0", "canonical", "Canonical", "", "2024-02-01T00:00:00Z")
+	insertContainer(t, srcDB, childUUID, "P-000c1", "child", "Child", rootUUID, "2024-02-01T00:00:00Z")
+	insertContainer(t, srcDB, otherUUID, "P-000c2", "other", "Other", rootUUID, "2024-02-01T00:00:00Z")
+	insertTask(t, srcDB, taskUUID, "T-000c3", "extract-me", "Extract Me", childUUID)
+	insertTask(t, srcDB, blockerUUID, "T-000c4", "blocker", "Blocker", childUUID)
+	insertTask(t, srcDB, otherTaskUUID, "T-000c5", "stay", "Stay", otherUUID)
+
+	if _, err := srcDB.Exec(`
+		INSERT INTO comments (uuid, id, task_uuid, actor_uuid, body, etag, created_at)
+		VALUES ('00000000-0000-0000-0000-0000000000c6', 'C-00001', ?, ?, 'keep this', 1, '2024-02-01T00:00:00Z')
+	`, taskUUID, testActorUUID); err != nil {
+		t.Fatalf("failed to insert comment: %v", err)
+	}
+	if _, err := srcDB.Exec(`
+		INSERT INTO task_relations (from_task_uuid, to_task_uuid, kind, created_at, created_by_actor_uuid)
+		VALUES (?, ?, 'blocks', '2024-02-01T00:00:00Z', ?)
+	`, blockerUUID, taskUUID, testActorUUID); err != nil {
+		t.Fatalf("failed to insert relation: %v", err)
+	}
+
+	srcAttach := filepath.Join(t.TempDir(), "src-attach")
+	if err := attach.EnsureTaskDir(srcAttach, taskUUID); err != nil {
+		t.Fatalf("failed to ensure task dir: %v", err)
+	}
+	relPath := filepath.Join("tasks", taskUUID, "note.txt")
+	if err := os.WriteFile(filepath.Join(srcAttach, relPath), []byte("hello"), 0644); err != nil {
+		t.Fatalf("failed to write attachment: %v", err)
+	}
+	if _, err := srcDB.Exec(`
+		INSERT INTO attachments (uuid, id, task_uuid, filename, relative_path, mime_type, size_bytes, checksum, created_at, created_by_actor_uuid)
+		VALUES ('00000000-0000-0000-0000-0000000000c7', 'ATT-00001', ?, 'note.txt', ?, 'text/plain', 5, NULL, '2024-02-01T00:00:00Z', ?)
+	`, taskUUID, relPath, testActorUUID); err != nil {
+		t.Fatalf("failed to insert attachment: %v", err)
+	}
+
+	return srcDB, srcAttach
+}
+
+func TestExtractSubtree(t *testing.T) {
+	srcDB, srcAttach := setupExtractSource(t)
+	destPath := filepath.Join(t.TempDir(), "child.db")
+	destAttach := filepath.Join(t.TempDir(), "dest-attach")
+
+	report, err := extractSubtree(extractOptions{
+		SourceDB:        srcDB,
+		DestPath:        destPath,
+		Prefix:          "canonical/child",
+		SourceAttachDir: srcAttach,
+		DestAttachDir:   destAttach,
+		ActorUUID:       testActorUUID,
+	})
+	if err != nil {
+		t.Fatalf("extract failed: %v", err)
+	}
+	if report.DestPrefix != "child" || report.Stats.Tasks.Created != 2 {
+		t.Fatalf("unexpected report: prefix=%s stats=%+v", report.DestPrefix, report.Stats.Tasks)
+	}
+
+	destDB, err := db.Open(destPath)
+	if err != nil {
+		t.Fatalf("failed to open extracted db: %v", err)
+	}
+	defer destDB.Close()
+
+	var id, path string
+	if err := destDB.QueryRow(`
+		SELECT t.id, v.path FROM tasks t JOIN v_task_paths v ON v.uuid = t.uuid WHERE t.uuid = ?
+	`, "00000000-0000-0000-0000-0000000000c3").Scan(&id, &path); err != nil {
+		t.Fatalf("failed to load extracted task: %v", err)
+	}
+	if id != "T-000c3" || path != "child/extract-me" {
+		t.Fatalf("expected T-000c3 at child/extract-me, got %s at %s", id, path)
+	}
+
+	counts := map[string]string{
+		"tasks outside subtree": "SELECT COUNT(*) FROM tasks WHERE uuid = '00000000-0000-0000-0000-0000000000c5'",
+		"containers":            "SELECT COUNT(*) FROM containers",
+		"comments":              "SELECT COUNT(*) FROM comments",
+		"relations":             "SELECT COUNT(*) FROM task_relations",
+		"attachments":           "SELECT COUNT(*) FROM attachments",
+		"actors":                "SELECT COUNT(*) FROM actors",
+	}
+	want := map[string]int{
+		"tasks outside subtree": 0,
+		"containers":            1,
+		"comments":              1,
+		"relations":             1,
+		"attachments":           1,
+		"actors":                1,
+	}
+	for label, query := range counts {
+		var got int
+		if err := destDB.QueryRow(query).Scan(&got); err != nil {
+			t.Fatalf("failed to count %s: %v", label, err)
+		}
+		if got != want[label] {
+			t.Fatalf("expected %d %s, got %d", want[label], label, got)
+		}
+	}
+
+	destFile := filepath.Join(destAttach, "tasks", "00000000-0000-0000-0000-0000000000c3", "note.txt")
+	if _, err := os.Stat(destFile); err != nil {
+		t.Fatalf("expected attachment file copied: %v", err)
+	}
+}
+
+func TestExtractSubtreeDryRun(t *testing.T) {
+	srcDB, srcAttach := setupExtractSource(t)
+	destPath := filepath.Join(t.TempDir(), "child.db")
+
+	report, err := extractSubtree(extractOptions{
+		SourceDB:        srcDB,
+		DestPath:        destPath,
+		Prefix:          "canonical/child",
+		SourceAttachDir: srcAttach,
+		DestAttachDir:   t.TempDir(),
+		DryRun:          true,
+		ActorUUID:       testActorUUID,
+	})
+	if err != nil {
+		t.Fatalf("extract dry-run failed: %v", err)
+	}
+	if !report.DryRun || report.DestDB != destPath || report.Stats.Tasks.Created != 2 {
+		t.Fatalf("unexpected dry-run report: %+v", report)
+	}
+	if _, err := os.Stat(destPath); !os.IsNotExist(err) {
+		t.Fatalf("expected dry-run not to create %s", destPath)
+	}
+}
@@ -35,6 +35,8 @@ Examples:
   wrkq find --assigned-project rex             # Find tasks assigned to a project
   wrkq find --requested-by agent-spaces        # Find tasks requested by a project
   wrkq find --ack-pending                       # Find completed/cancelled tasks awaiting ack
+  wrkq find --labels-any bug,urgent            # Find tasks labeled bug or urgent
+  wrkq find --labels-all backend,api           # Find tasks labeled both backend and api
 `,
 	RunE: appctx.WithApp(appctx.DefaultOptions(), runFind),
 }
@@ -51,6 +53,8 @@ var (
 	findRequestedBy     string
 	findAssignedProject string
 	findAckPending      bool
+	findLabelsAny       []string
+	findLabelsAll       []string
 	findLimit           int
 	findCursor          string
 	findPorcelain       bool
@@ -67,12 +71,14 @@ func init() {
 	findCmd.Flags().StringVar(&findState, "state", "", "Filter by state: idea, draft, open, in_progress, completed, blocked, cancelled, archived, deleted, or 'all' for everything")
 	findCmd.Flags().StringVar(&findDueBefore, "due-before", "", "Filter tasks due before date (YYYY-MM-DD)")
 	findCmd.Flags().StringVar(&findDueAfter, "due-after", "", "Filter tasks due after date (YYYY-MM-DD)")
-	findCmd.Flags().StringVar(&findKind, "kind", "", "Filter by task kind: task, subtask, spike, bug, chore")
+	findCmd.Flags().StringVar(&findKind, "kind", "", "Filter by task kind (e.g. task, bug, chore)")
 	findCmd.Flags().StringVar(&findAssignee, "assignee", "", "Filter by assignee (actor slug or ID)")
 	findCmd.Flags().StringVar(&findParentTask, "parent-task", "", "Filter subtasks of a specific parent task (ID or path)")
 	findCmd.Flags().StringVar(&findRequestedBy, "requested-by", "", "Filter by requester project ID")
 	findCmd.Flags().StringVar(&findAssignedProject, "assigned-project", "", "Filter by assignee project ID")
 	findCmd.Flags().BoolVar(&findAckPending, "ack-pending", false, "Filter for ack-pending tasks (acknowledged_at is null; completed/cancelled)")
+	findCmd.Flags().StringSliceVar(&findLabelsAny, "labels-any", nil, "Filter tasks having any of these labels (comma-separated or repeated)")
+	findCmd.Flags().StringSliceVar(&findLabelsAll, "labels-all", nil, "Filter tasks having all of these labels (comma-separated or repeated)")
 	findCmd.Flags().IntVar(&findLimit, "limit", 0, "Limit number of results")
 	findCmd.Flags().StringVar(&findCursor, "cursor", "", "Pagination cursor")
 	findCmd.Flags().BoolVar(&findPorcelain, "porcelain", false, "Stable machine-readable output")
@@ -121,6 +127,8 @@ func runFind(app *appctx.App, cmd *cobra.Command, args []string) error {
 		requestedByProjectID: findRequestedBy,
 		assignedProjectID:    findAssignedProject,
 		ackPending:           findAckPending,
+		labelsAny:            findLabelsAny,
+		labelsAll:            findLabelsAll,
 		limit:                findLimit,
 		cursor:               findCursor,
 	})
@@ -175,6 +183,13 @@ func runFind(app *appctx.App, cmd *cobra.Command, args []string) error {
 	return render.RenderTable(results, findPorcelain)
 }
 
+// Assignee sentinels accepted by the daemon's task filters: the calling
+// actor (X-Wrkq-Actor), and tasks with no assignee.
+const (
+	assigneeMe   = "@me"
+	assigneeNone = "@none"
+)
+
 type findOptions struct {
 	paths                []string
 	typeFilter           string
@@ -184,12 +199,140 @@ type findOptions struct {
 	dueAfter             string
 	kind                 string
 	assigneeUUID         string
+	unassigned           bool // only tasks with no assignee
 	parentTaskUUID       string
 	requestedByProjectID string
 	assignedProjectID    string
 	ackPending           bool
+	labelsAny            []string // match tasks with any of these labels
+	labelsAll            []string // match tasks with every one of these labels
+	priorityMin          int      // 0 means no lower bound
+	priorityMax          int      // 0 means no upper bound
+	dueFrom              string   // RFC3339, inclusive
+	dueUntil             string   // RFC3339, exclusive
+	excludeTerminal      bool     // drop completed, cancelled, archived and deleted tasks
 	limit                int
 	cursor               string
+	sort                 []string // task sort keys (default: updated_at)
+	direction            string   // "asc" or "desc" (default: desc)
+}
+
+// dueWindowBounds translates a named due window into a [from, until) range of
+// RFC3339 UTC timestamps, with days and weeks (starting Monday) taken in
+// now's location. An empty bound is open. "overdue" also drops finished
+// (completed, cancelled, archived or deleted) tasks, which are never overdue.
+func dueWindowBounds(window string, now time.Time) (from, until string, excludeTerminal bool, err error) {
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	format := func(t time.Time) string { return t.UTC().Format(time.RFC3339) }
+
+	switch window {
+	case "overdue":
+		return "", format(now), true, nil
+	case "today":
+		return format(startOfDay), format(startOfDay.AddDate(0, 0, 1)), false, nil
+	case "this_week":
+		startOfWeek := startOfDay.AddDate(0, 0, -((int(startOfDay.Weekday()) + 6) % 7))
+		return format(startOfWeek), format(startOfWeek.AddDate(0, 0, 7)), false, nil
+	default:
+		return "", "", false, fmt.Errorf("invalid due window: %s (expected overdue, today or this_week)", window)
+	}
+}
+
+// taskSortColumns maps logical task sort keys to SQL expressions. Nullable
+// columns are coalesced so cursor comparisons never see NULL and unset (NULL
+// or empty) values sort after everything else in ascending order.
+var taskSortColumns = map[string]string{
+	"updated_at": "t.updated_at",
+	"priority":   "t.priority",
+	"due_at":     "COALESCE(NULLIF(t.due_at, ''), '9999-12-31T23:59:59Z')",
+	"title":      "t.title",
+	"slug":       "t.slug",
+	"state":      "t.state",
+}
+
+// parseTaskSort splits a comma-separated sort list like "priority,due_at" into
+// validated keys. An empty list sorts by updated_at.
+func parseTaskSort(sortList string) ([]string, error) {
+	var keys []string
+	for _, part := range strings.Split(sortList, ",") {
+		key := strings.TrimSpace(part)
+		if key == "" {
+			continue
+		}
+		if _, ok := taskSortColumns[key]; !ok {
+			return nil, fmt.Errorf("invalid sort field: %s", key)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// taskSortApplyOptions returns cursor options matching the ORDER BY for opts.
+func taskSortApplyOptions(opts findOptions) (cursor.ApplyOptions, error) {
+	keys := opts.sort
+	if len(keys) == 0 {
+		keys = []string{"updated_at"}
+	}
+
+	var descending bool
+	switch opts.direction {
+	case "", "desc":
+		descending = true
+	case "asc":
+		descending = false
+	default:
+		return cursor.ApplyOptions{}, fmt.Errorf("invalid sort direction: %s (must be asc or desc)", opts.direction)
+	}
+
+	applyOpts := cursor.ApplyOptions{IDField: "t.id", Limit: opts.limit}
+	for _, key := range keys {
+		column, ok := taskSortColumns[key]
+		if !ok {
+			return cursor.ApplyOptions{}, fmt.Errorf("invalid sort field: %s", key)
+		}
+		applyOpts.SortFields = append(applyOpts.SortFields, key)
+		applyOpts.SQLFields = append(applyOpts.SQLFields, column)
+		applyOpts.Descending = append(applyOpts.Descending, descending)
+	}
+	return applyOpts, nil
+}
+
+// nextTaskCursor builds the cursor continuing after r for the sort in opts.
+func nextTaskCursor(r findResult, opts findOptions) (string, error) {
+	applyOpts, err := taskSortApplyOptions(opts)
+	if err != nil {
+		return "", err
+	}
+	values := make([]interface{}, 0, len(applyOpts.SortFields))
+	for _, key := range applyOpts.SortFields {
+		switch key {
+		case "updated_at":
+			values = append(values, r.UpdatedAt)
+		case "priority":
+			var priority int
+			if r.Priority != nil {
+				priority = *r.Priority
+			}
+			values = append(values, priority)
+		case "due_at":
+			dueAt := "9999-12-31T23:59:59Z"
+			if r.DueAt != nil && *r.DueAt != "" {
+				dueAt = *r.DueAt
+			}
+			values = append(values, dueAt)
+		case "title":
+			values = append(values, r.Title)
+		case "slug":
+			values = append(values, r.Slug)
+		case "state":
+			var state string
+			if r.State != nil {
+				state = *r.State
+			}
+			values = append(values, state)
+		}
+	}
+	return cursor.BuildNextCursor(applyOpts.SortFields, values, r.ID)
 }
 
 type findResult struct {
@@ -261,142 +404,55 @@ func executeFindQuery(database *db.DB, opts findOptions) ([]findResult, bool, er
 }
 
 func findTasks(database *db.DB, opts findOptions, skipPagination bool) ([]findResult, bool, error) {
+	applyOpts, err := taskSortApplyOptions(opts)
+	if err != nil {
+		return nil, false, err
+	}
+
 	// Build cursor pagination (only if not mixing with containers)
-	var pag *cursor.ApplyResult
-	var err error
-	if !skipPagination {
-		pag, err = cursor.Apply(opts.cursor, cursor.ApplyOptions{
-			SortFields: []string{"updated_at"},
-			SQLFields:  []string{"t.updated_at"},
-			Descending: []bool{true},
-			IDField:    "t.id",
-			Limit:      opts.limit,
-		})
-		if err != nil {
-			return nil, false, err
-		}
+	cursorStr := opts.cursor
+	if skipPagination {
+		cursorStr = ""
+		applyOpts.Limit = 0
+	}
+	pag, err := cursor.Apply(cursorStr, applyOpts)
+	if err != nil {
+		return nil, false, err
 	}
 
+	// Assignee slugs and parent IDs are joined in, so a page of tasks takes
+	// one query however many actors and parents it refers to
 	query := `
 		SELECT t.uuid, t.id, t.slug, t.title, t.state, t.priority, t.kind,
-		       t.assignee_actor_uuid, t.parent_task_uuid, t.requested_by_project_id,
+		       asg.slug, pt.id, t.requested_by_project_id,
 		       t.assigned_project_id, t.acknowledged_at, t.resolution, t.due_at, t.etag,
 		       cp.path || '/' || t.slug AS path, t.updated_at
 		FROM tasks t
 		JOIN v_container_paths cp ON cp.uuid = t.project_uuid
+		LEFT JOIN actors asg ON asg.uuid = t.assignee_actor_uuid
+		LEFT JOIN tasks pt ON pt.uuid = t.parent_task_uuid
 		WHERE 1=1
 	`
-	args := []interface{}{}
-
-	// Filter by state (default: exclude archived, deleted, and idea)
-	switch opts.state {
-	case "all":
-		// Include all states (no filter)
-	case "":
-		// Default: exclude archived, deleted, and idea
-		query += " AND t.state NOT IN ('archived', 'deleted', 'idea')"
-	default:
-		query += " AND t.state = ?"
-		args = append(args, opts.state)
-	}
-
-	// Filter by kind
-	if opts.kind != "" {
-		query += " AND t.kind = ?"
-		args = append(args, opts.kind)
-	}
+	var args []interface{}
 
-	// Filter by assignee
-	if opts.assigneeUUID != "" {
-		query += " AND t.assignee_actor_uuid = ?"
-		args = append(args, opts.assigneeUUID)
-	}
-
-	// Filter by parent task
-	if opts.parentTaskUUID != "" {
-		query += " AND t.parent_task_uuid = ?"
-		args = append(args, opts.parentTaskUUID)
-	}
-
-	// Filter by requested-by project
-	if opts.requestedByProjectID != "" {
-		query += " AND t.requested_by_project_id = ?"
-		args = append(args, opts.requestedByProjectID)
-	}
-
-	// Filter by assigned project
-	if opts.assignedProjectID != "" {
-		query += " AND t.assigned_project_id = ?"
-		args = append(args, opts.assignedProjectID)
-	}
-
-	// Filter by ack pending
-	if opts.ackPending {
-		query += " AND t.acknowledged_at IS NULL AND t.state IN ('completed', 'cancelled')"
-	}
-
-	// Filter by due date
-	if opts.dueBefore != "" {
-		dueBeforeTime, err := time.Parse("2006-01-02", opts.dueBefore)
-		if err != nil {
-			return nil, false, fmt.Errorf("invalid due-before date: %w", err)
-		}
-		query += " AND t.due_at IS NOT NULL AND t.due_at < ?"
-		args = append(args, dueBeforeTime.Format(time.RFC3339))
-	}
-
-	if opts.dueAfter != "" {
-		dueAfterTime, err := time.Parse("2006-01-02", opts.dueAfter)
-		if err != nil {
-			return nil, false, fmt.Errorf("invalid due-after date: %w", err)
-		}
-		query += " AND t.due_at IS NOT NULL AND t.due_at > ?"
-		args = append(args, dueAfterTime.Format(time.RFC3339))
-	}
-
-	// Filter by slug glob
-	if opts.slugGlob != "" {
-		// Convert glob to SQL GLOB pattern
-		pattern := paths.GlobToSQLPattern(opts.slugGlob)
-		query += " AND t.slug GLOB ?"
-		args = append(args, pattern)
-	}
-
-	// Filter by path prefix
-	if len(opts.paths) > 0 {
-		// Build OR conditions for each path
-		pathConditions := []string{}
-		for _, p := range opts.paths {
-			// Support glob patterns in paths
-			if strings.Contains(p, "*") {
-				pattern := paths.GlobToSQLPattern(p)
-				pathConditions = append(pathConditions, "(cp.path || '/' || t.slug) GLOB ?")
-				args = append(args, pattern)
-			} else {
-				pathConditions = append(pathConditions, "(cp.path || '/' || t.slug) LIKE ? || '%'")
-				args = append(args, p)
-			}
-		}
-		if len(pathConditions) > 0 {
-			query += " AND (" + strings.Join(pathConditions, " OR ") + ")"
-		}
+	filterClause, filterArgs, err := taskFilterClause(opts)
+	if err != nil {
+		return nil, false, err
 	}
+	query += filterClause
+	args = append(args, filterArgs...)
 
 	// Add cursor WHERE clause if present
-	if pag != nil && pag.WhereClause != "" {
+	if pag.WhereClause != "" {
 		query += " AND " + pag.WhereClause
 		args = append(args, pag.Params...)
 	}
 
 	// Add ORDER BY
-	if pag != nil {
-		query += " " + pag.OrderByClause
-	} else {
-		query += " ORDER BY t.updated_at DESC"
-	}
+	query += " " + pag.OrderByClause
 
 	// Add LIMIT
-	if pag != nil && pag.LimitClause != "" {
+	if pag.LimitClause != "" {
 		query += " " + pag.LimitClause
 		args = append(args, *pag.LimitParam)
 	}
@@ -410,12 +466,12 @@ func findTasks(database *db.DB, opts findOptions, skipPagination bool) ([]findRe
 	results := []findResult{}
 	for rows.Next() {
 		var r findResult
-		var state, kind, assigneeUUID, parentTaskUUID, dueAt sql.NullString
+		var state, kind, assignee, parentTaskID, dueAt sql.NullString
 		var requestedBy, assignedProject, acknowledgedAt, resolution sql.NullString
 		var priority sql.NullInt64
 
 		err := rows.Scan(&r.UUID, &r.ID, &r.Slug, &r.Title, &state, &priority, &kind,
-			&assigneeUUID, &parentTaskUUID, &requestedBy, &assignedProject,
+			&assignee, &parentTaskID, &requestedBy, &assignedProject,
 			&acknowledgedAt, &resolution, &dueAt, &r.ETag, &r.Path, &r.UpdatedAt)
 		if err != nil {
 			return nil, false, fmt.Errorf("scan failed: %w", err)
@@ -432,19 +488,11 @@ func findTasks(database *db.DB, opts findOptions, skipPagination bool) ([]findRe
 		if kind.Valid {
 			r.Kind = &kind.String
 		}
-		if assigneeUUID.Valid {
-			// Resolve assignee UUID to slug
-			var slug string
-			if err := database.QueryRow("SELECT slug FROM actors WHERE uuid = ?", assigneeUUID.String).Scan(&slug); err == nil {
-				r.Assignee = &slug
-			}
+		if assignee.Valid {
+			r.Assignee = &assignee.String
 		}
-		if parentTaskUUID.Valid {
-			// Get parent task ID
-			var parentID string
-			if err := database.QueryRow("SELECT id FROM tasks WHERE uuid = ?", parentTaskUUID.String).Scan(&parentID); err == nil {
-				r.ParentTaskID = &parentID
-			}
+		if parentTaskID.Valid {
+			r.ParentTaskID = &parentTaskID.String
 		}
 		if requestedBy.Valid {
 			r.RequestedByProjectID = &requestedBy.String
@@ -581,3 +629,169 @@ func findContainers(database *db.DB, opts findOptions, skipPagination bool) ([]f
 
 	return results, hasMore, nil
 }
+
+// taskFilterClause builds the " AND ..." conditions shared by task listing and
+// counting. It expects tasks aliased as t and v_container_paths as cp.
+func taskFilterClause(opts findOptions) (string, []interface{}, error) {
+	query := ""
+	args := []interface{}{}
+
+	// Filter by state (default: exclude archived, deleted, and idea)
+	switch opts.state {
+	case "all":
+		// Include all states (no filter)
+	case "":
+		// Default: exclude archived, deleted, and idea
+		query += " AND t.state NOT IN ('archived', 'deleted', 'idea')"
+	default:
+		query += " AND t.state = ?"
+		args = append(args, opts.state)
+	}
+
+	// Filter by kind
+	if opts.kind != "" {
+		query += " AND t.kind = ?"
+		args = append(args, opts.kind)
+	}
+
+	// Filter by assignee
+	if opts.assigneeUUID != "" {
+		query += " AND t.assignee_actor_uuid = ?"
+		args = append(args, opts.assigneeUUID)
+	}
+
+	if opts.unassigned {
+		query += " AND t.assignee_actor_uuid IS NULL"
+	}
+
+	// Filter by parent task
+	if opts.parentTaskUUID != "" {
+		query += " AND t.parent_task_uuid = ?"
+		args = append(args, opts.parentTaskUUID)
+	}
+
+	// Filter by requested-by project
+	if opts.requestedByProjectID != "" {
+		query += " AND t.requested_by_project_id = ?"
+		args = append(args, opts.requestedByProjectID)
+	}
+
+	// Filter by assigned project
+	if opts.assignedProjectID != "" {
+		query += " AND t.assigned_project_id = ?"
+		args = append(args, opts.assignedProjectID)
+	}
+
+	// Filter by ack pending
+	if opts.ackPending {
+		query += " AND t.acknowledged_at IS NULL AND t.state IN ('completed', 'cancelled')"
+	}
+
+	// Filter by labels (stored as a JSON array; invalid or NULL labels match nothing)
+	if labels := uniqueLabels(opts.labelsAny); len(labels) > 0 {
+		placeholders := strings.TrimRight(strings.Repeat("?,", len(labels)), ",")
+		query += " AND EXISTS (SELECT 1 FROM json_each(" + taskLabelsJSON + ") WHERE value IN (" + placeholders + "))"
+		for _, label := range labels {
+			args = append(args, label)
+		}
+	}
+	if labels := uniqueLabels(opts.labelsAll); len(labels) > 0 {
+		placeholders := strings.TrimRight(strings.Repeat("?,", len(labels)), ",")
+		query += " AND (SELECT COUNT(DISTINCT value) FROM json_each(" + taskLabelsJSON + ") WHERE value IN (" + placeholders + ")) = ?"
+		for _, label := range labels {
+			args = append(args, label)
+		}
+		args = append(args, len(labels))
+	}
+
+	// Filter by priority range
+	if opts.priorityMin > 0 {
+		query += " AND t.priority >= ?"
+		args = append(args, opts.priorityMin)
+	}
+	if opts.priorityMax > 0 {
+		query += " AND t.priority <= ?"
+		args = append(args, opts.priorityMax)
+	}
+
+	if opts.excludeTerminal {
+		query += " AND t.state NOT IN ('completed', 'cancelled', 'archived', 'deleted')"
+	}
+
+	// Filter by due window
+	if opts.dueFrom != "" {
+		query += " AND t.due_at IS NOT NULL AND t.due_at != '' AND t.due_at >= ?"
+		args = append(args, opts.dueFrom)
+	}
+	if opts.dueUntil != "" {
+		query += " AND t.due_at IS NOT NULL AND t.due_at != '' AND t.due_at < ?"
+		args = append(args, opts.dueUntil)
+	}
+
+	// Filter by due date
+	if opts.dueBefore != "" {
+		dueBeforeTime, err := time.Parse("2006-01-02", opts.dueBefore)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid due-before date: %w", err)
+		}
+		query += " AND t.due_at IS NOT NULL AND t.due_at != '' AND t.due_at < ?"
+		args = append(args, dueBeforeTime.Format(time.RFC3339))
+	}
+
+	if opts.dueAfter != "" {
+		dueAfterTime, err := time.Parse("2006-01-02", opts.dueAfter)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid due-after date: %w", err)
+		}
+		query += " AND t.due_at IS NOT NULL AND t.due_at != '' AND t.due_at > ?"
+		args = append(args, dueAfterTime.Format(time.RFC3339))
+	}
+
+	// Filter by slug glob
+	if opts.slugGlob != "" {
+		// Convert glob to SQL GLOB pattern
+		pattern := paths.GlobToSQLPattern(opts.slugGlob)
+		query += " AND t.slug GLOB ?"
+		args = append(args, pattern)
+	}
+
+	// Filter by path prefix
+	if len(opts.paths) > 0 {
+		// Build OR conditions for each path
+		pathConditions := []string{}
+		for _, p := range opts.paths {
+			// Support glob patterns in paths
+			if strings.Contains(p, "*") {
+				pattern := paths.GlobToSQLPattern(p)
+				pathConditions = append(pathConditions, "(cp.path || '/' || t.slug) GLOB ?")
+				args = append(args, pattern)
+			} else {
+				pathConditions = append(pathConditions, "(cp.path || '/' || t.slug) LIKE ? || '%'")
+				args = append(args, p)
+			}
+		}
+		if len(pathConditions) > 0 {
+			query += " AND (" + strings.Join(pathConditions, " OR ") + ")"
+		}
+	}
+
+	return query, args, nil
+}
+
+// taskLabelsJSON is t.labels as a JSON array safe to pass to json_each.
+const taskLabelsJSON = "CASE WHEN json_valid(t.labels) THEN t.labels ELSE '[]' END"
+
+// uniqueLabels trims and de-duplicates label filters, dropping empty ones.
+func uniqueLabels(labels []string) []string {
+	seen := make(map[string]bool, len(labels))
+	var result []string
+	for _, label := range labels {
+		label = strings.TrimSpace(label)
+		if label == "" || seen[label] {
+			continue
+		}
+		seen[label] = true
+		result = append(result, label)
+	}
+	return result
+}
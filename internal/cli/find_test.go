@@ -1,9 +1,14 @@
 package cli
 
 import (
+	"database/sql"
+	"fmt"
+	"reflect"
 	"sort"
 	"testing"
+	"time"
 
+	"github.com/lherron/wrkq/internal/cursor"
 	"github.com/lherron/wrkq/internal/db"
 )
 
@@ -34,6 +39,171 @@ func TestFindFiltersForRoundtripFields(t *testing.T) {
 	assertIDs(t, results, []string{"T-00401", "T-00403"})
 }
 
+func TestFindTasksLabelFilters(t *testing.T) {
+	database, _ := setupTestEnv(t)
+
+	insertFindTask(t, database, "00000000-0000-0000-0000-000000000411", "T-00411", "lbl-ab", "open", "", "", nil)
+	insertFindTask(t, database, "00000000-0000-0000-0000-000000000412", "T-00412", "lbl-c", "open", "", "", nil)
+	insertFindTask(t, database, "00000000-0000-0000-0000-000000000413", "T-00413", "lbl-none", "open", "", "", nil)
+	for uuid, labels := range map[string]interface{}{
+		"00000000-0000-0000-0000-000000000411": `["a","b"]`,
+		"00000000-0000-0000-0000-000000000412": `["c"]`,
+		"00000000-0000-0000-0000-000000000413": nil,
+	} {
+		if _, err := database.Exec("UPDATE tasks SET labels = ? WHERE uuid = ?", labels, uuid); err != nil {
+			t.Fatalf("failed to set labels: %v", err)
+		}
+	}
+
+	results, _, err := findTasks(database, findOptions{labelsAll: []string{"a"}}, true)
+	if err != nil {
+		t.Fatalf("findTasks failed: %v", err)
+	}
+	assertIDs(t, results, []string{"T-00411"})
+
+	results, _, err = findTasks(database, findOptions{labelsAll: []string{"a", "c"}}, true)
+	if err != nil {
+		t.Fatalf("findTasks failed: %v", err)
+	}
+	assertIDs(t, results, []string{})
+
+	results, _, err = findTasks(database, findOptions{labelsAny: []string{"a", "c"}}, true)
+	if err != nil {
+		t.Fatalf("findTasks failed: %v", err)
+	}
+	assertIDs(t, results, []string{"T-00411", "T-00412"})
+
+	// Duplicates in the filter don't inflate the required count
+	results, _, err = findTasks(database, findOptions{labelsAll: []string{"b", "a", "a"}}, true)
+	if err != nil {
+		t.Fatalf("findTasks failed: %v", err)
+	}
+	assertIDs(t, results, []string{"T-00411"})
+}
+
+func TestFindTasksMatchesPerRowEnrichment(t *testing.T) {
+	database, dbPath := setupTestEnv(t)
+	if _, err := database.Exec(`
+		INSERT INTO actors (uuid, id, slug, display_name, role, created_at, updated_at)
+		VALUES ('00000000-0000-0000-0000-000000000009', 'A-00009', 'helper', 'Helper', 'agent', datetime('now'), datetime('now'))
+	`); err != nil {
+		t.Fatalf("failed to create actor: %v", err)
+	}
+
+	insertFindTask(t, database, "00000000-0000-0000-0000-000000000501", "T-00501", "parent", "open", "", "", nil)
+	insertFindTask(t, database, "00000000-0000-0000-0000-000000000502", "T-00502", "other-parent", "open", "", "", nil)
+	for i := 3; i <= 8; i++ {
+		uuid := fmt.Sprintf("00000000-0000-0000-0000-00000000050%d", i)
+		insertFindTask(t, database, uuid, fmt.Sprintf("T-0050%d", i), fmt.Sprintf("child-%d", i), "open", "", "", nil)
+		assignee := []interface{}{nil, "00000000-0000-0000-0000-000000000001", "00000000-0000-0000-0000-000000000009"}[i%3]
+		parent := []interface{}{"00000000-0000-0000-0000-000000000501", "00000000-0000-0000-0000-000000000502", nil}[i%3]
+		if _, err := database.Exec("UPDATE tasks SET parent_task_uuid = ?, assignee_actor_uuid = ? WHERE uuid = ?", parent, assignee, uuid); err != nil {
+			t.Fatalf("failed to link task: %v", err)
+		}
+	}
+
+	counted, queries := openCountingDB(t, dbPath)
+	for _, opts := range []findOptions{
+		{},
+		{sort: []string{"slug"}, direction: "asc"},
+		{assigneeUUID: "00000000-0000-0000-0000-000000000009"},
+		{parentTaskUUID: "00000000-0000-0000-0000-000000000501"},
+	} {
+		queries.Store(0)
+		joined, _, err := findTasks(counted, opts, true)
+		if err != nil {
+			t.Fatalf("findTasks failed: %v", err)
+		}
+		if got := queries.Load(); got != 1 {
+			t.Errorf("expected one query for %+v, got %d", opts, got)
+		}
+		perRow, err := findTasksPerRow(database, opts)
+		if err != nil {
+			t.Fatalf("per-row find failed: %v", err)
+		}
+		if len(joined) == 0 || !reflect.DeepEqual(joined, perRow) {
+			t.Errorf("joined and per-row results differ for %+v:\n%+v\n%+v", opts, joined, perRow)
+		}
+	}
+}
+
+// findTasksPerRow is findTasks as it was before the assignee slugs and
+// parent IDs were joined in: the same filters and order, but its own select
+// of the raw references, each looked up with its own query. It is the
+// reference the joined version must match.
+func findTasksPerRow(database *db.DB, opts findOptions) ([]findResult, error) {
+	applyOpts, err := taskSortApplyOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	applyOpts.Limit = 0
+	pag, err := cursor.Apply("", applyOpts)
+	if err != nil {
+		return nil, err
+	}
+	filterClause, args, err := taskFilterClause(opts)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := database.Query(`
+		SELECT t.uuid, t.id, t.slug, t.title, t.state, t.priority, t.kind,
+		       t.assignee_actor_uuid, t.parent_task_uuid, t.requested_by_project_id,
+		       t.assigned_project_id, t.acknowledged_at, t.resolution, t.due_at, t.etag,
+		       cp.path || '/' || t.slug AS path, t.updated_at
+		FROM tasks t
+		JOIN v_container_paths cp ON cp.uuid = t.project_uuid
+		WHERE 1=1
+	`+filterClause+" "+pag.OrderByClause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []findResult{}
+	for rows.Next() {
+		r := findResult{Type: "task"}
+		var state, kind, assigneeUUID, parentTaskUUID, dueAt sql.NullString
+		var requestedBy, assignedProject, acknowledgedAt, resolution sql.NullString
+		var priority sql.NullInt64
+		if err := rows.Scan(&r.UUID, &r.ID, &r.Slug, &r.Title, &state, &priority, &kind,
+			&assigneeUUID, &parentTaskUUID, &requestedBy, &assignedProject,
+			&acknowledgedAt, &resolution, &dueAt, &r.ETag, &r.Path, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		for _, field := range []struct {
+			value sql.NullString
+			dst   **string
+		}{
+			{state, &r.State}, {kind, &r.Kind}, {requestedBy, &r.RequestedByProjectID},
+			{assignedProject, &r.AssignedProjectID}, {acknowledgedAt, &r.AcknowledgedAt},
+			{resolution, &r.Resolution}, {dueAt, &r.DueAt},
+		} {
+			if field.value.Valid {
+				value := field.value.String
+				*field.dst = &value
+			}
+		}
+		if priority.Valid {
+			p := int(priority.Int64)
+			r.Priority = &p
+		}
+		if assigneeUUID.Valid {
+			var slug string
+			if err := database.QueryRow("SELECT slug FROM actors WHERE uuid = ?", assigneeUUID.String).Scan(&slug); err == nil {
+				r.Assignee = &slug
+			}
+		}
+		if parentTaskUUID.Valid {
+			var parentID string
+			if err := database.QueryRow("SELECT id FROM tasks WHERE uuid = ?", parentTaskUUID.String).Scan(&parentID); err == nil {
+				r.ParentTaskID = &parentID
+			}
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
 func insertFindTask(t *testing.T, database *db.DB, uuid, id, slug, state, requestedBy, assignedProject string, acknowledgedAt interface{}) {
 	t.Helper()
 	_, err := database.Exec(`
@@ -71,3 +241,123 @@ func assertIDs(t *testing.T, results []findResult, expected []string) {
 		}
 	}
 }
+
+func TestFindTasksSortPagination(t *testing.T) {
+	database, _ := setupTestEnv(t)
+
+	priorities := []int{1, 2, 2, 3, 2, 1, 3, 2, 4, 2, 1}
+	for i, priority := range priorities {
+		var dueAt interface{}
+		if i%3 != 0 {
+			dueAt = fmt.Sprintf("2025-0%d-01T00:00:00Z", 1+i%2)
+		}
+		_, err := database.Exec(`
+			INSERT INTO tasks (uuid, id, slug, title, project_uuid, state, priority, due_at,
+				created_at, updated_at, created_by_actor_uuid, updated_by_actor_uuid, etag)
+			VALUES (?, ?, ?, ?, '00000000-0000-0000-0000-000000000002', 'open', ?, ?, datetime('now'), datetime('now'),
+				'00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001', 1)
+		`, fmt.Sprintf("00000000-0000-0000-0000-%012d", 600+i), fmt.Sprintf("T-%05d", 600+i),
+			fmt.Sprintf("sorted-%d", i), fmt.Sprintf("Sorted %d", i), priority, dueAt)
+		if err != nil {
+			t.Fatalf("failed to insert task: %v", err)
+		}
+	}
+
+	cases := []struct {
+		sort      string
+		direction string
+	}{
+		{"priority", "asc"},
+		{"priority", "desc"},
+		{"priority,due_at", "asc"},
+		{"priority,due_at", "desc"},
+		{"", ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.sort+"_"+tc.direction, func(t *testing.T) {
+			keys, err := parseTaskSort(tc.sort)
+			if err != nil {
+				t.Fatalf("parseTaskSort failed: %v", err)
+			}
+			opts := findOptions{sort: keys, direction: tc.direction, limit: 3}
+
+			all, _, err := findTasks(database, findOptions{sort: keys, direction: tc.direction}, true)
+			if err != nil {
+				t.Fatalf("findTasks failed: %v", err)
+			}
+
+			var paged []string
+			seen := map[string]bool{}
+			for page := 0; page < 20; page++ {
+				results, hasMore, err := findTasks(database, opts, false)
+				if err != nil {
+					t.Fatalf("findTasks page %d failed: %v", page, err)
+				}
+				for _, r := range results {
+					if seen[r.ID] {
+						t.Fatalf("task %s returned twice", r.ID)
+					}
+					seen[r.ID] = true
+					paged = append(paged, r.ID)
+				}
+				if !hasMore {
+					break
+				}
+				opts.cursor, err = nextTaskCursor(results[len(results)-1], opts)
+				if err != nil {
+					t.Fatalf("nextTaskCursor failed: %v", err)
+				}
+			}
+
+			if len(paged) != len(all) {
+				t.Fatalf("expected %d tasks across pages, got %d (%v)", len(all), len(paged), paged)
+			}
+			for i := range all {
+				if paged[i] != all[i].ID {
+					t.Fatalf("paged order %v does not match unpaged order at %d", paged, i)
+				}
+			}
+			if tc.sort == "priority" {
+				for i := 1; i < len(all); i++ {
+					prev, cur := *all[i-1].Priority, *all[i].Priority
+					if (tc.direction == "asc" && cur < prev) || (tc.direction == "desc" && cur > prev) {
+						t.Fatalf("results not sorted by priority %s", tc.direction)
+					}
+				}
+			}
+		})
+	}
+
+	if _, err := parseTaskSort("priority,bogus"); err == nil {
+		t.Fatalf("expected error for unknown sort field")
+	}
+}
+
+func TestDueWindowBounds(t *testing.T) {
+	loc := time.FixedZone("UTC-5", -5*3600)
+	// Thursday 2024-03-14 22:30 at UTC-5 is already Friday in UTC
+	now := time.Date(2024, 3, 14, 22, 30, 0, 0, loc)
+
+	tests := []struct {
+		window, from, until string
+		excludeTerminal     bool
+	}{
+		{"overdue", "", "2024-03-15T03:30:00Z", true},
+		{"today", "2024-03-14T05:00:00Z", "2024-03-15T05:00:00Z", false},
+		{"this_week", "2024-03-11T05:00:00Z", "2024-03-18T05:00:00Z", false},
+	}
+	for _, tt := range tests {
+		from, until, excludeTerminal, err := dueWindowBounds(tt.window, now)
+		if err != nil {
+			t.Fatalf("dueWindowBounds(%q) failed: %v", tt.window, err)
+		}
+		if from != tt.from || until != tt.until || excludeTerminal != tt.excludeTerminal {
+			t.Errorf("dueWindowBounds(%q) = %q, %q, %v; want %q, %q, %v", tt.window, from, until, excludeTerminal, tt.from, tt.until, tt.excludeTerminal)
+		}
+	}
+
+	if _, _, _, err := dueWindowBounds("next_year", now); err == nil {
+		t.Error("expected error for unknown window")
+	}
+}
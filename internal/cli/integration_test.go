@@ -35,7 +35,7 @@ func setupTestEnv(t *testing.T) (*db.DB, string) {
 	// Seed default actor
 	_, err = database.Exec(`
 		INSERT INTO actors (uuid, id, slug, display_name, role, created_at, updated_at)
-		VALUES ('00000000-0000-0000-0000-000000000001', 'A-00001', 'test-user', 'Test User', 'human', datetime('now'), datetime('now'))
+		VALUES ('00000000-0000-0000-0000-000000000001', 'A-00001', 'test-user', 'Test User', 'human', strftime('%Y-%m-%dT%H:%M:%SZ','now'), strftime('%Y-%m-%dT%H:%M:%SZ','now'))
 	`)
 	if err != nil {
 		database.Close()
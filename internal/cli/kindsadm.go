@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/lherron/wrkq/internal/cli/appctx"
+	"github.com/lherron/wrkq/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var kindsAdmCmd = &cobra.Command{
+	Use:   "kinds",
+	Short: "Manage the task kind vocabulary",
+	Long: `Administrative commands for the task kinds this database accepts. Creating
+or updating a task with a kind outside the vocabulary fails. New databases
+start with task, subtask, spike, bug, chore, feature and epic.`,
+}
+
+var kindsAdmLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List task kinds",
+	Args:  cobra.NoArgs,
+	RunE:  appctx.WithApp(appctx.DefaultOptions(), runKindsAdmLs),
+}
+
+var kindsAdmAddCmd = &cobra.Command{
+	Use:   "add <kind>...",
+	Short: "Add task kinds",
+	Long:  `Adds kinds to the vocabulary. Kinds use lowercase letters, digits, '-' and '_'. Adding an existing kind is a no-op.`,
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  appctx.WithApp(appctx.DefaultOptions(), runKindsAdmAdd),
+}
+
+var kindsAdmRmCmd = &cobra.Command{
+	Use:   "rm <kind>...",
+	Short: "Remove task kinds",
+	Long:  `Removes kinds from the vocabulary. A kind still used by a task can't be removed.`,
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  appctx.WithApp(appctx.DefaultOptions(), runKindsAdmRm),
+}
+
+var kindsAdmLsJSON bool
+
+func init() {
+	rootAdmCmd.AddCommand(kindsAdmCmd)
+	kindsAdmCmd.AddCommand(kindsAdmLsCmd)
+	kindsAdmCmd.AddCommand(kindsAdmAddCmd)
+	kindsAdmCmd.AddCommand(kindsAdmRmCmd)
+
+	kindsAdmLsCmd.Flags().BoolVar(&kindsAdmLsJSON, "json", false, "Output as JSON")
+}
+
+func runKindsAdmLs(app *appctx.App, cmd *cobra.Command, args []string) error {
+	kinds, err := store.New(app.DB).Kinds.List()
+	if err != nil {
+		return err
+	}
+
+	if kindsAdmLsJSON {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(kinds)
+	}
+	for _, kind := range kinds {
+		fmt.Fprintln(cmd.OutOrStdout(), kind)
+	}
+	return nil
+}
+
+func runKindsAdmAdd(app *appctx.App, cmd *cobra.Command, args []string) error {
+	s := store.New(app.DB)
+	for _, kind := range args {
+		if err := s.Kinds.Add(kind); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Added task kind: %s\n", kind)
+	}
+	return nil
+}
+
+func runKindsAdmRm(app *appctx.App, cmd *cobra.Command, args []string) error {
+	s := store.New(app.DB)
+	for _, kind := range args {
+		if err := s.Kinds.Remove(kind); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Removed task kind: %s\n", kind)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+)
+
+// queryRower is the part of *sql.DB, *db.DB and *sql.Tx a lookupCache needs.
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+type lookupResult struct {
+	value string
+	found bool
+}
+
+// lookupCache memoizes the single-row lookups made while building lists of
+// tasks: container paths and IDs, actor slugs and task IDs. Misses are
+// cached too, so a dangling reference costs one query rather than one per
+// row. Entries are never invalidated, so a cache must only live for one
+// request or command, and code that changes the rows it reads must look
+// them up afresh rather than through a cache already in use.
+type lookupCache struct {
+	db      queryRower
+	results map[string]map[string]lookupResult
+}
+
+func newLookupCache(database queryRower) *lookupCache {
+	return &lookupCache{db: database, results: map[string]map[string]lookupResult{}}
+}
+
+// lookup runs query, a single-column lookup by key, unless its result is
+// cached. found is false when no row matched; other errors aren't cached.
+func (c *lookupCache) lookup(query, key string) (value string, found bool, err error) {
+	cached := c.results[query]
+	if cached == nil {
+		cached = map[string]lookupResult{}
+		c.results[query] = cached
+	}
+	if result, ok := cached[key]; ok {
+		return result.value, result.found, nil
+	}
+	err = c.db.QueryRow(query, key).Scan(&value)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", false, err
+	}
+	found = err == nil
+	cached[key] = lookupResult{value: value, found: found}
+	return value, found, nil
+}
+
+// containerPath returns the path of the container with the given UUID.
+func (c *lookupCache) containerPath(containerUUID string) (string, error) {
+	path, found, err := c.lookup("SELECT path FROM v_container_paths WHERE uuid = ?", containerUUID)
+	if err == nil && !found {
+		err = sql.ErrNoRows
+	}
+	return path, err
+}
+
+// containerID returns the friendly ID of the container with the given UUID,
+// or "" if there is none.
+func (c *lookupCache) containerID(containerUUID string) string {
+	id, _, _ := c.lookup("SELECT id FROM containers WHERE uuid = ?", containerUUID)
+	return id
+}
+
+// actorSlug returns the slug of the actor with the given UUID, if any.
+func (c *lookupCache) actorSlug(actorUUID string) (string, bool) {
+	slug, found, _ := c.lookup("SELECT slug FROM actors WHERE uuid = ?", actorUUID)
+	return slug, found
+}
+
+// taskID returns the friendly ID of the task with the given UUID, if any.
+func (c *lookupCache) taskID(taskUUID string) (string, bool) {
+	id, found, _ := c.lookup("SELECT id FROM tasks WHERE uuid = ?", taskUUID)
+	return id, found
+}
+
+type lookupCacheKey struct{}
+
+// withLookups gives each request its own lookupCache, so a request resolves
+// each container path once, and nothing cached outlives the request that
+// read it.
+func (s *daemonServer) withLookups(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		next(w, r.WithContext(context.WithValue(r.Context(), lookupCacheKey{}, newLookupCache(s.db))))
+	}
+}
+
+// lookups returns the request's lookupCache, or a new one for a request
+// that didn't come through withLookups.
+func (s *daemonServer) lookups(r *http.Request) *lookupCache {
+	if cache, ok := r.Context().Value(lookupCacheKey{}).(*lookupCache); ok {
+		return cache
+	}
+	return newLookupCache(s.db)
+}
@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -19,6 +21,7 @@ import (
 	"github.com/lherron/wrkq/internal/events"
 	"github.com/lherron/wrkq/internal/paths"
 	"github.com/lherron/wrkq/internal/selectors"
+	"github.com/lherron/wrkq/internal/store"
 	"github.com/spf13/cobra"
 )
 
@@ -29,18 +32,31 @@ var mergeAdmCmd = &cobra.Command{
 
 This imports containers, tasks, comments, relations, and attachments from a
 source database into a destination database under a project path prefix.
-UUIDs are preserved and conflicts are resolved by favoring the newest record.
-Use --dry-run to validate and emit a report without writing.`,
+UUIDs are preserved and conflicts are resolved by --conflict-strategy:
+newest (default) keeps the most recently updated record, source-wins and
+dest-wins always prefer one side, and manual skips conflicting records and
+lists them in the report for review (exiting 4 if any remain).
+Use --prune to soft-delete destination tasks under the prefix that no longer
+exist in the source. Repeat --project (or list selectors in --projects-file, one per line with an
+optional destination prefix) to merge several projects in one destination
+transaction; a failure rolls back every project unless --continue-on-error.
+Use --dry-run to validate and emit a report without writing; add --plan to emit
+the per-record plan as JSONL (to --report or stdout) instead of the report.`,
 	RunE: runMergeAdm,
 }
 
 var (
 	mergeSourceDB      string
 	mergeDestDB        string
-	mergeProject       string
+	mergeProjects      []string
+	mergeProjectsFile  string
+	mergeContinueOnErr bool
 	mergePathPrefix    string
 	mergeReportPath    string
 	mergeDryRun        bool
+	mergePrune         bool
+	mergeStrategy      string
+	mergePlan          bool
 	mergeSrcAttachDir  string
 	mergeDestAttachDir string
 )
@@ -50,10 +66,15 @@ func init() {
 
 	mergeAdmCmd.Flags().StringVar(&mergeSourceDB, "source", "", "Source database path")
 	mergeAdmCmd.Flags().StringVar(&mergeDestDB, "dest", "", "Destination database path (overrides --db)")
-	mergeAdmCmd.Flags().StringVar(&mergeProject, "project", "", "Source project selector (slug, path, ID, or UUID)")
+	mergeAdmCmd.Flags().StringArrayVar(&mergeProjects, "project", nil, "Source project selector (slug, path, ID, or UUID); repeatable")
+	mergeAdmCmd.Flags().StringVar(&mergeProjectsFile, "projects-file", "", "File listing source project selectors, one per line")
+	mergeAdmCmd.Flags().BoolVar(&mergeContinueOnErr, "continue-on-error", false, "Keep merging remaining projects when one fails")
 	mergeAdmCmd.Flags().StringVar(&mergePathPrefix, "path-prefix", "", "Destination path prefix override")
 	mergeAdmCmd.Flags().BoolVar(&mergeDryRun, "dry-run", false, "Validate without writing")
+	mergeAdmCmd.Flags().StringVar(&mergeStrategy, "conflict-strategy", string(mergeStrategyNewest), "Conflict strategy: newest, source-wins, dest-wins, or manual")
+	mergeAdmCmd.Flags().BoolVar(&mergePrune, "prune", false, "Soft-delete destination tasks under the prefix that are missing from the source")
 	mergeAdmCmd.Flags().StringVar(&mergeReportPath, "report", "", "Write JSON report to path")
+	mergeAdmCmd.Flags().BoolVar(&mergePlan, "plan", false, "With --dry-run, emit the per-record merge plan as JSONL")
 	mergeAdmCmd.Flags().StringVar(&mergeSrcAttachDir, "source-attach-dir", "", "Source attachments directory (defaults to WRKQ_ATTACH_DIR)")
 	mergeAdmCmd.Flags().StringVar(&mergeDestAttachDir, "dest-attach-dir", "", "Destination attachments directory (defaults to WRKQ_ATTACH_DIR)")
 }
@@ -81,8 +102,34 @@ func runMergeAdm(cmd *cobra.Command, args []string) error {
 		return exitError(2, fmt.Errorf("destination database path not specified (use --dest or --db or set WRKQ_DB_PATH)"))
 	}
 
-	if mergeProject == "" {
-		return exitError(2, fmt.Errorf("project selector not specified (use --project)"))
+	specs := make([]mergeProjectSpec, 0, len(mergeProjects))
+	for _, selector := range mergeProjects {
+		specs = append(specs, mergeProjectSpec{Selector: selector})
+	}
+	if mergeProjectsFile != "" {
+		fileSpecs, err := readMergeProjectsFile(mergeProjectsFile)
+		if err != nil {
+			return exitError(2, err)
+		}
+		specs = append(specs, fileSpecs...)
+	}
+	if len(specs) == 0 {
+		return exitError(2, fmt.Errorf("project selector not specified (use --project or --projects-file)"))
+	}
+	if mergePathPrefix != "" {
+		if len(specs) > 1 {
+			return exitError(2, fmt.Errorf("--path-prefix can only be used with a single project; set prefixes in --projects-file instead"))
+		}
+		specs[0].PathPrefix = mergePathPrefix
+	}
+
+	strategy, err := parseMergeConflictStrategy(mergeStrategy)
+	if err != nil {
+		return exitError(2, err)
+	}
+
+	if mergePlan && !mergeDryRun {
+		return exitError(2, fmt.Errorf("--plan requires --dry-run"))
 	}
 
 	srcDB, err := db.Open(mergeSourceDB)
@@ -129,19 +176,48 @@ func runMergeAdm(cmd *cobra.Command, args []string) error {
 		DestDB:          destDB,
 		SourceAttachDir: srcAttachDir,
 		DestAttachDir:   attachDir,
-		ProjectSelector: mergeProject,
-		PathPrefix:      mergePathPrefix,
 		DryRun:          mergeDryRun,
+		Prune:           mergePrune,
+		Strategy:        strategy,
+		Plan:            mergePlan,
+		ContinueOnError: mergeContinueOnErr,
 		ActorUUID:       actorUUID,
 	}
 
-	report, err := mergeProjectIntoCanonical(opts)
+	reports, err := mergeProjectsIntoCanonical(opts, specs)
 	if err != nil {
 		return exitError(1, err)
 	}
 
-	if mergeReportPath != "" {
-		data, err := json.MarshalIndent(report, "", "  ")
+	var plan []mergePlanOp
+	for _, report := range reports {
+		plan = append(plan, report.Plan...)
+	}
+
+	summaryOut := cmd.OutOrStdout()
+	if mergePlan {
+		if mergeReportPath != "" {
+			f, err := os.Create(mergeReportPath)
+			if err != nil {
+				return exitError(1, fmt.Errorf("failed to create plan file: %w", err))
+			}
+			err = writeMergePlan(f, plan)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return exitError(1, fmt.Errorf("failed to write plan: %w", err))
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "✓ Plan written to %s\n", mergeReportPath)
+		} else {
+			if err := writeMergePlan(cmd.OutOrStdout(), plan); err != nil {
+				return exitError(1, fmt.Errorf("failed to write plan: %w", err))
+			}
+			// Keep stdout pure JSONL when the plan is streamed there.
+			summaryOut = cmd.ErrOrStderr()
+		}
+	} else if mergeReportPath != "" {
+		data, err := json.MarshalIndent(reports, "", "  ")
 		if err != nil {
 			return exitError(1, fmt.Errorf("failed to encode report: %w", err))
 		}
@@ -151,11 +227,55 @@ func runMergeAdm(cmd *cobra.Command, args []string) error {
 		fmt.Fprintf(cmd.OutOrStdout(), "✓ Report written to %s\n", mergeReportPath)
 	}
 
-	printMergeSummary(cmd, report)
+	var failed, conflicts int
+	for i, report := range reports {
+		if i > 0 {
+			fmt.Fprintln(summaryOut)
+		}
+		printMergeSummary(summaryOut, report)
+		if report.Error != "" {
+			failed++
+		}
+		conflicts += len(report.Conflicts)
+	}
+
+	if failed > 0 {
+		return exitError(5, fmt.Errorf("%d of %d projects failed to merge", failed, len(reports)))
+	}
+	if strategy == mergeStrategyManual && conflicts > 0 {
+		return exitError(4, fmt.Errorf("%d unresolved merge conflicts; review the report and re-run with another --conflict-strategy", conflicts))
+	}
 
 	return nil
 }
 
+// readMergeProjectsFile parses a projects file. Each non-empty line holds a
+// project selector optionally followed by a destination path prefix; lines
+// starting with # are ignored.
+func readMergeProjectsFile(path string) ([]mergeProjectSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read projects file: %w", err)
+	}
+	var specs []mergeProjectSpec
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("projects file %s:%d: expected \"<selector> [prefix]\"", path, i+1)
+		}
+		spec := mergeProjectSpec{Selector: fields[0]}
+		if len(fields) == 2 {
+			spec.PathPrefix = fields[1]
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
 func ensureMigrationsReady(database *db.DB, label string, allowPending bool, dryRun bool) error {
 	_, pending, err := database.MigrationStatus()
 	if err != nil {
@@ -178,7 +298,21 @@ type mergeOptions struct {
 	ProjectSelector string
 	PathPrefix      string
 	DryRun          bool
+	Prune           bool
+	Strategy        mergeConflictStrategy
+	Plan            bool
+	ContinueOnError bool
 	ActorUUID       string
+	// IncludeActors lists extra source actor UUIDs to copy even when no
+	// merged record references them.
+	IncludeActors []string
+}
+
+// mergeProjectSpec identifies one source project to merge and its optional
+// destination prefix override.
+type mergeProjectSpec struct {
+	Selector   string
+	PathPrefix string
 }
 
 type mergeReport struct {
@@ -189,12 +323,82 @@ type mergeReport struct {
 	SourceProjectPath  string          `json:"source_project_path"`
 	DestPrefix         string          `json:"dest_prefix"`
 	DryRun             bool            `json:"dry_run"`
+	Prune              bool            `json:"prune,omitempty"`
+	ConflictStrategy   string          `json:"conflict_strategy"`
 	Stats              mergeStats      `json:"stats"`
 	Renames            []mergeRename   `json:"renames,omitempty"`
 	Conflicts          []mergeConflict `json:"conflicts,omitempty"`
 	ActorMismatches    []actorMismatch `json:"actor_mismatches,omitempty"`
 	Warnings           []string        `json:"warnings,omitempty"`
 	AttachmentWarnings []string        `json:"attachment_warnings,omitempty"`
+	Error              string          `json:"error,omitempty"`
+
+	// Plan holds per-record operations when planning is enabled. It is
+	// emitted separately as JSONL rather than as part of the report.
+	Plan        []mergePlanOp `json:"-"`
+	planEnabled bool
+}
+
+// mergePlanOp is a single intended operation in a merge plan.
+type mergePlanOp struct {
+	Entity  string `json:"entity"`
+	UUID    string `json:"uuid"`
+	Op      string `json:"op"`
+	OldPath string `json:"old_path,omitempty"`
+	NewPath string `json:"new_path,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+func (r *mergeReport) addPlan(op mergePlanOp) {
+	if r.planEnabled {
+		r.Plan = append(r.Plan, op)
+	}
+}
+
+// planOp maps merge outcome flags to a plan operation name. Renames take
+// precedence; an empty OldPath distinguishes a renamed create from an update.
+func planOp(created, updated, renamed bool) string {
+	switch {
+	case renamed:
+		return "rename"
+	case created:
+		return "create"
+	case updated:
+		return "update"
+	default:
+		return "skip"
+	}
+}
+
+func writeMergePlan(w io.Writer, plan []mergePlanOp) error {
+	enc := json.NewEncoder(w)
+	for _, op := range plan {
+		if err := enc.Encode(op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookupPlanPath returns the current destination path for a record, or "" if
+// it does not exist yet.
+func lookupPlanPath(exec *mergeExecutor, view, uuid string) (string, error) {
+	var path string
+	err := exec.QueryRow("SELECT path FROM "+view+" WHERE uuid = ?", uuid).Scan(&path)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to lookup path for %s: %w", uuid, err)
+	}
+	return path, nil
+}
+
+func joinPlanPath(parent, slug string) string {
+	if parent == "" {
+		return slug
+	}
+	return parent + "/" + slug
 }
 
 type mergeStats struct {
@@ -205,8 +409,10 @@ type mergeStats struct {
 	Comments     mergeCounts `json:"comments"`
 	Relations    mergeCounts `json:"relations"`
 	Attachments  mergeCounts `json:"attachments"`
+	TimeEntries  mergeCounts `json:"time_entries"`
 	FilesCopied  int         `json:"files_copied"`
 	FilesMissing int         `json:"files_missing"`
+	Pruned       int         `json:"pruned"`
 }
 
 type mergeCounts struct {
@@ -244,118 +450,220 @@ type actorMismatch struct {
 	DestRole   string `json:"dest_role"`
 }
 
+// mergeProjectIntoCanonical merges a single source project. It is a
+// convenience wrapper around mergeProjectsIntoCanonical.
 func mergeProjectIntoCanonical(opts mergeOptions) (*mergeReport, error) {
-	projectUUID, _, err := selectors.ResolveContainer(opts.SourceDB, opts.ProjectSelector)
+	reports, err := mergeProjectsIntoCanonical(opts, []mergeProjectSpec{{Selector: opts.ProjectSelector, PathPrefix: opts.PathPrefix}})
+	if err != nil {
+		return nil, err
+	}
+	return reports[0], nil
+}
+
+// mergeProjectsIntoCanonical merges each source project into the destination
+// within a single transaction. Sequence syncing and attachment copies run once
+// after all projects have merged. Without ContinueOnError the first failure
+// aborts the whole merge; with it, the failed project is rolled back to a
+// savepoint and recorded in its report.
+func mergeProjectsIntoCanonical(opts mergeOptions, specs []mergeProjectSpec) ([]*mergeReport, error) {
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no projects to merge")
+	}
+
+	var tx *sql.Tx
+	if !opts.DryRun {
+		var err error
+		tx, err = opts.DestDB.Begin()
+		if err != nil {
+			return nil, fmt.Errorf("failed to begin destination transaction: %w", err)
+		}
+		defer tx.Rollback()
+	}
+
+	writer := events.NewWriter(opts.DestDB.DB)
+	exec := newMergeExecutor(opts.DestDB, tx)
+	useSavepoints := tx != nil && opts.ContinueOnError
+
+	reports := make([]*mergeReport, 0, len(specs))
+	fileCopies := make([][]fileCopy, 0, len(specs))
+	for _, spec := range specs {
+		if useSavepoints {
+			if _, err := exec.Exec("SAVEPOINT merge_project"); err != nil {
+				return nil, fmt.Errorf("failed to create savepoint: %w", err)
+			}
+		}
+
+		report, files, err := mergeProject(exec, writer, opts, spec)
+		if err != nil {
+			if !opts.ContinueOnError {
+				if len(specs) > 1 {
+					return nil, fmt.Errorf("project %s: %w", spec.Selector, err)
+				}
+				return nil, err
+			}
+			if useSavepoints {
+				if _, rbErr := exec.Exec("ROLLBACK TO merge_project"); rbErr != nil {
+					return nil, fmt.Errorf("failed to roll back project %s: %w", spec.Selector, rbErr)
+				}
+				if _, relErr := exec.Exec("RELEASE merge_project"); relErr != nil {
+					return nil, fmt.Errorf("failed to release savepoint: %w", relErr)
+				}
+			}
+			reports = append(reports, &mergeReport{
+				SourceDB:        opts.SourceDB.Path(),
+				DestDB:          opts.DestDB.Path(),
+				ProjectSelector: spec.Selector,
+				DryRun:          opts.DryRun,
+				Error:           err.Error(),
+			})
+			fileCopies = append(fileCopies, nil)
+			continue
+		}
+
+		if useSavepoints {
+			if _, err := exec.Exec("RELEASE merge_project"); err != nil {
+				return nil, fmt.Errorf("failed to release savepoint: %w", err)
+			}
+		}
+		reports = append(reports, report)
+		fileCopies = append(fileCopies, files)
+	}
+
+	if !opts.DryRun {
+		if _, err := db.FixSequenceDrifts(exec, db.DefaultSequenceSpecs()); err != nil {
+			return nil, fmt.Errorf("failed to sync sequences: %w", err)
+		}
+		if err := syncCommentSequence(exec); err != nil {
+			return nil, fmt.Errorf("failed to sync comment sequence: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit merge: %w", err)
+		}
+
+		for i, report := range reports {
+			if len(fileCopies[i]) == 0 {
+				continue
+			}
+			copied, missing, warnings := performFileCopies(fileCopies[i], opts.SourceAttachDir, opts.DestAttachDir)
+			report.Stats.FilesCopied = copied
+			report.Stats.FilesMissing = missing
+			report.AttachmentWarnings = append(report.AttachmentWarnings, warnings...)
+		}
+	}
+
+	return reports, nil
+}
+
+// mergeProject merges one source project using the shared executor and
+// returns its report along with the attachment files to copy after commit.
+func mergeProject(exec *mergeExecutor, writer *events.Writer, opts mergeOptions, spec mergeProjectSpec) (*mergeReport, []fileCopy, error) {
+	dryRun := opts.DryRun
+
+	projectUUID, _, err := selectors.ResolveContainer(opts.SourceDB, spec.Selector)
 	if err != nil {
-		return nil, fmt.Errorf("failed to resolve source project: %w", err)
+		return nil, nil, fmt.Errorf("failed to resolve source project: %w", err)
 	}
 
 	var sourceProjectPath string
 	if err := opts.SourceDB.QueryRow("SELECT path FROM v_container_paths WHERE uuid = ?", projectUUID).Scan(&sourceProjectPath); err != nil {
-		return nil, fmt.Errorf("failed to resolve source project path: %w", err)
+		return nil, nil, fmt.Errorf("failed to resolve source project path: %w", err)
 	}
 
-	destPrefix, err := resolveDestPrefix(opts.ProjectSelector, opts.PathPrefix, sourceProjectPath)
+	destPrefix, err := resolveDestPrefix(spec.Selector, spec.PathPrefix, sourceProjectPath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	strategy := opts.Strategy
+	if strategy == "" {
+		strategy = mergeStrategyNewest
 	}
 
 	report := &mergeReport{
 		SourceDB:          opts.SourceDB.Path(),
 		DestDB:            opts.DestDB.Path(),
-		ProjectSelector:   opts.ProjectSelector,
+		ProjectSelector:   spec.Selector,
 		SourceProjectUUID: projectUUID,
 		SourceProjectPath: sourceProjectPath,
 		DestPrefix:        destPrefix,
-		DryRun:            opts.DryRun,
+		DryRun:            dryRun,
+		Prune:             opts.Prune,
+		ConflictStrategy:  string(strategy),
+		planEnabled:       opts.Plan,
 	}
 
 	sourceData, err := loadSourceData(opts.SourceDB, projectUUID, sourceProjectPath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	actorUUIDs := collectActorUUIDs(sourceData)
+	for _, extra := range opts.IncludeActors {
+		if !slices.Contains(actorUUIDs, extra) {
+			actorUUIDs = append(actorUUIDs, extra)
+		}
+	}
 	actors, err := loadSourceActors(opts.SourceDB, actorUUIDs)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	sourceData.Actors = actors
 
-	var tx *sql.Tx
-	if !opts.DryRun {
-		tx, err = opts.DestDB.Begin()
-		if err != nil {
-			return nil, fmt.Errorf("failed to begin destination transaction: %w", err)
-		}
-		defer tx.Rollback()
-	}
-
-	writer := events.NewWriter(opts.DestDB.DB)
-	exec := newMergeExecutor(opts.DestDB, tx)
-
-	actorMap, err := mergeActors(exec, writer, opts.ActorUUID, sourceData.Actors, report, opts.DryRun)
+	actorMap, err := mergeActors(exec, writer, opts.ActorUUID, sourceData.Actors, report, dryRun)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	prefixParentUUID, prefixParentPath, err := ensurePrefixChain(exec, writer, opts.ActorUUID, destPrefix, report, opts.DryRun)
+	prefixParentUUID, prefixParentPath, err := ensurePrefixChain(exec, writer, opts.ActorUUID, destPrefix, report, dryRun)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	containerMap := make(map[string]string)
 	containerPath := make(map[string]string)
 
-	if err := mergeContainers(exec, writer, opts.ActorUUID, sourceData, sourceProjectPath, destPrefix, prefixParentUUID, prefixParentPath, actorMap, containerMap, containerPath, report, opts.DryRun); err != nil {
-		return nil, err
+	if err := mergeContainers(exec, writer, opts.ActorUUID, sourceData, sourceProjectPath, destPrefix, prefixParentUUID, prefixParentPath, actorMap, containerMap, containerPath, strategy, report, dryRun); err != nil {
+		return nil, nil, err
 	}
 
-	sectionMap, err := mergeSections(exec, writer, opts.ActorUUID, sourceData.Sections, projectUUID, containerMap[projectUUID], actorMap, report, opts.DryRun)
+	sectionMap, err := mergeSections(exec, writer, opts.ActorUUID, sourceData.Sections, projectUUID, containerMap[projectUUID], actorMap, report, dryRun)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	if err := applySectionRefs(exec, sourceData.Containers, sectionMap, containerMap, report, opts.DryRun); err != nil {
-		return nil, err
+	if err := applySectionRefs(exec, sourceData.Containers, sectionMap, containerMap, report, dryRun); err != nil {
+		return nil, nil, err
 	}
 
-	taskMap, err := mergeTasks(exec, writer, opts.ActorUUID, sourceData.Tasks, containerMap, actorMap, report, opts.DryRun)
+	taskMap, err := mergeTasks(exec, writer, opts.ActorUUID, sourceData.Tasks, containerMap, containerPath, actorMap, strategy, report, dryRun)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	if err := mergeComments(exec, writer, opts.ActorUUID, sourceData.Comments, taskMap, actorMap, report, opts.DryRun); err != nil {
-		return nil, err
+	if opts.Prune {
+		if err := pruneMissingTasks(exec, writer, opts.ActorUUID, destPrefix, taskMap, report, dryRun); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	if err := mergeRelations(exec, writer, opts.ActorUUID, sourceData.Relations, taskMap, actorMap, report, opts.DryRun); err != nil {
-		return nil, err
+	if err := mergeComments(exec, writer, opts.ActorUUID, sourceData.Comments, taskMap, actorMap, strategy, report, dryRun); err != nil {
+		return nil, nil, err
 	}
 
-	fileCopies, err := mergeAttachments(exec, writer, opts.ActorUUID, sourceData.Attachments, taskMap, actorMap, report, opts.DryRun)
-	if err != nil {
-		return nil, err
+	if err := mergeRelations(exec, writer, opts.ActorUUID, sourceData.Relations, taskMap, actorMap, report, dryRun); err != nil {
+		return nil, nil, err
 	}
 
-	if !opts.DryRun {
-		if _, err := db.FixSequenceDrifts(exec, db.DefaultSequenceSpecs()); err != nil {
-			return nil, fmt.Errorf("failed to sync sequences: %w", err)
-		}
-		if err := syncCommentSequence(exec); err != nil {
-			return nil, fmt.Errorf("failed to sync comment sequence: %w", err)
-		}
-		if err := tx.Commit(); err != nil {
-			return nil, fmt.Errorf("failed to commit merge: %w", err)
-		}
+	if err := mergeTimeEntries(exec, writer, opts.ActorUUID, sourceData.TimeEntries, taskMap, actorMap, report, dryRun); err != nil {
+		return nil, nil, err
+	}
 
-		copied, missing, warnings := performFileCopies(fileCopies, opts.SourceAttachDir, opts.DestAttachDir)
-		report.Stats.FilesCopied = copied
-		report.Stats.FilesMissing = missing
-		report.AttachmentWarnings = append(report.AttachmentWarnings, warnings...)
+	fileCopies, err := mergeAttachments(exec, writer, opts.ActorUUID, sourceData.Attachments, taskMap, actorMap, report, dryRun)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return report, nil
+	return report, fileCopies, nil
 }
 
 type mergeExecutor struct {
@@ -423,8 +731,7 @@ func looksLikeUUID(value string) bool {
 	return err == nil
 }
 
-func printMergeSummary(cmd *cobra.Command, report *mergeReport) {
-	out := cmd.OutOrStdout()
+func printMergeSummary(out io.Writer, report *mergeReport) {
 	fmt.Fprintf(out, "Merge %s -> %s\n", report.SourceDB, report.DestDB)
 	fmt.Fprintf(out, "Project: %s (%s)\n", report.ProjectSelector, report.SourceProjectPath)
 	fmt.Fprintf(out, "Prefix: %s\n", report.DestPrefix)
@@ -434,8 +741,12 @@ func printMergeSummary(cmd *cobra.Command, report *mergeReport) {
 	fmt.Fprintf(out, "Actors: %d created, %d updated, %d skipped\n", report.Stats.Actors.Created, report.Stats.Actors.Updated, report.Stats.Actors.Skipped)
 	fmt.Fprintf(out, "Containers: %d created, %d updated, %d renamed, %d skipped\n", report.Stats.Containers.Created, report.Stats.Containers.Updated, report.Stats.Containers.Renamed, report.Stats.Containers.Skipped)
 	fmt.Fprintf(out, "Tasks: %d created, %d updated, %d renamed, %d skipped\n", report.Stats.Tasks.Created, report.Stats.Tasks.Updated, report.Stats.Tasks.Renamed, report.Stats.Tasks.Skipped)
+	if report.Prune {
+		fmt.Fprintf(out, "Pruned tasks: %d\n", report.Stats.Pruned)
+	}
 	fmt.Fprintf(out, "Comments: %d created, %d updated, %d skipped\n", report.Stats.Comments.Created, report.Stats.Comments.Updated, report.Stats.Comments.Skipped)
 	fmt.Fprintf(out, "Relations: %d created, %d skipped\n", report.Stats.Relations.Created, report.Stats.Relations.Skipped)
+	fmt.Fprintf(out, "Time entries: %d created, %d skipped\n", report.Stats.TimeEntries.Created, report.Stats.TimeEntries.Skipped)
 	fmt.Fprintf(out, "Attachments: %d created, %d deduped, %d skipped\n", report.Stats.Attachments.Created, report.Stats.Attachments.Deduped, report.Stats.Attachments.Skipped)
 	if !report.DryRun {
 		fmt.Fprintf(out, "Files copied: %d, missing: %d\n", report.Stats.FilesCopied, report.Stats.FilesMissing)
@@ -461,6 +772,7 @@ type sourceData struct {
 	Comments    []sourceComment
 	Relations   []sourceRelation
 	Attachments []sourceAttachment
+	TimeEntries []sourceTimeEntry
 	Sections    []sourceSection
 	Actors      []sourceActor
 }
@@ -497,6 +809,7 @@ type sourceTask struct {
 	AssigneeUUID   sql.NullString
 	StartAt        sql.NullString
 	DueAt          sql.NullString
+	Estimate       sql.NullInt64
 	Labels         sql.NullString
 	Description    string
 	ETag           int64
@@ -521,6 +834,7 @@ type sourceComment struct {
 	UpdatedAt     sql.NullString
 	DeletedAt     sql.NullString
 	DeletedByUUID sql.NullString
+	ParentUUID    sql.NullString
 }
 
 type sourceRelation struct {
@@ -532,6 +846,16 @@ type sourceRelation struct {
 	CreatedBy    string
 }
 
+type sourceTimeEntry struct {
+	UUID      string
+	TaskUUID  string
+	ActorUUID string
+	Minutes   int
+	Note      string
+	StartedAt sql.NullString
+	CreatedAt string
+}
+
 type sourceAttachment struct {
 	UUID      string
 	ID        sql.NullString
@@ -570,6 +894,7 @@ type sourceActor struct {
 	Meta        sql.NullString
 	CreatedAt   string
 	UpdatedAt   string
+	ArchivedAt  sql.NullString
 }
 
 func loadSourceData(database *db.DB, projectUUID, projectPath string) (*sourceData, error) {
@@ -604,7 +929,7 @@ func loadSourceData(database *db.DB, projectUUID, projectPath string) (*sourceDa
 
 	tasks, err := database.Query(`
 		SELECT t.uuid, t.id, t.slug, t.title, t.project_uuid, t.state, t.priority, t.kind,
-		       t.parent_task_uuid, t.assignee_actor_uuid, t.start_at, t.due_at, t.labels,
+		       t.parent_task_uuid, t.assignee_actor_uuid, t.start_at, t.due_at, t.estimate_minutes, t.labels,
 		       t.description, t.etag, t.created_at, t.updated_at, t.completed_at,
 		       t.archived_at, t.deleted_at, t.created_by_actor_uuid, t.updated_by_actor_uuid
 		FROM tasks t
@@ -620,7 +945,7 @@ func loadSourceData(database *db.DB, projectUUID, projectPath string) (*sourceDa
 		var t sourceTask
 		if err := tasks.Scan(&t.UUID, &t.ID, &t.Slug, &t.Title, &t.ProjectUUID, &t.State,
 			&t.Priority, &t.Kind, &t.ParentTaskUUID, &t.AssigneeUUID, &t.StartAt,
-			&t.DueAt, &t.Labels, &t.Description, &t.ETag, &t.CreatedAt, &t.UpdatedAt,
+			&t.DueAt, &t.Estimate, &t.Labels, &t.Description, &t.ETag, &t.CreatedAt, &t.UpdatedAt,
 			&t.CompletedAt, &t.ArchivedAt, &t.DeletedAt, &t.CreatedBy, &t.UpdatedBy); err != nil {
 			return nil, fmt.Errorf("failed to scan source task: %w", err)
 		}
@@ -632,11 +957,12 @@ func loadSourceData(database *db.DB, projectUUID, projectPath string) (*sourceDa
 
 	comments, err := database.Query(`
 		SELECT c.uuid, c.id, c.task_uuid, c.actor_uuid, c.body, c.meta, c.etag, c.created_at,
-		       c.updated_at, c.deleted_at, c.deleted_by_actor_uuid
+		       c.updated_at, c.deleted_at, c.deleted_by_actor_uuid, c.parent_comment_uuid
 		FROM comments c
 		JOIN tasks t ON t.uuid = c.task_uuid
 		JOIN v_container_paths v ON v.uuid = t.project_uuid
 		WHERE v.path = ? OR v.path LIKE ?
+		ORDER BY c.created_at, c.id
 	`, projectPath, pathLike)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query source comments: %w", err)
@@ -646,7 +972,7 @@ func loadSourceData(database *db.DB, projectUUID, projectPath string) (*sourceDa
 	for comments.Next() {
 		var c sourceComment
 		if err := comments.Scan(&c.UUID, &c.ID, &c.TaskUUID, &c.ActorUUID, &c.Body, &c.Meta,
-			&c.ETag, &c.CreatedAt, &c.UpdatedAt, &c.DeletedAt, &c.DeletedByUUID); err != nil {
+			&c.ETag, &c.CreatedAt, &c.UpdatedAt, &c.DeletedAt, &c.DeletedByUUID, &c.ParentUUID); err != nil {
 			return nil, fmt.Errorf("failed to scan source comment: %w", err)
 		}
 		data.Comments = append(data.Comments, c)
@@ -678,6 +1004,30 @@ func loadSourceData(database *db.DB, projectUUID, projectPath string) (*sourceDa
 		return nil, fmt.Errorf("failed to iterate relations: %w", err)
 	}
 
+	timeEntries, err := database.Query(`
+		SELECT te.uuid, te.task_uuid, te.actor_uuid, te.minutes, te.note, te.started_at, te.created_at
+		FROM time_entries te
+		JOIN tasks t ON t.uuid = te.task_uuid
+		JOIN v_container_paths v ON v.uuid = t.project_uuid
+		WHERE v.path = ? OR v.path LIKE ?
+		ORDER BY te.created_at, te.rowid
+	`, projectPath, pathLike)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query source time entries: %w", err)
+	}
+	defer timeEntries.Close()
+
+	for timeEntries.Next() {
+		var e sourceTimeEntry
+		if err := timeEntries.Scan(&e.UUID, &e.TaskUUID, &e.ActorUUID, &e.Minutes, &e.Note, &e.StartedAt, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan source time entry: %w", err)
+		}
+		data.TimeEntries = append(data.TimeEntries, e)
+	}
+	if err := timeEntries.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate time entries: %w", err)
+	}
+
 	attachments, err := database.Query(`
 		SELECT a.uuid, a.id, a.task_uuid, a.filename, a.relative_path, a.mime_type,
 		       a.size_bytes, a.checksum, a.created_at, a.created_by_actor_uuid
@@ -755,6 +1105,9 @@ func collectActorUUIDs(data *sourceData) []string {
 	for _, r := range data.Relations {
 		set[r.CreatedBy] = struct{}{}
 	}
+	for _, e := range data.TimeEntries {
+		set[e.ActorUUID] = struct{}{}
+	}
 	for _, a := range data.Attachments {
 		if a.CreatedBy.Valid {
 			set[a.CreatedBy.String] = struct{}{}
@@ -778,7 +1131,7 @@ func loadSourceActors(database *db.DB, uuids []string) ([]sourceActor, error) {
 	placeholders := strings.Repeat("?,", len(uuids))
 	placeholders = strings.TrimSuffix(placeholders, ",")
 	query := fmt.Sprintf(`
-		SELECT uuid, id, slug, display_name, role, meta, created_at, updated_at
+		SELECT uuid, id, slug, display_name, role, meta, created_at, updated_at, archived_at
 		FROM actors
 		WHERE uuid IN (%s)
 	`, placeholders)
@@ -794,7 +1147,7 @@ func loadSourceActors(database *db.DB, uuids []string) ([]sourceActor, error) {
 	var actors []sourceActor
 	for rows.Next() {
 		var a sourceActor
-		if err := rows.Scan(&a.UUID, &a.ID, &a.Slug, &a.DisplayName, &a.Role, &a.Meta, &a.CreatedAt, &a.UpdatedAt); err != nil {
+		if err := rows.Scan(&a.UUID, &a.ID, &a.Slug, &a.DisplayName, &a.Role, &a.Meta, &a.CreatedAt, &a.UpdatedAt, &a.ArchivedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan source actor: %w", err)
 		}
 		actors = append(actors, a)
@@ -842,9 +1195,9 @@ func mergeActors(exec *mergeExecutor, writer *events.Writer, actorUUID string, a
 				if !dryRun {
 					if _, err := exec.Exec(`
 						UPDATE actors
-						SET display_name = ?, meta = ?
+						SET display_name = ?, meta = ?, archived_at = ?
 						WHERE uuid = ?
-					`, nullOrValue(a.DisplayName), nullOrValue(a.Meta), destUUID); err != nil {
+					`, nullOrValue(a.DisplayName), nullOrValue(a.Meta), nullOrValue(a.ArchivedAt), destUUID); err != nil {
 						return nil, fmt.Errorf("failed to update actor %s: %w", a.Slug, err)
 					}
 					payload := buildActorPayload(a)
@@ -853,8 +1206,10 @@ func mergeActors(exec *mergeExecutor, writer *events.Writer, actorUUID string, a
 					}
 				}
 				report.Stats.Actors.Updated++
+				report.addPlan(mergePlanOp{Entity: "actor", UUID: destUUID, Op: "update", NewPath: a.Slug})
 			} else {
 				report.Stats.Actors.Skipped++
+				report.addPlan(mergePlanOp{Entity: "actor", UUID: destUUID, Op: "skip", NewPath: a.Slug})
 			}
 			continue
 		}
@@ -885,9 +1240,9 @@ func mergeActors(exec *mergeExecutor, writer *events.Writer, actorUUID string, a
 			if sourceNewer(a.UpdatedAt, existingUpdated, 0, 0) && !dryRun {
 				if _, err := exec.Exec(`
 					UPDATE actors
-					SET slug = ?, display_name = ?, meta = ?
+					SET slug = ?, display_name = ?, meta = ?, archived_at = ?
 					WHERE uuid = ?
-				`, a.Slug, nullOrValue(a.DisplayName), nullOrValue(a.Meta), a.UUID); err != nil {
+				`, a.Slug, nullOrValue(a.DisplayName), nullOrValue(a.Meta), nullOrValue(a.ArchivedAt), a.UUID); err != nil {
 					return nil, fmt.Errorf("failed to update actor %s: %w", a.UUID, err)
 				}
 				payload := buildActorPayload(a)
@@ -895,10 +1250,13 @@ func mergeActors(exec *mergeExecutor, writer *events.Writer, actorUUID string, a
 					return nil, err
 				}
 				report.Stats.Actors.Updated++
+				report.addPlan(mergePlanOp{Entity: "actor", UUID: a.UUID, Op: "update", OldPath: existingSlug, NewPath: a.Slug})
 			} else if sourceNewer(a.UpdatedAt, existingUpdated, 0, 0) {
 				report.Stats.Actors.Updated++
+				report.addPlan(mergePlanOp{Entity: "actor", UUID: a.UUID, Op: "update", OldPath: existingSlug, NewPath: a.Slug})
 			} else {
 				report.Stats.Actors.Skipped++
+				report.addPlan(mergePlanOp{Entity: "actor", UUID: a.UUID, Op: "skip", OldPath: existingSlug, NewPath: existingSlug})
 			}
 			continue
 		}
@@ -916,10 +1274,10 @@ func mergeActors(exec *mergeExecutor, writer *events.Writer, actorUUID string, a
 				}
 			}
 			if _, err := exec.Exec(`
-				INSERT INTO actors (uuid, id, slug, display_name, role, meta, created_at, updated_at)
-				VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+				INSERT INTO actors (uuid, id, slug, display_name, role, meta, created_at, updated_at, archived_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 			`, a.UUID, idValue, a.Slug, nullOrValue(a.DisplayName), a.Role,
-				nullOrValue(a.Meta), a.CreatedAt, a.UpdatedAt); err != nil {
+				nullOrValue(a.Meta), a.CreatedAt, a.UpdatedAt, nullOrValue(a.ArchivedAt)); err != nil {
 				return nil, fmt.Errorf("failed to insert actor %s: %w", a.Slug, err)
 			}
 			payload := buildActorPayload(a)
@@ -928,6 +1286,7 @@ func mergeActors(exec *mergeExecutor, writer *events.Writer, actorUUID string, a
 			}
 		}
 		report.Stats.Actors.Created++
+		report.addPlan(mergePlanOp{Entity: "actor", UUID: a.UUID, Op: "create", NewPath: a.Slug})
 	}
 	return actorMap, nil
 }
@@ -940,6 +1299,9 @@ func buildActorPayload(a sourceActor) map[string]any {
 	if a.DisplayName.Valid {
 		payload["display_name"] = a.DisplayName.String
 	}
+	if a.ArchivedAt.Valid {
+		payload["archived_at"] = a.ArchivedAt.String
+	}
 	return payload
 }
 
@@ -992,7 +1354,7 @@ func ensurePrefixChain(exec *mergeExecutor, writer *events.Writer, actorUUID str
 	return parentUUID, parentPath, nil
 }
 
-func mergeContainers(exec *mergeExecutor, writer *events.Writer, actorUUID string, data *sourceData, sourceRootPath, destPrefix string, prefixParentUUID *string, prefixParentPath string, actorMap map[string]string, containerMap map[string]string, containerPath map[string]string, report *mergeReport, dryRun bool) error {
+func mergeContainers(exec *mergeExecutor, writer *events.Writer, actorUUID string, data *sourceData, sourceRootPath, destPrefix string, prefixParentUUID *string, prefixParentPath string, actorMap map[string]string, containerMap map[string]string, containerPath map[string]string, strategy mergeConflictStrategy, report *mergeReport, dryRun bool) error {
 	if len(data.Containers) == 0 {
 		return nil
 	}
@@ -1031,7 +1393,16 @@ func mergeContainers(exec *mergeExecutor, writer *events.Writer, actorUUID strin
 			desiredSlug = rootSlug
 		}
 
-		actualUUID, actualSlug, actualPath, created, updated, renamed, err := mergeContainer(exec, writer, actorUUID, c, desiredParentUUID, parentPath, desiredSlug, actorMap, report, dryRun)
+		var oldPath string
+		if report.planEnabled {
+			var err error
+			oldPath, err = lookupPlanPath(exec, "v_container_paths", c.UUID)
+			if err != nil {
+				return err
+			}
+		}
+
+		actualUUID, actualSlug, actualPath, created, updated, renamed, err := mergeContainer(exec, writer, actorUUID, c, desiredParentUUID, parentPath, desiredSlug, actorMap, strategy, report, dryRun)
 		if err != nil {
 			return err
 		}
@@ -1056,9 +1427,15 @@ func mergeContainers(exec *mergeExecutor, writer *events.Writer, actorUUID strin
 				Reason: "slug_collision",
 			})
 		}
-		if isRoot {
-			_ = actualSlug
+		newPath := actualPath
+		if created || updated {
+			newPath = joinPlanPath(parentPath, actualSlug)
+		}
+		op := mergePlanOp{Entity: "container", UUID: c.UUID, Op: planOp(created, updated, renamed), OldPath: oldPath, NewPath: newPath}
+		if renamed {
+			op.Reason = "slug_collision"
 		}
+		report.addPlan(op)
 	}
 
 	return nil
@@ -1076,7 +1453,7 @@ func buildPath(prefixParentPath, desiredSlug string, c sourceContainer, containe
 	return desiredSlug
 }
 
-func mergeContainer(exec *mergeExecutor, writer *events.Writer, actorUUID string, c sourceContainer, desiredParentUUID *string, parentPath string, desiredSlug string, actorMap map[string]string, report *mergeReport, dryRun bool) (string, string, string, bool, bool, bool, error) {
+func mergeContainer(exec *mergeExecutor, writer *events.Writer, actorUUID string, c sourceContainer, desiredParentUUID *string, parentPath string, desiredSlug string, actorMap map[string]string, strategy mergeConflictStrategy, report *mergeReport, dryRun bool) (string, string, string, bool, bool, bool, error) {
 	var destSlug, destParent sql.NullString
 	var destTitle, destDescription, destKind sql.NullString
 	var destSection sql.NullString
@@ -1137,7 +1514,18 @@ func mergeContainer(exec *mergeExecutor, writer *events.Writer, actorUUID string
 		created = true
 	} else {
 		actualSlug = destSlug.String
-		if sourceNewer(c.UpdatedAt, destUpdated, c.ETag, destETag) {
+		if strategy == mergeStrategyManual {
+			conflicts := fieldConflicts("container", c.UUID, []mergeFieldPair{
+				{"slug", desiredSlug, destSlug.String},
+				{"title", c.Title, destTitle.String},
+				{"description", c.Description, destDescription.String},
+				{"kind", c.Kind, destKind.String},
+			})
+			if len(conflicts) > 0 {
+				report.Stats.Containers.Conflicts++
+				report.Conflicts = append(report.Conflicts, conflicts...)
+			}
+		} else if strategy.sourceWins(c.UpdatedAt, destUpdated, c.ETag, destETag) {
 			var err error
 			actualSlug, renamed, err = resolveSlug()
 			if err != nil {
@@ -1233,6 +1621,10 @@ func mergeSections(exec *mergeExecutor, writer *events.Writer, actorUUID string,
 		if destProjectUUID == "" {
 			return nil, fmt.Errorf("destination project uuid missing for sections")
 		}
+		wipLimit, limitErr := store.NormalizeWIPLimit(s.WIPLimit)
+		if limitErr != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("section %s wip_limit %d cleared (%v)", s.UUID, s.WIPLimit.Int64, limitErr))
+		}
 		var destSlug string
 		var destUpdated string
 		var destRole string
@@ -1265,12 +1657,17 @@ func mergeSections(exec *mergeExecutor, writer *events.Writer, actorUUID string,
 						wip_limit, meta, created_at, updated_at, archived_at)
 					VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 				`, s.UUID, idValue, destProjectUUID, slug, s.Title, s.OrderIndex, s.Role, boolToInt(s.IsDefault),
-					nullOrValue(s.WIPLimit), nullOrValue(s.Meta), s.CreatedAt, s.UpdatedAt, nullOrValue(s.ArchivedAt))
+					wipLimit, nullOrValue(s.Meta), s.CreatedAt, s.UpdatedAt, nullOrValue(s.ArchivedAt))
 				if err != nil {
 					return nil, fmt.Errorf("failed to insert section %s: %w", s.UUID, err)
 				}
 			}
 			report.Stats.Sections.Created++
+			sectionPlan := mergePlanOp{Entity: "section", UUID: s.UUID, Op: planOp(true, false, renamed), NewPath: slug}
+			if renamed {
+				sectionPlan.Reason = "slug_collision"
+			}
+			report.addPlan(sectionPlan)
 			if renamed {
 				report.Stats.Sections.Renamed++
 				report.Renames = append(report.Renames, mergeRename{
@@ -1294,13 +1691,18 @@ func mergeSections(exec *mergeExecutor, writer *events.Writer, actorUUID string,
 					UPDATE sections
 					SET slug = ?, title = ?, order_index = ?, role = ?, is_default = ?, wip_limit = ?, meta = ?, archived_at = ?
 					WHERE uuid = ?
-				`, slug, s.Title, s.OrderIndex, s.Role, boolToInt(s.IsDefault), nullOrValue(s.WIPLimit),
+				`, slug, s.Title, s.OrderIndex, s.Role, boolToInt(s.IsDefault), wipLimit,
 					nullOrValue(s.Meta), nullOrValue(s.ArchivedAt), s.UUID)
 				if err != nil {
 					return nil, fmt.Errorf("failed to update section %s: %w", s.UUID, err)
 				}
 			}
 			report.Stats.Sections.Updated++
+			sectionPlan := mergePlanOp{Entity: "section", UUID: s.UUID, Op: planOp(false, true, renamed), OldPath: destSlug, NewPath: slug}
+			if renamed {
+				sectionPlan.Reason = "slug_collision"
+			}
+			report.addPlan(sectionPlan)
 			if renamed {
 				report.Stats.Sections.Renamed++
 				report.Renames = append(report.Renames, mergeRename{
@@ -1316,6 +1718,7 @@ func mergeSections(exec *mergeExecutor, writer *events.Writer, actorUUID string,
 				report.Stats.Sections.Conflicts++
 			}
 			report.Stats.Sections.Skipped++
+			report.addPlan(mergePlanOp{Entity: "section", UUID: s.UUID, Op: "skip", OldPath: destSlug, NewPath: destSlug})
 		}
 	}
 
@@ -1365,7 +1768,7 @@ func applySectionRefs(exec *mergeExecutor, containers []sourceContainer, section
 	return nil
 }
 
-func mergeTasks(exec *mergeExecutor, writer *events.Writer, actorUUID string, tasks []sourceTask, containerMap map[string]string, actorMap map[string]string, report *mergeReport, dryRun bool) (map[string]string, error) {
+func mergeTasks(exec *mergeExecutor, writer *events.Writer, actorUUID string, tasks []sourceTask, containerMap map[string]string, containerPath map[string]string, actorMap map[string]string, strategy mergeConflictStrategy, report *mergeReport, dryRun bool) (map[string]string, error) {
 	taskMap := make(map[string]string)
 	parents := make([]sourceTask, 0, len(tasks))
 	subtasks := make([]sourceTask, 0, len(tasks))
@@ -1394,8 +1797,17 @@ func mergeTasks(exec *mergeExecutor, writer *events.Writer, actorUUID string, ta
 			}
 		}
 
+		var oldPath string
+		if report.planEnabled {
+			var err error
+			oldPath, err = lookupPlanPath(exec, "v_task_paths", t.UUID)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		actualUUID := t.UUID
-		actualSlug, created, updated, renamed, err := mergeTask(exec, writer, actorUUID, t, destProjectUUID, parentUUID, actorMap, report, dryRun)
+		actualSlug, created, updated, renamed, err := mergeTask(exec, writer, actorUUID, t, destProjectUUID, parentUUID, actorMap, strategy, report, dryRun)
 		if err != nil {
 			return nil, err
 		}
@@ -1418,18 +1830,33 @@ func mergeTasks(exec *mergeExecutor, writer *events.Writer, actorUUID string, ta
 				Reason: "slug_collision",
 			})
 		}
+
+		newPath := oldPath
+		if created || updated {
+			newPath = joinPlanPath(containerPath[t.ProjectUUID], actualSlug)
+		}
+		op := mergePlanOp{Entity: "task", UUID: t.UUID, Op: planOp(created, updated, renamed), OldPath: oldPath, NewPath: newPath}
+		if renamed {
+			op.Reason = "slug_collision"
+		}
+		report.addPlan(op)
 	}
 
 	return taskMap, nil
 }
 
-func mergeTask(exec *mergeExecutor, writer *events.Writer, actorUUID string, t sourceTask, destProjectUUID string, parentUUID sql.NullString, actorMap map[string]string, report *mergeReport, dryRun bool) (string, bool, bool, bool, error) {
-	var destSlug string
+func mergeTask(exec *mergeExecutor, writer *events.Writer, actorUUID string, t sourceTask, destProjectUUID string, parentUUID sql.NullString, actorMap map[string]string, strategy mergeConflictStrategy, report *mergeReport, dryRun bool) (string, bool, bool, bool, error) {
+	var destSlug, destTitle, destState, destKind, destDescription string
+	var destPriority int
+	var destDueAt, destLabels sql.NullString
+	var destEstimate sql.NullInt64
 	var destETag int64
 	var destUpdated string
 	err := exec.QueryRow(`
-		SELECT slug, etag, updated_at FROM tasks WHERE uuid = ?
-	`, t.UUID).Scan(&destSlug, &destETag, &destUpdated)
+		SELECT slug, title, state, priority, kind, description, due_at, estimate_minutes, labels, etag, updated_at
+		FROM tasks WHERE uuid = ?
+	`, t.UUID).Scan(&destSlug, &destTitle, &destState, &destPriority, &destKind, &destDescription,
+		&destDueAt, &destEstimate, &destLabels, &destETag, &destUpdated)
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		return "", false, false, false, fmt.Errorf("failed to lookup task %s: %w", t.UUID, err)
 	}
@@ -1456,12 +1883,12 @@ func mergeTask(exec *mergeExecutor, writer *events.Writer, actorUUID string, t s
 			}
 			_, err = exec.Exec(`
 				INSERT INTO tasks (uuid, id, slug, title, project_uuid, state, priority, kind, parent_task_uuid,
-					assignee_actor_uuid, start_at, due_at, labels, description, etag, created_at, updated_at,
+					assignee_actor_uuid, start_at, due_at, estimate_minutes, labels, description, etag, created_at, updated_at,
 					completed_at, archived_at, deleted_at, created_by_actor_uuid, updated_by_actor_uuid)
-				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 			`, t.UUID, idValue, slug, t.Title, destProjectUUID, t.State, t.Priority, t.Kind,
 				nullOrValue(parentUUID), mapActorNullable(actorMap, t.AssigneeUUID), nullOrValue(t.StartAt),
-				nullOrValue(t.DueAt), nullOrValue(t.Labels), t.Description, t.ETag, t.CreatedAt, t.UpdatedAt,
+				nullOrValue(t.DueAt), nullOrValue(t.Estimate), nullOrValue(t.Labels), t.Description, t.ETag, t.CreatedAt, t.UpdatedAt,
 				nullOrValue(t.CompletedAt), nullOrValue(t.ArchivedAt), nullOrValue(t.DeletedAt),
 				mapActor(actorMap, t.CreatedBy), mapActor(actorMap, t.UpdatedBy))
 			if err != nil {
@@ -1475,7 +1902,26 @@ func mergeTask(exec *mergeExecutor, writer *events.Writer, actorUUID string, t s
 		return slug, true, false, renamed, nil
 	}
 
-	if !sourceNewer(t.UpdatedAt, destUpdated, t.ETag, destETag) {
+	if strategy == mergeStrategyManual {
+		conflicts := fieldConflicts("task", t.UUID, []mergeFieldPair{
+			{"slug", t.Slug, destSlug},
+			{"title", t.Title, destTitle},
+			{"state", t.State, destState},
+			{"priority", fmt.Sprintf("%d", t.Priority), fmt.Sprintf("%d", destPriority)},
+			{"kind", t.Kind, destKind},
+			{"description", t.Description, destDescription},
+			{"due_at", t.DueAt.String, destDueAt.String},
+			{"estimate_minutes", nullIntString(t.Estimate), nullIntString(destEstimate)},
+			{"labels", t.Labels.String, destLabels.String},
+		})
+		if len(conflicts) > 0 {
+			report.Stats.Tasks.Conflicts++
+			report.Conflicts = append(report.Conflicts, conflicts...)
+		}
+		return destSlug, false, false, false, nil
+	}
+
+	if !strategy.sourceWins(t.UpdatedAt, destUpdated, t.ETag, destETag) {
 		report.Stats.Tasks.Conflicts++
 		return destSlug, false, false, false, nil
 	}
@@ -1489,11 +1935,11 @@ func mergeTask(exec *mergeExecutor, writer *events.Writer, actorUUID string, t s
 		_, err := exec.Exec(`
 			UPDATE tasks
 			SET slug = ?, title = ?, project_uuid = ?, state = ?, priority = ?, kind = ?, parent_task_uuid = ?,
-				assignee_actor_uuid = ?, start_at = ?, due_at = ?, labels = ?, description = ?,
+				assignee_actor_uuid = ?, start_at = ?, due_at = ?, estimate_minutes = ?, labels = ?, description = ?,
 				completed_at = ?, archived_at = ?, deleted_at = ?, updated_by_actor_uuid = ?, updated_at = ?
 			WHERE uuid = ?
 		`, slug, t.Title, destProjectUUID, t.State, t.Priority, t.Kind, nullOrValue(parentUUID),
-			mapActorNullable(actorMap, t.AssigneeUUID), nullOrValue(t.StartAt), nullOrValue(t.DueAt), nullOrValue(t.Labels),
+			mapActorNullable(actorMap, t.AssigneeUUID), nullOrValue(t.StartAt), nullOrValue(t.DueAt), nullOrValue(t.Estimate), nullOrValue(t.Labels),
 			t.Description, nullOrValue(t.CompletedAt), nullOrValue(t.ArchivedAt), nullOrValue(t.DeletedAt),
 			mapActor(actorMap, t.UpdatedBy), t.UpdatedAt, t.UUID)
 		if err != nil {
@@ -1507,6 +1953,74 @@ func mergeTask(exec *mergeExecutor, writer *events.Writer, actorUUID string, t s
 	return slug, false, true, renamed, nil
 }
 
+// pruneMissingTasks soft-deletes destination tasks under the merge prefix whose
+// UUIDs were not present in the source. Tasks outside the prefix are untouched.
+func pruneMissingTasks(exec *mergeExecutor, writer *events.Writer, actorUUID, destPrefix string, taskMap map[string]string, report *mergeReport, dryRun bool) error {
+	rows, err := exec.Query(`
+		SELECT t.uuid, t.etag
+		FROM tasks t
+		JOIN v_container_paths v ON v.uuid = t.project_uuid
+		WHERE (v.path = ? OR v.path LIKE ?) AND t.state != 'deleted'
+		ORDER BY t.id
+	`, destPrefix, destPrefix+"/%")
+	if err != nil {
+		return fmt.Errorf("failed to query destination tasks for prune: %w", err)
+	}
+
+	type pruneCandidate struct {
+		uuid string
+		etag int64
+	}
+	var candidates []pruneCandidate
+	for rows.Next() {
+		var c pruneCandidate
+		if err := rows.Scan(&c.uuid, &c.etag); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan destination task: %w", err)
+		}
+		if _, ok := taskMap[c.uuid]; ok {
+			continue
+		}
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return fmt.Errorf("failed to iterate destination tasks: %w", err)
+	}
+	rows.Close()
+
+	for _, c := range candidates {
+		report.Stats.Pruned++
+		if report.planEnabled {
+			path, err := lookupPlanPath(exec, "v_task_paths", c.uuid)
+			if err != nil {
+				return err
+			}
+			report.addPlan(mergePlanOp{Entity: "task", UUID: c.uuid, Op: "prune", OldPath: path, Reason: "missing_from_source"})
+		}
+		if dryRun {
+			continue
+		}
+		newETag := c.etag + 1
+		if _, err := exec.Exec(`
+			UPDATE tasks
+			SET state = 'deleted',
+			    deleted_at = strftime('%Y-%m-%dT%H:%M:%SZ','now'),
+			    etag = ?,
+			    updated_by_actor_uuid = ?
+			WHERE uuid = ?
+		`, newETag, actorUUID, c.uuid); err != nil {
+			return fmt.Errorf("failed to prune task %s: %w", c.uuid, err)
+		}
+		payload := map[string]any{"action": "merge_pruned", "reason": "missing_from_source"}
+		if err := logMergeEvent(exec, writer, actorUUID, "task", c.uuid, "task.deleted", &newETag, payload); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func ensureUniqueTaskSlug(exec *mergeExecutor, projectUUID, uuid, desired string) (string, bool, error) {
 	for idx := 0; idx < 1000; idx++ {
 		candidate := desired
@@ -1531,20 +2045,22 @@ func ensureUniqueTaskSlug(exec *mergeExecutor, projectUUID, uuid, desired string
 	return "", false, fmt.Errorf("unable to resolve task slug collision for %s", desired)
 }
 
-func mergeComments(exec *mergeExecutor, writer *events.Writer, actorUUID string, comments []sourceComment, taskMap map[string]string, actorMap map[string]string, report *mergeReport, dryRun bool) error {
+func mergeComments(exec *mergeExecutor, writer *events.Writer, actorUUID string, comments []sourceComment, taskMap map[string]string, actorMap map[string]string, strategy mergeConflictStrategy, report *mergeReport, dryRun bool) error {
 	for _, c := range comments {
 		report.Stats.Comments.Seen++
 		destTask, ok := taskMap[c.TaskUUID]
 		if !ok {
 			report.Warnings = append(report.Warnings, fmt.Sprintf("comment %s references missing task %s", c.UUID, c.TaskUUID))
 			report.Stats.Comments.Skipped++
+			report.addPlan(mergePlanOp{Entity: "comment", UUID: c.UUID, Op: "skip", Reason: "missing_task"})
 			continue
 		}
 		var destETag int64
+		var destBody string
 		var destUpdated sql.NullString
 		err := exec.QueryRow(`
-			SELECT etag, updated_at FROM comments WHERE uuid = ?
-		`, c.UUID).Scan(&destETag, &destUpdated)
+			SELECT etag, body, updated_at FROM comments WHERE uuid = ?
+		`, c.UUID).Scan(&destETag, &destBody, &destUpdated)
 		if err != nil && !errors.Is(err, sql.ErrNoRows) {
 			return fmt.Errorf("failed to lookup comment %s: %w", c.UUID, err)
 		}
@@ -1558,11 +2074,22 @@ func mergeComments(exec *mergeExecutor, writer *events.Writer, actorUUID string,
 						return err
 					}
 				}
+				// Replies keep their parent only if it landed on the same task;
+				// comments are read oldest first, so parents are merged first
+				var parentUUID interface{}
+				if c.ParentUUID.Valid {
+					var parentTask string
+					if err := exec.QueryRow("SELECT task_uuid FROM comments WHERE uuid = ?", c.ParentUUID.String).Scan(&parentTask); err == nil && parentTask == destTask {
+						parentUUID = c.ParentUUID.String
+					} else {
+						report.Warnings = append(report.Warnings, fmt.Sprintf("comment %s replies to comment %s, which was not merged onto the same task; merging it as a top-level comment", c.UUID, c.ParentUUID.String))
+					}
+				}
 				_, err := exec.Exec(`
-					INSERT INTO comments (uuid, id, task_uuid, actor_uuid, body, meta, etag, created_at, updated_at, deleted_at, deleted_by_actor_uuid)
-					VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+					INSERT INTO comments (uuid, id, task_uuid, actor_uuid, body, meta, etag, created_at, updated_at, deleted_at, deleted_by_actor_uuid, parent_comment_uuid)
+					VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 				`, c.UUID, commentID, destTask, mapActor(actorMap, c.ActorUUID), c.Body, nullOrValue(c.Meta), c.ETag,
-					c.CreatedAt, nullOrValue(c.UpdatedAt), nullOrValue(c.DeletedAt), mapActorNullable(actorMap, c.DeletedByUUID))
+					c.CreatedAt, nullOrValue(c.UpdatedAt), nullOrValue(c.DeletedAt), mapActorNullable(actorMap, c.DeletedByUUID), parentUUID)
 				if err != nil {
 					return fmt.Errorf("failed to insert comment %s: %w", c.UUID, err)
 				}
@@ -1572,11 +2099,28 @@ func mergeComments(exec *mergeExecutor, writer *events.Writer, actorUUID string,
 				}
 			}
 			report.Stats.Comments.Created++
+			report.addPlan(mergePlanOp{Entity: "comment", UUID: c.UUID, Op: "create"})
 			continue
 		}
 
-		if destUpdated.Valid && !sourceNewer(nullableString(c.UpdatedAt, c.CreatedAt), destUpdated.String, c.ETag, destETag) {
+		if strategy == mergeStrategyManual {
+			conflicts := fieldConflicts("comment", c.UUID, []mergeFieldPair{
+				{"body", c.Body, destBody},
+			})
+			if len(conflicts) > 0 {
+				report.Stats.Comments.Conflicts++
+				report.Conflicts = append(report.Conflicts, conflicts...)
+				report.addPlan(mergePlanOp{Entity: "comment", UUID: c.UUID, Op: "skip", Reason: "manual_conflict"})
+			} else {
+				report.Stats.Comments.Skipped++
+				report.addPlan(mergePlanOp{Entity: "comment", UUID: c.UUID, Op: "skip"})
+			}
+			continue
+		}
+
+		if destUpdated.Valid && !strategy.sourceWins(nullableString(c.UpdatedAt, c.CreatedAt), destUpdated.String, c.ETag, destETag) {
 			report.Stats.Comments.Skipped++
+			report.addPlan(mergePlanOp{Entity: "comment", UUID: c.UUID, Op: "skip"})
 			continue
 		}
 
@@ -1596,6 +2140,7 @@ func mergeComments(exec *mergeExecutor, writer *events.Writer, actorUUID string,
 			}
 		}
 		report.Stats.Comments.Updated++
+		report.addPlan(mergePlanOp{Entity: "comment", UUID: c.UUID, Op: "update"})
 	}
 	return nil
 }
@@ -1608,6 +2153,7 @@ func mergeRelations(exec *mergeExecutor, writer *events.Writer, actorUUID string
 		if !okFrom || !okTo {
 			report.Warnings = append(report.Warnings, fmt.Sprintf("relation %s -> %s skipped (missing task)", r.FromTaskUUID, r.ToTaskUUID))
 			report.Stats.Relations.Skipped++
+			report.addPlan(mergePlanOp{Entity: "relation", UUID: r.FromTaskUUID, Op: "skip", NewPath: r.Kind + ":" + r.ToTaskUUID, Reason: "missing_task"})
 			continue
 		}
 		var count int
@@ -1618,8 +2164,20 @@ func mergeRelations(exec *mergeExecutor, writer *events.Writer, actorUUID string
 		}
 		if count > 0 {
 			report.Stats.Relations.Skipped++
+			report.addPlan(mergePlanOp{Entity: "relation", UUID: fromTask, Op: "skip", NewPath: r.Kind + ":" + toTask, Reason: "exists"})
 			continue
 		}
+		if r.Kind == string(domain.TaskRelationBlocks) {
+			if err := checkBlocksCycle(exec, fromTask, toTask); err != nil {
+				if !errors.Is(err, errBlocksCycle) {
+					return err
+				}
+				report.Warnings = append(report.Warnings, fmt.Sprintf("relation %s -> %s skipped (%v)", r.FromTaskUUID, r.ToTaskUUID, err))
+				report.Stats.Relations.Skipped++
+				report.addPlan(mergePlanOp{Entity: "relation", UUID: fromTask, Op: "skip", NewPath: r.Kind + ":" + toTask, Reason: "cycle"})
+				continue
+			}
+		}
 		if !dryRun {
 			_, err := exec.Exec(`
 				INSERT INTO task_relations (from_task_uuid, to_task_uuid, kind, meta, created_at, created_by_actor_uuid)
@@ -1634,6 +2192,45 @@ func mergeRelations(exec *mergeExecutor, writer *events.Writer, actorUUID string
 			}
 		}
 		report.Stats.Relations.Created++
+		report.addPlan(mergePlanOp{Entity: "relation", UUID: fromTask, Op: "create", NewPath: r.Kind + ":" + toTask})
+	}
+	return nil
+}
+
+func mergeTimeEntries(exec *mergeExecutor, writer *events.Writer, actorUUID string, entries []sourceTimeEntry, taskMap map[string]string, actorMap map[string]string, report *mergeReport, dryRun bool) error {
+	for _, e := range entries {
+		report.Stats.TimeEntries.Seen++
+		taskUUID, ok := taskMap[e.TaskUUID]
+		if !ok {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("time entry %s skipped (missing task)", e.UUID))
+			report.Stats.TimeEntries.Skipped++
+			report.addPlan(mergePlanOp{Entity: "time_entry", UUID: e.UUID, Op: "skip", Reason: "missing_task"})
+			continue
+		}
+		var count int
+		if err := exec.QueryRow(`SELECT COUNT(*) FROM time_entries WHERE uuid = ?`, e.UUID).Scan(&count); err != nil {
+			return fmt.Errorf("failed to check time entry: %w", err)
+		}
+		if count > 0 {
+			report.Stats.TimeEntries.Skipped++
+			report.addPlan(mergePlanOp{Entity: "time_entry", UUID: e.UUID, Op: "skip", Reason: "exists"})
+			continue
+		}
+		if !dryRun {
+			_, err := exec.Exec(`
+				INSERT INTO time_entries (uuid, task_uuid, actor_uuid, minutes, note, started_at, created_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?)
+			`, e.UUID, taskUUID, mapActor(actorMap, e.ActorUUID), e.Minutes, e.Note, nullOrValue(e.StartedAt), e.CreatedAt)
+			if err != nil {
+				return fmt.Errorf("failed to insert time entry: %w", err)
+			}
+			payload := map[string]any{"time_entry_uuid": e.UUID, "minutes": e.Minutes}
+			if err := logMergeEvent(exec, writer, actorUUID, "task", taskUUID, "task.time_logged", nil, payload); err != nil {
+				return err
+			}
+		}
+		report.Stats.TimeEntries.Created++
+		report.addPlan(mergePlanOp{Entity: "time_entry", UUID: e.UUID, Op: "create"})
 	}
 	return nil
 }
@@ -1651,6 +2248,7 @@ func mergeAttachments(exec *mergeExecutor, writer *events.Writer, actorUUID stri
 		if !ok {
 			report.Warnings = append(report.Warnings, fmt.Sprintf("attachment %s references missing task %s", a.UUID, a.TaskUUID))
 			report.Stats.Attachments.Skipped++
+			report.addPlan(mergePlanOp{Entity: "attachment", UUID: a.UUID, Op: "skip", NewPath: a.RelPath, Reason: "missing_task"})
 			continue
 		}
 
@@ -1667,9 +2265,11 @@ func mergeAttachments(exec *mergeExecutor, writer *events.Writer, actorUUID stri
 				report.Stats.Attachments.Conflicts++
 				report.Warnings = append(report.Warnings, fmt.Sprintf("attachment uuid %s has different relative_path", a.UUID))
 				report.Stats.Attachments.Skipped++
+				report.addPlan(mergePlanOp{Entity: "attachment", UUID: a.UUID, Op: "skip", OldPath: existingRelPath.String, NewPath: a.RelPath, Reason: "path_conflict"})
 				continue
 			}
 			report.Stats.Attachments.Deduped++
+			report.addPlan(mergePlanOp{Entity: "attachment", UUID: a.UUID, Op: "skip", OldPath: a.RelPath, NewPath: a.RelPath, Reason: "deduped"})
 			files = append(files, fileCopy{SourceRelPath: a.RelPath, DestRelPath: a.RelPath})
 			continue
 		}
@@ -1685,12 +2285,14 @@ func mergeAttachments(exec *mergeExecutor, writer *events.Writer, actorUUID stri
 		if err == nil {
 			if existingChecksumByPath.Valid && a.Checksum.Valid && existingChecksumByPath.String == a.Checksum.String {
 				report.Stats.Attachments.Deduped++
+				report.addPlan(mergePlanOp{Entity: "attachment", UUID: a.UUID, Op: "skip", OldPath: a.RelPath, NewPath: a.RelPath, Reason: "deduped"})
 				files = append(files, fileCopy{SourceRelPath: a.RelPath, DestRelPath: a.RelPath})
 				continue
 			}
 			report.Stats.Attachments.Conflicts++
 			report.Warnings = append(report.Warnings, fmt.Sprintf("attachment conflict at %s", a.RelPath))
 			report.Stats.Attachments.Skipped++
+			report.addPlan(mergePlanOp{Entity: "attachment", UUID: a.UUID, Op: "skip", OldPath: a.RelPath, NewPath: a.RelPath, Reason: "path_conflict"})
 			continue
 		}
 
@@ -1719,6 +2321,7 @@ func mergeAttachments(exec *mergeExecutor, writer *events.Writer, actorUUID stri
 			}
 		}
 		report.Stats.Attachments.Created++
+		report.addPlan(mergePlanOp{Entity: "attachment", UUID: a.UUID, Op: "create", NewPath: a.RelPath})
 		files = append(files, fileCopy{SourceRelPath: a.RelPath, DestRelPath: a.RelPath})
 	}
 	return files, nil
@@ -1835,6 +2438,68 @@ func mapActorNullable(mapping map[string]string, uuid sql.NullString) any {
 	return mapActor(mapping, uuid.String)
 }
 
+// mergeConflictStrategy decides which side wins when a record exists in both
+// the source and destination databases.
+type mergeConflictStrategy string
+
+const (
+	mergeStrategyNewest     mergeConflictStrategy = "newest"
+	mergeStrategySourceWins mergeConflictStrategy = "source-wins"
+	mergeStrategyDestWins   mergeConflictStrategy = "dest-wins"
+	mergeStrategyManual     mergeConflictStrategy = "manual"
+)
+
+func parseMergeConflictStrategy(value string) (mergeConflictStrategy, error) {
+	switch strategy := mergeConflictStrategy(strings.ToLower(strings.TrimSpace(value))); strategy {
+	case "":
+		return mergeStrategyNewest, nil
+	case mergeStrategyNewest, mergeStrategySourceWins, mergeStrategyDestWins, mergeStrategyManual:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("invalid conflict strategy %q (expected newest, source-wins, dest-wins, or manual)", value)
+	}
+}
+
+// sourceWins reports whether the source record should overwrite the existing
+// destination record. Manual conflicts are handled by the callers, so manual
+// never overwrites here.
+func (s mergeConflictStrategy) sourceWins(srcUpdated, destUpdated string, srcETag, destETag int64) bool {
+	switch s {
+	case mergeStrategySourceWins:
+		return srcUpdated != destUpdated || srcETag != destETag
+	case mergeStrategyDestWins, mergeStrategyManual:
+		return false
+	default:
+		return sourceNewer(srcUpdated, destUpdated, srcETag, destETag)
+	}
+}
+
+type mergeFieldPair struct {
+	Field  string
+	Source string
+	Dest   string
+}
+
+// fieldConflicts returns an unresolved conflict for each field whose source
+// and destination values differ.
+func fieldConflicts(entity, uuid string, fields []mergeFieldPair) []mergeConflict {
+	var conflicts []mergeConflict
+	for _, f := range fields {
+		if f.Source == f.Dest {
+			continue
+		}
+		conflicts = append(conflicts, mergeConflict{
+			Entity:   entity,
+			UUID:     uuid,
+			Field:    f.Field,
+			Source:   f.Source,
+			Dest:     f.Dest,
+			Resolved: "manual",
+		})
+	}
+	return conflicts
+}
+
 func sourceNewer(srcUpdated, destUpdated string, srcETag, destETag int64) bool {
 	if srcUpdated != "" && destUpdated != "" {
 		srcTime, err1 := time.Parse(time.RFC3339, srcUpdated)
@@ -1846,6 +2511,15 @@ func sourceNewer(srcUpdated, destUpdated string, srcETag, destETag int64) bool {
 	return srcETag > destETag
 }
 
+// nullIntString formats a nullable integer for a field conflict, with ""
+// for NULL.
+func nullIntString(v sql.NullInt64) string {
+	if !v.Valid {
+		return ""
+	}
+	return fmt.Sprintf("%d", v.Int64)
+}
+
 func nullableString(primary sql.NullString, fallback string) string {
 	if primary.Valid {
 		return primary.String
@@ -1868,11 +2542,7 @@ func nextCommentID(exec *mergeExecutor) (string, error) {
 }
 
 func syncCommentSequence(exec *mergeExecutor) error {
-	var nextSeq int
-	if err := exec.QueryRow("SELECT COALESCE(MAX(CAST(SUBSTR(id, 3) AS INTEGER)), 0) FROM comments").Scan(&nextSeq); err != nil {
-		return err
-	}
-	_, err := exec.Exec("UPDATE comment_sequences SET value = ? WHERE name = 'next_comment'", nextSeq)
+	_, err := db.FixCommentSequence(exec)
 	return err
 }
 
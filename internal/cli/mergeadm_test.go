@@ -1,6 +1,11 @@
 package cli
 
 import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -264,6 +269,106 @@ func TestMergeAttachmentCopy(t *testing.T) {
 	}
 }
 
+func TestMergeCarriesTimeTracking(t *testing.T) {
+	srcDB, _ := setupMergeDB(t)
+	destDB, _ := setupMergeDB(t)
+
+	projectUUID := "00000000-0000-0000-0000-000000000060"
+	taskUUID := "00000000-0000-0000-0000-000000000061"
+	insertContainer(t, srcDB, projectUUID, "P-00060", "proj", "Project", "", "2024-02-01T00:00:00Z")
+	insertTask(t, srcDB, taskUUID, "T-00060", "timed", "Timed", projectUUID)
+	if _, err := srcDB.Exec(`UPDATE tasks SET estimate_minutes = 120 WHERE uuid = ?`, taskUUID); err != nil {
+		t.Fatalf("failed to set estimate: %v", err)
+	}
+	for i, minutes := range []int{20, 40} {
+		_, err := srcDB.Exec(`
+			INSERT INTO time_entries (uuid, task_uuid, actor_uuid, minutes, created_at)
+			VALUES (?, ?, ?, ?, '2024-02-01T00:00:00Z')
+		`, fmt.Sprintf("00000000-0000-0000-0000-00000000007%d", i), taskUUID, testActorUUID, minutes)
+		if err != nil {
+			t.Fatalf("failed to insert time entry: %v", err)
+		}
+	}
+
+	opts := mergeOptions{
+		SourceDB:        srcDB,
+		DestDB:          destDB,
+		ProjectSelector: "proj",
+		PathPrefix:      "proj",
+		ActorUUID:       testActorUUID,
+	}
+	report, err := mergeProjectIntoCanonical(opts)
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	if report.Stats.TimeEntries.Created != 2 {
+		t.Fatalf("expected 2 time entries created, got %+v", report.Stats.TimeEntries)
+	}
+
+	var estimate, logged int
+	if err := destDB.QueryRow(`
+		SELECT t.estimate_minutes, (SELECT SUM(minutes) FROM time_entries WHERE task_uuid = t.uuid)
+		FROM tasks t WHERE t.uuid = ?
+	`, taskUUID).Scan(&estimate, &logged); err != nil {
+		t.Fatalf("failed to read merged task: %v", err)
+	}
+	if estimate != 120 || logged != 60 {
+		t.Errorf("expected estimate 120 and 60 minutes logged, got %d and %d", estimate, logged)
+	}
+
+	report, err = mergeProjectIntoCanonical(opts)
+	if err != nil {
+		t.Fatalf("second merge failed: %v", err)
+	}
+	if report.Stats.TimeEntries.Created != 0 || report.Stats.TimeEntries.Skipped != 2 {
+		t.Errorf("expected a repeat merge to skip both entries, got %+v", report.Stats.TimeEntries)
+	}
+}
+
+func TestMergeSkipsBlocksCycle(t *testing.T) {
+	srcDB, _ := setupMergeDB(t)
+	destDB, _ := setupMergeDB(t)
+
+	projectUUID := "00000000-0000-0000-0000-000000000090"
+	firstUUID := "00000000-0000-0000-0000-000000000091"
+	secondUUID := "00000000-0000-0000-0000-000000000092"
+	insertContainer(t, srcDB, projectUUID, "P-00090", "proj", "Project", "", "2024-02-01T00:00:00Z")
+	insertTask(t, srcDB, firstUUID, "T-00091", "first", "First", projectUUID)
+	insertTask(t, srcDB, secondUUID, "T-00092", "second", "Second", projectUUID)
+	// Source rows predating the cycle check can already contain a cycle
+	for _, pair := range [][2]string{{firstUUID, secondUUID}, {secondUUID, firstUUID}} {
+		_, err := srcDB.Exec(`
+			INSERT INTO task_relations (from_task_uuid, to_task_uuid, kind, created_at, created_by_actor_uuid)
+			VALUES (?, ?, 'blocks', '2024-02-01T00:00:00Z', ?)
+		`, pair[0], pair[1], testActorUUID)
+		if err != nil {
+			t.Fatalf("failed to insert relation: %v", err)
+		}
+	}
+
+	report, err := mergeProjectIntoCanonical(mergeOptions{
+		SourceDB:        srcDB,
+		DestDB:          destDB,
+		ProjectSelector: "proj",
+		PathPrefix:      "proj",
+		ActorUUID:       testActorUUID,
+	})
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	if report.Stats.Relations.Created != 1 || report.Stats.Relations.Skipped != 1 {
+		t.Fatalf("expected one relation created and one skipped, got %+v", report.Stats.Relations)
+	}
+
+	var count int
+	if err := destDB.QueryRow(`SELECT COUNT(*) FROM task_relations WHERE kind = 'blocks'`).Scan(&count); err != nil {
+		t.Fatalf("failed to count relations: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 blocks relation in the destination, got %d", count)
+	}
+}
+
 func TestMergeDryRunNoWrite(t *testing.T) {
 	srcDB, _ := setupMergeDB(t)
 	destDB, _ := setupMergeDB(t)
@@ -293,3 +398,447 @@ func TestMergeDryRunNoWrite(t *testing.T) {
 		t.Fatalf("expected no containers written in dry-run")
 	}
 }
+
+func TestMergeCarriesActorArchivedAt(t *testing.T) {
+	srcDB, _ := setupMergeDB(t)
+	destDB, _ := setupMergeDB(t)
+
+	projectUUID := "00000000-0000-0000-0000-000000000065"
+	insertContainer(t, srcDB, projectUUID, "P-00065", "proj", "Project", "", "2024-02-01T00:00:00Z")
+	if _, err := srcDB.Exec("UPDATE actors SET archived_at = '2024-03-01T00:00:00Z' WHERE uuid = ?", testActorUUID); err != nil {
+		t.Fatalf("failed to archive source actor: %v", err)
+	}
+
+	_, err := mergeProjectIntoCanonical(mergeOptions{
+		SourceDB:        srcDB,
+		DestDB:          destDB,
+		SourceAttachDir: t.TempDir(),
+		DestAttachDir:   t.TempDir(),
+		ProjectSelector: "proj",
+		PathPrefix:      "proj",
+		ActorUUID:       testActorUUID,
+	})
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+
+	var archivedAt sql.NullString
+	if err := destDB.QueryRow("SELECT archived_at FROM actors WHERE uuid = ?", testActorUUID).Scan(&archivedAt); err != nil {
+		t.Fatalf("failed to query dest actor: %v", err)
+	}
+	if archivedAt.String != "2024-03-01T00:00:00Z" {
+		t.Fatalf("expected archived_at to carry across, got %v", archivedAt)
+	}
+}
+
+func TestMergePruneDeletesMissingTasks(t *testing.T) {
+	srcDB, _ := setupMergeDB(t)
+	destDB, _ := setupMergeDB(t)
+
+	projectUUID := "00000000-0000-0000-0000-000000000070"
+	keepUUID := "00000000-0000-0000-0000-000000000071"
+	goneUUID := "00000000-0000-0000-0000-000000000072"
+	otherProjectUUID := "00000000-0000-0000-0000-000000000073"
+	otherTaskUUID := "00000000-0000-0000-0000-000000000074"
+
+	insertContainer(t, srcDB, projectUUID, "P-00070", "proj", "Project", "", "2024-02-01T00:00:00Z")
+	insertTask(t, srcDB, keepUUID, "T-00071", "keep", "Keep", projectUUID)
+	insertTask(t, srcDB, goneUUID, "T-00072", "gone", "Gone", projectUUID)
+
+	insertContainer(t, destDB, otherProjectUUID, "P-00073", "other", "Other", "", "2024-02-01T00:00:00Z")
+	insertTask(t, destDB, otherTaskUUID, "T-00074", "outside", "Outside", otherProjectUUID)
+
+	merge := func(prune, dryRun bool) *mergeReport {
+		t.Helper()
+		report, err := mergeProjectIntoCanonical(mergeOptions{
+			SourceDB:        srcDB,
+			DestDB:          destDB,
+			SourceAttachDir: t.TempDir(),
+			DestAttachDir:   t.TempDir(),
+			ProjectSelector: "proj",
+			PathPrefix:      "proj",
+			DryRun:          dryRun,
+			Prune:           prune,
+			ActorUUID:       testActorUUID,
+		})
+		if err != nil {
+			t.Fatalf("merge failed: %v", err)
+		}
+		return report
+	}
+
+	merge(false, false)
+
+	if _, err := srcDB.Exec("DELETE FROM tasks WHERE uuid = ?", goneUUID); err != nil {
+		t.Fatalf("failed to delete source task: %v", err)
+	}
+
+	taskState := func(uuid string) string {
+		t.Helper()
+		var state string
+		if err := destDB.QueryRow("SELECT state FROM tasks WHERE uuid = ?", uuid).Scan(&state); err != nil {
+			t.Fatalf("failed to load task %s: %v", uuid, err)
+		}
+		return state
+	}
+
+	report := merge(true, true)
+	if report.Stats.Pruned != 1 {
+		t.Fatalf("expected 1 pruned task in dry-run, got %d", report.Stats.Pruned)
+	}
+	if state := taskState(goneUUID); state != "open" {
+		t.Fatalf("expected dry-run to leave task open, got %s", state)
+	}
+
+	report = merge(true, false)
+	if report.Stats.Pruned != 1 {
+		t.Fatalf("expected 1 pruned task, got %d", report.Stats.Pruned)
+	}
+	if state := taskState(goneUUID); state != "deleted" {
+		t.Fatalf("expected pruned task to be deleted, got %s", state)
+	}
+	if state := taskState(keepUUID); state != "open" {
+		t.Fatalf("expected kept task to remain open, got %s", state)
+	}
+	if state := taskState(otherTaskUUID); state != "open" {
+		t.Fatalf("expected task outside prefix to be untouched, got %s", state)
+	}
+
+	var events int
+	if err := destDB.QueryRow(`
+		SELECT COUNT(*) FROM event_log
+		WHERE resource_uuid = ? AND event_type = 'task.deleted' AND payload LIKE '%merge_pruned%'
+	`, goneUUID).Scan(&events); err != nil {
+		t.Fatalf("failed to query events: %v", err)
+	}
+	if events != 1 {
+		t.Fatalf("expected 1 merge prune event, got %d", events)
+	}
+}
+
+func insertTaskAt(t *testing.T, database *db.DB, uuid, id, slug, title, projectUUID, updatedAt string) {
+	t.Helper()
+	_, err := database.Exec(`
+		INSERT INTO tasks (uuid, id, slug, title, project_uuid, state, priority, kind, description, etag,
+			created_at, updated_at, created_by_actor_uuid, updated_by_actor_uuid)
+		VALUES (?, ?, ?, ?, ?, 'open', 3, 'task', '', 1, '2024-01-01T00:00:00Z', ?, ?, ?)
+	`, uuid, id, slug, title, projectUUID, updatedAt, testActorUUID, testActorUUID)
+	if err != nil {
+		t.Fatalf("failed to insert task: %v", err)
+	}
+}
+
+// setupStrategyMerge seeds a source and destination that share a project and
+// two tasks: one newer in the source and one newer in the destination.
+func setupStrategyMerge(t *testing.T) (*db.DB, *db.DB, string, string) {
+	t.Helper()
+	srcDB, _ := setupMergeDB(t)
+	destDB, _ := setupMergeDB(t)
+
+	projectUUID := "00000000-0000-0000-0000-000000000080"
+	srcNewerUUID := "00000000-0000-0000-0000-000000000081"
+	destNewerUUID := "00000000-0000-0000-0000-000000000082"
+
+	for _, database := range []*db.DB{srcDB, destDB} {
+		insertContainer(t, database, projectUUID, "P-00080", "proj", "Project", "", "2024-01-01T00:00:00Z")
+	}
+	insertTaskAt(t, srcDB, srcNewerUUID, "T-00081", "src-newer", "Source A", projectUUID, "2024-03-01T00:00:00Z")
+	insertTaskAt(t, destDB, srcNewerUUID, "T-00081", "src-newer", "Dest A", projectUUID, "2024-02-01T00:00:00Z")
+	insertTaskAt(t, srcDB, destNewerUUID, "T-00082", "dest-newer", "Source B", projectUUID, "2024-02-01T00:00:00Z")
+	insertTaskAt(t, destDB, destNewerUUID, "T-00082", "dest-newer", "Dest B", projectUUID, "2024-03-01T00:00:00Z")
+
+	return srcDB, destDB, srcNewerUUID, destNewerUUID
+}
+
+func runStrategyMerge(t *testing.T, srcDB, destDB *db.DB, strategy mergeConflictStrategy) *mergeReport {
+	t.Helper()
+	report, err := mergeProjectIntoCanonical(mergeOptions{
+		SourceDB:        srcDB,
+		DestDB:          destDB,
+		SourceAttachDir: t.TempDir(),
+		DestAttachDir:   t.TempDir(),
+		ProjectSelector: "proj",
+		PathPrefix:      "proj",
+		Strategy:        strategy,
+		ActorUUID:       testActorUUID,
+	})
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	return report
+}
+
+func assertTaskTitle(t *testing.T, database *db.DB, uuid, want string) {
+	t.Helper()
+	var title string
+	if err := database.QueryRow("SELECT title FROM tasks WHERE uuid = ?", uuid).Scan(&title); err != nil {
+		t.Fatalf("failed to load task %s: %v", uuid, err)
+	}
+	if title != want {
+		t.Fatalf("task %s: expected title %q, got %q", uuid, want, title)
+	}
+}
+
+func TestMergeConflictStrategyNewest(t *testing.T) {
+	srcDB, destDB, srcNewer, destNewer := setupStrategyMerge(t)
+
+	report := runStrategyMerge(t, srcDB, destDB, mergeStrategyNewest)
+	if report.Stats.Tasks.Updated != 1 || report.Stats.Tasks.Conflicts != 1 {
+		t.Fatalf("expected 1 update and 1 conflict, got %+v", report.Stats.Tasks)
+	}
+	assertTaskTitle(t, destDB, srcNewer, "Source A")
+	assertTaskTitle(t, destDB, destNewer, "Dest B")
+}
+
+func TestMergeConflictStrategySourceWins(t *testing.T) {
+	srcDB, destDB, srcNewer, destNewer := setupStrategyMerge(t)
+
+	report := runStrategyMerge(t, srcDB, destDB, mergeStrategySourceWins)
+	if report.Stats.Tasks.Updated != 2 {
+		t.Fatalf("expected 2 updates, got %+v", report.Stats.Tasks)
+	}
+	assertTaskTitle(t, destDB, srcNewer, "Source A")
+	assertTaskTitle(t, destDB, destNewer, "Source B")
+}
+
+func TestMergeConflictStrategyDestWins(t *testing.T) {
+	srcDB, destDB, srcNewer, destNewer := setupStrategyMerge(t)
+
+	report := runStrategyMerge(t, srcDB, destDB, mergeStrategyDestWins)
+	if report.Stats.Tasks.Updated != 0 {
+		t.Fatalf("expected no updates, got %+v", report.Stats.Tasks)
+	}
+	assertTaskTitle(t, destDB, srcNewer, "Dest A")
+	assertTaskTitle(t, destDB, destNewer, "Dest B")
+}
+
+func TestMergeConflictStrategyManual(t *testing.T) {
+	srcDB, destDB, srcNewer, destNewer := setupStrategyMerge(t)
+
+	report := runStrategyMerge(t, srcDB, destDB, mergeStrategyManual)
+	if report.Stats.Tasks.Updated != 0 || report.Stats.Tasks.Conflicts != 2 {
+		t.Fatalf("expected 2 conflicts and no updates, got %+v", report.Stats.Tasks)
+	}
+	assertTaskTitle(t, destDB, srcNewer, "Dest A")
+	assertTaskTitle(t, destDB, destNewer, "Dest B")
+
+	if len(report.Conflicts) != 2 {
+		t.Fatalf("expected 2 recorded conflicts, got %d: %+v", len(report.Conflicts), report.Conflicts)
+	}
+	for _, c := range report.Conflicts {
+		if c.Entity != "task" || c.Field != "title" || c.Resolved != "manual" {
+			t.Fatalf("unexpected conflict entry: %+v", c)
+		}
+		if c.Source == c.Dest || c.Source == "" || c.Dest == "" {
+			t.Fatalf("expected both field values recorded, got %+v", c)
+		}
+	}
+}
+
+func TestParseMergeConflictStrategy(t *testing.T) {
+	if got, err := parseMergeConflictStrategy(""); err != nil || got != mergeStrategyNewest {
+		t.Fatalf("expected default newest, got %q (%v)", got, err)
+	}
+	if got, err := parseMergeConflictStrategy("Source-Wins"); err != nil || got != mergeStrategySourceWins {
+		t.Fatalf("expected source-wins, got %q (%v)", got, err)
+	}
+	if _, err := parseMergeConflictStrategy("random"); err == nil {
+		t.Fatalf("expected error for unknown strategy")
+	}
+}
+
+func TestMergeDryRunPlan(t *testing.T) {
+	srcDB, _ := setupMergeDB(t)
+	destDB, _ := setupMergeDB(t)
+
+	projectUUID := "00000000-0000-0000-0000-000000000090"
+	renamedUUID := "00000000-0000-0000-0000-000000000091"
+	skippedUUID := "00000000-0000-0000-0000-000000000092"
+	occupantUUID := "00000000-0000-0000-0000-000000000093"
+
+	for _, database := range []*db.DB{srcDB, destDB} {
+		insertContainer(t, database, projectUUID, "P-00090", "proj", "Project", "", "2024-01-01T00:00:00Z")
+	}
+	insertTask(t, srcDB, renamedUUID, "T-00091", "alpha", "Alpha", projectUUID)
+	insertTask(t, srcDB, skippedUUID, "T-00092", "beta", "Beta", projectUUID)
+	insertTask(t, destDB, occupantUUID, "T-00093", "alpha", "Occupant", projectUUID)
+	insertTaskAt(t, destDB, skippedUUID, "T-00092", "beta", "Beta (dest)", projectUUID, "2024-06-01T00:00:00Z")
+
+	report, err := mergeProjectIntoCanonical(mergeOptions{
+		SourceDB:        srcDB,
+		DestDB:          destDB,
+		SourceAttachDir: t.TempDir(),
+		DestAttachDir:   t.TempDir(),
+		ProjectSelector: "proj",
+		PathPrefix:      "proj",
+		DryRun:          true,
+		Plan:            true,
+		ActorUUID:       testActorUUID,
+	})
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeMergePlan(&buf, report.Plan); err != nil {
+		t.Fatalf("failed to write plan: %v", err)
+	}
+
+	var plan []mergePlanOp
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var op mergePlanOp
+		if err := json.Unmarshal(scanner.Bytes(), &op); err != nil {
+			t.Fatalf("plan line is not JSON: %q: %v", scanner.Text(), err)
+		}
+		plan = append(plan, op)
+	}
+
+	want := []mergePlanOp{
+		{Entity: "actor", UUID: testActorUUID, Op: "skip", NewPath: "test-user"},
+		{Entity: "container", UUID: projectUUID, Op: "skip", OldPath: "proj", NewPath: "proj"},
+		{Entity: "task", UUID: renamedUUID, Op: "rename", NewPath: "proj/alpha--dup-2", Reason: "slug_collision"},
+		{Entity: "task", UUID: skippedUUID, Op: "skip", OldPath: "proj/beta", NewPath: "proj/beta"},
+	}
+	if len(plan) != len(want) {
+		t.Fatalf("expected %d plan ops, got %d: %+v", len(want), len(plan), plan)
+	}
+	for i := range want {
+		if plan[i] != want[i] {
+			t.Fatalf("plan[%d]: expected %+v, got %+v", i, want[i], plan[i])
+		}
+	}
+
+	var count int
+	if err := destDB.QueryRow("SELECT COUNT(*) FROM tasks WHERE uuid = ?", renamedUUID).Scan(&count); err != nil {
+		t.Fatalf("failed to query dest task: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("expected plan dry-run not to write tasks")
+	}
+}
+
+func setupMultiProjectSource(t *testing.T) (*db.DB, *db.DB) {
+	t.Helper()
+	srcDB, _ := setupMergeDB(t)
+	destDB, _ := setupMergeDB(t)
+
+	insertContainer(t, srcDB, "00000000-0000-0000-0000-0000000000a0", "P-000a0", "alpha", "Alpha", "", "2024-02-01T00:00:00Z")
+	insertTask(t, srcDB, "00000000-0000-0000-0000-0000000000a1", "T-000a1", "alpha-task", "Alpha Task", "00000000-0000-0000-0000-0000000000a0")
+	insertContainer(t, srcDB, "00000000-0000-0000-0000-0000000000b0", "P-000b0", "beta", "Beta", "", "2024-02-01T00:00:00Z")
+	insertTask(t, srcDB, "00000000-0000-0000-0000-0000000000b1", "T-000b1", "beta-task", "Beta Task", "00000000-0000-0000-0000-0000000000b0")
+
+	return srcDB, destDB
+}
+
+func multiMergeOptions(t *testing.T, srcDB, destDB *db.DB) mergeOptions {
+	return mergeOptions{
+		SourceDB:        srcDB,
+		DestDB:          destDB,
+		SourceAttachDir: t.TempDir(),
+		DestAttachDir:   t.TempDir(),
+		ActorUUID:       testActorUUID,
+	}
+}
+
+func countDestTasks(t *testing.T, database *db.DB) int {
+	t.Helper()
+	var count int
+	if err := database.QueryRow("SELECT COUNT(*) FROM tasks").Scan(&count); err != nil {
+		t.Fatalf("failed to count tasks: %v", err)
+	}
+	return count
+}
+
+func TestMergeMultipleProjects(t *testing.T) {
+	srcDB, destDB := setupMultiProjectSource(t)
+
+	reports, err := mergeProjectsIntoCanonical(multiMergeOptions(t, srcDB, destDB), []mergeProjectSpec{
+		{Selector: "alpha"},
+		{Selector: "beta", PathPrefix: "team/beta"},
+	})
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("expected 2 reports, got %d", len(reports))
+	}
+	if reports[0].ProjectSelector != "alpha" || reports[1].DestPrefix != "team/beta" {
+		t.Fatalf("unexpected reports: %+v, %+v", reports[0], reports[1])
+	}
+
+	var path string
+	if err := destDB.QueryRow("SELECT path FROM v_task_paths WHERE uuid = ?", "00000000-0000-0000-0000-0000000000b1").Scan(&path); err != nil {
+		t.Fatalf("failed to load task path: %v", err)
+	}
+	if path != "team/beta/beta-task" {
+		t.Fatalf("expected team/beta/beta-task, got %s", path)
+	}
+	if count := countDestTasks(t, destDB); count != 2 {
+		t.Fatalf("expected 2 tasks, got %d", count)
+	}
+}
+
+func TestMergeMultipleProjectsFailureRollsBack(t *testing.T) {
+	srcDB, destDB := setupMultiProjectSource(t)
+
+	_, err := mergeProjectsIntoCanonical(multiMergeOptions(t, srcDB, destDB), []mergeProjectSpec{
+		{Selector: "alpha"},
+		{Selector: "missing"},
+	})
+	if err == nil {
+		t.Fatalf("expected merge to fail")
+	}
+	if count := countDestTasks(t, destDB); count != 0 {
+		t.Fatalf("expected prior projects rolled back, found %d tasks", count)
+	}
+}
+
+func TestMergeMultipleProjectsContinueOnError(t *testing.T) {
+	srcDB, destDB := setupMultiProjectSource(t)
+
+	opts := multiMergeOptions(t, srcDB, destDB)
+	opts.ContinueOnError = true
+	reports, err := mergeProjectsIntoCanonical(opts, []mergeProjectSpec{
+		{Selector: "alpha"},
+		{Selector: "missing"},
+		{Selector: "beta"},
+	})
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	if len(reports) != 3 {
+		t.Fatalf("expected 3 reports, got %d", len(reports))
+	}
+	if reports[0].Error != "" || reports[2].Error != "" {
+		t.Fatalf("expected alpha and beta to succeed: %q, %q", reports[0].Error, reports[2].Error)
+	}
+	if reports[1].Error == "" {
+		t.Fatalf("expected missing project to report an error")
+	}
+	if count := countDestTasks(t, destDB); count != 2 {
+		t.Fatalf("expected 2 tasks, got %d", count)
+	}
+}
+
+func TestReadMergeProjectsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "projects.txt")
+	content := "# monorepo projects\nalpha\n\nbeta team/beta\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write projects file: %v", err)
+	}
+
+	specs, err := readMergeProjectsFile(path)
+	if err != nil {
+		t.Fatalf("failed to read projects file: %v", err)
+	}
+	want := []mergeProjectSpec{{Selector: "alpha"}, {Selector: "beta", PathPrefix: "team/beta"}}
+	if len(specs) != len(want) {
+		t.Fatalf("expected %d specs, got %+v", len(want), specs)
+	}
+	for i := range want {
+		if specs[i] != want[i] {
+			t.Fatalf("spec %d: expected %+v, got %+v", i, want[i], specs[i])
+		}
+	}
+}
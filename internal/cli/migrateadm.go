@@ -1,7 +1,9 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 
 	"github.com/lherron/wrkq/internal/config"
 	"github.com/lherron/wrkq/internal/db"
@@ -20,13 +22,36 @@ This command is safe to run multiple times - it only applies migrations that
 haven't been applied yet.
 
 Use --dry-run to see which migrations would be applied without running them.
-Use --status to show the current migration status.`,
+Use --status to show the current migration status.
+Use --plan to show the current version and each pending migration with its
+description and the rows it will touch; it exits 1 if anything is pending.
+Use 'wrkqadm migrate down' to revert migrations during development.`,
 	RunE: runMigrateAdm,
 }
 
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Revert the most recent migrations",
+	Long: `Down reverts the most recently applied migrations by running their down
+scripts (migrations/down/<name>.sql), newest first. Older migrations that
+rebuild tables have no down script and cannot be reverted; nothing is changed
+unless every migration in range can be.
+
+This is intended for development and bundle interop version bumps. Reverting
+can drop columns and tables, so it refuses to run against a database that
+holds tasks or comments unless --force is given. Take a backup first
+(wrkqadm backup).`,
+	Args: cobra.NoArgs,
+	RunE: runMigrateDownAdm,
+}
+
 var (
-	migrateDryRun bool
-	migrateStatus bool
+	migrateDryRun    bool
+	migrateStatus    bool
+	migratePlan      bool
+	migratePlanJSON  bool
+	migrateDownSteps int
+	migrateDownForce bool
 )
 
 func init() {
@@ -34,6 +59,12 @@ func init() {
 
 	migrateAdmCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "Show which migrations would be applied without running them")
 	migrateAdmCmd.Flags().BoolVar(&migrateStatus, "status", false, "Show current migration status")
+	migrateAdmCmd.Flags().BoolVar(&migratePlan, "plan", false, "Show pending migrations and affected rows without applying them")
+	migrateAdmCmd.Flags().BoolVar(&migratePlanJSON, "json", false, "Output the plan as JSON (with --plan)")
+
+	migrateAdmCmd.AddCommand(migrateDownCmd)
+	migrateDownCmd.Flags().IntVar(&migrateDownSteps, "steps", 1, "Number of migrations to revert")
+	migrateDownCmd.Flags().BoolVar(&migrateDownForce, "force", false, "Revert even if the database holds tasks or comments")
 }
 
 func runMigrateAdm(cmd *cobra.Command, args []string) error {
@@ -65,6 +96,19 @@ func runMigrateAdm(cmd *cobra.Command, args []string) error {
 		return showMigrationStatus(database)
 	}
 
+	// Handle --plan flag
+	if migratePlan {
+		pending, err := showMigrationPlan(cmd, database)
+		if err != nil {
+			return err
+		}
+		if pending {
+			database.Close()
+			os.Exit(1)
+		}
+		return nil
+	}
+
 	// Handle --dry-run flag
 	if migrateDryRun {
 		return showPendingMigrations(database)
@@ -88,6 +132,69 @@ func runMigrateAdm(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runMigrateDownAdm(cmd *cobra.Command, args []string) error {
+	if migrateDownSteps < 1 {
+		return exitError(2, fmt.Errorf("--steps must be at least 1"))
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return exitError(1, fmt.Errorf("failed to load config: %w", err))
+	}
+
+	dbPathFlag := cmd.Flag("db").Value.String()
+	if dbPathFlag != "" {
+		cfg.DBPath = dbPathFlag
+	}
+
+	if cfg.DBPath == "" {
+		return exitError(2, fmt.Errorf("database path not specified (use --db flag or set WRKQ_DB_PATH)"))
+	}
+
+	database, err := db.Open(cfg.DBPath)
+	if err != nil {
+		return exitError(1, fmt.Errorf("failed to open database: %w", err))
+	}
+	defer database.Close()
+
+	applied, _, err := database.MigrationStatus()
+	if err != nil {
+		return exitError(1, fmt.Errorf("failed to get migration status: %w", err))
+	}
+	if migrateDownSteps > len(applied) {
+		return exitError(2, fmt.Errorf("--steps %d exceeds the %d applied migration(s)", migrateDownSteps, len(applied)))
+	}
+
+	if !migrateDownForce {
+		var rows int
+		if err := database.QueryRow(`
+			SELECT (SELECT COUNT(*) FROM tasks) + (SELECT COUNT(*) FROM comments)
+		`).Scan(&rows); err != nil {
+			return exitError(1, fmt.Errorf("failed to check for data: %w", err))
+		}
+		if rows > 0 {
+			return exitError(1, fmt.Errorf("database holds %d task(s)/comment(s); use --force to revert anyway", rows))
+		}
+	}
+
+	// Revert to the migration just before the last N
+	target := ""
+	if keep := len(applied) - migrateDownSteps; keep > 0 {
+		target = applied[keep-1]
+	}
+
+	reverted, err := database.RollbackTo(target)
+	for _, m := range reverted {
+		fmt.Fprintf(cmd.OutOrStdout(), "✓ Reverted migration: %s\n", m)
+	}
+	if err != nil {
+		return exitError(1, fmt.Errorf("failed to revert migrations: %w", err))
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "\nReverted %d migration(s).\n", len(reverted))
+	return nil
+}
+
 func showMigrationStatus(database *db.DB) error {
 	applied, pending, err := database.MigrationStatus()
 	if err != nil {
@@ -119,6 +226,48 @@ func showMigrationStatus(database *db.DB) error {
 	return nil
 }
 
+// showMigrationPlan prints the migration plan and reports whether anything
+// is pending.
+func showMigrationPlan(cmd *cobra.Command, database *db.DB) (bool, error) {
+	plan, err := database.MigrationPlan()
+	if err != nil {
+		return false, exitError(1, fmt.Errorf("failed to plan migrations: %w", err))
+	}
+
+	if migratePlanJSON {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		return len(plan.Pending) > 0, encoder.Encode(plan)
+	}
+
+	out := cmd.OutOrStdout()
+	current := plan.CurrentVersion
+	if current == "" {
+		current = "none"
+	}
+	fmt.Fprintf(out, "Current version: %s\n", current)
+
+	if len(plan.Pending) == 0 {
+		fmt.Fprintln(out, "No pending migrations. Database is up to date.")
+		return false, nil
+	}
+
+	fmt.Fprintf(out, "\nPending migrations (%d):\n", len(plan.Pending))
+	for _, m := range plan.Pending {
+		fmt.Fprintf(out, "  ○ %s", m.Version)
+		if m.Description != "" {
+			fmt.Fprintf(out, " - %s", m.Description)
+		}
+		fmt.Fprintln(out)
+		for _, t := range m.Tables {
+			fmt.Fprintf(out, "      touches %s (%d rows)\n", t.Table, t.Rows)
+		}
+	}
+	fmt.Fprintln(out, "\nNothing was applied. Run 'wrkqadm migrate' to apply.")
+
+	return true, nil
+}
+
 func showPendingMigrations(database *db.DB) error {
 	_, pending, err := database.MigrationStatus()
 	if err != nil {
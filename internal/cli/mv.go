@@ -33,6 +33,7 @@ var (
 	mvYes           bool
 	mvNullglob      bool
 	mvOverwriteTask bool
+	mvIgnoreWIP     bool
 )
 
 func init() {
@@ -43,6 +44,7 @@ func init() {
 	mvCmd.Flags().BoolVar(&mvYes, "yes", false, "Skip confirmation prompts")
 	mvCmd.Flags().BoolVar(&mvNullglob, "nullglob", false, "Zero matches is a no-op instead of error")
 	mvCmd.Flags().BoolVar(&mvOverwriteTask, "overwrite-task", false, "Allow overwriting existing tasks")
+	mvCmd.Flags().BoolVar(&mvIgnoreWIP, "ignore-wip-limit", false, "Move even if the destination section is at its WIP limit")
 }
 
 func runMv(app *appctx.App, cmd *cobra.Command, args []string) error {
@@ -111,7 +113,9 @@ func moveToContainer(cmd *cobra.Command, s *store.Store, actorUUID, src, dstCont
 		}
 
 		// Move task to destination container using store
-		_, err := s.Tasks.Move(actorUUID, srcTaskUUID, dstContainerUUID, mvIfMatch)
+		_, err := s.Tasks.MoveWithOptions(actorUUID, srcTaskUUID, dstContainerUUID, mvIfMatch, store.MoveOptions{
+			BypassWIPLimit: mvIgnoreWIP,
+		})
 		if err != nil {
 			return err
 		}
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -9,6 +10,7 @@ import (
 	"github.com/lherron/wrkq/internal/config"
 	"github.com/lherron/wrkq/internal/db"
 	"github.com/lherron/wrkq/internal/patch"
+	"github.com/lherron/wrkq/internal/snapshot"
 	"github.com/spf13/cobra"
 )
 
@@ -30,7 +32,10 @@ var patchCreateCmd = &cobra.Command{
 into the target snapshot.
 
 The patch is normalized to remove redundant operations and uses UUID paths
-(not friendly IDs) for all references.`,
+(not friendly IDs) for all references.
+
+By default a changed entity is replaced whole. Use --field-level to emit one
+op per changed field (e.g. replace /tasks/<uuid>/state) for smaller patches.`,
 	RunE: runPatchCreate,
 }
 
@@ -39,6 +44,7 @@ var (
 	patchCreateTo                string
 	patchCreateOut               string
 	patchCreateAllowNonCanonical bool
+	patchCreateFieldLevel        bool
 	patchCreateJSON              bool
 )
 
@@ -48,6 +54,7 @@ func init() {
 	patchAdmCmd.AddCommand(patchValidateCmd)
 	patchAdmCmd.AddCommand(patchApplyCmd)
 	patchAdmCmd.AddCommand(patchRebaseCmd)
+	patchAdmCmd.AddCommand(patchMergeCmd)
 	patchAdmCmd.AddCommand(patchSummarizeCmd)
 
 	// Create flags
@@ -55,6 +62,7 @@ func init() {
 	patchCreateCmd.Flags().StringVar(&patchCreateTo, "to", "", "Target snapshot file (required)")
 	patchCreateCmd.Flags().StringVar(&patchCreateOut, "out", "", "Output patch file (required)")
 	patchCreateCmd.Flags().BoolVar(&patchCreateAllowNonCanonical, "allow-noncanonical", false, "Skip canonicalization check")
+	patchCreateCmd.Flags().BoolVar(&patchCreateFieldLevel, "field-level", false, "Emit per-field ops for changed entities")
 	patchCreateCmd.Flags().BoolVar(&patchCreateJSON, "json", false, "Output result as JSON")
 	patchCreateCmd.MarkFlagRequired("from")
 	patchCreateCmd.MarkFlagRequired("to")
@@ -73,6 +81,7 @@ func init() {
 	patchApplyCmd.Flags().StringVar(&patchApplyIfMatch, "if-match", "", "Require snapshot_rev to match")
 	patchApplyCmd.Flags().BoolVar(&patchApplyDryRun, "dry-run", false, "Validate without writing")
 	patchApplyCmd.Flags().BoolVar(&patchApplyStrict, "strict", false, "Enable strict validation")
+	patchApplyCmd.Flags().BoolVar(&patchApplyDirect, "direct", false, "Write operations directly to the database in one transaction")
 	patchApplyCmd.Flags().BoolVar(&patchApplyJSON, "json", false, "Output result as JSON")
 	patchApplyCmd.MarkFlagRequired("patch")
 
@@ -88,6 +97,17 @@ func init() {
 	patchRebaseCmd.MarkFlagRequired("new-base")
 	patchRebaseCmd.MarkFlagRequired("out")
 
+	// Merge flags
+	patchMergeCmd.Flags().StringVar(&patchMergeBase, "base", "", "Base snapshot both patches were created from (required)")
+	patchMergeCmd.Flags().StringVar(&patchMergeA, "a", "", "First patch file (required)")
+	patchMergeCmd.Flags().StringVar(&patchMergeB, "b", "", "Second patch file (required)")
+	patchMergeCmd.Flags().StringVar(&patchMergeOut, "out", "", "Output merged patch file (required)")
+	patchMergeCmd.Flags().BoolVar(&patchMergeJSON, "json", false, "Output result as JSON")
+	patchMergeCmd.MarkFlagRequired("base")
+	patchMergeCmd.MarkFlagRequired("a")
+	patchMergeCmd.MarkFlagRequired("b")
+	patchMergeCmd.MarkFlagRequired("out")
+
 	// Summarize flags
 	patchSummarizeCmd.Flags().StringVar(&patchSummarizePatch, "patch", "", "Patch file to summarize (required)")
 	patchSummarizeCmd.Flags().StringVar(&patchSummarizeBase, "base", "", "Base snapshot for context (optional)")
@@ -101,6 +121,7 @@ func runPatchCreate(cmd *cobra.Command, args []string) error {
 		ToPath:            patchCreateTo,
 		OutputPath:        patchCreateOut,
 		AllowNonCanonical: patchCreateAllowNonCanonical,
+		FieldLevel:        patchCreateFieldLevel,
 	}
 
 	result, err := patch.Create(opts)
@@ -185,7 +206,13 @@ var patchApplyCmd = &cobra.Command{
 validates the result, and commits the changes.
 
 Use --if-match to require the current snapshot_rev to match before applying.
-Use --dry-run to validate without writing changes.`,
+Use --dry-run to validate without writing changes.
+
+Use --direct to write operations on tasks, containers, and comments straight
+to the database in one transaction instead of re-importing a patched
+snapshot. Direct mode logs events and bumps etags like normal edits, upserts
+on add/replace, soft-deletes on remove, and exits 4 without writing if any
+operation conflicts with the current state.`,
 	RunE: runPatchApply,
 }
 
@@ -194,6 +221,7 @@ var (
 	patchApplyIfMatch string
 	patchApplyDryRun  bool
 	patchApplyStrict  bool
+	patchApplyDirect  bool
 	patchApplyJSON    bool
 )
 
@@ -217,6 +245,10 @@ func runPatchApply(cmd *cobra.Command, args []string) error {
 	}
 	defer database.Close()
 
+	if patchApplyDirect {
+		return runPatchApplyDirect(cmd, database, cfg)
+	}
+
 	opts := patch.ApplyOptions{
 		PatchPath: patchApplyPatch,
 		IfMatch:   patchApplyIfMatch,
@@ -255,6 +287,60 @@ func runPatchApply(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// runPatchApplyDirect applies the patch with patch.ApplyToDatabase.
+func runPatchApplyDirect(cmd *cobra.Command, database *db.DB, cfg *config.Config) error {
+	if patchApplyDryRun || patchApplyStrict {
+		return exitError(2, fmt.Errorf("--dry-run and --strict are not supported with --direct"))
+	}
+
+	p, err := patch.LoadPatch(patchApplyPatch)
+	if err != nil {
+		return exitError(1, err)
+	}
+
+	if patchApplyIfMatch != "" {
+		_, data, err := snapshot.ExportToSnapshot(database.DB, snapshot.ExportOptions{Canonical: true})
+		if err != nil {
+			return exitError(1, fmt.Errorf("failed to export current state: %w", err))
+		}
+		if currentRev := snapshot.ComputeSnapshotRev(data); currentRev != patchApplyIfMatch {
+			return exitError(4, fmt.Errorf("snapshot_rev mismatch: expected %s, got %s", patchApplyIfMatch, currentRev))
+		}
+	}
+
+	actorUUID, err := resolveBundleActor(database, cmd, cfg)
+	if err != nil {
+		return exitError(1, err)
+	}
+
+	result, applyErr := patch.ApplyToDatabase(database.DB, p, actorUUID)
+	if applyErr != nil && !errors.Is(applyErr, patch.ErrConflict) {
+		return exitError(1, applyErr)
+	}
+
+	if patchApplyJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			return exitError(1, fmt.Errorf("failed to encode result: %w", err))
+		}
+	} else if result.Applied {
+		fmt.Println("✓ Patch applied successfully")
+		fmt.Printf("  operations: %d (created: %d, updated: %d, deleted: %d, unchanged: %d)\n",
+			result.OpCount, result.Created, result.Updated, result.Deleted, result.Unchanged)
+	}
+
+	if len(result.Conflicts) > 0 {
+		fmt.Fprintf(cmd.OutOrStderr(), "\nConflicts detected (nothing was written):\n")
+		for _, conflict := range result.Conflicts {
+			fmt.Fprintf(cmd.OutOrStderr(), "  - %s (%s)\n", conflict.Path, conflict.Reason)
+		}
+		return exitError(4, applyErr)
+	}
+
+	return nil
+}
+
 // Rebase command
 var patchRebaseCmd = &cobra.Command{
 	Use:   "rebase",
@@ -323,6 +409,84 @@ func runPatchRebase(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// Merge command
+var patchMergeCmd = &cobra.Command{
+	Use:   "merge",
+	Short: "Three-way merge two patches created from the same base",
+	Long: `Merge combines two patches that were both created against the same base
+snapshot into a single patch.
+
+Edits to different entities, or to different fields of the same entity,
+merge cleanly. When both patches change the same field to different values,
+add the same entity with different contents, or one removes an entity the
+other modifies, the path is reported as a conflict and left out of the
+merged patch. New entities in --b whose friendly IDs collide with --a are
+renumbered (see code_rewrites in --json output).
+
+The merged patch is always written to --out. Exits 4 if there are conflicts.`,
+	RunE: runPatchMerge,
+}
+
+var (
+	patchMergeBase string
+	patchMergeA    string
+	patchMergeB    string
+	patchMergeOut  string
+	patchMergeJSON bool
+)
+
+func runPatchMerge(cmd *cobra.Command, args []string) error {
+	base, _, err := snapshot.LoadSnapshot(patchMergeBase)
+	if err != nil {
+		return exitError(1, err)
+	}
+	a, err := patch.LoadPatch(patchMergeA)
+	if err != nil {
+		return exitError(1, err)
+	}
+	b, err := patch.LoadPatch(patchMergeB)
+	if err != nil {
+		return exitError(1, err)
+	}
+
+	result, err := patch.Merge(base, a, b)
+	if err != nil {
+		return exitError(1, err)
+	}
+	if err := result.Patch.Save(patchMergeOut); err != nil {
+		return exitError(1, err)
+	}
+
+	if patchMergeJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			return exitError(1, fmt.Errorf("failed to encode result: %w", err))
+		}
+	} else {
+		adds, replaces, removes := result.Patch.CountOps()
+		fmt.Printf("✓ Merged patch: %s\n", patchMergeOut)
+		fmt.Printf("  operations: %d (add: %d, replace: %d, remove: %d)\n",
+			len(result.Patch), adds, replaces, removes)
+
+		for resourceType, rewrites := range result.CodeRewrites {
+			for uuid, rewrite := range rewrites {
+				fmt.Printf("  ID rewrite: %s %s: %s → %s\n", resourceType, uuid, rewrite.From, rewrite.To)
+			}
+		}
+	}
+
+	if len(result.Conflicts) > 0 {
+		fmt.Fprintf(cmd.OutOrStderr(), "\nConflicts (not included in merged patch):\n")
+		for _, conflict := range result.Conflicts {
+			fmt.Fprintf(cmd.OutOrStderr(), "  - %s (%s)\n", conflict.Path, conflict.Reason)
+		}
+		return exitError(4, fmt.Errorf("%d merge conflict(s)", len(result.Conflicts)))
+	}
+
+	return nil
+}
+
 // Summarize command
 var patchSummarizeCmd = &cobra.Command{
 	Use:   "summarize",
@@ -331,7 +495,9 @@ var patchSummarizeCmd = &cobra.Command{
 for use in PR descriptions or review.
 
 The optional --base flag provides a snapshot for enriched context
-(titles, paths) in the output.
+(titles, paths) in the output. With a base, replaced tasks also show
+field-level changes (title, state, priority) as current → incoming and
+a unified diff of description edits.
 
 Output formats:
   text     - Simple one-line summary
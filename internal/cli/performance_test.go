@@ -1,13 +1,21 @@
 package cli
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"io"
 	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/lherron/wrkq/internal/cli/appctx"
+	"github.com/lherron/wrkq/internal/config"
 	"github.com/lherron/wrkq/internal/db"
+	"github.com/mattn/go-sqlite3"
+	"github.com/spf13/cobra"
 )
 
 // TestPerformance_List5kTasks tests that listing 5000 tasks completes under 200ms p95
@@ -299,6 +307,77 @@ func BenchmarkListTasks(b *testing.B) {
 	}
 }
 
+// BenchmarkCatLookups counts the queries cat takes to print 100 tasks that
+// share a few assignees and parents. Without the lookup cache every task
+// cost a query for each of its creator's, updater's and assignee's slugs, its
+// project's ID and its parent's ID, 495 of 1095 in all; with it each distinct
+// actor, project and parent is looked up once, 10 in all.
+func BenchmarkCatLookups(b *testing.B) {
+	raw, dbPath := setupBenchPerfEnv(b, 100)
+	defer raw.Close()
+	linkBenchTasks(b, raw)
+
+	database, queries := openCountingDB(b, dbPath)
+	app := &appctx.App{Config: &config.Config{DBPath: dbPath}, DB: database}
+	cmd := &cobra.Command{}
+	cmd.SetOut(io.Discard)
+	args := make([]string, 100)
+	for i := range args {
+		args[i] = fmt.Sprintf("T-%05d", i+1)
+	}
+	catJSON, catExcludeComments = true, true
+	defer func() { catJSON, catExcludeComments = false, false }()
+	b.ResetTimer()
+
+	var lookups int64
+	for i := 0; i < b.N; i++ {
+		queries.Store(0)
+		if err := runCat(app, cmd, args); err != nil {
+			b.Fatalf("runCat failed: %v", err)
+		}
+		lookups = queries.Load()
+	}
+	b.ReportMetric(float64(lookups), "queries/op")
+}
+
+// BenchmarkFindTasksEnrichment lists 100 tasks that share a few assignees and
+// parents, reporting the queries each listing takes: one with the assignee
+// slugs and parent IDs joined in, against 196 for the per-row version that
+// looks each one up separately.
+func BenchmarkFindTasksEnrichment(b *testing.B) {
+	raw, dbPath := setupBenchPerfEnv(b, 100)
+	defer raw.Close()
+	linkBenchTasks(b, raw)
+
+	database, queries := openCountingDB(b, dbPath)
+	for _, variant := range []struct {
+		name string
+		find func() ([]findResult, error)
+	}{
+		{"joined", func() ([]findResult, error) {
+			results, _, err := findTasks(database, findOptions{}, true)
+			return results, err
+		}},
+		{"per-row", func() ([]findResult, error) {
+			return findTasksPerRow(database, findOptions{})
+		}},
+	} {
+		b.Run(variant.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				queries.Store(0)
+				results, err := variant.find()
+				if err != nil {
+					b.Fatalf("findTasks failed: %v", err)
+				}
+				if len(results) != 100 {
+					b.Fatalf("Expected 100 tasks, got %d", len(results))
+				}
+			}
+			b.ReportMetric(float64(queries.Load()), "queries/op")
+		})
+	}
+}
+
 // BenchmarkCreateTask benchmarks task creation
 func BenchmarkCreateTask(b *testing.B) {
 	database, _ := setupBenchPerfEnv(b, 0)
@@ -464,6 +543,71 @@ func setupBenchPerfEnv(b *testing.B, numTasks int) (*sql.DB, string) {
 	return database.DB, dbPath
 }
 
+// linkBenchTasks adds three more actors to a setupBenchPerfEnv database and
+// spreads its tasks across them and a few parents.
+func linkBenchTasks(b *testing.B, raw *sql.DB) {
+	b.Helper()
+	for i := 2; i <= 4; i++ {
+		if _, err := raw.Exec(`
+			INSERT INTO actors (uuid, id, slug, display_name, role, created_at, updated_at)
+			VALUES (?, ?, ?, ?, 'agent', datetime('now'), datetime('now'))
+		`, fmt.Sprintf("00000000-0000-0000-0000-00000000010%d", i), fmt.Sprintf("A-%05d", i), fmt.Sprintf("agent-%d", i), fmt.Sprintf("Agent %d", i)); err != nil {
+			b.Fatalf("Failed to create actor: %v", err)
+		}
+	}
+	// Tasks 0-4 are the parents of the rest, and each task is assigned to
+	// one of four actors
+	if _, err := raw.Exec(`
+		UPDATE tasks SET
+			parent_task_uuid = CASE WHEN CAST(substr(uuid, 12) AS INTEGER) >= 5
+				THEN 'bench-task-' || (CAST(substr(uuid, 12) AS INTEGER) % 5) END,
+			assignee_actor_uuid = CASE CAST(substr(uuid, 12) AS INTEGER) % 4
+				WHEN 0 THEN '00000000-0000-0000-0000-000000000001'
+				ELSE '00000000-0000-0000-0000-00000000010' || (1 + CAST(substr(uuid, 12) AS INTEGER) % 4) END
+	`); err != nil {
+		b.Fatalf("Failed to link tasks: %v", err)
+	}
+}
+
+// openCountingDB opens a second handle on the database at dbPath that counts
+// the statements run through it.
+func openCountingDB(tb testing.TB, dbPath string) (*db.DB, *atomic.Int64) {
+	tb.Helper()
+	queries := &atomic.Int64{}
+	raw := sql.OpenDB(countingConnector{dsn: dbPath, queries: queries})
+	tb.Cleanup(func() { raw.Close() })
+	return &db.DB{DB: raw}, queries
+}
+
+type countingConnector struct {
+	dsn     string
+	queries *atomic.Int64
+}
+
+func (c countingConnector) Connect(context.Context) (driver.Conn, error) {
+	conn, err := c.Driver().Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return countingConn{Conn: conn, queries: c.queries}, nil
+}
+
+func (c countingConnector) Driver() driver.Driver {
+	return &sqlite3.SQLiteDriver{}
+}
+
+// countingConn hides the driver's direct query methods, so database/sql
+// prepares every statement and each one is counted.
+type countingConn struct {
+	driver.Conn
+	queries *atomic.Int64
+}
+
+func (c countingConn) Prepare(query string) (driver.Stmt, error) {
+	c.queries.Add(1)
+	return c.Conn.Prepare(query)
+}
+
 func calculatePercentiles(timings []time.Duration) (p50, p95, p99 time.Duration) {
 	// Sort timings
 	sorted := make([]time.Duration, len(timings))
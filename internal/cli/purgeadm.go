@@ -0,0 +1,316 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lherron/wrkq/internal/cli/appctx"
+	"github.com/lherron/wrkq/internal/db"
+	"github.com/lherron/wrkq/internal/selectors"
+	"github.com/lherron/wrkq/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var purgeAdmCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Permanently remove tasks deleted longer ago than a retention period",
+	Long: `Hard-purges every task that has been in the deleted state since before
+--older-than, along with its comments, attachments (rows and files),
+relations, reactions and time entries. This is a retention sweep over
+soft-deleted tasks; 'wrkq rm --purge' purges individual tasks instead.
+
+--older-than takes a number of days or weeks (30d, 2w) or a Go duration
+(720h). --project limits the sweep to a container and everything below it.
+
+A deleted task whose subtasks are not all being purged with it is skipped,
+since purging it would take them with it.
+
+Use --dry-run to see what would be purged.`,
+	Example: `  wrkqadm purge --older-than 30d --dry-run
+  wrkqadm purge --older-than 90d --project portal --json`,
+	Args: cobra.NoArgs,
+	RunE: appctx.WithApp(appctx.WithActor(), runPurgeAdm),
+}
+
+var (
+	purgeOlderThan string
+	purgeProject   string
+	purgeDryRun    bool
+	purgeJSON      bool
+	purgePorcelain bool
+)
+
+func init() {
+	rootAdmCmd.AddCommand(purgeAdmCmd)
+	purgeAdmCmd.Flags().StringVar(&purgeOlderThan, "older-than", "", "Purge tasks deleted longer ago than this (e.g. 30d, 2w, 720h)")
+	purgeAdmCmd.Flags().StringVar(&purgeProject, "project", "", "Only purge tasks under this container")
+	purgeAdmCmd.Flags().BoolVar(&purgeDryRun, "dry-run", false, "List what would be purged without purging")
+	purgeAdmCmd.Flags().BoolVar(&purgeJSON, "json", false, "Output as JSON")
+	purgeAdmCmd.Flags().BoolVar(&purgePorcelain, "porcelain", false, "Machine-readable output")
+	_ = purgeAdmCmd.MarkFlagRequired("older-than")
+}
+
+// purgedTask is one task swept (or, with --dry-run, to be swept) by
+// wrkqadm purge.
+type purgedTask struct {
+	ID          string `json:"id"`
+	UUID        string `json:"uuid"`
+	Path        string `json:"path"`
+	DeletedAt   string `json:"deleted_at"`
+	Attachments int    `json:"attachments"`
+	Bytes       int64  `json:"bytes"`
+}
+
+// skippedTask is a deleted task old enough to purge that was left alone.
+type skippedTask struct {
+	ID     string `json:"id"`
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+type purgeReport struct {
+	DryRun      bool          `json:"dry_run"`
+	Cutoff      string        `json:"cutoff"`
+	Tasks       []purgedTask  `json:"tasks"`
+	Skipped     []skippedTask `json:"skipped"`
+	Attachments int           `json:"attachments"`
+	BytesFreed  int64         `json:"bytes_freed"`
+}
+
+type purgeOptions struct {
+	Cutoff      time.Time
+	ProjectUUID string
+	DryRun      bool
+}
+
+func runPurgeAdm(app *appctx.App, cmd *cobra.Command, args []string) error {
+	age, err := parseRetentionAge(purgeOlderThan)
+	if err != nil {
+		return err
+	}
+	opts := purgeOptions{Cutoff: time.Now().UTC().Add(-age), DryRun: purgeDryRun}
+	if purgeProject != "" {
+		opts.ProjectUUID, _, err = selectors.ResolveContainer(app.DB, purgeProject)
+		if err != nil {
+			return err
+		}
+	}
+
+	report, err := purgeDeletedTasks(app.DB, app.Config.AttachDir, app.ActorUUID, opts)
+	if err != nil {
+		return err
+	}
+
+	if purgeJSON {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		if !purgePorcelain {
+			encoder.SetIndent("", "  ")
+		}
+		return encoder.Encode(report)
+	}
+
+	out := cmd.OutOrStdout()
+	if purgePorcelain {
+		for _, task := range report.Tasks {
+			fmt.Fprintln(out, task.ID)
+		}
+		return nil
+	}
+
+	verb := "Purged"
+	if purgeDryRun {
+		verb = "Would purge"
+	}
+	for _, task := range report.Tasks {
+		fmt.Fprintf(out, "%s %s %s (deleted %s)\n", verb, task.ID, task.Path, task.DeletedAt)
+	}
+	for _, task := range report.Skipped {
+		fmt.Fprintf(out, "Skipped %s %s (%s)\n", task.ID, task.Path, task.Reason)
+	}
+	fmt.Fprintf(out, "%s %d task(s) deleted before %s, %d attachment(s), %d bytes.\n",
+		verb, len(report.Tasks), report.Cutoff, report.Attachments, report.BytesFreed)
+	return nil
+}
+
+// parseRetentionAge parses an --older-than value: whole days ("30d"), weeks
+// ("2w"), or anything time.ParseDuration accepts.
+func parseRetentionAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(value, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(value, "w"):
+		unit = 7 * 24 * time.Hour
+	}
+	var age time.Duration
+	if unit != 0 {
+		n, err := strconv.Atoi(strings.TrimSpace(value[:len(value)-1]))
+		if err != nil {
+			return 0, fmt.Errorf("invalid --older-than value %q (use e.g. 30d, 2w or 720h)", value)
+		}
+		age = time.Duration(n) * unit
+	} else {
+		var err error
+		if age, err = time.ParseDuration(value); err != nil {
+			return 0, fmt.Errorf("invalid --older-than value %q (use e.g. 30d, 2w or 720h)", value)
+		}
+	}
+	if age <= 0 {
+		return 0, fmt.Errorf("--older-than must be positive")
+	}
+	return age, nil
+}
+
+// purgeDeletedTasks hard-purges the tasks deleted before opts.Cutoff (under
+// opts.ProjectUUID, if set) with TaskStore.Purge, then removes their
+// attachment files. Subtasks are purged before their parents; a task with a
+// subtask that is not itself due for purging is skipped.
+func purgeDeletedTasks(database *db.DB, attachDir, actorUUID string, opts purgeOptions) (*purgeReport, error) {
+	report := &purgeReport{
+		DryRun:  opts.DryRun,
+		Cutoff:  opts.Cutoff.UTC().Format(time.RFC3339),
+		Tasks:   []purgedTask{},
+		Skipped: []skippedTask{},
+	}
+
+	query := `
+		WITH RECURSIVE subtree(uuid) AS (
+			SELECT uuid FROM containers WHERE ? = '' OR uuid = ?
+			UNION
+			SELECT c.uuid FROM containers c JOIN subtree s ON c.parent_uuid = s.uuid
+		)
+		SELECT t.uuid, t.id, COALESCE(tp.path, t.slug), t.deleted_at,
+		       (SELECT COUNT(*) FROM attachments a WHERE a.task_uuid = t.uuid),
+		       (SELECT COALESCE(SUM(a.size_bytes), 0) FROM attachments a WHERE a.task_uuid = t.uuid)
+		FROM tasks t
+		LEFT JOIN v_task_paths tp ON tp.uuid = t.uuid
+		WHERE t.state = 'deleted' AND t.deleted_at IS NOT NULL AND t.deleted_at < ?
+		  AND t.project_uuid IN (SELECT uuid FROM subtree)
+		ORDER BY t.deleted_at, t.id
+	`
+	rows, err := database.Query(query, opts.ProjectUUID, opts.ProjectUUID, report.Cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deleted tasks: %w", err)
+	}
+	var candidates []purgedTask
+	for rows.Next() {
+		var task purgedTask
+		if err := rows.Scan(&task.UUID, &task.ID, &task.Path, &task.DeletedAt, &task.Attachments, &task.Bytes); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan deleted task: %w", err)
+		}
+		candidates = append(candidates, task)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Leave out tasks with a subtask that stays, repeating until stable so
+	// a skip propagates up to the task's own ancestors
+	due := make(map[string]bool, len(candidates))
+	for _, task := range candidates {
+		due[task.UUID] = true
+	}
+	children := make(map[string][]string)
+	childRows, err := database.Query(`SELECT uuid, parent_task_uuid FROM tasks WHERE parent_task_uuid IS NOT NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query subtasks: %w", err)
+	}
+	for childRows.Next() {
+		var child, parent string
+		if err := childRows.Scan(&child, &parent); err != nil {
+			childRows.Close()
+			return nil, fmt.Errorf("failed to scan subtask: %w", err)
+		}
+		children[parent] = append(children[parent], child)
+	}
+	childRows.Close()
+	if err := childRows.Err(); err != nil {
+		return nil, err
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, task := range candidates {
+			if !due[task.UUID] {
+				continue
+			}
+			for _, child := range children[task.UUID] {
+				if !due[child] {
+					due[task.UUID] = false
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	var queue []purgedTask
+	for _, task := range candidates {
+		if due[task.UUID] {
+			queue = append(queue, task)
+		} else {
+			report.Skipped = append(report.Skipped, skippedTask{ID: task.ID, Path: task.Path, Reason: "has subtasks that are not being purged"})
+		}
+	}
+
+	if opts.DryRun {
+		for _, task := range queue {
+			report.Tasks = append(report.Tasks, task)
+			report.Attachments += task.Attachments
+			report.BytesFreed += task.Bytes
+		}
+		return report, nil
+	}
+
+	// Purge leaves first: each pass takes the tasks none of whose subtasks
+	// remain. Parent links can form cycles, which never empty; those are
+	// reported as skipped.
+	s := store.New(database)
+	purged := make(map[string]bool, len(queue))
+	for len(queue) > 0 {
+		var next []purgedTask
+		for _, task := range queue {
+			remaining := false
+			for _, child := range children[task.UUID] {
+				if !purged[child] {
+					remaining = true
+					break
+				}
+			}
+			if remaining {
+				next = append(next, task)
+				continue
+			}
+
+			attachments, err := s.Tasks.GetAttachments(task.UUID)
+			if err != nil {
+				return report, fmt.Errorf("failed to get attachments for %s: %w", task.ID, err)
+			}
+			result, err := s.Tasks.Purge(actorUUID, task.UUID, 0)
+			if err != nil {
+				return report, fmt.Errorf("failed to purge %s: %w", task.ID, err)
+			}
+			removePurgedTaskFiles(attachDir, task.UUID, attachments)
+
+			purged[task.UUID] = true
+			task.Attachments = result.AttachmentsDeleted
+			task.Bytes = result.BytesFreed
+			report.Tasks = append(report.Tasks, task)
+			report.Attachments += result.AttachmentsDeleted
+			report.BytesFreed += result.BytesFreed
+		}
+		if len(next) == len(queue) {
+			for _, task := range next {
+				report.Skipped = append(report.Skipped, skippedTask{ID: task.ID, Path: task.Path, Reason: "subtask cycle"})
+			}
+			break
+		}
+		queue = next
+	}
+	return report, nil
+}
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lherron/wrkq/internal/attach"
+)
+
+func TestPurgeDeletedTasksSweepsOnlyOldDeletions(t *testing.T) {
+	database, _ := setupTestEnv(t)
+	attachDir := t.TempDir()
+	actorUUID := "00000000-0000-0000-0000-000000000001"
+
+	old := time.Now().UTC().AddDate(0, 0, -45).Format(time.RFC3339)
+	recent := time.Now().UTC().AddDate(0, 0, -5).Format(time.RFC3339)
+	seed := func(uuid, id, slug, state string, deletedAt, parent interface{}) {
+		t.Helper()
+		if _, err := database.Exec(`
+			INSERT INTO tasks (uuid, id, slug, title, project_uuid, state, priority, parent_task_uuid, deleted_at,
+				created_by_actor_uuid, updated_by_actor_uuid, etag)
+			VALUES (?, ?, ?, ?, '00000000-0000-0000-0000-000000000002', ?, 3, ?, ?, ?, ?, 1)
+		`, uuid, id, slug, slug, state, parent, deletedAt, actorUUID, actorUUID); err != nil {
+			t.Fatalf("failed to seed %s: %v", slug, err)
+		}
+	}
+	seed("00000000-0000-0000-0000-0000000000b1", "T-00001", "old-gone", "deleted", old, nil)
+	seed("00000000-0000-0000-0000-0000000000b2", "T-00002", "recent-gone", "deleted", recent, nil)
+	seed("00000000-0000-0000-0000-0000000000b3", "T-00003", "live", "open", nil, nil)
+	seed("00000000-0000-0000-0000-0000000000b4", "T-00004", "old-parent", "deleted", old, nil)
+	seed("00000000-0000-0000-0000-0000000000b5", "T-00005", "recent-child", "deleted", recent, "00000000-0000-0000-0000-0000000000b4")
+	seed("00000000-0000-0000-0000-0000000000b6", "T-00006", "old-parent-2", "deleted", old, nil)
+	seed("00000000-0000-0000-0000-0000000000b7", "T-00007", "old-child", "deleted", old, "00000000-0000-0000-0000-0000000000b6")
+
+	// The old deletion has a comment, a relation and an attachment file
+	relativePath := attach.RelativePath("00000000-0000-0000-0000-0000000000b1", "notes.txt")
+	filePath := attach.AbsolutePath(attachDir, relativePath)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		t.Fatalf("failed to create attachment dir: %v", err)
+	}
+	if err := os.WriteFile(filePath, []byte("notes\n"), 0644); err != nil {
+		t.Fatalf("failed to write attachment: %v", err)
+	}
+	for _, stmt := range []string{
+		`INSERT INTO comments (uuid, id, task_uuid, actor_uuid, body)
+		 VALUES ('00000000-0000-0000-0000-0000000000c1', 'C-00001', '00000000-0000-0000-0000-0000000000b1', '00000000-0000-0000-0000-000000000001', 'bye')`,
+		`INSERT INTO task_relations (from_task_uuid, to_task_uuid, kind, created_by_actor_uuid)
+		 VALUES ('00000000-0000-0000-0000-0000000000b1', '00000000-0000-0000-0000-0000000000b3', 'blocks', '00000000-0000-0000-0000-000000000001')`,
+		`INSERT INTO attachments (id, task_uuid, filename, relative_path, size_bytes, created_by_actor_uuid)
+		 VALUES ('', '00000000-0000-0000-0000-0000000000b1', 'notes.txt', '` + relativePath + `', 6, '00000000-0000-0000-0000-000000000001')`,
+	} {
+		if _, err := database.Exec(stmt); err != nil {
+			t.Fatalf("failed to seed: %v", err)
+		}
+	}
+
+	opts := purgeOptions{Cutoff: time.Now().UTC().AddDate(0, 0, -30), DryRun: true}
+	report, err := purgeDeletedTasks(database, attachDir, actorUUID, opts)
+	if err != nil {
+		t.Fatalf("dry-run purge failed: %v", err)
+	}
+	if len(report.Tasks) != 3 || report.Attachments != 1 || report.BytesFreed != 6 {
+		t.Fatalf("expected 3 tasks and 1 attachment in the dry run, got %+v", report)
+	}
+	if len(report.Skipped) != 1 || report.Skipped[0].ID != "T-00004" {
+		t.Fatalf("expected the parent of a recent deletion skipped, got %+v", report.Skipped)
+	}
+	var count int
+	if err := database.QueryRow(`SELECT COUNT(*) FROM tasks`).Scan(&count); err != nil || count != 7 {
+		t.Fatalf("expected the dry run to leave all 7 tasks, got %d (%v)", count, err)
+	}
+
+	opts.DryRun = false
+	report, err = purgeDeletedTasks(database, attachDir, actorUUID, opts)
+	if err != nil {
+		t.Fatalf("purge failed: %v", err)
+	}
+	purged := map[string]bool{}
+	for _, task := range report.Tasks {
+		purged[task.ID] = true
+	}
+	if len(purged) != 3 || !purged["T-00001"] || !purged["T-00006"] || !purged["T-00007"] {
+		t.Fatalf("expected T-00001, T-00006 and T-00007 purged, got %+v", report.Tasks)
+	}
+
+	rows, err := database.Query(`SELECT id FROM tasks ORDER BY id`)
+	if err != nil {
+		t.Fatalf("failed to list tasks: %v", err)
+	}
+	var remaining []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("failed to scan task: %v", err)
+		}
+		remaining = append(remaining, id)
+	}
+	rows.Close()
+	if len(remaining) != 4 || remaining[0] != "T-00002" || remaining[1] != "T-00003" || remaining[2] != "T-00004" || remaining[3] != "T-00005" {
+		t.Errorf("expected recent deletions, the live task and the skipped parent to remain, got %v", remaining)
+	}
+
+	for table, query := range map[string]string{
+		"comments":       `SELECT COUNT(*) FROM comments`,
+		"task_relations": `SELECT COUNT(*) FROM task_relations`,
+		"attachments":    `SELECT COUNT(*) FROM attachments`,
+	} {
+		if err := database.QueryRow(query).Scan(&count); err != nil || count != 0 {
+			t.Errorf("expected %s of purged tasks removed, got %d (%v)", table, count, err)
+		}
+	}
+	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+		t.Errorf("expected the attachment file removed, got %v", err)
+	}
+	if err := database.QueryRow(`SELECT COUNT(*) FROM event_log WHERE event_type = 'task.purged'`).Scan(&count); err != nil || count != 3 {
+		t.Errorf("expected 3 task.purged events, got %d (%v)", count, err)
+	}
+}
+
+func TestParseRetentionAge(t *testing.T) {
+	for value, want := range map[string]time.Duration{
+		"30d":  30 * 24 * time.Hour,
+		"2w":   14 * 24 * time.Hour,
+		"720h": 720 * time.Hour,
+	} {
+		got, err := parseRetentionAge(value)
+		if err != nil || got != want {
+			t.Errorf("parseRetentionAge(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "0d", "-3d", "soon", "d"} {
+		if _, err := parseRetentionAge(value); err == nil {
+			t.Errorf("parseRetentionAge(%q) expected an error", value)
+		}
+	}
+}
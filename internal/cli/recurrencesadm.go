@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lherron/wrkq/internal/cli/appctx"
+	"github.com/lherron/wrkq/internal/domain"
+	"github.com/lherron/wrkq/internal/render"
+	"github.com/lherron/wrkq/internal/selectors"
+	"github.com/lherron/wrkq/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var recurrencesAdmCmd = &cobra.Command{
+	Use:   "recurrences",
+	Short: "Manage recurring tasks",
+	Long: `Administrative commands for recurring tasks. A recurrence turns a task into
+a template that is materialized as a new open task every N days or weeks.
+Each occurrence copies the template's title, description, kind, priority,
+labels and assignee, is due at the occurrence time, and is linked back to
+the template with a relates_to relation.`,
+}
+
+var recurrencesAdmSetCmd = &cobra.Command{
+	Use:   "set <task>",
+	Short: "Make a task recur",
+	Long: `Creates or replaces the recurrence of a template task. Replacing a
+recurrence keeps its record of occurrences already generated.
+
+Examples:
+  wrkqadm recurrences set inbox/standup --every 1 --unit day --start 2024-03-01T09:00:00Z
+  wrkqadm recurrences set inbox/report --every 2 --unit week --day mon`,
+	Args: cobra.ExactArgs(1),
+	RunE: appctx.WithApp(appctx.DefaultOptions(), runRecurrencesAdmSet),
+}
+
+var recurrencesAdmLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List recurrences",
+	Args:  cobra.NoArgs,
+	RunE:  appctx.WithApp(appctx.DefaultOptions(), runRecurrencesAdmList),
+}
+
+var recurrencesAdmRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Generate due occurrences",
+	Long: `Materializes every occurrence due at --now (default: the current time) that
+hasn't been generated yet, in one transaction. Running it again in the same
+window creates nothing, so it is safe to call from cron. A recurrence that
+started long ago catches up at most 100 occurrences per run.`,
+	Args: cobra.NoArgs,
+	RunE: appctx.WithApp(appctx.WithActor(), runRecurrencesAdmRun),
+}
+
+var (
+	recurrencesAdmSetEvery     int
+	recurrencesAdmSetUnit      string
+	recurrencesAdmSetDay       string
+	recurrencesAdmSetStart     string
+	recurrencesAdmLsJSON       bool
+	recurrencesAdmLsPorcelain  bool
+	recurrencesAdmRunNow       string
+	recurrencesAdmRunJSON      bool
+	recurrencesAdmRunPorcelain bool
+)
+
+func init() {
+	rootAdmCmd.AddCommand(recurrencesAdmCmd)
+	recurrencesAdmCmd.AddCommand(recurrencesAdmSetCmd)
+	recurrencesAdmCmd.AddCommand(recurrencesAdmLsCmd)
+	recurrencesAdmCmd.AddCommand(recurrencesAdmRunCmd)
+
+	recurrencesAdmSetCmd.Flags().IntVar(&recurrencesAdmSetEvery, "every", 1, "Interval between occurrences")
+	recurrencesAdmSetCmd.Flags().StringVar(&recurrencesAdmSetUnit, "unit", domain.RecurrenceWeek, "Interval unit (day, week)")
+	recurrencesAdmSetCmd.Flags().StringVar(&recurrencesAdmSetDay, "day", "", "Weekday for weekly recurrences (sun..sat or 0-6)")
+	recurrencesAdmSetCmd.Flags().StringVar(&recurrencesAdmSetStart, "start", "", "First occurrence (RFC3339 or YYYY-MM-DD, default: now)")
+
+	recurrencesAdmLsCmd.Flags().BoolVar(&recurrencesAdmLsJSON, "json", false, "Output as JSON")
+	recurrencesAdmLsCmd.Flags().BoolVar(&recurrencesAdmLsPorcelain, "porcelain", false, "Machine-readable output")
+
+	recurrencesAdmRunCmd.Flags().StringVar(&recurrencesAdmRunNow, "now", "", "Generate as of this time (RFC3339, default: now)")
+	recurrencesAdmRunCmd.Flags().BoolVar(&recurrencesAdmRunJSON, "json", false, "Output as JSON")
+	recurrencesAdmRunCmd.Flags().BoolVar(&recurrencesAdmRunPorcelain, "porcelain", false, "Machine-readable output")
+}
+
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// parseWeekday accepts a weekday name (sun, monday, ...) or 0-6 (0 = Sunday).
+func parseWeekday(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n <= 6 {
+		return n, nil
+	}
+	for i, name := range weekdayNames {
+		if s == name || s == strings.ToLower(time.Weekday(i).String()) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday: %s (expected sun..sat or 0-6)", s)
+}
+
+// parseRecurrenceTime accepts RFC3339 or a YYYY-MM-DD date (midnight UTC).
+func parseRecurrenceTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: expected RFC3339 or YYYY-MM-DD", s)
+	}
+	return t, nil
+}
+
+func runRecurrencesAdmSet(app *appctx.App, cmd *cobra.Command, args []string) error {
+	taskUUID, taskID, err := selectors.ResolveTask(app.DB, args[0])
+	if err != nil {
+		return err
+	}
+
+	r := domain.Recurrence{
+		TaskUUID: taskUUID,
+		Interval: recurrencesAdmSetEvery,
+		Unit:     recurrencesAdmSetUnit,
+		StartsAt: time.Now().UTC().Truncate(time.Second),
+	}
+	if recurrencesAdmSetDay != "" {
+		day, err := parseWeekday(recurrencesAdmSetDay)
+		if err != nil {
+			return err
+		}
+		r.Weekday = &day
+	}
+	if recurrencesAdmSetStart != "" {
+		if r.StartsAt, err = parseRecurrenceTime(recurrencesAdmSetStart); err != nil {
+			return err
+		}
+	}
+
+	saved, err := store.New(app.DB).Recurrences.Set(r)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "%s recurs %s, first occurrence %s\n",
+		taskID, describeRecurrence(saved), saved.FirstOccurrence().UTC().Format(time.RFC3339))
+	return nil
+}
+
+// describeRecurrence renders a spec as e.g. "every 2 weeks on mon".
+func describeRecurrence(r *domain.Recurrence) string {
+	desc := fmt.Sprintf("every %d %s", r.Interval, r.Unit)
+	if r.Interval != 1 {
+		desc += "s"
+	}
+	if r.Weekday != nil {
+		desc += " on " + weekdayNames[*r.Weekday]
+	}
+	return desc
+}
+
+func runRecurrencesAdmList(app *appctx.App, cmd *cobra.Command, args []string) error {
+	recurrences, err := store.New(app.DB).Recurrences.List()
+	if err != nil {
+		return err
+	}
+
+	if recurrencesAdmLsJSON {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		if !recurrencesAdmLsPorcelain {
+			encoder.SetIndent("", "  ")
+		}
+		return encoder.Encode(recurrences)
+	}
+
+	headers := []string{"Task", "Schedule", "Starts", "Last Generated"}
+	rows := make([][]string, 0, len(recurrences))
+	for i := range recurrences {
+		r := &recurrences[i]
+		task := r.TaskUUID
+		var path string
+		if err := app.DB.QueryRow("SELECT path FROM v_task_paths WHERE uuid = ?", r.TaskUUID).Scan(&path); err == nil {
+			task = path
+		}
+		last := ""
+		if r.LastGeneratedAt != nil {
+			last = r.LastGeneratedAt.UTC().Format(time.RFC3339)
+		}
+		rows = append(rows, []string{task, describeRecurrence(r), r.StartsAt.UTC().Format(time.RFC3339), last})
+	}
+
+	renderer := render.NewRenderer(cmd.OutOrStdout(), render.Options{
+		Format:    render.FormatTable,
+		Porcelain: recurrencesAdmLsPorcelain,
+	})
+	return renderer.RenderTable(headers, rows)
+}
+
+func runRecurrencesAdmRun(app *appctx.App, cmd *cobra.Command, args []string) error {
+	now := time.Now()
+	if recurrencesAdmRunNow != "" {
+		t, err := time.Parse(time.RFC3339, recurrencesAdmRunNow)
+		if err != nil {
+			return fmt.Errorf("invalid --now: %w", err)
+		}
+		now = t
+	}
+
+	generated, err := store.New(app.DB).Recurrences.Generate(app.ActorUUID, now)
+	if err != nil {
+		return err
+	}
+
+	if recurrencesAdmRunJSON {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		if !recurrencesAdmRunPorcelain {
+			encoder.SetIndent("", "  ")
+		}
+		return encoder.Encode(map[string]interface{}{
+			"generated": generated,
+		})
+	}
+
+	if len(generated) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "No occurrences due.")
+		return nil
+	}
+
+	headers := []string{"ID", "Slug", "Due"}
+	rows := make([][]string, 0, len(generated))
+	for _, g := range generated {
+		rows = append(rows, []string{g.TaskID, g.Slug, g.OccurrenceAt.UTC().Format(time.RFC3339)})
+	}
+	renderer := render.NewRenderer(cmd.OutOrStdout(), render.Options{
+		Format:    render.FormatTable,
+		Porcelain: recurrencesAdmRunPorcelain,
+	})
+	return renderer.RenderTable(headers, rows)
+}
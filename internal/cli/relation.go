@@ -1,13 +1,19 @@
 package cli
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/lherron/wrkq/internal/cli/appctx"
+	"github.com/lherron/wrkq/internal/db"
 	"github.com/lherron/wrkq/internal/domain"
+	"github.com/lherron/wrkq/internal/events"
 	"github.com/lherron/wrkq/internal/render"
 	"github.com/lherron/wrkq/internal/selectors"
+	"github.com/lherron/wrkq/internal/webhooks"
 	"github.com/spf13/cobra"
 )
 
@@ -53,7 +59,7 @@ var relationRmCmd = &cobra.Command{
 Examples:
   wrkq relation rm T-00001 blocks T-00002`,
 	Args: cobra.ExactArgs(3),
-	RunE: appctx.WithApp(appctx.DefaultOptions(), runRelationRm),
+	RunE: appctx.WithApp(appctx.WithActor(), runRelationRm),
 }
 
 var relationLsCmd = &cobra.Command{
@@ -118,13 +124,14 @@ func runRelationAdd(app *appctx.App, cmd *cobra.Command, args []string) error {
 	}
 
 	// Insert the relation
-	_, err = database.Exec(`
-		INSERT INTO task_relations (from_task_uuid, to_task_uuid, kind, created_by_actor_uuid)
-		VALUES (?, ?, ?, ?)
-	`, fromTaskUUID, toTaskUUID, kind, actorUUID)
+	created, err := insertTaskRelation(database, fromTaskUUID, toTaskUUID, kind, actorUUID)
 	if err != nil {
 		return fmt.Errorf("failed to create relation: %w", err)
 	}
+	if !created {
+		fmt.Fprintf(cmd.OutOrStdout(), "Relation already exists: %s %s %s\n", fromTaskID, kind, toTaskID)
+		return nil
+	}
 
 	fmt.Fprintf(cmd.OutOrStdout(), "Created relation: %s %s %s\n", fromTaskID, kind, toTaskID)
 	return nil
@@ -157,23 +164,197 @@ func runRelationRm(app *appctx.App, cmd *cobra.Command, args []string) error {
 	}
 
 	// Delete the relation
-	result, err := database.Exec(`
+	deleted, err := deleteTaskRelation(database, fromTaskUUID, toTaskUUID, kind, app.ActorUUID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("relation not found: %s %s %s", fromTaskID, kind, toTaskID)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Removed relation: %s %s %s\n", fromTaskID, kind, toTaskID)
+	return nil
+}
+
+// insertTaskRelation inserts a relation, rejecting "blocks" relations that
+// would close a cycle in the blocks graph. The check and insert share a
+// transaction so concurrent writers cannot race a cycle in. Inserting a
+// relation that already exists is a no-op; the result says whether it was
+// new, and only new relations log a task.relation.created event (and, for
+// blocks, queue webhooks for the blocked task, whose blocked_by changed).
+func insertTaskRelation(database *db.DB, fromUUID, toUUID, kind, actorUUID string) (bool, error) {
+	tx, err := database.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if kind == string(domain.TaskRelationBlocks) {
+		if err := checkBlocksCycle(tx, fromUUID, toUUID); err != nil {
+			return false, err
+		}
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO task_relations (from_task_uuid, to_task_uuid, kind, created_by_actor_uuid)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (from_task_uuid, to_task_uuid, kind) DO NOTHING
+	`, fromUUID, toUUID, kind, actorUUID)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	if err := logRelationEvent(database, tx, "task.relation.created", fromUUID, toUUID, kind, actorUUID); err != nil {
+		return false, err
+	}
+	return true, commitRelationChange(database, tx, toUUID, kind)
+}
+
+// deleteTaskRelation removes a relation and logs a task.relation.deleted
+// event, queueing webhooks like insertTaskRelation. It reports false when
+// there was no such relation.
+func deleteTaskRelation(database *db.DB, fromUUID, toUUID, kind, actorUUID string) (bool, error) {
+	tx, err := database.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
 		DELETE FROM task_relations
 		WHERE from_task_uuid = ? AND to_task_uuid = ? AND kind = ?
-	`, fromTaskUUID, toTaskUUID, kind)
+	`, fromUUID, toUUID, kind)
 	if err != nil {
-		return fmt.Errorf("failed to delete relation: %w", err)
+		return false, fmt.Errorf("failed to delete relation: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, nil
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		return fmt.Errorf("relation not found: %s %s %s", fromTaskID, kind, toTaskID)
+	if err := logRelationEvent(database, tx, "task.relation.deleted", fromUUID, toUUID, kind, actorUUID); err != nil {
+		return false, err
 	}
+	return true, commitRelationChange(database, tx, toUUID, kind)
+}
 
-	fmt.Fprintf(cmd.OutOrStdout(), "Removed relation: %s %s %s\n", fromTaskID, kind, toTaskID)
+// commitRelationChange commits a relation change. A blocks relation changes
+// the blocked task's webhook payload (blocked_by), so that task's webhooks
+// are queued in the transaction and sent after commit.
+func commitRelationChange(database *db.DB, tx *sql.Tx, toUUID, kind string) error {
+	notify := kind == string(domain.TaskRelationBlocks)
+	if notify {
+		if err := webhooks.EnqueueTask(tx, toUUID); err != nil {
+			return fmt.Errorf("failed to queue webhooks: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if notify {
+		webhooks.Notify(database)
+	}
 	return nil
 }
 
+// logRelationEvent records a relation change on the from task, with the
+// same payload merges use.
+func logRelationEvent(database *db.DB, tx *sql.Tx, eventType, fromUUID, toUUID, kind, actorUUID string) error {
+	payloadJSON, _ := json.Marshal(map[string]string{"from": fromUUID, "to": toUUID, "kind": kind})
+	payload := string(payloadJSON)
+	if err := events.NewWriter(database.DB).LogEvent(tx, &domain.Event{
+		ActorUUID:    &actorUUID,
+		ResourceType: "task",
+		ResourceUUID: &fromUUID,
+		EventType:    eventType,
+		Payload:      &payload,
+	}); err != nil {
+		return fmt.Errorf("failed to log event: %w", err)
+	}
+	return nil
+}
+
+// errBlocksCycle marks a blocks relation refused because it would close a cycle.
+var errBlocksCycle = errors.New("blocks relation would create a cycle")
+
+// relationQuerier is the read access checkBlocksCycle needs; both *sql.Tx and
+// the merge executor satisfy it.
+type relationQuerier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// checkBlocksCycle returns an error if adding "from blocks to" would create a
+// cycle, i.e. if to already (transitively) blocks from. The error wraps
+// errBlocksCycle and lists the offending path by friendly ID.
+func checkBlocksCycle(tx relationQuerier, fromUUID, toUUID string) error {
+	// Breadth-first search from toUUID along existing blocks edges.
+	parent := map[string]string{toUUID: ""}
+	queue := []string{toUUID}
+	found := false
+	for len(queue) > 0 && !found {
+		current := queue[0]
+		queue = queue[1:]
+
+		rows, err := tx.Query(`
+			SELECT to_task_uuid FROM task_relations
+			WHERE from_task_uuid = ? AND kind = 'blocks'
+		`, current)
+		if err != nil {
+			return fmt.Errorf("failed to walk blocks graph: %w", err)
+		}
+		var next []string
+		for rows.Next() {
+			var uuid string
+			if err := rows.Scan(&uuid); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to walk blocks graph: %w", err)
+			}
+			next = append(next, uuid)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to walk blocks graph: %w", err)
+		}
+
+		for _, uuid := range next {
+			if _, seen := parent[uuid]; seen {
+				continue
+			}
+			parent[uuid] = current
+			if uuid == fromUUID {
+				found = true
+				break
+			}
+			queue = append(queue, uuid)
+		}
+	}
+
+	if !found {
+		return nil
+	}
+
+	// Reconstruct from -> to -> ... -> from.
+	var reversed []string
+	for node := fromUUID; node != ""; node = parent[node] {
+		reversed = append(reversed, node)
+	}
+	cycle := []string{fromUUID}
+	for i := len(reversed) - 1; i >= 0; i-- {
+		cycle = append(cycle, reversed[i])
+	}
+
+	ids := make([]string, len(cycle))
+	for i, uuid := range cycle {
+		if err := tx.QueryRow("SELECT id FROM tasks WHERE uuid = ?", uuid).Scan(&ids[i]); err != nil {
+			ids[i] = uuid
+		}
+	}
+	return fmt.Errorf("%w: %s", errBlocksCycle, strings.Join(ids, " -> "))
+}
+
 type Relation struct {
 	Direction   string `json:"direction"` // "outgoing" or "incoming"
 	Kind        string `json:"kind"`
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInsertTaskRelationRejectsBlocksCycles(t *testing.T) {
+	database, _ := setupTestEnv(t)
+	actor := "00000000-0000-0000-0000-000000000001"
+
+	a := "00000000-0000-0000-0000-000000000701"
+	b := "00000000-0000-0000-0000-000000000702"
+	c := "00000000-0000-0000-0000-000000000703"
+	insertFindTask(t, database, a, "T-00701", "cycle-a", "open", "", "", nil)
+	insertFindTask(t, database, b, "T-00702", "cycle-b", "open", "", "", nil)
+	insertFindTask(t, database, c, "T-00703", "cycle-c", "open", "", "", nil)
+
+	if _, err := insertTaskRelation(database, a, b, "blocks", actor); err != nil {
+		t.Fatalf("A blocks B failed: %v", err)
+	}
+
+	// Direct cycle: A -> B -> A
+	_, err := insertTaskRelation(database, b, a, "blocks", actor)
+	if err == nil {
+		t.Fatalf("expected direct cycle to be rejected")
+	}
+	if !strings.Contains(err.Error(), "T-00702 -> T-00701 -> T-00702") {
+		t.Fatalf("expected cycle path in error, got %v", err)
+	}
+
+	if _, err := insertTaskRelation(database, b, c, "blocks", actor); err != nil {
+		t.Fatalf("B blocks C failed: %v", err)
+	}
+
+	// Indirect cycle: A -> B -> C -> A
+	_, err = insertTaskRelation(database, c, a, "blocks", actor)
+	if err == nil {
+		t.Fatalf("expected indirect cycle to be rejected")
+	}
+	if !strings.Contains(err.Error(), "T-00703 -> T-00701 -> T-00702 -> T-00703") {
+		t.Fatalf("expected cycle path in error, got %v", err)
+	}
+
+	// Other relation kinds are not subject to cycle detection.
+	if _, err := insertTaskRelation(database, c, a, "relates_to", actor); err != nil {
+		t.Fatalf("relates_to should be allowed: %v", err)
+	}
+	if _, err := insertTaskRelation(database, b, a, "duplicates", actor); err != nil {
+		t.Fatalf("duplicates should be allowed: %v", err)
+	}
+
+	var count int
+	if err := database.QueryRow("SELECT COUNT(*) FROM task_relations WHERE kind = 'blocks'").Scan(&count); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 blocks relations, got %d", count)
+	}
+}
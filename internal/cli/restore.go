@@ -13,6 +13,7 @@ import (
 	"github.com/lherron/wrkq/internal/events"
 	"github.com/lherron/wrkq/internal/id"
 	"github.com/lherron/wrkq/internal/selectors"
+	"github.com/lherron/wrkq/internal/store"
 	"github.com/lherron/wrkq/internal/webhooks"
 	"github.com/spf13/cobra"
 )
@@ -280,6 +281,14 @@ func restoreTaskWithOptions(database *db.DB, opts restoreTaskOptions) error {
 		return fmt.Errorf("failed to log event: %w", err)
 	}
 
+	var parentTaskUUID sql.NullString
+	if err := tx.QueryRow("SELECT parent_task_uuid FROM tasks WHERE uuid = ?", opts.taskUUID).Scan(&parentTaskUUID); err != nil {
+		return fmt.Errorf("failed to get parent task: %w", err)
+	}
+	if err := store.RefreshParentProgressTx(tx, eventWriter, opts.actorUUID, parentTaskUUID.String); err != nil {
+		return err
+	}
+
 	// Add comment if provided
 	if opts.comment != "" {
 		// Get next comment ID by calculating from MAX(id)+1
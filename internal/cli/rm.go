@@ -303,17 +303,7 @@ func removeTask(s *store.Store, attachDir, actorUUID, taskUUID string) (*rmResul
 		result.BytesFreed = purgeResult.BytesFreed
 
 		// Delete attachment files AFTER successful DB purge
-		for _, a := range attachments {
-			filePath := filepath.Join(attachDir, a.RelativePath)
-			if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
-				// Log warning but continue
-				fmt.Fprintf(os.Stderr, "Warning: failed to delete file %s: %v\n", filePath, err)
-			}
-		}
-
-		// Delete task directory
-		taskDir := filepath.Join(attachDir, "tasks", taskUUID)
-		os.RemoveAll(taskDir) // Ignore errors, directory might not exist
+		removePurgedTaskFiles(attachDir, taskUUID, attachments)
 	} else {
 		// Archive task (soft delete)
 		_, err := s.Tasks.Archive(actorUUID, taskUUID, 0)
@@ -324,3 +314,20 @@ func removeTask(s *store.Store, attachDir, actorUUID, taskUUID string) (*rmResul
 
 	return result, nil
 }
+
+// removePurgedTaskFiles deletes a purged task's attachment files and its
+// attachment directory. Failures are reported as warnings, since the rows
+// are already gone.
+func removePurgedTaskFiles(attachDir, taskUUID string, attachments []store.AttachmentInfo) {
+	for _, a := range attachments {
+		filePath := filepath.Join(attachDir, a.RelativePath)
+		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+			// Log warning but continue
+			fmt.Fprintf(os.Stderr, "Warning: failed to delete file %s: %v\n", filePath, err)
+		}
+	}
+
+	// Delete task directory
+	taskDir := filepath.Join(attachDir, "tasks", taskUUID)
+	os.RemoveAll(taskDir) // Ignore errors, directory might not exist
+}
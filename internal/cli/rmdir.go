@@ -95,6 +95,24 @@ func removeContainer(cmd *cobra.Command, database *db.DB, actorUUID, path string
 		return fmt.Errorf("container not empty: %s (contains %d task(s) and %d child container(s)). Use --force to remove anyway", path, taskCount, childCount)
 	}
 
+	// Refuse while API tokens are restricted to this container or one below
+	// it; deleting them silently could leave the daemon with no tokens
+	var tokenCount int
+	err = database.QueryRow(`
+		WITH RECURSIVE subtree(uuid) AS (
+			SELECT ?
+			UNION ALL
+			SELECT c.uuid FROM containers c JOIN subtree s ON c.parent_uuid = s.uuid
+		)
+		SELECT COUNT(*) FROM api_tokens WHERE project_uuid IN (SELECT uuid FROM subtree)
+	`, containerUUID).Scan(&tokenCount)
+	if err != nil {
+		return fmt.Errorf("failed to check API tokens: %w", err)
+	}
+	if tokenCount > 0 {
+		return fmt.Errorf("container %s is the project of %d API token(s); revoke them first with wrkqadm tokens revoke", path, tokenCount)
+	}
+
 	// Confirm if force is used and container is not empty
 	if rmdirForce && !rmdirYes && (taskCount > 0 || childCount > 0) {
 		fmt.Fprintf(cmd.ErrOrStderr(), "\nWARNING: This will permanently delete:\n")
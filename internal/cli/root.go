@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"github.com/lherron/wrkq/internal/config"
 	"github.com/spf13/cobra"
 )
 
@@ -23,5 +24,7 @@ func init() {
 	// Global flags can be added here
 	rootCmd.PersistentFlags().String("db", "", "Path to database file (overrides WRKQ_DB_PATH)")
 	rootCmd.PersistentFlags().String("as", "", "Actor to perform action as (slug or friendly ID)")
+	rootCmd.PersistentFlags().StringVar(&config.File, "config", "", "Path to config file (overrides WRKQ_CONFIG)")
+	rootCmd.PersistentFlags().StringVar(&config.Profile, "profile", "", "Config profile to use (overrides WRKQ_PROFILE)")
 	rootCmd.PersistentFlags().String("project", "", "Project to operate under (overrides WRKQ_PROJECT_ROOT)")
 }
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"github.com/lherron/wrkq/internal/config"
 	"github.com/spf13/cobra"
 )
 
@@ -23,4 +24,6 @@ func init() {
 	// Global flags for wrkqadm
 	rootAdmCmd.PersistentFlags().String("db", "", "Path to database file (overrides WRKQ_DB_PATH)")
 	rootAdmCmd.PersistentFlags().String("as", "", "Actor to perform action as (slug or friendly ID)")
+	rootAdmCmd.PersistentFlags().StringVar(&config.File, "config", "", "Path to config file (overrides WRKQ_CONFIG)")
+	rootAdmCmd.PersistentFlags().StringVar(&config.Profile, "profile", "", "Config profile to use (overrides WRKQ_PROFILE)")
 }
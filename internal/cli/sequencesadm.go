@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/lherron/wrkq/internal/cli/appctx"
+	"github.com/lherron/wrkq/internal/db"
+	"github.com/lherron/wrkq/internal/render"
+	"github.com/spf13/cobra"
+)
+
+var sequencesAdmCmd = &cobra.Command{
+	Use:   "sequences",
+	Short: "Inspect and repair friendly-ID sequences",
+	Long: `Administrative commands for the sequences that allocate friendly IDs
+(A-, P-, T-, ATT-, C- and event IDs).`,
+}
+
+var sequencesAdmSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Advance sequences that lag behind existing IDs",
+	Long: `Advances every friendly-ID sequence that is below the highest ID already in
+its table, so new inserts don't collide. Sequences drift after manual imports
+or restores that write explicit IDs; merge repairs them automatically, this
+command does it on its own. Sequences are only ever moved forward.
+
+Use --dry-run to report drift without changing anything.`,
+	Args: cobra.NoArgs,
+	RunE: appctx.WithApp(appctx.DefaultOptions(), runSequencesAdmSync),
+}
+
+var (
+	sequencesAdmSyncDryRun    bool
+	sequencesAdmSyncJSON      bool
+	sequencesAdmSyncPorcelain bool
+)
+
+// sequenceSync describes one sequence moved (or, in a dry run, to be moved)
+// from Old to New.
+type sequenceSync struct {
+	Sequence string `json:"sequence"`
+	Table    string `json:"table"`
+	Old      int    `json:"old"`
+	New      int    `json:"new"`
+}
+
+func init() {
+	rootAdmCmd.AddCommand(sequencesAdmCmd)
+	sequencesAdmCmd.AddCommand(sequencesAdmSyncCmd)
+
+	sequencesAdmSyncCmd.Flags().BoolVar(&sequencesAdmSyncDryRun, "dry-run", false, "Report drift without repairing it")
+	sequencesAdmSyncCmd.Flags().BoolVar(&sequencesAdmSyncJSON, "json", false, "Output as JSON")
+	sequencesAdmSyncCmd.Flags().BoolVar(&sequencesAdmSyncPorcelain, "porcelain", false, "Machine-readable output")
+}
+
+func runSequencesAdmSync(app *appctx.App, cmd *cobra.Command, args []string) error {
+	synced, err := syncSequences(app.DB, sequencesAdmSyncDryRun)
+	if err != nil {
+		return err
+	}
+
+	if sequencesAdmSyncJSON {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		if !sequencesAdmSyncPorcelain {
+			encoder.SetIndent("", "  ")
+		}
+		return encoder.Encode(map[string]interface{}{
+			"dry_run":   sequencesAdmSyncDryRun,
+			"sequences": synced,
+		})
+	}
+
+	if len(synced) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "All sequences are in sync.")
+		return nil
+	}
+
+	headers := []string{"Sequence", "Table", "Old", "New"}
+	rows := make([][]string, 0, len(synced))
+	for _, s := range synced {
+		rows = append(rows, []string{s.Sequence, s.Table, strconv.Itoa(s.Old), strconv.Itoa(s.New)})
+	}
+
+	r := render.NewRenderer(cmd.OutOrStdout(), render.Options{
+		Format:    render.FormatTable,
+		Porcelain: sequencesAdmSyncPorcelain,
+	})
+	if err := r.RenderTable(headers, rows); err != nil {
+		return err
+	}
+
+	if sequencesAdmSyncDryRun && !sequencesAdmSyncPorcelain {
+		fmt.Fprintln(cmd.OutOrStdout(), "\nDry run: no sequences were changed.")
+	}
+	return nil
+}
+
+// syncSequences repairs sqlite_sequence drift for the default specs and the
+// comment sequence in one transaction (or only reports it when dryRun).
+func syncSequences(database *db.DB, dryRun bool) ([]sequenceSync, error) {
+	tx, err := database.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var drifts []db.SequenceDrift
+	var commentDrift *db.SequenceDrift
+	if dryRun {
+		drifts, err = db.SequenceDrifts(tx, db.DefaultSequenceSpecs())
+		if err == nil {
+			commentDrift, err = db.CommentSequenceDrift(tx)
+		}
+	} else {
+		drifts, err = db.FixSequenceDrifts(tx, db.DefaultSequenceSpecs())
+		if err == nil {
+			commentDrift, err = db.FixCommentSequence(tx)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if commentDrift != nil {
+		drifts = append(drifts, *commentDrift)
+	}
+
+	synced := make([]sequenceSync, 0, len(drifts))
+	for _, d := range drifts {
+		synced = append(synced, sequenceSync{Sequence: d.SeqTable, Table: d.EntityTable, Old: d.SeqValue, New: d.MaxID})
+	}
+
+	if !dryRun {
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit sequence sync: %w", err)
+		}
+	}
+	return synced, nil
+}
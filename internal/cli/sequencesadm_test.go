@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"testing"
+)
+
+func TestSyncSequencesRepairsTaskDrift(t *testing.T) {
+	database, _ := setupTestEnv(t)
+
+	// Explicit friendly IDs (as a restore or manual import writes them)
+	// bypass the sequences
+	stmts := []string{
+		`INSERT INTO tasks (uuid, id, slug, title, project_uuid, state, priority, created_by_actor_uuid, updated_by_actor_uuid, etag)
+		 VALUES ('00000000-0000-0000-0000-0000000000e1', 'T-00500', 'imported', 'Imported', '00000000-0000-0000-0000-000000000002',
+			'open', 3, '00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001', 1)`,
+		`INSERT INTO comments (uuid, id, task_uuid, actor_uuid, body)
+		 VALUES ('00000000-0000-0000-0000-0000000000e2', 'C-00300', '00000000-0000-0000-0000-0000000000e1', '00000000-0000-0000-0000-000000000001', 'imported')`,
+	}
+	for _, stmt := range stmts {
+		if _, err := database.Exec(stmt); err != nil {
+			t.Fatalf("failed to seed: %v", err)
+		}
+	}
+
+	findSync := func(synced []sequenceSync, sequence string) *sequenceSync {
+		for i := range synced {
+			if synced[i].Sequence == sequence {
+				return &synced[i]
+			}
+		}
+		return nil
+	}
+
+	synced, err := syncSequences(database, true)
+	if err != nil {
+		t.Fatalf("dry-run syncSequences failed: %v", err)
+	}
+	if s := findSync(synced, "task_seq"); s == nil || s.New != 500 || s.Old >= 500 {
+		t.Fatalf("expected task_seq drift to 500, got %+v", s)
+	}
+	if s := findSync(synced, "comment_sequences"); s == nil || s.Old != 0 || s.New != 300 {
+		t.Fatalf("expected comment_sequences 0 -> 300, got %+v", s)
+	}
+
+	var seq int
+	database.QueryRow("SELECT COALESCE((SELECT seq FROM sqlite_sequence WHERE name = 'task_seq'), 0)").Scan(&seq)
+	if seq >= 500 {
+		t.Fatalf("dry run should not change task_seq, got %d", seq)
+	}
+
+	if _, err := syncSequences(database, false); err != nil {
+		t.Fatalf("syncSequences failed: %v", err)
+	}
+
+	// The next task and comment get fresh IDs instead of colliding
+	if _, err := database.Exec(`
+		INSERT INTO tasks (uuid, slug, title, project_uuid, state, priority, created_by_actor_uuid, updated_by_actor_uuid, etag)
+		VALUES ('00000000-0000-0000-0000-0000000000e3', 'fresh', 'Fresh', '00000000-0000-0000-0000-000000000002',
+			'open', 3, '00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001', 1)
+	`); err != nil {
+		t.Fatalf("insert after sync failed: %v", err)
+	}
+	var id string
+	database.QueryRow("SELECT id FROM tasks WHERE uuid = '00000000-0000-0000-0000-0000000000e3'").Scan(&id)
+	if id != "T-00501" {
+		t.Errorf("expected next task ID T-00501, got %s", id)
+	}
+
+	var commentSeq int
+	database.QueryRow("SELECT value FROM comment_sequences WHERE name = 'next_comment'").Scan(&commentSeq)
+	if commentSeq != 300 {
+		t.Errorf("expected comment sequence 300, got %d", commentSeq)
+	}
+
+	synced, err = syncSequences(database, true)
+	if err != nil {
+		t.Fatalf("syncSequences failed: %v", err)
+	}
+	if len(synced) != 0 {
+		t.Errorf("expected no drift after sync, got %+v", synced)
+	}
+}
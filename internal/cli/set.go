@@ -93,7 +93,7 @@ func init() {
 	setCmd.Flags().StringVar(&setMetaFile, "meta-file", "", "Load task metadata from file (JSON object or null)")
 	setCmd.Flags().StringVar(&setDueAt, "due-at", "", "Update task due date")
 	setCmd.Flags().StringVar(&setStartAt, "start-at", "", "Update task start date")
-	setCmd.Flags().StringVar(&setKind, "kind", "", "Update task kind (task, subtask, spike, bug, chore)")
+	setCmd.Flags().StringVar(&setKind, "kind", "", "Update task kind (one of the configured kinds)")
 	setCmd.Flags().StringVar(&setAssignee, "assignee", "", "Update task assignee (actor slug or ID)")
 	setCmd.Flags().StringVar(&setRequestedBy, "requested-by", "", "Update requester project ID")
 	setCmd.Flags().StringVar(&setAssignedProject, "assigned-project", "", "Update assignee project ID")
@@ -272,7 +272,11 @@ func buildFieldsFromFlags(database *db.DB) (map[string]interface{}, error) {
 
 	// Handle kind
 	if setKind != "" {
-		if err := domain.ValidateTaskKind(setKind); err != nil {
+		kinds, err := store.New(database).Kinds.List()
+		if err != nil {
+			return nil, err
+		}
+		if err := domain.ValidateTaskKind(setKind, kinds); err != nil {
 			return nil, err
 		}
 		fields["kind"] = setKind
@@ -30,7 +30,14 @@ The snapshot includes all actors, containers, tasks, and comments in a
 deterministic format suitable for diffing and version control.
 
 Canonicalization ensures byte-for-byte identical output for the same
-database state (sorted keys, no insignificant whitespace, sorted arrays).`,
+database state (sorted keys, no insignificant whitespace, sorted arrays).
+
+Use --since <timestamp> or --since-event <event-id> for an incremental
+export: a partial snapshot holding only entities updated in that window,
+plus meta.partial recording the window, the entities archived or deleted
+in it, and the last event id (the --since-event for the next export).
+Diff a partial snapshot against a full base with 'patch create'; entities
+it omits are treated as unchanged. Partial snapshots cannot be imported.`,
 	RunE: appctx.WithApp(appctx.DefaultOptions(), runStateExport),
 }
 
@@ -38,6 +45,8 @@ var (
 	stateExportOut           string
 	stateExportNoCanonical   bool
 	stateExportIncludeEvents bool
+	stateExportSince         string
+	stateExportSinceEvent    int64
 	stateExportJSON          bool
 )
 
@@ -51,6 +60,8 @@ func init() {
 	stateExportCmd.Flags().StringVar(&stateExportOut, "out", snapshot.DefaultOutputPath, "Output file path")
 	stateExportCmd.Flags().BoolVar(&stateExportNoCanonical, "no-canonical", false, "Disable canonicalization (pretty print)")
 	stateExportCmd.Flags().BoolVar(&stateExportIncludeEvents, "include-events", false, "Include full event log in snapshot")
+	stateExportCmd.Flags().StringVar(&stateExportSince, "since", "", "Only export entities updated at or after this timestamp (partial snapshot)")
+	stateExportCmd.Flags().Int64Var(&stateExportSinceEvent, "since-event", 0, "Only export entities touched by events after this event id (partial snapshot)")
 	stateExportCmd.Flags().BoolVar(&stateExportJSON, "json", false, "Output result as JSON")
 
 	// Import flags
@@ -72,6 +83,8 @@ func runStateExport(app *appctx.App, cmd *cobra.Command, args []string) error {
 		OutputPath:    stateExportOut,
 		Canonical:     !stateExportNoCanonical,
 		IncludeEvents: stateExportIncludeEvents,
+		Since:         stateExportSince,
+		SinceEvent:    stateExportSinceEvent,
 	}
 
 	result, err := snapshot.Export(database.DB, opts)
@@ -94,6 +107,9 @@ func runStateExport(app *appctx.App, cmd *cobra.Command, args []string) error {
 		if result.EventCount > 0 {
 			fmt.Printf("  events: %d\n", result.EventCount)
 		}
+		if result.Partial {
+			fmt.Printf("  partial: %d removed\n", result.RemovedCount)
+		}
 	}
 
 	return nil
@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lherron/wrkq/internal/actors"
+	"github.com/lherron/wrkq/internal/cli/appctx"
+	"github.com/lherron/wrkq/internal/domain"
+	"github.com/lherron/wrkq/internal/render"
+	"github.com/lherron/wrkq/internal/selectors"
+	"github.com/lherron/wrkq/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var tokensAdmCmd = &cobra.Command{
+	Use:   "tokens",
+	Short: "Manage daemon API tokens",
+	Long: `Administrative commands for the API tokens wrkqd accepts. Each token acts as
+one actor with a scope: read (read endpoints only), write (also writes) or
+admin (also actor management, bundles, and acting as another actor through
+X-Wrkq-Actor). A token may be restricted to a container and everything below
+it.
+
+While no tokens exist, wrkqd runs in legacy mode: its --token (if set) grants
+full access and the actor comes from X-Wrkq-Actor. Creating the first token
+switches it to token mode, where only tokens are accepted; revoking the last
+one switches it back.`,
+}
+
+var tokensAdmCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create an API token",
+	Long:  `Creates a token and prints it. Only a hash is stored, so the token can't be shown again.`,
+	Example: `  wrkqadm tokens create ci-bot --actor ci-bot --scope write
+  wrkqadm tokens create dashboard --actor viewer --scope read --project portal`,
+	Args: cobra.ExactArgs(1),
+	RunE: appctx.WithApp(appctx.DefaultOptions(), runTokensAdmCreate),
+}
+
+var tokensAdmLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List API tokens",
+	Args:  cobra.NoArgs,
+	RunE:  appctx.WithApp(appctx.DefaultOptions(), runTokensAdmLs),
+}
+
+var tokensAdmRevokeCmd = &cobra.Command{
+	Use:   "revoke <name|uuid>...",
+	Short: "Revoke API tokens",
+	Long:  `Deletes tokens; requests presenting them are refused from then on.`,
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  appctx.WithApp(appctx.DefaultOptions(), runTokensAdmRevoke),
+}
+
+var (
+	tokensAdmCreateActor   string
+	tokensAdmCreateScope   string
+	tokensAdmCreateProject string
+	tokensAdmCreateJSON    bool
+	tokensAdmLsJSON        bool
+	tokensAdmLsPorcelain   bool
+)
+
+func init() {
+	rootAdmCmd.AddCommand(tokensAdmCmd)
+	tokensAdmCmd.AddCommand(tokensAdmCreateCmd)
+	tokensAdmCmd.AddCommand(tokensAdmLsCmd)
+	tokensAdmCmd.AddCommand(tokensAdmRevokeCmd)
+
+	tokensAdmCreateCmd.Flags().StringVar(&tokensAdmCreateActor, "actor", "", "Actor the token acts as (slug, ID or UUID)")
+	tokensAdmCreateCmd.Flags().StringVar(&tokensAdmCreateScope, "scope", string(domain.TokenScopeRead), "Token scope (read, write, admin)")
+	tokensAdmCreateCmd.Flags().StringVar(&tokensAdmCreateProject, "project", "", "Restrict the token to this container and everything below it")
+	tokensAdmCreateCmd.Flags().BoolVar(&tokensAdmCreateJSON, "json", false, "Output as JSON")
+	_ = tokensAdmCreateCmd.MarkFlagRequired("actor")
+
+	tokensAdmLsCmd.Flags().BoolVar(&tokensAdmLsJSON, "json", false, "Output as JSON")
+	tokensAdmLsCmd.Flags().BoolVar(&tokensAdmLsPorcelain, "porcelain", false, "Machine-readable output")
+}
+
+func runTokensAdmCreate(app *appctx.App, cmd *cobra.Command, args []string) error {
+	actorUUID, err := actors.NewResolver(app.DB.DB).Resolve(tokensAdmCreateActor)
+	if err != nil {
+		return fmt.Errorf("failed to resolve actor: %w", err)
+	}
+	var projectUUID string
+	if tokensAdmCreateProject != "" {
+		if projectUUID, _, err = selectors.ResolveContainer(app.DB, tokensAdmCreateProject); err != nil {
+			return err
+		}
+	}
+
+	token, plaintext, err := store.New(app.DB).Tokens.Create(actorUUID, args[0], tokensAdmCreateScope, projectUUID)
+	if err != nil {
+		return err
+	}
+
+	if tokensAdmCreateJSON {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(struct {
+			*domain.APIToken
+			Token string `json:"token"`
+		}{token, plaintext})
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Created %s token %s for %s\n", token.Scope, token.Name, token.ActorSlug)
+	fmt.Fprintln(out, plaintext)
+	fmt.Fprintln(out, "Store it now: the token can't be shown again.")
+	return nil
+}
+
+func runTokensAdmLs(app *appctx.App, cmd *cobra.Command, args []string) error {
+	tokens, err := store.New(app.DB).Tokens.List()
+	if err != nil {
+		return err
+	}
+
+	if tokensAdmLsJSON {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		if !tokensAdmLsPorcelain {
+			encoder.SetIndent("", "  ")
+		}
+		return encoder.Encode(tokens)
+	}
+
+	headers := []string{"Name", "Actor", "Scope", "Project", "Created"}
+	var rows [][]string
+	for _, token := range tokens {
+		project := ""
+		if token.ProjectUUID != nil {
+			if err := app.DB.QueryRow("SELECT path FROM v_container_paths WHERE uuid = ?", *token.ProjectUUID).Scan(&project); err != nil {
+				project = *token.ProjectUUID
+			}
+		}
+		rows = append(rows, []string{
+			token.Name,
+			token.ActorSlug,
+			string(token.Scope),
+			project,
+			token.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	r := render.NewRenderer(cmd.OutOrStdout(), render.Options{
+		Format:    render.FormatTable,
+		Porcelain: tokensAdmLsPorcelain,
+	})
+	return r.RenderTable(headers, rows)
+}
+
+func runTokensAdmRevoke(app *appctx.App, cmd *cobra.Command, args []string) error {
+	s := store.New(app.DB)
+	for _, selector := range args {
+		if err := s.Tokens.Revoke(selector); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Revoked token: %s\n", selector)
+	}
+	return nil
+}
@@ -60,7 +60,7 @@ func init() {
 	touchCmd.Flags().StringVarP(&touchDescription, "description", "d", "", "Description for the task (use @file.md for file or - for stdin)")
 	touchCmd.Flags().StringVar(&touchState, "state", "open", "Initial task state (idea, draft, open, in_progress, completed, blocked, cancelled)")
 	touchCmd.Flags().IntVar(&touchPriority, "priority", 3, "Initial task priority (1-4)")
-	touchCmd.Flags().StringVar(&touchKind, "kind", "", "Task kind: task, subtask, spike, bug, chore (default: task)")
+	touchCmd.Flags().StringVar(&touchKind, "kind", "", "Task kind, one of the configured kinds (default: task; see wrkqadm kinds ls)")
 	touchCmd.Flags().StringVar(&touchParentTask, "parent-task", "", "Parent task ID or path (for subtasks)")
 	touchCmd.Flags().StringVar(&touchAssignee, "assignee", "", "Assignee actor slug or ID")
 	touchCmd.Flags().StringVar(&touchRequestedBy, "requested-by", "", "Requester project ID (return-to target)")
@@ -98,7 +98,11 @@ func runTouch(app *appctx.App, cmd *cobra.Command, args []string) error {
 
 	// Validate kind if provided
 	if touchKind != "" {
-		if err := domain.ValidateTaskKind(touchKind); err != nil {
+		kinds, err := store.New(app.DB).Kinds.List()
+		if err != nil {
+			return err
+		}
+		if err := domain.ValidateTaskKind(touchKind, kinds); err != nil {
 			return err
 		}
 	}
@@ -80,9 +80,39 @@ type treeNode struct {
 	IsArchived           bool        `json:"is_archived"`
 	IsDeleted            bool        `json:"is_deleted"`
 	AllTasksCompleted    bool        `json:"all_tasks_completed,omitempty"` // for containers
+	TaskCounts           *treeCounts `json:"task_counts,omitempty"`         // containers, when requested
+	HasChildren          *bool       `json:"has_children,omitempty"`        // containers cut off by the depth limit
 	Children             []*treeNode `json:"children,omitempty"`
 }
 
+// treeCounts tallies a container's tasks by state. Other holds states
+// outside the four listed (idea, draft, cancelled, and archived/deleted when
+// those are included).
+type treeCounts struct {
+	Open       int `json:"open"`
+	InProgress int `json:"in_progress"`
+	Blocked    int `json:"blocked"`
+	Completed  int `json:"completed"`
+	Other      int `json:"other"`
+	Total      int `json:"total"`
+}
+
+func (c *treeCounts) add(state string, n int) {
+	switch state {
+	case "open":
+		c.Open += n
+	case "in_progress":
+		c.InProgress += n
+	case "blocked":
+		c.Blocked += n
+	case "completed":
+		c.Completed += n
+	default:
+		c.Other += n
+	}
+	c.Total += n
+}
+
 func displayTree(database *db.DB, rootPath string, maxDepth int, includeArchived bool, openOnly bool, porcelain bool, jsonOutput bool) error {
 	// Build tree structure
 	root, err := buildTree(database, rootPath, maxDepth, includeArchived, openOnly, 0)
@@ -191,6 +221,16 @@ func buildTree(database *db.DB, path string, maxDepth int, includeArchived bool,
 		node.Children = child.Children
 		node.AllTasksCompleted = child.AllTasksCompleted
 
+		// Children of this node were not loaded; say whether there are any
+		if maxDepth > 0 && currentDepth+1 >= maxDepth {
+			hasChildren, err := treeHasChildren(database, node.UUID, includeArchived, openOnly)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			node.HasChildren = &hasChildren
+		}
+
 		root.Children = append(root.Children, &node)
 	}
 	rows.Close()
@@ -301,6 +341,115 @@ func buildTree(database *db.DB, path string, maxDepth int, includeArchived bool,
 	return root, nil
 }
 
+// treeHasChildren reports whether a container has any subcontainer or task
+// that buildTree would show under the same filters. Like buildTree, it hides
+// a container's tasks once every task in it and in the containers below it is
+// completed, archived or deleted.
+func treeHasChildren(database *db.DB, containerUUID string, includeArchived, openOnly bool) (bool, error) {
+	containerFilter := ""
+	subtreeFilter := ""
+	taskFilter := ""
+	if !includeArchived {
+		containerFilter = " AND archived_at IS NULL"
+		subtreeFilter = " AND c.archived_at IS NULL"
+		taskFilter += " AND archived_at IS NULL AND deleted_at IS NULL"
+	}
+	if openOnly {
+		taskFilter += " AND state IN ('open', 'in_progress', 'blocked')"
+	}
+
+	var hasChildren bool
+	err := database.QueryRow(`
+		WITH RECURSIVE subtree(uuid) AS (
+			SELECT ?
+			UNION ALL
+			SELECT c.uuid FROM containers c JOIN subtree s ON c.parent_uuid = s.uuid
+			WHERE 1 = 1`+subtreeFilter+`
+		)
+		SELECT EXISTS (SELECT 1 FROM containers WHERE parent_uuid = ?`+containerFilter+`)
+		    OR (EXISTS (SELECT 1 FROM tasks WHERE project_uuid = ?`+taskFilter+`)
+		        AND EXISTS (
+		            SELECT 1 FROM tasks t JOIN subtree s ON t.project_uuid = s.uuid
+		            WHERE t.archived_at IS NULL AND t.deleted_at IS NULL AND t.state != 'completed'
+		        ))
+	`, containerUUID, containerUUID, containerUUID).Scan(&hasChildren)
+	if err != nil {
+		return false, fmt.Errorf("failed to check children: %w", err)
+	}
+	return hasChildren, nil
+}
+
+// annotateTreeCounts sets TaskCounts on every container node under nodes.
+// Counts cover the tasks buildTree would show with the same includeArchived
+// and openOnly settings; a container's own tasks only, or with rollup those
+// of every container below it too (including ones past the depth limit).
+// All containers are counted in one recursive query.
+func annotateTreeCounts(database *db.DB, nodes []*treeNode, includeArchived, openOnly, rollup bool) error {
+	containerFilter := ""
+	taskFilter := ""
+	if !includeArchived {
+		containerFilter = " AND c.archived_at IS NULL"
+		taskFilter += " AND t.archived_at IS NULL AND t.deleted_at IS NULL"
+	}
+	if openOnly {
+		taskFilter += " AND t.state IN ('open', 'in_progress', 'blocked')"
+	}
+
+	// closure pairs each container with itself and, for rollups, with every
+	// container below it
+	rows, err := database.Query(`
+		WITH RECURSIVE closure(ancestor, descendant) AS (
+			SELECT uuid, uuid FROM containers
+			UNION ALL
+			SELECT cl.ancestor, c.uuid
+			FROM closure cl
+			JOIN containers c ON c.parent_uuid = cl.descendant
+			WHERE ?`+containerFilter+`
+		)
+		SELECT cl.ancestor, t.state, COUNT(*)
+		FROM closure cl
+		JOIN tasks t ON t.project_uuid = cl.descendant
+		WHERE 1 = 1`+taskFilter+`
+		GROUP BY cl.ancestor, t.state
+	`, rollup)
+	if err != nil {
+		return fmt.Errorf("failed to count tasks: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]*treeCounts)
+	for rows.Next() {
+		var containerUUID, state string
+		var n int
+		if err := rows.Scan(&containerUUID, &state, &n); err != nil {
+			return fmt.Errorf("failed to scan task counts: %w", err)
+		}
+		if counts[containerUUID] == nil {
+			counts[containerUUID] = &treeCounts{}
+		}
+		counts[containerUUID].add(state, n)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var walk func([]*treeNode)
+	walk = func(nodes []*treeNode) {
+		for _, node := range nodes {
+			if node.Type != "container" {
+				continue
+			}
+			node.TaskCounts = counts[node.UUID]
+			if node.TaskCounts == nil {
+				node.TaskCounts = &treeCounts{}
+			}
+			walk(node.Children)
+		}
+	}
+	walk(nodes)
+	return nil
+}
+
 func printTree(node *treeNode, prefix string, isLast bool, porcelain bool) {
 	for i, child := range node.Children {
 		isLastChild := i == len(node.Children)-1
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/lherron/wrkq/internal/bundle"
 	"github.com/spf13/cobra"
 )
 
@@ -34,7 +35,7 @@ func runVersion(cmd *cobra.Command, args []string) error {
 			"version":                   Version,
 			"commit":                    GitCommit,
 			"build_date":                BuildDate,
-			"machine_interface_version": 1,
+			"machine_interface_version": bundle.MachineInterfaceVersion,
 			"supported_commands": []string{
 				// M0 - Core
 				"init", "whoami", "actors", "actor",
@@ -79,7 +80,7 @@ func runVersion(cmd *cobra.Command, args []string) error {
 	fmt.Fprintf(cmd.OutOrStdout(), "wrkq version %s\n", Version)
 	fmt.Fprintf(cmd.OutOrStdout(), "  commit: %s\n", GitCommit)
 	fmt.Fprintf(cmd.OutOrStdout(), "  built:  %s\n", BuildDate)
-	fmt.Fprintf(cmd.OutOrStdout(), "  machine interface: v%d\n", 1)
+	fmt.Fprintf(cmd.OutOrStdout(), "  machine interface: v%d\n", bundle.MachineInterfaceVersion)
 
 	return nil
 }
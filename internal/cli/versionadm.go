@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/lherron/wrkq/internal/bundle"
 	"github.com/spf13/cobra"
 )
 
@@ -28,7 +29,7 @@ func runVersionAdm(cmd *cobra.Command, args []string) error {
 			"version":                   Version,
 			"commit":                    GitCommit,
 			"build_date":                BuildDate,
-			"machine_interface_version": 1,
+			"machine_interface_version": bundle.MachineInterfaceVersion,
 			"supported_commands": []string{
 				// Admin commands
 				"init",
@@ -51,7 +52,7 @@ func runVersionAdm(cmd *cobra.Command, args []string) error {
 	fmt.Fprintf(cmd.OutOrStdout(), "wrkqadm version %s\n", Version)
 	fmt.Fprintf(cmd.OutOrStdout(), "  commit: %s\n", GitCommit)
 	fmt.Fprintf(cmd.OutOrStdout(), "  built:  %s\n", BuildDate)
-	fmt.Fprintf(cmd.OutOrStdout(), "  machine interface: v%d\n", 1)
+	fmt.Fprintf(cmd.OutOrStdout(), "  machine interface: v%d\n", bundle.MachineInterfaceVersion)
 
 	return nil
 }
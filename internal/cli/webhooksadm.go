@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/lherron/wrkq/internal/cli/appctx"
+	"github.com/lherron/wrkq/internal/render"
+	"github.com/lherron/wrkq/internal/webhooks"
+	"github.com/spf13/cobra"
+)
+
+var webhooksAdmCmd = &cobra.Command{
+	Use:   "webhooks",
+	Short: "Inspect and redrive webhook deliveries",
+	Long: `Administrative commands for webhook deliveries. Every delivery is queued
+with the change that caused it; failed attempts are retried with exponential
+backoff and the delivery is marked dead when retries run out or the receiver
+rejects it with a non-retryable status.`,
+}
+
+var webhooksAdmLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List recorded webhook deliveries",
+	Long:  `Lists recorded webhook deliveries, oldest first. Defaults to dead-lettered deliveries; use --status all to show every recorded delivery.`,
+	RunE:  appctx.WithApp(appctx.DefaultOptions(), runWebhooksAdmList),
+}
+
+var webhooksAdmRedriveCmd = &cobra.Command{
+	Use:   "redrive <id>...",
+	Short: "Retry dead-lettered webhook deliveries",
+	Long: `Sends each delivery's stored payload again under the normal retry policy,
+signing it with the secret currently configured for its URL.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: appctx.WithApp(appctx.DefaultOptions(), runWebhooksAdmRedrive),
+}
+
+var (
+	webhooksAdmLsStatus    string
+	webhooksAdmLsJSON      bool
+	webhooksAdmLsPorcelain bool
+	webhooksAdmRedriveJSON bool
+)
+
+func init() {
+	rootAdmCmd.AddCommand(webhooksAdmCmd)
+	webhooksAdmCmd.AddCommand(webhooksAdmLsCmd)
+	webhooksAdmCmd.AddCommand(webhooksAdmRedriveCmd)
+
+	webhooksAdmLsCmd.Flags().StringVar(&webhooksAdmLsStatus, "status", webhooks.DeliveryDead, "Filter by status (pending, delivered, dead, all)")
+	webhooksAdmLsCmd.Flags().BoolVar(&webhooksAdmLsJSON, "json", false, "Output as JSON")
+	webhooksAdmLsCmd.Flags().BoolVar(&webhooksAdmLsPorcelain, "porcelain", false, "Machine-readable output")
+
+	webhooksAdmRedriveCmd.Flags().BoolVar(&webhooksAdmRedriveJSON, "json", false, "Output as JSON")
+}
+
+func runWebhooksAdmList(app *appctx.App, cmd *cobra.Command, args []string) error {
+	status := webhooksAdmLsStatus
+	switch status {
+	case "all":
+		status = ""
+	case webhooks.DeliveryPending, webhooks.DeliveryDelivered, webhooks.DeliveryDead:
+	default:
+		return fmt.Errorf("invalid status: must be one of: pending, delivered, dead, all")
+	}
+
+	deliveries, err := webhooks.ListDeliveries(app.DB, status)
+	if err != nil {
+		return err
+	}
+
+	if webhooksAdmLsJSON {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		if !webhooksAdmLsPorcelain {
+			encoder.SetIndent("", "  ")
+		}
+		return encoder.Encode(deliveries)
+	}
+
+	headers := []string{"ID", "Status", "Attempts", "URL", "Last Error", "Updated"}
+	var rows [][]string
+	for _, d := range deliveries {
+		lastError := ""
+		if d.LastError != nil {
+			lastError = *d.LastError
+		}
+		rows = append(rows, []string{
+			strconv.FormatInt(d.ID, 10),
+			d.Status,
+			strconv.Itoa(d.Attempts),
+			d.URL,
+			lastError,
+			d.UpdatedAt,
+		})
+	}
+
+	r := render.NewRenderer(cmd.OutOrStdout(), render.Options{
+		Format:    render.FormatTable,
+		Porcelain: webhooksAdmLsPorcelain,
+	})
+
+	return r.RenderTable(headers, rows)
+}
+
+func runWebhooksAdmRedrive(app *appctx.App, cmd *cobra.Command, args []string) error {
+	ids := make([]int64, 0, len(args))
+	for _, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid delivery id: %s", arg)
+		}
+		ids = append(ids, id)
+	}
+
+	var results []*webhooks.Delivery
+	failed := 0
+	for _, id := range ids {
+		d, err := webhooks.Redrive(app.DB, id)
+		if err != nil {
+			return err
+		}
+		results = append(results, d)
+		if d.Status != webhooks.DeliveryDelivered {
+			failed++
+		}
+		if !webhooksAdmRedriveJSON {
+			fmt.Fprintf(cmd.OutOrStdout(), "Delivery %d: %s (%d attempts)\n", d.ID, d.Status, d.Attempts)
+		}
+	}
+
+	if webhooksAdmRedriveJSON {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d deliveries still failing", failed, len(ids))
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/joho/godotenv"
 	"gopkg.in/yaml.v3"
@@ -19,27 +20,69 @@ type Config struct {
 	LogLevel         string `yaml:"log_level"`
 	Output           string `yaml:"output"`
 	Pager            string `yaml:"pager"`
+	// WebhookStrictTemplates skips webhook URLs with unknown {placeholders}
+	// instead of sending them with the placeholder left in place.
+	WebhookStrictTemplates bool `yaml:"webhook_strict_templates"`
 }
 
+// File and Profile select the config file and a profile within it; the
+// --config and --profile flags set them. When empty, WRKQ_CONFIG and
+// WRKQ_PROFILE apply.
+var (
+	File    string
+	Profile string
+)
+
 // Load loads configuration from multiple sources with precedence:
 // 1. Environment variables
 // 2. ./.env.local (dotenv) - walks up parent directories to find it
-// 3. ~/.config/wrkq/config.yaml (YAML)
+// 3. The selected profile of the config file
+// 4. The config file's top-level settings: --config, WRKQ_CONFIG, or
+// ~/.config/wrkq/config.yaml (YAML)
+//
+// A profile is a named set of settings under "profiles:" that overrides
+// the top-level ones, e.g. to switch between the canonical database and a
+// per-project one:
+//
+//	db_path: ~/.local/share/wrkq/wrkq.db
+//	profiles:
+//	  work:
+//	    db_path: /srv/wrkq/canonical.db
+//
+// The default config file is optional; a file named with --config or
+// WRKQ_CONFIG, or a selected profile, must exist.
 func Load() (*Config, error) {
+	return load("")
+}
+
+// LoadFrom is Load with the config file at path instead of the selected one.
+func LoadFrom(path string) (*Config, error) {
+	if path == "" {
+		return nil, fmt.Errorf("config file path is required")
+	}
+	return load(path)
+}
+
+func load(path string) (*Config, error) {
 	cfg := &Config{
 		AttachmentsMaxMB: 50,
 		LogLevel:         "info",
 		Output:           "table",
 	}
 
-	// Load .env.local if it exists (walking up parent directories)
+	// Load .env.local if it exists (walking up parent directories); it may
+	// also select the config file or profile
 	if envPath := findEnvLocal(); envPath != "" {
 		_ = godotenv.Load(envPath)
 	}
 
-	// Load ~/.config/wrkq/config.yaml if it exists
-	if err := loadYAMLConfig(cfg); err != nil {
-		// YAML config is optional, so we don't fail if it doesn't exist
+	required := path != ""
+	if path == "" {
+		path, _ = FilePath()
+		required = File != "" || os.Getenv("WRKQ_CONFIG") != ""
+	}
+	if err := loadYAMLConfig(cfg, path, required, SelectedProfile()); err != nil {
+		return nil, err
 	}
 
 	// Override with environment variables
@@ -64,6 +107,9 @@ func Load() (*Config, error) {
 	if projectRoot := os.Getenv("WRKQ_PROJECT_ROOT"); projectRoot != "" {
 		cfg.ProjectRoot = projectRoot
 	}
+	if strict := os.Getenv("WRKQ_WEBHOOK_STRICT_TEMPLATES"); strict != "" {
+		cfg.WebhookStrictTemplates = strict == "1" || strings.EqualFold(strict, "true")
+	}
 
 	// Set defaults if not configured
 	if cfg.DBPath == "" {
@@ -96,20 +142,52 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
-// loadYAMLConfig loads configuration from ~/.config/wrkq/config.yaml
-func loadYAMLConfig(cfg *Config) error {
-	homeDir, err := os.UserHomeDir()
+// loadYAMLConfig loads configuration from the YAML config file at path,
+// then overlays the named profile, if any. Unless the file is required or a
+// profile is selected, a missing or unreadable file is ignored.
+func loadYAMLConfig(cfg *Config, path string, required bool, profile string) error {
+	strict := required || profile != ""
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return err
+		if !strict {
+			return nil
+		}
+		return fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	configPath := filepath.Join(homeDir, ".config", "wrkq", "config.yaml")
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return err
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		if !strict {
+			return nil
+		}
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if profile == "" {
+		return nil
+	}
+
+	var file struct {
+		Profiles map[string]yaml.Node `yaml:"profiles"`
 	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse profiles in %s: %w", path, err)
+	}
+	node, ok := file.Profiles[profile]
+	if !ok {
+		return fmt.Errorf("unknown profile %q in %s", profile, path)
+	}
+	if err := node.Decode(cfg); err != nil {
+		return fmt.Errorf("failed to parse profile %q in %s: %w", profile, path, err)
+	}
+	return nil
+}
 
-	return yaml.Unmarshal(data, cfg)
+// SelectedProfile returns the profile chosen with --profile or
+// WRKQ_PROFILE, or "" for the top-level settings alone.
+func SelectedProfile() string {
+	if Profile != "" {
+		return Profile
+	}
+	return os.Getenv("WRKQ_PROFILE")
 }
 
 // getEnvOrFile gets an environment variable value, or reads it from a file
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -140,3 +141,82 @@ func TestFindEnvLocal_NotFound(t *testing.T) {
 		t.Errorf("expected empty string when no .env.local found, got %s", result)
 	}
 }
+
+func TestLoad_ProfileOverridesDefault(t *testing.T) {
+	setupConfigHome(t, `db_path: /default.db
+pager: less
+profiles:
+  work:
+    db_path: /work.db
+  project:
+    db_path: /project.db
+`)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.DBPath != "/default.db" {
+		t.Fatalf("expected top-level db_path without a profile, got %s", cfg.DBPath)
+	}
+
+	t.Setenv("WRKQ_PROFILE", "project")
+	if cfg, err = Load(); err != nil || cfg.DBPath != "/project.db" {
+		t.Fatalf("expected WRKQ_PROFILE to select project, got %+v (%v)", cfg, err)
+	}
+
+	Profile = "work"
+	if cfg, err = Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.DBPath != "/work.db" || cfg.Pager != "less" {
+		t.Fatalf("expected --profile over WRKQ_PROFILE, inheriting top-level keys, got %+v", cfg)
+	}
+
+	t.Setenv("WRKQ_DB_PATH", "/env.db")
+	if cfg, err = Load(); err != nil || cfg.DBPath != "/env.db" {
+		t.Fatalf("expected WRKQ_DB_PATH over the profile, got %+v (%v)", cfg, err)
+	}
+
+	Profile = "missing"
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), `unknown profile "missing"`) {
+		t.Fatalf("expected an unknown profile error, got %v", err)
+	}
+}
+
+func TestLoad_ConfigFileOverride(t *testing.T) {
+	home := setupConfigHome(t, "db_path: /default.db\n")
+	other := filepath.Join(home, "other.yaml")
+	if err := os.WriteFile(other, []byte("db_path: /other.db\nprofiles:\n  work:\n    db_path: /other-work.db\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFrom(other)
+	if err != nil || cfg.DBPath != "/other.db" {
+		t.Fatalf("expected LoadFrom to read %s, got %+v (%v)", other, cfg, err)
+	}
+
+	t.Setenv("WRKQ_CONFIG", other)
+	if cfg, err = Load(); err != nil || cfg.DBPath != "/other.db" {
+		t.Fatalf("expected WRKQ_CONFIG to select %s, got %+v (%v)", other, cfg, err)
+	}
+	Profile = "work"
+	if cfg, err = Load(); err != nil || cfg.DBPath != "/other-work.db" {
+		t.Fatalf("expected the profile from %s, got %+v (%v)", other, cfg, err)
+	}
+	Profile = ""
+
+	File = filepath.Join(home, ".config", "wrkq", "config.yaml")
+	if cfg, err = Load(); err != nil || cfg.DBPath != "/default.db" {
+		t.Fatalf("expected --config over WRKQ_CONFIG, got %+v (%v)", cfg, err)
+	}
+
+	// A named config file must exist; the default one need not
+	File = filepath.Join(home, "missing.yaml")
+	if _, err := Load(); err == nil {
+		t.Fatal("expected a missing --config file to fail")
+	}
+	if _, err := LoadFrom(filepath.Join(home, "missing.yaml")); err == nil {
+		t.Fatal("expected LoadFrom a missing file to fail")
+	}
+}
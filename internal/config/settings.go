@@ -0,0 +1,262 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Key describes a configuration setting: its config.yaml name, the
+// environment variables that override it (highest precedence first), and how
+// to read the effective value from a loaded Config.
+type Key struct {
+	Name string
+	Env  []string
+	// Secret values are masked when displayed
+	Secret bool
+	// EnvOnly settings cannot be written to the config file
+	EnvOnly bool
+	// Kind is "string", "int" or "bool"; used to validate config set
+	Kind  string
+	value func(c *Config) string
+}
+
+// Keys lists every setting shown by wrkqadm config.
+var Keys = []Key{
+	{Name: "db_path", Env: []string{"WRKQ_DB_PATH", "WRKQ_DB_PATH_FILE"}, Kind: "string",
+		value: func(c *Config) string { return c.DBPath }},
+	{Name: "attach_dir", Env: []string{"WRKQ_ATTACH_DIR"}, Kind: "string",
+		value: func(c *Config) string { return c.AttachDir }},
+	{Name: "attachments_max_mb", Kind: "int",
+		value: func(c *Config) string { return strconv.Itoa(c.AttachmentsMaxMB) }},
+	{Name: "default_actor", Env: []string{"WRKQ_ACTOR_ID", "WRKQ_ACTOR"}, Kind: "string",
+		value: func(c *Config) string { return c.GetActorID() }},
+	{Name: "project_root", Env: []string{"WRKQ_PROJECT_ROOT"}, Kind: "string",
+		value: func(c *Config) string { return c.ProjectRoot }},
+	{Name: "log_level", Env: []string{"WRKQ_LOG_LEVEL"}, Kind: "string",
+		value: func(c *Config) string { return c.LogLevel }},
+	{Name: "output", Env: []string{"WRKQ_OUTPUT"}, Kind: "string",
+		value: func(c *Config) string { return c.Output }},
+	{Name: "pager", Env: []string{"WRKQ_PAGER"}, Kind: "string",
+		value: func(c *Config) string { return c.Pager }},
+	{Name: "webhook_strict_templates", Env: []string{"WRKQ_WEBHOOK_STRICT_TEMPLATES"}, Kind: "bool",
+		value: func(c *Config) string { return strconv.FormatBool(c.WebhookStrictTemplates) }},
+	{Name: "daemon_token", Env: []string{"WRKQD_TOKEN"}, Secret: true, EnvOnly: true, Kind: "string",
+		value: func(c *Config) string { return os.Getenv("WRKQD_TOKEN") }},
+}
+
+// LookupKey finds a setting by its config.yaml name.
+func LookupKey(name string) (Key, bool) {
+	for _, k := range Keys {
+		if k.Name == name {
+			return k, true
+		}
+	}
+	return Key{}, false
+}
+
+// Setting is a resolved configuration value and where it came from.
+type Setting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	Secret bool   `json:"secret,omitempty"`
+}
+
+// Resolve loads the configuration and reports each setting's effective value
+// and source: an environment variable, .env.local (which only fills in
+// variables the environment doesn't set), the selected profile, the config
+// file, or the default.
+func Resolve() ([]Setting, error) {
+	cfg, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var dotenv map[string]string
+	envLocal := findEnvLocal()
+	if envLocal != "" {
+		dotenv, _ = godotenv.Read(envLocal)
+	}
+
+	fileKeys := map[string]interface{}{}
+	var profileKeys map[string]interface{}
+	profile := SelectedProfile()
+	path, _ := FilePath()
+	if data, err := os.ReadFile(path); err == nil {
+		_ = yaml.Unmarshal(data, &fileKeys)
+		var file struct {
+			Profiles map[string]map[string]interface{} `yaml:"profiles"`
+		}
+		if profile != "" && yaml.Unmarshal(data, &file) == nil {
+			profileKeys = file.Profiles[profile]
+		}
+	}
+
+	settings := make([]Setting, 0, len(Keys))
+	for _, k := range Keys {
+		s := Setting{Key: k.Name, Value: k.value(cfg), Source: "default", Secret: k.Secret}
+		if _, ok := fileKeys[k.Name]; ok && !k.EnvOnly {
+			s.Source = "config file " + path
+		}
+		if _, ok := profileKeys[k.Name]; ok && !k.EnvOnly {
+			s.Source = fmt.Sprintf("profile %s in %s", profile, path)
+		}
+		for _, env := range k.Env {
+			v := os.Getenv(env)
+			if v == "" {
+				continue
+			}
+			if dv, ok := dotenv[env]; ok && dv == v {
+				s.Source = fmt.Sprintf("%s in %s", env, envLocal)
+			} else {
+				s.Source = "environment variable " + env
+			}
+			break
+		}
+		settings = append(settings, s)
+	}
+	return settings, nil
+}
+
+// FilePath returns the path of the YAML config file (which may not exist):
+// File, WRKQ_CONFIG, or ~/.config/wrkq/config.yaml.
+func FilePath() (string, error) {
+	if File != "" {
+		return File, nil
+	}
+	if path := os.Getenv("WRKQ_CONFIG"); path != "" {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".config", "wrkq", "config.yaml"), nil
+}
+
+// SetFileValue writes key: value to the config file, or to the selected
+// profile in it, creating either if needed and preserving other keys and
+// comments. It returns the file path.
+func SetFileValue(name, value string) (string, error) {
+	k, ok := LookupKey(name)
+	if !ok {
+		return "", fmt.Errorf("unknown config key: %s", name)
+	}
+	if k.EnvOnly {
+		return "", fmt.Errorf("%s can only be set with %s", name, k.Env[0])
+	}
+
+	valueNode := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+	switch k.Kind {
+	case "int":
+		if _, err := strconv.Atoi(value); err != nil {
+			return "", fmt.Errorf("%s must be an integer", name)
+		}
+		valueNode.Tag = "!!int"
+	case "bool":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("%s must be true or false", name)
+		}
+		valueNode.Value = strconv.FormatBool(b)
+		valueNode.Tag = "!!bool"
+	default:
+		valueNode.Tag = "!!str"
+	}
+
+	path, err := FilePath()
+	if err != nil {
+		return "", err
+	}
+
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read config file: %w", err)
+	}
+	if len(data) > 0 {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return "", fmt.Errorf("failed to parse config file: %w", err)
+		}
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return "", fmt.Errorf("config file %s is not a YAML mapping", path)
+	}
+	if profile := SelectedProfile(); profile != "" {
+		profiles, err := childMapping(root, "profiles")
+		if err == nil {
+			root, err = childMapping(profiles, profile)
+		}
+		if err != nil {
+			return "", fmt.Errorf("config file %s: %w", path, err)
+		}
+	}
+
+	replaced := false
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == name {
+			valueNode.HeadComment = root.Content[i+1].HeadComment
+			valueNode.LineComment = root.Content[i+1].LineComment
+			root.Content[i+1] = valueNode
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, valueNode)
+	}
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode config file: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		return "", fmt.Errorf("failed to write config file: %w", err)
+	}
+	return path, nil
+}
+
+// childMapping returns the mapping stored under key in the mapping parent,
+// adding an empty one if key is absent.
+func childMapping(parent *yaml.Node, key string) (*yaml.Node, error) {
+	for i := 0; i+1 < len(parent.Content); i += 2 {
+		if parent.Content[i].Value != key {
+			continue
+		}
+		child := parent.Content[i+1]
+		if child.Kind == yaml.ScalarNode && child.Tag == "!!null" {
+			*child = yaml.Node{Kind: yaml.MappingNode}
+		}
+		if child.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s is not a YAML mapping", key)
+		}
+		return child, nil
+	}
+	child := &yaml.Node{Kind: yaml.MappingNode}
+	parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, child)
+	return child, nil
+}
+
+// Mask hides a secret value, keeping the last four characters of long values
+// so they can be told apart.
+func Mask(value string) string {
+	if value == "" {
+		return ""
+	}
+	if len(value) <= 8 {
+		return "********"
+	}
+	return "********" + value[len(value)-4:]
+}
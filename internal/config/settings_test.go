@@ -0,0 +1,195 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// setupConfigHome points HOME at a temp dir with the given config.yaml and
+// runs the test from a directory beneath it, with wrkq variables unset.
+func setupConfigHome(t *testing.T, configYAML string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, env := range []string{"WRKQ_CONFIG", "WRKQ_PROFILE"} {
+		t.Setenv(env, "")
+		os.Unsetenv(env)
+	}
+	File, Profile = "", ""
+	t.Cleanup(func() { File, Profile = "", "" })
+	for _, k := range Keys {
+		for _, env := range k.Env {
+			t.Setenv(env, "")
+			os.Unsetenv(env)
+		}
+	}
+
+	if configYAML != "" {
+		path := filepath.Join(home, ".config", "wrkq", "config.yaml")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(configYAML), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	work := filepath.Join(home, "work")
+	if err := os.Mkdir(work, 0755); err != nil {
+		t.Fatal(err)
+	}
+	oldCwd, _ := os.Getwd()
+	t.Cleanup(func() { os.Chdir(oldCwd) })
+	if err := os.Chdir(work); err != nil {
+		t.Fatal(err)
+	}
+	return home
+}
+
+func findSetting(t *testing.T, settings []Setting, key string) Setting {
+	t.Helper()
+	for _, s := range settings {
+		if s.Key == key {
+			return s
+		}
+	}
+	t.Fatalf("setting %s not found", key)
+	return Setting{}
+}
+
+func TestResolve_EnvOverridesFile(t *testing.T) {
+	setupConfigHome(t, "db_path: /from/file.db\npager: less\n")
+
+	settings, err := Resolve()
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	db := findSetting(t, settings, "db_path")
+	if db.Value != "/from/file.db" || !strings.HasPrefix(db.Source, "config file") {
+		t.Fatalf("expected file value, got %+v", db)
+	}
+	if output := findSetting(t, settings, "output"); output.Value != "table" || output.Source != "default" {
+		t.Fatalf("expected default output, got %+v", output)
+	}
+
+	t.Setenv("WRKQ_DB_PATH", "/from/env.db")
+	settings, err = Resolve()
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	db = findSetting(t, settings, "db_path")
+	if db.Value != "/from/env.db" || db.Source != "environment variable WRKQ_DB_PATH" {
+		t.Fatalf("expected env value, got %+v", db)
+	}
+	if pager := findSetting(t, settings, "pager"); pager.Value != "less" {
+		t.Fatalf("expected file pager, got %+v", pager)
+	}
+}
+
+func TestResolve_EnvLocalSourceAndSecrets(t *testing.T) {
+	home := setupConfigHome(t, "")
+	if err := os.WriteFile(filepath.Join(home, ".env.local"), []byte("WRKQ_OUTPUT=json\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Load exports .env.local values; make sure they are removed afterwards
+	t.Setenv("WRKQ_OUTPUT", "")
+	os.Unsetenv("WRKQ_OUTPUT")
+	t.Setenv("WRKQD_TOKEN", "super-secret-token")
+
+	settings, err := Resolve()
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	output := findSetting(t, settings, "output")
+	if output.Value != "json" || !strings.HasPrefix(output.Source, "WRKQ_OUTPUT in ") {
+		t.Fatalf("expected .env.local source, got %+v", output)
+	}
+	token := findSetting(t, settings, "daemon_token")
+	if !token.Secret || Mask(token.Value) != "********oken" {
+		t.Fatalf("unexpected token setting: %+v (masked %q)", token, Mask(token.Value))
+	}
+}
+
+func TestSetFileValue(t *testing.T) {
+	home := setupConfigHome(t, "# my settings\ndb_path: /old.db # keep\npager: less\n")
+
+	path, err := SetFileValue("db_path", "/new.db")
+	if err != nil {
+		t.Fatalf("SetFileValue failed: %v", err)
+	}
+	if want := filepath.Join(home, ".config", "wrkq", "config.yaml"); path != want {
+		t.Fatalf("unexpected path %s", path)
+	}
+	if _, err := SetFileValue("webhook_strict_templates", "1"); err != nil {
+		t.Fatalf("SetFileValue failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	content := string(data)
+	for _, want := range []string{"# my settings", "db_path: /new.db # keep", "pager: less", "webhook_strict_templates: true"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected %q in config file:\n%s", want, content)
+		}
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.DBPath != "/new.db" || !cfg.WebhookStrictTemplates {
+		t.Fatalf("config not reloaded: %+v", cfg)
+	}
+
+	if _, err := SetFileValue("attachments_max_mb", "lots"); err == nil {
+		t.Error("expected non-integer value to fail")
+	}
+	if _, err := SetFileValue("daemon_token", "x"); err == nil {
+		t.Error("expected env-only key to fail")
+	}
+	if _, err := SetFileValue("nope", "x"); err == nil {
+		t.Error("expected unknown key to fail")
+	}
+}
+
+func TestSetFileValue_Profile(t *testing.T) {
+	setupConfigHome(t, "db_path: /default.db\nprofiles:\n  work:\n    pager: less\n")
+	Profile = "work"
+
+	path, err := SetFileValue("db_path", "/work.db")
+	if err != nil {
+		t.Fatalf("SetFileValue failed: %v", err)
+	}
+	Profile = "scratch"
+	if _, err := SetFileValue("output", "json"); err != nil {
+		t.Fatalf("SetFileValue failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	var file struct {
+		DBPath   string                       `yaml:"db_path"`
+		Profiles map[string]map[string]string `yaml:"profiles"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		t.Fatalf("invalid config file: %v\n%s", err, data)
+	}
+	if file.DBPath != "/default.db" || file.Profiles["work"]["db_path"] != "/work.db" ||
+		file.Profiles["work"]["pager"] != "less" || file.Profiles["scratch"]["output"] != "json" {
+		t.Fatalf("expected values written to their profiles, got:\n%s", data)
+	}
+
+	Profile = "work"
+	settings, err := Resolve()
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if db := findSetting(t, settings, "db_path"); db.Value != "/work.db" || !strings.HasPrefix(db.Source, "profile work in ") {
+		t.Fatalf("expected profile source, got %+v", db)
+	}
+	if output := findSetting(t, settings, "output"); output.Source != "default" {
+		t.Fatalf("expected another profile's value ignored, got %+v", output)
+	}
+}
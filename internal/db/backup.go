@@ -0,0 +1,22 @@
+package db
+
+import (
+	"fmt"
+	"os"
+)
+
+// BackupTo writes a consistent copy of the database to path using VACUUM INTO.
+// The copy is taken inside a single read transaction, so concurrent writers
+// are not blocked and the result needs no WAL/SHM files. path must not exist.
+func (db *DB) BackupTo(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("output file already exists: %s (remove it first or choose a different path)", path)
+	}
+
+	if _, err := db.Exec("VACUUM INTO ?", path); err != nil {
+		// Don't leave a partial copy behind
+		os.Remove(path)
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return nil
+}
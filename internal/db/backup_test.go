@@ -0,0 +1,99 @@
+package db
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackupToDuringWrites(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	database, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	// A second connection keeps writing while the backup runs
+	writer, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open writer: %v", err)
+	}
+	defer writer.Close()
+
+	var written atomic.Int64
+	stop := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				done <- nil
+				return
+			default:
+			}
+			if _, err := writer.Exec(
+				"INSERT INTO actors (uuid, slug, role) VALUES (?, ?, 'agent')",
+				fmt.Sprintf("writer-uuid-%d", i), fmt.Sprintf("writer-%d", i),
+			); err != nil {
+				done <- err
+				return
+			}
+			written.Add(1)
+		}
+	}()
+
+	for written.Load() < 20 {
+		select {
+		case err := <-done:
+			t.Fatalf("writer stopped early: %v", err)
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	backupPath := filepath.Join(tmpDir, "backup.db")
+	backupErr := database.BackupTo(backupPath)
+
+	close(stop)
+	if err := <-done; err != nil {
+		t.Fatalf("concurrent write failed: %v", err)
+	}
+	if backupErr != nil {
+		t.Fatalf("BackupTo failed: %v", backupErr)
+	}
+
+	backup, err := Open(backupPath)
+	if err != nil {
+		t.Fatalf("failed to open backup: %v", err)
+	}
+	defer backup.Close()
+
+	var integrity string
+	if err := backup.QueryRow("PRAGMA integrity_check").Scan(&integrity); err != nil {
+		t.Fatalf("integrity_check failed: %v", err)
+	}
+	if integrity != "ok" {
+		t.Fatalf("expected integrity_check ok, got %q", integrity)
+	}
+
+	var copied int
+	if err := backup.QueryRow("SELECT COUNT(*) FROM actors WHERE slug LIKE 'writer-%'").Scan(&copied); err != nil {
+		t.Fatalf("failed to count actors: %v", err)
+	}
+	if copied < 20 {
+		t.Errorf("expected at least 20 actors in backup, got %d", copied)
+	}
+
+	// An existing output file is never overwritten
+	if err := database.BackupTo(backupPath); err == nil {
+		t.Error("expected BackupTo to refuse an existing file")
+	}
+}
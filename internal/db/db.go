@@ -12,7 +12,7 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
-//go:embed migrations/*.sql
+//go:embed migrations/*.sql migrations/down/*.sql
 var migrationsFS embed.FS
 
 // DB wraps a SQLite database connection
@@ -115,6 +115,10 @@ func (db *DB) Migrate() error {
 			tx.Rollback()
 			return fmt.Errorf("failed to execute migration %s: %w", migration, err)
 		}
+		if err := bumpSchemaVersion(tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to execute migration %s: %w", migration, err)
+		}
 
 		// Record migration as applied
 		_, err = tx.Exec("INSERT INTO schema_migrations (version) VALUES (?)", migration)
@@ -131,6 +135,21 @@ func (db *DB) Migrate() error {
 	return nil
 }
 
+// bumpSchemaVersion increments the schema cookie inside a migration's
+// transaction. Migrations that edit sqlite_master through writable_schema
+// (000023_task_kinds.sql) don't change it themselves, and other connections
+// only reload their cached schema when it changes.
+func bumpSchemaVersion(tx *sql.Tx) error {
+	var version int64
+	if err := tx.QueryRow("PRAGMA schema_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema_version: %w", err)
+	}
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA schema_version = %d", version+1)); err != nil {
+		return fmt.Errorf("failed to bump schema_version: %w", err)
+	}
+	return nil
+}
+
 // BeginTx starts a new transaction
 func (db *DB) BeginTx() (*sql.Tx, error) {
 	return db.Begin()
@@ -197,6 +216,10 @@ func (db *DB) MigrateWithInfo() ([]string, error) {
 			tx.Rollback()
 			return applied, fmt.Errorf("failed to execute migration %s: %w", migration, err)
 		}
+		if err := bumpSchemaVersion(tx); err != nil {
+			tx.Rollback()
+			return applied, fmt.Errorf("failed to execute migration %s: %w", migration, err)
+		}
 
 		// Record migration as applied
 		_, err = tx.Exec("INSERT INTO schema_migrations (version) VALUES (?)", migration)
@@ -0,0 +1,91 @@
+-- Migration: Full-text search over task titles, descriptions, and comments
+-- task_search is an FTS4 index with one document per task (title + description)
+-- and one per live comment (body). search_docs maps each document to its
+-- entity and parent task so triggers can address documents by UUID.
+--
+-- FTS4 is used rather than FTS5 because go-sqlite3 only compiles FTS5 in with
+-- the sqlite_fts5 build tag; FTS4 is always available and provides MATCH,
+-- snippet() and matchinfo() for ranking.
+--
+-- If the tasks or comments tables are ever rebuilt, recreate these triggers.
+
+CREATE TABLE search_docs (
+  docid INTEGER PRIMARY KEY,
+  entity_uuid TEXT NOT NULL UNIQUE,
+  task_uuid TEXT NOT NULL
+);
+
+CREATE INDEX search_docs_task_uuid_idx ON search_docs(task_uuid);
+
+CREATE VIRTUAL TABLE task_search USING fts4(title, body, tokenize=porter);
+
+-- Build the index for existing rows
+INSERT INTO search_docs (entity_uuid, task_uuid)
+SELECT uuid, uuid FROM tasks;
+
+INSERT INTO search_docs (entity_uuid, task_uuid)
+SELECT uuid, task_uuid FROM comments WHERE deleted_at IS NULL;
+
+INSERT INTO task_search (docid, title, body)
+SELECT d.docid, t.title, t.description
+  FROM search_docs d JOIN tasks t ON t.uuid = d.entity_uuid;
+
+INSERT INTO task_search (docid, title, body)
+SELECT d.docid, '', c.body
+  FROM search_docs d JOIN comments c ON c.uuid = d.entity_uuid;
+
+-- Tasks
+CREATE TRIGGER tasks_ai_search
+AFTER INSERT ON tasks
+BEGIN
+  INSERT INTO search_docs (entity_uuid, task_uuid) VALUES (NEW.uuid, NEW.uuid);
+  INSERT INTO task_search (docid, title, body)
+  VALUES ((SELECT docid FROM search_docs WHERE entity_uuid = NEW.uuid), NEW.title, NEW.description);
+END;
+
+CREATE TRIGGER tasks_au_search
+AFTER UPDATE OF title, description ON tasks
+BEGIN
+  UPDATE task_search SET title = NEW.title, body = NEW.description
+   WHERE docid = (SELECT docid FROM search_docs WHERE entity_uuid = NEW.uuid);
+END;
+
+CREATE TRIGGER tasks_ad_search
+AFTER DELETE ON tasks
+BEGIN
+  DELETE FROM task_search
+   WHERE docid = (SELECT docid FROM search_docs WHERE entity_uuid = OLD.uuid);
+  DELETE FROM search_docs WHERE entity_uuid = OLD.uuid;
+END;
+
+-- Comments (soft-deleted comments are dropped from the index)
+CREATE TRIGGER comments_ai_search
+AFTER INSERT ON comments
+WHEN NEW.deleted_at IS NULL
+BEGIN
+  INSERT INTO search_docs (entity_uuid, task_uuid) VALUES (NEW.uuid, NEW.task_uuid);
+  INSERT INTO task_search (docid, title, body)
+  VALUES ((SELECT docid FROM search_docs WHERE entity_uuid = NEW.uuid), '', NEW.body);
+END;
+
+CREATE TRIGGER comments_au_search
+AFTER UPDATE OF body, deleted_at, task_uuid ON comments
+BEGIN
+  DELETE FROM task_search
+   WHERE docid = (SELECT docid FROM search_docs WHERE entity_uuid = OLD.uuid);
+  DELETE FROM search_docs WHERE entity_uuid = OLD.uuid;
+
+  INSERT INTO search_docs (entity_uuid, task_uuid)
+  SELECT NEW.uuid, NEW.task_uuid WHERE NEW.deleted_at IS NULL;
+  INSERT INTO task_search (docid, title, body)
+  SELECT docid, '', NEW.body FROM search_docs
+   WHERE entity_uuid = NEW.uuid AND NEW.deleted_at IS NULL;
+END;
+
+CREATE TRIGGER comments_ad_search
+AFTER DELETE ON comments
+BEGIN
+  DELETE FROM task_search
+   WHERE docid = (SELECT docid FROM search_docs WHERE entity_uuid = OLD.uuid);
+  DELETE FROM search_docs WHERE entity_uuid = OLD.uuid;
+END;
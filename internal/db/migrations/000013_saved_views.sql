@@ -0,0 +1,30 @@
+-- Migration: Saved views (named task filters)
+-- A view stores a tasks/list filter as JSON. Selectors in the filter (project,
+-- assignee, parent task) are stored as given and resolved when the view runs,
+-- so renames and moves don't invalidate saved views.
+
+CREATE TABLE views (
+  uuid TEXT NOT NULL PRIMARY KEY
+        DEFAULT (
+          lower(
+            hex(randomblob(4)) || '-' ||
+            hex(randomblob(2)) || '-' ||
+            '4' || substr(hex(randomblob(2)),2) || '-' ||
+            substr('89ab', abs(random()) % 4 + 1, 1) || substr(hex(randomblob(2)),2) || '-' ||
+            hex(randomblob(6))
+          )
+        ),
+  owner_actor_uuid TEXT NOT NULL REFERENCES actors(uuid) ON DELETE CASCADE,
+  name TEXT NOT NULL CHECK (length(trim(name)) > 0 AND length(name) <= 255),
+  filter TEXT NOT NULL DEFAULT '{}' CHECK (json_valid(filter)),
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ','now')),
+  updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ','now')),
+  UNIQUE(owner_actor_uuid, name)
+);
+
+CREATE TRIGGER views_au_touch
+AFTER UPDATE ON views
+BEGIN
+  UPDATE views SET updated_at = strftime('%Y-%m-%dT%H:%M:%SZ','now')
+   WHERE rowid = NEW.rowid;
+END;
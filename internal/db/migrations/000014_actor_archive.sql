@@ -0,0 +1,6 @@
+-- Migration: Actor lifecycle
+-- Adds archived_at so actors can be deactivated without deleting them.
+-- Archived actors still resolve (tasks, comments and events keep pointing at
+-- them) but are hidden from default actor listings.
+
+ALTER TABLE actors ADD COLUMN archived_at TEXT;
@@ -0,0 +1,18 @@
+-- Migration: Idempotency keys for daemon write endpoints
+-- Stores the response of a keyed write so a retried request (same actor, same
+-- Idempotency-Key) is answered from here instead of being executed again.
+-- Rows older than the daemon's TTL are ignored and pruned lazily.
+
+CREATE TABLE idempotency_keys (
+  actor_uuid TEXT NOT NULL REFERENCES actors(uuid) ON DELETE CASCADE,
+  key TEXT NOT NULL CHECK (length(key) BETWEEN 1 AND 255),
+  endpoint TEXT NOT NULL,
+  request_hash TEXT NOT NULL,
+  resource_uuid TEXT,
+  status INTEGER NOT NULL,
+  response TEXT NOT NULL,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ','now')),
+  PRIMARY KEY (actor_uuid, key)
+);
+
+CREATE INDEX idempotency_keys_created_at_idx ON idempotency_keys(created_at);
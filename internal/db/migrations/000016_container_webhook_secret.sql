@@ -0,0 +1,5 @@
+-- Migration: Webhook signing secrets
+-- When set, webhooks declared on this container (or a descendant without its
+-- own secret) are signed with HMAC-SHA256. See docs/CLI-REFERENCE.md.
+
+ALTER TABLE containers ADD COLUMN webhook_secret TEXT;
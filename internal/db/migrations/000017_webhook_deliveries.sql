@@ -0,0 +1,29 @@
+-- Migration: Webhook delivery tracking
+-- A row is written once a delivery attempt fails. It stays 'pending' (with
+-- next_retry) while retries are in progress, becomes 'delivered' if a retry
+-- succeeds, and 'dead' once retries are exhausted or the receiver rejects the
+-- request outright. Dead (and stale pending) rows can be redriven with
+-- wrkqadm webhooks redrive. The signing secret is never stored; it is looked
+-- up again at redrive time.
+
+CREATE TABLE webhook_deliveries (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  task_uuid TEXT NOT NULL,
+  url TEXT NOT NULL,
+  payload TEXT NOT NULL,
+  status TEXT NOT NULL CHECK (status IN ('pending', 'delivered', 'dead')),
+  attempts INTEGER NOT NULL DEFAULT 0,
+  last_error TEXT,
+  next_retry TEXT,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ','now')),
+  updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ','now'))
+);
+
+CREATE INDEX webhook_deliveries_status_idx ON webhook_deliveries(status, id);
+
+CREATE TRIGGER webhook_deliveries_au_touch
+AFTER UPDATE ON webhook_deliveries
+BEGIN
+  UPDATE webhook_deliveries SET updated_at = strftime('%Y-%m-%dT%H:%M:%SZ','now')
+   WHERE rowid = NEW.rowid;
+END;
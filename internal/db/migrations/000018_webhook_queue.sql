@@ -0,0 +1,12 @@
+-- Migration: Queue every webhook delivery
+-- Writes now insert a 'pending' webhook_deliveries row per target in the same
+-- transaction as the change, and a dispatcher drains the queue. container_uuid
+-- records the task's container at enqueue time so the signing secret can be
+-- looked up even after the task is purged. claimed_until is a lease taken by
+-- the process sending a delivery; an expired lease (e.g. after a crash) makes
+-- the row eligible again.
+
+ALTER TABLE webhook_deliveries ADD COLUMN container_uuid TEXT;
+ALTER TABLE webhook_deliveries ADD COLUMN claimed_until TEXT;
+
+CREATE INDEX webhook_deliveries_due_idx ON webhook_deliveries(status, next_retry);
@@ -0,0 +1,30 @@
+-- Migration: Task templates
+-- A template stores the create fields for a recurring task shape (title,
+-- description, labels, kind, priority, ...) as a JSON object. Templates are
+-- shared by everyone using the database; title and description may contain
+-- {{var}} placeholders filled in when a task is created from the template.
+
+CREATE TABLE task_templates (
+  uuid TEXT NOT NULL PRIMARY KEY
+        DEFAULT (
+          lower(
+            hex(randomblob(4)) || '-' ||
+            hex(randomblob(2)) || '-' ||
+            '4' || substr(hex(randomblob(2)),2) || '-' ||
+            substr('89ab', abs(random()) % 4 + 1, 1) || substr(hex(randomblob(2)),2) || '-' ||
+            hex(randomblob(6))
+          )
+        ),
+  name TEXT NOT NULL UNIQUE CHECK (length(trim(name)) > 0 AND length(name) <= 255),
+  fields TEXT NOT NULL DEFAULT '{}' CHECK (json_valid(fields) AND json_type(fields) = 'object'),
+  created_by_actor_uuid TEXT REFERENCES actors(uuid) ON DELETE SET NULL,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ','now')),
+  updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ','now'))
+);
+
+CREATE TRIGGER task_templates_au_touch
+AFTER UPDATE ON task_templates
+BEGIN
+  UPDATE task_templates SET updated_at = strftime('%Y-%m-%dT%H:%M:%SZ','now')
+   WHERE rowid = NEW.rowid;
+END;
@@ -0,0 +1,25 @@
+-- Migration: Recurring tasks
+-- A recurrence turns a task into a template that is materialized as a new
+-- task every interval days or weeks (optionally on a fixed weekday, 0 =
+-- Sunday), starting at starts_at. last_generated_at is the most recent
+-- occurrence already materialized, so running generation twice in the same
+-- window creates nothing new. Occurrences link back to the template task with
+-- a relates_to relation.
+
+CREATE TABLE task_recurrences (
+  task_uuid TEXT NOT NULL PRIMARY KEY REFERENCES tasks(uuid) ON DELETE CASCADE,
+  interval INTEGER NOT NULL DEFAULT 1 CHECK (interval >= 1),
+  unit TEXT NOT NULL CHECK (unit IN ('day', 'week')),
+  weekday INTEGER CHECK (weekday IS NULL OR (unit = 'week' AND weekday BETWEEN 0 AND 6)),
+  starts_at TEXT NOT NULL,
+  last_generated_at TEXT,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ','now')),
+  updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ','now'))
+);
+
+CREATE TRIGGER task_recurrences_au_touch
+AFTER UPDATE ON task_recurrences
+BEGIN
+  UPDATE task_recurrences SET updated_at = strftime('%Y-%m-%dT%H:%M:%SZ','now')
+   WHERE rowid = NEW.rowid;
+END;
@@ -0,0 +1,9 @@
+-- Migration: Comment threads
+-- parent_comment_uuid makes a comment a reply to another comment on the same
+-- task. It is a plain column rather than a foreign key so snapshot and merge
+-- imports can insert comments in any order; callers validate the parent and
+-- purging a comment detaches its replies.
+
+ALTER TABLE comments ADD COLUMN parent_comment_uuid TEXT;
+
+CREATE INDEX idx_comments_parent ON comments(parent_comment_uuid);
@@ -0,0 +1,27 @@
+-- Migration: Reactions
+-- A reaction is a lightweight acknowledgement (an emoji or short name such
+-- as "+1") left by an actor on a task or comment. Each actor can leave a
+-- given reaction on a resource once. Reactions go away with their resource.
+
+CREATE TABLE reactions (
+  resource_type TEXT NOT NULL CHECK (resource_type IN ('task','comment')),
+  resource_uuid TEXT NOT NULL,
+  actor_uuid    TEXT NOT NULL REFERENCES actors(uuid) ON DELETE CASCADE,
+  emoji         TEXT NOT NULL CHECK (length(emoji) > 0 AND length(emoji) <= 32),
+  created_at    TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ','now')),
+  UNIQUE (resource_type, resource_uuid, actor_uuid, emoji)
+);
+
+CREATE INDEX reactions_resource_idx ON reactions(resource_type, resource_uuid);
+
+CREATE TRIGGER reactions_task_ad
+AFTER DELETE ON tasks
+BEGIN
+  DELETE FROM reactions WHERE resource_type = 'task' AND resource_uuid = OLD.uuid;
+END;
+
+CREATE TRIGGER reactions_comment_ad
+AFTER DELETE ON comments
+BEGIN
+  DELETE FROM reactions WHERE resource_type = 'comment' AND resource_uuid = OLD.uuid;
+END;
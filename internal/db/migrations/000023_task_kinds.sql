@@ -0,0 +1,49 @@
+-- Migration: Task kind vocabulary
+-- Task kinds move from a CHECK constraint on tasks.kind to the task_kinds
+-- table, so a database can add its own (wrkqadm kinds add). An empty kind
+-- is still accepted for rows written before kinds were enforced.
+
+CREATE TABLE task_kinds (
+  kind       TEXT NOT NULL PRIMARY KEY
+             CHECK (kind = lower(kind) AND kind GLOB '[a-z][a-z0-9_-]*' AND length(kind) <= 32),
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ','now'))
+);
+
+INSERT INTO task_kinds (kind) VALUES
+  ('task'), ('subtask'), ('spike'), ('bug'), ('chore'), ('feature'), ('epic');
+
+-- Rebuilding tasks to drop the CHECK would cascade-delete its children
+-- inside the migration transaction. Removing a CHECK doesn't change how
+-- rows are stored, so edit the table definition in place instead; the
+-- migration runner bumps schema_version in the same transaction so other
+-- connections reload the edited schema.
+PRAGMA writable_schema = ON;
+UPDATE sqlite_master
+SET sql = replace(sql, ' CHECK (kind IN (''task'',''subtask'',''spike'',''bug'',''chore''))', '')
+WHERE type = 'table' AND name = 'tasks';
+PRAGMA writable_schema = RESET;
+
+-- replace() only matches the exact text the earlier migrations wrote. If a
+-- database's definition differs (say, in spacing) the CHECK would silently
+-- survive, so fail the migration instead: the guard row is 0 while it's there.
+CREATE TEMP TABLE migration_guard (
+  ok INTEGER CONSTRAINT tasks_kind_check_not_removed CHECK (ok)
+);
+INSERT INTO migration_guard (ok)
+SELECT instr(replace(sql, ' ', ''), 'CHECK(kindIN(') = 0 FROM sqlite_master
+WHERE type = 'table' AND name = 'tasks';
+DROP TABLE migration_guard;
+
+CREATE TRIGGER tasks_kind_check_insert
+BEFORE INSERT ON tasks
+WHEN NEW.kind != '' AND NOT EXISTS (SELECT 1 FROM task_kinds WHERE kind = NEW.kind)
+BEGIN
+  SELECT RAISE(ABORT, 'Invalid task kind: not in task_kinds');
+END;
+
+CREATE TRIGGER tasks_kind_check_update
+BEFORE UPDATE OF kind ON tasks
+WHEN NEW.kind != '' AND NOT EXISTS (SELECT 1 FROM task_kinds WHERE kind = NEW.kind)
+BEGIN
+  SELECT RAISE(ABORT, 'Invalid task kind: not in task_kinds');
+END;
@@ -0,0 +1,8 @@
+-- Migration: Subtask progress rollup
+-- Containers can opt in (subtask_rollup = 1) to keeping a summary of each
+-- parent task's subtasks in tasks.subtask_progress, as JSON
+-- {"done": n, "total": m}. It is refreshed when a subtask changes state,
+-- which bumps the parent's etag, so it is off by default.
+
+ALTER TABLE containers ADD COLUMN subtask_rollup INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tasks ADD COLUMN subtask_progress TEXT;
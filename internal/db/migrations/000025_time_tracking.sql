@@ -0,0 +1,19 @@
+-- Migration: Time tracking
+-- Tasks get an optional effort estimate in minutes, and time_entries records
+-- the time actors log against a task. The logged total for a task is the sum
+-- of its entries. Entries go away with their task.
+
+ALTER TABLE tasks ADD COLUMN estimate_minutes INTEGER
+  CHECK (estimate_minutes IS NULL OR estimate_minutes >= 0);
+
+CREATE TABLE time_entries (
+  uuid       TEXT NOT NULL PRIMARY KEY,
+  task_uuid  TEXT NOT NULL REFERENCES tasks(uuid) ON DELETE CASCADE,
+  actor_uuid TEXT NOT NULL REFERENCES actors(uuid) ON DELETE RESTRICT,
+  minutes    INTEGER NOT NULL CHECK (minutes > 0),
+  note       TEXT NOT NULL DEFAULT '',
+  started_at TEXT,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ','now'))
+);
+
+CREATE INDEX time_entries_task_idx ON time_entries(task_uuid);
@@ -0,0 +1,16 @@
+-- Migration: Scoped API tokens
+-- Each token acts as one actor with a scope (read, write or admin) and may be
+-- restricted to a container's subtree. Only a SHA-256 hash of the token is
+-- stored. While the table is empty the daemon keeps its single shared token,
+-- so a token's project can't be deleted out from under it: removing the
+-- project must not silently drop the daemon back to that mode.
+
+CREATE TABLE api_tokens (
+  uuid         TEXT NOT NULL PRIMARY KEY,
+  name         TEXT NOT NULL UNIQUE CHECK (length(name) > 0),
+  token_hash   TEXT NOT NULL UNIQUE,
+  actor_uuid   TEXT NOT NULL REFERENCES actors(uuid) ON DELETE CASCADE,
+  scope        TEXT NOT NULL CHECK (scope IN ('read', 'write', 'admin')),
+  project_uuid TEXT REFERENCES containers(uuid) ON DELETE RESTRICT,
+  created_at   TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ','now'))
+);
@@ -0,0 +1,4 @@
+-- Down: Saved views
+
+DROP TRIGGER IF EXISTS views_au_touch;
+DROP TABLE IF EXISTS views;
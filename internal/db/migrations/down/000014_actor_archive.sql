@@ -0,0 +1,3 @@
+-- Down: Actor lifecycle
+
+ALTER TABLE actors DROP COLUMN archived_at;
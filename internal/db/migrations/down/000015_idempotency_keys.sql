@@ -0,0 +1,4 @@
+-- Down: Idempotency keys for daemon write endpoints
+
+DROP INDEX IF EXISTS idempotency_keys_created_at_idx;
+DROP TABLE IF EXISTS idempotency_keys;
@@ -0,0 +1,3 @@
+-- Down: Webhook signing secrets
+
+ALTER TABLE containers DROP COLUMN webhook_secret;
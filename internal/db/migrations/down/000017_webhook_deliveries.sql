@@ -0,0 +1,5 @@
+-- Down: Webhook delivery tracking
+
+DROP TRIGGER IF EXISTS webhook_deliveries_au_touch;
+DROP INDEX IF EXISTS webhook_deliveries_status_idx;
+DROP TABLE IF EXISTS webhook_deliveries;
@@ -0,0 +1,5 @@
+-- Down: Queue every webhook delivery
+
+DROP INDEX IF EXISTS webhook_deliveries_due_idx;
+ALTER TABLE webhook_deliveries DROP COLUMN claimed_until;
+ALTER TABLE webhook_deliveries DROP COLUMN container_uuid;
@@ -0,0 +1,4 @@
+-- Down: Task templates
+
+DROP TRIGGER IF EXISTS task_templates_au_touch;
+DROP TABLE IF EXISTS task_templates;
@@ -0,0 +1,4 @@
+-- Down: Recurring tasks
+
+DROP TRIGGER IF EXISTS task_recurrences_au_touch;
+DROP TABLE IF EXISTS task_recurrences;
@@ -0,0 +1,4 @@
+-- Down: Comment threads
+
+DROP INDEX IF EXISTS idx_comments_parent;
+ALTER TABLE comments DROP COLUMN parent_comment_uuid;
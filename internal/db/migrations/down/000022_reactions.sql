@@ -0,0 +1,5 @@
+-- Down: Reactions
+
+DROP TRIGGER IF EXISTS reactions_comment_ad;
+DROP TRIGGER IF EXISTS reactions_task_ad;
+DROP TABLE IF EXISTS reactions;
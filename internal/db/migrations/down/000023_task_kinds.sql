@@ -0,0 +1,25 @@
+-- Down: Task kind vocabulary
+-- Restores the CHECK on tasks.kind. Tasks already using a kind outside the
+-- original five should be changed first, or integrity_check will flag them.
+-- As on the way up, the runner bumps schema_version for the in-place edit.
+
+DROP TRIGGER IF EXISTS tasks_kind_check_update;
+DROP TRIGGER IF EXISTS tasks_kind_check_insert;
+
+PRAGMA writable_schema = ON;
+UPDATE sqlite_master
+SET sql = replace(sql, 'kind TEXT NOT NULL DEFAULT ''task''', 'kind TEXT NOT NULL DEFAULT ''task'' CHECK (kind IN (''task'',''subtask'',''spike'',''bug'',''chore''))')
+WHERE type = 'table' AND name = 'tasks';
+PRAGMA writable_schema = RESET;
+
+-- As on the way up, fail rather than report success if replace() didn't
+-- match and the CHECK wasn't restored.
+CREATE TEMP TABLE migration_guard (
+  ok INTEGER CONSTRAINT tasks_kind_check_not_restored CHECK (ok)
+);
+INSERT INTO migration_guard (ok)
+SELECT instr(replace(sql, ' ', ''), 'CHECK(kindIN(') > 0 FROM sqlite_master
+WHERE type = 'table' AND name = 'tasks';
+DROP TABLE migration_guard;
+
+DROP TABLE IF EXISTS task_kinds;
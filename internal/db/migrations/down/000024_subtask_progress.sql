@@ -0,0 +1,4 @@
+-- Down: Subtask progress rollup
+
+ALTER TABLE tasks DROP COLUMN subtask_progress;
+ALTER TABLE containers DROP COLUMN subtask_rollup;
@@ -0,0 +1,5 @@
+-- Down: Time tracking
+
+DROP INDEX IF EXISTS time_entries_task_idx;
+DROP TABLE IF EXISTS time_entries;
+ALTER TABLE tasks DROP COLUMN estimate_minutes;
@@ -0,0 +1,3 @@
+-- Down: Scoped API tokens
+
+DROP TABLE IF EXISTS api_tokens;
@@ -0,0 +1,142 @@
+package db
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// MigrationPlan describes what Migrate would do, without applying anything.
+type MigrationPlan struct {
+	// CurrentVersion is the last applied migration, or "" for a new database
+	CurrentVersion string             `json:"current_version"`
+	Pending        []PlannedMigration `json:"pending"`
+}
+
+// PlannedMigration is a pending migration and the existing rows it touches.
+type PlannedMigration struct {
+	Version     string `json:"version"`
+	Description string `json:"description"`
+	// Tables lists tables the migration updates, deletes from, or copies
+	// out of, with their current row counts. Tables that don't exist yet
+	// (created by an earlier pending migration) are omitted.
+	Tables []TableEstimate `json:"tables,omitempty"`
+}
+
+// TableEstimate is the current row count of a table a migration touches.
+type TableEstimate struct {
+	Table string `json:"table"`
+	Rows  int64  `json:"rows"`
+}
+
+var (
+	sqlLineComment = regexp.MustCompile(`--[^\n]*`)
+	// Trigger bodies run on future writes, not during the migration
+	sqlTriggerBody = regexp.MustCompile(`(?is)\bCREATE\s+TRIGGER\b.*?\bEND\s*;`)
+	sqlStatements  = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\bUPDATE\s+(\w+)\s+SET\b`),
+		regexp.MustCompile(`(?i)\bDELETE\s+FROM\s+(\w+)`),
+		regexp.MustCompile(`(?is)\bINSERT\s+(?:OR\s+\w+\s+)?INTO\s+\w+\s*(?:\([^)]*\))?\s*SELECT\b[^;]*?\bFROM\s+(\w+)`),
+	}
+)
+
+// MigrationPlan reports the current version and every pending migration with
+// its description (the "-- Migration:" header) and an estimate of affected
+// rows, derived from the tables its data statements read or modify.
+func (db *DB) MigrationPlan() (*MigrationPlan, error) {
+	applied, pending, err := db.MigrationStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &MigrationPlan{Pending: []PlannedMigration{}}
+	if len(applied) > 0 {
+		plan.CurrentVersion = applied[len(applied)-1]
+	}
+
+	for _, migration := range pending {
+		content, err := migrationsFS.ReadFile(path.Join("migrations", migration))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", migration, err)
+		}
+
+		planned := PlannedMigration{
+			Version:     migration,
+			Description: migrationDescription(string(content)),
+		}
+		for _, table := range touchedTables(string(content)) {
+			var exists int
+			if err := db.QueryRow(
+				"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table,
+			).Scan(&exists); err != nil {
+				return nil, fmt.Errorf("failed to check table %s: %w", table, err)
+			}
+			if exists == 0 {
+				continue
+			}
+			var rows int64
+			if err := db.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM "%s"`, table)).Scan(&rows); err != nil {
+				return nil, fmt.Errorf("failed to count rows in %s: %w", table, err)
+			}
+			planned.Tables = append(planned.Tables, TableEstimate{Table: table, Rows: rows})
+		}
+		plan.Pending = append(plan.Pending, planned)
+	}
+
+	return plan, nil
+}
+
+// migrationDescription returns the text of the "-- Migration:" header, or the
+// first comment line if there is none.
+func migrationDescription(content string) string {
+	first := ""
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "--") {
+			continue
+		}
+		text := strings.TrimSpace(strings.TrimPrefix(line, "--"))
+		if rest, ok := strings.CutPrefix(text, "Migration:"); ok {
+			return strings.TrimSpace(rest)
+		}
+		if first == "" {
+			first = text
+		}
+	}
+	return first
+}
+
+// touchedTables lists, in order of first appearance, the tables whose
+// existing rows a migration updates, deletes, or copies.
+func touchedTables(content string) []string {
+	content = sqlLineComment.ReplaceAllString(content, "")
+	content = sqlTriggerBody.ReplaceAllString(content, "")
+
+	type hit struct {
+		pos   int
+		table string
+	}
+	var hits []hit
+	for _, re := range sqlStatements {
+		for _, m := range re.FindAllStringSubmatchIndex(content, -1) {
+			hits = append(hits, hit{pos: m[0], table: content[m[2]:m[3]]})
+		}
+	}
+
+	// Order by position so the list reads like the migration
+	sort.Slice(hits, func(i, j int) bool { return hits[i].pos < hits[j].pos })
+
+	seen := make(map[string]bool)
+	var tables []string
+	for _, h := range hits {
+		name := strings.ToLower(h.table)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		tables = append(tables, h.table)
+	}
+	return tables
+}
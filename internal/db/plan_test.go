@@ -0,0 +1,76 @@
+package db
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMigrationPlan(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := Open(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	plan, err := database.MigrationPlan()
+	if err != nil {
+		t.Fatalf("MigrationPlan failed: %v", err)
+	}
+	if len(plan.Pending) != 0 {
+		t.Fatalf("expected nothing pending, got %+v", plan.Pending)
+	}
+
+	if _, err := database.RollbackTo("000016"); err != nil {
+		t.Fatalf("RollbackTo failed: %v", err)
+	}
+
+	plan, err = database.MigrationPlan()
+	if err != nil {
+		t.Fatalf("MigrationPlan failed: %v", err)
+	}
+	if plan.CurrentVersion != "000016_container_webhook_secret.sql" {
+		t.Errorf("unexpected current version: %s", plan.CurrentVersion)
+	}
+	if len(plan.Pending) != 10 {
+		t.Fatalf("expected 10 pending migrations, got %+v", plan.Pending)
+	}
+	if got := plan.Pending[0]; got.Version != "000017_webhook_deliveries.sql" || got.Description != "Webhook delivery tracking" {
+		t.Errorf("unexpected first pending migration: %+v", got)
+	}
+
+	// Planning applies nothing
+	if _, pending, _ := database.MigrationStatus(); len(pending) != 10 {
+		t.Errorf("expected plan to leave 10 pending, got %v", pending)
+	}
+}
+
+func TestTouchedTables(t *testing.T) {
+	sql := `
+-- UPDATE ignored SET x = 1
+CREATE TABLE tasks_new (uuid TEXT);
+INSERT INTO tasks_new (uuid)
+SELECT uuid FROM tasks;
+UPDATE containers SET kind = 'project' WHERE kind IS NULL;
+CREATE TRIGGER t_au AFTER UPDATE ON comments
+BEGIN
+  UPDATE comments SET updated_at = 'now' WHERE rowid = NEW.rowid;
+END;
+DELETE FROM event_log WHERE id < 0;
+UPDATE tasks SET state = 'open' WHERE state = 'todo';
+`
+	got := touchedTables(sql)
+	want := []string{"tasks", "containers", "event_log"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("touchedTables = %v, want %v", got, want)
+	}
+
+	if got := migrationDescription("-- Migration: Saved views\n-- more\nCREATE TABLE x (a);"); got != "Saved views" {
+		t.Errorf("unexpected description: %q", got)
+	}
+}
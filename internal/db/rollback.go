@@ -0,0 +1,91 @@
+package db
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// HasDownMigration reports whether migration (e.g. 000018_webhook_queue.sql)
+// ships a down script in migrations/down.
+func HasDownMigration(migration string) bool {
+	_, err := fs.Stat(migrationsFS, path.Join("migrations", "down", migration))
+	return err == nil
+}
+
+// RollbackTo reverts every applied migration newer than version, newest
+// first, by running its down script from migrations/down and removing it from
+// schema_migrations. version is an applied migration name or its numeric
+// prefix (e.g. "000016"); an empty version reverts all applied migrations.
+// Nothing is reverted unless every migration in range has a down script.
+// It returns the migrations that were rolled back, in the order they ran.
+func (db *DB) RollbackTo(version string) ([]string, error) {
+	applied, _, err := db.MigrationStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	target := -1
+	if version != "" {
+		for i, m := range applied {
+			if m == version || strings.HasPrefix(m, version+"_") {
+				target = i
+				break
+			}
+		}
+		if target < 0 {
+			return nil, fmt.Errorf("migration %s is not applied", version)
+		}
+	}
+
+	var toRevert []string
+	for i := len(applied) - 1; i > target; i-- {
+		toRevert = append(toRevert, applied[i])
+	}
+
+	var missing []string
+	for _, m := range toRevert {
+		if !HasDownMigration(m) {
+			missing = append(missing, m)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("no down script for migration(s): %s", strings.Join(missing, ", "))
+	}
+
+	var reverted []string
+	for _, migration := range toRevert {
+		content, err := migrationsFS.ReadFile(path.Join("migrations", "down", migration))
+		if err != nil {
+			return reverted, fmt.Errorf("failed to read down migration %s: %w", migration, err)
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return reverted, fmt.Errorf("failed to begin transaction for %s: %w", migration, err)
+		}
+
+		if _, err := tx.Exec(string(content)); err != nil {
+			tx.Rollback()
+			return reverted, fmt.Errorf("failed to execute down migration %s: %w", migration, err)
+		}
+		if err := bumpSchemaVersion(tx); err != nil {
+			tx.Rollback()
+			return reverted, fmt.Errorf("failed to execute down migration %s: %w", migration, err)
+		}
+
+		if _, err := tx.Exec("DELETE FROM schema_migrations WHERE version = ?", migration); err != nil {
+			tx.Rollback()
+			return reverted, fmt.Errorf("failed to unrecord migration %s: %w", migration, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return reverted, fmt.Errorf("failed to commit down migration %s: %w", migration, err)
+		}
+
+		reverted = append(reverted, migration)
+	}
+
+	return reverted, nil
+}
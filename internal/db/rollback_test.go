@@ -0,0 +1,194 @@
+package db
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRollbackToAndMigrateBack(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := Open(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	before := schemaSQL(t, database)
+	applied, _, err := database.MigrationStatus()
+	if err != nil {
+		t.Fatalf("MigrationStatus failed: %v", err)
+	}
+
+	reverted, err := database.RollbackTo("000012")
+	if err != nil {
+		t.Fatalf("RollbackTo failed: %v", err)
+	}
+	if len(reverted) != len(applied)-12 || reverted[0] != applied[len(applied)-1] {
+		t.Fatalf("expected newest-first rollback of %d migrations, got %v", len(applied)-12, reverted)
+	}
+
+	nowApplied, pending, err := database.MigrationStatus()
+	if err != nil {
+		t.Fatalf("MigrationStatus failed: %v", err)
+	}
+	if got := nowApplied[len(nowApplied)-1]; got != "000012_task_search.sql" {
+		t.Errorf("expected version 000012_task_search.sql, got %s", got)
+	}
+	if len(pending) != len(reverted) {
+		t.Errorf("expected %d pending migrations, got %v", len(reverted), pending)
+	}
+
+	for _, table := range []string{"views", "idempotency_keys", "webhook_deliveries"} {
+		var count int
+		database.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&count)
+		if count != 0 {
+			t.Errorf("expected table %s to be dropped", table)
+		}
+	}
+	var archivedCol int
+	database.QueryRow("SELECT COUNT(*) FROM pragma_table_info('actors') WHERE name = 'archived_at'").Scan(&archivedCol)
+	if archivedCol != 0 {
+		t.Error("expected actors.archived_at to be dropped")
+	}
+
+	// Migrating forward again restores the original schema
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to re-migrate database: %v", err)
+	}
+	if after := schemaSQL(t, database); after != before {
+		t.Errorf("schema differs after rollback and re-migrate:\nbefore:\n%s\nafter:\n%s", before, after)
+	}
+}
+
+func TestRollbackToRequiresDownScripts(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := Open(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	// The baseline has no down script, so nothing is reverted
+	_, err = database.RollbackTo("")
+	if err == nil || !strings.Contains(err.Error(), "000001_baseline.sql") {
+		t.Fatalf("expected missing down script error, got %v", err)
+	}
+	if _, pending, _ := database.MigrationStatus(); len(pending) != 0 {
+		t.Errorf("expected no migrations reverted, got pending %v", pending)
+	}
+
+	if _, err := database.RollbackTo("999999"); err == nil {
+		t.Error("expected unknown version to fail")
+	}
+}
+
+func TestBumpSchemaVersion(t *testing.T) {
+	database, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	var before, after int64
+	if err := database.QueryRow("PRAGMA schema_version").Scan(&before); err != nil {
+		t.Fatalf("failed to read schema_version: %v", err)
+	}
+	tx, err := database.Begin()
+	if err != nil {
+		t.Fatalf("failed to begin: %v", err)
+	}
+	if err := bumpSchemaVersion(tx); err != nil {
+		tx.Rollback()
+		t.Fatalf("bumpSchemaVersion failed: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	if err := database.QueryRow("PRAGMA schema_version").Scan(&after); err != nil {
+		t.Fatalf("failed to read schema_version: %v", err)
+	}
+	if after != before+1 {
+		t.Errorf("expected schema_version %d, got %d", before+1, after)
+	}
+}
+
+func TestTaskKindsMigrationRefusesUnmatchedCheck(t *testing.T) {
+	database, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	if _, err := database.RollbackTo("000022"); err != nil {
+		t.Fatalf("RollbackTo failed: %v", err)
+	}
+
+	// Respace the CHECK so the migration's replace() no longer matches it
+	tx, err := database.Begin()
+	if err != nil {
+		t.Fatalf("failed to begin: %v", err)
+	}
+	if _, err := tx.Exec(`
+		PRAGMA writable_schema = ON;
+		UPDATE sqlite_master SET sql = replace(sql, '''task'',''subtask''', '''task'', ''subtask''')
+		WHERE type = 'table' AND name = 'tasks';
+		PRAGMA writable_schema = RESET;
+	`); err != nil {
+		tx.Rollback()
+		t.Fatalf("failed to edit tasks: %v", err)
+	}
+	if err := bumpSchemaVersion(tx); err != nil {
+		tx.Rollback()
+		t.Fatalf("bumpSchemaVersion failed: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	err = database.Migrate()
+	if err == nil || !strings.Contains(err.Error(), "tasks_kind_check_not_removed") {
+		t.Fatalf("expected the migration to fail on the surviving CHECK, got %v", err)
+	}
+	if _, pending, _ := database.MigrationStatus(); len(pending) == 0 || pending[0] != "000023_task_kinds.sql" {
+		t.Errorf("expected 000023 left pending, got %v", pending)
+	}
+	var tables int
+	database.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'task_kinds'").Scan(&tables)
+	if tables != 0 {
+		t.Error("expected the failed migration rolled back")
+	}
+}
+
+func schemaSQL(t *testing.T, database *DB) string {
+	t.Helper()
+	rows, err := database.Query(`
+		SELECT type, name, COALESCE(sql, '') FROM sqlite_master
+		WHERE name NOT LIKE 'sqlite_%'
+		ORDER BY type, name
+	`)
+	if err != nil {
+		t.Fatalf("failed to read schema: %v", err)
+	}
+	defer rows.Close()
+
+	var b strings.Builder
+	for rows.Next() {
+		var typ, name, sql string
+		if err := rows.Scan(&typ, &name, &sql); err != nil {
+			t.Fatalf("failed to scan schema: %v", err)
+		}
+		b.WriteString(typ + " " + name + ": " + sql + "\n")
+	}
+	return b.String()
+}
@@ -134,3 +134,49 @@ func setSequence(exec sqlExecutor, seqTable string, value int) error {
 	_, err = exec.Exec("INSERT INTO sqlite_sequence (name, seq) VALUES (?, ?)", seqTable, value)
 	return err
 }
+
+// CommentSequenceDrift reports drift between comment_sequences.next_comment
+// and the max existing comment ID (C-NNNNN), or nil if the sequence is caught
+// up. Comments use their own sequence table rather than sqlite_sequence.
+func CommentSequenceDrift(exec sqlExecutor) (*SequenceDrift, error) {
+	maxID, err := maxExistingID(exec, SequenceSpec{EntityTable: "comments", IDColumn: "id", Prefix: "C-"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute max ID for comments: %w", err)
+	}
+
+	var value int
+	err = exec.QueryRow("SELECT value FROM comment_sequences WHERE name = 'next_comment'").Scan(&value)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to read comment sequence: %w", err)
+	}
+
+	if value >= maxID {
+		return nil, nil
+	}
+	return &SequenceDrift{
+		SeqTable:    "comment_sequences",
+		EntityTable: "comments",
+		MaxID:       maxID,
+		SeqValue:    value,
+	}, nil
+}
+
+// FixCommentSequence advances comment_sequences.next_comment to the max
+// existing comment ID. Returns the drift that was repaired, or nil.
+func FixCommentSequence(exec sqlExecutor) (*SequenceDrift, error) {
+	drift, err := CommentSequenceDrift(exec)
+	if err != nil || drift == nil {
+		return nil, err
+	}
+
+	res, err := exec.Exec("UPDATE comment_sequences SET value = ? WHERE name = 'next_comment'", drift.MaxID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update comment sequence: %w", err)
+	}
+	if rows, err := res.RowsAffected(); err == nil && rows == 0 {
+		if _, err := exec.Exec("INSERT INTO comment_sequences (name, value) VALUES ('next_comment', ?)", drift.MaxID); err != nil {
+			return nil, fmt.Errorf("failed to update comment sequence: %w", err)
+		}
+	}
+	return drift, nil
+}
@@ -0,0 +1,51 @@
+package domain
+
+import "time"
+
+// Recurrence units
+const (
+	RecurrenceDay  = "day"
+	RecurrenceWeek = "week"
+)
+
+// Recurrence materializes a template task as a new task every Interval
+// days or weeks, starting at StartsAt
+type Recurrence struct {
+	TaskUUID        string     `json:"task_uuid" db:"task_uuid"`
+	Interval        int        `json:"interval" db:"interval"`
+	Unit            string     `json:"unit" db:"unit"`
+	Weekday         *int       `json:"weekday,omitempty" db:"weekday"` // weekly only, 0 = Sunday
+	StartsAt        time.Time  `json:"starts_at" db:"starts_at"`
+	LastGeneratedAt *time.Time `json:"last_generated_at,omitempty" db:"last_generated_at"`
+}
+
+// FirstOccurrence returns the first scheduled time: StartsAt, moved forward
+// to the next Weekday for weekly recurrences that fix one.
+func (r *Recurrence) FirstOccurrence() time.Time {
+	first := r.StartsAt
+	if r.Unit == RecurrenceWeek && r.Weekday != nil {
+		first = first.AddDate(0, 0, (*r.Weekday-int(first.Weekday())+7)%7)
+	}
+	return first
+}
+
+// DueOccurrences returns, oldest first and at most limit, the scheduled
+// times at or before now that come after LastGeneratedAt.
+func (r *Recurrence) DueOccurrences(now time.Time, limit int) []time.Time {
+	days := r.Interval
+	if r.Unit == RecurrenceWeek {
+		days *= 7
+	}
+	if days < 1 {
+		return nil
+	}
+
+	var due []time.Time
+	for t := r.FirstOccurrence(); !t.After(now) && len(due) < limit; t = t.AddDate(0, 0, days) {
+		if r.LastGeneratedAt != nil && !t.After(*r.LastGeneratedAt) {
+			continue
+		}
+		due = append(due, t)
+	}
+	return due
+}
@@ -24,6 +24,8 @@ const (
 	TaskKindSpike   TaskKind = "spike"
 	TaskKindBug     TaskKind = "bug"
 	TaskKindChore   TaskKind = "chore"
+	TaskKindFeature TaskKind = "feature"
+	TaskKindEpic    TaskKind = "epic"
 )
 
 // TaskResolution represents the resolution of a completed task
@@ -56,16 +58,39 @@ const (
 	TaskRelationDuplicates TaskRelationKind = "duplicates"
 )
 
+// TokenScope is what an API token may do. Each scope includes the ones
+// before it: write tokens can read, admin tokens can also write.
+type TokenScope string
+
+const (
+	TokenScopeRead  TokenScope = "read"
+	TokenScopeWrite TokenScope = "write"
+	TokenScopeAdmin TokenScope = "admin"
+)
+
+// Allows reports whether a token with scope s may make a request that needs
+// scope need.
+func (s TokenScope) Allows(need TokenScope) bool {
+	return tokenScopeRank[s] >= tokenScopeRank[need] && tokenScopeRank[need] > 0
+}
+
+var tokenScopeRank = map[TokenScope]int{
+	TokenScopeRead:  1,
+	TokenScopeWrite: 2,
+	TokenScopeAdmin: 3,
+}
+
 // Actor represents an actor in the system
 type Actor struct {
-	UUID        string    `json:"uuid" db:"uuid"`
-	ID          string    `json:"id" db:"id"`
-	Slug        string    `json:"slug" db:"slug"`
-	DisplayName *string   `json:"display_name,omitempty" db:"display_name"`
-	Role        string    `json:"role" db:"role"`           // human, agent, system
-	Meta        *string   `json:"meta,omitempty" db:"meta"` // JSON
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	UUID        string     `json:"uuid" db:"uuid"`
+	ID          string     `json:"id" db:"id"`
+	Slug        string     `json:"slug" db:"slug"`
+	DisplayName *string    `json:"display_name,omitempty" db:"display_name"`
+	Role        string     `json:"role" db:"role"`           // human, agent, system
+	Meta        *string    `json:"meta,omitempty" db:"meta"` // JSON
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	ArchivedAt  *time.Time `json:"archived_at,omitempty" db:"archived_at"` // set when deactivated
 }
 
 // Container represents a project or subproject
@@ -98,7 +123,7 @@ type Task struct {
 	AssignedProjectID    *string    `json:"assigned_project_id,omitempty" db:"assigned_project_id"`
 	State                string     `json:"state" db:"state"`       // idea, draft, open, in_progress, completed, blocked, cancelled, archived, deleted
 	Priority             int        `json:"priority" db:"priority"` // 1-4, 1 is highest
-	Kind                 TaskKind   `json:"kind" db:"kind"`         // task, subtask, spike, bug, chore
+	Kind                 TaskKind   `json:"kind" db:"kind"`         // one of the task_kinds vocabulary
 	ParentTaskUUID       *string    `json:"parent_task_uuid,omitempty" db:"parent_task_uuid"`
 	AssigneeActorUUID    *string    `json:"assignee_actor_uuid,omitempty" db:"assignee_actor_uuid"`
 	AcknowledgedAt       *time.Time `json:"acknowledged_at,omitempty" db:"acknowledged_at"`
@@ -111,6 +136,7 @@ type Task struct {
 	RunStatus            *string    `json:"run_status,omitempty" db:"run_status"`
 	StartAt              *time.Time `json:"start_at,omitempty" db:"start_at"`
 	DueAt                *time.Time `json:"due_at,omitempty" db:"due_at"`
+	EstimateMinutes      *int       `json:"estimate_minutes,omitempty" db:"estimate_minutes"`
 	Labels               *string    `json:"labels,omitempty" db:"labels"` // JSON array
 	Meta                 *string    `json:"meta,omitempty" db:"meta"`     // JSON object
 	Description          string     `json:"description" db:"description"`
@@ -141,6 +167,26 @@ type Section struct {
 	ArchivedAt  *time.Time  `json:"archived_at,omitempty" db:"archived_at"`
 }
 
+// View is a saved, named task filter owned by an actor
+type View struct {
+	UUID           string          `json:"uuid" db:"uuid"`
+	OwnerActorUUID string          `json:"owner_actor_uuid" db:"owner_actor_uuid"`
+	Name           string          `json:"name" db:"name"`
+	Filter         json.RawMessage `json:"filter" db:"filter"` // JSON tasks/list request
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// TaskTemplate is a named, shared set of task create fields
+type TaskTemplate struct {
+	UUID               string          `json:"uuid" db:"uuid"`
+	Name               string          `json:"name" db:"name"`
+	Fields             json.RawMessage `json:"fields" db:"fields"` // JSON tasks/create fields
+	CreatedByActorUUID *string         `json:"created_by_actor_uuid,omitempty" db:"created_by_actor_uuid"`
+	CreatedAt          time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time       `json:"updated_at" db:"updated_at"`
+}
+
 // TaskRelation represents a dependency or relationship between tasks
 type TaskRelation struct {
 	FromTaskUUID       string           `json:"from_task_uuid" db:"from_task_uuid"`
@@ -164,6 +210,62 @@ type Comment struct {
 	UpdatedAt          *time.Time `json:"updated_at,omitempty" db:"updated_at"`                       // nullable; reserved for future editable comments
 	DeletedAt          *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`                       // nullable; soft delete timestamp
 	DeletedByActorUUID *string    `json:"deleted_by_actor_uuid,omitempty" db:"deleted_by_actor_uuid"` // nullable; actor who soft-deleted
+	ParentCommentUUID  *string    `json:"parent_comment_uuid,omitempty" db:"parent_comment_uuid"`     // nullable; comment this one replies to
+}
+
+// Reaction is a lightweight acknowledgement left by an actor on a task or
+// comment
+type Reaction struct {
+	ResourceType string    `json:"resource_type" db:"resource_type"` // task or comment
+	ResourceUUID string    `json:"resource_uuid" db:"resource_uuid"`
+	ActorUUID    string    `json:"actor_uuid" db:"actor_uuid"`
+	ActorSlug    string    `json:"actor_slug"`
+	Emoji        string    `json:"emoji" db:"emoji"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// ReactionSummary counts one reaction on a resource and lists who left it
+type ReactionSummary struct {
+	Emoji  string   `json:"emoji"`
+	Count  int      `json:"count"`
+	Actors []string `json:"actors"` // actor slugs, oldest reaction first
+}
+
+// SubtaskProgress summarizes a parent task's subtasks for projects with
+// subtask_rollup on. Deleted and cancelled subtasks are not counted.
+type SubtaskProgress struct {
+	Done  int `json:"done"` // completed or archived
+	Total int `json:"total"`
+}
+
+// TimeEntry is a block of time an actor logged against a task
+type TimeEntry struct {
+	UUID      string     `json:"uuid" db:"uuid"`
+	TaskUUID  string     `json:"task_uuid" db:"task_uuid"`
+	ActorUUID string     `json:"actor_uuid" db:"actor_uuid"`
+	ActorSlug string     `json:"actor_slug"`
+	Minutes   int        `json:"minutes" db:"minutes"`
+	Note      string     `json:"note,omitempty" db:"note"`
+	StartedAt *time.Time `json:"started_at,omitempty" db:"started_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// TimeTotal sums the time logged against a task
+type TimeTotal struct {
+	Minutes int `json:"minutes"`
+	Entries int `json:"entries"`
+}
+
+// APIToken is a daemon API token. Only a hash of the token is stored; the
+// token itself is shown once, when it is created.
+type APIToken struct {
+	UUID        string     `json:"uuid" db:"uuid"`
+	Name        string     `json:"name" db:"name"`
+	ActorUUID   string     `json:"actor_uuid" db:"actor_uuid"`
+	ActorSlug   string     `json:"actor_slug"`
+	Scope       TokenScope `json:"scope" db:"scope"`
+	ProjectUUID *string    `json:"project_uuid,omitempty" db:"project_uuid"` // nullable; restricts the token to this container's subtree
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
 }
 
 // Attachment represents a file attachment
@@ -3,7 +3,9 @@ package domain
 import (
 	"fmt"
 	"regexp"
+	"strings"
 	"time"
+	"unicode"
 )
 
 // UUIDv4Regex validates lowercase UUIDv4 format
@@ -12,7 +14,7 @@ var UUIDv4Regex = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89a
 // ValidateUUID validates a UUID v4 format (lowercase with hyphens)
 func ValidateUUID(uuid string) error {
 	if !UUIDv4Regex.MatchString(uuid) {
-		return fmt.Errorf("invalid UUID: must be lowercase UUIDv4 format (e.g., 550e8400-e29b-41d4-a716-446655440000)")
+		return validationErrorf("invalid UUID: must be lowercase UUIDv4 format (e.g., 550e8400-e29b-41d4-a716-446655440000)")
 	}
 	return nil
 }
@@ -23,14 +25,76 @@ func ValidateState(state string) error {
 	case "idea", "draft", "open", "in_progress", "completed", "blocked", "cancelled", "archived", "deleted":
 		return nil
 	default:
-		return fmt.Errorf("invalid state: must be one of: idea, draft, open, in_progress, completed, blocked, cancelled, archived, deleted")
+		return validationErrorf("invalid state: must be one of: idea, draft, open, in_progress, completed, blocked, cancelled, archived, deleted")
 	}
 }
 
 // ValidatePriority validates a task priority
 func ValidatePriority(priority int) error {
 	if priority < 1 || priority > 4 {
-		return fmt.Errorf("invalid priority: must be between 1 and 4")
+		return validationErrorf("invalid priority: must be between 1 and 4")
+	}
+	return nil
+}
+
+// ValidateEstimateMinutes validates a task's effort estimate
+func ValidateEstimateMinutes(minutes int) error {
+	if minutes < 0 {
+		return validationErrorf("invalid estimate_minutes: must not be negative")
+	}
+	return nil
+}
+
+// ValidateTimeEntryMinutes validates the minutes of a logged time entry
+func ValidateTimeEntryMinutes(minutes int) error {
+	if minutes < 1 {
+		return validationErrorf("invalid minutes: must be at least 1")
+	}
+	return nil
+}
+
+// ValidateTokenScope validates an API token scope
+func ValidateTokenScope(scope string) error {
+	switch TokenScope(scope) {
+	case TokenScopeRead, TokenScopeWrite, TokenScopeAdmin:
+		return nil
+	default:
+		return validationErrorf("invalid token scope: must be one of: read, write, admin")
+	}
+}
+
+// ValidateRecurrence validates a recurrence spec
+func ValidateRecurrence(r *Recurrence) error {
+	if r.Interval < 1 {
+		return validationErrorf("invalid recurrence interval: must be at least 1")
+	}
+	switch r.Unit {
+	case RecurrenceDay, RecurrenceWeek:
+	default:
+		return validationErrorf("invalid recurrence unit: must be one of: day, week")
+	}
+	if r.Weekday != nil {
+		if r.Unit != RecurrenceWeek {
+			return validationErrorf("invalid recurrence: a weekday only applies to weekly recurrences")
+		}
+		if *r.Weekday < 0 || *r.Weekday > 6 {
+			return validationErrorf("invalid recurrence weekday: must be between 0 (Sunday) and 6")
+		}
+	}
+	if r.StartsAt.IsZero() {
+		return validationErrorf("invalid recurrence: start time is required")
+	}
+	return nil
+}
+
+// ValidateReaction validates a reaction: an emoji or a short name such as
+// "+1", without whitespace
+func ValidateReaction(emoji string) error {
+	if emoji == "" || len(emoji) > 32 {
+		return validationErrorf("invalid reaction: must be 1 to 32 bytes")
+	}
+	if strings.IndexFunc(emoji, unicode.IsSpace) >= 0 {
+		return validationErrorf("invalid reaction: must not contain whitespace")
 	}
 	return nil
 }
@@ -41,7 +105,7 @@ func ValidateActorRole(role string) error {
 	case "human", "agent", "system":
 		return nil
 	default:
-		return fmt.Errorf("invalid actor role: must be one of: human, agent, system")
+		return validationErrorf("invalid actor role: must be one of: human, agent, system")
 	}
 }
 
@@ -51,7 +115,7 @@ func ValidateResourceType(resourceType string) error {
 	case "task", "container", "attachment", "actor", "config", "system", "section", "task_relation":
 		return nil
 	default:
-		return fmt.Errorf("invalid resource type: must be one of: task, container, attachment, actor, config, system, section, task_relation")
+		return validationErrorf("invalid resource type: must be one of: task, container, attachment, actor, config, system, section, task_relation")
 	}
 }
 
@@ -61,18 +125,29 @@ func ValidateContainerKind(kind string) error {
 	case "project", "feature", "area", "misc":
 		return nil
 	default:
-		return fmt.Errorf("invalid container kind: must be one of: project, feature, area, misc")
+		return validationErrorf("invalid container kind: must be one of: project, feature, area, misc")
 	}
 }
 
-// ValidateTaskKind validates a task kind
-func ValidateTaskKind(kind string) error {
-	switch kind {
-	case "task", "subtask", "spike", "bug", "chore":
+// DefaultTaskKinds is the task kind vocabulary a new database starts with.
+var DefaultTaskKinds = []string{"task", "subtask", "spike", "bug", "chore", "feature", "epic"}
+
+// ValidateTaskKind validates a task kind against allowed, the configured
+// vocabulary (DefaultTaskKinds when nil). An empty kind is accepted for
+// backward compatibility.
+func ValidateTaskKind(kind string, allowed []string) error {
+	if kind == "" {
 		return nil
-	default:
-		return fmt.Errorf("invalid task kind: must be one of: task, subtask, spike, bug, chore")
 	}
+	if allowed == nil {
+		allowed = DefaultTaskKinds
+	}
+	for _, k := range allowed {
+		if k == kind {
+			return nil
+		}
+	}
+	return validationErrorf("invalid task kind %q: must be one of: %s", kind, strings.Join(allowed, ", "))
 }
 
 // ValidateResolution validates a task resolution
@@ -81,7 +156,7 @@ func ValidateResolution(resolution string) error {
 	case "done", "wont_do", "duplicate", "needs_info":
 		return nil
 	default:
-		return fmt.Errorf("invalid resolution: must be one of: done, wont_do, duplicate, needs_info")
+		return validationErrorf("invalid resolution: must be one of: done, wont_do, duplicate, needs_info")
 	}
 }
 
@@ -91,7 +166,7 @@ func ValidateRunStatus(status string) error {
 	case "queued", "running", "completed", "failed", "cancelled", "timed_out":
 		return nil
 	default:
-		return fmt.Errorf("invalid run_status: must be one of: queued, running, completed, failed, cancelled, timed_out")
+		return validationErrorf("invalid run_status: must be one of: queued, running, completed, failed, cancelled, timed_out")
 	}
 }
 
@@ -101,7 +176,7 @@ func ValidateSectionRole(role string) error {
 	case "backlog", "ready", "active", "review", "done":
 		return nil
 	default:
-		return fmt.Errorf("invalid section role: must be one of: backlog, ready, active, review, done")
+		return validationErrorf("invalid section role: must be one of: backlog, ready, active, review, done")
 	}
 }
 
@@ -111,7 +186,7 @@ func ValidateTaskRelationKind(kind string) error {
 	case "blocks", "relates_to", "duplicates":
 		return nil
 	default:
-		return fmt.Errorf("invalid task relation kind: must be one of: blocks, relates_to, duplicates")
+		return validationErrorf("invalid task relation kind: must be one of: blocks, relates_to, duplicates")
 	}
 }
 
@@ -119,11 +194,24 @@ func ValidateTaskRelationKind(kind string) error {
 func ValidateTimestamp(s string) (time.Time, error) {
 	t, err := time.Parse(time.RFC3339, s)
 	if err != nil {
-		return time.Time{}, fmt.Errorf("invalid timestamp format: expected ISO8601/RFC3339")
+		return time.Time{}, validationErrorf("invalid timestamp format: expected ISO8601/RFC3339")
 	}
 	return t, nil
 }
 
+// ValidationError is returned when a value fails one of the Validate checks.
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func validationErrorf(format string, args ...interface{}) error {
+	return &ValidationError{Message: fmt.Sprintf(format, args...)}
+}
+
 // ETagMismatchError is returned when an etag doesn't match
 type ETagMismatchError struct {
 	Expected int64
@@ -134,6 +222,19 @@ func (e *ETagMismatchError) Error() string {
 	return fmt.Sprintf("etag mismatch: expected %d, got %d", e.Expected, e.Actual)
 }
 
+// WIPLimitExceededError is returned when adding a task would push a section
+// past its wip_limit.
+type WIPLimitExceededError struct {
+	SectionUUID string
+	SectionID   string
+	Limit       int
+	Count       int
+}
+
+func (e *WIPLimitExceededError) Error() string {
+	return fmt.Sprintf("wip limit exceeded for section %s: %d active tasks, limit %d", e.SectionID, e.Count, e.Limit)
+}
+
 // CheckETag validates an etag against the current value
 func CheckETag(expected, actual int64) error {
 	if expected != actual {
@@ -70,6 +70,31 @@ func TestValidatePriority(t *testing.T) {
 	}
 }
 
+func TestValidateTimeMinutes(t *testing.T) {
+	tests := []struct {
+		name        string
+		minutes     int
+		estimateErr bool
+		entryErr    bool
+	}{
+		{name: "zero", minutes: 0, estimateErr: false, entryErr: true},
+		{name: "one", minutes: 1, estimateErr: false, entryErr: false},
+		{name: "large", minutes: 6000, estimateErr: false, entryErr: false},
+		{name: "negative", minutes: -5, estimateErr: true, entryErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateEstimateMinutes(tt.minutes); (err != nil) != tt.estimateErr {
+				t.Errorf("ValidateEstimateMinutes(%d) error = %v, wantErr %v", tt.minutes, err, tt.estimateErr)
+			}
+			if err := ValidateTimeEntryMinutes(tt.minutes); (err != nil) != tt.entryErr {
+				t.Errorf("ValidateTimeEntryMinutes(%d) error = %v, wantErr %v", tt.minutes, err, tt.entryErr)
+			}
+		})
+	}
+}
+
 func TestValidateActorRole(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -126,6 +151,35 @@ func TestValidateResolution(t *testing.T) {
 	}
 }
 
+func TestValidateTaskKind(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    string
+		allowed []string
+		wantErr bool
+	}{
+		{name: "default bug", kind: "bug", wantErr: false},
+		{name: "default epic", kind: "epic", wantErr: false},
+		{name: "configured kind", kind: "incident", allowed: []string{"task", "incident"}, wantErr: false},
+		{name: "empty", kind: "", allowed: []string{"task"}, wantErr: false},
+		{name: "unknown", kind: "story", wantErr: true},
+		{name: "not configured", kind: "bug", allowed: []string{"task", "incident"}, wantErr: true},
+		{name: "uppercase", kind: "BUG", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTaskKind(tt.kind, tt.allowed)
+			if tt.wantErr && err == nil {
+				t.Error("ValidateTaskKind() expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("ValidateTaskKind() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
 func TestValidateResourceType(t *testing.T) {
 	tests := []struct {
 		name    string
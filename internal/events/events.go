@@ -169,6 +169,30 @@ func (w *Writer) LogCommentCreated(tx *sql.Tx, actorUUID string, comment *domain
 	return w.LogEvent(tx, event)
 }
 
+// LogCommentUpdated logs a comment edit event
+func (w *Writer) LogCommentUpdated(tx *sql.Tx, actorUUID string, comment *domain.Comment) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"task_id":    comment.TaskUUID,
+		"comment_id": comment.ID,
+		"actor_id":   actorUUID,
+	})
+	if err != nil {
+		return err
+	}
+
+	payloadStr := string(payload)
+	event := &domain.Event{
+		ActorUUID:    &actorUUID,
+		ResourceType: "comment",
+		ResourceUUID: &comment.UUID,
+		EventType:    "comment.updated",
+		ETag:         &comment.ETag,
+		Payload:      &payloadStr,
+	}
+
+	return w.LogEvent(tx, event)
+}
+
 // LogCommentDeleted logs a comment soft-delete event
 func (w *Writer) LogCommentDeleted(tx *sql.Tx, actorUUID string, comment *domain.Comment) error {
 	payload, err := json.Marshal(map[string]interface{}{
@@ -89,6 +89,9 @@ func applyAdd(snap *snapshot.Snapshot, path []string, value interface{}) error {
 	if len(path) < 2 {
 		return fmt.Errorf("path too short for add: %v", path)
 	}
+	if len(path) > 2 {
+		return applyFieldOp(snap, "add", path, value)
+	}
 
 	collection := path[0]
 	key := path[1]
@@ -135,6 +138,9 @@ func applyRemove(snap *snapshot.Snapshot, path []string) error {
 	if len(path) < 2 {
 		return fmt.Errorf("path too short for remove: %v", path)
 	}
+	if len(path) > 2 {
+		return applyFieldOp(snap, "remove", path, nil)
+	}
 
 	collection := path[0]
 	key := path[1]
@@ -176,6 +182,9 @@ func applyReplace(snap *snapshot.Snapshot, path []string, value interface{}) err
 	if len(path) < 2 {
 		return fmt.Errorf("path too short for replace: %v", path)
 	}
+	if len(path) > 2 {
+		return applyFieldOp(snap, "replace", path, value)
+	}
 
 	collection := path[0]
 	key := path[1]
@@ -233,6 +242,69 @@ func applyReplace(snap *snapshot.Snapshot, path []string, value interface{}) err
 	return nil
 }
 
+// applyFieldOp applies an add, replace, or remove to a field inside a single
+// entity, e.g. /tasks/<uuid>/state or /tasks/<uuid>/labels/0.
+func applyFieldOp(snap *snapshot.Snapshot, op string, path []string, value interface{}) error {
+	collection, key, field := path[0], path[1], path[2:]
+
+	switch collection {
+	case "actors":
+		entry, ok := snap.Actors[key]
+		if !ok {
+			return fmt.Errorf("actor not found: %s", key)
+		}
+		updated, err := patchEntity(entry, op, field, value)
+		if err != nil {
+			return err
+		}
+		snap.Actors[key] = updated
+	case "containers":
+		entry, ok := snap.Containers[key]
+		if !ok {
+			return fmt.Errorf("container not found: %s", key)
+		}
+		updated, err := patchEntity(entry, op, field, value)
+		if err != nil {
+			return err
+		}
+		snap.Containers[key] = updated
+	case "tasks":
+		entry, ok := snap.Tasks[key]
+		if !ok {
+			return fmt.Errorf("task not found: %s", key)
+		}
+		updated, err := patchEntity(entry, op, field, value)
+		if err != nil {
+			return err
+		}
+		snap.Tasks[key] = updated
+	case "comments":
+		entry, ok := snap.Comments[key]
+		if !ok {
+			return fmt.Errorf("comment not found: %s", key)
+		}
+		updated, err := patchEntity(entry, op, field, value)
+		if err != nil {
+			return err
+		}
+		snap.Comments[key] = updated
+	case "links":
+		entry, ok := snap.Links[key]
+		if !ok {
+			return fmt.Errorf("link not found: %s", key)
+		}
+		updated, err := patchEntity(entry, op, field, value)
+		if err != nil {
+			return err
+		}
+		snap.Links[key] = updated
+	default:
+		return fmt.Errorf("unsupported collection for %s: %s", op, collection)
+	}
+
+	return nil
+}
+
 func applyTest(snap *snapshot.Snapshot, path []string, value interface{}) error {
 	current, found := getValueAtPath(snap, "/"+strings.Join(path, "/"))
 	if !found {
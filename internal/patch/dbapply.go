@@ -0,0 +1,845 @@
+package patch
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lherron/wrkq/internal/domain"
+	"github.com/lherron/wrkq/internal/events"
+	"github.com/lherron/wrkq/internal/id"
+	"github.com/lherron/wrkq/internal/snapshot"
+	"github.com/lherron/wrkq/internal/store"
+)
+
+// ErrConflict is returned by ApplyToDatabase when one or more operations
+// conflict with the current database state. The result lists every conflict
+// and nothing is written.
+var ErrConflict = errors.New("patch conflicts with database state")
+
+// column is a single column/value pair used to diff and write entity rows.
+type column struct {
+	name  string
+	value interface{}
+}
+
+// dbApplier applies patch operations inside a single transaction.
+type dbApplier struct {
+	tx        *sql.Tx
+	ew        *events.Writer
+	actorUUID string
+	result    *DatabaseApplyResult
+	baseETags map[string]int64
+	// stateChanged lists tasks whose state was written, so their parents'
+	// subtask rollups can be refreshed before commit
+	stateChanged []string
+}
+
+// ApplyToDatabase applies a patch directly to the database in one
+// transaction, translating operations on /tasks, /containers, and /comments
+// into row writes with event logging and etag increments.
+//
+// Whole-entity add and replace upsert the entity and remove soft-deletes it;
+// field-level paths (e.g. /tasks/<uuid>/state) patch the stored row. test
+// guards compare against the stored row. A write whose etag is not newer
+// than the stored etag is a conflict; conflicts are collected and returned with ErrConflict, and the
+// transaction is rolled back.
+func ApplyToDatabase(db *sql.DB, p Patch, actorUUID string) (*DatabaseApplyResult, error) {
+	if actorUUID == "" {
+		return nil, fmt.Errorf("actor UUID is required")
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	a := &dbApplier{
+		tx:        tx,
+		ew:        events.NewWriter(db),
+		actorUUID: actorUUID,
+		result:    &DatabaseApplyResult{OpCount: len(p)},
+		baseETags: make(map[string]int64),
+	}
+
+	for i, op := range p {
+		if err := a.apply(op); err != nil {
+			return nil, fmt.Errorf("failed to apply operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+
+	if len(a.result.Conflicts) > 0 {
+		return a.result, ErrConflict
+	}
+
+	if err := a.refreshParentProgress(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	a.result.Applied = true
+	return a.result, nil
+}
+
+func (a *dbApplier) apply(op Operation) error {
+	parts := parseJSONPointer(op.Path)
+	if len(parts) < 2 {
+		return fmt.Errorf("invalid path: %s", op.Path)
+	}
+	uuid, field := parts[1], parts[2:]
+
+	switch op.Op {
+	case "add", "replace", "remove", "test":
+	default:
+		return fmt.Errorf("unsupported operation: %s", op.Op)
+	}
+
+	switch parts[0] {
+	case "tasks":
+		return a.applyTask(op, uuid, field)
+	case "containers":
+		return a.applyContainer(op, uuid, field)
+	case "comments":
+		return a.applyComment(op, uuid, field)
+	default:
+		return fmt.Errorf("unsupported collection for database apply: %s", parts[0])
+	}
+}
+
+func (a *dbApplier) applyTask(op Operation, uuid string, field []string) error {
+	current, err := loadTaskEntry(a.tx, uuid)
+	if err != nil {
+		return err
+	}
+	if current != nil {
+		current.ETag = a.baseETag(uuid, current.ETag)
+	}
+
+	switch op.Op {
+	case "test":
+		if current == nil {
+			a.conflict(op, uuid, "not_found", 0, 0, "task not found")
+			return nil
+		}
+		return a.test(op, uuid, *current, current.ETag, field)
+	case "remove":
+		if len(field) > 0 {
+			break
+		}
+		if current == nil {
+			a.conflict(op, uuid, "not_found", 0, 0, "task not found")
+			return nil
+		}
+		return a.deleteTask(uuid, current)
+	}
+
+	var next snapshot.TaskEntry
+	if len(field) > 0 {
+		if current == nil {
+			a.conflict(op, uuid, "not_found", 0, 0, "task not found")
+			return nil
+		}
+		if managedFields[field[0]] {
+			a.applyManagedField(op, uuid, current.ETag, field)
+			return nil
+		}
+		if next, err = patchEntity(*current, op.Op, field, op.Value); err != nil {
+			return err
+		}
+	} else if next, err = convertToTaskEntry(op.Value); err != nil {
+		return err
+	}
+
+	if current == nil {
+		return a.insertTask(uuid, &next)
+	}
+
+	changes := changedColumns(taskColumns(current), taskColumns(&next))
+	if len(changes) == 0 {
+		a.result.Unchanged++
+		return nil
+	}
+	if len(field) == 0 && a.stale(op, uuid, current.ETag, next.ETag) {
+		return nil
+	}
+	for _, col := range changes {
+		if col.name == "state" {
+			a.stateChanged = append(a.stateChanged, uuid)
+		}
+	}
+	return a.updateRow("tasks", "task", uuid, changes)
+}
+
+// refreshParentProgress refreshes the subtask rollup of the parent of every
+// task whose state the patch changed.
+func (a *dbApplier) refreshParentProgress() error {
+	parents := make([]string, 0, len(a.stateChanged))
+	for _, uuid := range a.stateChanged {
+		var parentUUID sql.NullString
+		if err := a.tx.QueryRow("SELECT parent_task_uuid FROM tasks WHERE uuid = ?", uuid).Scan(&parentUUID); err != nil {
+			return fmt.Errorf("failed to get parent task of %s: %w", uuid, err)
+		}
+		parents = append(parents, parentUUID.String)
+	}
+	return store.RefreshParentProgressTx(a.tx, a.ew, a.actorUUID, parents...)
+}
+
+func (a *dbApplier) applyContainer(op Operation, uuid string, field []string) error {
+	current, err := loadContainerEntry(a.tx, uuid)
+	if err != nil {
+		return err
+	}
+	if current != nil {
+		current.ETag = a.baseETag(uuid, current.ETag)
+	}
+
+	switch op.Op {
+	case "test":
+		if current == nil {
+			a.conflict(op, uuid, "not_found", 0, 0, "container not found")
+			return nil
+		}
+		return a.test(op, uuid, *current, current.ETag, field)
+	case "remove":
+		if len(field) > 0 {
+			break
+		}
+		if current == nil {
+			a.conflict(op, uuid, "not_found", 0, 0, "container not found")
+			return nil
+		}
+		return a.archiveContainer(uuid, current)
+	}
+
+	var next snapshot.ContainerEntry
+	if len(field) > 0 {
+		if current == nil {
+			a.conflict(op, uuid, "not_found", 0, 0, "container not found")
+			return nil
+		}
+		if managedFields[field[0]] {
+			a.applyManagedField(op, uuid, current.ETag, field)
+			return nil
+		}
+		if next, err = patchEntity(*current, op.Op, field, op.Value); err != nil {
+			return err
+		}
+	} else if next, err = convertToContainerEntry(op.Value); err != nil {
+		return err
+	}
+
+	if current == nil {
+		return a.insertContainer(uuid, &next)
+	}
+
+	changes := changedColumns(containerColumns(current), containerColumns(&next))
+	if len(changes) == 0 {
+		a.result.Unchanged++
+		return nil
+	}
+	if len(field) == 0 && a.stale(op, uuid, current.ETag, next.ETag) {
+		return nil
+	}
+	return a.updateRow("containers", "container", uuid, changes)
+}
+
+func (a *dbApplier) applyComment(op Operation, uuid string, field []string) error {
+	current, err := loadCommentEntry(a.tx, uuid)
+	if err != nil {
+		return err
+	}
+	if current != nil {
+		current.ETag = a.baseETag(uuid, current.ETag)
+	}
+
+	switch op.Op {
+	case "test":
+		if current == nil {
+			a.conflict(op, uuid, "not_found", 0, 0, "comment not found")
+			return nil
+		}
+		return a.test(op, uuid, *current, current.ETag, field)
+	case "remove":
+		if len(field) > 0 {
+			break
+		}
+		if current == nil {
+			a.conflict(op, uuid, "not_found", 0, 0, "comment not found")
+			return nil
+		}
+		return a.deleteComment(uuid, current)
+	}
+
+	var next snapshot.CommentEntry
+	if len(field) > 0 {
+		if current == nil {
+			a.conflict(op, uuid, "not_found", 0, 0, "comment not found")
+			return nil
+		}
+		if managedFields[field[0]] {
+			a.applyManagedField(op, uuid, current.ETag, field)
+			return nil
+		}
+		if next, err = patchEntity(*current, op.Op, field, op.Value); err != nil {
+			return err
+		}
+	} else if next, err = convertToCommentEntry(op.Value); err != nil {
+		return err
+	}
+
+	if current == nil {
+		return a.insertComment(uuid, &next)
+	}
+	if next.TaskUUID != "" && next.TaskUUID != current.TaskUUID {
+		return fmt.Errorf("comment %s cannot be moved to another task", uuid)
+	}
+	if next.ParentUUID != "" && next.ParentUUID != current.ParentUUID {
+		return fmt.Errorf("comment %s cannot be moved to another thread", uuid)
+	}
+
+	changes := changedColumns(commentColumns(current), commentColumns(&next))
+	if len(changes) == 0 {
+		a.result.Unchanged++
+		return nil
+	}
+	if len(field) == 0 && a.stale(op, uuid, current.ETag, next.ETag) {
+		return nil
+	}
+	return a.updateRow("comments", "comment", uuid, changes)
+}
+
+// test implements the RFC 6902 test operation against the stored entity.
+func (a *dbApplier) test(op Operation, uuid string, current interface{}, currentETag int64, field []string) error {
+	got, found := getFieldValue(current, field)
+	if !found {
+		return fmt.Errorf("path not found for test: %s", op.Path)
+	}
+	if deepEqual(got, op.Value) {
+		return nil
+	}
+
+	if len(field) == 1 && field[0] == "etag" {
+		var expected int64
+		if data, err := json.Marshal(op.Value); err == nil {
+			json.Unmarshal(data, &expected)
+		}
+		a.conflict(op, uuid, "etag_mismatch", expected, currentETag, "")
+		return nil
+	}
+	a.conflict(op, uuid, "test_failed", 0, 0, fmt.Sprintf("test failed at %s: values differ", op.Path))
+	return nil
+}
+
+// stale records a conflict when the database has advanced to (or past) the
+// etag carried by an incoming whole-entity write.
+func (a *dbApplier) stale(op Operation, uuid string, currentETag, incomingETag int64) bool {
+	if incomingETag <= 0 || currentETag < incomingETag {
+		return false
+	}
+	a.conflict(op, uuid, "etag_mismatch", incomingETag-1, currentETag, "")
+	return true
+}
+
+func (a *dbApplier) conflict(op Operation, uuid, reason string, expected, actual int64, message string) {
+	a.result.Conflicts = append(a.result.Conflicts, DatabaseConflict{
+		Op:           op.Op,
+		Path:         op.Path,
+		UUID:         uuid,
+		Reason:       reason,
+		ExpectedETag: expected,
+		ActualETag:   actual,
+		Message:      message,
+	})
+}
+
+func (a *dbApplier) insertTask(uuid string, task *snapshot.TaskEntry) error {
+	taskID, err := a.freeID("tasks", task.ID)
+	if err != nil {
+		return err
+	}
+
+	cols := append([]column{{"uuid", uuid}, {"id", taskID}}, taskColumns(task)...)
+	cols = append(cols, a.provenanceColumns(task.ETag, task.CreatedAt, task.CreatedBy)...)
+	if err := a.insertRow("tasks", cols); err != nil {
+		return fmt.Errorf("failed to insert task %s: %w", uuid, err)
+	}
+
+	return a.logCreated("task", uuid, "tasks", map[string]interface{}{
+		"slug":         task.Slug,
+		"title":        task.Title,
+		"project_uuid": task.ProjectUUID,
+	})
+}
+
+func (a *dbApplier) insertContainer(uuid string, container *snapshot.ContainerEntry) error {
+	containerID, err := a.freeID("containers", container.ID)
+	if err != nil {
+		return err
+	}
+
+	cols := append([]column{{"uuid", uuid}, {"id", containerID}}, containerColumns(container)...)
+	cols = append(cols, a.provenanceColumns(container.ETag, container.CreatedAt, container.CreatedBy)...)
+	if err := a.insertRow("containers", cols); err != nil {
+		return fmt.Errorf("failed to insert container %s: %w", uuid, err)
+	}
+
+	return a.logCreated("container", uuid, "containers", map[string]interface{}{
+		"slug":        container.Slug,
+		"parent_uuid": nullIfEmpty(container.ParentUUID),
+	})
+}
+
+func (a *dbApplier) insertComment(uuid string, comment *snapshot.CommentEntry) error {
+	commentID, err := a.freeID("comments", comment.ID)
+	if err != nil {
+		return err
+	}
+	if commentID == "" {
+		// Comments have no ID trigger; allocate the next C-xxxxx like comment add
+		var nextSeq int
+		if err := a.tx.QueryRow("SELECT COALESCE(MAX(CAST(SUBSTR(id, 3) AS INTEGER)), 0) + 1 FROM comments").Scan(&nextSeq); err != nil {
+			return fmt.Errorf("failed to calculate next comment ID: %w", err)
+		}
+		if _, err := a.tx.Exec("UPDATE comment_sequences SET value = ? WHERE name = 'next_comment'", nextSeq); err != nil {
+			return fmt.Errorf("failed to update comment sequence: %w", err)
+		}
+		commentID = id.FormatComment(nextSeq)
+	}
+
+	if comment.ParentUUID != "" {
+		var parentTask string
+		err := a.tx.QueryRow("SELECT task_uuid FROM comments WHERE uuid = ?", comment.ParentUUID).Scan(&parentTask)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("comment %s replies to unknown comment %s", uuid, comment.ParentUUID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load parent comment %s: %w", comment.ParentUUID, err)
+		}
+		if parentTask != comment.TaskUUID {
+			return fmt.Errorf("comment %s replies to comment %s on another task", uuid, comment.ParentUUID)
+		}
+	}
+
+	authorUUID := comment.ActorUUID
+	if authorUUID == "" {
+		authorUUID = a.actorUUID
+	}
+	etag := comment.ETag
+	if etag < 1 {
+		etag = 1
+	}
+
+	cols := append([]column{
+		{"uuid", uuid},
+		{"id", commentID},
+		{"task_uuid", comment.TaskUUID},
+		{"actor_uuid", authorUUID},
+	}, commentColumns(comment)...)
+	cols = append(cols, column{"etag", etag})
+	if comment.CreatedAt != "" {
+		cols = append(cols, column{"created_at", comment.CreatedAt})
+	}
+	if comment.ParentUUID != "" {
+		cols = append(cols, column{"parent_comment_uuid", comment.ParentUUID})
+	}
+	if err := a.insertRow("comments", cols); err != nil {
+		return fmt.Errorf("failed to insert comment %s: %w", uuid, err)
+	}
+
+	return a.logCreated("comment", uuid, "comments", map[string]interface{}{
+		"task_uuid": comment.TaskUUID,
+	})
+}
+
+// freeID returns want when it is unused, or "" so the insert falls back to a
+// freshly allocated friendly ID.
+func (a *dbApplier) freeID(table, want string) (string, error) {
+	if want == "" {
+		return "", nil
+	}
+	var count int
+	if err := a.tx.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE id = ?", want).Scan(&count); err != nil {
+		return "", fmt.Errorf("failed to check friendly ID %s: %w", want, err)
+	}
+	if count > 0 {
+		return "", nil
+	}
+	return want, nil
+}
+
+// provenanceColumns returns the etag, creation, and actor columns for a new
+// task or container row.
+func (a *dbApplier) provenanceColumns(etag int64, createdAt, createdBy string) []column {
+	if etag < 1 {
+		etag = 1
+	}
+	if createdBy == "" {
+		createdBy = a.actorUUID
+	}
+	cols := []column{
+		{"etag", etag},
+		{"created_by_actor_uuid", createdBy},
+		{"updated_by_actor_uuid", a.actorUUID},
+	}
+	if createdAt != "" {
+		cols = append(cols, column{"created_at", createdAt})
+	}
+	return cols
+}
+
+func (a *dbApplier) insertRow(table string, cols []column) error {
+	names := make([]string, len(cols))
+	placeholders := make([]string, len(cols))
+	args := make([]interface{}, len(cols))
+	for i, col := range cols {
+		names[i] = col.name
+		placeholders[i] = "?"
+		args[i] = col.value
+	}
+	_, err := a.tx.Exec(fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		table, strings.Join(names, ", "), strings.Join(placeholders, ", ")), args...)
+	return err
+}
+
+// updateRow writes the changed columns, bumps the etag, and logs an
+// <resourceType>.updated event with the changed values.
+func (a *dbApplier) updateRow(table, resourceType, uuid string, changes []column) error {
+	sets := make([]string, 0, len(changes)+2)
+	args := make([]interface{}, 0, len(changes)+2)
+	fields := make(map[string]interface{}, len(changes))
+	for _, col := range changes {
+		sets = append(sets, col.name+" = ?")
+		args = append(args, col.value)
+		fields[col.name] = col.value
+	}
+	sets = append(sets, "etag = etag + 1")
+	if table == "comments" {
+		// Comments have no updated_by column or touch trigger
+		sets = append(sets, "updated_at = strftime('%Y-%m-%dT%H:%M:%SZ','now')")
+	} else {
+		sets = append(sets, "updated_by_actor_uuid = ?")
+		args = append(args, a.actorUUID)
+	}
+	args = append(args, uuid)
+
+	if _, err := a.tx.Exec(fmt.Sprintf("UPDATE %s SET %s WHERE uuid = ?", table, strings.Join(sets, ", ")), args...); err != nil {
+		return fmt.Errorf("failed to update %s %s: %w", resourceType, uuid, err)
+	}
+
+	if err := a.logEvent(resourceType, uuid, table, resourceType+".updated", fields); err != nil {
+		return err
+	}
+	a.result.Updated++
+	return nil
+}
+
+// deleteTask soft-deletes a task by moving it to the deleted state.
+func (a *dbApplier) deleteTask(uuid string, current *snapshot.TaskEntry) error {
+	if current.State == "deleted" {
+		a.result.Unchanged++
+		return nil
+	}
+
+	if _, err := a.tx.Exec(`
+		UPDATE tasks
+		SET state = 'deleted',
+		    etag = etag + 1,
+		    updated_by_actor_uuid = ?
+		WHERE uuid = ?
+	`, a.actorUUID, uuid); err != nil {
+		return fmt.Errorf("failed to delete task %s: %w", uuid, err)
+	}
+	a.stateChanged = append(a.stateChanged, uuid)
+
+	if err := a.logEvent("task", uuid, "tasks", "task.deleted", map[string]interface{}{
+		"slug": current.Slug,
+	}); err != nil {
+		return err
+	}
+	a.result.Deleted++
+	return nil
+}
+
+// archiveContainer soft-deletes a container the way `wrkq rm` does.
+func (a *dbApplier) archiveContainer(uuid string, current *snapshot.ContainerEntry) error {
+	if current.ArchivedAt != "" {
+		a.result.Unchanged++
+		return nil
+	}
+
+	if _, err := a.tx.Exec(`
+		UPDATE containers
+		SET archived_at = strftime('%Y-%m-%dT%H:%M:%SZ','now'),
+		    updated_by_actor_uuid = ?,
+		    etag = etag + 1
+		WHERE uuid = ?
+	`, a.actorUUID, uuid); err != nil {
+		return fmt.Errorf("failed to archive container %s: %w", uuid, err)
+	}
+
+	if err := a.logEvent("container", uuid, "containers", "container.archived", map[string]interface{}{
+		"slug":        current.Slug,
+		"soft_delete": true,
+	}); err != nil {
+		return err
+	}
+	a.result.Deleted++
+	return nil
+}
+
+func (a *dbApplier) deleteComment(uuid string, current *snapshot.CommentEntry) error {
+	if current.DeletedAt != "" {
+		a.result.Unchanged++
+		return nil
+	}
+
+	if _, err := a.tx.Exec(`
+		UPDATE comments
+		SET deleted_at = strftime('%Y-%m-%dT%H:%M:%SZ','now'),
+		    deleted_by_actor_uuid = ?,
+		    etag = etag + 1
+		WHERE uuid = ?
+	`, a.actorUUID, uuid); err != nil {
+		return fmt.Errorf("failed to delete comment %s: %w", uuid, err)
+	}
+
+	if err := a.logEvent("comment", uuid, "comments", "comment.deleted", map[string]interface{}{
+		"task_uuid": current.TaskUUID,
+	}); err != nil {
+		return err
+	}
+	a.result.Deleted++
+	return nil
+}
+
+func (a *dbApplier) logCreated(resourceType, uuid, table string, payload map[string]interface{}) error {
+	if err := a.logEvent(resourceType, uuid, table, resourceType+".created", payload); err != nil {
+		return err
+	}
+	a.result.Created++
+	return nil
+}
+
+// logEvent records an event for a row written by the patch, tagging it with
+// the row's post-write etag.
+func (a *dbApplier) logEvent(resourceType, uuid, table, eventType string, payload map[string]interface{}) error {
+	var etag int64
+	if err := a.tx.QueryRow("SELECT etag FROM "+table+" WHERE uuid = ?", uuid).Scan(&etag); err != nil {
+		return fmt.Errorf("failed to read %s etag: %w", resourceType, err)
+	}
+
+	payload["source"] = "patch"
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event payload: %w", err)
+	}
+	payloadStr := string(payloadJSON)
+
+	if err := a.ew.LogEvent(a.tx, &domain.Event{
+		ActorUUID:    &a.actorUUID,
+		ResourceType: resourceType,
+		ResourceUUID: &uuid,
+		EventType:    eventType,
+		ETag:         &etag,
+		Payload:      &payloadStr,
+	}); err != nil {
+		return fmt.Errorf("failed to log event: %w", err)
+	}
+	return nil
+}
+
+// managedFields are maintained by wrkq on every write. Field-level ops on
+// them are not written: an etag replace acts as a stale-write guard and the
+// rest are ignored, matching how whole-entity writes treat them.
+var managedFields = map[string]bool{
+	"id":         true,
+	"etag":       true,
+	"created_at": true,
+	"updated_at": true,
+	"created_by": true,
+	"updated_by": true,
+	"task_uuid":  true,
+	"actor_uuid": true,
+}
+
+func (a *dbApplier) applyManagedField(op Operation, uuid string, baseETag int64, field []string) {
+	if field[0] == "etag" && len(field) == 1 && op.Op != "remove" {
+		var incoming int64
+		if data, err := json.Marshal(op.Value); err == nil {
+			json.Unmarshal(data, &incoming)
+		}
+		if a.stale(op, uuid, baseETag, incoming) {
+			return
+		}
+	}
+	a.result.Unchanged++
+}
+
+// baseETag returns an entity's etag as it was before this patch first
+// touched it, so guards are not tripped by the patch's own earlier writes.
+func (a *dbApplier) baseETag(uuid string, current int64) int64 {
+	if etag, ok := a.baseETags[uuid]; ok {
+		return etag
+	}
+	a.baseETags[uuid] = current
+	return current
+}
+
+// changedColumns returns the columns in next whose values differ from current.
+func changedColumns(current, next []column) []column {
+	var changes []column
+	for i, col := range next {
+		if current[i].value != col.value {
+			changes = append(changes, col)
+		}
+	}
+	return changes
+}
+
+// taskColumns returns the patchable task columns in storage form.
+func taskColumns(t *snapshot.TaskEntry) []column {
+	var labels interface{}
+	if len(t.Labels) > 0 {
+		sorted := append([]string(nil), t.Labels...)
+		sort.Strings(sorted)
+		labelsJSON, _ := json.Marshal(sorted)
+		labels = string(labelsJSON)
+	}
+
+	return []column{
+		{"slug", t.Slug},
+		{"title", t.Title},
+		{"project_uuid", t.ProjectUUID},
+		{"requested_by_project_id", nullIfEmpty(t.RequestedByProjectID)},
+		{"assigned_project_id", nullIfEmpty(t.AssignedProjectID)},
+		{"acknowledged_at", nullIfEmpty(t.AcknowledgedAt)},
+		{"resolution", nullIfEmpty(t.Resolution)},
+		{"state", t.State},
+		{"priority", t.Priority},
+		{"start_at", nullIfEmpty(t.StartAt)},
+		{"due_at", nullIfEmpty(t.DueAt)},
+		{"labels", labels},
+		{"description", t.Description},
+		{"completed_at", nullIfEmpty(t.CompletedAt)},
+		{"archived_at", nullIfEmpty(t.ArchivedAt)},
+	}
+}
+
+// containerColumns returns the patchable container columns in storage form.
+func containerColumns(c *snapshot.ContainerEntry) []column {
+	return []column{
+		{"slug", c.Slug},
+		{"title", c.Title},
+		{"parent_uuid", nullIfEmpty(c.ParentUUID)},
+		{"archived_at", nullIfEmpty(c.ArchivedAt)},
+	}
+}
+
+// commentColumns returns the patchable comment columns in storage form.
+func commentColumns(c *snapshot.CommentEntry) []column {
+	return []column{
+		{"body", c.Body},
+		{"meta", nullIfEmpty(c.Meta)},
+	}
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func loadTaskEntry(tx *sql.Tx, uuid string) (*snapshot.TaskEntry, error) {
+	var t snapshot.TaskEntry
+	var requestedBy, assignedProject, acknowledgedAt, resolution sql.NullString
+	var startAt, dueAt, labels, completedAt, archivedAt sql.NullString
+	err := tx.QueryRow(`
+		SELECT id, slug, title, project_uuid, requested_by_project_id,
+		       assigned_project_id, acknowledged_at, resolution, state, priority,
+		       start_at, due_at, labels, description, etag,
+		       created_at, updated_at, completed_at, archived_at,
+		       created_by_actor_uuid, updated_by_actor_uuid
+		FROM tasks WHERE uuid = ?
+	`, uuid).Scan(&t.ID, &t.Slug, &t.Title, &t.ProjectUUID, &requestedBy,
+		&assignedProject, &acknowledgedAt, &resolution, &t.State, &t.Priority,
+		&startAt, &dueAt, &labels, &t.Description, &t.ETag,
+		&t.CreatedAt, &t.UpdatedAt, &completedAt, &archivedAt,
+		&t.CreatedBy, &t.UpdatedBy)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load task %s: %w", uuid, err)
+	}
+
+	t.RequestedByProjectID = requestedBy.String
+	t.AssignedProjectID = assignedProject.String
+	t.AcknowledgedAt = acknowledgedAt.String
+	t.Resolution = resolution.String
+	t.StartAt = startAt.String
+	t.DueAt = dueAt.String
+	t.CompletedAt = completedAt.String
+	t.ArchivedAt = archivedAt.String
+	if labels.Valid && labels.String != "" && labels.String != "[]" {
+		if err := json.Unmarshal([]byte(labels.String), &t.Labels); err != nil {
+			return nil, fmt.Errorf("failed to parse labels for task %s: %w", uuid, err)
+		}
+	}
+	return &t, nil
+}
+
+func loadContainerEntry(tx *sql.Tx, uuid string) (*snapshot.ContainerEntry, error) {
+	var c snapshot.ContainerEntry
+	var title, parentUUID, archivedAt sql.NullString
+	err := tx.QueryRow(`
+		SELECT id, slug, title, parent_uuid, etag,
+		       created_at, updated_at, archived_at,
+		       created_by_actor_uuid, updated_by_actor_uuid
+		FROM containers WHERE uuid = ?
+	`, uuid).Scan(&c.ID, &c.Slug, &title, &parentUUID, &c.ETag,
+		&c.CreatedAt, &c.UpdatedAt, &archivedAt,
+		&c.CreatedBy, &c.UpdatedBy)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load container %s: %w", uuid, err)
+	}
+
+	c.Title = title.String
+	c.ParentUUID = parentUUID.String
+	c.ArchivedAt = archivedAt.String
+	return &c, nil
+}
+
+func loadCommentEntry(tx *sql.Tx, uuid string) (*snapshot.CommentEntry, error) {
+	var c snapshot.CommentEntry
+	var meta, updatedAt, deletedAt, deletedBy, parentUUID sql.NullString
+	err := tx.QueryRow(`
+		SELECT id, task_uuid, actor_uuid, body, meta, etag,
+		       created_at, updated_at, deleted_at, deleted_by_actor_uuid, parent_comment_uuid
+		FROM comments WHERE uuid = ?
+	`, uuid).Scan(&c.ID, &c.TaskUUID, &c.ActorUUID, &c.Body, &meta, &c.ETag,
+		&c.CreatedAt, &updatedAt, &deletedAt, &deletedBy, &parentUUID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load comment %s: %w", uuid, err)
+	}
+
+	c.Meta = meta.String
+	c.UpdatedAt = updatedAt.String
+	c.DeletedAt = deletedAt.String
+	c.DeletedBy = deletedBy.String
+	c.ParentUUID = parentUUID.String
+	return &c, nil
+}
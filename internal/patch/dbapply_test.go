@@ -0,0 +1,229 @@
+package patch
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/lherron/wrkq/internal/db"
+	"github.com/lherron/wrkq/internal/snapshot"
+)
+
+const (
+	dbTestActor     = "00000000-0000-0000-0000-00000000a001"
+	dbTestProject   = "00000000-0000-0000-0000-00000000b001"
+	dbTestTask      = "00000000-0000-0000-0000-00000000c001"
+	dbTestComment   = "00000000-0000-0000-0000-00000000d001"
+	dbTestNewTask   = "00000000-0000-0000-0000-00000000c002"
+	dbTestNewFolder = "00000000-0000-0000-0000-00000000b002"
+)
+
+func setupPatchDB(t *testing.T) *db.DB {
+	t.Helper()
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to migrate db: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	seed := []string{
+		`INSERT INTO actors (uuid, id, slug, role) VALUES ('` + dbTestActor + `', '', 'tester', 'human')`,
+		`INSERT INTO containers (uuid, id, slug, title, created_by_actor_uuid, updated_by_actor_uuid)
+		 VALUES ('` + dbTestProject + `', '', 'proj', 'Project', '` + dbTestActor + `', '` + dbTestActor + `')`,
+		`INSERT INTO tasks (uuid, id, slug, title, project_uuid, state, priority, description, created_by_actor_uuid, updated_by_actor_uuid)
+		 VALUES ('` + dbTestTask + `', '', 'existing', 'Existing', '` + dbTestProject + `', 'open', 3, 'body', '` + dbTestActor + `', '` + dbTestActor + `')`,
+		`INSERT INTO comments (uuid, id, task_uuid, actor_uuid, body) VALUES ('` + dbTestComment + `', 'C-00001', '` + dbTestTask + `', '` + dbTestActor + `', 'first')`,
+	}
+	for _, stmt := range seed {
+		if _, err := database.Exec(stmt); err != nil {
+			t.Fatalf("failed to seed db: %v", err)
+		}
+	}
+	return database
+}
+
+func loadTestTask(t *testing.T, database *db.DB, uuid string) *snapshot.TaskEntry {
+	t.Helper()
+	tx, err := database.Begin()
+	if err != nil {
+		t.Fatalf("failed to begin: %v", err)
+	}
+	defer tx.Rollback()
+	task, err := loadTaskEntry(tx, uuid)
+	if err != nil {
+		t.Fatalf("failed to load task: %v", err)
+	}
+	return task
+}
+
+func countEvents(t *testing.T, database *db.DB, eventType string) int {
+	t.Helper()
+	var n int
+	if err := database.QueryRow("SELECT COUNT(*) FROM event_log WHERE event_type = ?", eventType).Scan(&n); err != nil {
+		t.Fatalf("failed to count events: %v", err)
+	}
+	return n
+}
+
+func TestApplyToDatabase_Upsert(t *testing.T) {
+	database := setupPatchDB(t)
+	current := loadTestTask(t, database, dbTestTask)
+
+	updated := *current
+	updated.Title = "Renamed"
+	updated.Labels = []string{"b", "a"}
+	updated.ETag = current.ETag + 1
+
+	p := Patch{
+		{Op: "replace", Path: "/tasks/" + dbTestTask, Value: updated},
+		{Op: "add", Path: "/containers/" + dbTestNewFolder, Value: snapshot.ContainerEntry{
+			ID: "P-00002", Slug: "sub", Title: "Sub", ParentUUID: dbTestProject,
+		}},
+		{Op: "add", Path: "/tasks/" + dbTestNewTask, Value: snapshot.TaskEntry{
+			ID: "T-00001", Slug: "fresh", Title: "Fresh", ProjectUUID: dbTestNewFolder, State: "open", Priority: 2,
+		}},
+		{Op: "replace", Path: "/comments/" + dbTestComment + "/body", Value: "edited"},
+	}
+
+	result, err := ApplyToDatabase(database.DB, p, dbTestActor)
+	if err != nil {
+		t.Fatalf("ApplyToDatabase failed: %v", err)
+	}
+	if !result.Applied || result.Created != 2 || result.Updated != 2 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	task := loadTestTask(t, database, dbTestTask)
+	if task.Title != "Renamed" || task.ETag != current.ETag+1 {
+		t.Fatalf("expected renamed task at etag %d, got %q at %d", current.ETag+1, task.Title, task.ETag)
+	}
+	if len(task.Labels) != 2 || task.Labels[0] != "a" {
+		t.Fatalf("expected sorted labels, got %v", task.Labels)
+	}
+
+	// T-00001 is taken, so the new task must get a fresh friendly ID
+	fresh := loadTestTask(t, database, dbTestNewTask)
+	if fresh == nil || fresh.ID == "T-00001" || fresh.ID == "" {
+		t.Fatalf("expected new task with fresh ID, got %+v", fresh)
+	}
+
+	var body string
+	if err := database.QueryRow("SELECT body FROM comments WHERE uuid = ?", dbTestComment).Scan(&body); err != nil {
+		t.Fatalf("failed to read comment: %v", err)
+	}
+	if body != "edited" {
+		t.Fatalf("expected edited comment, got %q", body)
+	}
+
+	if countEvents(t, database, "task.updated") != 1 || countEvents(t, database, "task.created") != 1 ||
+		countEvents(t, database, "container.created") != 1 || countEvents(t, database, "comment.updated") != 1 {
+		t.Fatalf("expected one event per write")
+	}
+
+	// Re-applying the same patch is a no-op
+	result, err = ApplyToDatabase(database.DB, p[:2], dbTestActor)
+	if err != nil {
+		t.Fatalf("re-apply failed: %v", err)
+	}
+	if result.Unchanged != 2 || result.Updated != 0 || result.Created != 0 {
+		t.Fatalf("expected re-apply to be unchanged, got %+v", result)
+	}
+}
+
+func TestApplyToDatabase_RemoveSoftDeletes(t *testing.T) {
+	database := setupPatchDB(t)
+
+	p := Patch{
+		{Op: "remove", Path: "/comments/" + dbTestComment},
+		{Op: "remove", Path: "/tasks/" + dbTestTask},
+	}
+	result, err := ApplyToDatabase(database.DB, p, dbTestActor)
+	if err != nil {
+		t.Fatalf("ApplyToDatabase failed: %v", err)
+	}
+	if result.Deleted != 2 {
+		t.Fatalf("expected 2 deletes, got %+v", result)
+	}
+
+	task := loadTestTask(t, database, dbTestTask)
+	if task == nil || task.State != "deleted" {
+		t.Fatalf("expected task soft-deleted, got %+v", task)
+	}
+
+	var deletedAt, deletedBy string
+	if err := database.QueryRow("SELECT deleted_at, deleted_by_actor_uuid FROM comments WHERE uuid = ?", dbTestComment).Scan(&deletedAt, &deletedBy); err != nil {
+		t.Fatalf("expected comment row to remain: %v", err)
+	}
+	if deletedAt == "" || deletedBy != dbTestActor {
+		t.Fatalf("expected comment soft-deleted by actor, got %q by %q", deletedAt, deletedBy)
+	}
+}
+
+func TestApplyToDatabase_ConflictsRollBack(t *testing.T) {
+	database := setupPatchDB(t)
+	current := loadTestTask(t, database, dbTestTask)
+
+	stale := *current
+	stale.Title = "Stale write"
+
+	p := Patch{
+		{Op: "add", Path: "/tasks/" + dbTestNewTask, Value: snapshot.TaskEntry{
+			Slug: "fresh", Title: "Fresh", ProjectUUID: dbTestProject, State: "open", Priority: 2,
+		}},
+		{Op: "replace", Path: "/tasks/" + dbTestTask, Value: stale},
+		{Op: "test", Path: "/comments/" + dbTestComment + "/etag", Value: 7},
+	}
+
+	result, err := ApplyToDatabase(database.DB, p, dbTestActor)
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+	if result == nil || result.Applied || len(result.Conflicts) != 2 {
+		t.Fatalf("expected 2 conflicts, got %+v", result)
+	}
+	if c := result.Conflicts[0]; c.Reason != "etag_mismatch" || c.UUID != dbTestTask || c.ActualETag != current.ETag {
+		t.Fatalf("unexpected task conflict: %+v", c)
+	}
+	if c := result.Conflicts[1]; c.Reason != "etag_mismatch" || c.ExpectedETag != 7 || c.ActualETag != 1 {
+		t.Fatalf("unexpected comment conflict: %+v", c)
+	}
+
+	if loadTestTask(t, database, dbTestNewTask) != nil {
+		t.Fatal("expected insert to be rolled back")
+	}
+	if task := loadTestTask(t, database, dbTestTask); task.Title != "Existing" {
+		t.Fatalf("expected task unchanged, got %q", task.Title)
+	}
+}
+
+func TestApplyToDatabase_FieldLevel(t *testing.T) {
+	database := setupPatchDB(t)
+	current := loadTestTask(t, database, dbTestTask)
+
+	p := Patch{
+		{Op: "replace", Path: "/tasks/" + dbTestTask + "/state", Value: "in_progress"},
+		{Op: "remove", Path: "/tasks/" + dbTestTask + "/description"},
+		{Op: "replace", Path: "/tasks/" + dbTestTask + "/etag", Value: current.ETag + 1},
+	}
+	result, err := ApplyToDatabase(database.DB, p, dbTestActor)
+	if err != nil {
+		t.Fatalf("ApplyToDatabase failed: %v", err)
+	}
+	if result.Updated != 2 || result.Unchanged != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	task := loadTestTask(t, database, dbTestTask)
+	if task.State != "in_progress" || task.Description != "" {
+		t.Fatalf("expected field edits applied, got state=%q description=%q", task.State, task.Description)
+	}
+
+	// The same etag guard is now stale
+	_, err = ApplyToDatabase(database.DB, p[2:], dbTestActor)
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected stale etag to conflict, got %v", err)
+	}
+}
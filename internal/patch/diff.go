@@ -35,8 +35,14 @@ func Create(opts CreateOptions) (*CreateResult, error) {
 		return nil, fmt.Errorf("failed to parse target snapshot: %w", err)
 	}
 
+	// A partial target narrows the diff; a partial base cannot stand in for
+	// the full state it was exported from
+	if base.Meta.Partial != nil {
+		return nil, fmt.Errorf("base snapshot is partial; diff a partial target against a full base")
+	}
+
 	// Compute diff
-	patch := computeDiff(&base, &target)
+	patch := computeDiff(&base, &target, opts.FieldLevel)
 
 	// Normalize patch (remove redundant ops)
 	patch = normalizePatch(patch)
@@ -63,36 +69,76 @@ func Create(opts CreateOptions) (*CreateResult, error) {
 }
 
 // computeDiff generates RFC 6902 operations to transform base into target.
-func computeDiff(base, target *snapshot.Snapshot) Patch {
+// With fieldLevel, changed entities produce one op per changed field instead
+// of a whole-entity replace.
+//
+// A partial target (an incremental export) only describes entities that
+// changed, so the base is first narrowed to the entities the target contains
+// or lists as removed; everything else is treated as unchanged.
+func computeDiff(base, target *snapshot.Snapshot, fieldLevel bool) Patch {
 	var ops Patch
 
+	if target.Meta.Partial != nil {
+		base = narrowToPartial(base, target)
+	}
+
 	// Diff actors
 	ops = append(ops, diffMap("/actors", base.Actors, target.Actors,
-		func(v interface{}) interface{} { return v })...)
+		func(v interface{}) interface{} { return v }, fieldLevel)...)
 
 	// Diff containers
 	ops = append(ops, diffMap("/containers", base.Containers, target.Containers,
-		func(v interface{}) interface{} { return v })...)
+		func(v interface{}) interface{} { return v }, fieldLevel)...)
 
 	// Diff tasks
 	ops = append(ops, diffMap("/tasks", base.Tasks, target.Tasks,
-		func(v interface{}) interface{} { return v })...)
+		func(v interface{}) interface{} { return v }, fieldLevel)...)
 
 	// Diff comments
 	ops = append(ops, diffMap("/comments", base.Comments, target.Comments,
-		func(v interface{}) interface{} { return v })...)
+		func(v interface{}) interface{} { return v }, fieldLevel)...)
 
 	// Diff links
 	ops = append(ops, diffMap("/links", base.Links, target.Links,
-		func(v interface{}) interface{} { return v })...)
+		func(v interface{}) interface{} { return v }, fieldLevel)...)
 
 	// Note: we don't diff meta or events - those are regenerated
 
 	return ops
 }
 
+// narrowToPartial returns a view of base holding only the entities present
+// in the partial target or removed in its window.
+func narrowToPartial(base, target *snapshot.Snapshot) *snapshot.Snapshot {
+	removed := make(map[string]bool)
+	for _, uuids := range target.Meta.Partial.Removed {
+		for _, uuid := range uuids {
+			removed[uuid] = true
+		}
+	}
+
+	return &snapshot.Snapshot{
+		Meta:       base.Meta,
+		Actors:     narrowMap(base.Actors, target.Actors, nil),
+		Containers: narrowMap(base.Containers, target.Containers, removed),
+		Tasks:      narrowMap(base.Tasks, target.Tasks, removed),
+		Comments:   narrowMap(base.Comments, target.Comments, removed),
+		Links:      narrowMap(base.Links, target.Links, nil),
+	}
+}
+
+func narrowMap[T any](base, target map[string]T, removed map[string]bool) map[string]T {
+	narrowed := make(map[string]T)
+	for uuid, entry := range base {
+		if _, ok := target[uuid]; ok || removed[uuid] {
+			narrowed[uuid] = entry
+		}
+	}
+	return narrowed
+}
+
 // diffMap computes operations for a map-based collection (keyed by UUID).
-func diffMap[T any](basePath string, base, target map[string]T, normalize func(interface{}) interface{}) Patch {
+func diffMap[T any](basePath string, base, target map[string]T, normalize func(interface{}) interface{}, fieldLevel bool) Patch {
 	var ops Patch
 
 	// Collect all keys
@@ -131,7 +177,9 @@ func diffMap[T any](basePath string, base, target map[string]T, normalize func(i
 			})
 		} else if inBase && inTarget {
 			// Check for changes - compare as JSON for deep equality
-			if !deepEqual(baseVal, targetVal) {
+			if fieldLevel {
+				ops = append(ops, diffFields(path, baseVal, targetVal)...)
+			} else if !deepEqual(baseVal, targetVal) {
 				// Replace entire entity (simpler than field-level patches)
 				ops = append(ops, Operation{
 					Op:    "replace",
@@ -207,7 +255,14 @@ func normalizePatch(patch Patch) Patch {
 
 // DiffSnapshots computes a patch directly from snapshot structs.
 func DiffSnapshots(base, target *snapshot.Snapshot) Patch {
-	patch := computeDiff(base, target)
+	patch := computeDiff(base, target, false)
+	return normalizePatch(patch)
+}
+
+// DiffSnapshotFields computes a patch like DiffSnapshots, but emits
+// field-level ops (e.g. replace /tasks/<uuid>/state) for changed entities.
+func DiffSnapshotFields(base, target *snapshot.Snapshot) Patch {
+	patch := computeDiff(base, target, true)
 	return normalizePatch(patch)
 }
 
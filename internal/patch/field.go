@@ -0,0 +1,209 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// patchEntity applies an add, replace, or remove at a JSON Pointer path
+// inside a single entity (e.g. ["state"] or ["labels", "0"]) and returns the
+// updated entity. The first path token must name a field of T; removing a
+// top-level field is only allowed for optional (omitempty) fields.
+func patchEntity[T any](entry T, op string, path []string, value interface{}) (T, error) {
+	var zero T
+	if len(path) == 0 {
+		return zero, fmt.Errorf("empty field path")
+	}
+
+	optional, ok := entityField(reflect.TypeOf(entry), path[0])
+	if !ok {
+		return zero, fmt.Errorf("unknown field: %s", path[0])
+	}
+	if op == "remove" && len(path) == 1 && !optional {
+		return zero, fmt.Errorf("cannot remove required field: %s", path[0])
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return zero, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return zero, err
+	}
+
+	// Top-level fields always exist on the struct, even when omitted from JSON
+	if len(path) == 1 {
+		if op == "remove" {
+			delete(doc, path[0])
+		} else {
+			doc[path[0]] = value
+		}
+	} else {
+		child, ok := doc[path[0]]
+		if !ok {
+			return zero, fmt.Errorf("path not found: %s", path[0])
+		}
+		updated, err := setPointer(child, path[1:], op, value)
+		if err != nil {
+			return zero, fmt.Errorf("%s: %w", path[0], err)
+		}
+		doc[path[0]] = updated
+	}
+
+	data, err = json.Marshal(doc)
+	if err != nil {
+		return zero, err
+	}
+	var result T
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&result); err != nil {
+		return zero, fmt.Errorf("invalid value for %s: %w", strings.Join(path, "/"), err)
+	}
+	return result, nil
+}
+
+// setPointer applies op at path within a decoded JSON value, following
+// RFC 6902 semantics for objects and arrays ("-" appends on add).
+func setPointer(node interface{}, path []string, op string, value interface{}) (interface{}, error) {
+	token := path[0]
+	last := len(path) == 1
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, exists := n[token]
+		if last {
+			switch op {
+			case "add":
+				n[token] = value
+			case "replace":
+				if !exists {
+					return nil, fmt.Errorf("path not found: %s", token)
+				}
+				n[token] = value
+			case "remove":
+				if !exists {
+					return nil, fmt.Errorf("path not found: %s", token)
+				}
+				delete(n, token)
+			}
+			return n, nil
+		}
+		if !exists {
+			return nil, fmt.Errorf("path not found: %s", token)
+		}
+		updated, err := setPointer(child, path[1:], op, value)
+		if err != nil {
+			return nil, err
+		}
+		n[token] = updated
+		return n, nil
+
+	case []interface{}:
+		if last && op == "add" && token == "-" {
+			return append(n, value), nil
+		}
+		idx, err := strconv.Atoi(token)
+		if err != nil || idx < 0 {
+			return nil, fmt.Errorf("invalid array index: %s", token)
+		}
+		if last && op == "add" {
+			if idx > len(n) {
+				return nil, fmt.Errorf("array index out of range: %d", idx)
+			}
+			n = append(n, nil)
+			copy(n[idx+1:], n[idx:])
+			n[idx] = value
+			return n, nil
+		}
+		if idx >= len(n) {
+			return nil, fmt.Errorf("array index out of range: %d", idx)
+		}
+		if last {
+			if op == "remove" {
+				return append(n[:idx], n[idx+1:]...), nil
+			}
+			n[idx] = value
+			return n, nil
+		}
+		updated, err := setPointer(n[idx], path[1:], op, value)
+		if err != nil {
+			return nil, err
+		}
+		n[idx] = updated
+		return n, nil
+
+	default:
+		return nil, fmt.Errorf("cannot traverse into scalar at %s", token)
+	}
+}
+
+// entityField reports whether typ has a field with the given JSON name and
+// whether that field is optional (tagged omitempty).
+func entityField(typ reflect.Type, name string) (optional bool, ok bool) {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return false, false
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		tag := typ.Field(i).Tag.Get("json")
+		tagName, opts, _ := strings.Cut(tag, ",")
+		if tagName == name {
+			return strings.Contains(opts, "omitempty"), true
+		}
+	}
+	return false, false
+}
+
+// diffFields computes field-level operations that transform one entity into
+// another, emitting one op per changed top-level JSON field in sorted order.
+func diffFields(path string, base, target interface{}) Patch {
+	baseFields := toFieldMap(base)
+	targetFields := toFieldMap(target)
+
+	keys := make([]string, 0, len(baseFields)+len(targetFields))
+	for k := range baseFields {
+		keys = append(keys, k)
+	}
+	for k := range targetFields {
+		if _, ok := baseFields[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var ops Patch
+	for _, key := range keys {
+		fieldPath := path + "/" + escapeJSONPointer(key)
+		baseVal, inBase := baseFields[key]
+		targetVal, inTarget := targetFields[key]
+
+		switch {
+		case inBase && !inTarget:
+			ops = append(ops, Operation{Op: "remove", Path: fieldPath})
+		case !inBase && inTarget:
+			ops = append(ops, Operation{Op: "add", Path: fieldPath, Value: targetVal})
+		case !deepEqual(baseVal, targetVal):
+			ops = append(ops, Operation{Op: "replace", Path: fieldPath, Value: targetVal})
+		}
+	}
+	return ops
+}
+
+func toFieldMap(v interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fields
+	}
+	json.Unmarshal(data, &fields)
+	return fields
+}
@@ -0,0 +1,269 @@
+package patch
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lherron/wrkq/internal/snapshot"
+)
+
+// MergeConflict describes a path that both patches changed incompatibly.
+// A and B hold each side's operations on that path so callers can present
+// them for resolution.
+type MergeConflict struct {
+	Path   string      `json:"path"`
+	Reason string      `json:"reason"` // add_add, replace_replace, remove_modify
+	A      []Operation `json:"a"`
+	B      []Operation `json:"b"`
+}
+
+// MergeResult contains the result of a three-way patch merge.
+type MergeResult struct {
+	// Patch holds the cleanly merged operations (field-level for edits)
+	Patch        Patch                           `json:"patch"`
+	Conflicts    []MergeConflict                 `json:"conflicts,omitempty"`
+	CodeRewrites map[string]map[string]IDRewrite `json:"code_rewrites,omitempty"`
+}
+
+// Merge combines two patches derived from the same base into one patch.
+//
+// Both patches are applied to base and re-diffed field by field, so edits to
+// different fields of the same entity merge cleanly. Paths changed on both
+// sides to different values are reported as conflicts and left out of the
+// merged patch. New entities in b whose friendly IDs collide with IDs in
+// a's result are renumbered, as Rebase does.
+func Merge(base *snapshot.Snapshot, a, b Patch) (*MergeResult, error) {
+	stateA, err := ApplyToSnapshot(base, a)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply patch A: %w", err)
+	}
+	stateB, err := ApplyToSnapshot(base, b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply patch B: %w", err)
+	}
+
+	codeRewrites, err := renumberMergeCollisions(base, stateA, stateB)
+	if err != nil {
+		return nil, err
+	}
+
+	opsA := groupByEntity(DiffSnapshotFields(base, stateA))
+	opsB := groupByEntity(DiffSnapshotFields(base, stateB))
+
+	result := &MergeResult{Patch: Patch{}}
+	if len(codeRewrites) > 0 {
+		result.CodeRewrites = codeRewrites
+	}
+
+	for _, entity := range mergeEntityOrder(opsA, opsB) {
+		entityA, entityB := opsA[entity], opsB[entity]
+		switch {
+		case len(entityB) == 0:
+			result.Patch = append(result.Patch, entityA...)
+		case len(entityA) == 0:
+			result.Patch = append(result.Patch, entityB...)
+		default:
+			merged, conflicts := mergeEntityOps(entity, entityA, entityB)
+			result.Patch = append(result.Patch, merged...)
+			result.Conflicts = append(result.Conflicts, conflicts...)
+		}
+	}
+
+	// Sanity check: the clean part of the merge must apply to base
+	if _, err := ApplyToSnapshot(base, result.Patch); err != nil {
+		return nil, fmt.Errorf("merged patch does not apply to base: %w", err)
+	}
+
+	return result, nil
+}
+
+// renumberMergeCollisions renumbers b's new entities whose friendly IDs are
+// already used in a's result. Entities both sides added with the same UUID
+// are treated as pre-existing so they keep their IDs; they merge or conflict
+// as add/add.
+func renumberMergeCollisions(base, stateA, stateB *snapshot.Snapshot) (map[string]map[string]IDRewrite, error) {
+	known, err := copySnapshot(base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy snapshot: %w", err)
+	}
+	for uuid, actor := range stateA.Actors {
+		if _, ok := stateB.Actors[uuid]; ok {
+			known.Actors[uuid] = actor
+		}
+	}
+	for uuid, container := range stateA.Containers {
+		if _, ok := stateB.Containers[uuid]; ok {
+			known.Containers[uuid] = container
+		}
+	}
+	for uuid, task := range stateA.Tasks {
+		if _, ok := stateB.Tasks[uuid]; ok {
+			known.Tasks[uuid] = task
+		}
+	}
+	for uuid, comment := range stateA.Comments {
+		if _, ok := stateB.Comments[uuid]; ok {
+			known.Comments[uuid] = comment
+		}
+	}
+
+	codeRewrites := make(map[string]map[string]IDRewrite)
+	if rewrites, err := renumberActors(stateB, known, stateA, false); err != nil {
+		return nil, err
+	} else if len(rewrites) > 0 {
+		codeRewrites["actors"] = rewrites
+	}
+	if rewrites, err := renumberContainers(stateB, known, stateA, false); err != nil {
+		return nil, err
+	} else if len(rewrites) > 0 {
+		codeRewrites["containers"] = rewrites
+	}
+	if rewrites, err := renumberTasks(stateB, known, stateA, false); err != nil {
+		return nil, err
+	} else if len(rewrites) > 0 {
+		codeRewrites["tasks"] = rewrites
+	}
+	if rewrites, err := renumberComments(stateB, known, stateA, false); err != nil {
+		return nil, err
+	} else if len(rewrites) > 0 {
+		codeRewrites["comments"] = rewrites
+	}
+
+	return codeRewrites, nil
+}
+
+// groupByEntity buckets operations by their /<collection>/<uuid> prefix.
+func groupByEntity(p Patch) map[string]Patch {
+	groups := make(map[string]Patch)
+	for _, op := range p {
+		entity := entityPath(op.Path)
+		groups[entity] = append(groups[entity], op)
+	}
+	return groups
+}
+
+func entityPath(path string) string {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 3)
+	if len(parts) < 2 {
+		return path
+	}
+	return "/" + parts[0] + "/" + parts[1]
+}
+
+// mergeEntityOrder returns every touched entity path in the order computeDiff
+// emits collections, then by path.
+func mergeEntityOrder(opsA, opsB map[string]Patch) []string {
+	rank := map[string]int{"actors": 0, "containers": 1, "tasks": 2, "comments": 3, "links": 4}
+
+	seen := make(map[string]bool)
+	var entities []string
+	for _, groups := range []map[string]Patch{opsA, opsB} {
+		for entity := range groups {
+			if !seen[entity] {
+				seen[entity] = true
+				entities = append(entities, entity)
+			}
+		}
+	}
+
+	collection := func(entity string) int {
+		parts := parseJSONPointer(entity)
+		if r, ok := rank[parts[0]]; ok {
+			return r
+		}
+		return len(rank)
+	}
+	sort.Slice(entities, func(i, j int) bool {
+		ci, cj := collection(entities[i]), collection(entities[j])
+		if ci != cj {
+			return ci < cj
+		}
+		return entities[i] < entities[j]
+	})
+	return entities
+}
+
+// mergeFieldsResolved are bumped by every edit, so both sides changing them is
+// expected rather than a conflict: the merge keeps the later value.
+var mergeFieldsResolved = map[string]bool{"etag": true, "updated_at": true, "updated_by": true}
+
+// mergeEntityOps merges both sides' operations on a single entity.
+func mergeEntityOps(entity string, a, b Patch) (Patch, []MergeConflict) {
+	wholeA, wholeB := a[0].Path == entity, b[0].Path == entity
+
+	// Whole-entity ops (add/remove) on either side cannot be combined with
+	// anything but an identical op
+	if wholeA || wholeB {
+		if len(a) == len(b) && deepEqual(a, b) {
+			return a, nil
+		}
+		reason := "remove_modify"
+		if wholeA && wholeB && a[0].Op == "add" && b[0].Op == "add" {
+			reason = "add_add"
+		}
+		return nil, []MergeConflict{{Path: entity, Reason: reason, A: a, B: b}}
+	}
+
+	fieldsA := make(map[string]Operation, len(a))
+	for _, op := range a {
+		fieldsA[op.Path] = op
+	}
+	fieldsB := make(map[string]Operation, len(b))
+	for _, op := range b {
+		fieldsB[op.Path] = op
+	}
+
+	var merged Patch
+	var conflicts []MergeConflict
+	for _, op := range a {
+		other, ok := fieldsB[op.Path]
+		if !ok || deepEqual(op, other) {
+			merged = append(merged, op)
+			continue
+		}
+		if mergeFieldsResolved[strings.TrimPrefix(op.Path, entity+"/")] {
+			merged = append(merged, laterOp(op, other, fieldsA, fieldsB, entity))
+			continue
+		}
+		conflicts = append(conflicts, MergeConflict{
+			Path:   op.Path,
+			Reason: "replace_replace",
+			A:      []Operation{op},
+			B:      []Operation{other},
+		})
+	}
+	for _, op := range b {
+		if _, ok := fieldsA[op.Path]; !ok {
+			merged = append(merged, op)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Path < merged[j].Path })
+	return merged, conflicts
+}
+
+// laterOp picks the value for a bookkeeping field changed on both sides: the
+// higher etag, or the side with the later updated_at for the other fields.
+func laterOp(a, b Operation, fieldsA, fieldsB map[string]Operation, entity string) Operation {
+	if strings.HasSuffix(a.Path, "/etag") {
+		if toFloat(b.Value) > toFloat(a.Value) {
+			return b
+		}
+		return a
+	}
+
+	updatedA := fmt.Sprint(fieldsA[entity+"/updated_at"].Value)
+	updatedB := fmt.Sprint(fieldsB[entity+"/updated_at"].Value)
+	if updatedB > updatedA {
+		return b
+	}
+	return a
+}
+
+func toFloat(v interface{}) float64 {
+	if f, ok := v.(float64); ok {
+		return f
+	}
+	return 0
+}
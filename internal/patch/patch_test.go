@@ -255,6 +255,136 @@ func TestApplyToSnapshot_RemoveNotFound(t *testing.T) {
 	}
 }
 
+func fieldOpsBase() *snapshot.Snapshot {
+	return &snapshot.Snapshot{
+		Meta: snapshot.Meta{SchemaVersion: 1, MachineInterfaceVersion: 1},
+		Tasks: map[string]snapshot.TaskEntry{
+			"task-1": {ID: "T-00001", Slug: "task", Title: "Task", ProjectUUID: "container-1", State: "open", Priority: 2,
+				Labels: []string{"backend", "urgent"}, Description: "Some details", ETag: 1},
+		},
+	}
+}
+
+func TestApplyToSnapshot_FieldReplace(t *testing.T) {
+	p := Patch{
+		{Op: "replace", Path: "/tasks/task-1/state", Value: "completed"},
+		{Op: "replace", Path: "/tasks/task-1/labels/1", Value: "later"},
+		{Op: "add", Path: "/tasks/task-1/labels/-", Value: "frontend"},
+	}
+
+	result, err := ApplyToSnapshot(fieldOpsBase(), p)
+	if err != nil {
+		t.Fatalf("failed to apply patch: %v", err)
+	}
+
+	task := result.Tasks["task-1"]
+	if task.State != "completed" {
+		t.Errorf("expected state 'completed', got '%s'", task.State)
+	}
+	if len(task.Labels) != 3 || task.Labels[1] != "later" || task.Labels[2] != "frontend" {
+		t.Errorf("unexpected labels: %v", task.Labels)
+	}
+	if task.Title != "Task" {
+		t.Errorf("expected untouched fields to be preserved, got title '%s'", task.Title)
+	}
+}
+
+func TestApplyToSnapshot_FieldRemoveOptional(t *testing.T) {
+	p := Patch{
+		{Op: "remove", Path: "/tasks/task-1/description"},
+		{Op: "remove", Path: "/tasks/task-1/labels/0"},
+	}
+
+	result, err := ApplyToSnapshot(fieldOpsBase(), p)
+	if err != nil {
+		t.Fatalf("failed to apply patch: %v", err)
+	}
+
+	task := result.Tasks["task-1"]
+	if task.Description != "" {
+		t.Errorf("expected description removed, got '%s'", task.Description)
+	}
+	if len(task.Labels) != 1 || task.Labels[0] != "urgent" {
+		t.Errorf("unexpected labels: %v", task.Labels)
+	}
+
+	// Required fields cannot be removed
+	if _, err := ApplyToSnapshot(fieldOpsBase(), Patch{{Op: "remove", Path: "/tasks/task-1/title"}}); err == nil {
+		t.Error("expected error removing required field")
+	}
+}
+
+func TestApplyToSnapshot_FieldUnknown(t *testing.T) {
+	tests := []Operation{
+		{Op: "replace", Path: "/tasks/task-1/colour", Value: "red"},
+		{Op: "replace", Path: "/tasks/task-1/labels/5", Value: "x"},
+		{Op: "replace", Path: "/tasks/task-1/title/inner", Value: "x"},
+		{Op: "replace", Path: "/tasks/missing/state", Value: "open"},
+	}
+
+	for _, op := range tests {
+		if _, err := ApplyToSnapshot(fieldOpsBase(), Patch{op}); err == nil {
+			t.Errorf("expected error for %s %s", op.Op, op.Path)
+		}
+	}
+}
+
+func TestDiffSnapshotFields(t *testing.T) {
+	base := fieldOpsBase()
+	target, _ := ApplyToSnapshot(base, Patch{
+		{Op: "replace", Path: "/tasks/task-1/state", Value: "completed"},
+		{Op: "remove", Path: "/tasks/task-1/description"},
+	})
+
+	p := DiffSnapshotFields(base, target)
+	if len(p) != 2 {
+		t.Fatalf("expected 2 field ops, got %d: %+v", len(p), p)
+	}
+	if p[0].Op != "remove" || p[0].Path != "/tasks/task-1/description" {
+		t.Errorf("unexpected op: %+v", p[0])
+	}
+	if p[1].Op != "replace" || p[1].Path != "/tasks/task-1/state" || p[1].Value != "completed" {
+		t.Errorf("unexpected op: %+v", p[1])
+	}
+
+	// Applying the field-level patch reproduces the target
+	roundTrip, err := ApplyToSnapshot(base, p)
+	if err != nil {
+		t.Fatalf("failed to apply field-level patch: %v", err)
+	}
+	if !deepEqual(roundTrip.Tasks, target.Tasks) {
+		t.Errorf("round trip mismatch: %+v vs %+v", roundTrip.Tasks, target.Tasks)
+	}
+}
+
+func TestDiffSnapshots_PartialTarget(t *testing.T) {
+	base := fieldOpsBase()
+	base.Tasks["task-2"] = snapshot.TaskEntry{ID: "T-00002", Slug: "other", Title: "Other", ProjectUUID: "container-1", State: "open", ETag: 1}
+	base.Tasks["task-3"] = snapshot.TaskEntry{ID: "T-00003", Slug: "gone", Title: "Gone", ProjectUUID: "container-1", State: "open", ETag: 1}
+
+	// Only task-2 changed and task-3 was archived; task-1 is omitted
+	changed := base.Tasks["task-2"]
+	changed.Title = "Other (edited)"
+	target := &snapshot.Snapshot{
+		Meta: snapshot.Meta{SchemaVersion: 1, MachineInterfaceVersion: 1, Partial: &snapshot.Partial{
+			Since:   "2025-01-01T00:00:00Z",
+			Removed: map[string][]string{"tasks": {"task-3"}},
+		}},
+		Tasks: map[string]snapshot.TaskEntry{"task-2": changed},
+	}
+
+	p := DiffSnapshots(base, target)
+	if len(p) != 2 {
+		t.Fatalf("expected 2 ops, got %d: %+v", len(p), p)
+	}
+	if p[0].Op != "replace" || p[0].Path != "/tasks/task-2" {
+		t.Errorf("unexpected op: %+v", p[0])
+	}
+	if p[1].Op != "remove" || p[1].Path != "/tasks/task-3" {
+		t.Errorf("unexpected op: %+v", p[1])
+	}
+}
+
 func TestValidateSnapshot_Valid(t *testing.T) {
 	snap := &snapshot.Snapshot{
 		Meta: snapshot.Meta{SchemaVersion: 1, MachineInterfaceVersion: 1},
@@ -337,6 +467,46 @@ func TestValidateSnapshot_ContainerCycle(t *testing.T) {
 	}
 }
 
+func TestValidateSnapshot_CommentThreads(t *testing.T) {
+	base := func(comments map[string]snapshot.CommentEntry) *snapshot.Snapshot {
+		return &snapshot.Snapshot{
+			Meta: snapshot.Meta{SchemaVersion: 1, MachineInterfaceVersion: 1},
+			Actors: map[string]snapshot.ActorEntry{
+				"actor-1": {ID: "A-00001", Slug: "test", Role: "human", CreatedAt: "2025-01-01T00:00:00Z", UpdatedAt: "2025-01-01T00:00:00Z"},
+			},
+			Containers: map[string]snapshot.ContainerEntry{
+				"container-1": {ID: "P-00001", Slug: "proj", Title: "Project", CreatedBy: "actor-1", UpdatedBy: "actor-1", ETag: 1, CreatedAt: "2025-01-01T00:00:00Z", UpdatedAt: "2025-01-01T00:00:00Z"},
+			},
+			Tasks: map[string]snapshot.TaskEntry{
+				"task-1": {ID: "T-00001", Slug: "one", Title: "One", ProjectUUID: "container-1", State: "open", Priority: 2, CreatedBy: "actor-1", UpdatedBy: "actor-1", ETag: 1, CreatedAt: "2025-01-01T00:00:00Z", UpdatedAt: "2025-01-01T00:00:00Z"},
+				"task-2": {ID: "T-00002", Slug: "two", Title: "Two", ProjectUUID: "container-1", State: "open", Priority: 2, CreatedBy: "actor-1", UpdatedBy: "actor-1", ETag: 1, CreatedAt: "2025-01-01T00:00:00Z", UpdatedAt: "2025-01-01T00:00:00Z"},
+			},
+			Comments: comments,
+		}
+	}
+
+	if err := ValidateSnapshot(base(map[string]snapshot.CommentEntry{
+		"comment-1": {ID: "C-00001", TaskUUID: "task-1", ActorUUID: "actor-1", Body: "question", ETag: 1},
+		"comment-2": {ID: "C-00002", TaskUUID: "task-1", ActorUUID: "actor-1", Body: "answer", ETag: 1, ParentUUID: "comment-1"},
+	})); err != nil {
+		t.Errorf("expected reply on the same task to be valid, got: %v", err)
+	}
+
+	if err := ValidateSnapshot(base(map[string]snapshot.CommentEntry{
+		"comment-1": {ID: "C-00001", TaskUUID: "task-1", ActorUUID: "actor-1", Body: "question", ETag: 1},
+		"comment-2": {ID: "C-00002", TaskUUID: "task-2", ActorUUID: "actor-1", Body: "answer", ETag: 1, ParentUUID: "comment-1"},
+	})); err == nil {
+		t.Error("expected error for reply to a comment on another task")
+	}
+
+	if err := ValidateSnapshot(base(map[string]snapshot.CommentEntry{
+		"comment-1": {ID: "C-00001", TaskUUID: "task-1", ActorUUID: "actor-1", Body: "a", ETag: 1, ParentUUID: "comment-2"},
+		"comment-2": {ID: "C-00002", TaskUUID: "task-1", ActorUUID: "actor-1", Body: "b", ETag: 1, ParentUUID: "comment-1"},
+	})); err == nil {
+		t.Error("expected error for comment thread cycle")
+	}
+}
+
 func TestEscapeJSONPointer(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -584,6 +754,54 @@ func TestIncrementFriendlyID(t *testing.T) {
 			want:     "T-abc-2",
 			wantErr:  false,
 		},
+		{
+			name:     "widen past the original width",
+			id:       "T-99999",
+			existing: map[string]bool{"T-99999": true},
+			strict:   true,
+			want:     "T-100000",
+			wantErr:  false,
+		},
+		{
+			name:     "6-digit existing set",
+			id:       "T-00050",
+			existing: map[string]bool{"T-00050": true, "T-100000": true, "T-100041": true, "T-99999": true},
+			strict:   true,
+			want:     "T-100042",
+			wantErr:  false,
+		},
+		{
+			name:     "numbers beyond int range",
+			id:       "T-99999999999999999999",
+			existing: map[string]bool{"T-99999999999999999999": true},
+			strict:   true,
+			want:     "T-100000000000000000000",
+			wantErr:  false,
+		},
+		{
+			name:     "multi-dash prefix",
+			id:       "ATT-00009",
+			existing: map[string]bool{"ATT-00009": true, "T-00500": true},
+			strict:   true,
+			want:     "ATT-00010",
+			wantErr:  false,
+		},
+		{
+			name:     "fallback suffix continues instead of stacking",
+			id:       "T-abc-2",
+			existing: map[string]bool{"T-abc": true, "T-abc-2": true, "T-abc-5": true, "T-abc-x": true},
+			strict:   false,
+			want:     "T-abc-6",
+			wantErr:  false,
+		},
+		{
+			name:     "fallback suffix in strict mode",
+			id:       "T-abc-2",
+			existing: map[string]bool{},
+			strict:   true,
+			want:     "",
+			wantErr:  true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -789,6 +1007,132 @@ func TestRebase_WithCollision(t *testing.T) {
 	}
 }
 
+func mergeBase() *snapshot.Snapshot {
+	return &snapshot.Snapshot{
+		Meta: snapshot.Meta{SchemaVersion: 1, MachineInterfaceVersion: 1},
+		Containers: map[string]snapshot.ContainerEntry{
+			"container-1": {ID: "P-00001", Slug: "proj", Title: "Project", ETag: 1},
+		},
+		Tasks: map[string]snapshot.TaskEntry{
+			"task-1": {ID: "T-00001", Slug: "task", Title: "Task", ProjectUUID: "container-1", State: "open", Priority: 2, ETag: 1, UpdatedAt: "2025-01-01T00:00:00Z"},
+		},
+		Comments: map[string]snapshot.CommentEntry{},
+	}
+}
+
+func newTaskValue(id, slug, title string) map[string]interface{} {
+	return map[string]interface{}{
+		"id": id, "slug": slug, "title": title, "project_uuid": "container-1",
+		"state": "open", "priority": float64(2), "etag": float64(1),
+	}
+}
+
+func TestMerge_AddAdd(t *testing.T) {
+	a := Patch{{Op: "add", Path: "/tasks/task-a", Value: newTaskValue("T-00002", "from-a", "From A")}}
+	b := Patch{
+		{Op: "add", Path: "/tasks/task-b", Value: newTaskValue("T-00002", "from-b", "From B")},
+		{Op: "add", Path: "/tasks/task-same", Value: newTaskValue("T-00010", "shared", "Shared")},
+	}
+	a = append(a, b[1])
+
+	result, err := Merge(mergeBase(), a, b)
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	if len(result.Conflicts) != 0 {
+		t.Fatalf("expected no conflicts, got %+v", result.Conflicts)
+	}
+	if len(result.Patch) != 3 {
+		t.Fatalf("expected 3 merged ops, got %d: %+v", len(result.Patch), result.Patch)
+	}
+
+	// B's colliding ID is renumbered; the identical add is kept once
+	rewrite, ok := result.CodeRewrites["tasks"]["task-b"]
+	if !ok || rewrite.From != "T-00002" || rewrite.To != "T-00011" {
+		t.Fatalf("expected task-b renumbered from T-00002, got %+v", result.CodeRewrites)
+	}
+	if _, ok := result.CodeRewrites["tasks"]["task-same"]; ok {
+		t.Error("expected identical add not to be renumbered")
+	}
+
+	merged, err := ApplyToSnapshot(mergeBase(), result.Patch)
+	if err != nil {
+		t.Fatalf("failed to apply merged patch: %v", err)
+	}
+	if merged.Tasks["task-a"].ID != "T-00002" || merged.Tasks["task-b"].ID != "T-00011" {
+		t.Errorf("unexpected IDs: a=%s b=%s", merged.Tasks["task-a"].ID, merged.Tasks["task-b"].ID)
+	}
+}
+
+func TestMerge_AddAddConflict(t *testing.T) {
+	a := Patch{{Op: "add", Path: "/tasks/task-x", Value: newTaskValue("T-00002", "x", "Version A")}}
+	b := Patch{{Op: "add", Path: "/tasks/task-x", Value: newTaskValue("T-00002", "x", "Version B")}}
+
+	result, err := Merge(mergeBase(), a, b)
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	if len(result.Conflicts) != 1 || result.Conflicts[0].Reason != "add_add" || result.Conflicts[0].Path != "/tasks/task-x" {
+		t.Fatalf("expected add_add conflict, got %+v", result.Conflicts)
+	}
+	if len(result.Patch) != 0 {
+		t.Errorf("expected conflicting add left out, got %+v", result.Patch)
+	}
+}
+
+func TestMerge_ReplaceReplace(t *testing.T) {
+	a := Patch{
+		{Op: "replace", Path: "/tasks/task-1/title", Value: "Title A"},
+		{Op: "replace", Path: "/tasks/task-1/priority", Value: 1},
+		{Op: "replace", Path: "/tasks/task-1/etag", Value: 2},
+		{Op: "replace", Path: "/tasks/task-1/updated_at", Value: "2025-01-02T00:00:00Z"},
+	}
+	b := Patch{
+		{Op: "replace", Path: "/tasks/task-1/title", Value: "Title B"},
+		{Op: "replace", Path: "/tasks/task-1/state", Value: "completed"},
+		{Op: "replace", Path: "/tasks/task-1/etag", Value: 2},
+		{Op: "replace", Path: "/tasks/task-1/updated_at", Value: "2025-01-03T00:00:00Z"},
+	}
+
+	result, err := Merge(mergeBase(), a, b)
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	if len(result.Conflicts) != 1 {
+		t.Fatalf("expected 1 conflict, got %+v", result.Conflicts)
+	}
+	conflict := result.Conflicts[0]
+	if conflict.Path != "/tasks/task-1/title" || conflict.Reason != "replace_replace" ||
+		conflict.A[0].Value != "Title A" || conflict.B[0].Value != "Title B" {
+		t.Fatalf("unexpected conflict: %+v", conflict)
+	}
+
+	merged, err := ApplyToSnapshot(mergeBase(), result.Patch)
+	if err != nil {
+		t.Fatalf("failed to apply merged patch: %v", err)
+	}
+	task := merged.Tasks["task-1"]
+	if task.Priority != 1 || task.State != "completed" || task.Title != "Task" {
+		t.Errorf("expected non-conflicting edits merged, got %+v", task)
+	}
+	if task.UpdatedAt != "2025-01-03T00:00:00Z" || task.ETag != 2 {
+		t.Errorf("expected later bookkeeping values, got updated_at=%s etag=%d", task.UpdatedAt, task.ETag)
+	}
+}
+
+func TestMerge_RemoveModify(t *testing.T) {
+	a := Patch{{Op: "remove", Path: "/tasks/task-1"}}
+	b := Patch{{Op: "replace", Path: "/tasks/task-1/state", Value: "completed"}}
+
+	result, err := Merge(mergeBase(), a, b)
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	if len(result.Conflicts) != 1 || result.Conflicts[0].Reason != "remove_modify" {
+		t.Fatalf("expected remove_modify conflict, got %+v", result.Conflicts)
+	}
+}
+
 func TestSummarize_TextFormat(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -945,6 +1289,65 @@ func TestSummarize_WithBase(t *testing.T) {
 	}
 }
 
+func TestSummarize_FieldChanges(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	base := &snapshot.Snapshot{
+		Tasks: map[string]snapshot.TaskEntry{
+			"task-1": {ID: "T-00001", Slug: "one", Title: "One", State: "open", Priority: 3},
+			"task-2": {ID: "T-00002", Slug: "two", Title: "Two", State: "open", Priority: 3, Description: "line one\nline two\n"},
+		},
+	}
+	basePath := filepath.Join(tmpDir, "base.json")
+	baseData, _ := json.MarshalIndent(base, "", "  ")
+	os.WriteFile(basePath, baseData, 0644)
+
+	incoming := base.Tasks["task-2"]
+	incoming.Priority = 1
+	incoming.Description = "line one\nline 2\n"
+
+	p := Patch{
+		{Op: "replace", Path: "/tasks/task-1/state", Value: "completed"},
+		{Op: "replace", Path: "/tasks/task-2", Value: incoming},
+	}
+	patchPath := filepath.Join(tmpDir, "patch.json")
+	p.Save(patchPath)
+
+	result, err := Summarize(SummarizeOptions{
+		PatchPath: patchPath,
+		BasePath:  basePath,
+		Format:    "markdown",
+	})
+	if err != nil {
+		t.Fatalf("summarize failed: %v", err)
+	}
+	if len(result.Details) != 2 {
+		t.Fatalf("expected 2 details, got %d", len(result.Details))
+	}
+
+	stateDetail := result.Details[0]
+	if stateDetail.OldValue != "open" || stateDetail.NewValue != "completed" {
+		t.Errorf("expected state open → completed, got %q → %q", stateDetail.OldValue, stateDetail.NewValue)
+	}
+	if change, ok := stateDetail.Changes["state"]; !ok || change.Current != "open" || change.Incoming != "completed" {
+		t.Errorf("expected state change in details, got %+v", stateDetail.Changes)
+	}
+	if !contains(result.Summary, "state: open → completed") {
+		t.Errorf("expected markdown to show state change, got:\n%s", result.Summary)
+	}
+
+	wholeDetail := result.Details[1]
+	if _, ok := wholeDetail.Changes["priority"]; !ok || len(wholeDetail.Changes) != 2 {
+		t.Errorf("expected priority and description changes, got %+v", wholeDetail.Changes)
+	}
+	if !contains(wholeDetail.DescriptionDiff, "-line two") || !contains(wholeDetail.DescriptionDiff, "+line 2") {
+		t.Errorf("expected description diff, got:\n%s", wholeDetail.DescriptionDiff)
+	}
+	if !contains(result.Summary, "```diff") {
+		t.Error("expected description diff block in markdown")
+	}
+}
+
 func TestSummarize_EmptyPatch(t *testing.T) {
 	tmpDir := t.TempDir()
 
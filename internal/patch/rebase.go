@@ -3,10 +3,11 @@ package patch
 import (
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"os"
 	"regexp"
 	"sort"
-	"strconv"
+	"strings"
 
 	"github.com/lherron/wrkq/internal/snapshot"
 )
@@ -41,8 +42,13 @@ type IDRewrite struct {
 	To   string `json:"to"`
 }
 
-// friendlyIDPattern matches friendly IDs like T-00001, P-00123, etc.
-var friendlyIDPattern = regexp.MustCompile(`^([A-Z])-(\d+)$`)
+// friendlyIDPattern matches friendly IDs like T-00001, P-00123, etc. The
+// prefix may have several dash-separated parts, as in ATT-00001.
+var friendlyIDPattern = regexp.MustCompile(`^([A-Z]+(?:-[A-Z]+)*)-(\d+)$`)
+
+// fallbackSuffixPattern splits the numeric suffix off a malformed ID that
+// the non-strict fallback already renamed, such as T-abc-2.
+var fallbackSuffixPattern = regexp.MustCompile(`^(.+)-(\d+)$`)
 
 // Rebase rebases a patch from old-base to new-base, auto-renumbering colliding IDs.
 func Rebase(opts RebaseOptions) (*RebaseResult, error) {
@@ -301,53 +307,89 @@ func keysOf[T any](m map[string]T) []string {
 // incrementFriendlyID finds the next available friendly ID.
 // It parses the ID format (e.g., T-00050), finds max among existing,
 // and returns the next available (e.g., T-00051).
+//
+// The number keeps the original's zero-padded width and widens when it
+// needs more digits (T-99999 becomes T-100000); it is never truncated.
+// Numbers are compared by value, however long. A malformed ID is an error
+// when strict; otherwise it gets a numeric suffix one past the highest
+// suffix already used for the same base, so T-abc becomes T-abc-2 and
+// T-abc-2 becomes T-abc-3 rather than T-abc-2-2.
 func incrementFriendlyID(id string, existing map[string]bool, strict bool) (string, error) {
 	match := friendlyIDPattern.FindStringSubmatch(id)
 	if match == nil {
 		if strict {
 			return "", fmt.Errorf("malformed friendly ID: %s", id)
 		}
-		// Non-strict: append suffix
-		suffix := 2
-		newID := fmt.Sprintf("%s-%d", id, suffix)
-		for existing[newID] {
-			suffix++
-			newID = fmt.Sprintf("%s-%d", id, suffix)
-		}
-		return newID, nil
+		return nextFallbackID(id, existing), nil
 	}
 
 	prefix := match[1]
 	numStr := match[2]
 	width := len(numStr)
 
-	// Find max number among existing IDs with same prefix
-	maxNum := 0
+	// Find max number among existing IDs with same prefix, starting from
+	// the original ID's own number
+	maxNum, _ := new(big.Int).SetString(numStr, 10)
 	for existingID := range existing {
 		existingMatch := friendlyIDPattern.FindStringSubmatch(existingID)
 		if existingMatch != nil && existingMatch[1] == prefix {
-			num, err := strconv.Atoi(existingMatch[2])
-			if err == nil && num > maxNum {
+			if num, ok := new(big.Int).SetString(existingMatch[2], 10); ok && num.Cmp(maxNum) > 0 {
 				maxNum = num
 			}
 		}
 	}
 
-	// Also check the original ID's number
-	origNum, _ := strconv.Atoi(numStr)
-	if origNum > maxNum {
-		maxNum = origNum
-	}
-
 	// Generate new ID with incremented number
-	newNum := maxNum + 1
-	newID := fmt.Sprintf("%s-%0*d", prefix, width, newNum)
+	one := big.NewInt(1)
+	newNum := new(big.Int).Add(maxNum, one)
+	newID := formatFriendlyID(prefix, width, newNum)
 
 	// Safety check - ensure it's not taken
 	for existing[newID] {
-		newNum++
-		newID = fmt.Sprintf("%s-%0*d", prefix, width, newNum)
+		newNum.Add(newNum, one)
+		newID = formatFriendlyID(prefix, width, newNum)
 	}
 
 	return newID, nil
 }
+
+// formatFriendlyID renders prefix-num with num zero-padded to at least width
+// digits.
+func formatFriendlyID(prefix string, width int, num *big.Int) string {
+	digits := num.String()
+	if len(digits) < width {
+		digits = strings.Repeat("0", width-len(digits)) + digits
+	}
+	return prefix + "-" + digits
+}
+
+// nextFallbackID renames a malformed ID by giving it a numeric suffix past
+// every suffix existing already uses for the same base.
+func nextFallbackID(id string, existing map[string]bool) string {
+	base := id
+	maxSuffix := big.NewInt(1)
+	if match := fallbackSuffixPattern.FindStringSubmatch(id); match != nil {
+		base = match[1]
+		if num, _ := new(big.Int).SetString(match[2], 10); num.Cmp(maxSuffix) > 0 {
+			maxSuffix = num
+		}
+	}
+	for existingID := range existing {
+		rest, ok := strings.CutPrefix(existingID, base+"-")
+		if !ok || rest == "" || strings.Trim(rest, "0123456789") != "" {
+			continue
+		}
+		if num, ok := new(big.Int).SetString(rest, 10); ok && num.Sign() > 0 && num.Cmp(maxSuffix) > 0 {
+			maxSuffix = num
+		}
+	}
+
+	one := big.NewInt(1)
+	suffix := new(big.Int).Add(maxSuffix, one)
+	newID := base + "-" + suffix.String()
+	for existing[newID] {
+		suffix.Add(suffix, one)
+		newID = base + "-" + suffix.String()
+	}
+	return newID
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/lherron/wrkq/internal/snapshot"
+	"github.com/pmezard/go-difflib/difflib"
 )
 
 // SummarizeOptions configures patch summarize behavior.
@@ -56,8 +57,22 @@ type OpDetail struct {
 	Field     string `json:"field,omitempty"`
 	OldValue  string `json:"old_value,omitempty"`
 	NewValue  string `json:"new_value,omitempty"`
+	// Changes and DescriptionDiff are filled for task replaces when a base
+	// snapshot is available
+	Changes         map[string]FieldChange `json:"changes,omitempty"`
+	DescriptionDiff string                 `json:"description_diff,omitempty"`
 }
 
+// FieldChange is a single field's current (base) and incoming (patch) value.
+type FieldChange struct {
+	Current  interface{} `json:"current,omitempty"`
+	Incoming interface{} `json:"incoming,omitempty"`
+}
+
+// summarizedTaskFields are the task fields compared for replace operations,
+// in display order.
+var summarizedTaskFields = []string{"title", "state", "priority", "description"}
+
 // Summarize generates a human-friendly summary of a patch.
 func Summarize(opts SummarizeOptions) (*SummarizeResult, error) {
 	// Load patch
@@ -156,9 +171,69 @@ func processOperation(op Operation, base *snapshot.Snapshot) *OpDetail {
 	// Extract info from operation value
 	enrichFromValue(detail, op)
 
+	// Compare replaced task fields against the base
+	if base != nil && op.Op == "replace" && entityType == "tasks" {
+		if task, ok := base.Tasks[uuid]; ok {
+			enrichChanges(detail, task, op)
+		}
+	}
+
 	return detail
 }
 
+// enrichChanges fills the field-level changes between a base task and the
+// value a replace operation writes, for either a whole-entity or a single
+// field replace.
+func enrichChanges(detail *OpDetail, current snapshot.TaskEntry, op Operation) {
+	currentFields := toFieldMap(current)
+
+	incomingFields := make(map[string]interface{})
+	if detail.Field != "" {
+		if len(parseJSONPointer(op.Path)) != 3 {
+			return
+		}
+		incomingFields[detail.Field] = op.Value
+		if old, ok := currentFields[detail.Field]; ok {
+			detail.OldValue = fmt.Sprint(old)
+		}
+		if detail.NewValue == "" && op.Value != nil {
+			detail.NewValue = fmt.Sprint(op.Value)
+		}
+	} else {
+		incomingFields = toFieldMap(op.Value)
+	}
+
+	changes := make(map[string]FieldChange)
+	for _, field := range summarizedTaskFields {
+		incoming, ok := incomingFields[field]
+		if !ok && detail.Field != "" {
+			continue
+		}
+		if deepEqual(currentFields[field], incoming) {
+			continue
+		}
+		changes[field] = FieldChange{Current: currentFields[field], Incoming: incoming}
+	}
+	if len(changes) > 0 {
+		detail.Changes = changes
+	}
+
+	if change, ok := changes["description"]; ok {
+		currentDesc, _ := change.Current.(string)
+		incomingDesc, _ := change.Incoming.(string)
+		diff := difflib.UnifiedDiff{
+			A:        difflib.SplitLines(currentDesc),
+			B:        difflib.SplitLines(incomingDesc),
+			FromFile: "current",
+			ToFile:   "incoming",
+			Context:  3,
+		}
+		if diffText, err := difflib.GetUnifiedDiffString(diff); err == nil {
+			detail.DescriptionDiff = diffText
+		}
+	}
+}
+
 // singularEntity converts plural entity type to singular.
 func singularEntity(plural string) string {
 	switch plural {
@@ -377,6 +452,9 @@ func formatMarkdown(counts EntityCounts, details []OpDetail) string {
 		if d.Field != "" && d.NewValue != "" {
 			pathOrTitle = fmt.Sprintf("%s: `%s`", d.Field, d.NewValue)
 		}
+		if len(d.Changes) > 0 {
+			pathOrTitle = formatChanges(d.Changes)
+		}
 
 		// Escape pipe characters in table
 		pathOrTitle = strings.ReplaceAll(pathOrTitle, "|", "\\|")
@@ -384,5 +462,42 @@ func formatMarkdown(counts EntityCounts, details []OpDetail) string {
 		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n", entity, op, id, pathOrTitle))
 	}
 
+	// Description diffs don't fit in the table, so list them afterwards
+	for _, d := range details {
+		if d.DescriptionDiff == "" {
+			continue
+		}
+		id := d.ID
+		if id == "" {
+			id = d.UUID
+		}
+		sb.WriteString(fmt.Sprintf("\n### %s description\n\n```diff\n%s```\n", id, d.DescriptionDiff))
+	}
+
 	return sb.String()
 }
+
+// formatChanges renders field changes as "field: current → incoming",
+// summarizing descriptions rather than inlining them.
+func formatChanges(changes map[string]FieldChange) string {
+	var parts []string
+	for _, field := range summarizedTaskFields {
+		change, ok := changes[field]
+		if !ok {
+			continue
+		}
+		if field == "description" {
+			parts = append(parts, "description changed")
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s: %s → %s", field, formatChangeValue(change.Current), formatChangeValue(change.Incoming)))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func formatChangeValue(v interface{}) string {
+	if v == nil {
+		return "-"
+	}
+	return fmt.Sprint(v)
+}
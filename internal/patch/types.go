@@ -33,6 +33,8 @@ type CreateOptions struct {
 	OutputPath string
 	// AllowNonCanonical skips canonicalization check
 	AllowNonCanonical bool
+	// FieldLevel emits per-field ops for changed entities
+	FieldLevel bool
 }
 
 // ValidateOptions configures patch validation behavior.
@@ -84,6 +86,29 @@ type ApplyResult struct {
 	RemoveCount int    `json:"removes"`
 }
 
+// DatabaseApplyResult contains the result of ApplyToDatabase.
+type DatabaseApplyResult struct {
+	Applied   bool               `json:"applied"`
+	OpCount   int                `json:"ops"`
+	Created   int                `json:"created"`
+	Updated   int                `json:"updated"`
+	Deleted   int                `json:"deleted"`
+	Unchanged int                `json:"unchanged"`
+	Conflicts []DatabaseConflict `json:"conflicts,omitempty"`
+}
+
+// DatabaseConflict describes an operation that could not be applied because
+// the database diverged from the state the patch expects.
+type DatabaseConflict struct {
+	Op           string `json:"op"`
+	Path         string `json:"path"`
+	UUID         string `json:"uuid,omitempty"`
+	Reason       string `json:"reason"`
+	ExpectedETag int64  `json:"expected_etag,omitempty"`
+	ActualETag   int64  `json:"actual_etag,omitempty"`
+	Message      string `json:"message,omitempty"`
+}
+
 // LoadPatch reads and parses a patch file.
 func LoadPatch(path string) (Patch, error) {
 	data, err := os.ReadFile(path)
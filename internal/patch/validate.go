@@ -73,6 +73,13 @@ func validateSnapshotInvariants(snap *snapshot.Snapshot) []string {
 		if _, ok := snap.Actors[comment.ActorUUID]; !ok {
 			errors = append(errors, fmt.Sprintf("comment %s references unknown actor %s", uuid, comment.ActorUUID))
 		}
+		if comment.ParentUUID != "" {
+			if parent, ok := snap.Comments[comment.ParentUUID]; !ok {
+				errors = append(errors, fmt.Sprintf("comment %s replies to unknown comment %s", uuid, comment.ParentUUID))
+			} else if parent.TaskUUID != comment.TaskUUID {
+				errors = append(errors, fmt.Sprintf("comment %s replies to comment %s on another task", uuid, comment.ParentUUID))
+			}
+		}
 	}
 
 	// 3. FK constraints - containers with parent must reference valid parent
@@ -145,8 +152,9 @@ func validateSnapshotInvariants(snap *snapshot.Snapshot) []string {
 		commentIDs[comment.ID] = uuid
 	}
 
-	// 7. Container hierarchy is acyclic (no container can be its own ancestor)
+	// 7. Container hierarchy and comment threads are acyclic
 	errors = append(errors, checkContainerCycles(snap)...)
+	errors = append(errors, checkCommentCycles(snap)...)
 
 	// 8. Actors referenced by tasks must exist
 	for uuid, task := range snap.Tasks {
@@ -231,3 +239,25 @@ func ValidatePatchOps(p Patch) []string {
 
 	return errors
 }
+
+// checkCommentCycles detects comments that are (transitively) replies to
+// themselves.
+func checkCommentCycles(snap *snapshot.Snapshot) []string {
+	var errors []string
+
+	for uuid := range snap.Comments {
+		visited := make(map[string]bool)
+		current := uuid
+
+		for current != "" {
+			if visited[current] {
+				errors = append(errors, fmt.Sprintf("cycle detected in comment thread at %s", uuid))
+				break
+			}
+			visited[current] = true
+			current = snap.Comments[current].ParentUUID
+		}
+	}
+
+	return errors
+}
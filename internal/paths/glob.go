@@ -1,6 +1,7 @@
 package paths
 
 import (
+	"fmt"
 	"path/filepath"
 	"strings"
 )
@@ -68,6 +69,27 @@ func IsGlobPattern(s string) bool {
 	return strings.ContainsAny(s, "*?[")
 }
 
+// ValidateGlobSegment checks one segment of a path glob. A segment without
+// glob characters must be a valid slug; otherwise it may only contain slug
+// characters and the wildcards * and ?, and ** must stand alone.
+func ValidateGlobSegment(s string) error {
+	if !IsGlobPattern(s) {
+		return ValidateSlug(s)
+	}
+	if s == "**" {
+		return nil
+	}
+	if strings.Contains(s, "**") {
+		return fmt.Errorf("invalid glob segment %q: ** must be a whole segment", s)
+	}
+	for _, r := range s {
+		if !((r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '*' || r == '?') {
+			return fmt.Errorf("invalid glob segment %q: only [a-z0-9-], * and ? are allowed", s)
+		}
+	}
+	return nil
+}
+
 // GlobToSQLPattern converts a shell-style glob pattern to SQLite GLOB pattern
 // SQLite GLOB is case-sensitive and uses * and ? wildcards
 func GlobToSQLPattern(pattern string) string {
@@ -218,6 +218,19 @@ func TestIsGlobPattern(t *testing.T) {
 	}
 }
 
+func TestValidateGlobSegment(t *testing.T) {
+	for _, valid := range []string{"clients", "*", "**", "acme-*", "v?"} {
+		if err := ValidateGlobSegment(valid); err != nil {
+			t.Errorf("ValidateGlobSegment(%q) = %v, want nil", valid, err)
+		}
+	}
+	for _, invalid := range []string{"", "Clients", "a**", "[ab]", "*.txt", "..", "a b"} {
+		if err := ValidateGlobSegment(invalid); err == nil {
+			t.Errorf("ValidateGlobSegment(%q) = nil, want error", invalid)
+		}
+	}
+}
+
 // Benchmark glob matching
 func BenchmarkMatchGlob(b *testing.B) {
 	benchmarks := []struct {
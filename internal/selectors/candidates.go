@@ -0,0 +1,152 @@
+package selectors
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lherron/wrkq/internal/db"
+	"github.com/lherron/wrkq/internal/paths"
+)
+
+// Candidate is one resource a selector may refer to.
+type Candidate struct {
+	UUID       string `json:"uuid"`
+	FriendlyID string `json:"id"`
+	Path       string `json:"path"`
+}
+
+// AmbiguousError is returned when a selector matches more than one resource,
+// e.g. a bare task slug that exists in several projects.
+type AmbiguousError struct {
+	Type       Type
+	Selector   string
+	Candidates []Candidate
+}
+
+func (e *AmbiguousError) Error() string {
+	matches := make([]string, 0, len(e.Candidates))
+	for _, c := range e.Candidates {
+		matches = append(matches, fmt.Sprintf("%s (%s)", c.Path, c.FriendlyID))
+	}
+	return fmt.Sprintf("ambiguous %s selector %q, did you mean: %s", e.Type, e.Selector, strings.Join(matches, ", "))
+}
+
+// suggestionError adds a "did you mean" hint to a not-found error when the
+// selector matches exactly one resource by path suffix.
+func suggestionError(err error, candidate Candidate) error {
+	return fmt.Errorf("%w, did you mean: %s (%s)", err, candidate.Path, candidate.FriendlyID)
+}
+
+// ResolveTaskAll returns every task a selector may refer to, ordered by path.
+// Friendly IDs and UUIDs match at most one task. A path matches exactly (a
+// bare slug matches that slug in every root project); if nothing matches
+// exactly, tasks whose path ends with the given path are returned instead, so
+// "login" finds portal/auth/login. An empty result means nothing matched.
+func ResolveTaskAll(database *db.DB, selector string) ([]Candidate, error) {
+	parsed := Parse(selector)
+	if parsed.Type != TypeTask && parsed.Type != TypeAuto {
+		return nil, fmt.Errorf("expected task selector (t:), got %s selector", parsed.Type)
+	}
+	token := parsed.Token
+
+	if strings.HasPrefix(token, "T-") || isUUID(token) {
+		return queryCandidates(database, "SELECT uuid, id, path FROM v_task_paths WHERE id = ? OR uuid = ?", token, token)
+	}
+
+	path, segments, err := normalizePath(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []Candidate
+	if len(segments) == 1 {
+		candidates, err = queryCandidates(database, `
+			SELECT uuid, id, path FROM v_task_paths
+			WHERE slug = ? AND project_uuid IN (SELECT uuid FROM containers WHERE parent_uuid IS NULL)
+			ORDER BY path
+		`, path)
+	} else {
+		candidates, err = queryCandidates(database, "SELECT uuid, id, path FROM v_task_paths WHERE path = ?", path)
+	}
+	if err != nil || len(candidates) > 0 {
+		return candidates, err
+	}
+
+	return queryCandidates(database, `
+		SELECT uuid, id, path FROM v_task_paths
+		WHERE substr(path, -length('/' || ?1)) = '/' || ?1
+		ORDER BY path
+	`, path)
+}
+
+// ResolveContainerAll returns every container a selector may refer to,
+// ordered by path, with the same exact-then-suffix matching as
+// ResolveTaskAll.
+func ResolveContainerAll(database *db.DB, selector string) ([]Candidate, error) {
+	parsed := Parse(selector)
+	if parsed.Type == TypeComment {
+		return nil, fmt.Errorf("expected container selector, got comment selector (c:)")
+	}
+	if parsed.Type == TypeTask {
+		return nil, fmt.Errorf("expected container selector, got task selector (t:)")
+	}
+	token := parsed.Token
+
+	if strings.HasPrefix(token, "P-") || isUUID(token) {
+		return queryCandidates(database, "SELECT uuid, id, path FROM v_container_paths WHERE id = ? OR uuid = ?", token, token)
+	}
+
+	path, _, err := normalizePath(token)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := queryCandidates(database, "SELECT uuid, id, path FROM v_container_paths WHERE path = ?", path)
+	if err != nil || len(candidates) > 0 {
+		return candidates, err
+	}
+
+	return queryCandidates(database, `
+		SELECT uuid, id, path FROM v_container_paths
+		WHERE substr(path, -length('/' || ?1)) = '/' || ?1
+		ORDER BY path
+	`, path)
+}
+
+func isUUID(token string) bool {
+	return len(token) == 36 && strings.Count(token, "-") == 4
+}
+
+// normalizePath normalizes every segment of a slug path.
+func normalizePath(token string) (string, []string, error) {
+	segments := paths.SplitPath(token)
+	if len(segments) == 0 {
+		return "", nil, fmt.Errorf("invalid path: %s", token)
+	}
+	for i, segment := range segments {
+		slug, err := paths.NormalizeSlug(segment)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid slug %q: %w", segment, err)
+		}
+		segments[i] = slug
+	}
+	return paths.JoinPath(segments...), segments, nil
+}
+
+func queryCandidates(database *db.DB, query string, args ...interface{}) ([]Candidate, error) {
+	rows, err := database.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	candidates := []Candidate{}
+	for rows.Next() {
+		var c Candidate
+		if err := rows.Scan(&c.UUID, &c.FriendlyID, &c.Path); err != nil {
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
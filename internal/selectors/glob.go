@@ -0,0 +1,46 @@
+package selectors
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lherron/wrkq/internal/db"
+	"github.com/lherron/wrkq/internal/paths"
+)
+
+// ResolveContainersGlob returns the UUIDs of every container whose path
+// matches pattern, ordered by path. Wildcards work per segment: * and ? never
+// cross a "/", so "clients/*/active" matches clients/acme/active but not
+// clients/acme/eu/active; a whole "**" segment matches any number of
+// segments. Each segment is validated with paths.ValidateGlobSegment. An
+// empty result means nothing matched.
+func ResolveContainersGlob(database *db.DB, pattern string) ([]string, error) {
+	segments := paths.SplitPath(strings.ToLower(pattern))
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("invalid container pattern: %q", pattern)
+	}
+	for _, segment := range segments {
+		if err := paths.ValidateGlobSegment(segment); err != nil {
+			return nil, err
+		}
+	}
+	pattern = paths.JoinPath(segments...)
+
+	rows, err := database.Query("SELECT uuid, path FROM v_container_paths ORDER BY path")
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	defer rows.Close()
+
+	uuids := []string{}
+	for rows.Next() {
+		var uuid, path string
+		if err := rows.Scan(&uuid, &path); err != nil {
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+		if paths.MatchGlob(pattern, path) {
+			uuids = append(uuids, uuid)
+		}
+	}
+	return uuids, rows.Err()
+}
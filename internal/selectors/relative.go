@@ -0,0 +1,56 @@
+package selectors
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lherron/wrkq/internal/paths"
+)
+
+// IsRelative reports whether selector is a path relative to a base container
+// ("./x", "../x", "." or ".."), ignoring a t: prefix.
+func IsRelative(selector string) bool {
+	token := Parse(selector).Token
+	return token == "." || token == ".." ||
+		strings.HasPrefix(token, "./") || strings.HasPrefix(token, "../")
+}
+
+// ResolveRelative turns a relative selector into an absolute path selector by
+// joining it onto base, a container path: with base "portal/auth",
+// "./login" becomes "portal/auth/login" and "../api/spec" becomes
+// "portal/api/spec". A t: prefix is kept. Selectors that aren't relative
+// (full paths, friendly IDs, UUIDs) are returned unchanged, so callers can
+// apply this to every selector. ".." may pass through the root but the
+// result must name something below it: climbing above the root, or ending
+// at the root itself, is an error.
+func ResolveRelative(base, selector string) (string, error) {
+	if !IsRelative(selector) {
+		return selector, nil
+	}
+
+	parsed := Parse(selector)
+	segments := paths.SplitPath(base)
+	for _, part := range strings.Split(parsed.Token, "/") {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if len(segments) == 0 {
+				return "", fmt.Errorf("selector %q escapes above the root (base %q)", selector, base)
+			}
+			segments = segments[:len(segments)-1]
+		default:
+			segments = append(segments, part)
+		}
+	}
+
+	if len(segments) == 0 {
+		return "", fmt.Errorf("selector %q resolves to the root (base %q), not a container or task", selector, base)
+	}
+
+	resolved := paths.JoinPath(segments...)
+	if parsed.Type == TypeTask {
+		resolved = "t:" + resolved
+	}
+	return resolved, nil
+}
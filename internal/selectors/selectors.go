@@ -164,6 +164,11 @@ func ResolveTaskByPath(database *db.DB, path string) (string, string, error) {
 		parentPath := paths.JoinPath(segments[:len(segments)-1]...)
 		uuid, _, err := WalkContainerPath(database, parentPath)
 		if err != nil {
+			if candidates, cerr := ResolveTaskAll(database, path); cerr == nil && len(candidates) > 1 {
+				return "", "", &AmbiguousError{Type: TypeTask, Selector: path, Candidates: candidates}
+			} else if cerr == nil && len(candidates) == 1 {
+				return "", "", suggestionError(err, candidates[0])
+			}
 			return "", "", err
 		}
 		parentUUID = &uuid
@@ -191,13 +196,27 @@ func ResolveTaskByPath(database *db.DB, path string) (string, string, error) {
 		`, normalizedSlug, *parentUUID).Scan(&taskUUID, &friendlyID)
 	}
 
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return "", "", fmt.Errorf("task not found: %s", path)
-		}
+	if err != nil && err != sql.ErrNoRows {
 		return "", "", fmt.Errorf("database error: %w", err)
 	}
 
+	// A bare slug can exist in several root projects, and a partial path can
+	// match in several places; report them instead of picking one
+	var candidates []Candidate
+	if err == sql.ErrNoRows || parentUUID == nil {
+		var cerr error
+		if candidates, cerr = ResolveTaskAll(database, path); cerr == nil && len(candidates) > 1 {
+			return "", "", &AmbiguousError{Type: TypeTask, Selector: path, Candidates: candidates}
+		}
+	}
+	if err == sql.ErrNoRows {
+		notFound := fmt.Errorf("task not found: %s", path)
+		if len(candidates) == 1 {
+			return "", "", suggestionError(notFound, candidates[0])
+		}
+		return "", "", notFound
+	}
+
 	return taskUUID, friendlyID, nil
 }
 
@@ -334,7 +353,16 @@ func ResolveContainer(database *db.DB, selector string) (string, string, error)
 		return "", "", fmt.Errorf("invalid path: %s", token)
 	}
 
-	return WalkContainerPath(database, token)
+	uuid, friendlyID, err := WalkContainerPath(database, token)
+	if err != nil {
+		// A partial path (e.g. "auth" for portal/auth) may match in several places
+		if candidates, cerr := ResolveContainerAll(database, token); cerr == nil && len(candidates) > 1 {
+			return "", "", &AmbiguousError{Type: TypeContainer, Selector: token, Candidates: candidates}
+		} else if cerr == nil && len(candidates) == 1 {
+			return "", "", suggestionError(err, candidates[0])
+		}
+	}
+	return uuid, friendlyID, err
 }
 
 // ResolveComment resolves a comment selector to its UUID
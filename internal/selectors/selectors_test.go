@@ -1,7 +1,14 @@
 package selectors
 
 import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/lherron/wrkq/internal/db"
 )
 
 func TestParse(t *testing.T) {
@@ -57,3 +64,240 @@ func TestParseEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+// setupAmbiguousDB creates portal/login and admin/login (the same task slug
+// in two root projects) plus portal/auth/reset and admin/auth/reset.
+func setupAmbiguousDB(t *testing.T) *db.DB {
+	t.Helper()
+	database, err := db.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	stmts := []string{
+		`INSERT INTO actors (uuid, id, slug, role) VALUES ('actor', 'A-00001', 'tester', 'human')`,
+		`INSERT INTO containers (uuid, id, slug, title, created_by_actor_uuid, updated_by_actor_uuid)
+		 VALUES ('portal', 'P-00001', 'portal', 'Portal', 'actor', 'actor')`,
+		`INSERT INTO containers (uuid, id, slug, title, created_by_actor_uuid, updated_by_actor_uuid)
+		 VALUES ('admin', 'P-00002', 'admin', 'Admin', 'actor', 'actor')`,
+		`INSERT INTO containers (uuid, id, slug, title, parent_uuid, created_by_actor_uuid, updated_by_actor_uuid)
+		 VALUES ('portal-auth', 'P-00003', 'auth', 'Auth', 'portal', 'actor', 'actor')`,
+		`INSERT INTO containers (uuid, id, slug, title, parent_uuid, created_by_actor_uuid, updated_by_actor_uuid)
+		 VALUES ('admin-auth', 'P-00004', 'auth', 'Auth', 'admin', 'actor', 'actor')`,
+	}
+	tasks := []struct{ uuid, id, slug, project string }{
+		{"t-portal-login", "T-00001", "login", "portal"},
+		{"t-admin-login", "T-00002", "login", "admin"},
+		{"t-portal-reset", "T-00003", "reset", "portal-auth"},
+		{"t-admin-reset", "T-00004", "reset", "admin-auth"},
+	}
+	for _, task := range tasks {
+		stmts = append(stmts, fmt.Sprintf(`INSERT INTO tasks (uuid, id, slug, title, project_uuid, state, priority, created_by_actor_uuid, updated_by_actor_uuid)
+		 VALUES ('%s', '%s', '%s', 'Task', '%s', 'open', 3, 'actor', 'actor')`, task.uuid, task.id, task.slug, task.project))
+	}
+	for _, stmt := range stmts {
+		if _, err := database.Exec(stmt); err != nil {
+			t.Fatalf("failed to seed: %v", err)
+		}
+	}
+	return database
+}
+
+func candidatePaths(candidates []Candidate) []string {
+	out := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		out = append(out, c.Path)
+	}
+	return out
+}
+
+func TestResolveTaskAll(t *testing.T) {
+	database := setupAmbiguousDB(t)
+
+	tests := []struct {
+		selector string
+		want     []string
+	}{
+		{"login", []string{"admin/login", "portal/login"}},
+		{"portal/login", []string{"portal/login"}},
+		{"auth/reset", []string{"admin/auth/reset", "portal/auth/reset"}},
+		{"reset", []string{"admin/auth/reset", "portal/auth/reset"}},
+		{"T-00003", []string{"portal/auth/reset"}},
+		{"t:admin/auth/reset", []string{"admin/auth/reset"}},
+		{"missing", []string{}},
+	}
+	for _, tt := range tests {
+		candidates, err := ResolveTaskAll(database, tt.selector)
+		if err != nil {
+			t.Fatalf("ResolveTaskAll(%q) failed: %v", tt.selector, err)
+		}
+		if got := candidatePaths(candidates); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ResolveTaskAll(%q) = %v, want %v", tt.selector, got, tt.want)
+		}
+	}
+}
+
+func TestResolveContainerAll(t *testing.T) {
+	database := setupAmbiguousDB(t)
+
+	candidates, err := ResolveContainerAll(database, "auth")
+	if err != nil {
+		t.Fatalf("ResolveContainerAll failed: %v", err)
+	}
+	if got := candidatePaths(candidates); !reflect.DeepEqual(got, []string{"admin/auth", "portal/auth"}) {
+		t.Errorf("unexpected candidates: %v", got)
+	}
+
+	candidates, err = ResolveContainerAll(database, "portal/auth")
+	if err != nil {
+		t.Fatalf("ResolveContainerAll failed: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].FriendlyID != "P-00003" {
+		t.Errorf("expected exact match P-00003, got %+v", candidates)
+	}
+}
+
+func TestResolveReportsAmbiguity(t *testing.T) {
+	database := setupAmbiguousDB(t)
+
+	// A bare slug in two root projects no longer resolves to an arbitrary one
+	_, _, err := ResolveTask(database, "login")
+	var ambiguous *AmbiguousError
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("expected AmbiguousError, got %v", err)
+	}
+	if got := candidatePaths(ambiguous.Candidates); !reflect.DeepEqual(got, []string{"admin/login", "portal/login"}) {
+		t.Errorf("unexpected candidates: %v", got)
+	}
+	if !strings.Contains(err.Error(), "did you mean: admin/login (T-00002), portal/login (T-00001)") {
+		t.Errorf("unexpected message: %v", err)
+	}
+
+	if _, _, err := ResolveTask(database, "auth/reset"); !errors.As(err, &ambiguous) {
+		t.Errorf("expected AmbiguousError for partial path, got %v", err)
+	}
+	if _, _, err := ResolveContainer(database, "auth"); !errors.As(err, &ambiguous) {
+		t.Errorf("expected AmbiguousError for container, got %v", err)
+	}
+
+	// Unambiguous selectors still resolve
+	if uuid, _, err := ResolveTask(database, "portal/login"); err != nil || uuid != "t-portal-login" {
+		t.Errorf("expected portal/login to resolve, got %q %v", uuid, err)
+	}
+	if _, _, err := ResolveTask(database, "missing"); err == nil || errors.As(err, &ambiguous) || strings.Contains(err.Error(), "did you mean") {
+		t.Errorf("expected plain not-found error, got %v", err)
+	}
+}
+
+func TestResolveSuggestsSingleCandidate(t *testing.T) {
+	database := setupAmbiguousDB(t)
+	for _, stmt := range []string{
+		`INSERT INTO containers (uuid, id, slug, title, parent_uuid, created_by_actor_uuid, updated_by_actor_uuid)
+		 VALUES ('admin-billing', 'P-00005', 'billing', 'Billing', 'admin', 'actor', 'actor')`,
+		`INSERT INTO tasks (uuid, id, slug, title, project_uuid, state, priority, created_by_actor_uuid, updated_by_actor_uuid)
+		 VALUES ('t-portal-logout', 'T-00005', 'logout', 'Task', 'portal-auth', 'open', 3, 'actor', 'actor')`,
+	} {
+		if _, err := database.Exec(stmt); err != nil {
+			t.Fatalf("failed to seed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		resolve func() error
+		want    string
+	}{
+		{"bare task slug", func() error { _, _, err := ResolveTask(database, "logout"); return err }, "did you mean: portal/auth/logout (T-00005)"},
+		{"partial task path", func() error { _, _, err := ResolveTask(database, "auth/logout"); return err }, "did you mean: portal/auth/logout (T-00005)"},
+		{"container", func() error { _, _, err := ResolveContainer(database, "billing"); return err }, "did you mean: admin/billing (P-00005)"},
+	}
+	for _, tt := range tests {
+		err := tt.resolve()
+		var ambiguous *AmbiguousError
+		if err == nil || errors.As(err, &ambiguous) {
+			t.Errorf("%s: expected a not-found error, got %v", tt.name, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected %q in %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestResolveRelative(t *testing.T) {
+	tests := []struct {
+		base     string
+		selector string
+		want     string
+	}{
+		{"portal/auth", "./login", "portal/auth/login"},
+		{"portal/auth", "../api/spec", "portal/api/spec"},
+		{"portal/auth", "..", "portal"},
+		{"portal/auth", ".", "portal/auth"},
+		{"portal/auth", "t:./login", "t:portal/auth/login"},
+		{"portal", "../inbox/task", "inbox/task"},
+		{"portal/auth", "inbox/task", "inbox/task"},
+		{"portal/auth", "T-00001", "T-00001"},
+		{"", "./inbox", "inbox"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.base+"|"+tt.selector, func(t *testing.T) {
+			got, err := ResolveRelative(tt.base, tt.selector)
+			if err != nil {
+				t.Fatalf("ResolveRelative(%q, %q) failed: %v", tt.base, tt.selector, err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveRelative(%q, %q) = %q, want %q", tt.base, tt.selector, got, tt.want)
+			}
+		})
+	}
+
+	for _, tt := range []struct{ base, selector, wantErr string }{
+		{"portal", "../../x", "escapes above the root"},
+		{"", "../x", "escapes above the root"},
+		{"portal", "..", "resolves to the root"},
+	} {
+		_, err := ResolveRelative(tt.base, tt.selector)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ResolveRelative(%q, %q) error = %v, want %q", tt.base, tt.selector, err, tt.wantErr)
+		}
+	}
+}
+
+func TestResolveContainersGlob(t *testing.T) {
+	database := setupAmbiguousDB(t)
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"*", []string{"admin", "portal"}},
+		{"*/auth", []string{"admin-auth", "portal-auth"}},
+		{"port*/auth", []string{"portal-auth"}},
+		{"*/*", []string{"admin-auth", "portal-auth"}},
+		{"**/auth", []string{"admin-auth", "portal-auth"}},
+		{"portal/**", []string{"portal", "portal-auth"}},
+		{"*/missing", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			got, err := ResolveContainersGlob(database, tt.pattern)
+			if err != nil {
+				t.Fatalf("ResolveContainersGlob(%q) failed: %v", tt.pattern, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveContainersGlob(%q) = %v, want %v", tt.pattern, got, tt.want)
+			}
+		})
+	}
+
+	for _, bad := range []string{"", "*/../x", "a**/b", "[ab]"} {
+		if _, err := ResolveContainersGlob(database, bad); err == nil {
+			t.Errorf("ResolveContainersGlob(%q) succeeded, want error", bad)
+		}
+	}
+}
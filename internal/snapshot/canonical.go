@@ -130,6 +130,9 @@ func buildOrderedMeta(m *Meta) orderedMap {
 		result = append(result, keyValue{"generated_at", m.GeneratedAt})
 	}
 	result = append(result, keyValue{"machine_interface_version", m.MachineInterfaceVersion})
+	if m.Partial != nil {
+		result = append(result, keyValue{"partial", m.Partial})
+	}
 	result = append(result, keyValue{"schema_version", m.SchemaVersion})
 	if m.SnapshotRev != "" {
 		result = append(result, keyValue{"snapshot_rev", m.SnapshotRev})
@@ -75,6 +75,12 @@ func Export(db *sql.DB, opts ExportOptions) (*ExportResult, error) {
 		LinkCount:      len(snap.Links),
 		EventCount:     len(snap.Events),
 	}
+	if snap.Meta.Partial != nil {
+		result.Partial = true
+		for _, uuids := range snap.Meta.Partial.Removed {
+			result.RemovedCount += len(uuids)
+		}
+	}
 
 	return result, nil
 }
@@ -121,6 +127,11 @@ func ExportToSnapshot(db *sql.DB, opts ExportOptions) (*Snapshot, []byte, error)
 }
 
 func buildSnapshot(db *sql.DB, opts ExportOptions) (*Snapshot, error) {
+	filter, err := newChangeFilter(opts)
+	if err != nil {
+		return nil, err
+	}
+
 	snap := &Snapshot{
 		Meta: Meta{
 			SchemaVersion:           1,
@@ -135,28 +146,37 @@ func buildSnapshot(db *sql.DB, opts ExportOptions) (*Snapshot, error) {
 	}
 
 	// Export actors
-	if err := exportActors(db, snap); err != nil {
+	if err := exportActors(db, snap, filter); err != nil {
 		return nil, fmt.Errorf("failed to export actors: %w", err)
 	}
 
 	// Export containers
-	if err := exportContainers(db, snap); err != nil {
+	if err := exportContainers(db, snap, filter); err != nil {
 		return nil, fmt.Errorf("failed to export containers: %w", err)
 	}
 
 	// Export tasks
-	if err := exportTasks(db, snap); err != nil {
+	if err := exportTasks(db, snap, filter); err != nil {
 		return nil, fmt.Errorf("failed to export tasks: %w", err)
 	}
 
 	// Export comments
-	if err := exportComments(db, snap); err != nil {
+	if err := exportComments(db, snap, filter); err != nil {
 		return nil, fmt.Errorf("failed to export comments: %w", err)
 	}
 
+	// Record the change window for incremental exports
+	if filter != nil {
+		partial, err := exportPartial(db, filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export removals: %w", err)
+		}
+		snap.Meta.Partial = partial
+	}
+
 	// Export events if requested
 	if opts.IncludeEvents {
-		if err := exportEvents(db, snap); err != nil {
+		if err := exportEvents(db, snap, filter); err != nil {
 			return nil, fmt.Errorf("failed to export events: %w", err)
 		}
 	}
@@ -164,12 +184,107 @@ func buildSnapshot(db *sql.DB, opts ExportOptions) (*Snapshot, error) {
 	return snap, nil
 }
 
-func exportActors(db *sql.DB, snap *Snapshot) error {
+// changeFilter narrows an incremental export to the entities changed since
+// a timestamp or since an event_log id. A nil filter exports everything.
+type changeFilter struct {
+	since      string
+	sinceEvent int64
+}
+
+func newChangeFilter(opts ExportOptions) (*changeFilter, error) {
+	if opts.Since == "" && opts.SinceEvent == 0 {
+		return nil, nil
+	}
+	if opts.Since != "" && opts.SinceEvent != 0 {
+		return nil, fmt.Errorf("since and since-event are mutually exclusive")
+	}
+	if opts.SinceEvent < 0 {
+		return nil, fmt.Errorf("invalid since-event: %d", opts.SinceEvent)
+	}
+	if opts.Since != "" {
+		if _, err := ParseTimestamp(opts.Since); err != nil {
+			return nil, fmt.Errorf("invalid since timestamp %q (expected YYYY-MM-DDTHH:MM:SSZ)", opts.Since)
+		}
+	}
+	return &changeFilter{since: opts.Since, sinceEvent: opts.SinceEvent}, nil
+}
+
+// clause returns an SQL condition selecting rows of resourceType that changed
+// in the window. timeExpr is the column holding the row's change time.
+func (f *changeFilter) clause(resourceType, timeExpr string) (string, []interface{}) {
+	if f == nil {
+		return "1 = 1", nil
+	}
+	if f.since != "" {
+		// Columns mix RFC3339 and datetime('now') text, so compare as
+		// julian days rather than strings
+		return "julianday(" + timeExpr + ") >= julianday(?)", []interface{}{f.since}
+	}
+	return "uuid IN (SELECT resource_uuid FROM event_log WHERE id > ? AND resource_type = ?)",
+		[]interface{}{f.sinceEvent, resourceType}
+}
+
+// exportPartial builds the partial metadata: the window, the event_log
+// high-water mark, and the entities archived or deleted in the window.
+func exportPartial(db *sql.DB, f *changeFilter) (*Partial, error) {
+	partial := &Partial{
+		Since:      f.since,
+		SinceEvent: f.sinceEvent,
+	}
+
+	if err := db.QueryRow("SELECT COALESCE(MAX(id), 0) FROM event_log").Scan(&partial.LastEvent); err != nil {
+		return nil, err
+	}
+
+	removals := []struct {
+		collection   string
+		table        string
+		resourceType string
+		removedCol   string
+	}{
+		{"containers", "containers", "container", "archived_at"},
+		{"tasks", "tasks", "task", "archived_at"},
+		{"comments", "comments", "comment", "deleted_at"},
+	}
+
+	for _, r := range removals {
+		cond, args := f.clause(r.resourceType, r.removedCol)
+		rows, err := db.Query(`SELECT uuid FROM `+r.table+` WHERE `+r.removedCol+` IS NOT NULL AND `+cond+` ORDER BY uuid`, args...)
+		if err != nil {
+			return nil, err
+		}
+		var uuids []string
+		for rows.Next() {
+			var uuid string
+			if err := rows.Scan(&uuid); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			uuids = append(uuids, uuid)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		if len(uuids) > 0 {
+			if partial.Removed == nil {
+				partial.Removed = make(map[string][]string)
+			}
+			partial.Removed[r.collection] = uuids
+		}
+	}
+
+	return partial, nil
+}
+
+func exportActors(db *sql.DB, snap *Snapshot, filter *changeFilter) error {
+	cond, args := filter.clause("actor", "updated_at")
 	rows, err := db.Query(`
 		SELECT uuid, id, slug, display_name, role, meta, created_at, updated_at
 		FROM actors
+		WHERE `+cond+`
 		ORDER BY uuid
-	`)
+	`, args...)
 	if err != nil {
 		return err
 	}
@@ -204,15 +319,16 @@ func exportActors(db *sql.DB, snap *Snapshot) error {
 	return rows.Err()
 }
 
-func exportContainers(db *sql.DB, snap *Snapshot) error {
+func exportContainers(db *sql.DB, snap *Snapshot, filter *changeFilter) error {
+	cond, args := filter.clause("container", "updated_at")
 	rows, err := db.Query(`
 		SELECT uuid, id, slug, title, parent_uuid, etag,
 		       created_at, updated_at, archived_at,
 		       created_by_actor_uuid, updated_by_actor_uuid
 		FROM containers
-		WHERE archived_at IS NULL
+		WHERE archived_at IS NULL AND `+cond+`
 		ORDER BY uuid
-	`)
+	`, args...)
 	if err != nil {
 		return err
 	}
@@ -254,7 +370,8 @@ func exportContainers(db *sql.DB, snap *Snapshot) error {
 	return rows.Err()
 }
 
-func exportTasks(db *sql.DB, snap *Snapshot) error {
+func exportTasks(db *sql.DB, snap *Snapshot, filter *changeFilter) error {
+	cond, args := filter.clause("task", "updated_at")
 	rows, err := db.Query(`
 		SELECT uuid, id, slug, title, project_uuid, requested_by_project_id,
 		       assigned_project_id, acknowledged_at, resolution, state, priority,
@@ -262,9 +379,9 @@ func exportTasks(db *sql.DB, snap *Snapshot) error {
 		       created_at, updated_at, completed_at, archived_at,
 		       created_by_actor_uuid, updated_by_actor_uuid
 		FROM tasks
-		WHERE archived_at IS NULL
+		WHERE archived_at IS NULL AND `+cond+`
 		ORDER BY uuid
-	`)
+	`, args...)
 	if err != nil {
 		return err
 	}
@@ -344,14 +461,16 @@ func exportTasks(db *sql.DB, snap *Snapshot) error {
 	return rows.Err()
 }
 
-func exportComments(db *sql.DB, snap *Snapshot) error {
+func exportComments(db *sql.DB, snap *Snapshot, filter *changeFilter) error {
+	cond, args := filter.clause("comment", "COALESCE(updated_at, created_at)")
 	rows, err := db.Query(`
 		SELECT uuid, id, task_uuid, actor_uuid, body, meta, etag,
-		       created_at, updated_at, deleted_at, deleted_by_actor_uuid
-		FROM comments
-		WHERE deleted_at IS NULL
+		       created_at, updated_at, deleted_at, deleted_by_actor_uuid,
+		       (SELECT p.uuid FROM comments p WHERE p.uuid = c.parent_comment_uuid AND p.deleted_at IS NULL)
+		FROM comments c
+		WHERE deleted_at IS NULL AND `+cond+`
 		ORDER BY uuid
-	`)
+	`, args...)
 	if err != nil {
 		return err
 	}
@@ -359,11 +478,13 @@ func exportComments(db *sql.DB, snap *Snapshot) error {
 
 	for rows.Next() {
 		var uuid, id, taskUUID, actorUUID, body, createdAt string
-		var meta, updatedAt, deletedAt, deletedBy sql.NullString
+		var meta, updatedAt, deletedAt, deletedBy, parentUUID sql.NullString
 		var etag int64
 
+		// Deleted comments aren't exported, so replies to them become
+		// top-level comments in the snapshot
 		if err := rows.Scan(&uuid, &id, &taskUUID, &actorUUID, &body, &meta, &etag,
-			&createdAt, &updatedAt, &deletedAt, &deletedBy); err != nil {
+			&createdAt, &updatedAt, &deletedAt, &deletedBy, &parentUUID); err != nil {
 			return err
 		}
 
@@ -388,6 +509,9 @@ func exportComments(db *sql.DB, snap *Snapshot) error {
 		if deletedBy.Valid {
 			entry.DeletedBy = deletedBy.String
 		}
+		if parentUUID.Valid {
+			entry.ParentUUID = parentUUID.String
+		}
 
 		snap.Comments[uuid] = entry
 	}
@@ -395,15 +519,24 @@ func exportComments(db *sql.DB, snap *Snapshot) error {
 	return rows.Err()
 }
 
-func exportEvents(db *sql.DB, snap *Snapshot) error {
+func exportEvents(db *sql.DB, snap *Snapshot, filter *changeFilter) error {
 	snap.Events = make(map[string]EventEntry)
 
+	// Incremental exports only carry events inside the window
+	cond, args := "1 = 1", []interface{}(nil)
+	if filter != nil && filter.since != "" {
+		cond, args = "julianday(timestamp) >= julianday(?)", []interface{}{filter.since}
+	} else if filter != nil {
+		cond, args = "id > ?", []interface{}{filter.sinceEvent}
+	}
+
 	rows, err := db.Query(`
 		SELECT id, timestamp, actor_uuid, resource_type, resource_uuid,
 		       event_type, etag, payload
 		FROM event_log
+		WHERE `+cond+`
 		ORDER BY id
-	`)
+	`, args...)
 	if err != nil {
 		return err
 	}
@@ -127,6 +127,9 @@ func LoadSnapshot(path string) (*Snapshot, []byte, error) {
 
 func validateSnapshot(snap *Snapshot) error {
 	// Validate meta
+	if snap.Meta.Partial != nil {
+		return fmt.Errorf("snapshot is partial (incremental export); only full snapshots can be imported")
+	}
 	if snap.Meta.SchemaVersion < 1 {
 		return fmt.Errorf("invalid schema_version: %d", snap.Meta.SchemaVersion)
 	}
@@ -150,6 +153,15 @@ func validateSnapshot(snap *Snapshot) error {
 		if _, ok := snap.Actors[comment.ActorUUID]; !ok {
 			return fmt.Errorf("comment %s references unknown actor %s", uuid, comment.ActorUUID)
 		}
+		if comment.ParentUUID != "" {
+			parent, ok := snap.Comments[comment.ParentUUID]
+			if !ok {
+				return fmt.Errorf("comment %s replies to unknown comment %s", uuid, comment.ParentUUID)
+			}
+			if parent.TaskUUID != comment.TaskUUID {
+				return fmt.Errorf("comment %s replies to comment %s on another task", uuid, comment.ParentUUID)
+			}
+		}
 	}
 
 	// Containers with parent_uuid must reference valid containers
@@ -453,8 +465,9 @@ func importComments(tx *sql.Tx, snap *Snapshot) error {
 
 	stmt, err := tx.Prepare(`
 		INSERT INTO comments (uuid, id, task_uuid, actor_uuid, body, meta, etag,
-		                      created_at, updated_at, deleted_at, deleted_by_actor_uuid)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		                      created_at, updated_at, deleted_at, deleted_by_actor_uuid,
+		                      parent_comment_uuid)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(uuid) DO UPDATE SET
 			id = excluded.id,
 			task_uuid = excluded.task_uuid,
@@ -465,7 +478,8 @@ func importComments(tx *sql.Tx, snap *Snapshot) error {
 			created_at = excluded.created_at,
 			updated_at = excluded.updated_at,
 			deleted_at = excluded.deleted_at,
-			deleted_by_actor_uuid = excluded.deleted_by_actor_uuid
+			deleted_by_actor_uuid = excluded.deleted_by_actor_uuid,
+			parent_comment_uuid = excluded.parent_comment_uuid
 	`)
 	if err != nil {
 		return err
@@ -475,7 +489,7 @@ func importComments(tx *sql.Tx, snap *Snapshot) error {
 	for _, uuid := range uuids {
 		comment := snap.Comments[uuid]
 
-		var meta, updatedAt, deletedAt, deletedBy interface{}
+		var meta, updatedAt, deletedAt, deletedBy, parentUUID interface{}
 		if comment.Meta != "" {
 			meta = comment.Meta
 		}
@@ -488,10 +502,13 @@ func importComments(tx *sql.Tx, snap *Snapshot) error {
 		if comment.DeletedBy != "" {
 			deletedBy = comment.DeletedBy
 		}
+		if comment.ParentUUID != "" {
+			parentUUID = comment.ParentUUID
+		}
 
 		if _, err := stmt.Exec(uuid, comment.ID, comment.TaskUUID, comment.ActorUUID,
 			comment.Body, meta, comment.ETag, comment.CreatedAt, updatedAt,
-			deletedAt, deletedBy); err != nil {
+			deletedAt, deletedBy, parentUUID); err != nil {
 			return fmt.Errorf("failed to import comment %s: %w", uuid, err)
 		}
 	}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -88,7 +89,8 @@ func createTestDB(t *testing.T) *sql.DB {
 			created_at TEXT NOT NULL DEFAULT (datetime('now')),
 			updated_at TEXT,
 			deleted_at TEXT,
-			deleted_by_actor_uuid TEXT REFERENCES actors(uuid)
+			deleted_by_actor_uuid TEXT REFERENCES actors(uuid),
+			parent_comment_uuid TEXT
 		);
 
 		CREATE TABLE event_log (
@@ -318,6 +320,86 @@ func TestExportWithLabelsSorted(t *testing.T) {
 	}
 }
 
+func TestExportSince(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+	seedTestData(t, db)
+
+	// A later task plus a container archived in the window; the seeded task
+	// and comment predate it
+	stmts := []string{
+		`INSERT INTO containers (uuid, id, slug, title, created_at, updated_at, archived_at, created_by_actor_uuid, updated_by_actor_uuid)
+		 VALUES ('container-uuid-2', 'P-00002', 'old', 'Old', '2025-01-01T00:00:00Z', '2025-03-01T00:00:00Z', '2025-03-01T00:00:00Z', 'actor-uuid-1', 'actor-uuid-1')`,
+		`INSERT INTO tasks (uuid, id, slug, title, project_uuid, state, created_at, updated_at, created_by_actor_uuid, updated_by_actor_uuid)
+		 VALUES ('task-uuid-2', 'T-00002', 'later', 'Later', 'container-uuid-1', 'open', '2025-03-01T00:00:00Z', '2025-03-01T00:00:00Z', 'actor-uuid-1', 'actor-uuid-1')`,
+		`INSERT INTO event_log (resource_type, resource_uuid, event_type) VALUES ('task', 'task-uuid-1', 'task.updated')`,
+		`INSERT INTO event_log (resource_type, resource_uuid, event_type) VALUES ('comment', 'comment-uuid-1', 'comment.updated')`,
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("failed to seed: %v", err)
+		}
+	}
+
+	snap, _, err := ExportToSnapshot(db, ExportOptions{Canonical: true, Since: "2025-02-01T00:00:00Z"})
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	if len(snap.Tasks) != 1 || len(snap.Containers) != 0 || len(snap.Comments) != 0 || len(snap.Actors) != 0 {
+		t.Fatalf("expected only the later task, got %d tasks, %d containers, %d comments, %d actors",
+			len(snap.Tasks), len(snap.Containers), len(snap.Comments), len(snap.Actors))
+	}
+	if _, ok := snap.Tasks["task-uuid-2"]; !ok {
+		t.Errorf("expected task-uuid-2 in partial snapshot")
+	}
+	partial := snap.Meta.Partial
+	if partial == nil || partial.Since != "2025-02-01T00:00:00Z" || partial.LastEvent != 2 {
+		t.Fatalf("unexpected partial meta: %+v", partial)
+	}
+	if removed := partial.Removed["containers"]; len(removed) != 1 || removed[0] != "container-uuid-2" {
+		t.Errorf("expected archived container in removed, got %v", partial.Removed)
+	}
+
+	// Events after id 1 touch only the comment
+	snap, _, err = ExportToSnapshot(db, ExportOptions{Canonical: true, SinceEvent: 1})
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	if len(snap.Comments) != 1 || len(snap.Tasks) != 0 {
+		t.Errorf("expected only the comment, got %d comments, %d tasks", len(snap.Comments), len(snap.Tasks))
+	}
+
+	if err := validateSnapshot(snap); err == nil {
+		t.Error("expected partial snapshot to be rejected for import")
+	}
+
+	if _, _, err := ExportToSnapshot(db, ExportOptions{Since: "yesterday"}); err == nil {
+		t.Error("expected invalid since to fail")
+	}
+}
+
+func TestExportSinceDefaultTimestamps(t *testing.T) {
+	db := createTestDB(t)
+	defer db.Close()
+	seedTestData(t, db)
+
+	// The column default writes datetime('now'), which has a space where
+	// RFC3339 has a T
+	if _, err := db.Exec(`INSERT INTO comments (uuid, id, task_uuid, actor_uuid, body, etag)
+		VALUES ('comment-uuid-2', 'C-00002', 'task-uuid-1', 'actor-uuid-1', 'Today', 1)`); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	since := time.Now().UTC().Truncate(24 * time.Hour).Format("2006-01-02T15:04:05Z")
+	snap, _, err := ExportToSnapshot(db, ExportOptions{Canonical: true, Since: since})
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	if _, ok := snap.Comments["comment-uuid-2"]; !ok || len(snap.Comments) != 1 {
+		t.Errorf("expected only today's comment, got %d comments", len(snap.Comments))
+	}
+}
+
 func TestRoundTrip(t *testing.T) {
 	db := createTestDB(t)
 	defer db.Close()
@@ -27,6 +27,24 @@ type Meta struct {
 	SnapshotRev             string `json:"snapshot_rev,omitempty"`
 	GeneratedAt             string `json:"generated_at,omitempty"`
 	MachineInterfaceVersion int    `json:"machine_interface_version"`
+	// Partial is set on incremental exports, which hold only changed entities
+	Partial *Partial `json:"partial,omitempty"`
+}
+
+// Partial describes the change window of an incremental snapshot.
+//
+// A partial snapshot contains only the entities that changed in the window;
+// everything absent is unchanged relative to the base it is diffed against.
+// Entities archived or deleted in the window are listed under Removed, keyed
+// by collection ("containers", "tasks", "comments"). Fields are declared in
+// lexicographic order so the encoding stays canonical.
+type Partial struct {
+	// LastEvent is the highest event_log id at export time; pass it as
+	// SinceEvent to continue from this snapshot
+	LastEvent  int64               `json:"last_event"`
+	Removed    map[string][]string `json:"removed,omitempty"`
+	Since      string              `json:"since,omitempty"`
+	SinceEvent int64               `json:"since_event,omitempty"`
 }
 
 // ActorEntry represents an actor in the snapshot.
@@ -95,6 +113,8 @@ type CommentEntry struct {
 	UpdatedAt string `json:"updated_at,omitempty"`
 	DeletedAt string `json:"deleted_at,omitempty"`
 	DeletedBy string `json:"deleted_by,omitempty"`
+	// ParentUUID is the comment this one replies to, on the same task
+	ParentUUID string `json:"parent_comment_uuid,omitempty"`
 }
 
 // LinkEntry represents a link/dependency in the snapshot.
@@ -129,6 +149,12 @@ type ExportOptions struct {
 	Canonical bool
 	// IncludeEvents includes full event log in snapshot
 	IncludeEvents bool
+	// Since limits the export to entities updated at or after this
+	// timestamp, producing a partial snapshot
+	Since string
+	// SinceEvent limits the export to entities touched by events with an
+	// event_log id greater than this, producing a partial snapshot
+	SinceEvent int64
 }
 
 // ImportOptions configures snapshot import behavior.
@@ -153,6 +179,8 @@ type ExportResult struct {
 	CommentCount   int    `json:"comments"`
 	LinkCount      int    `json:"links,omitempty"`
 	EventCount     int    `json:"events,omitempty"`
+	RemovedCount   int    `json:"removed,omitempty"`
+	Partial        bool   `json:"partial,omitempty"`
 }
 
 // ImportResult contains the result of an import operation.
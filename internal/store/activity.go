@@ -0,0 +1,223 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lherron/wrkq/internal/cursor"
+)
+
+// ActivityEntry is one event in a container's activity feed, with the actor
+// and the affected task or container resolved to friendly names.
+type ActivityEntry struct {
+	EventID      int64     `json:"event_id"`
+	Timestamp    time.Time `json:"timestamp"`
+	Actor        string    `json:"actor"`
+	EventType    string    `json:"event_type"`
+	ResourceType string    `json:"resource_type"`
+	ResourceUUID string    `json:"resource_uuid"`
+	// ResourceID and Path name the task for task, comment and attachment
+	// events, and the container for container events.
+	ResourceID string `json:"resource_id"`
+	Path       string `json:"path"`
+	Summary    string `json:"summary"`
+}
+
+// activityVerbs maps event types to the verb used in summaries. Types not
+// listed fall back to the part after the dot.
+var activityVerbs = map[string]string{
+	"task.created":       "created",
+	"task.copied":        "copied",
+	"task.moved":         "moved",
+	"task.archived":      "archived",
+	"task.deleted":       "deleted",
+	"task.restored":      "restored",
+	"comment.created":    "commented on",
+	"comment.restored":   "restored a comment on",
+	"attachment.created": "attached a file to",
+	"attachment.deleted": "removed an attachment from",
+	"reaction.added":     "reacted to",
+	"reaction.removed":   "removed a reaction from",
+	"container.created":  "created",
+	"container.updated":  "updated",
+	"container.moved":    "moved",
+	"container.archived": "archived",
+	"container.deleted":  "deleted",
+	"container.restored": "restored",
+
+	"task.relation.created": "linked",
+	"task.relation.deleted": "unlinked",
+}
+
+// Activity returns the events for containerUUID and every container below
+// it, newest first: task events in the subtree (including comments and
+// attachments on those tasks) and events on the containers themselves. A
+// zero since means no lower bound. limit caps the page; the second return
+// value is the cursor for the next page, or "" on the last one.
+//
+// Events are matched through the resource's current location, so a task
+// moved out of the subtree takes its history with it, and events whose
+// resource no longer exists (purged tasks, deleted attachments) are omitted.
+func (s *Store) Activity(containerUUID string, since time.Time, limit int, cursorStr string) ([]ActivityEntry, string, error) {
+	pag, err := cursor.Apply(cursorStr, cursor.ApplyOptions{
+		SortFields: []string{"timestamp"},
+		SQLFields:  []string{"e.timestamp"},
+		Descending: []bool{true},
+		IDField:    "e.id",
+		Limit:      limit,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The previous state is read from the latest earlier event for the same
+	// task that recorded one (its creation or an earlier state change).
+	query := `
+		WITH subtree AS (
+			SELECT c.uuid FROM v_container_paths c, v_container_paths root
+			WHERE root.uuid = ?
+			  AND (c.uuid = root.uuid OR substr(c.path, 1, length(root.path) + 1) = root.path || '/')
+		)
+		SELECT e.id, e.timestamp, e.event_type, e.resource_type, COALESCE(e.resource_uuid, ''),
+		       COALESCE(e.payload, ''), COALESCE(a.slug, ''),
+		       COALESCE(tp.id, cp.id, ''), COALESCE(tp.path, cp.path, ''),
+		       (SELECT json_extract(p.payload, '$.state')
+		          FROM event_log p
+		         WHERE e.resource_type = 'task' AND p.resource_type = 'task'
+		           AND p.resource_uuid = e.resource_uuid AND p.id < e.id
+		           AND json_valid(p.payload) AND json_extract(p.payload, '$.state') IS NOT NULL
+		         ORDER BY p.id DESC LIMIT 1)
+		FROM event_log e
+		LEFT JOIN actors a ON a.uuid = e.actor_uuid
+		LEFT JOIN comments cm ON e.resource_type = 'comment' AND cm.uuid = e.resource_uuid
+		LEFT JOIN attachments att ON e.resource_type = 'attachment' AND att.uuid = e.resource_uuid
+		LEFT JOIN v_task_paths tp ON tp.uuid = CASE e.resource_type
+			WHEN 'task' THEN e.resource_uuid
+			WHEN 'comment' THEN cm.task_uuid
+			WHEN 'attachment' THEN att.task_uuid
+		END
+		LEFT JOIN v_container_paths cp ON e.resource_type = 'container' AND cp.uuid = e.resource_uuid
+		WHERE (tp.project_uuid IN (SELECT uuid FROM subtree) OR cp.uuid IN (SELECT uuid FROM subtree))
+	`
+	args := []interface{}{containerUUID}
+
+	if !since.IsZero() {
+		query += " AND e.timestamp >= ?"
+		args = append(args, since.UTC().Format(time.RFC3339))
+	}
+	if pag.WhereClause != "" {
+		query += " AND " + pag.WhereClause
+		args = append(args, pag.Params...)
+	}
+	query += " " + pag.OrderByClause
+	if pag.LimitClause != "" {
+		query += " " + pag.LimitClause
+		args = append(args, *pag.LimitParam)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query activity: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []ActivityEntry{}
+	for rows.Next() {
+		var e ActivityEntry
+		var timestamp, payload string
+		var prevState sql.NullString
+		if err := rows.Scan(&e.EventID, &timestamp, &e.EventType, &e.ResourceType, &e.ResourceUUID,
+			&payload, &e.Actor, &e.ResourceID, &e.Path, &prevState); err != nil {
+			return nil, "", fmt.Errorf("failed to scan activity: %w", err)
+		}
+		e.Timestamp, err = time.Parse(time.RFC3339, timestamp)
+		if err != nil {
+			e.Timestamp, _ = time.Parse("2006-01-02 15:04:05", timestamp)
+		}
+		e.Summary = activitySummary(e, payload, prevState.String)
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var nextCursor string
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+		last := entries[len(entries)-1]
+		nextCursor, err = cursor.BuildNextCursor(
+			[]string{"timestamp"},
+			[]interface{}{last.Timestamp.UTC().Format(time.RFC3339)},
+			strconv.FormatInt(last.EventID, 10),
+		)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	return entries, nextCursor, nil
+}
+
+// activitySummary renders an entry as a sentence such as
+// "alice changed T-00012 state open→in_progress".
+func activitySummary(e ActivityEntry, payload, prevState string) string {
+	actor := e.Actor
+	if actor == "" {
+		actor = "someone"
+	}
+	target := e.ResourceID
+	if e.ResourceType == "container" {
+		target = e.Path
+	}
+
+	if e.EventType == "task.updated" {
+		var fields map[string]interface{}
+		_ = json.Unmarshal([]byte(payload), &fields)
+
+		var parts []string
+		if state, ok := fields["state"].(string); ok {
+			if prevState != "" && prevState != state {
+				parts = append(parts, fmt.Sprintf("%s changed %s state %s→%s", actor, target, prevState, state))
+			} else {
+				parts = append(parts, fmt.Sprintf("%s set %s state %s", actor, target, state))
+			}
+		}
+
+		var changed []string
+		for key := range fields {
+			switch key {
+			case "state":
+			case "assignee_actor_uuid":
+				changed = append(changed, "assignee")
+			default:
+				changed = append(changed, key)
+			}
+		}
+		if len(changed) > 0 {
+			sort.Strings(changed)
+			if len(parts) == 0 {
+				parts = append(parts, fmt.Sprintf("%s updated %s %s", actor, target, strings.Join(changed, ", ")))
+			} else {
+				parts = append(parts, "and updated "+strings.Join(changed, ", "))
+			}
+		}
+		if len(parts) == 0 {
+			return fmt.Sprintf("%s updated %s", actor, target)
+		}
+		return strings.Join(parts, " ")
+	}
+
+	verb, ok := activityVerbs[e.EventType]
+	if !ok {
+		verb = e.EventType
+		if i := strings.IndexByte(verb, '.'); i >= 0 {
+			verb = verb[i+1:]
+		}
+	}
+	return fmt.Sprintf("%s %s %s", actor, verb, target)
+}
@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/lherron/wrkq/internal/domain"
 	"github.com/lherron/wrkq/internal/events"
+	"github.com/lherron/wrkq/internal/webhooks"
 )
 
 // ContainerStore handles container persistence operations.
@@ -157,8 +159,18 @@ func (cs *ContainerStore) UpdateFields(actorUUID, containerUUID string, fields m
 			return fmt.Errorf("failed to update container: %w", err)
 		}
 
-		// Log event with structured payload
-		changesJSON, err := json.Marshal(fields)
+		// Log event with structured payload; secrets never reach the event log
+		changes := fields
+		if secret, ok := fields["webhook_secret"]; ok {
+			changes = make(map[string]interface{}, len(fields))
+			for key, value := range fields {
+				changes[key] = value
+			}
+			if secret != nil {
+				changes["webhook_secret"] = "[redacted]"
+			}
+		}
+		changesJSON, err := json.Marshal(changes)
 		if err != nil {
 			return fmt.Errorf("failed to marshal changes: %w", err)
 		}
@@ -182,7 +194,80 @@ func (cs *ContainerStore) UpdateFields(actorUUID, containerUUID string, fields m
 	return newETag, err
 }
 
-// Move moves a container to a different parent and logs a container.moved event.
+// Rename changes a container's slug, refusing a slug already used by a
+// sibling, and logs a container.updated event. Descendant paths come from
+// v_container_paths, so they follow in the same transaction.
+// Returns the new etag on success.
+func (cs *ContainerStore) Rename(actorUUID, containerUUID, newSlug string, ifMatch int64) (int64, error) {
+	var newETag int64
+
+	err := cs.store.withTx(func(tx *sql.Tx, ew *events.Writer) error {
+		var currentETag int64
+		var parentUUID *string
+		var oldSlug string
+		err := tx.QueryRow("SELECT etag, parent_uuid, slug FROM containers WHERE uuid = ?", containerUUID).Scan(&currentETag, &parentUUID, &oldSlug)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("container not found: %s", containerUUID)
+			}
+			return fmt.Errorf("failed to get container: %w", err)
+		}
+
+		// Check etag if ifMatch was provided
+		if err := checkETag(currentETag, ifMatch); err != nil {
+			return err
+		}
+
+		var taken bool
+		err = tx.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM containers WHERE slug = ? AND parent_uuid IS ? AND uuid != ?)
+		`, newSlug, parentUUID, containerUUID).Scan(&taken)
+		if err != nil {
+			return fmt.Errorf("failed to check slug: %w", err)
+		}
+		if taken {
+			return &domain.ValidationError{Message: fmt.Sprintf("a sibling container named %q already exists", newSlug)}
+		}
+
+		_, err = tx.Exec(`
+			UPDATE containers
+			SET slug = ?,
+				etag = etag + 1,
+				updated_by_actor_uuid = ?
+			WHERE uuid = ?
+		`, newSlug, actorUUID, containerUUID)
+		if err != nil {
+			return fmt.Errorf("failed to rename container: %w", err)
+		}
+
+		payload := map[string]interface{}{
+			"slug":     newSlug,
+			"old_slug": oldSlug,
+		}
+		payloadJSON, _ := json.Marshal(payload)
+		payloadStr := string(payloadJSON)
+		newETag = currentETag + 1
+
+		if err := ew.LogEvent(tx, &domain.Event{
+			ActorUUID:    &actorUUID,
+			ResourceType: "container",
+			ResourceUUID: &containerUUID,
+			EventType:    "container.updated",
+			ETag:         &newETag,
+			Payload:      &payloadStr,
+		}); err != nil {
+			return fmt.Errorf("failed to log event: %w", err)
+		}
+
+		return nil
+	})
+
+	return newETag, err
+}
+
+// Move moves a container to a different parent (nil for the root) and logs
+// a container.moved event. It refuses to move a container under itself or
+// one of its descendants, or next to a sibling with the same slug.
 // Returns the new etag on success.
 func (cs *ContainerStore) Move(actorUUID, containerUUID string, newParentUUID *string, ifMatch int64) (int64, error) {
 	var newETag int64
@@ -191,7 +276,8 @@ func (cs *ContainerStore) Move(actorUUID, containerUUID string, newParentUUID *s
 		// Get current state
 		var currentETag int64
 		var oldParentUUID *string
-		err := tx.QueryRow("SELECT etag, parent_uuid FROM containers WHERE uuid = ?", containerUUID).Scan(&currentETag, &oldParentUUID)
+		var slug string
+		err := tx.QueryRow("SELECT etag, parent_uuid, slug FROM containers WHERE uuid = ?", containerUUID).Scan(&currentETag, &oldParentUUID, &slug)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fmt.Errorf("container not found: %s", containerUUID)
@@ -204,6 +290,37 @@ func (cs *ContainerStore) Move(actorUUID, containerUUID string, newParentUUID *s
 			return err
 		}
 
+		if newParentUUID != nil {
+			// Walk up from the new parent; reaching the container means a cycle
+			var cycle bool
+			err := tx.QueryRow(`
+				WITH RECURSIVE ancestors(uuid) AS (
+					SELECT ?
+					UNION
+					SELECT c.parent_uuid FROM containers c JOIN ancestors a ON c.uuid = a.uuid
+					WHERE c.parent_uuid IS NOT NULL
+				)
+				SELECT EXISTS (SELECT 1 FROM ancestors WHERE uuid = ?)
+			`, *newParentUUID, containerUUID).Scan(&cycle)
+			if err != nil {
+				return fmt.Errorf("failed to check ancestry: %w", err)
+			}
+			if cycle {
+				return &domain.ValidationError{Message: "cannot move a container under itself or one of its descendants"}
+			}
+		}
+
+		var taken bool
+		err = tx.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM containers WHERE slug = ? AND parent_uuid IS ? AND uuid != ?)
+		`, slug, newParentUUID, containerUUID).Scan(&taken)
+		if err != nil {
+			return fmt.Errorf("failed to check slug: %w", err)
+		}
+		if taken {
+			return &domain.ValidationError{Message: fmt.Sprintf("a container named %q already exists under the target parent", slug)}
+		}
+
 		// Update the container
 		_, err = tx.Exec(`
 			UPDATE containers
@@ -304,6 +421,302 @@ func (cs *ContainerStore) Archive(actorUUID, containerUUID string, ifMatch int64
 	return newETag, err
 }
 
+// ContainerLifecycleResult summarizes a cascading archive or restore.
+type ContainerLifecycleResult struct {
+	ETag       int64
+	Containers int
+	Tasks      int
+}
+
+// containerSubtreeCTE selects the container bound to the first placeholder
+// and every container below it.
+const containerSubtreeCTE = `
+	WITH RECURSIVE subtree(uuid) AS (
+		SELECT ?
+		UNION ALL
+		SELECT c.uuid FROM containers c JOIN subtree s ON c.parent_uuid = s.uuid
+	)`
+
+// ArchiveTree archives a container along with its subcontainers and their
+// tasks, all stamped with the same archived_at so RestoreTree can undo it
+// later. Each task.archived event records the task's prior state for
+// RestoreTree to put back. Without cascade it refuses a container that still
+// has active tasks or subcontainers. ifMatch applies to the container itself.
+func (cs *ContainerStore) ArchiveTree(actorUUID, containerUUID string, ifMatch int64, cascade bool) (*ContainerLifecycleResult, error) {
+	result := &ContainerLifecycleResult{}
+	archivedAt := time.Now().UTC().Format(time.RFC3339)
+
+	err := cs.store.withTx(func(tx *sql.Tx, ew *events.Writer) error {
+		var currentETag int64
+		var archived sql.NullString
+		err := tx.QueryRow("SELECT etag, archived_at FROM containers WHERE uuid = ?", containerUUID).Scan(&currentETag, &archived)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("container not found: %s: %w", containerUUID, err)
+			}
+			return fmt.Errorf("failed to get container: %w", err)
+		}
+		if err := checkETag(currentETag, ifMatch); err != nil {
+			return err
+		}
+		if archived.Valid {
+			return fmt.Errorf("container is already archived")
+		}
+
+		containerUUIDs, err := queryUUIDs(tx, containerSubtreeCTE+`
+			SELECT c.uuid FROM containers c JOIN subtree s ON s.uuid = c.uuid
+			WHERE c.archived_at IS NULL ORDER BY c.uuid = ? DESC, c.uuid
+		`, containerUUID, containerUUID)
+		if err != nil {
+			return fmt.Errorf("failed to list containers: %w", err)
+		}
+		taskUUIDs, err := queryUUIDs(tx, containerSubtreeCTE+`
+			SELECT t.uuid FROM tasks t JOIN subtree s ON s.uuid = t.project_uuid
+			WHERE t.state NOT IN ('archived', 'deleted') ORDER BY t.uuid
+		`, containerUUID)
+		if err != nil {
+			return fmt.Errorf("failed to list tasks: %w", err)
+		}
+		if !cascade && (len(taskUUIDs) > 0 || len(containerUUIDs) > 1) {
+			return fmt.Errorf("container is not empty: has %d active task(s) and %d active subcontainer(s)",
+				len(taskUUIDs), len(containerUUIDs)-1)
+		}
+
+		var parentUUIDs []string
+		for _, taskUUID := range taskUUIDs {
+			var priorState string
+			var parentUUID sql.NullString
+			if err := tx.QueryRow("SELECT state, parent_task_uuid FROM tasks WHERE uuid = ?", taskUUID).Scan(&priorState, &parentUUID); err != nil {
+				return fmt.Errorf("failed to read task state: %w", err)
+			}
+			parentUUIDs = append(parentUUIDs, parentUUID.String)
+			if _, err := tx.Exec(`
+				UPDATE tasks
+				SET state = 'archived',
+					archived_at = ?,
+					updated_by_actor_uuid = ?,
+					etag = etag + 1
+				WHERE uuid = ?
+			`, archivedAt, actorUUID, taskUUID); err != nil {
+				return fmt.Errorf("failed to archive task: %w", err)
+			}
+			var etag int64
+			if err := tx.QueryRow("SELECT etag FROM tasks WHERE uuid = ?", taskUUID).Scan(&etag); err != nil {
+				return fmt.Errorf("failed to read etag: %w", err)
+			}
+			if err := logLifecycleEvent(tx, ew, actorUUID, "task", taskUUID, "task.archived", etag, containerUUID, priorState); err != nil {
+				return err
+			}
+			if err := webhooks.EnqueueTask(tx, taskUUID); err != nil {
+				return fmt.Errorf("failed to queue webhooks: %w", err)
+			}
+		}
+		if err := RefreshParentProgressTx(tx, ew, actorUUID, parentUUIDs...); err != nil {
+			return err
+		}
+		for _, uuid := range containerUUIDs {
+			if _, err := tx.Exec(`
+				UPDATE containers
+				SET archived_at = ?,
+					updated_by_actor_uuid = ?,
+					etag = etag + 1
+				WHERE uuid = ?
+			`, archivedAt, actorUUID, uuid); err != nil {
+				return fmt.Errorf("failed to archive container: %w", err)
+			}
+			var etag int64
+			if err := tx.QueryRow("SELECT etag FROM containers WHERE uuid = ?", uuid).Scan(&etag); err != nil {
+				return fmt.Errorf("failed to read etag: %w", err)
+			}
+			if err := logLifecycleEvent(tx, ew, actorUUID, "container", uuid, "container.archived", etag, containerUUID, ""); err != nil {
+				return err
+			}
+			if uuid == containerUUID {
+				result.ETag = etag
+			}
+		}
+
+		result.Containers = len(containerUUIDs)
+		result.Tasks = len(taskUUIDs)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Tasks > 0 {
+		webhooks.Notify(cs.store.db)
+	}
+	return result, nil
+}
+
+// RestoreTree clears archived_at on an archived container and restores the
+// subcontainers and tasks archived together with it (same archived_at). Each
+// task goes back to the state its task.archived event recorded, or open if
+// there is none. Things archived separately, before or after, stay archived.
+func (cs *ContainerStore) RestoreTree(actorUUID, containerUUID string, ifMatch int64) (*ContainerLifecycleResult, error) {
+	result := &ContainerLifecycleResult{}
+
+	err := cs.store.withTx(func(tx *sql.Tx, ew *events.Writer) error {
+		var currentETag int64
+		var archived sql.NullString
+		err := tx.QueryRow("SELECT etag, archived_at FROM containers WHERE uuid = ?", containerUUID).Scan(&currentETag, &archived)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("container not found: %s: %w", containerUUID, err)
+			}
+			return fmt.Errorf("failed to get container: %w", err)
+		}
+		if err := checkETag(currentETag, ifMatch); err != nil {
+			return err
+		}
+		if !archived.Valid {
+			return fmt.Errorf("container is not archived")
+		}
+
+		containerUUIDs, err := queryUUIDs(tx, containerSubtreeCTE+`
+			SELECT c.uuid FROM containers c JOIN subtree s ON s.uuid = c.uuid
+			WHERE c.archived_at = ? ORDER BY c.uuid = ? DESC, c.uuid
+		`, containerUUID, archived.String, containerUUID)
+		if err != nil {
+			return fmt.Errorf("failed to list containers: %w", err)
+		}
+		taskUUIDs, err := queryUUIDs(tx, containerSubtreeCTE+`
+			SELECT t.uuid FROM tasks t JOIN subtree s ON s.uuid = t.project_uuid
+			WHERE t.state = 'archived' AND t.archived_at = ? ORDER BY t.uuid
+		`, containerUUID, archived.String)
+		if err != nil {
+			return fmt.Errorf("failed to list tasks: %w", err)
+		}
+
+		for _, uuid := range containerUUIDs {
+			if _, err := tx.Exec(`
+				UPDATE containers
+				SET archived_at = NULL,
+					updated_by_actor_uuid = ?,
+					etag = etag + 1
+				WHERE uuid = ?
+			`, actorUUID, uuid); err != nil {
+				return fmt.Errorf("failed to restore container: %w", err)
+			}
+			var etag int64
+			if err := tx.QueryRow("SELECT etag FROM containers WHERE uuid = ?", uuid).Scan(&etag); err != nil {
+				return fmt.Errorf("failed to read etag: %w", err)
+			}
+			if err := logLifecycleEvent(tx, ew, actorUUID, "container", uuid, "container.restored", etag, containerUUID, ""); err != nil {
+				return err
+			}
+			if uuid == containerUUID {
+				result.ETag = etag
+			}
+		}
+		var parentUUIDs []string
+		for _, taskUUID := range taskUUIDs {
+			var priorState sql.NullString
+			err := tx.QueryRow(`
+				SELECT json_extract(payload, '$.prior_state') FROM event_log
+				WHERE resource_type = 'task' AND resource_uuid = ? AND event_type = 'task.archived'
+				ORDER BY id DESC LIMIT 1
+			`, taskUUID).Scan(&priorState)
+			if err != nil && err != sql.ErrNoRows {
+				return fmt.Errorf("failed to read prior task state: %w", err)
+			}
+			state := "open"
+			if priorState.Valid && priorState.String != "" && priorState.String != "archived" && priorState.String != "deleted" {
+				state = priorState.String
+			}
+			if _, err := tx.Exec(`
+				UPDATE tasks
+				SET state = ?,
+					archived_at = NULL,
+					updated_by_actor_uuid = ?,
+					etag = etag + 1
+				WHERE uuid = ?
+			`, state, actorUUID, taskUUID); err != nil {
+				return fmt.Errorf("failed to restore task: %w", err)
+			}
+			var etag int64
+			var parentUUID sql.NullString
+			if err := tx.QueryRow("SELECT etag, parent_task_uuid FROM tasks WHERE uuid = ?", taskUUID).Scan(&etag, &parentUUID); err != nil {
+				return fmt.Errorf("failed to read etag: %w", err)
+			}
+			parentUUIDs = append(parentUUIDs, parentUUID.String)
+			if err := logLifecycleEvent(tx, ew, actorUUID, "task", taskUUID, "task.restored", etag, containerUUID, state); err != nil {
+				return err
+			}
+			if err := webhooks.EnqueueTask(tx, taskUUID); err != nil {
+				return fmt.Errorf("failed to queue webhooks: %w", err)
+			}
+		}
+		if err := RefreshParentProgressTx(tx, ew, actorUUID, parentUUIDs...); err != nil {
+			return err
+		}
+
+		result.Containers = len(containerUUIDs)
+		result.Tasks = len(taskUUIDs)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Tasks > 0 {
+		webhooks.Notify(cs.store.db)
+	}
+	return result, nil
+}
+
+// logLifecycleEvent logs an archive or restore event for one resource
+// touched by ArchiveTree or RestoreTree, recording which container the
+// operation started from and, if given, the task's state: the one it had
+// before an archive (prior_state) or the one a restore put back
+// (target_state, as wrkq restore logs it).
+func logLifecycleEvent(tx *sql.Tx, ew *events.Writer, actorUUID, resourceType, resourceUUID, eventType string, etag int64, rootUUID, taskState string) error {
+	payload := map[string]interface{}{
+		"container_uuid": rootUUID,
+	}
+	if taskState != "" && eventType == "task.restored" {
+		payload["target_state"] = taskState
+	} else if taskState != "" {
+		payload["prior_state"] = taskState
+	}
+	if eventType == "task.archived" || eventType == "container.archived" {
+		payload["soft_delete"] = true
+	}
+	payloadJSON, _ := json.Marshal(payload)
+	payloadStr := string(payloadJSON)
+
+	if err := ew.LogEvent(tx, &domain.Event{
+		ActorUUID:    &actorUUID,
+		ResourceType: resourceType,
+		ResourceUUID: &resourceUUID,
+		EventType:    eventType,
+		ETag:         &etag,
+		Payload:      &payloadStr,
+	}); err != nil {
+		return fmt.Errorf("failed to log event: %w", err)
+	}
+	return nil
+}
+
+func queryUUIDs(tx *sql.Tx, query string, args ...interface{}) ([]string, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var uuids []string
+	for rows.Next() {
+		var uuid string
+		if err := rows.Scan(&uuid); err != nil {
+			return nil, err
+		}
+		uuids = append(uuids, uuid)
+	}
+	return uuids, rows.Err()
+}
+
 // Delete hard-deletes an empty container.
 func (cs *ContainerStore) Delete(actorUUID, containerUUID string, ifMatch int64) error {
 	return cs.store.withTx(func(tx *sql.Tx, ew *events.Writer) error {
@@ -337,6 +750,9 @@ func (cs *ContainerStore) Delete(actorUUID, containerUUID string, ifMatch int64)
 		if childCount > 0 {
 			return fmt.Errorf("container is not empty: has %d children", childCount)
 		}
+		if err := checkNoProjectTokens(tx, containerUUID); err != nil {
+			return err
+		}
 
 		// Log event BEFORE deleting
 		payload := map[string]interface{}{
@@ -366,6 +782,23 @@ func (cs *ContainerStore) Delete(actorUUID, containerUUID string, ifMatch int64)
 	})
 }
 
+// checkNoProjectTokens refuses to delete a container that API tokens are
+// restricted to, or one with such a container below it. Revoking the tokens
+// is left to the caller, since dropping the last one would switch the daemon
+// back to its shared token.
+func checkNoProjectTokens(tx *sql.Tx, containerUUID string) error {
+	var tokens int
+	if err := tx.QueryRow(containerSubtreeCTE+`
+		SELECT COUNT(*) FROM api_tokens WHERE project_uuid IN (SELECT uuid FROM subtree)
+	`, containerUUID).Scan(&tokens); err != nil {
+		return fmt.Errorf("failed to check API tokens: %w", err)
+	}
+	if tokens > 0 {
+		return fmt.Errorf("container is the project of %d API token(s); revoke them first", tokens)
+	}
+	return nil
+}
+
 // GetByUUID retrieves a container by UUID.
 func (cs *ContainerStore) GetByUUID(uuid string) (*domain.Container, error) {
 	container := &domain.Container{}
@@ -392,6 +825,13 @@ func (cs *ContainerStore) GetByUUID(uuid string) (*domain.Container, error) {
 		return nil, fmt.Errorf("failed to get container: %w", err)
 	}
 	container.Kind = domain.ContainerKind(kind)
+	if t := parseTimeNullable(&createdAt); t != nil {
+		container.CreatedAt = *t
+	}
+	if t := parseTimeNullable(&updatedAt); t != nil {
+		container.UpdatedAt = *t
+	}
+	container.ArchivedAt = parseTimeNullable(archivedAt)
 	return container, nil
 }
 
@@ -0,0 +1,342 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HistoryEntry is one event in a task's history, with the actor resolved to
+// a slug and the payload parsed.
+type HistoryEntry struct {
+	EventID      int64                  `json:"event_id"`
+	Timestamp    time.Time              `json:"timestamp"`
+	Actor        string                 `json:"actor"`
+	EventType    string                 `json:"event_type"`
+	ResourceType string                 `json:"resource_type"`
+	ResourceUUID string                 `json:"resource_uuid"`
+	Payload      map[string]interface{} `json:"payload,omitempty"`
+	// Changes lists the fields a task.created or task.updated event set,
+	// with the value each had before.
+	Changes []FieldChange `json:"changes,omitempty"`
+	Summary string        `json:"summary"`
+}
+
+// FieldChange is one field set by a task event. From is nil when the field
+// had no recorded value before.
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// TaskHistory returns the events for a task, oldest first: events on the
+// task itself, on its comments and attachments, and relation events that
+// name it from either end.
+//
+// Like Activity, comment and attachment events are matched through the row
+// they refer to, so events for attachments that have since been deleted are
+// omitted.
+func (s *Store) TaskHistory(taskUUID string) ([]HistoryEntry, error) {
+	var friendlyID string
+	if err := s.db.QueryRow("SELECT id FROM tasks WHERE uuid = ?", taskUUID).Scan(&friendlyID); err == sql.ErrNoRows {
+		return nil, fmt.Errorf("task not found: %s", taskUUID)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to look up task: %w", err)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT e.id, e.timestamp, e.event_type, e.resource_type, COALESCE(e.resource_uuid, ''),
+		       COALESCE(e.payload, ''), COALESCE(a.slug, '')
+		FROM event_log e
+		LEFT JOIN actors a ON a.uuid = e.actor_uuid
+		LEFT JOIN comments cm ON e.resource_type = 'comment' AND cm.uuid = e.resource_uuid
+		LEFT JOIN attachments att ON e.resource_type = 'attachment' AND att.uuid = e.resource_uuid
+		WHERE (e.resource_type = 'task' AND e.resource_uuid = ?)
+		   OR cm.task_uuid = ?
+		   OR att.task_uuid = ?
+		   OR (e.resource_type = 'task' AND e.event_type LIKE 'task.relation.%'
+		       AND json_valid(e.payload) AND json_extract(e.payload, '$.to') = ?)
+		ORDER BY e.id
+	`, taskUUID, taskUUID, taskUUID, taskUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query task history: %w", err)
+	}
+	defer rows.Close()
+
+	// current tracks the task's fields as of the event being read, so each
+	// update can report what it changed from.
+	current := map[string]interface{}{}
+	entries := []HistoryEntry{}
+	for rows.Next() {
+		var e HistoryEntry
+		var timestamp, payload string
+		if err := rows.Scan(&e.EventID, &timestamp, &e.EventType, &e.ResourceType, &e.ResourceUUID,
+			&payload, &e.Actor); err != nil {
+			return nil, fmt.Errorf("failed to scan task history: %w", err)
+		}
+		e.Timestamp, err = time.Parse(time.RFC3339, timestamp)
+		if err != nil {
+			e.Timestamp, _ = time.Parse("2006-01-02 15:04:05", timestamp)
+		}
+		if payload != "" {
+			_ = json.Unmarshal([]byte(payload), &e.Payload)
+		}
+
+		prevState, _ := current["state"].(string)
+		own := e.ResourceType == "task" && e.ResourceUUID == taskUUID
+		if own && (e.EventType == "task.created" || e.EventType == "task.updated") {
+			e.Changes = fieldChanges(current, e.Payload)
+		}
+		e.Summary = activitySummary(ActivityEntry{
+			Actor:        e.Actor,
+			EventType:    e.EventType,
+			ResourceType: e.ResourceType,
+			ResourceID:   friendlyID,
+		}, payload, prevState)
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// fieldChanges diffs an event's fields against current, in field order,
+// and folds them into current.
+func fieldChanges(current, fields map[string]interface{}) []FieldChange {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	changes := make([]FieldChange, 0, len(keys))
+	for _, key := range keys {
+		changes = append(changes, FieldChange{Field: key, From: current[key], To: fields[key]})
+		current[key] = fields[key]
+	}
+	return changes
+}
+
+// TaskSnapshot is a task's fields as they stood at AsOf, rebuilt from the
+// event log by TaskAsOf.
+type TaskSnapshot struct {
+	UUID string    `json:"uuid"`
+	ID   string    `json:"id"`
+	AsOf time.Time `json:"as_of"`
+	// ETag and EventID come from the last event replayed.
+	ETag    int64                  `json:"etag"`
+	EventID int64                  `json:"event_id"`
+	Fields  map[string]interface{} `json:"fields"`
+	// Unknown names fields whose value at AsOf the log cannot tell: they are
+	// not in the task.created payload and changed only after AsOf. Their
+	// entry in Fields is nil.
+	Unknown []string `json:"unknown,omitempty"`
+}
+
+// snapshotFields are the task columns TaskAsOf reconstructs.
+var snapshotFields = []string{
+	"slug", "title", "description", "state", "priority", "kind", "project_uuid",
+	"parent_task_uuid", "assignee_actor_uuid", "requested_by_project_id",
+	"assigned_project_id", "resolution", "labels", "start_at", "due_at",
+	"estimate_minutes", "meta",
+}
+
+// createdOptional are the fields task.created records only when they are
+// set, so their absence from the payload means they started out empty.
+var createdOptional = map[string]bool{
+	"parent_task_uuid":        true,
+	"assignee_actor_uuid":     true,
+	"requested_by_project_id": true,
+	"assigned_project_id":     true,
+	"resolution":              true,
+	"labels":                  true,
+	"start_at":                true,
+	"due_at":                  true,
+	"estimate_minutes":        true,
+}
+
+type replayEvent struct {
+	id        int64
+	timestamp time.Time
+	eventType string
+	etag      sql.NullInt64
+	payload   map[string]interface{}
+}
+
+// TaskAsOf rebuilds a task's fields at asOf by replaying its events up to
+// and including that moment: task.created, then task.updated, moves,
+// archives, deletes and restores.
+//
+// A field's starting value is its task.created value. Fields the created
+// payload never carries fall back to the task's current value when no event
+// ever changed them, since that is still the value it was created with; a
+// task with no task.created event (for example one imported by a bundle)
+// starts from its current row the same way.
+func (s *Store) TaskAsOf(taskUUID string, asOf time.Time) (*TaskSnapshot, error) {
+	snap := &TaskSnapshot{UUID: taskUUID, AsOf: asOf.UTC()}
+	var createdAt string
+	err := s.db.QueryRow("SELECT id, created_at FROM tasks WHERE uuid = ?", taskUUID).Scan(&snap.ID, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("task not found: %s", taskUUID)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to look up task: %w", err)
+	}
+	if created, err := time.Parse(time.RFC3339, createdAt); err == nil && asOf.Before(created) {
+		return nil, fmt.Errorf("task %s did not exist at %s", snap.ID, asOf.UTC().Format(time.RFC3339))
+	}
+
+	current, err := s.currentTaskFields(taskUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, timestamp, event_type, etag, COALESCE(payload, '')
+		FROM event_log
+		WHERE resource_type = 'task' AND resource_uuid = ?
+		ORDER BY id
+	`, taskUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query task events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []replayEvent
+	for rows.Next() {
+		var e replayEvent
+		var timestamp, payload string
+		if err := rows.Scan(&e.id, &timestamp, &e.eventType, &e.etag, &payload); err != nil {
+			return nil, fmt.Errorf("failed to scan task event: %w", err)
+		}
+		e.timestamp, err = time.Parse(time.RFC3339, timestamp)
+		if err != nil {
+			e.timestamp, _ = time.Parse("2006-01-02 15:04:05", timestamp)
+		}
+		if payload != "" {
+			_ = json.Unmarshal([]byte(payload), &e.payload)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// changedLater records, for each field, whether any event at all sets
+	// it; firstProject is the project a task started in, if it ever moved.
+	var created map[string]interface{}
+	changedLater := map[string]bool{}
+	var firstProject interface{}
+	for _, e := range events {
+		switch e.eventType {
+		case "task.created":
+			if created == nil {
+				created = e.payload
+				if created == nil {
+					created = map[string]interface{}{}
+				}
+			}
+		case "task.updated":
+			for key := range e.payload {
+				changedLater[key] = true
+			}
+		case "task.moved", "task.restored":
+			if e.eventType == "task.moved" && firstProject == nil {
+				firstProject = e.payload["old_project_uuid"]
+			}
+			if _, ok := e.payload["new_project_uuid"]; ok {
+				changedLater["project_uuid"] = true
+			}
+			if _, ok := e.payload["moved_to"]; ok {
+				changedLater["project_uuid"] = true
+			}
+		}
+	}
+
+	fields := make(map[string]interface{}, len(snapshotFields))
+	unknown := map[string]bool{}
+	for _, key := range snapshotFields {
+		value, inCreated := created[key]
+		switch {
+		case inCreated:
+			fields[key] = value
+		case key == "project_uuid" && firstProject != nil:
+			fields[key] = firstProject
+		case !changedLater[key]:
+			fields[key] = current[key]
+		case created != nil && createdOptional[key]:
+			fields[key] = nil
+		default:
+			fields[key] = nil
+			unknown[key] = true
+		}
+	}
+
+	for _, e := range events {
+		if e.timestamp.After(asOf) {
+			break
+		}
+		switch e.eventType {
+		case "task.updated":
+			for key, value := range e.payload {
+				if _, ok := fields[key]; ok {
+					fields[key] = value
+					delete(unknown, key)
+				}
+			}
+		case "task.moved":
+			if project, ok := e.payload["new_project_uuid"]; ok {
+				fields["project_uuid"] = project
+			}
+		case "task.archived":
+			fields["state"] = "archived"
+		case "task.deleted":
+			fields["state"] = "deleted"
+		case "task.restored":
+			fields["state"] = "open"
+			if state, ok := e.payload["target_state"].(string); ok && state != "" {
+				fields["state"] = state
+			}
+			if project, ok := e.payload["moved_to"]; ok {
+				fields["project_uuid"] = project
+			}
+		}
+		snap.EventID = e.id
+		if e.etag.Valid {
+			snap.ETag = e.etag.Int64
+		}
+	}
+
+	snap.Fields = fields
+	for key := range unknown {
+		snap.Unknown = append(snap.Unknown, key)
+	}
+	sort.Strings(snap.Unknown)
+	return snap, nil
+}
+
+// currentTaskFields reads the snapshotFields columns of a task's row.
+func (s *Store) currentTaskFields(taskUUID string) (map[string]interface{}, error) {
+	values := make([]interface{}, len(snapshotFields))
+	ptrs := make([]interface{}, len(snapshotFields))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	query := "SELECT " + strings.Join(snapshotFields, ", ") + " FROM tasks WHERE uuid = ?"
+	if err := s.db.QueryRow(query, taskUUID).Scan(ptrs...); err != nil {
+		return nil, fmt.Errorf("failed to read task: %w", err)
+	}
+
+	fields := make(map[string]interface{}, len(snapshotFields))
+	for i, key := range snapshotFields {
+		if b, ok := values[i].([]byte); ok {
+			values[i] = string(b)
+		}
+		fields[key] = values[i]
+	}
+	return fields, nil
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+
+	"github.com/lherron/wrkq/internal/domain"
+)
+
+// taskKindPattern mirrors the CHECK on task_kinds.kind.
+var taskKindPattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,31}$`)
+
+// TaskKindStore handles the task kind vocabulary in the task_kinds table.
+//
+// Like views, kinds are configuration rather than tracked work: they carry
+// no etag and changes are not written to the event log.
+type TaskKindStore struct {
+	store *Store
+}
+
+type rowQuerier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// List returns the configured task kinds in alphabetical order.
+func (ks *TaskKindStore) List() ([]string, error) {
+	return taskKinds(ks.store.db)
+}
+
+// Add adds kind to the vocabulary. Adding a kind that exists is a no-op.
+func (ks *TaskKindStore) Add(kind string) error {
+	if !taskKindPattern.MatchString(kind) {
+		return &domain.ValidationError{Message: fmt.Sprintf("invalid task kind %q: use lowercase letters, digits, '-' and '_', at most 32 characters", kind)}
+	}
+	if _, err := ks.store.db.Exec("INSERT INTO task_kinds (kind) VALUES (?) ON CONFLICT (kind) DO NOTHING", kind); err != nil {
+		return fmt.Errorf("failed to add task kind: %w", err)
+	}
+	return nil
+}
+
+// Remove removes kind from the vocabulary. A kind still used by a task
+// can't be removed.
+func (ks *TaskKindStore) Remove(kind string) error {
+	var inUse int
+	if err := ks.store.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE kind = ?", kind).Scan(&inUse); err != nil {
+		return fmt.Errorf("failed to check task kind: %w", err)
+	}
+	if inUse > 0 {
+		return &domain.ValidationError{Message: fmt.Sprintf("task kind %q is used by %d task(s)", kind, inUse)}
+	}
+	res, err := ks.store.db.Exec("DELETE FROM task_kinds WHERE kind = ?", kind)
+	if err != nil {
+		return fmt.Errorf("failed to remove task kind: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("task kind not found: %s", kind)
+	}
+	return nil
+}
+
+func taskKinds(q rowQuerier) ([]string, error) {
+	rows, err := q.Query("SELECT kind FROM task_kinds ORDER BY kind")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list task kinds: %w", err)
+	}
+	defer rows.Close()
+
+	kinds := []string{}
+	for rows.Next() {
+		var kind string
+		if err := rows.Scan(&kind); err != nil {
+			return nil, fmt.Errorf("failed to scan task kind: %w", err)
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, rows.Err()
+}
+
+// validateTaskKindTx checks kind against the vocabulary as seen by tx.
+func validateTaskKindTx(tx *sql.Tx, kind string) error {
+	if kind == "" {
+		return nil
+	}
+	kinds, err := taskKinds(tx)
+	if err != nil {
+		return err
+	}
+	return domain.ValidateTaskKind(kind, kinds)
+}
@@ -0,0 +1,148 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lherron/wrkq/internal/domain"
+	"github.com/lherron/wrkq/internal/events"
+)
+
+// ReactionStore handles reactions on tasks and comments.
+//
+// Adding a reaction the actor already left, or removing one they didn't,
+// changes nothing and logs no event, so clients can retry freely. Reactions
+// don't bump the etag of the resource they are on.
+type ReactionStore struct {
+	store *Store
+}
+
+func validateReactionTarget(resourceType, emoji string) error {
+	if resourceType != "task" && resourceType != "comment" {
+		return fmt.Errorf("invalid reaction resource type: %s (must be task or comment)", resourceType)
+	}
+	return domain.ValidateReaction(emoji)
+}
+
+// Add records actorUUID's reaction on a resource and logs a reaction.added
+// event. It reports whether the reaction was new.
+func (rs *ReactionStore) Add(actorUUID, resourceType, resourceUUID, emoji string) (bool, error) {
+	if err := validateReactionTarget(resourceType, emoji); err != nil {
+		return false, err
+	}
+
+	var added bool
+	err := rs.store.withTx(func(tx *sql.Tx, ew *events.Writer) error {
+		result, err := tx.Exec(`
+			INSERT INTO reactions (resource_type, resource_uuid, actor_uuid, emoji)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(resource_type, resource_uuid, actor_uuid, emoji) DO NOTHING
+		`, resourceType, resourceUUID, actorUUID, emoji)
+		if err != nil {
+			return fmt.Errorf("failed to add reaction: %w", err)
+		}
+		n, _ := result.RowsAffected()
+		if n == 0 {
+			return nil
+		}
+		added = true
+		return logReactionEvent(tx, ew, actorUUID, resourceType, resourceUUID, "reaction.added", emoji)
+	})
+	return added, err
+}
+
+// Remove deletes actorUUID's reaction from a resource and logs a
+// reaction.removed event. It reports whether there was a reaction to remove.
+func (rs *ReactionStore) Remove(actorUUID, resourceType, resourceUUID, emoji string) (bool, error) {
+	if err := validateReactionTarget(resourceType, emoji); err != nil {
+		return false, err
+	}
+
+	var removed bool
+	err := rs.store.withTx(func(tx *sql.Tx, ew *events.Writer) error {
+		result, err := tx.Exec(`
+			DELETE FROM reactions
+			WHERE resource_type = ? AND resource_uuid = ? AND actor_uuid = ? AND emoji = ?
+		`, resourceType, resourceUUID, actorUUID, emoji)
+		if err != nil {
+			return fmt.Errorf("failed to remove reaction: %w", err)
+		}
+		n, _ := result.RowsAffected()
+		if n == 0 {
+			return nil
+		}
+		removed = true
+		return logReactionEvent(tx, ew, actorUUID, resourceType, resourceUUID, "reaction.removed", emoji)
+	})
+	return removed, err
+}
+
+func logReactionEvent(tx *sql.Tx, ew *events.Writer, actorUUID, resourceType, resourceUUID, eventType, emoji string) error {
+	payload, err := json.Marshal(map[string]string{"emoji": emoji})
+	if err != nil {
+		return fmt.Errorf("failed to marshal event payload: %w", err)
+	}
+	payloadStr := string(payload)
+	if err := ew.LogEvent(tx, &domain.Event{
+		ActorUUID:    &actorUUID,
+		ResourceType: resourceType,
+		ResourceUUID: &resourceUUID,
+		EventType:    eventType,
+		Payload:      &payloadStr,
+	}); err != nil {
+		return fmt.Errorf("failed to log event: %w", err)
+	}
+	return nil
+}
+
+// List returns the reactions on a resource, oldest first.
+func (rs *ReactionStore) List(resourceType, resourceUUID string) ([]domain.Reaction, error) {
+	rows, err := rs.store.db.Query(`
+		SELECT r.resource_type, r.resource_uuid, r.actor_uuid, COALESCE(a.slug, ''), r.emoji, r.created_at
+		FROM reactions r
+		LEFT JOIN actors a ON a.uuid = r.actor_uuid
+		WHERE r.resource_type = ? AND r.resource_uuid = ?
+		ORDER BY r.created_at, r.rowid
+	`, resourceType, resourceUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reactions: %w", err)
+	}
+	defer rows.Close()
+
+	reactions := []domain.Reaction{}
+	for rows.Next() {
+		var r domain.Reaction
+		var createdAt string
+		if err := rows.Scan(&r.ResourceType, &r.ResourceUUID, &r.ActorUUID, &r.ActorSlug, &r.Emoji, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan reaction: %w", err)
+		}
+		r.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		reactions = append(reactions, r)
+	}
+	return reactions, rows.Err()
+}
+
+// Summary groups the reactions on a resource by emoji, in the order each
+// emoji was first used.
+func (rs *ReactionStore) Summary(resourceType, resourceUUID string) ([]domain.ReactionSummary, error) {
+	reactions, err := rs.List(resourceType, resourceUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := []domain.ReactionSummary{}
+	index := make(map[string]int)
+	for _, r := range reactions {
+		i, ok := index[r.Emoji]
+		if !ok {
+			i = len(summaries)
+			index[r.Emoji] = i
+			summaries = append(summaries, domain.ReactionSummary{Emoji: r.Emoji, Actors: []string{}})
+		}
+		summaries[i].Count++
+		summaries[i].Actors = append(summaries[i].Actors, r.ActorSlug)
+	}
+	return summaries, nil
+}
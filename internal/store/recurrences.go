@@ -0,0 +1,209 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lherron/wrkq/internal/domain"
+	"github.com/lherron/wrkq/internal/events"
+	"github.com/lherron/wrkq/internal/webhooks"
+)
+
+// maxOccurrencesPerRun bounds how many occurrences one Generate call
+// materializes per recurrence, so a recurrence that started long ago catches
+// up over several runs instead of flooding a project in one.
+const maxOccurrencesPerRun = 100
+
+// RecurrenceStore handles recurrence specs and the generation of their
+// occurrences.
+type RecurrenceStore struct {
+	store *Store
+}
+
+// GeneratedOccurrence is one task materialized from a recurrence.
+type GeneratedOccurrence struct {
+	TemplateUUID string    `json:"template_uuid"`
+	TaskUUID     string    `json:"task_uuid"`
+	TaskID       string    `json:"task_id"`
+	Slug         string    `json:"slug"`
+	OccurrenceAt time.Time `json:"occurrence_at"`
+}
+
+const recurrenceSelect = `
+	SELECT task_uuid, interval, unit, weekday, starts_at, last_generated_at
+	FROM task_recurrences
+`
+
+func scanRecurrence(row interface{ Scan(...interface{}) error }) (*domain.Recurrence, error) {
+	var r domain.Recurrence
+	var weekday sql.NullInt64
+	var startsAt string
+	var lastGenerated sql.NullString
+	if err := row.Scan(&r.TaskUUID, &r.Interval, &r.Unit, &weekday, &startsAt, &lastGenerated); err != nil {
+		return nil, err
+	}
+	if weekday.Valid {
+		w := int(weekday.Int64)
+		r.Weekday = &w
+	}
+	r.StartsAt, _ = time.Parse(time.RFC3339, startsAt)
+	if lastGenerated.Valid {
+		t, err := time.Parse(time.RFC3339, lastGenerated.String)
+		if err == nil {
+			r.LastGeneratedAt = &t
+		}
+	}
+	return &r, nil
+}
+
+// Set creates or replaces the recurrence of a task. Replacing a spec keeps
+// its last_generated_at so occurrences already materialized aren't repeated.
+func (rs *RecurrenceStore) Set(r domain.Recurrence) (*domain.Recurrence, error) {
+	if err := domain.ValidateRecurrence(&r); err != nil {
+		return nil, err
+	}
+
+	_, err := rs.store.db.Exec(`
+		INSERT INTO task_recurrences (task_uuid, interval, unit, weekday, starts_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(task_uuid) DO UPDATE SET
+			interval = excluded.interval,
+			unit = excluded.unit,
+			weekday = excluded.weekday,
+			starts_at = excluded.starts_at
+	`, r.TaskUUID, r.Interval, r.Unit, r.Weekday, r.StartsAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save recurrence: %w", err)
+	}
+
+	saved, err := scanRecurrence(rs.store.db.QueryRow(recurrenceSelect+" WHERE task_uuid = ?", r.TaskUUID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load recurrence: %w", err)
+	}
+	return saved, nil
+}
+
+// List returns every recurrence ordered by template task.
+func (rs *RecurrenceStore) List() ([]domain.Recurrence, error) {
+	return listRecurrences(rs.store.db)
+}
+
+func listRecurrences(q interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}) ([]domain.Recurrence, error) {
+	rows, err := q.Query(recurrenceSelect + " ORDER BY task_uuid")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recurrences: %w", err)
+	}
+	defer rows.Close()
+
+	recurrences := []domain.Recurrence{}
+	for rows.Next() {
+		r, err := scanRecurrence(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan recurrence: %w", err)
+		}
+		recurrences = append(recurrences, *r)
+	}
+	return recurrences, rows.Err()
+}
+
+// Generate materializes every occurrence due at now as a new open task in
+// the template task's container, copying its title, description, kind,
+// priority, labels and assignee, due at the occurrence time and linked back
+// to the template with a relates_to relation. Everything happens in one
+// transaction that reads the specs and advances each last_generated_at, so
+// running it again for the same now, or concurrently, creates nothing twice.
+// Templates that are archived or deleted are skipped.
+func (rs *RecurrenceStore) Generate(actorUUID string, now time.Time) ([]GeneratedOccurrence, error) {
+	generated := []GeneratedOccurrence{}
+	err := rs.store.withTx(func(tx *sql.Tx, ew *events.Writer) error {
+		recurrences, err := listRecurrences(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range recurrences {
+			due := r.DueOccurrences(now, maxOccurrencesPerRun)
+			if len(due) == 0 {
+				continue
+			}
+
+			var template CreateParams
+			var state string
+			var assignee sql.NullString
+			err := tx.QueryRow(`
+				SELECT slug, title, COALESCE(description, ''), project_uuid, state, priority,
+				       COALESCE(kind, ''), COALESCE(labels, ''), assignee_actor_uuid
+				FROM tasks WHERE uuid = ?
+			`, r.TaskUUID).Scan(&template.Slug, &template.Title, &template.Description, &template.ProjectUUID,
+				&state, &template.Priority, &template.Kind, &template.Labels, &assignee)
+			if err != nil {
+				return fmt.Errorf("failed to load template task %s: %w", r.TaskUUID, err)
+			}
+			if state == "archived" || state == "deleted" {
+				continue
+			}
+			if assignee.Valid {
+				template.AssigneeActorUUID = &assignee.String
+			}
+
+			for _, at := range due {
+				params := template
+				params.Slug = fmt.Sprintf("%s-%s", template.Slug, at.UTC().Format("20060102"))
+				params.State = "open"
+				params.DueAt = at.UTC().Format(time.RFC3339)
+
+				created, err := createTaskTx(tx, ew, actorUUID, params)
+				if err != nil {
+					return fmt.Errorf("failed to create occurrence %s: %w", params.Slug, err)
+				}
+
+				meta, _ := json.Marshal(map[string]string{"recurrence_of": r.TaskUUID, "occurrence_at": params.DueAt})
+				if _, err := tx.Exec(`
+					INSERT INTO task_relations (from_task_uuid, to_task_uuid, kind, meta, created_by_actor_uuid)
+					VALUES (?, ?, 'relates_to', ?, ?)
+				`, created.UUID, r.TaskUUID, string(meta), actorUUID); err != nil {
+					return fmt.Errorf("failed to link occurrence %s: %w", params.Slug, err)
+				}
+				relationPayload, _ := json.Marshal(map[string]string{"from": created.UUID, "to": r.TaskUUID, "kind": "relates_to"})
+				relationPayloadStr := string(relationPayload)
+				if err := ew.LogEvent(tx, &domain.Event{
+					ActorUUID:    &actorUUID,
+					ResourceType: "task",
+					ResourceUUID: &created.UUID,
+					EventType:    "task.relation.created",
+					Payload:      &relationPayloadStr,
+				}); err != nil {
+					return fmt.Errorf("failed to log event: %w", err)
+				}
+
+				generated = append(generated, GeneratedOccurrence{
+					TemplateUUID: r.TaskUUID,
+					TaskUUID:     created.UUID,
+					TaskID:       created.ID,
+					Slug:         params.Slug,
+					OccurrenceAt: at,
+				})
+			}
+
+			if _, err := tx.Exec(
+				"UPDATE task_recurrences SET last_generated_at = ? WHERE task_uuid = ?",
+				due[len(due)-1].UTC().Format(time.RFC3339), r.TaskUUID,
+			); err != nil {
+				return fmt.Errorf("failed to update recurrence marker: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(generated) > 0 {
+		webhooks.Notify(rs.store.db)
+	}
+	return generated, nil
+}
@@ -0,0 +1,231 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SearchFilters narrows a full-text task search.
+type SearchFilters struct {
+	// ProjectUUID limits results to tasks in that container's subtree
+	ProjectUUID string
+	// PathPrefixes limits results to tasks whose path starts with any prefix
+	PathPrefixes []string
+	// State filters by task state; "" excludes archived and deleted tasks,
+	// "all" includes every state
+	State string
+	// Limit caps the number of results (default 50)
+	Limit int
+}
+
+// SearchResult is a task matching a full-text search, with the snippet from
+// its best-matching document.
+type SearchResult struct {
+	UUID      string   `json:"uuid"`
+	ID        string   `json:"id"`
+	Slug      string   `json:"slug"`
+	Title     string   `json:"title"`
+	State     string   `json:"state"`
+	Priority  int      `json:"priority"`
+	Path      string   `json:"path"`
+	Score     float64  `json:"score"`
+	Snippet   string   `json:"snippet"`
+	MatchedIn []string `json:"matched_in"` // "task" and/or "comment"
+}
+
+const defaultSearchLimit = 50
+
+// searchColumnWeights weights title matches above description and comment
+// body matches, in task_search column order.
+var searchColumnWeights = []float64{2.0, 1.0}
+
+// Search runs a full-text query over task titles, descriptions, and live
+// comment bodies, returning matching tasks ranked by relevance. A task whose
+// comments match is returned even if its own text does not. Query terms are
+// ANDed; a trailing * makes a term a prefix match.
+func (ts *TaskStore) Search(query string, filters SearchFilters) ([]SearchResult, error) {
+	match := buildMatchQuery(query)
+	if match == "" {
+		return nil, fmt.Errorf("search query is required")
+	}
+
+	sqlQuery := `
+		SELECT t.uuid, t.id, t.slug, t.title, t.state, t.priority,
+		       cp.path || '/' || t.slug AS path,
+		       d.entity_uuid != d.task_uuid AS is_comment,
+		       snippet(task_search, '[', ']', '…', -1, 12),
+		       matchinfo(task_search, 'pcx')
+		FROM task_search
+		JOIN search_docs d ON d.docid = task_search.docid
+		JOIN tasks t ON t.uuid = d.task_uuid
+		JOIN v_container_paths cp ON cp.uuid = t.project_uuid
+		WHERE task_search MATCH ?
+	`
+	args := []interface{}{match}
+
+	switch filters.State {
+	case "all":
+	case "":
+		sqlQuery += " AND t.state NOT IN ('archived', 'deleted')"
+	default:
+		sqlQuery += " AND t.state = ?"
+		args = append(args, filters.State)
+	}
+
+	if filters.ProjectUUID != "" {
+		var projectPath string
+		if err := ts.store.db.QueryRow("SELECT path FROM v_container_paths WHERE uuid = ?", filters.ProjectUUID).Scan(&projectPath); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("container not found: %s", filters.ProjectUUID)
+			}
+			return nil, fmt.Errorf("failed to resolve container path: %w", err)
+		}
+		sqlQuery += " AND (cp.path = ? OR cp.path LIKE ? || '/%')"
+		args = append(args, projectPath, projectPath)
+	}
+
+	if len(filters.PathPrefixes) > 0 {
+		var conditions []string
+		for _, prefix := range filters.PathPrefixes {
+			conditions = append(conditions, "(cp.path || '/' || t.slug) LIKE ? || '%'")
+			args = append(args, prefix)
+		}
+		sqlQuery += " AND (" + strings.Join(conditions, " OR ") + ")"
+	}
+
+	rows, err := ts.store.db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search tasks: %w", err)
+	}
+	defer rows.Close()
+
+	// Documents are aggregated per task: scores add up, and the snippet comes
+	// from the best-scoring document
+	byTask := make(map[string]*SearchResult)
+	bestScore := make(map[string]float64)
+	for rows.Next() {
+		var r SearchResult
+		var isComment bool
+		var snippet string
+		var info []byte
+		if err := rows.Scan(&r.UUID, &r.ID, &r.Slug, &r.Title, &r.State, &r.Priority, &r.Path,
+			&isComment, &snippet, &info); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+
+		score := matchScore(info)
+		source := "task"
+		if isComment {
+			source = "comment"
+		}
+
+		existing, ok := byTask[r.UUID]
+		if !ok {
+			r.Score = score
+			r.Snippet = snippet
+			r.MatchedIn = []string{source}
+			byTask[r.UUID] = &r
+			bestScore[r.UUID] = score
+			continue
+		}
+		existing.Score += score
+		if score > bestScore[r.UUID] {
+			bestScore[r.UUID] = score
+			existing.Snippet = snippet
+		}
+		if !containsString(existing.MatchedIn, source) {
+			existing.MatchedIn = append(existing.MatchedIn, source)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating search results: %w", err)
+	}
+
+	results := make([]SearchResult, 0, len(byTask))
+	for _, r := range byTask {
+		sort.Strings(r.MatchedIn)
+		results = append(results, *r)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+
+	limit := filters.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+// buildMatchQuery turns free text into an FTS MATCH expression where every
+// term is quoted, so user input can't produce FTS syntax errors.
+func buildMatchQuery(query string) string {
+	var terms []string
+	for _, field := range strings.Fields(query) {
+		prefix := strings.HasSuffix(field, "*")
+		term := strings.Trim(field, `"*`)
+		term = strings.ReplaceAll(term, `"`, "")
+		if term == "" {
+			continue
+		}
+		if prefix {
+			term += "*"
+		}
+		terms = append(terms, `"`+term+`"`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// matchScore computes a tf-idf style score from matchinfo(..., 'pcx'): for
+// every phrase and column, hits in this document over hits in all documents,
+// weighted by column.
+func matchScore(info []byte) float64 {
+	if len(info) < 8 {
+		return 0
+	}
+	ints := make([]uint32, len(info)/4)
+	for i := range ints {
+		ints[i] = binary.NativeEndian.Uint32(info[i*4:])
+	}
+
+	phrases, columns := int(ints[0]), int(ints[1])
+	var score float64
+	for p := 0; p < phrases; p++ {
+		for c := 0; c < columns; c++ {
+			idx := 2 + 3*(p*columns+c)
+			if idx+1 >= len(ints) {
+				return score
+			}
+			hitsHere, hitsAll := ints[idx], ints[idx+1]
+			if hitsHere == 0 || hitsAll == 0 {
+				continue
+			}
+			weight := 1.0
+			if c < len(searchColumnWeights) {
+				weight = searchColumnWeights[c]
+			}
+			score += weight * float64(hitsHere) / float64(hitsAll)
+		}
+	}
+	return score
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
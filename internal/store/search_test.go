@@ -0,0 +1,89 @@
+package store
+
+import (
+	"testing"
+)
+
+func TestTaskStore_SearchMatchesComments(t *testing.T) {
+	database := setupTestDB(t)
+	actorUUID := setupTestActor(t, database)
+	containerUUID := setupTestContainer(t, database, actorUUID)
+	s := New(database)
+
+	commented, err := s.Tasks.Create(actorUUID, CreateParams{
+		Slug: "commented", Title: "Refresh tokens", ProjectUUID: containerUUID, State: "open", Priority: 2,
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	titled, err := s.Tasks.Create(actorUUID, CreateParams{
+		Slug: "titled", Title: "Fix flaky websocket reconnect", Description: "Happens on resume",
+		ProjectUUID: containerUUID, State: "open", Priority: 2,
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// "websocket" appears in the second task's title, and only in a comment on the first
+	if _, err := database.Exec(`
+		INSERT INTO comments (uuid, id, task_uuid, actor_uuid, body)
+		VALUES ('00000000-0000-0000-0000-0000000000c1', 'C-00001', ?, ?, 'Root cause is the websocket keepalive')
+	`, commented.UUID, actorUUID); err != nil {
+		t.Fatalf("failed to insert comment: %v", err)
+	}
+
+	results, err := s.Tasks.Search("websocket", SearchFilters{})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d: %+v", len(results), results)
+	}
+	// Title matches rank above comment matches
+	if results[0].UUID != titled.UUID || results[1].UUID != commented.UUID {
+		t.Fatalf("unexpected ranking: %+v", results)
+	}
+	if got := results[1].MatchedIn; len(got) != 1 || got[0] != "comment" {
+		t.Errorf("expected comment match, got %v", got)
+	}
+	if results[1].Snippet != "Root cause is the [websocket] keepalive" {
+		t.Errorf("unexpected snippet: %q", results[1].Snippet)
+	}
+
+	// Terms are ANDed and prefixes are supported
+	results, err = s.Tasks.Search("keep* root", SearchFilters{})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].UUID != commented.UUID {
+		t.Fatalf("expected only the commented task, got %+v", results)
+	}
+
+	// Soft-deleted comments drop out of the index
+	if _, err := database.Exec("UPDATE comments SET deleted_at = '2025-01-01T00:00:00Z' WHERE id = 'C-00001'"); err != nil {
+		t.Fatalf("failed to delete comment: %v", err)
+	}
+	results, err = s.Tasks.Search("keepalive", SearchFilters{})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 0 {
+		t.Fatalf("expected no results after comment delete, got %+v", results)
+	}
+
+	// Title edits are reindexed
+	if _, err := s.Tasks.UpdateFields(actorUUID, commented.UUID, map[string]interface{}{"title": "Rotate refresh secrets"}, 0); err != nil {
+		t.Fatalf("UpdateFields failed: %v", err)
+	}
+	results, err = s.Tasks.Search("rotate", SearchFilters{})
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].UUID != commented.UUID {
+		t.Fatalf("expected renamed task, got %+v", results)
+	}
+
+	if _, err := s.Tasks.Search(`  "" `, SearchFilters{}); err == nil {
+		t.Error("expected empty query to fail")
+	}
+}
@@ -0,0 +1,452 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/lherron/wrkq/internal/domain"
+	"github.com/lherron/wrkq/internal/events"
+)
+
+// SectionStore handles section (kanban column) persistence operations.
+//
+// Sections have no etag or event resource type of their own, so changes are
+// logged as section.* events against the owning project container.
+type SectionStore struct {
+	store *Store
+}
+
+// SectionCreateParams contains parameters for creating a new section.
+type SectionCreateParams struct {
+	ProjectUUID string
+	Slug        string
+	Title       string // defaults to Slug if empty
+	Role        string // defaults to "ready"
+	OrderIndex  *int   // defaults to after the last section
+	IsDefault   bool
+	WIPLimit    *int
+	Meta        *string
+}
+
+const sectionSelect = `
+	SELECT uuid, id, project_uuid, slug, title, order_index, role, is_default,
+	       wip_limit, meta, created_at, updated_at, archived_at
+	FROM sections
+`
+
+func scanSection(row interface{ Scan(...interface{}) error }) (*domain.Section, error) {
+	var s domain.Section
+	var id, meta, archivedAt sql.NullString
+	var wipLimit sql.NullInt64
+	var role, createdAt, updatedAt string
+	var isDefault int
+
+	if err := row.Scan(&s.UUID, &id, &s.ProjectUUID, &s.Slug, &s.Title, &s.OrderIndex, &role, &isDefault,
+		&wipLimit, &meta, &createdAt, &updatedAt, &archivedAt); err != nil {
+		return nil, err
+	}
+
+	s.ID = id.String
+	s.Role = domain.SectionRole(role)
+	s.IsDefault = isDefault != 0
+	if wipLimit.Valid {
+		limit := int(wipLimit.Int64)
+		s.WIPLimit = &limit
+	}
+	if meta.Valid {
+		s.Meta = &meta.String
+	}
+	s.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	s.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	if archivedAt.Valid {
+		t, err := time.Parse(time.RFC3339, archivedAt.String)
+		if err == nil {
+			s.ArchivedAt = &t
+		}
+	}
+	return &s, nil
+}
+
+// Get returns a section by UUID.
+func (ss *SectionStore) Get(sectionUUID string) (*domain.Section, error) {
+	section, err := scanSection(ss.store.db.QueryRow(sectionSelect+" WHERE uuid = ?", sectionUUID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("section not found: %s", sectionUUID)
+	}
+	return section, err
+}
+
+// List returns the sections of a project ordered by order_index.
+func (ss *SectionStore) List(projectUUID string, includeArchived bool) ([]domain.Section, error) {
+	query := sectionSelect + " WHERE project_uuid = ?"
+	if !includeArchived {
+		query += " AND archived_at IS NULL"
+	}
+	query += " ORDER BY order_index, id"
+
+	rows, err := ss.store.db.Query(query, projectUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sections: %w", err)
+	}
+	defer rows.Close()
+
+	sections := []domain.Section{}
+	for rows.Next() {
+		section, err := scanSection(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan section: %w", err)
+		}
+		sections = append(sections, *section)
+	}
+	return sections, rows.Err()
+}
+
+// Resolve finds a section within a project by UUID, friendly ID (S-00001), or slug.
+func (ss *SectionStore) Resolve(projectUUID, selector string) (string, error) {
+	var uuid string
+	err := ss.store.db.QueryRow(`
+		SELECT uuid FROM sections
+		WHERE project_uuid = ? AND (uuid = ? OR id = ? OR slug = ?)
+	`, projectUUID, selector, strings.ToUpper(selector), selector).Scan(&uuid)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("section not found: %s", selector)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve section: %w", err)
+	}
+	return uuid, nil
+}
+
+// NormalizeWIPLimit converts a wip_limit value from any writer (a typed
+// *int, a decoded JSON number, or a scanned column) to *int and checks that
+// it is positive. A nil value clears the limit.
+func NormalizeWIPLimit(value interface{}) (*int, error) {
+	var limit int
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case *int:
+		if v == nil {
+			return nil, nil
+		}
+		limit = *v
+	case int:
+		limit = v
+	case int64:
+		limit = int(v)
+	case float64:
+		if v != math.Trunc(v) {
+			return nil, fmt.Errorf("wip_limit must be an integer or null")
+		}
+		limit = int(v)
+	case sql.NullInt64:
+		if !v.Valid {
+			return nil, nil
+		}
+		limit = int(v.Int64)
+	default:
+		return nil, fmt.Errorf("wip_limit must be an integer or null")
+	}
+	if limit < 1 {
+		return nil, fmt.Errorf("wip_limit must be positive")
+	}
+	return &limit, nil
+}
+
+// Create creates a new section and logs a section.created event on the project.
+func (ss *SectionStore) Create(actorUUID string, params SectionCreateParams) (*domain.Section, error) {
+	title := params.Title
+	if title == "" {
+		title = params.Slug
+	}
+	role := params.Role
+	if role == "" {
+		role = string(domain.SectionRoleReady)
+	}
+	if err := domain.ValidateSectionRole(role); err != nil {
+		return nil, err
+	}
+	if _, err := NormalizeWIPLimit(params.WIPLimit); err != nil {
+		return nil, err
+	}
+
+	var sectionUUID string
+	err := ss.store.withTx(func(tx *sql.Tx, ew *events.Writer) error {
+		orderIndex := 0
+		if params.OrderIndex != nil {
+			orderIndex = *params.OrderIndex
+		} else if err := tx.QueryRow(
+			"SELECT COALESCE(MAX(order_index) + 1, 0) FROM sections WHERE project_uuid = ?",
+			params.ProjectUUID,
+		).Scan(&orderIndex); err != nil {
+			return fmt.Errorf("failed to compute order index: %w", err)
+		}
+
+		if params.IsDefault {
+			if err := clearDefaultSection(tx, params.ProjectUUID); err != nil {
+				return err
+			}
+		}
+
+		res, err := tx.Exec(`
+			INSERT INTO sections (id, project_uuid, slug, title, order_index, role, is_default, wip_limit, meta)
+			VALUES ('', ?, ?, ?, ?, ?, ?, ?, ?)
+		`, params.ProjectUUID, params.Slug, title, orderIndex, role, boolToInt(params.IsDefault), params.WIPLimit, params.Meta)
+		if err != nil {
+			return fmt.Errorf("failed to create section: %w", err)
+		}
+
+		rowID, err := res.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get last insert ID: %w", err)
+		}
+		if err := tx.QueryRow("SELECT uuid FROM sections WHERE rowid = ?", rowID).Scan(&sectionUUID); err != nil {
+			return fmt.Errorf("failed to get section UUID: %w", err)
+		}
+
+		return logSectionEvent(tx, ew, actorUUID, params.ProjectUUID, "section.created", map[string]interface{}{
+			"section_uuid": sectionUUID,
+			"slug":         params.Slug,
+			"title":        title,
+			"role":         role,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ss.Get(sectionUUID)
+}
+
+// UpdateFields updates specified fields on a section and logs a section.updated event.
+// Supported fields: slug, title, role, is_default, wip_limit (nil clears), meta.
+func (ss *SectionStore) UpdateFields(actorUUID, sectionUUID string, fields map[string]interface{}) (*domain.Section, error) {
+	err := ss.store.withTx(func(tx *sql.Tx, ew *events.Writer) error {
+		var projectUUID string
+		if err := tx.QueryRow("SELECT project_uuid FROM sections WHERE uuid = ?", sectionUUID).Scan(&projectUUID); err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("section not found: %s", sectionUUID)
+			}
+			return fmt.Errorf("failed to load section: %w", err)
+		}
+
+		var setClauses []string
+		var args []interface{}
+		changed := []string{}
+		for _, field := range []string{"slug", "title", "role", "is_default", "wip_limit", "meta"} {
+			value, ok := fields[field]
+			if !ok {
+				continue
+			}
+			switch field {
+			case "role":
+				role, _ := value.(string)
+				if err := domain.ValidateSectionRole(role); err != nil {
+					return err
+				}
+			case "wip_limit":
+				limit, err := NormalizeWIPLimit(value)
+				if err != nil {
+					return err
+				}
+				value = limit
+			case "is_default":
+				isDefault, _ := value.(bool)
+				if isDefault {
+					if err := clearDefaultSection(tx, projectUUID); err != nil {
+						return err
+					}
+				}
+				value = boolToInt(isDefault)
+			}
+			setClauses = append(setClauses, field+" = ?")
+			args = append(args, value)
+			changed = append(changed, field)
+		}
+
+		if len(setClauses) == 0 {
+			return nil
+		}
+
+		args = append(args, sectionUUID)
+		if _, err := tx.Exec("UPDATE sections SET "+strings.Join(setClauses, ", ")+" WHERE uuid = ?", args...); err != nil {
+			return fmt.Errorf("failed to update section: %w", err)
+		}
+
+		return logSectionEvent(tx, ew, actorUUID, projectUUID, "section.updated", map[string]interface{}{
+			"section_uuid": sectionUUID,
+			"fields":       changed,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ss.Get(sectionUUID)
+}
+
+// Reorder rewrites order_index for a project's sections in one transaction.
+// Listed sections take positions 0..n-1; unlisted sections keep their relative
+// order after them.
+func (ss *SectionStore) Reorder(actorUUID, projectUUID string, sectionUUIDs []string) ([]domain.Section, error) {
+	err := ss.store.withTx(func(tx *sql.Tx, ew *events.Writer) error {
+		rows, err := tx.Query("SELECT uuid FROM sections WHERE project_uuid = ? ORDER BY order_index, id", projectUUID)
+		if err != nil {
+			return fmt.Errorf("failed to load sections: %w", err)
+		}
+		var existing []string
+		for rows.Next() {
+			var uuid string
+			if err := rows.Scan(&uuid); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan section: %w", err)
+			}
+			existing = append(existing, uuid)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		known := make(map[string]bool, len(existing))
+		for _, uuid := range existing {
+			known[uuid] = true
+		}
+
+		ordered := make([]string, 0, len(existing))
+		listed := make(map[string]bool, len(sectionUUIDs))
+		for _, uuid := range sectionUUIDs {
+			if !known[uuid] {
+				return fmt.Errorf("section %s does not belong to project", uuid)
+			}
+			if listed[uuid] {
+				return fmt.Errorf("section %s listed more than once", uuid)
+			}
+			listed[uuid] = true
+			ordered = append(ordered, uuid)
+		}
+		for _, uuid := range existing {
+			if !listed[uuid] {
+				ordered = append(ordered, uuid)
+			}
+		}
+
+		for i, uuid := range ordered {
+			if _, err := tx.Exec("UPDATE sections SET order_index = ? WHERE uuid = ? AND order_index != ?", i, uuid, i); err != nil {
+				return fmt.Errorf("failed to reorder section: %w", err)
+			}
+		}
+
+		return logSectionEvent(tx, ew, actorUUID, projectUUID, "section.reordered", map[string]interface{}{
+			"order": ordered,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ss.List(projectUUID, true)
+}
+
+// countsTowardWIP reports whether a task in state occupies a WIP slot.
+// An empty state means the schema default (open).
+func countsTowardWIP(state string) bool {
+	switch state {
+	case "", "draft", "open", "in_progress", "blocked":
+		return true
+	}
+	return false
+}
+
+// checkWIPLimitTx returns a WIPLimitExceededError if adding one more active task
+// to containerUUID would exceed the wip_limit of the container's section.
+// excludeTaskUUID is not counted (used when a task moves between containers);
+// a move within the same section never fails.
+func checkWIPLimitTx(tx *sql.Tx, containerUUID, excludeTaskUUID string) error {
+	var sectionUUID string
+	var sectionID sql.NullString
+	var limit int
+	err := tx.QueryRow(`
+		SELECT s.uuid, s.id, s.wip_limit
+		FROM containers c
+		JOIN sections s ON s.uuid = c.section_uuid
+		WHERE c.uuid = ? AND s.wip_limit IS NOT NULL AND s.archived_at IS NULL
+	`, containerUUID).Scan(&sectionUUID, &sectionID, &limit)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load section wip limit: %w", err)
+	}
+
+	if excludeTaskUUID != "" {
+		var alreadyInSection int
+		if err := tx.QueryRow(`
+			SELECT COUNT(*) FROM tasks t
+			JOIN containers c ON c.uuid = t.project_uuid
+			WHERE t.uuid = ? AND c.section_uuid = ?
+		`, excludeTaskUUID, sectionUUID).Scan(&alreadyInSection); err != nil {
+			return fmt.Errorf("failed to check task section: %w", err)
+		}
+		if alreadyInSection > 0 {
+			return nil
+		}
+	}
+
+	var count int
+	if err := tx.QueryRow(`
+		SELECT COUNT(*) FROM tasks t
+		JOIN containers c ON c.uuid = t.project_uuid
+		WHERE c.section_uuid = ?
+		  AND t.state IN ('draft', 'open', 'in_progress', 'blocked')
+	`, sectionUUID).Scan(&count); err != nil {
+		return fmt.Errorf("failed to count section tasks: %w", err)
+	}
+
+	if count >= limit {
+		return &domain.WIPLimitExceededError{
+			SectionUUID: sectionUUID,
+			SectionID:   sectionID.String,
+			Limit:       limit,
+			Count:       count,
+		}
+	}
+	return nil
+}
+
+func clearDefaultSection(tx *sql.Tx, projectUUID string) error {
+	if _, err := tx.Exec("UPDATE sections SET is_default = 0 WHERE project_uuid = ? AND is_default = 1", projectUUID); err != nil {
+		return fmt.Errorf("failed to clear default section: %w", err)
+	}
+	return nil
+}
+
+func logSectionEvent(tx *sql.Tx, ew *events.Writer, actorUUID, projectUUID, eventType string, payload map[string]interface{}) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event payload: %w", err)
+	}
+	payloadStr := string(payloadJSON)
+
+	if err := ew.LogEvent(tx, &domain.Event{
+		ActorUUID:    &actorUUID,
+		ResourceType: "container",
+		ResourceUUID: &projectUUID,
+		EventType:    eventType,
+		Payload:      &payloadStr,
+	}); err != nil {
+		return fmt.Errorf("failed to log event: %w", err)
+	}
+	return nil
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
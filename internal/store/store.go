@@ -16,8 +16,16 @@ type Store struct {
 	db *db.DB
 
 	// Domain-specific stores
-	Tasks      *TaskStore
-	Containers *ContainerStore
+	Tasks       *TaskStore
+	Containers  *ContainerStore
+	Sections    *SectionStore
+	Views       *ViewStore
+	Templates   *TemplateStore
+	Recurrences *RecurrenceStore
+	Reactions   *ReactionStore
+	Kinds       *TaskKindStore
+	TimeEntries *TimeEntryStore
+	Tokens      *TokenStore
 }
 
 // New creates a new Store wrapping the given database connection.
@@ -25,6 +33,14 @@ func New(database *db.DB) *Store {
 	s := &Store{db: database}
 	s.Tasks = &TaskStore{store: s}
 	s.Containers = &ContainerStore{store: s}
+	s.Sections = &SectionStore{store: s}
+	s.Views = &ViewStore{store: s}
+	s.Templates = &TemplateStore{store: s}
+	s.Recurrences = &RecurrenceStore{store: s}
+	s.Reactions = &ReactionStore{store: s}
+	s.Kinds = &TaskKindStore{store: s}
+	s.TimeEntries = &TimeEntryStore{store: s}
+	s.Tokens = &TokenStore{store: s}
 	return s
 }
 
@@ -1,11 +1,16 @@
 package store
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/lherron/wrkq/internal/db"
+	"github.com/lherron/wrkq/internal/domain"
 )
 
 // setupTestDB creates a temporary test database with migrations applied.
@@ -185,6 +190,177 @@ func TestTaskStore_UpdateFields(t *testing.T) {
 	}
 }
 
+func TestTaskStore_EnforcesTaskKinds(t *testing.T) {
+	database := setupTestDB(t)
+	actorUUID := setupTestActor(t, database)
+	containerUUID := setupTestContainer(t, database, actorUUID)
+	s := New(database)
+
+	var validationErr *domain.ValidationError
+	_, err := s.Tasks.Create(actorUUID, CreateParams{Slug: "outage", Title: "Outage", ProjectUUID: containerUUID, State: "open", Priority: 3, Kind: "incident"})
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a validation error for an unknown kind, got %v", err)
+	}
+
+	if err := s.Kinds.Add("incident"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	result, err := s.Tasks.Create(actorUUID, CreateParams{Slug: "outage", Title: "Outage", ProjectUUID: containerUUID, State: "open", Priority: 3, Kind: "incident"})
+	if err != nil {
+		t.Fatalf("Create with a configured kind failed: %v", err)
+	}
+
+	if _, err := s.Tasks.UpdateFields(actorUUID, result.UUID, map[string]interface{}{"kind": "story"}, 0); !errors.As(err, &validationErr) {
+		t.Errorf("expected a validation error updating to an unknown kind, got %v", err)
+	}
+	if _, err := s.Tasks.UpdateFields(actorUUID, result.UUID, map[string]interface{}{"kind": "epic"}, 0); err != nil {
+		t.Errorf("update to a default kind failed: %v", err)
+	}
+	if err := s.Kinds.Remove("epic"); !errors.As(err, &validationErr) {
+		t.Errorf("expected a kind in use to be kept, got %v", err)
+	}
+
+	// The schema enforces the vocabulary for writes that bypass the store
+	if _, err := database.Exec("UPDATE tasks SET kind = 'story' WHERE uuid = ?", result.UUID); err == nil {
+		t.Error("expected the kind trigger to reject an unknown kind")
+	}
+	if _, err := database.Exec("UPDATE tasks SET kind = '' WHERE uuid = ?", result.UUID); err != nil {
+		t.Errorf("expected an empty kind to be accepted, got %v", err)
+	}
+}
+
+func TestTaskStore_SubtaskProgressRollup(t *testing.T) {
+	database := setupTestDB(t)
+	actorUUID := setupTestActor(t, database)
+	containerUUID := setupTestContainer(t, database, actorUUID)
+	s := New(database)
+
+	parent, err := s.Tasks.Create(actorUUID, CreateParams{Slug: "parent", Title: "Parent", ProjectUUID: containerUUID, State: "open", Priority: 3})
+	if err != nil {
+		t.Fatalf("Create parent failed: %v", err)
+	}
+	var subtasks []string
+	for _, slug := range []string{"first", "second"} {
+		result, err := s.Tasks.Create(actorUUID, CreateParams{
+			Slug: slug, Title: slug, ProjectUUID: containerUUID, State: "open", Priority: 3,
+			Kind: "subtask", ParentTaskUUID: &parent.UUID,
+		})
+		if err != nil {
+			t.Fatalf("Create subtask failed: %v", err)
+		}
+		subtasks = append(subtasks, result.UUID)
+	}
+	parentState := func() (int64, *string) {
+		t.Helper()
+		var etag int64
+		var progress *string
+		if err := database.QueryRow("SELECT etag, subtask_progress FROM tasks WHERE uuid = ?", parent.UUID).Scan(&etag, &progress); err != nil {
+			t.Fatalf("failed to read parent: %v", err)
+		}
+		return etag, progress
+	}
+
+	// Off by default: the parent is left alone
+	if _, err := s.Tasks.UpdateFields(actorUUID, subtasks[0], map[string]interface{}{"state": "in_progress"}, 0); err != nil {
+		t.Fatalf("UpdateFields failed: %v", err)
+	}
+	if etag, progress := parentState(); etag != parent.ETag || progress != nil {
+		t.Fatalf("expected the parent untouched without subtask_rollup, got etag %d progress %v", etag, progress)
+	}
+
+	if _, err := s.Containers.UpdateFields(actorUUID, containerUUID, map[string]interface{}{"subtask_rollup": true}, 0); err != nil {
+		t.Fatalf("failed to enable subtask_rollup: %v", err)
+	}
+	if _, err := s.Tasks.UpdateFields(actorUUID, subtasks[0], map[string]interface{}{"state": "completed"}, 0); err != nil {
+		t.Fatalf("UpdateFields failed: %v", err)
+	}
+	etag, progress := parentState()
+	if progress == nil || *progress != `{"done":1,"total":2}` {
+		t.Fatalf("expected 1/2 done, got %v", progress)
+	}
+	if etag <= parent.ETag {
+		t.Errorf("expected the parent etag to be bumped past %d, got %d", parent.ETag, etag)
+	}
+	var events int
+	database.QueryRow("SELECT COUNT(*) FROM event_log WHERE resource_uuid = ? AND event_type = 'task.updated'", parent.UUID).Scan(&events)
+	if events != 1 {
+		t.Errorf("expected 1 task.updated event on the parent, got %d", events)
+	}
+
+	// A transition that leaves the counts alone doesn't touch the parent again
+	if _, err := s.Tasks.UpdateFields(actorUUID, subtasks[1], map[string]interface{}{"state": "in_progress"}, 0); err != nil {
+		t.Fatalf("UpdateFields failed: %v", err)
+	}
+	if again, _ := parentState(); again != etag {
+		t.Errorf("expected the parent etag to stay %d, got %d", etag, again)
+	}
+}
+
+func TestTaskStore_SubtaskProgressLifecycle(t *testing.T) {
+	database := setupTestDB(t)
+	actorUUID := setupTestActor(t, database)
+	containerUUID := setupTestContainer(t, database, actorUUID)
+	s := New(database)
+
+	if _, err := s.Containers.UpdateFields(actorUUID, containerUUID, map[string]interface{}{"subtask_rollup": true}, 0); err != nil {
+		t.Fatalf("failed to enable subtask_rollup: %v", err)
+	}
+	parent, err := s.Tasks.Create(actorUUID, CreateParams{Slug: "parent", Title: "Parent", ProjectUUID: containerUUID, State: "open", Priority: 3})
+	if err != nil {
+		t.Fatalf("Create parent failed: %v", err)
+	}
+	progress := func() string {
+		t.Helper()
+		var summary sql.NullString
+		if err := database.QueryRow("SELECT subtask_progress FROM tasks WHERE uuid = ?", parent.UUID).Scan(&summary); err != nil {
+			t.Fatalf("failed to read parent: %v", err)
+		}
+		return summary.String
+	}
+
+	var subtasks []string
+	for _, slug := range []string{"first", "second"} {
+		result, err := s.Tasks.Create(actorUUID, CreateParams{
+			Slug: slug, Title: slug, ProjectUUID: containerUUID, State: "open", Priority: 3,
+			Kind: "subtask", ParentTaskUUID: &parent.UUID,
+		})
+		if err != nil {
+			t.Fatalf("Create subtask failed: %v", err)
+		}
+		subtasks = append(subtasks, result.UUID)
+	}
+	if got := progress(); got != `{"done":0,"total":2}` {
+		t.Fatalf("expected 0/2 after creating subtasks, got %s", got)
+	}
+
+	if _, err := s.Tasks.Archive(actorUUID, subtasks[0], 0); err != nil {
+		t.Fatalf("Archive failed: %v", err)
+	}
+	if got := progress(); got != `{"done":1,"total":2}` {
+		t.Fatalf("expected 1/2 after archiving a subtask, got %s", got)
+	}
+
+	if _, err := s.Tasks.Purge(actorUUID, subtasks[0], 0); err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if got := progress(); got != `{"done":0,"total":1}` {
+		t.Fatalf("expected 0/1 after purging a subtask, got %s", got)
+	}
+
+	if _, err := s.Containers.ArchiveTree(actorUUID, containerUUID, 0, true); err != nil {
+		t.Fatalf("ArchiveTree failed: %v", err)
+	}
+	if got := progress(); got != `{"done":1,"total":1}` {
+		t.Fatalf("expected 1/1 after archiving the tree, got %s", got)
+	}
+	if _, err := s.Containers.RestoreTree(actorUUID, containerUUID, 0); err != nil {
+		t.Fatalf("RestoreTree failed: %v", err)
+	}
+	if got := progress(); got != `{"done":0,"total":1}` {
+		t.Fatalf("expected 0/1 after restoring the tree, got %s", got)
+	}
+}
+
 func TestTaskStore_UpdateFields_MetaReplace(t *testing.T) {
 	database := setupTestDB(t)
 	actorUUID := setupTestActor(t, database)
@@ -295,6 +471,132 @@ func TestTaskStore_Move(t *testing.T) {
 	}
 }
 
+func TestStore_TaskAsOf(t *testing.T) {
+	database := setupTestDB(t)
+	actorUUID := setupTestActor(t, database)
+	s := New(database)
+
+	container1, _ := s.Containers.Create(actorUUID, ContainerCreateParams{Slug: "project-1"})
+	container2, _ := s.Containers.Create(actorUUID, ContainerCreateParams{Slug: "project-2"})
+	created, err := s.Tasks.Create(actorUUID, CreateParams{
+		Slug:        "as-of-test",
+		Title:       "Original Title",
+		Description: "first draft",
+		ProjectUUID: container1.UUID,
+		State:       "open",
+		Priority:    3,
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := s.Tasks.UpdateFields(actorUUID, created.UUID, map[string]interface{}{"title": "Renamed"}, 0); err != nil {
+		t.Fatalf("rename failed: %v", err)
+	}
+	if _, err := s.Tasks.Move(actorUUID, created.UUID, container2.UUID, 0); err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	if _, err := s.Tasks.UpdateFields(actorUUID, created.UUID, map[string]interface{}{"description": "rewritten"}, 0); err != nil {
+		t.Fatalf("description update failed: %v", err)
+	}
+
+	// Spread the events an hour apart so as_of can fall between them
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	if _, err := database.Exec(`UPDATE tasks SET created_at = ? WHERE uuid = ?`, base.Format(time.RFC3339), created.UUID); err != nil {
+		t.Fatalf("failed to backdate task: %v", err)
+	}
+	rows, err := database.Query(`SELECT id FROM event_log WHERE resource_type = 'task' AND resource_uuid = ? ORDER BY id`, created.UUID)
+	if err != nil {
+		t.Fatalf("failed to list events: %v", err)
+	}
+	var eventIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("failed to scan event: %v", err)
+		}
+		eventIDs = append(eventIDs, id)
+	}
+	rows.Close()
+	if len(eventIDs) != 4 {
+		t.Fatalf("expected 4 task events, got %d", len(eventIDs))
+	}
+	for i, id := range eventIDs {
+		stamp := base.Add(time.Duration(i) * time.Hour).Format(time.RFC3339)
+		if _, err := database.Exec(`UPDATE event_log SET timestamp = ? WHERE id = ?`, stamp, id); err != nil {
+			t.Fatalf("failed to backdate event: %v", err)
+		}
+	}
+
+	snap, err := s.TaskAsOf(created.UUID, base.Add(30*time.Minute))
+	if err != nil {
+		t.Fatalf("TaskAsOf failed: %v", err)
+	}
+	if snap.Fields["title"] != "Original Title" {
+		t.Errorf("expected the title before the rename, got %v", snap.Fields["title"])
+	}
+	if snap.Fields["project_uuid"] != container1.UUID {
+		t.Errorf("expected the original project, got %v", snap.Fields["project_uuid"])
+	}
+	if snap.Fields["description"] != nil || len(snap.Unknown) != 1 || snap.Unknown[0] != "description" {
+		t.Errorf("expected description to be unknown before its first logged change, got %v %v", snap.Fields["description"], snap.Unknown)
+	}
+	if snap.EventID != eventIDs[0] {
+		t.Errorf("expected only the create event replayed, got event %d", snap.EventID)
+	}
+
+	snap, err = s.TaskAsOf(created.UUID, base.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("TaskAsOf failed: %v", err)
+	}
+	if snap.Fields["title"] != "Renamed" || snap.Fields["project_uuid"] != container2.UUID {
+		t.Errorf("expected the rename and move applied, got %v", snap.Fields)
+	}
+	if snap.Fields["state"] != "open" || snap.Fields["slug"] != "as-of-test" {
+		t.Errorf("expected create-time state and slug, got %v", snap.Fields)
+	}
+
+	snap, err = s.TaskAsOf(created.UUID, base.Add(4*time.Hour))
+	if err != nil {
+		t.Fatalf("TaskAsOf failed: %v", err)
+	}
+	if snap.Fields["description"] != "rewritten" || len(snap.Unknown) != 0 {
+		t.Errorf("expected the current description, got %v %v", snap.Fields["description"], snap.Unknown)
+	}
+
+	if _, err := s.TaskAsOf(created.UUID, base.Add(-time.Hour)); err == nil {
+		t.Error("expected an error before the task existed")
+	}
+}
+
+func TestStore_TaskAsOfAfterRestoreTree(t *testing.T) {
+	database := setupTestDB(t)
+	actorUUID := setupTestActor(t, database)
+	containerUUID := setupTestContainer(t, database, actorUUID)
+	s := New(database)
+
+	created, err := s.Tasks.Create(actorUUID, CreateParams{Slug: "wip", Title: "WIP", ProjectUUID: containerUUID, State: "open", Priority: 3})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := s.Tasks.UpdateFields(actorUUID, created.UUID, map[string]interface{}{"state": "in_progress"}, 0); err != nil {
+		t.Fatalf("UpdateFields failed: %v", err)
+	}
+	if _, err := s.Containers.ArchiveTree(actorUUID, containerUUID, 0, true); err != nil {
+		t.Fatalf("ArchiveTree failed: %v", err)
+	}
+	if _, err := s.Containers.RestoreTree(actorUUID, containerUUID, 0); err != nil {
+		t.Fatalf("RestoreTree failed: %v", err)
+	}
+
+	snap, err := s.TaskAsOf(created.UUID, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("TaskAsOf failed: %v", err)
+	}
+	if snap.Fields["state"] != "in_progress" {
+		t.Errorf("expected the restored in_progress state in history, got %v", snap.Fields["state"])
+	}
+}
+
 func TestTaskStore_Archive(t *testing.T) {
 	database := setupTestDB(t)
 	actorUUID := setupTestActor(t, database)
@@ -469,6 +771,29 @@ func TestContainerStore_Move(t *testing.T) {
 	}
 }
 
+func TestContainerStore_MoveRejectsCycleAndDuplicateSlug(t *testing.T) {
+	database := setupTestDB(t)
+	actorUUID := setupTestActor(t, database)
+	s := New(database)
+
+	parent, _ := s.Containers.Create(actorUUID, ContainerCreateParams{Slug: "parent"})
+	child, _ := s.Containers.Create(actorUUID, ContainerCreateParams{Slug: "child", ParentUUID: &parent.UUID})
+	if _, err := s.Containers.Create(actorUUID, ContainerCreateParams{Slug: "child"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if _, err := s.Containers.Move(actorUUID, parent.UUID, &child.UUID, 0); err == nil {
+		t.Error("expected moving a container under its child to fail")
+	}
+	if _, err := s.Containers.Move(actorUUID, parent.UUID, &parent.UUID, 0); err == nil {
+		t.Error("expected moving a container under itself to fail")
+	}
+	var validationErr *domain.ValidationError
+	if _, err := s.Containers.Move(actorUUID, child.UUID, nil, 0); !errors.As(err, &validationErr) {
+		t.Errorf("expected duplicate slug at the root to fail validation, got %v", err)
+	}
+}
+
 func TestContainerStore_Delete(t *testing.T) {
 	database := setupTestDB(t)
 	actorUUID := setupTestActor(t, database)
@@ -818,3 +1143,359 @@ func TestTaskStore_BlockedBy_DraftStateBlocks(t *testing.T) {
 		t.Errorf("expected state 'draft', got %q", blockers[0].State)
 	}
 }
+
+func TestTaskStore_WIPLimit(t *testing.T) {
+	database := setupTestDB(t)
+	actorUUID := setupTestActor(t, database)
+	projectUUID := setupTestContainer(t, database, actorUUID)
+	s := New(database)
+
+	limit := 2
+	section, err := s.Sections.Create(actorUUID, SectionCreateParams{
+		ProjectUUID: projectUUID,
+		Slug:        "doing",
+		WIPLimit:    &limit,
+	})
+	if err != nil {
+		t.Fatalf("failed to create section: %v", err)
+	}
+
+	feature, err := s.Containers.Create(actorUUID, ContainerCreateParams{Slug: "feature", ParentUUID: &projectUUID, Kind: "feature"})
+	if err != nil {
+		t.Fatalf("failed to create feature: %v", err)
+	}
+	if _, err := database.Exec("UPDATE containers SET section_uuid = ? WHERE uuid = ?", section.UUID, feature.UUID); err != nil {
+		t.Fatalf("failed to assign section: %v", err)
+	}
+
+	for i := 0; i < limit; i++ {
+		if _, err := s.Tasks.Create(actorUUID, CreateParams{
+			Slug: fmt.Sprintf("wip-%d", i), Title: "WIP", ProjectUUID: feature.UUID, State: "open", Priority: 3,
+		}); err != nil {
+			t.Fatalf("create %d failed: %v", i, err)
+		}
+	}
+
+	// A completed task does not occupy a slot.
+	if _, err := s.Tasks.Create(actorUUID, CreateParams{
+		Slug: "wip-done", Title: "Done", ProjectUUID: feature.UUID, State: "completed", Priority: 3,
+	}); err != nil {
+		t.Fatalf("completed task should not count toward WIP: %v", err)
+	}
+
+	_, err = s.Tasks.Create(actorUUID, CreateParams{
+		Slug: "wip-over", Title: "Over", ProjectUUID: feature.UUID, State: "open", Priority: 3,
+	})
+	var wipErr *domain.WIPLimitExceededError
+	if !errors.As(err, &wipErr) {
+		t.Fatalf("expected WIPLimitExceededError, got %v", err)
+	}
+	if wipErr.Limit != limit || wipErr.Count != limit {
+		t.Fatalf("unexpected error details: %+v", wipErr)
+	}
+
+	if _, err := s.Tasks.Create(actorUUID, CreateParams{
+		Slug: "wip-bypass", Title: "Bypass", ProjectUUID: feature.UUID, State: "open", Priority: 3, BypassWIPLimit: true,
+	}); err != nil {
+		t.Fatalf("bypass create failed: %v", err)
+	}
+
+	outside, err := s.Tasks.Create(actorUUID, CreateParams{
+		Slug: "outside", Title: "Outside", ProjectUUID: projectUUID, State: "open", Priority: 3,
+	})
+	if err != nil {
+		t.Fatalf("create outside section failed: %v", err)
+	}
+	if _, err := s.Tasks.Move(actorUUID, outside.UUID, feature.UUID, 0); !errors.As(err, &wipErr) {
+		t.Fatalf("expected move into full section to fail, got %v", err)
+	}
+	if _, err := s.Tasks.MoveWithOptions(actorUUID, outside.UUID, feature.UUID, 0, MoveOptions{BypassWIPLimit: true}); err != nil {
+		t.Fatalf("bypass move failed: %v", err)
+	}
+}
+
+func TestSectionStore_WIPLimitValidation(t *testing.T) {
+	database := setupTestDB(t)
+	actorUUID := setupTestActor(t, database)
+	projectUUID := setupTestContainer(t, database, actorUUID)
+	s := New(database)
+
+	zero := 0
+	if _, err := s.Sections.Create(actorUUID, SectionCreateParams{ProjectUUID: projectUUID, Slug: "frozen", WIPLimit: &zero}); err == nil {
+		t.Fatal("expected a zero wip_limit to be rejected on create")
+	}
+	section, err := s.Sections.Create(actorUUID, SectionCreateParams{ProjectUUID: projectUUID, Slug: "doing"})
+	if err != nil {
+		t.Fatalf("failed to create section: %v", err)
+	}
+
+	// Decoded JSON arrives as float64, so every form must be checked
+	for _, value := range []interface{}{float64(-1), float64(0), 2.5, "3", -1} {
+		if _, err := s.Sections.UpdateFields(actorUUID, section.UUID, map[string]interface{}{"wip_limit": value}); err == nil {
+			t.Errorf("expected wip_limit %#v to be rejected", value)
+		}
+	}
+
+	updated, err := s.Sections.UpdateFields(actorUUID, section.UUID, map[string]interface{}{"wip_limit": float64(3)})
+	if err != nil {
+		t.Fatalf("failed to set wip_limit: %v", err)
+	}
+	if updated.WIPLimit == nil || *updated.WIPLimit != 3 {
+		t.Fatalf("expected wip_limit 3, got %v", updated.WIPLimit)
+	}
+	updated, err = s.Sections.UpdateFields(actorUUID, section.UUID, map[string]interface{}{"wip_limit": nil})
+	if err != nil {
+		t.Fatalf("failed to clear wip_limit: %v", err)
+	}
+	if updated.WIPLimit != nil {
+		t.Fatalf("expected wip_limit cleared, got %d", *updated.WIPLimit)
+	}
+}
+
+func TestTaskStore_Ready(t *testing.T) {
+	database := setupTestDB(t)
+	actorUUID := setupTestActor(t, database)
+	containerUUID := setupTestContainer(t, database, actorUUID)
+	s := New(database)
+
+	create := func(slug, state string, priority int, dueAt string) string {
+		t.Helper()
+		result, err := s.Tasks.Create(actorUUID, CreateParams{
+			Slug:        slug,
+			Title:       slug,
+			ProjectUUID: containerUUID,
+			State:       state,
+			Priority:    priority,
+			DueAt:       dueAt,
+		})
+		if err != nil {
+			t.Fatalf("Create %s failed: %v", slug, err)
+		}
+		return result.UUID
+	}
+	block := func(from, to string) {
+		t.Helper()
+		if _, err := database.Exec(`
+			INSERT INTO task_relations (from_task_uuid, to_task_uuid, kind, created_by_actor_uuid)
+			VALUES (?, ?, 'blocks', ?)
+		`, from, to, actorUUID); err != nil {
+			t.Fatalf("Create relation failed: %v", err)
+		}
+	}
+
+	blocker := create("blocker", "in_progress", 1, "")
+	done := create("done", "completed", 1, "")
+	idea := create("idea", "idea", 1, "")
+	blocked := create("blocked", "open", 1, "")
+	unblocked := create("unblocked", "blocked", 2, "2025-01-01T00:00:00Z")
+	ideaBlocked := create("idea-blocked", "open", 2, "")
+	create("free-early", "open", 2, "2024-06-01T00:00:00Z")
+	create("draft", "draft", 1, "")
+
+	block(blocker, blocked)
+	block(done, unblocked)
+	block(idea, ideaBlocked)
+
+	ready, err := s.Tasks.Ready(containerUUID, nil)
+	if err != nil {
+		t.Fatalf("Ready failed: %v", err)
+	}
+
+	var slugs []string
+	for _, task := range ready {
+		slugs = append(slugs, task.Slug)
+	}
+	expected := []string{"free-early", "unblocked", "idea-blocked"}
+	if fmt.Sprint(slugs) != fmt.Sprint(expected) {
+		t.Fatalf("expected ready tasks %v, got %v", expected, slugs)
+	}
+
+	ready, err = s.Tasks.Ready("", []string{"test-project/free"})
+	if err != nil {
+		t.Fatalf("Ready with prefix failed: %v", err)
+	}
+	if len(ready) != 1 || ready[0].Slug != "free-early" {
+		t.Fatalf("expected only free-early for prefix filter, got %v", ready)
+	}
+}
+
+func TestRecurrenceStore_Generate(t *testing.T) {
+	database := setupTestDB(t)
+	actorUUID := setupTestActor(t, database)
+	containerUUID := setupTestContainer(t, database, actorUUID)
+	s := New(database)
+
+	createTemplate := func(slug string) string {
+		t.Helper()
+		result, err := s.Tasks.Create(actorUUID, CreateParams{
+			Slug: slug, Title: "Recurring " + slug, ProjectUUID: containerUUID,
+			State: "idea", Priority: 2, Labels: `["routine"]`,
+		})
+		if err != nil {
+			t.Fatalf("failed to create template task: %v", err)
+		}
+		return result.UUID
+	}
+	monday := 1
+	daily := createTemplate("standup")
+	weekly := createTemplate("report")
+	for _, r := range []domain.Recurrence{
+		{TaskUUID: daily, Interval: 1, Unit: domain.RecurrenceDay, StartsAt: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)},
+		// Starts on a Wednesday; every other Monday from then on
+		{TaskUUID: weekly, Interval: 2, Unit: domain.RecurrenceWeek, Weekday: &monday, StartsAt: time.Date(2024, 3, 6, 9, 0, 0, 0, time.UTC)},
+	} {
+		if _, err := s.Recurrences.Set(r); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+
+	slugs := func(generated []GeneratedOccurrence) []string {
+		out := []string{}
+		for _, g := range generated {
+			out = append(out, g.Slug)
+		}
+		return out
+	}
+
+	now := time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)
+	generated, err := s.Recurrences.Generate(actorUUID, time.Date(2024, 3, 3, 10, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if got := fmt.Sprint(slugs(generated)); got != "[standup-20240301 standup-20240302 standup-20240303]" {
+		t.Errorf("unexpected daily occurrences: %s", got)
+	}
+
+	// Running again in the same window generates nothing
+	generated, err = s.Recurrences.Generate(actorUUID, time.Date(2024, 3, 3, 10, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if len(generated) != 0 {
+		t.Errorf("expected no occurrences on rerun, got %v", slugs(generated))
+	}
+
+	generated, err = s.Recurrences.Generate(actorUUID, now)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	var weeklySlugs []string
+	for _, g := range generated {
+		if g.TemplateUUID == weekly {
+			weeklySlugs = append(weeklySlugs, g.Slug)
+		}
+	}
+	if got := fmt.Sprint(weeklySlugs); got != "[report-20240311 report-20240325]" {
+		t.Errorf("unexpected weekly occurrences: %s", got)
+	}
+	if len(generated) != 2+29 {
+		t.Errorf("expected 29 daily catch-up occurrences plus 2 weekly, got %d", len(generated))
+	}
+
+	var state, dueAt, labels string
+	var links int
+	if err := database.QueryRow("SELECT state, due_at, labels FROM tasks WHERE slug = 'report-20240325'").Scan(&state, &dueAt, &labels); err != nil {
+		t.Fatalf("failed to load occurrence: %v", err)
+	}
+	if state != "open" || dueAt != "2024-03-25T09:00:00Z" || labels != `["routine"]` {
+		t.Errorf("unexpected occurrence: state=%s due_at=%s labels=%s", state, dueAt, labels)
+	}
+	if err := database.QueryRow(
+		"SELECT COUNT(*) FROM task_relations WHERE to_task_uuid = ? AND kind = 'relates_to'", weekly,
+	).Scan(&links); err != nil || links != 2 {
+		t.Errorf("expected 2 occurrences linked to the weekly template, got %d (%v)", links, err)
+	}
+	var linkEvents int
+	if err := database.QueryRow(
+		"SELECT COUNT(*) FROM event_log WHERE event_type = 'task.relation.created'",
+	).Scan(&linkEvents); err != nil || linkEvents != 3+2+29 {
+		t.Errorf("expected a task.relation.created event per occurrence, got %d (%v)", linkEvents, err)
+	}
+
+	if _, err := s.Recurrences.Set(domain.Recurrence{TaskUUID: daily, Interval: 1, Unit: domain.RecurrenceDay, Weekday: &monday, StartsAt: now}); err == nil {
+		t.Error("expected a weekday on a daily recurrence to be rejected")
+	}
+}
+
+func TestTokenStore(t *testing.T) {
+	database := setupTestDB(t)
+	actorUUID := setupTestActor(t, database)
+	s := New(database)
+
+	if n, err := s.Tokens.Count(); err != nil || n != 0 {
+		t.Fatalf("expected no tokens, got %d (%v)", n, err)
+	}
+	token, plaintext, err := s.Tokens.Create(actorUUID, "ci", "write", "")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if token.Scope != domain.TokenScopeWrite || token.ProjectUUID != nil || len(plaintext) != len("wrkq_")+64 {
+		t.Fatalf("unexpected token %+v (%q)", token, plaintext)
+	}
+
+	var stored string
+	if err := database.QueryRow("SELECT token_hash FROM api_tokens WHERE uuid = ?", token.UUID).Scan(&stored); err != nil {
+		t.Fatalf("failed to read token hash: %v", err)
+	}
+	if stored == plaintext {
+		t.Error("expected only a hash of the token stored")
+	}
+
+	got, err := s.Tokens.Authenticate(plaintext)
+	if err != nil || got == nil || got.UUID != token.UUID || got.ActorUUID != actorUUID {
+		t.Fatalf("Authenticate = %+v, %v", got, err)
+	}
+	if got, err := s.Tokens.Authenticate(plaintext + "x"); err != nil || got != nil {
+		t.Errorf("expected an unknown token to authenticate as nil, got %+v, %v", got, err)
+	}
+
+	if _, _, err := s.Tokens.Create(actorUUID, "ci", "read", ""); err == nil {
+		t.Error("expected a duplicate name to be rejected")
+	}
+	var validationErr *domain.ValidationError
+	if _, _, err := s.Tokens.Create(actorUUID, "root", "superuser", ""); !errors.As(err, &validationErr) {
+		t.Errorf("expected a validation error for an unknown scope, got %v", err)
+	}
+
+	if err := s.Tokens.Revoke("ci"); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if err := s.Tokens.Revoke("ci"); err == nil {
+		t.Error("expected revoking a missing token to fail")
+	}
+	if got, _ := s.Tokens.Authenticate(plaintext); got != nil {
+		t.Error("expected a revoked token not to authenticate")
+	}
+
+	// A deactivated actor's tokens stop working
+	_, active, err := s.Tokens.Create(actorUUID, "ops", "write", "")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := database.Exec("UPDATE actors SET archived_at = '2025-01-01T00:00:00Z' WHERE uuid = ?", actorUUID); err != nil {
+		t.Fatalf("failed to deactivate actor: %v", err)
+	}
+	if got, err := s.Tokens.Authenticate(active); err != nil || got != nil {
+		t.Errorf("expected a deactivated actor's token not to authenticate, got %+v, %v", got, err)
+	}
+}
+
+func TestContainerDeleteKeepsProjectTokens(t *testing.T) {
+	database := setupTestDB(t)
+	actorUUID := setupTestActor(t, database)
+	containerUUID := setupTestContainer(t, database, actorUUID)
+	s := New(database)
+
+	if _, _, err := s.Tokens.Create(actorUUID, "scoped", "read", containerUUID); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := s.Containers.Delete(actorUUID, containerUUID, 0); err == nil || !strings.Contains(err.Error(), "API token") {
+		t.Fatalf("expected the delete to be refused, got %v", err)
+	}
+	if _, err := database.Exec("DELETE FROM containers WHERE uuid = ?", containerUUID); err == nil {
+		t.Error("expected the foreign key to restrict deleting a token's project")
+	}
+	if n, err := s.Tokens.Count(); err != nil || n != 1 {
+		t.Errorf("expected the token to survive, got %d (%v)", n, err)
+	}
+}
@@ -26,7 +26,7 @@ type CreateParams struct {
 	ProjectUUID          string
 	State                string
 	Priority             int
-	Kind                 string  // task, subtask, spike, bug, chore - defaults to "task"
+	Kind                 string  // one of the task_kinds vocabulary - defaults to "task"
 	ParentTaskUUID       *string // for subtasks
 	AssigneeActorUUID    *string // task assignment
 	RequestedByProjectID *string
@@ -36,6 +36,8 @@ type CreateParams struct {
 	Meta                 *string // JSON object
 	DueAt                string
 	StartAt              string
+	EstimateMinutes      *int // planned effort in minutes
+	BypassWIPLimit       bool // skip the section wip_limit check (admin override)
 }
 
 // CreateResult contains the result of task creation.
@@ -49,134 +51,233 @@ type CreateResult struct {
 func (ts *TaskStore) Create(actorUUID string, params CreateParams) (*CreateResult, error) {
 	var result *CreateResult
 
-	// Default kind to "task" if not provided
-	kind := params.Kind
-	if kind == "" {
-		kind = "task"
+	err := ts.store.withTx(func(tx *sql.Tx, ew *events.Writer) error {
+		var err error
+		result, err = createTaskTx(tx, ew, actorUUID, params)
+		return err
+	})
+
+	if err == nil && result != nil {
+		webhooks.Notify(ts.store.db)
 	}
 
+	return result, err
+}
+
+// CreateManyResult reports the outcome of a single item in a CreateMany batch.
+// Exactly one of Result or Err is set.
+type CreateManyResult struct {
+	Index  int
+	Result *CreateResult
+	Err    error
+}
+
+// CreateMany creates several tasks in a single transaction.
+// When atomic is true, the first failure rolls back the whole batch and no task
+// is created. Otherwise each item runs inside its own savepoint, so a failing
+// item (e.g. a forced UUID collision) is reported without affecting the rest
+// and all successful items are committed together.
+func (ts *TaskStore) CreateMany(actorUUID string, items []CreateParams, atomic bool) ([]CreateManyResult, error) {
+	results := make([]CreateManyResult, 0, len(items))
+	failed := false
+
 	err := ts.store.withTx(func(tx *sql.Tx, ew *events.Writer) error {
-		// Build query - include uuid column only if forcing a specific UUID
-		var query string
-		var args []interface{}
+		for i, params := range items {
+			if atomic {
+				created, err := createTaskTx(tx, ew, actorUUID, params)
+				if err != nil {
+					results = append(results, CreateManyResult{Index: i, Err: err})
+					failed = true
+					return err
+				}
+				results = append(results, CreateManyResult{Index: i, Result: created})
+				continue
+			}
 
-		if params.UUID != "" {
-			query = `INSERT INTO tasks (uuid, id, slug, title, description, project_uuid, state, priority, kind,
-				parent_task_uuid, assignee_actor_uuid, requested_by_project_id, assigned_project_id, resolution,
-				labels, meta, due_at, start_at, created_by_actor_uuid, updated_by_actor_uuid)
-				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-			args = append(args, params.UUID)
-		} else {
-			query = `INSERT INTO tasks (id, slug, title, description, project_uuid, state, priority, kind,
-				parent_task_uuid, assignee_actor_uuid, requested_by_project_id, assigned_project_id, resolution,
-				labels, meta, due_at, start_at, created_by_actor_uuid, updated_by_actor_uuid)
-				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-		}
-
-		// Common args for both cases
-		args = append(args,
-			"", // id (auto-generated by trigger)
-			params.Slug,
-			params.Title,
-			params.Description,
-			params.ProjectUUID,
-			params.State,
-			params.Priority,
-			kind,
-			params.ParentTaskUUID,
-			params.AssigneeActorUUID,
-			params.RequestedByProjectID,
-			params.AssignedProjectID,
-			params.Resolution,
-			params.Labels,
-			params.Meta,
-			params.DueAt,
-			params.StartAt,
-			actorUUID,
-			actorUUID,
-		)
-
-		res, err := tx.Exec(query, args...)
-		if err != nil {
-			return fmt.Errorf("failed to create task: %w", err)
+			if _, err := tx.Exec("SAVEPOINT bulk_create_item"); err != nil {
+				return fmt.Errorf("failed to create savepoint: %w", err)
+			}
+			created, err := createTaskTx(tx, ew, actorUUID, params)
+			if err != nil {
+				if _, rbErr := tx.Exec("ROLLBACK TO SAVEPOINT bulk_create_item"); rbErr != nil {
+					return fmt.Errorf("failed to roll back savepoint: %w", rbErr)
+				}
+				results = append(results, CreateManyResult{Index: i, Err: err})
+			} else {
+				results = append(results, CreateManyResult{Index: i, Result: created})
+			}
+			if _, err := tx.Exec("RELEASE SAVEPOINT bulk_create_item"); err != nil {
+				return fmt.Errorf("failed to release savepoint: %w", err)
+			}
 		}
+		return nil
+	})
 
-		// Get the UUID and ID of the created task
-		rowID, err := res.LastInsertId()
-		if err != nil {
-			return fmt.Errorf("failed to get last insert ID: %w", err)
+	if err != nil {
+		if failed {
+			// Per-item failure in atomic mode: the batch was rolled back, so
+			// nothing reported as created actually exists.
+			for i := range results {
+				results[i].Result = nil
+			}
+			return results, nil
 		}
+		return nil, err
+	}
 
-		var uuid, id string
-		var etag int64
-		err = tx.QueryRow("SELECT uuid, id, etag FROM tasks WHERE rowid = ?", rowID).Scan(&uuid, &id, &etag)
-		if err != nil {
-			return fmt.Errorf("failed to get task UUID: %w", err)
-		}
+	webhooks.Notify(ts.store.db)
 
-		// Log event with structured payload
-		payload := map[string]interface{}{
-			"slug":     params.Slug,
-			"title":    params.Title,
-			"state":    params.State,
-			"priority": params.Priority,
-			"kind":     kind,
-		}
-		if params.ParentTaskUUID != nil {
-			payload["parent_task_uuid"] = *params.ParentTaskUUID
-		}
-		if params.AssigneeActorUUID != nil {
-			payload["assignee_actor_uuid"] = *params.AssigneeActorUUID
-		}
-		if params.RequestedByProjectID != nil {
-			payload["requested_by_project_id"] = *params.RequestedByProjectID
-		}
-		if params.AssignedProjectID != nil {
-			payload["assigned_project_id"] = *params.AssignedProjectID
-		}
-		if params.Resolution != nil {
-			payload["resolution"] = *params.Resolution
-		}
-		if params.Labels != "" {
-			payload["labels"] = params.Labels
-		}
-		if params.DueAt != "" {
-			payload["due_at"] = params.DueAt
-		}
-		if params.StartAt != "" {
-			payload["start_at"] = params.StartAt
-		}
+	return results, nil
+}
 
-		payloadJSON, err := json.Marshal(payload)
-		if err != nil {
-			return fmt.Errorf("failed to marshal event payload: %w", err)
+// createTaskTx inserts a task and logs a task.created event within tx.
+func createTaskTx(tx *sql.Tx, ew *events.Writer, actorUUID string, params CreateParams) (*CreateResult, error) {
+	// Default kind to "task" if not provided
+	kind := params.Kind
+	if kind == "" {
+		kind = "task"
+	}
+	if err := validateTaskKindTx(tx, kind); err != nil {
+		return nil, err
+	}
+	if params.EstimateMinutes != nil {
+		if err := domain.ValidateEstimateMinutes(*params.EstimateMinutes); err != nil {
+			return nil, err
 		}
-		payloadStr := string(payloadJSON)
+	}
 
-		if err := ew.LogEvent(tx, &domain.Event{
-			ActorUUID:    &actorUUID,
-			ResourceType: "task",
-			ResourceUUID: &uuid,
-			EventType:    "task.created",
-			ETag:         &etag,
-			Payload:      &payloadStr,
-		}); err != nil {
-			return fmt.Errorf("failed to log event: %w", err)
+	if !params.BypassWIPLimit && countsTowardWIP(params.State) {
+		if err := checkWIPLimitTx(tx, params.ProjectUUID, ""); err != nil {
+			return nil, err
 		}
+	}
 
-		result = &CreateResult{
-			UUID: uuid,
-			ID:   id,
-			ETag: etag,
-		}
-		return nil
-	})
+	// Build query - include uuid column only if forcing a specific UUID
+	var query string
+	var args []interface{}
+
+	if params.UUID != "" {
+		query = `INSERT INTO tasks (uuid, id, slug, title, description, project_uuid, state, priority, kind,
+			parent_task_uuid, assignee_actor_uuid, requested_by_project_id, assigned_project_id, resolution,
+			labels, meta, due_at, start_at, estimate_minutes, created_by_actor_uuid, updated_by_actor_uuid)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		args = append(args, params.UUID)
+	} else {
+		query = `INSERT INTO tasks (id, slug, title, description, project_uuid, state, priority, kind,
+			parent_task_uuid, assignee_actor_uuid, requested_by_project_id, assigned_project_id, resolution,
+			labels, meta, due_at, start_at, estimate_minutes, created_by_actor_uuid, updated_by_actor_uuid)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	}
 
-	if err == nil && result != nil {
-		webhooks.DispatchTask(ts.store.db, result.UUID)
+	// Common args for both cases
+	args = append(args,
+		"", // id (auto-generated by trigger)
+		params.Slug,
+		params.Title,
+		params.Description,
+		params.ProjectUUID,
+		params.State,
+		params.Priority,
+		kind,
+		params.ParentTaskUUID,
+		params.AssigneeActorUUID,
+		params.RequestedByProjectID,
+		params.AssignedProjectID,
+		params.Resolution,
+		params.Labels,
+		params.Meta,
+		params.DueAt,
+		params.StartAt,
+		params.EstimateMinutes,
+		actorUUID,
+		actorUUID,
+	)
+
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create task: %w", err)
 	}
 
-	return result, err
+	// Get the UUID and ID of the created task
+	rowID, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+
+	var uuid, id string
+	var etag int64
+	err = tx.QueryRow("SELECT uuid, id, etag FROM tasks WHERE rowid = ?", rowID).Scan(&uuid, &id, &etag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task UUID: %w", err)
+	}
+
+	// Log event with structured payload
+	payload := map[string]interface{}{
+		"slug":     params.Slug,
+		"title":    params.Title,
+		"state":    params.State,
+		"priority": params.Priority,
+		"kind":     kind,
+	}
+	if params.ParentTaskUUID != nil {
+		payload["parent_task_uuid"] = *params.ParentTaskUUID
+	}
+	if params.AssigneeActorUUID != nil {
+		payload["assignee_actor_uuid"] = *params.AssigneeActorUUID
+	}
+	if params.RequestedByProjectID != nil {
+		payload["requested_by_project_id"] = *params.RequestedByProjectID
+	}
+	if params.AssignedProjectID != nil {
+		payload["assigned_project_id"] = *params.AssignedProjectID
+	}
+	if params.Resolution != nil {
+		payload["resolution"] = *params.Resolution
+	}
+	if params.Labels != "" {
+		payload["labels"] = params.Labels
+	}
+	if params.DueAt != "" {
+		payload["due_at"] = params.DueAt
+	}
+	if params.StartAt != "" {
+		payload["start_at"] = params.StartAt
+	}
+	if params.EstimateMinutes != nil {
+		payload["estimate_minutes"] = *params.EstimateMinutes
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event payload: %w", err)
+	}
+	payloadStr := string(payloadJSON)
+
+	if err := ew.LogEvent(tx, &domain.Event{
+		ActorUUID:    &actorUUID,
+		ResourceType: "task",
+		ResourceUUID: &uuid,
+		EventType:    "task.created",
+		ETag:         &etag,
+		Payload:      &payloadStr,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to log event: %w", err)
+	}
+
+	if err := webhooks.EnqueueTask(tx, uuid); err != nil {
+		return nil, fmt.Errorf("failed to queue webhooks: %w", err)
+	}
+	if params.ParentTaskUUID != nil {
+		if err := RefreshParentProgressTx(tx, ew, actorUUID, *params.ParentTaskUUID); err != nil {
+			return nil, err
+		}
+	}
+
+	return &CreateResult{
+		UUID: uuid,
+		ID:   id,
+		ETag: etag,
+	}, nil
 }
 
 // UpdateFields updates specified fields on a task and logs a task.updated event.
@@ -189,7 +290,8 @@ func (ts *TaskStore) UpdateFields(actorUUID, taskUUID string, fields map[string]
 		// Get current etag and state
 		var currentETag int64
 		var currentState string
-		err := tx.QueryRow("SELECT etag, state FROM tasks WHERE uuid = ?", taskUUID).Scan(&currentETag, &currentState)
+		var parentTaskUUID sql.NullString
+		err := tx.QueryRow("SELECT etag, state, parent_task_uuid FROM tasks WHERE uuid = ?", taskUUID).Scan(&currentETag, &currentState, &parentTaskUUID)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fmt.Errorf("task not found: %s", taskUUID)
@@ -202,6 +304,17 @@ func (ts *TaskStore) UpdateFields(actorUUID, taskUUID string, fields map[string]
 			return err
 		}
 
+		if kind, ok := fields["kind"].(string); ok {
+			if err := validateTaskKindTx(tx, kind); err != nil {
+				return err
+			}
+		}
+		if estimate, ok := fields["estimate_minutes"].(int); ok {
+			if err := domain.ValidateEstimateMinutes(estimate); err != nil {
+				return err
+			}
+		}
+
 		// Check if we're transitioning to a completion state (for unblock webhook logic)
 		newState, hasStateChange := fields["state"].(string)
 		transitioningToCompletion := hasStateChange && !isCompletionState(currentState) && isCompletionState(newState)
@@ -306,17 +419,22 @@ func (ts *TaskStore) UpdateFields(actorUUID, taskUUID string, fields map[string]
 			return fmt.Errorf("failed to log event: %w", err)
 		}
 
+		// Queue webhooks for the updated task and any newly unblocked tasks
+		for _, uuid := range append([]string{taskUUID}, unblockedTaskUUIDs...) {
+			if err := webhooks.EnqueueTask(tx, uuid); err != nil {
+				return fmt.Errorf("failed to queue webhooks: %w", err)
+			}
+		}
+
+		// Refresh the parent's subtask rollup, if its project keeps one
+		if hasStateChange && newState != currentState {
+			return RefreshParentProgressTx(tx, ew, actorUUID, parentTaskUUID.String)
+		}
 		return nil
 	})
 
 	if err == nil {
-		// Dispatch webhook for the updated task
-		webhooks.DispatchTask(ts.store.db, taskUUID)
-
-		// Dispatch webhooks for newly unblocked tasks
-		for _, unblockedUUID := range unblockedTaskUUIDs {
-			webhooks.DispatchTask(ts.store.db, unblockedUUID)
-		}
+		webhooks.Notify(ts.store.db)
 	}
 
 	return newETag, err
@@ -325,13 +443,23 @@ func (ts *TaskStore) UpdateFields(actorUUID, taskUUID string, fields map[string]
 // Move moves a task to a different container and logs a task.updated event.
 // Returns the new etag on success.
 func (ts *TaskStore) Move(actorUUID, taskUUID, newProjectUUID string, ifMatch int64) (int64, error) {
+	return ts.MoveWithOptions(actorUUID, taskUUID, newProjectUUID, ifMatch, MoveOptions{})
+}
+
+// MoveOptions controls optional checks performed by MoveWithOptions.
+type MoveOptions struct {
+	BypassWIPLimit bool // skip the destination section wip_limit check (admin override)
+}
+
+// MoveWithOptions is Move with additional options.
+func (ts *TaskStore) MoveWithOptions(actorUUID, taskUUID, newProjectUUID string, ifMatch int64, opts MoveOptions) (int64, error) {
 	var newETag int64
 
 	err := ts.store.withTx(func(tx *sql.Tx, ew *events.Writer) error {
 		// Get current state
 		var currentETag int64
-		var oldProjectUUID string
-		err := tx.QueryRow("SELECT etag, project_uuid FROM tasks WHERE uuid = ?", taskUUID).Scan(&currentETag, &oldProjectUUID)
+		var oldProjectUUID, state string
+		err := tx.QueryRow("SELECT etag, project_uuid, state FROM tasks WHERE uuid = ?", taskUUID).Scan(&currentETag, &oldProjectUUID, &state)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fmt.Errorf("task not found: %s", taskUUID)
@@ -344,6 +472,12 @@ func (ts *TaskStore) Move(actorUUID, taskUUID, newProjectUUID string, ifMatch in
 			return err
 		}
 
+		if !opts.BypassWIPLimit && countsTowardWIP(state) {
+			if err := checkWIPLimitTx(tx, newProjectUUID, taskUUID); err != nil {
+				return err
+			}
+		}
+
 		// Update the task
 		_, err = tx.Exec(`
 			UPDATE tasks
@@ -376,16 +510,83 @@ func (ts *TaskStore) Move(actorUUID, taskUUID, newProjectUUID string, ifMatch in
 			return fmt.Errorf("failed to log event: %w", err)
 		}
 
+		if err := webhooks.EnqueueTask(tx, taskUUID); err != nil {
+			return fmt.Errorf("failed to queue webhooks: %w", err)
+		}
+
 		return nil
 	})
 
 	if err == nil {
-		webhooks.DispatchTask(ts.store.db, taskUUID)
+		webhooks.Notify(ts.store.db)
 	}
 
 	return newETag, err
 }
 
+// Reassign moves the given tasks from one assignee to another in a single
+// transaction, bumping each etag and logging a task.updated event. A nil
+// from or to means unassigned. Tasks that no longer have the from assignee,
+// or have reached a completion state, are skipped so concurrent changes are
+// not overwritten. Returns the number of tasks reassigned.
+func (ts *TaskStore) Reassign(actorUUID string, taskUUIDs []string, from, to *string) (int, error) {
+	reassigned := 0
+
+	err := ts.store.withTx(func(tx *sql.Tx, ew *events.Writer) error {
+		payloadJSON, err := json.Marshal(map[string]interface{}{"assignee_actor_uuid": to})
+		if err != nil {
+			return fmt.Errorf("failed to marshal changes: %w", err)
+		}
+		payloadStr := string(payloadJSON)
+
+		for _, taskUUID := range taskUUIDs {
+			var newETag int64
+			err := tx.QueryRow(`
+				UPDATE tasks
+				SET assignee_actor_uuid = ?,
+					etag = etag + 1,
+					updated_by_actor_uuid = ?
+				WHERE uuid = ?
+				  AND assignee_actor_uuid IS ?
+				  AND state NOT IN ('completed', 'cancelled', 'archived', 'deleted')
+				RETURNING etag
+			`, to, actorUUID, taskUUID, from).Scan(&newETag)
+			if err == sql.ErrNoRows {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to reassign task %s: %w", taskUUID, err)
+			}
+
+			if err := ew.LogEvent(tx, &domain.Event{
+				ActorUUID:    &actorUUID,
+				ResourceType: "task",
+				ResourceUUID: &taskUUID,
+				EventType:    "task.updated",
+				ETag:         &newETag,
+				Payload:      &payloadStr,
+			}); err != nil {
+				return fmt.Errorf("failed to log event: %w", err)
+			}
+
+			if err := webhooks.EnqueueTask(tx, taskUUID); err != nil {
+				return fmt.Errorf("failed to queue webhooks: %w", err)
+			}
+			reassigned++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if reassigned > 0 {
+		webhooks.Notify(ts.store.db)
+	}
+
+	return reassigned, nil
+}
+
 // ArchiveResult contains statistics about an archive operation.
 type ArchiveResult struct {
 	ETag int64
@@ -399,7 +600,8 @@ func (ts *TaskStore) Archive(actorUUID, taskUUID string, ifMatch int64) (*Archiv
 		// Get current state
 		var currentETag int64
 		var slug string
-		err := tx.QueryRow("SELECT etag, slug FROM tasks WHERE uuid = ?", taskUUID).Scan(&currentETag, &slug)
+		var parentTaskUUID sql.NullString
+		err := tx.QueryRow("SELECT etag, slug, parent_task_uuid FROM tasks WHERE uuid = ?", taskUUID).Scan(&currentETag, &slug, &parentTaskUUID)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fmt.Errorf("task not found: %s", taskUUID)
@@ -445,12 +647,19 @@ func (ts *TaskStore) Archive(actorUUID, taskUUID string, ifMatch int64) (*Archiv
 			return fmt.Errorf("failed to log event: %w", err)
 		}
 
+		if err := webhooks.EnqueueTask(tx, taskUUID); err != nil {
+			return fmt.Errorf("failed to queue webhooks: %w", err)
+		}
+		if err := RefreshParentProgressTx(tx, ew, actorUUID, parentTaskUUID.String); err != nil {
+			return err
+		}
+
 		result = &ArchiveResult{ETag: newETag}
 		return nil
 	})
 
 	if err == nil && result != nil {
-		webhooks.DispatchTask(ts.store.db, taskUUID)
+		webhooks.Notify(ts.store.db)
 	}
 
 	return result, err
@@ -466,13 +675,13 @@ type PurgeResult struct {
 // Returns the purge result including attachment statistics.
 func (ts *TaskStore) Purge(actorUUID, taskUUID string, ifMatch int64) (*PurgeResult, error) {
 	var result *PurgeResult
-	var webhookInfo *webhooks.TaskInfo
 
 	err := ts.store.withTx(func(tx *sql.Tx, ew *events.Writer) error {
 		// Get current state
 		var currentETag int64
 		var slug string
-		err := tx.QueryRow("SELECT etag, slug FROM tasks WHERE uuid = ?", taskUUID).Scan(&currentETag, &slug)
+		var parentTaskUUID sql.NullString
+		err := tx.QueryRow("SELECT etag, slug, parent_task_uuid FROM tasks WHERE uuid = ?", taskUUID).Scan(&currentETag, &slug, &parentTaskUUID)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return fmt.Errorf("task not found: %s", taskUUID)
@@ -485,8 +694,8 @@ func (ts *TaskStore) Purge(actorUUID, taskUUID string, ifMatch int64) (*PurgeRes
 			return err
 		}
 
-		// Capture webhook payload info before deletion
-		var info webhooks.TaskInfo
+		// Queue the webhook payload before deletion
+		info := webhooks.TaskInfo{TaskUUID: taskUUID}
 		if err := tx.QueryRow(`
 			SELECT t.id, t.project_uuid, c.id
 			FROM tasks t
@@ -495,7 +704,9 @@ func (ts *TaskStore) Purge(actorUUID, taskUUID string, ifMatch int64) (*PurgeRes
 		`, taskUUID).Scan(&info.TaskID, &info.ProjectUUID, &info.ProjectID); err != nil {
 			return fmt.Errorf("failed to load webhook info: %w", err)
 		}
-		webhookInfo = &info
+		if err := webhooks.EnqueueTaskInfo(tx, info); err != nil {
+			return fmt.Errorf("failed to queue webhooks: %w", err)
+		}
 
 		// Count attachments for statistics
 		var attachmentCount int
@@ -542,6 +753,9 @@ func (ts *TaskStore) Purge(actorUUID, taskUUID string, ifMatch int64) (*PurgeRes
 		if err != nil {
 			return fmt.Errorf("failed to delete task: %w", err)
 		}
+		if err := RefreshParentProgressTx(tx, ew, actorUUID, parentTaskUUID.String); err != nil {
+			return err
+		}
 
 		result = &PurgeResult{
 			AttachmentsDeleted: attachmentCount,
@@ -550,8 +764,8 @@ func (ts *TaskStore) Purge(actorUUID, taskUUID string, ifMatch int64) (*PurgeRes
 		return nil
 	})
 
-	if err == nil && webhookInfo != nil {
-		webhooks.DispatchTaskInfo(ts.store.db, *webhookInfo)
+	if err == nil {
+		webhooks.Notify(ts.store.db)
 	}
 
 	return result, err
@@ -657,6 +871,10 @@ type BlockingTask struct {
 	State string `json:"state"`
 }
 
+// nonBlockingStates lists blocker states that no longer (or never) block the
+// target task: finished work and uncommitted ideas.
+const nonBlockingStates = "'completed', 'archived', 'deleted', 'cancelled', 'idea'"
+
 // BlockedBy returns all incomplete tasks that are blocking the given task.
 // A task is considered "blocking" if there is a 'blocks' relation where
 // the blocking task is the source (from_task_uuid) and the given task is the target (to_task_uuid).
@@ -669,7 +887,7 @@ func (ts *TaskStore) BlockedBy(taskUUID string) ([]BlockingTask, error) {
 		JOIN tasks t ON r.from_task_uuid = t.uuid
 		WHERE r.to_task_uuid = ?
 		  AND r.kind = 'blocks'
-		  AND t.state NOT IN ('completed', 'archived', 'deleted', 'cancelled', 'idea')
+		  AND t.state NOT IN (`+nonBlockingStates+`)
 		ORDER BY t.id
 	`, taskUUID)
 	if err != nil {
@@ -698,6 +916,89 @@ func (ts *TaskStore) BlockedBy(taskUUID string) ([]BlockingTask, error) {
 	return blockers, nil
 }
 
+// ReadyTask is a task that can be picked up now (see Ready).
+type ReadyTask struct {
+	UUID     string  `json:"uuid"`
+	ID       string  `json:"id"`
+	Slug     string  `json:"slug"`
+	Title    string  `json:"title"`
+	State    string  `json:"state"`
+	Priority int     `json:"priority"`
+	DueAt    *string `json:"due_at,omitempty"`
+	Path     string  `json:"path"`
+}
+
+// Ready returns open or blocked tasks that have no incomplete blockers, using
+// the same definition of "incomplete" as BlockedBy. If projectUUID is set, only
+// tasks in that container's subtree are returned; pathPrefixes further narrow
+// results to tasks whose path starts with any of the prefixes. Results are
+// ordered by priority, then due_at (unset last), then id.
+func (ts *TaskStore) Ready(projectUUID string, pathPrefixes []string) ([]ReadyTask, error) {
+	query := `
+		SELECT t.uuid, t.id, t.slug, t.title, t.state, t.priority, t.due_at,
+		       cp.path || '/' || t.slug AS path
+		FROM tasks t
+		JOIN v_container_paths cp ON cp.uuid = t.project_uuid
+		WHERE t.state IN ('open', 'blocked')
+		  AND NOT EXISTS (
+			SELECT 1 FROM task_relations r
+			JOIN tasks b ON b.uuid = r.from_task_uuid
+			WHERE r.to_task_uuid = t.uuid
+			  AND r.kind = 'blocks'
+			  AND b.state NOT IN (` + nonBlockingStates + `)
+		  )
+	`
+	var args []interface{}
+
+	if projectUUID != "" {
+		var projectPath string
+		if err := ts.store.db.QueryRow("SELECT path FROM v_container_paths WHERE uuid = ?", projectUUID).Scan(&projectPath); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("container not found: %s", projectUUID)
+			}
+			return nil, fmt.Errorf("failed to resolve container path: %w", err)
+		}
+		query += " AND (cp.path = ? OR cp.path LIKE ? || '/%')"
+		args = append(args, projectPath, projectPath)
+	}
+
+	if len(pathPrefixes) > 0 {
+		var conditions []string
+		for _, prefix := range pathPrefixes {
+			conditions = append(conditions, "(cp.path || '/' || t.slug) LIKE ? || '%'")
+			args = append(args, prefix)
+		}
+		query += " AND (" + strings.Join(conditions, " OR ") + ")"
+	}
+
+	query += " ORDER BY t.priority ASC, COALESCE(NULLIF(t.due_at, ''), '9999-12-31T23:59:59Z') ASC, t.id ASC"
+
+	rows, err := ts.store.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ready tasks: %w", err)
+	}
+	defer rows.Close()
+
+	ready := []ReadyTask{}
+	for rows.Next() {
+		var rt ReadyTask
+		var dueAt sql.NullString
+		if err := rows.Scan(&rt.UUID, &rt.ID, &rt.Slug, &rt.Title, &rt.State, &rt.Priority, &dueAt, &rt.Path); err != nil {
+			return nil, fmt.Errorf("failed to scan ready task: %w", err)
+		}
+		if dueAt.Valid && dueAt.String != "" {
+			rt.DueAt = &dueAt.String
+		}
+		ready = append(ready, rt)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating ready tasks: %w", err)
+	}
+
+	return ready, nil
+}
+
 // GetTasksBlockedBy returns all task UUIDs that are blocked by the given task.
 // In other words, it finds tasks where the given task is the blocker (from_task_uuid).
 // This is the inverse of BlockedBy - BlockedBy returns "who is blocking me",
@@ -801,3 +1102,89 @@ func cascadeDeleteSubtasks(tx *sql.Tx, ew *events.Writer, actorUUID, parentTaskU
 
 	return nil
 }
+
+// RefreshParentProgressTx refreshes the subtask_progress of each parent task
+// in parentTaskUUIDs, skipping empty and repeated ones, and queues webhooks
+// for every parent whose summary changed. Every write that adds or removes a
+// subtask or changes its state calls it in the same transaction; writers
+// outside the store that do so (restore, bundle and patch apply) too.
+func RefreshParentProgressTx(tx *sql.Tx, ew *events.Writer, actorUUID string, parentTaskUUIDs ...string) error {
+	seen := make(map[string]bool, len(parentTaskUUIDs))
+	for _, parentUUID := range parentTaskUUIDs {
+		if parentUUID == "" || seen[parentUUID] {
+			continue
+		}
+		seen[parentUUID] = true
+		changed, err := refreshSubtaskProgressTx(tx, ew, actorUUID, parentUUID)
+		if err != nil {
+			return err
+		}
+		if changed {
+			if err := webhooks.EnqueueTask(tx, parentUUID); err != nil {
+				return fmt.Errorf("failed to queue webhooks: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// refreshSubtaskProgressTx recomputes a parent task's subtask_progress when
+// its project has subtask_rollup on. The parent is only written, bumping its
+// etag and logging task.updated, when the summary actually changes. It
+// reports whether it did.
+func refreshSubtaskProgressTx(tx *sql.Tx, ew *events.Writer, actorUUID, parentTaskUUID string) (bool, error) {
+	var rollup bool
+	var current sql.NullString
+	err := tx.QueryRow(`
+		SELECT c.subtask_rollup, t.subtask_progress
+		FROM tasks t
+		JOIN containers c ON c.uuid = t.project_uuid
+		WHERE t.uuid = ?
+	`, parentTaskUUID).Scan(&rollup, &current)
+	if err == sql.ErrNoRows || (err == nil && !rollup) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to load parent task: %w", err)
+	}
+
+	var progress domain.SubtaskProgress
+	if err := tx.QueryRow(`
+		SELECT COALESCE(SUM(state IN ('completed', 'archived')), 0), COUNT(*)
+		FROM tasks
+		WHERE parent_task_uuid = ? AND state NOT IN ('deleted', 'cancelled')
+	`, parentTaskUUID).Scan(&progress.Done, &progress.Total); err != nil {
+		return false, fmt.Errorf("failed to count subtasks: %w", err)
+	}
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return false, err
+	}
+	summary := string(data)
+	if current.Valid && current.String == summary {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE tasks SET subtask_progress = ?, updated_by_actor_uuid = ? WHERE uuid = ?
+	`, summary, actorUUID, parentTaskUUID); err != nil {
+		return false, fmt.Errorf("failed to update subtask progress: %w", err)
+	}
+	var etag int64
+	if err := tx.QueryRow("SELECT etag FROM tasks WHERE uuid = ?", parentTaskUUID).Scan(&etag); err != nil {
+		return false, fmt.Errorf("failed to read parent etag: %w", err)
+	}
+
+	payload := fmt.Sprintf(`{"subtask_progress":%s}`, summary)
+	if err := ew.LogEvent(tx, &domain.Event{
+		ActorUUID:    &actorUUID,
+		ResourceType: "task",
+		ResourceUUID: &parentTaskUUID,
+		EventType:    "task.updated",
+		ETag:         &etag,
+		Payload:      &payload,
+	}); err != nil {
+		return false, fmt.Errorf("failed to log event: %w", err)
+	}
+	return true, nil
+}
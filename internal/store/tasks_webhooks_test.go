@@ -776,3 +776,65 @@ func TestWebhookPayloadBlockedByOmittedWhenEmpty(t *testing.T) {
 		t.Fatalf("blocked_by should be omitted when empty, but found in payload: %s", string(rawPayload))
 	}
 }
+
+func TestUpdateFieldsDoesNotWaitForSlowWebhook(t *testing.T) {
+	database := setupWebhookTestDB(t)
+	actorUUID := setupWebhookTestActor(t, database)
+	s := New(database)
+
+	dispatcher := webhooks.StartDispatcher(database, time.Second)
+	defer dispatcher.Stop()
+
+	container, err := s.Containers.Create(actorUUID, ContainerCreateParams{Slug: "project"})
+	if err != nil {
+		t.Fatalf("failed to create container: %v", err)
+	}
+	result, err := s.Tasks.Create(actorUUID, CreateParams{
+		Slug: "task", Title: "Task", ProjectUUID: container.UUID, State: "open", Priority: 2,
+	})
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	delivered := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload webhooks.Payload
+		_ = json.Unmarshal(body, &payload)
+		time.Sleep(300 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+		delivered <- payload.State
+	}))
+	defer server.Close()
+
+	webhookURLs, _ := json.Marshal([]string{server.URL + "/hook"})
+	if _, err := s.Containers.UpdateFields(actorUUID, container.UUID, map[string]interface{}{"webhook_urls": string(webhookURLs)}, 0); err != nil {
+		t.Fatalf("failed to set webhook urls: %v", err)
+	}
+
+	start := time.Now()
+	if _, err := s.Tasks.UpdateFields(actorUUID, result.UUID, map[string]interface{}{"state": "in_progress"}, 0); err != nil {
+		t.Fatalf("failed to update task: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Fatalf("UpdateFields waited %v for the webhook receiver", elapsed)
+	}
+
+	// The delivery was queued in the write transaction
+	var queued int
+	if err := database.QueryRow("SELECT COUNT(*) FROM webhook_deliveries WHERE task_uuid = ?", result.UUID).Scan(&queued); err != nil {
+		t.Fatalf("failed to count deliveries: %v", err)
+	}
+	if queued != 1 {
+		t.Fatalf("expected 1 queued delivery, got %d", queued)
+	}
+
+	select {
+	case state := <-delivered:
+		if state != "in_progress" {
+			t.Fatalf("unexpected state: %s", state)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for background delivery")
+	}
+}
@@ -0,0 +1,116 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lherron/wrkq/internal/domain"
+)
+
+// TemplateStore handles task template persistence.
+//
+// Like views, templates are configuration rather than tracked work: they
+// carry no etag and their changes are not written to the event log. The
+// store only checks that fields is a JSON object; callers decide which keys
+// are meaningful.
+type TemplateStore struct {
+	store *Store
+}
+
+const templateSelect = `
+	SELECT uuid, name, fields, created_by_actor_uuid, created_at, updated_at
+	FROM task_templates
+`
+
+func scanTemplate(row interface{ Scan(...interface{}) error }) (*domain.TaskTemplate, error) {
+	var t domain.TaskTemplate
+	var fields, createdAt, updatedAt string
+	var createdBy sql.NullString
+	if err := row.Scan(&t.UUID, &t.Name, &fields, &createdBy, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	t.Fields = json.RawMessage(fields)
+	if createdBy.Valid {
+		t.CreatedByActorUUID = &createdBy.String
+	}
+	t.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	t.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	return &t, nil
+}
+
+// Create saves a template. Names are unique across the database.
+func (ts *TemplateStore) Create(actorUUID, name string, fields json.RawMessage) (*domain.TaskTemplate, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("template name is required")
+	}
+	if len(fields) == 0 {
+		fields = json.RawMessage("{}")
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(fields, &object); err != nil || object == nil {
+		return nil, fmt.Errorf("template fields must be a JSON object")
+	}
+
+	var exists int
+	if err := ts.store.db.QueryRow("SELECT COUNT(*) FROM task_templates WHERE name = ?", name).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check template name: %w", err)
+	}
+	if exists > 0 {
+		return nil, fmt.Errorf("template already exists: %s", name)
+	}
+
+	res, err := ts.store.db.Exec(
+		"INSERT INTO task_templates (name, fields, created_by_actor_uuid) VALUES (?, ?, ?)",
+		name, string(fields), actorUUID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create template: %w", err)
+	}
+	rowID, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+
+	template, err := scanTemplate(ts.store.db.QueryRow(templateSelect+" WHERE rowid = ?", rowID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load template: %w", err)
+	}
+	return template, nil
+}
+
+// List returns every template ordered by name.
+func (ts *TemplateStore) List() ([]domain.TaskTemplate, error) {
+	rows, err := ts.store.db.Query(templateSelect + " ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []domain.TaskTemplate{}
+	for rows.Next() {
+		template, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan template: %w", err)
+		}
+		templates = append(templates, *template)
+	}
+	return templates, rows.Err()
+}
+
+// Resolve finds a template by UUID or name.
+func (ts *TemplateStore) Resolve(selector string) (*domain.TaskTemplate, error) {
+	template, err := scanTemplate(ts.store.db.QueryRow(
+		templateSelect+" WHERE uuid = ? OR name = ?", selector, strings.TrimSpace(selector),
+	))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("template not found: %s", selector)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve template: %w", err)
+	}
+	return template, nil
+}
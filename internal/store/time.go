@@ -0,0 +1,145 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lherron/wrkq/internal/domain"
+	"github.com/lherron/wrkq/internal/events"
+)
+
+// TimeEntryStore handles the time actors log against tasks.
+//
+// Logging time records a task.time_logged event but, like reactions, does
+// not bump the task's etag: time is reported against a task rather than
+// changing it.
+type TimeEntryStore struct {
+	store *Store
+}
+
+// LogTimeParams describes a block of time to log against a task.
+type LogTimeParams struct {
+	Minutes   int
+	Note      string
+	StartedAt string // RFC 3339; optional
+}
+
+// Log records a time entry for actorUUID on a task and returns it.
+func (ts *TimeEntryStore) Log(actorUUID, taskUUID string, params LogTimeParams) (*domain.TimeEntry, error) {
+	if err := domain.ValidateTimeEntryMinutes(params.Minutes); err != nil {
+		return nil, err
+	}
+	var startedAt interface{}
+	if params.StartedAt != "" {
+		if _, err := domain.ValidateTimestamp(params.StartedAt); err != nil {
+			return nil, err
+		}
+		startedAt = params.StartedAt
+	}
+
+	entryUUID := uuid.New().String()
+	err := ts.store.withTx(func(tx *sql.Tx, ew *events.Writer) error {
+		var exists int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM tasks WHERE uuid = ?", taskUUID).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to look up task: %w", err)
+		}
+		if exists == 0 {
+			return fmt.Errorf("task not found: %s", taskUUID)
+		}
+
+		if _, err := tx.Exec(`
+			INSERT INTO time_entries (uuid, task_uuid, actor_uuid, minutes, note, started_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, entryUUID, taskUUID, actorUUID, params.Minutes, params.Note, startedAt); err != nil {
+			return fmt.Errorf("failed to log time: %w", err)
+		}
+
+		payload, err := json.Marshal(map[string]interface{}{
+			"time_entry_uuid": entryUUID,
+			"minutes":         params.Minutes,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to marshal event payload: %w", err)
+		}
+		payloadStr := string(payload)
+		if err := ew.LogEvent(tx, &domain.Event{
+			ActorUUID:    &actorUUID,
+			ResourceType: "task",
+			ResourceUUID: &taskUUID,
+			EventType:    "task.time_logged",
+			Payload:      &payloadStr,
+		}); err != nil {
+			return fmt.Errorf("failed to log event: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.get(entryUUID)
+}
+
+func (ts *TimeEntryStore) get(entryUUID string) (*domain.TimeEntry, error) {
+	entries, err := ts.query("WHERE te.uuid = ?", entryUUID)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("time entry not found: %s", entryUUID)
+	}
+	return &entries[0], nil
+}
+
+// List returns the time logged against a task, oldest first.
+func (ts *TimeEntryStore) List(taskUUID string) ([]domain.TimeEntry, error) {
+	return ts.query("WHERE te.task_uuid = ?", taskUUID)
+}
+
+// Total sums the time logged against a task.
+func (ts *TimeEntryStore) Total(taskUUID string) (domain.TimeTotal, error) {
+	var total domain.TimeTotal
+	err := ts.store.db.QueryRow(`
+		SELECT COALESCE(SUM(minutes), 0), COUNT(*) FROM time_entries WHERE task_uuid = ?
+	`, taskUUID).Scan(&total.Minutes, &total.Entries)
+	if err != nil {
+		return domain.TimeTotal{}, fmt.Errorf("failed to total time entries: %w", err)
+	}
+	return total, nil
+}
+
+func (ts *TimeEntryStore) query(where string, args ...interface{}) ([]domain.TimeEntry, error) {
+	rows, err := ts.store.db.Query(`
+		SELECT te.uuid, te.task_uuid, te.actor_uuid, COALESCE(a.slug, ''), te.minutes, te.note,
+		       te.started_at, te.created_at
+		FROM time_entries te
+		LEFT JOIN actors a ON a.uuid = te.actor_uuid
+		`+where+`
+		ORDER BY COALESCE(te.started_at, te.created_at), te.rowid
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list time entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []domain.TimeEntry{}
+	for rows.Next() {
+		var e domain.TimeEntry
+		var startedAt sql.NullString
+		var createdAt string
+		if err := rows.Scan(&e.UUID, &e.TaskUUID, &e.ActorUUID, &e.ActorSlug, &e.Minutes, &e.Note, &startedAt, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan time entry: %w", err)
+		}
+		if startedAt.Valid {
+			if t, err := time.Parse(time.RFC3339, startedAt.String); err == nil {
+				e.StartedAt = &t
+			}
+		}
+		e.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
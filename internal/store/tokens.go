@@ -0,0 +1,156 @@
+package store
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lherron/wrkq/internal/domain"
+)
+
+// apiTokenPrefix marks wrkq API tokens so they are easy to spot in configs
+// and logs.
+const apiTokenPrefix = "wrkq_"
+
+// TokenStore handles the daemon's API tokens in the api_tokens table.
+//
+// Like kinds, tokens are configuration rather than tracked work: they carry
+// no etag and changes are not written to the event log. Only the SHA-256
+// hash of a token is stored, so a token can't be recovered after Create.
+type TokenStore struct {
+	store *Store
+}
+
+const tokenSelect = `
+	SELECT t.uuid, t.name, t.actor_uuid, COALESCE(a.slug, ''), t.scope, t.project_uuid, t.created_at
+	FROM api_tokens t
+	LEFT JOIN actors a ON a.uuid = t.actor_uuid
+`
+
+func scanToken(row interface{ Scan(...interface{}) error }) (*domain.APIToken, error) {
+	var t domain.APIToken
+	var scope, createdAt string
+	var projectUUID sql.NullString
+	if err := row.Scan(&t.UUID, &t.Name, &t.ActorUUID, &t.ActorSlug, &scope, &projectUUID, &createdAt); err != nil {
+		return nil, err
+	}
+	t.Scope = domain.TokenScope(scope)
+	if projectUUID.Valid {
+		t.ProjectUUID = &projectUUID.String
+	}
+	t.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	return &t, nil
+}
+
+// hashToken returns the hex SHA-256 of a token, as stored in token_hash.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create issues a token named name that acts as actorUUID with scope,
+// restricted to projectUUID's subtree unless projectUUID is empty. It returns
+// the token's record and the token itself, which is not stored.
+func (ts *TokenStore) Create(actorUUID, name, scope, projectUUID string) (*domain.APIToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", &domain.ValidationError{Message: "token name is required"}
+	}
+	if err := domain.ValidateTokenScope(scope); err != nil {
+		return nil, "", err
+	}
+
+	var exists int
+	if err := ts.store.db.QueryRow("SELECT COUNT(*) FROM api_tokens WHERE name = ?", name).Scan(&exists); err != nil {
+		return nil, "", fmt.Errorf("failed to check token name: %w", err)
+	}
+	if exists > 0 {
+		return nil, "", fmt.Errorf("token already exists: %s", name)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	plaintext := apiTokenPrefix + hex.EncodeToString(secret)
+
+	var project interface{}
+	if projectUUID != "" {
+		project = projectUUID
+	}
+	tokenUUID := uuid.New().String()
+	if _, err := ts.store.db.Exec(`
+		INSERT INTO api_tokens (uuid, name, token_hash, actor_uuid, scope, project_uuid)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, tokenUUID, name, hashToken(plaintext), actorUUID, scope, project); err != nil {
+		return nil, "", fmt.Errorf("failed to create token: %w", err)
+	}
+
+	token, err := scanToken(ts.store.db.QueryRow(tokenSelect+" WHERE t.uuid = ?", tokenUUID))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load token: %w", err)
+	}
+	return token, plaintext, nil
+}
+
+// List returns every token ordered by name.
+func (ts *TokenStore) List() ([]domain.APIToken, error) {
+	rows, err := ts.store.db.Query(tokenSelect + " ORDER BY t.name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []domain.APIToken{}
+	for rows.Next() {
+		token, err := scanToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan token: %w", err)
+		}
+		tokens = append(tokens, *token)
+	}
+	return tokens, rows.Err()
+}
+
+// Revoke deletes the token with the given name or UUID.
+func (ts *TokenStore) Revoke(selector string) error {
+	res, err := ts.store.db.Exec("DELETE FROM api_tokens WHERE uuid = ? OR name = ?", selector, strings.TrimSpace(selector))
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("token not found: %s", selector)
+	}
+	return nil
+}
+
+// Authenticate returns the token matching plaintext, or nil if there is
+// none or its actor has been deactivated.
+func (ts *TokenStore) Authenticate(plaintext string) (*domain.APIToken, error) {
+	if plaintext == "" {
+		return nil, nil
+	}
+	token, err := scanToken(ts.store.db.QueryRow(tokenSelect+" WHERE t.token_hash = ? AND a.archived_at IS NULL", hashToken(plaintext)))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up token: %w", err)
+	}
+	return token, nil
+}
+
+// Count returns the number of tokens. With none, the daemon falls back to
+// its single shared token.
+func (ts *TokenStore) Count() (int, error) {
+	var count int
+	if err := ts.store.db.QueryRow("SELECT COUNT(*) FROM api_tokens").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count tokens: %w", err)
+	}
+	return count, nil
+}
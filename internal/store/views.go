@@ -0,0 +1,137 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lherron/wrkq/internal/domain"
+)
+
+// ViewStore handles saved view (named task filter) persistence.
+//
+// Views are per-actor preferences rather than tracked work, so they carry no
+// etag and their changes are not written to the event log. The store treats
+// the filter as opaque JSON; callers decide how to interpret and run it.
+type ViewStore struct {
+	store *Store
+}
+
+const viewSelect = `
+	SELECT uuid, owner_actor_uuid, name, filter, created_at, updated_at
+	FROM views
+`
+
+func scanView(row interface{ Scan(...interface{}) error }) (*domain.View, error) {
+	var v domain.View
+	var filter, createdAt, updatedAt string
+	if err := row.Scan(&v.UUID, &v.OwnerActorUUID, &v.Name, &filter, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	v.Filter = json.RawMessage(filter)
+	v.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	v.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	return &v, nil
+}
+
+// Create saves a view for ownerUUID. Names are unique per owner.
+func (vs *ViewStore) Create(ownerUUID, name string, filter json.RawMessage) (*domain.View, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("view name is required")
+	}
+	if len(filter) == 0 {
+		filter = json.RawMessage("{}")
+	}
+	if !json.Valid(filter) {
+		return nil, fmt.Errorf("view filter must be valid JSON")
+	}
+
+	var exists int
+	if err := vs.store.db.QueryRow(
+		"SELECT COUNT(*) FROM views WHERE owner_actor_uuid = ? AND name = ?", ownerUUID, name,
+	).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check view name: %w", err)
+	}
+	if exists > 0 {
+		return nil, fmt.Errorf("view already exists: %s", name)
+	}
+
+	res, err := vs.store.db.Exec(
+		"INSERT INTO views (owner_actor_uuid, name, filter) VALUES (?, ?, ?)",
+		ownerUUID, name, string(filter),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create view: %w", err)
+	}
+	rowID, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+
+	view, err := scanView(vs.store.db.QueryRow(viewSelect+" WHERE rowid = ?", rowID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load view: %w", err)
+	}
+	return view, nil
+}
+
+// List returns the views owned by ownerUUID ordered by name, or every view
+// (ordered by owner, then name) if ownerUUID is empty.
+func (vs *ViewStore) List(ownerUUID string) ([]domain.View, error) {
+	query := viewSelect
+	var args []interface{}
+	if ownerUUID != "" {
+		query += " WHERE owner_actor_uuid = ?"
+		args = append(args, ownerUUID)
+	}
+	query += " ORDER BY owner_actor_uuid, name"
+
+	rows, err := vs.store.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list views: %w", err)
+	}
+	defer rows.Close()
+
+	views := []domain.View{}
+	for rows.Next() {
+		view, err := scanView(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan view: %w", err)
+		}
+		views = append(views, *view)
+	}
+	return views, rows.Err()
+}
+
+// Resolve finds a view by UUID (any owner) or by name among ownerUUID's views.
+func (vs *ViewStore) Resolve(ownerUUID, selector string) (*domain.View, error) {
+	view, err := scanView(vs.store.db.QueryRow(
+		viewSelect+" WHERE uuid = ? OR (owner_actor_uuid = ? AND name = ?)",
+		selector, ownerUUID, strings.TrimSpace(selector),
+	))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("view not found: %s", selector)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve view: %w", err)
+	}
+	return view, nil
+}
+
+// Delete removes a view. Only the owner may delete it.
+func (vs *ViewStore) Delete(ownerUUID, selector string) (*domain.View, error) {
+	view, err := vs.Resolve(ownerUUID, selector)
+	if err != nil {
+		return nil, err
+	}
+	if view.OwnerActorUUID != ownerUUID {
+		return nil, fmt.Errorf("view %s is owned by another actor", selector)
+	}
+	if _, err := vs.store.db.Exec("DELETE FROM views WHERE uuid = ?", view.UUID); err != nil {
+		return nil, fmt.Errorf("failed to delete view: %w", err)
+	}
+	return view, nil
+}
@@ -0,0 +1,293 @@
+package webhooks
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/lherron/wrkq/internal/db"
+)
+
+// RetryPolicy bounds redelivery of a failed webhook. Attempts includes the
+// first try; the delay doubles after each failure up to MaxDelay.
+type RetryPolicy struct {
+	Attempts  int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// Retry is the policy used for every delivery. Without a running Dispatcher
+// deliveries are sent inline by the writer, so keep the worst case (Attempts
+// request timeouts plus the delays) small.
+var Retry = RetryPolicy{
+	Attempts:  3,
+	BaseDelay: 200 * time.Millisecond,
+	MaxDelay:  2 * time.Second,
+}
+
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d
+}
+
+// Delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// Delivery is a queued webhook delivery. Rows are removed once delivered on
+// the first attempt; deliveries that failed at least once are kept as a
+// record, ending up 'delivered' or 'dead'.
+type Delivery struct {
+	ID            int64   `json:"id"`
+	TaskUUID      string  `json:"task_uuid"`
+	ContainerUUID *string `json:"container_uuid,omitempty"`
+	URL           string  `json:"url"`
+	Payload       string  `json:"payload"`
+	Status        string  `json:"status"`
+	Attempts      int     `json:"attempts"`
+	LastError     *string `json:"last_error,omitempty"`
+	NextRetry     *string `json:"next_retry,omitempty"`
+	CreatedAt     string  `json:"created_at"`
+	UpdatedAt     string  `json:"updated_at"`
+}
+
+// deliveryError is a failed attempt; retryable failures (network errors,
+// 5xx, 429) are tried again, others fail the delivery immediately.
+type deliveryError struct {
+	err       error
+	retryable bool
+}
+
+func (e *deliveryError) Error() string { return e.err.Error() }
+func (e *deliveryError) Unwrap() error { return e.err }
+
+// deliveriesSucceeded and deliveriesFailed count the sends this process has
+// made, for the daemon's metrics.
+var deliveriesSucceeded, deliveriesFailed atomic.Int64
+
+// DeliveryCounts returns how many webhook sends this process has made that
+// succeeded and failed. Each retry counts as a send of its own.
+func DeliveryCounts() (succeeded, failed int64) {
+	return deliveriesSucceeded.Load(), deliveriesFailed.Load()
+}
+
+// attempt sends body to t up to attempts times with the policy's backoff,
+// calling onFailure after each failed attempt with the time of the next one
+// (nil if none). It returns the number of attempts made and the final error.
+func attempt(client *http.Client, t target, body []byte, attempts int, onFailure func(attempts int, err error, next *time.Time)) (int, error) {
+	if attempts < 1 {
+		attempts = 1
+	}
+	var err error
+	for n := 1; n <= attempts; n++ {
+		err = sendWebhook(client, t, body)
+		if err == nil {
+			deliveriesSucceeded.Add(1)
+			return n, nil
+		}
+		deliveriesFailed.Add(1)
+		var de *deliveryError
+		if n == attempts || (errors.As(err, &de) && !de.retryable) {
+			onFailure(n, err, nil)
+			return n, err
+		}
+		wait := Retry.delay(n)
+		next := time.Now().Add(wait)
+		onFailure(n, err, &next)
+		time.Sleep(wait)
+	}
+	return attempts, err
+}
+
+// claimLease is how long a process may hold a delivery before another may
+// take it over; it comfortably exceeds a full retry cycle.
+const claimLease = time.Minute
+
+// processDelivery claims and sends one delivery. Queued deliveries get the
+// policy's remaining attempts; a redrive gets a fresh set and may also pick
+// up dead deliveries. It returns nil if another process holds the delivery
+// or it isn't due.
+func processDelivery(database *db.DB, client *http.Client, id int64, redrive bool) (*Delivery, error) {
+	now := time.Now().UTC()
+	eligible := "status = 'pending' AND next_retry <= ?"
+	args := []interface{}{now.Add(claimLease).Format(time.RFC3339), id, now.Format(time.RFC3339), now.Format(time.RFC3339)}
+	if redrive {
+		eligible = "status IN ('pending', 'dead')"
+		args = args[:3]
+	}
+	res, err := database.Exec(`
+		UPDATE webhook_deliveries SET claimed_until = ?
+		WHERE id = ? AND (claimed_until IS NULL OR claimed_until <= ?) AND `+eligible, args...)
+	if err != nil {
+		return nil, fmt.Errorf("claim webhook delivery: %w", err)
+	}
+	if claimed, _ := res.RowsAffected(); claimed == 0 {
+		return nil, nil
+	}
+
+	d, err := scanDelivery(database.QueryRow(deliverySelect+" WHERE id = ?", id))
+	if err != nil {
+		return nil, fmt.Errorf("load webhook delivery: %w", err)
+	}
+
+	budget := Retry.Attempts
+	if !redrive {
+		budget -= d.Attempts
+	}
+	previous := d.Attempts
+	t := target{URL: d.URL, Secret: deliverySecret(database, d)}
+	n, sendErr := attempt(client, t, []byte(d.Payload), budget, func(attempts int, err error, next *time.Time) {
+		status, nextRetry := DeliveryDead, interface{}(nil)
+		if next != nil {
+			status, nextRetry = DeliveryPending, next.UTC().Format(time.RFC3339)
+		}
+		updateDelivery(database, id, status, previous+attempts, err.Error(), nextRetry)
+	})
+
+	switch {
+	case sendErr != nil:
+		log.Printf("webhooks: delivery %d to %q failed after %d attempt(s): %v", id, d.URL, previous+n, sendErr)
+	case previous+n == 1:
+		// Delivered first time: nothing worth keeping
+		if _, err := database.Exec("DELETE FROM webhook_deliveries WHERE id = ?", id); err != nil {
+			log.Printf("webhooks: failed to remove delivery %d: %v", id, err)
+		}
+		d.Status, d.Attempts = DeliveryDelivered, 1
+		return d, nil
+	default:
+		updateDelivery(database, id, DeliveryDelivered, previous+n, "", nil)
+	}
+
+	return scanDelivery(database.QueryRow(deliverySelect+" WHERE id = ?", id))
+}
+
+func updateDelivery(database *db.DB, id int64, status string, attempts int, lastError string, nextRetry interface{}) {
+	var errValue interface{}
+	if lastError != "" {
+		errValue = lastError
+	}
+	if _, err := database.Exec(`
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = ?, last_error = COALESCE(?, last_error), next_retry = ?,
+		    claimed_until = CASE WHEN ? = 'pending' THEN claimed_until END
+		WHERE id = ?
+	`, status, attempts, errValue, nextRetry, status, id); err != nil {
+		log.Printf("webhooks: failed to update delivery %d: %v", id, err)
+	}
+}
+
+const deliverySelect = `
+	SELECT id, task_uuid, container_uuid, url, payload, status, attempts, last_error, next_retry, created_at, updated_at
+	FROM webhook_deliveries
+`
+
+func scanDelivery(row interface{ Scan(...interface{}) error }) (*Delivery, error) {
+	var d Delivery
+	var containerUUID, lastError, nextRetry sql.NullString
+	if err := row.Scan(&d.ID, &d.TaskUUID, &containerUUID, &d.URL, &d.Payload, &d.Status, &d.Attempts,
+		&lastError, &nextRetry, &d.CreatedAt, &d.UpdatedAt); err != nil {
+		return nil, err
+	}
+	d.ContainerUUID = nullStringToPtr(containerUUID)
+	d.LastError = nullStringToPtr(lastError)
+	d.NextRetry = nullStringToPtr(nextRetry)
+	return &d, nil
+}
+
+// ListDeliveries returns tracked deliveries, oldest first, optionally
+// filtered by status ("" for all).
+func ListDeliveries(database *db.DB, status string) ([]Delivery, error) {
+	query := deliverySelect
+	var args []interface{}
+	if status != "" {
+		query += " WHERE status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY id"
+
+	rows, err := database.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, rows.Err()
+}
+
+// Redrive retries a recorded delivery with a fresh set of attempts,
+// re-signing it with the currently configured secret for its URL. The stored
+// payload is sent as-is. It returns the updated delivery; a failed redrive is
+// not an error, the delivery simply stays dead.
+func Redrive(database *db.DB, id int64) (*Delivery, error) {
+	d, err := scanDelivery(database.QueryRow(deliverySelect+" WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("webhook delivery not found: %d", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook delivery: %w", err)
+	}
+	if d.Status == DeliveryDelivered {
+		return nil, fmt.Errorf("webhook delivery %d was already delivered", id)
+	}
+
+	redriven, err := processDelivery(database, &http.Client{Timeout: defaultTimeout}, id, true)
+	if err != nil {
+		return nil, err
+	}
+	if redriven == nil {
+		return nil, fmt.Errorf("webhook delivery %d is being sent by another process", id)
+	}
+	return redriven, nil
+}
+
+// deliverySecret finds the signing secret now configured for the delivery's
+// URL on its container chain, or "" if the URL is no longer registered.
+// Deliveries queued before container_uuid was recorded fall back to the
+// task's current container.
+func deliverySecret(database *db.DB, d *Delivery) string {
+	var payload Payload
+	if err := json.Unmarshal([]byte(d.Payload), &payload); err != nil {
+		return ""
+	}
+	containerUUID := payload.ProjectUUID
+	if d.ContainerUUID != nil {
+		containerUUID = *d.ContainerUUID
+	}
+	if containerUUID == "" {
+		info, err := LookupTaskInfo(database, d.TaskUUID)
+		if err != nil {
+			return ""
+		}
+		containerUUID = info.ProjectUUID
+	}
+
+	targets, err := resolveTargets(database, containerUUID, payload)
+	if err != nil {
+		return ""
+	}
+	for _, t := range targets {
+		if t.URL == d.URL {
+			return t.Secret
+		}
+	}
+	return ""
+}
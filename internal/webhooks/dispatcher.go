@@ -0,0 +1,167 @@
+package webhooks
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lherron/wrkq/internal/db"
+)
+
+const (
+	defaultPollInterval = 5 * time.Second
+	drainBatch          = 100
+)
+
+// Dispatcher drains the webhook_deliveries queue in the background. While one
+// is running for a database, Notify only wakes it, so writes return without
+// waiting on receivers. Without one, Notify delivers inline.
+type Dispatcher struct {
+	database *db.DB
+	interval time.Duration
+	wake     chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+var (
+	dispatchersMu sync.Mutex
+	dispatchers   = map[*db.DB]*Dispatcher{}
+)
+
+// StartDispatcher starts draining the queue for database, polling every
+// pollInterval (default 5s) for retries and for rows left over by other
+// processes or a previous run. Stop it before closing the database.
+func StartDispatcher(database *db.DB, pollInterval time.Duration) *Dispatcher {
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+	d := &Dispatcher{
+		database: database,
+		interval: pollInterval,
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	dispatchersMu.Lock()
+	dispatchers[database] = d
+	dispatchersMu.Unlock()
+
+	go d.run()
+	return d
+}
+
+// Stop unregisters the dispatcher and waits for in-flight deliveries.
+func (d *Dispatcher) Stop() {
+	dispatchersMu.Lock()
+	if dispatchers[d.database] == d {
+		delete(dispatchers, d.database)
+	}
+	dispatchersMu.Unlock()
+
+	close(d.stop)
+	<-d.done
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		drain(d.database)
+		select {
+		case <-d.stop:
+			return
+		case <-d.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// Notify tells the dispatcher that deliveries were queued. If no dispatcher
+// is running in this process (the CLI, tests, or wrkqd in synchronous mode)
+// the due deliveries are sent before Notify returns.
+func Notify(database *db.DB) {
+	dispatchersMu.Lock()
+	d := dispatchers[database]
+	dispatchersMu.Unlock()
+
+	if d == nil {
+		drain(database)
+		return
+	}
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// drain sends every due, unclaimed delivery.
+func drain(database *db.DB) {
+	client := &http.Client{Timeout: defaultTimeout}
+	for {
+		ids, err := dueDeliveries(database, drainBatch)
+		if err != nil {
+			log.Printf("webhooks: failed to list due deliveries: %v", err)
+			return
+		}
+		if len(ids) == 0 {
+			return
+		}
+
+		workers := defaultConcurrency
+		if len(ids) < workers {
+			workers = len(ids)
+		}
+		jobs := make(chan int64)
+		var wg sync.WaitGroup
+		wg.Add(workers)
+		for i := 0; i < workers; i++ {
+			go func() {
+				defer wg.Done()
+				for id := range jobs {
+					if _, err := processDelivery(database, client, id, false); err != nil {
+						log.Printf("webhooks: delivery %d: %v", id, err)
+					}
+				}
+			}()
+		}
+		for _, id := range ids {
+			jobs <- id
+		}
+		close(jobs)
+		wg.Wait()
+
+		if len(ids) < drainBatch {
+			return
+		}
+	}
+}
+
+func dueDeliveries(database *db.DB, limit int) ([]int64, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	rows, err := database.Query(`
+		SELECT id FROM webhook_deliveries
+		WHERE status = 'pending' AND next_retry <= ?
+		  AND (claimed_until IS NULL OR claimed_until <= ?)
+		ORDER BY id
+		LIMIT ?
+	`, now, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
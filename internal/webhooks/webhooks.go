@@ -2,14 +2,18 @@ package webhooks
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/lherron/wrkq/internal/db"
@@ -18,8 +22,21 @@ import (
 const (
 	defaultTimeout     = 500 * time.Millisecond
 	defaultConcurrency = 4
+
+	// SignatureHeader carries "sha256=" followed by the hex HMAC-SHA256 of
+	// "<timestamp>.<body>", keyed with the container's webhook secret.
+	SignatureHeader = "X-Wrkq-Signature"
+	// TimestampHeader carries the Unix time (seconds) used in the signature.
+	TimestampHeader = "X-Wrkq-Timestamp"
 )
 
+// target is a webhook URL and the secret used to sign requests to it ("" for
+// unsigned).
+type target struct {
+	URL    string
+	Secret string
+}
+
 // BlockerInfo represents an incomplete blocking task.
 // This matches the format used in wrkq cat --json output.
 type BlockerInfo struct {
@@ -34,6 +51,7 @@ type Payload struct {
 	ProjectID    string          `json:"project_id"`
 	ProjectUUID  string          `json:"project_uuid"`
 	State        string          `json:"state"`
+	Assignee     *string         `json:"assignee"` // actor slug
 	Priority     int             `json:"priority"`
 	Kind         string          `json:"kind"`
 	RunStatus    *string         `json:"run_status"`
@@ -55,6 +73,7 @@ type TaskInfo struct {
 	ProjectID    string
 	ProjectUUID  string
 	State        string
+	Assignee     *string // actor slug
 	Priority     int
 	Kind         string
 	RunStatus    *string
@@ -69,30 +88,87 @@ type TaskInfo struct {
 	BlockedBy    []BlockerInfo
 }
 
-// DispatchTask resolves task info then dispatches webhooks.
+// queryer is satisfied by both *db.DB and *sql.Tx, so deliveries can be
+// enqueued inside the transaction that made the change.
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// DispatchTask queues webhooks for the task's current state and hands them to
+// the dispatcher. Prefer EnqueueTask inside the write transaction followed by
+// Notify after commit; DispatchTask is for writes that don't go through one.
 func DispatchTask(database *db.DB, taskUUID string) {
-	info, err := LookupTaskInfo(database, taskUUID)
-	if err != nil {
-		log.Printf("webhooks: lookup task %s failed: %v", taskUUID, err)
+	if err := EnqueueTask(database, taskUUID); err != nil {
+		log.Printf("webhooks: enqueue task %s failed: %v", taskUUID, err)
 		return
 	}
-	DispatchTaskInfo(database, info)
+	Notify(database)
 }
 
-// DispatchTaskInfo dispatches webhooks using pre-fetched task info.
+// DispatchTaskInfo queues webhooks using pre-fetched task info, then calls Notify.
 func DispatchTaskInfo(database *db.DB, info TaskInfo) {
+	if err := EnqueueTaskInfo(database, info); err != nil {
+		log.Printf("webhooks: enqueue task %s failed: %v", info.TaskID, err)
+		return
+	}
+	Notify(database)
+}
+
+// EnqueueTask queues a delivery of the task's current state to each of its
+// webhook targets. Call it inside the write transaction so the delivery is
+// persisted atomically with the change, then call Notify after commit.
+func EnqueueTask(q queryer, taskUUID string) error {
+	info, err := LookupTaskInfo(q, taskUUID)
+	if err != nil {
+		return err
+	}
+	return EnqueueTaskInfo(q, info)
+}
+
+// EnqueueTaskInfo queues deliveries using pre-fetched task info.
+func EnqueueTaskInfo(q queryer, info TaskInfo) error {
+	payload := buildPayload(info)
+	targets, err := resolveTargets(q, info.ProjectUUID, payload)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode payload: %w", err)
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, t := range targets {
+		if _, err := q.Exec(`
+			INSERT INTO webhook_deliveries (task_uuid, container_uuid, url, payload, status, attempts, next_retry)
+			VALUES (?, ?, ?, ?, 'pending', 0, ?)
+		`, info.TaskUUID, info.ProjectUUID, t.URL, string(body), now); err != nil {
+			return fmt.Errorf("enqueue webhook delivery: %w", err)
+		}
+	}
+	return nil
+}
+
+func buildPayload(info TaskInfo) Payload {
 	meta := json.RawMessage(`{}`)
 	if info.Meta != nil && *info.Meta != "" {
 		if json.Valid([]byte(*info.Meta)) {
 			meta = json.RawMessage(*info.Meta)
 		}
 	}
-	payload := Payload{
+	return Payload{
 		TicketID:     info.TaskID,
 		TicketUUID:   info.TaskUUID,
 		ProjectID:    info.ProjectID,
 		ProjectUUID:  info.ProjectUUID,
 		State:        info.State,
+		Assignee:     info.Assignee,
 		Priority:     info.Priority,
 		Kind:         info.Kind,
 		RunStatus:    info.RunStatus,
@@ -106,12 +182,6 @@ func DispatchTaskInfo(database *db.DB, info TaskInfo) {
 		SDKSessionID: info.SDKSessionID,
 		BlockedBy:    info.BlockedBy,
 	}
-	urls, err := ResolveWebhookTargets(database, info.ProjectUUID, payload)
-	if err != nil {
-		log.Printf("webhooks: resolve targets for task %s failed: %v", info.TaskID, err)
-		return
-	}
-	dispatchURLs(urls, payload)
 }
 
 // nullStringToPtr converts sql.NullString to *string.
@@ -123,21 +193,22 @@ func nullStringToPtr(ns sql.NullString) *string {
 }
 
 // LookupTaskInfo fetches the task and project friendly IDs for dispatch.
-func LookupTaskInfo(database *db.DB, taskUUID string) (TaskInfo, error) {
+func LookupTaskInfo(q queryer, taskUUID string) (TaskInfo, error) {
 	var info TaskInfo
-	var runStatus, resolution, meta sql.NullString
+	var assignee, runStatus, resolution, meta sql.NullString
 	var cpProjectID, cpWorkItemID, cpRunID, cpSessionID, sdkSessionID sql.NullString
 
-	err := database.QueryRow(`
+	err := q.QueryRow(`
 		SELECT t.id, t.uuid, t.project_uuid, c.id,
-		       t.state, t.priority, t.kind, t.run_status, t.resolution, t.meta, t.etag,
+		       t.state, a.slug, t.priority, t.kind, t.run_status, t.resolution, t.meta, t.etag,
 		       t.cp_project_id, t.cp_work_item_id, t.cp_run_id, t.cp_session_id, t.sdk_session_id
 		FROM tasks t
 		JOIN containers c ON c.uuid = t.project_uuid
+		LEFT JOIN actors a ON a.uuid = t.assignee_actor_uuid
 		WHERE t.uuid = ?
 	`, taskUUID).Scan(
 		&info.TaskID, &info.TaskUUID, &info.ProjectUUID, &info.ProjectID,
-		&info.State, &info.Priority, &info.Kind,
+		&info.State, &assignee, &info.Priority, &info.Kind,
 		&runStatus, &resolution, &meta, &info.ETag,
 		&cpProjectID, &cpWorkItemID, &cpRunID, &cpSessionID, &sdkSessionID,
 	)
@@ -145,6 +216,7 @@ func LookupTaskInfo(database *db.DB, taskUUID string) (TaskInfo, error) {
 		return TaskInfo{}, fmt.Errorf("lookup task info: %w", err)
 	}
 
+	info.Assignee = nullStringToPtr(assignee)
 	info.RunStatus = nullStringToPtr(runStatus)
 	info.Resolution = nullStringToPtr(resolution)
 	info.Meta = nullStringToPtr(meta)
@@ -155,7 +227,7 @@ func LookupTaskInfo(database *db.DB, taskUUID string) (TaskInfo, error) {
 	info.SDKSessionID = nullStringToPtr(sdkSessionID)
 
 	// Query incomplete blockers for this task
-	blockerRows, err := database.Query(`
+	blockerRows, err := q.Query(`
 		SELECT t.id, t.state
 		FROM task_relations r
 		JOIN tasks t ON r.from_task_uuid = t.uuid
@@ -185,62 +257,100 @@ func LookupTaskInfo(database *db.DB, taskUUID string) (TaskInfo, error) {
 
 // ResolveWebhookTargets collects, templates, normalizes, and de-dupes webhook URLs.
 func ResolveWebhookTargets(database *db.DB, containerUUID string, payload Payload) ([]string, error) {
-	raw, err := collectWebhookURLs(database, containerUUID)
+	targets, err := resolveTargets(database, containerUUID, payload)
 	if err != nil {
 		return nil, err
 	}
-	return normalizeWebhookURLs(raw, payload), nil
+	var urls []string
+	for _, t := range targets {
+		urls = append(urls, t.URL)
+	}
+	return urls, nil
 }
 
-func collectWebhookURLs(database *db.DB, containerUUID string) ([]string, error) {
-	rows, err := database.Query(`
-		WITH RECURSIVE container_chain(uuid, parent_uuid, webhook_urls) AS (
-			SELECT uuid, parent_uuid, webhook_urls FROM containers WHERE uuid = ?
+func resolveTargets(q queryer, containerUUID string, payload Payload) ([]target, error) {
+	raw, err := collectWebhookTargets(q, containerUUID)
+	if err != nil {
+		return nil, err
+	}
+	return normalizeWebhookTargets(raw, payload), nil
+}
+
+// collectWebhookTargets walks from the container up to the root, returning
+// each declared URL with the secret of the nearest container (itself or an
+// ancestor) that has one. URLs from nearer containers come first.
+func collectWebhookTargets(q queryer, containerUUID string) ([]target, error) {
+	rows, err := q.Query(`
+		WITH RECURSIVE container_chain(uuid, parent_uuid, webhook_urls, webhook_secret, depth) AS (
+			SELECT uuid, parent_uuid, webhook_urls, webhook_secret, 0 FROM containers WHERE uuid = ?
 			UNION ALL
-			SELECT c.uuid, c.parent_uuid, c.webhook_urls
+			SELECT c.uuid, c.parent_uuid, c.webhook_urls, c.webhook_secret, cc.depth + 1
 			FROM containers c
 			JOIN container_chain cc ON c.uuid = cc.parent_uuid
 		)
-		SELECT webhook_urls FROM container_chain
-		WHERE webhook_urls IS NOT NULL AND webhook_urls != ''
+		SELECT webhook_urls, webhook_secret FROM container_chain
+		ORDER BY depth
 	`, containerUUID)
 	if err != nil {
 		return nil, fmt.Errorf("query webhook urls: %w", err)
 	}
 	defer rows.Close()
 
-	var collected []string
+	type link struct {
+		urls   []string
+		secret string
+	}
+	var chain []link
 	for rows.Next() {
-		var jsonStr string
-		if err := rows.Scan(&jsonStr); err != nil {
+		var urlsJSON, secret sql.NullString
+		if err := rows.Scan(&urlsJSON, &secret); err != nil {
 			return nil, fmt.Errorf("scan webhook urls: %w", err)
 		}
-		var urls []string
-		if err := json.Unmarshal([]byte(jsonStr), &urls); err != nil {
-			return nil, fmt.Errorf("parse webhook urls: %w", err)
+		l := link{secret: secret.String}
+		if urlsJSON.Valid && urlsJSON.String != "" {
+			if err := json.Unmarshal([]byte(urlsJSON.String), &l.urls); err != nil {
+				return nil, fmt.Errorf("parse webhook urls: %w", err)
+			}
 		}
-		collected = append(collected, urls...)
+		chain = append(chain, l)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("iterating webhook urls: %w", err)
 	}
+
+	// Secrets are inherited from the nearest ancestor that has one
+	for i := len(chain) - 2; i >= 0; i-- {
+		if chain[i].secret == "" {
+			chain[i].secret = chain[i+1].secret
+		}
+	}
+
+	var collected []target
+	for _, l := range chain {
+		for _, u := range l.urls {
+			collected = append(collected, target{URL: u, Secret: l.secret})
+		}
+	}
 	return collected, nil
 }
 
-func normalizeWebhookURLs(urls []string, payload Payload) []string {
-	if len(urls) == 0 {
+func normalizeWebhookTargets(targets []target, payload Payload) []target {
+	if len(targets) == 0 {
 		return nil
 	}
 
-	seen := make(map[string]struct{}, len(urls))
-	var normalized []string
-
-	for _, raw := range urls {
-		trimmed := strings.TrimSpace(raw)
+	seen := make(map[string]struct{}, len(targets))
+	var normalized []target
+	for _, t := range targets {
+		trimmed := strings.TrimSpace(t.URL)
 		if trimmed == "" {
 			continue
 		}
-		templated := applyTemplate(trimmed, payload)
+		templated, err := ExpandURL(trimmed, payload)
+		if err != nil && StrictTemplates {
+			log.Printf("webhooks: skipping url %q: %v", trimmed, err)
+			continue
+		}
 		templated = strings.TrimSpace(templated)
 		if templated == "" {
 			continue
@@ -257,16 +367,72 @@ func normalizeWebhookURLs(urls []string, payload Payload) []string {
 			continue
 		}
 		seen[templated] = struct{}{}
-		normalized = append(normalized, templated)
+		normalized = append(normalized, target{URL: templated, Secret: t.Secret})
 	}
-
 	return normalized
 }
 
-func applyTemplate(raw string, payload Payload) string {
-	result := strings.ReplaceAll(raw, "{ticket_id}", payload.TicketID)
-	result = strings.ReplaceAll(result, "{project_id}", payload.ProjectID)
-	return result
+// placeholderPattern matches a {name} URL template placeholder.
+var placeholderPattern = regexp.MustCompile(`\{[A-Za-z0-9_]+\}`)
+
+// Placeholders lists the URL template placeholders ExpandURL substitutes.
+var Placeholders = []string{"{ticket_id}", "{ticket_uuid}", "{project_id}", "{project_uuid}", "{state}", "{assignee}"}
+
+// StrictTemplates makes dispatch skip URLs containing unknown placeholders
+// rather than sending them with the placeholder intact. Set from the
+// webhook_strict_templates config option.
+var StrictTemplates bool
+
+// ExpandURL substitutes payload values for the placeholders in a webhook URL
+// template. Values are path-escaped; an unassigned task expands {assignee} to
+// "". Unknown placeholders are left intact and reported in the error, so the
+// caller decides whether to use the result.
+func ExpandURL(raw string, payload Payload) (string, error) {
+	assignee := ""
+	if payload.Assignee != nil {
+		assignee = *payload.Assignee
+	}
+	values := map[string]string{
+		"{ticket_id}":    payload.TicketID,
+		"{ticket_uuid}":  payload.TicketUUID,
+		"{project_id}":   payload.ProjectID,
+		"{project_uuid}": payload.ProjectUUID,
+		"{state}":        payload.State,
+		"{assignee}":     assignee,
+	}
+
+	var unknown []string
+	expanded := placeholderPattern.ReplaceAllStringFunc(raw, func(placeholder string) string {
+		value, ok := values[placeholder]
+		if !ok {
+			unknown = append(unknown, placeholder)
+			return placeholder
+		}
+		return url.PathEscape(value)
+	})
+	if len(unknown) > 0 {
+		return expanded, fmt.Errorf("unknown placeholder(s) %s", strings.Join(unknown, ", "))
+	}
+	return expanded, nil
+}
+
+// UnknownPlaceholders returns the placeholders in raw that ExpandURL does not
+// recognize.
+func UnknownPlaceholders(raw string) []string {
+	var unknown []string
+	for _, placeholder := range placeholderPattern.FindAllString(raw, -1) {
+		known := false
+		for _, p := range Placeholders {
+			if p == placeholder {
+				known = true
+				break
+			}
+		}
+		if !known {
+			unknown = append(unknown, placeholder)
+		}
+	}
+	return unknown
 }
 
 func isValidWebhookURL(raw string) bool {
@@ -283,54 +449,42 @@ func isValidWebhookURL(raw string) bool {
 	return true
 }
 
-func dispatchURLs(urls []string, payload Payload) {
-	if len(urls) == 0 {
-		return
-	}
-
-	body, err := json.Marshal(payload)
+func sendWebhook(client *http.Client, t target, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, t.URL, bytes.NewReader(body))
 	if err != nil {
-		log.Printf("webhooks: failed to encode payload: %v", err)
-		return
+		return &deliveryError{err: fmt.Errorf("build request: %w", err)}
 	}
-
-	client := &http.Client{Timeout: defaultTimeout}
-	workers := defaultConcurrency
-	if len(urls) < workers {
-		workers = len(urls)
+	req.Header.Set("Content-Type", "application/json")
+	if t.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, Sign(t.Secret, timestamp, body))
 	}
 
-	jobs := make(chan string)
-	var wg sync.WaitGroup
-	wg.Add(workers)
-	for i := 0; i < workers; i++ {
-		go func() {
-			defer wg.Done()
-			for endpoint := range jobs {
-				sendWebhook(client, endpoint, body)
-			}
-		}()
+	resp, err := client.Do(req)
+	if err != nil {
+		return &deliveryError{err: err, retryable: true}
 	}
+	_ = resp.Body.Close()
 
-	for _, endpoint := range urls {
-		jobs <- endpoint
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return &deliveryError{err: fmt.Errorf("server returned %s", resp.Status), retryable: true}
+	default:
+		return &deliveryError{err: fmt.Errorf("server returned %s", resp.Status)}
 	}
-	close(jobs)
-	wg.Wait()
 }
 
-func sendWebhook(client *http.Client, endpoint string, body []byte) {
-	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
-	if err != nil {
-		log.Printf("webhooks: build request %q failed: %v", endpoint, err)
-		return
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("webhooks: request to %q failed: %v", endpoint, err)
-		return
-	}
-	_ = resp.Body.Close()
+// Sign returns the X-Wrkq-Signature value for body: "sha256=" followed by the
+// hex HMAC-SHA256 of "<timestamp>.<body>" keyed with secret. Receivers should
+// recompute it over the raw request body, compare with hmac.Equal, and reject
+// stale timestamps to prevent replays.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
 }
@@ -1,10 +1,18 @@
 package webhooks_test
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"path/filepath"
 	"reflect"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/lherron/wrkq/internal/db"
 	"github.com/lherron/wrkq/internal/store"
@@ -89,3 +97,273 @@ func TestResolveWebhookTargets(t *testing.T) {
 		t.Fatalf("unexpected urls\nexpected: %v\nactual:   %v", expected, urls)
 	}
 }
+
+func TestDispatchSignsWithInheritedSecret(t *testing.T) {
+	database := setupTestDB(t)
+	actorUUID := setupTestActor(t, database)
+	s := store.New(database)
+
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	got := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		select {
+		case got <- received{header: r.Header.Clone(), body: body}:
+		default:
+		}
+	}))
+	defer server.Close()
+
+	root, err := s.Containers.Create(actorUUID, store.ContainerCreateParams{Slug: "root"})
+	if err != nil {
+		t.Fatalf("failed to create root container: %v", err)
+	}
+	child, err := s.Containers.Create(actorUUID, store.ContainerCreateParams{Slug: "child", ParentUUID: &root.UUID})
+	if err != nil {
+		t.Fatalf("failed to create child container: %v", err)
+	}
+	urlsJSON, _ := json.Marshal([]string{server.URL + "/hook"})
+	if _, err := s.Containers.UpdateFields(actorUUID, root.UUID, map[string]interface{}{"webhook_secret": "s3cret"}, 0); err != nil {
+		t.Fatalf("failed to set webhook secret: %v", err)
+	}
+	if _, err := s.Containers.UpdateFields(actorUUID, child.UUID, map[string]interface{}{"webhook_urls": string(urlsJSON)}, 0); err != nil {
+		t.Fatalf("failed to set webhook urls: %v", err)
+	}
+
+	task, err := s.Tasks.Create(actorUUID, store.CreateParams{Slug: "signed", Title: "Signed", ProjectUUID: child.UUID, State: "open", Priority: 2})
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	webhooks.DispatchTask(database, task.UUID)
+
+	var req received
+	select {
+	case req = <-got:
+	default:
+		t.Fatal("webhook was not delivered")
+	}
+
+	timestamp := req.header.Get(webhooks.TimestampHeader)
+	if timestamp == "" {
+		t.Fatal("expected timestamp header")
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(timestamp + "." + string(req.body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if sig := req.header.Get(webhooks.SignatureHeader); sig != want {
+		t.Fatalf("signature mismatch\nexpected: %s\nactual:   %s", want, sig)
+	}
+
+	// The secret itself must not be recorded in the event log
+	var leaked int
+	if err := database.QueryRow("SELECT COUNT(*) FROM event_log WHERE payload LIKE '%s3cret%'").Scan(&leaked); err != nil {
+		t.Fatalf("failed to query events: %v", err)
+	}
+	if leaked != 0 {
+		t.Fatal("webhook secret leaked into the event log")
+	}
+}
+
+// fastRetry shrinks the retry delays for the duration of a test.
+func fastRetry(t *testing.T, attempts int) {
+	t.Helper()
+	saved := webhooks.Retry
+	webhooks.Retry = webhooks.RetryPolicy{Attempts: attempts, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	t.Cleanup(func() { webhooks.Retry = saved })
+}
+
+// setupHookedTask creates a task in a container whose webhook points at url.
+func setupHookedTask(t *testing.T, database *db.DB, url string) string {
+	t.Helper()
+	actorUUID := setupTestActor(t, database)
+	s := store.New(database)
+	project, err := s.Containers.Create(actorUUID, store.ContainerCreateParams{Slug: "project"})
+	if err != nil {
+		t.Fatalf("failed to create container: %v", err)
+	}
+	task, err := s.Tasks.Create(actorUUID, store.CreateParams{Slug: "hooked", Title: "Hooked", ProjectUUID: project.UUID, State: "open", Priority: 2})
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	// Set the hook after creating the task so only the test's dispatch is sent
+	urlsJSON, _ := json.Marshal([]string{url})
+	if _, err := s.Containers.UpdateFields(actorUUID, project.UUID, map[string]interface{}{"webhook_urls": string(urlsJSON)}, 0); err != nil {
+		t.Fatalf("failed to set webhook urls: %v", err)
+	}
+	return task.UUID
+}
+
+func TestDispatchRetriesUntilDelivered(t *testing.T) {
+	fastRetry(t, 3)
+	database := setupTestDB(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	taskUUID := setupHookedTask(t, database, server.URL+"/hook")
+	succeededBefore, failedBefore := webhooks.DeliveryCounts()
+	webhooks.DispatchTask(database, taskUUID)
+
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Fatalf("expected 3 attempts, got %d", got)
+	}
+	if succeeded, failed := webhooks.DeliveryCounts(); succeeded-succeededBefore != 1 || failed-failedBefore != 2 {
+		t.Errorf("expected 1 successful and 2 failed sends counted, got %d and %d", succeeded-succeededBefore, failed-failedBefore)
+	}
+	deliveries, err := webhooks.ListDeliveries(database, "")
+	if err != nil {
+		t.Fatalf("ListDeliveries failed: %v", err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("expected 1 recorded delivery, got %d", len(deliveries))
+	}
+	d := deliveries[0]
+	if d.Status != webhooks.DeliveryDelivered || d.Attempts != 3 || d.NextRetry != nil {
+		t.Fatalf("unexpected delivery record: %+v", d)
+	}
+	if d.LastError == nil || *d.LastError != "server returned 500 Internal Server Error" {
+		t.Fatalf("expected last error to be kept, got %v", d.LastError)
+	}
+}
+
+func TestDispatchDeadLettersAndRedrives(t *testing.T) {
+	fastRetry(t, 2)
+	database := setupTestDB(t)
+
+	var healthy atomic.Bool
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	taskUUID := setupHookedTask(t, database, server.URL+"/hook")
+	webhooks.DispatchTask(database, taskUUID)
+
+	dead, err := webhooks.ListDeliveries(database, webhooks.DeliveryDead)
+	if err != nil {
+		t.Fatalf("ListDeliveries failed: %v", err)
+	}
+	if len(dead) != 1 || dead[0].Attempts != 2 || dead[0].TaskUUID != taskUUID {
+		t.Fatalf("expected one dead delivery after 2 attempts, got %+v", dead)
+	}
+
+	healthy.Store(true)
+	d, err := webhooks.Redrive(database, dead[0].ID)
+	if err != nil {
+		t.Fatalf("Redrive failed: %v", err)
+	}
+	if d.Status != webhooks.DeliveryDelivered || d.Attempts != 3 {
+		t.Fatalf("unexpected delivery after redrive: %+v", d)
+	}
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Fatalf("expected 3 requests, got %d", got)
+	}
+	if _, err := webhooks.Redrive(database, d.ID); err == nil {
+		t.Fatal("expected redriving a delivered webhook to fail")
+	}
+}
+
+func TestDispatchDoesNotRetryClientErrors(t *testing.T) {
+	fastRetry(t, 3)
+	database := setupTestDB(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	webhooks.DispatchTask(database, setupHookedTask(t, database, server.URL+"/hook"))
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("expected a single attempt, got %d", got)
+	}
+	dead, err := webhooks.ListDeliveries(database, webhooks.DeliveryDead)
+	if err != nil {
+		t.Fatalf("ListDeliveries failed: %v", err)
+	}
+	if len(dead) != 1 {
+		t.Fatalf("expected the delivery to be dead-lettered, got %+v", dead)
+	}
+}
+
+func TestResolveWebhookTargetsPlaceholders(t *testing.T) {
+	database := setupTestDB(t)
+	actorUUID := setupTestActor(t, database)
+	s := store.New(database)
+
+	project, err := s.Containers.Create(actorUUID, store.ContainerCreateParams{Slug: "project"})
+	if err != nil {
+		t.Fatalf("failed to create container: %v", err)
+	}
+	urlsJSON, _ := json.Marshal([]string{
+		"http://example.com/{project_id}/{ticket_id}/{state}?to={assignee}&uuid={ticket_uuid}",
+		"http://example.com/{project_uuid}/{unknown}",
+	})
+	if _, err := s.Containers.UpdateFields(actorUUID, project.UUID, map[string]interface{}{"webhook_urls": string(urlsJSON)}, 0); err != nil {
+		t.Fatalf("failed to set webhook urls: %v", err)
+	}
+
+	task, err := s.Tasks.Create(actorUUID, store.CreateParams{
+		Slug: "routed", Title: "Routed", ProjectUUID: project.UUID, State: "in_progress", Priority: 2,
+		AssigneeActorUUID: &actorUUID,
+	})
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	info, err := webhooks.LookupTaskInfo(database, task.UUID)
+	if err != nil {
+		t.Fatalf("LookupTaskInfo failed: %v", err)
+	}
+	if info.Assignee == nil || *info.Assignee != "test-actor" {
+		t.Fatalf("expected assignee slug, got %v", info.Assignee)
+	}
+
+	payload := webhooks.Payload{
+		TicketID: task.ID, TicketUUID: task.UUID, ProjectID: project.ID, ProjectUUID: project.UUID,
+		State: info.State, Assignee: info.Assignee,
+	}
+	urls, err := webhooks.ResolveWebhookTargets(database, project.UUID, payload)
+	if err != nil {
+		t.Fatalf("ResolveWebhookTargets failed: %v", err)
+	}
+	expected := []string{
+		"http://example.com/" + project.ID + "/" + task.ID + "/in_progress?to=test-actor&uuid=" + task.UUID,
+		"http://example.com/" + project.UUID + "/{unknown}",
+	}
+	if !reflect.DeepEqual(urls, expected) {
+		t.Fatalf("unexpected urls\nexpected: %v\nactual:   %v", expected, urls)
+	}
+
+	// Strict mode drops the URL with the unknown placeholder
+	webhooks.StrictTemplates = true
+	defer func() { webhooks.StrictTemplates = false }()
+	urls, err = webhooks.ResolveWebhookTargets(database, project.UUID, payload)
+	if err != nil {
+		t.Fatalf("ResolveWebhookTargets failed: %v", err)
+	}
+	if !reflect.DeepEqual(urls, expected[:1]) {
+		t.Fatalf("unexpected strict urls: %v", urls)
+	}
+
+	if got := webhooks.UnknownPlaceholders(expected[1]); !reflect.DeepEqual(got, []string{"{unknown}"}) {
+		t.Fatalf("unexpected unknown placeholders: %v", got)
+	}
+}